@@ -0,0 +1,162 @@
+package sauri
+
+import (
+	"errors"
+	"fmt"
+	"github.com/haskekareem/sauri/storage"
+	"github.com/haskekareem/sauri/validator"
+	"net/http"
+	"strconv"
+)
+
+// Envelope is the JSON body of every API response written by Success, Fail and Paginated
+type Envelope struct {
+	Success bool                   `json:"success"`
+	Data    interface{}            `json:"data,omitempty"`
+	Meta    map[string]interface{} `json:"meta,omitempty"`
+	Error   *EnvelopeError         `json:"error,omitempty"`
+}
+
+// EnvelopeError describes what went wrong in a failed API response
+type EnvelopeError struct {
+	Code    string                   `json:"code"`
+	Message string                   `json:"message"`
+	Fields  validator.ErrorContainer `json:"fields,omitempty"`
+}
+
+// HTTPError is an error that knows the HTTP status and error code it is answered with,
+// return it from services and hand it to Response.Error
+type HTTPError struct {
+	Status  int
+	Code    string
+	Message string
+	Fields  validator.ErrorContainer
+	Err     error // cause, logged but never sent to the client
+}
+
+// NewHTTPError creates an HTTPError
+func NewHTTPError(status int, code, message string) *HTTPError {
+	return &HTTPError{Status: status, Code: code, Message: message}
+}
+
+// Error implements the error interface
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Unwrap returns the cause of the error
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// Wrap returns a copy of the error with its cause
+func (e *HTTPError) Wrap(err error) *HTTPError {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+// the errors APIs answer with most often
+var (
+	ErrBadRequest   = NewHTTPError(http.StatusBadRequest, "bad_request", "The request is invalid")
+	ErrUnauthorized = NewHTTPError(http.StatusUnauthorized, "unauthorized", "Authentication is required")
+	ErrForbidden    = NewHTTPError(http.StatusForbidden, "forbidden", "You are not allowed to do this")
+	ErrNotFound     = NewHTTPError(http.StatusNotFound, "not_found", "The resource was not found")
+	ErrConflict     = NewHTTPError(http.StatusConflict, "conflict", "The resource is in conflict with another")
+	ErrInternal     = NewHTTPError(http.StatusInternalServerError, "internal_error", "Something went wrong")
+)
+
+// AsHTTPError maps any error to an HTTPError: bad JSON bodies give 400, validation errors 422,
+// missing files 404, oversized uploads 413 and anything unknown a 500 that does not leak
+// its message
+func AsHTTPError(err error) *HTTPError {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+
+	var validationErr *validator.ValidationError
+	var jsonErr *JSONError
+	switch {
+	case errors.As(err, &jsonErr):
+		return NewHTTPError(http.StatusBadRequest, "invalid_json", jsonErr.Message).Wrap(err)
+	case errors.As(err, &validationErr):
+		return &HTTPError{Status: http.StatusUnprocessableEntity, Code: "validation_failed",
+			Message: validationErr.Message, Fields: validationErr.Errors, Err: err}
+	case errors.Is(err, storage.ErrNotFound):
+		return ErrNotFound.Wrap(err)
+	case errors.Is(err, ErrUploadTooLarge):
+		return NewHTTPError(http.StatusRequestEntityTooLarge, "upload_too_large", "The upload is too large").Wrap(err)
+	case errors.Is(err, storage.ErrExtensionNotAllowed):
+		return NewHTTPError(http.StatusUnsupportedMediaType, "extension_not_allowed", "This type of file is not allowed").Wrap(err)
+	}
+	return ErrInternal.Wrap(err)
+}
+
+// Success writes a 200 envelope with the data and optional metadata
+func (r *Response) Success(data interface{}, meta map[string]interface{}) error {
+	return r.JSON(Envelope{Success: true, Data: data, Meta: meta}, http.StatusOK)
+}
+
+// Fail writes an error envelope, fieldErrors may be nil
+func (r *Response) Fail(status int, code, message string, fieldErrors validator.ErrorContainer) error {
+	return r.JSON(Envelope{Error: &EnvelopeError{Code: code, Message: message, Fields: fieldErrors}}, status)
+}
+
+// Error writes the error envelope matching any error, see AsHTTPError
+func (r *Response) Error(err error) error {
+	httpErr := AsHTTPError(err)
+	return r.Fail(httpErr.Status, httpErr.Code, httpErr.Message, httpErr.Fields)
+}
+
+// Paginated writes a 200 envelope with a page of data and the pagination in the metadata
+func (r *Response) Paginated(data interface{}, paginator *Paginator) error {
+	return r.JSON(Envelope{Success: true, Data: data, Meta: map[string]interface{}{"pagination": paginator}}, http.StatusOK)
+}
+
+// Paginator holds the position of a page in a list
+type Paginator struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+}
+
+// maxPerPage keeps clients from asking for everything at once
+const maxPerPage = 100
+
+// NewPaginator reads the page and per_page query parameters of the request, falling back to
+// the first page of perPage items. Set the total with SetTotal once it is counted.
+func NewPaginator(r *http.Request, perPage int) *Paginator {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	if asked, err := strconv.Atoi(r.URL.Query().Get("per_page")); err == nil && asked > 0 {
+		perPage = min(asked, maxPerPage)
+	}
+	if perPage < 1 {
+		perPage = 15
+	}
+	return &Paginator{Page: page, PerPage: perPage}
+}
+
+// SetTotal sets the number of items in the whole list
+func (p *Paginator) SetTotal(total int) *Paginator {
+	p.Total = total
+	p.TotalPages = (total + p.PerPage - 1) / p.PerPage
+	return p
+}
+
+// Offset returns the number of items before the page, for SQL OFFSET
+func (p *Paginator) Offset() int {
+	return (p.Page - 1) * p.PerPage
+}
+
+// Limit returns the number of items of a page, for SQL LIMIT
+func (p *Paginator) Limit() int {
+	return p.PerPage
+}
@@ -0,0 +1,90 @@
+package sauri
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/haskekareem/sauri/storage"
+	"github.com/haskekareem/sauri/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsHTTPError(t *testing.T) {
+	fields := validator.ErrorContainer{"email": {"The email is required"}}
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"http error", ErrConflict, http.StatusConflict, "conflict", "The resource is in conflict with another"},
+		{"wrapped http error", fmt.Errorf("saving: %w", ErrForbidden), http.StatusForbidden, "forbidden", "You are not allowed to do this"},
+		{"json error", &JSONError{Message: "body must not be empty"}, http.StatusBadRequest, "invalid_json", "body must not be empty"},
+		{"validation error", validator.NewValidationError(fields), http.StatusUnprocessableEntity, "validation_failed", "The given data was invalid"},
+		{"missing file", fmt.Errorf("avatar: %w", storage.ErrNotFound), http.StatusNotFound, "not_found", "The resource was not found"},
+		{"upload too large", fmt.Errorf("%w: the request is over 10 bytes", ErrUploadTooLarge), http.StatusRequestEntityTooLarge, "upload_too_large", "The upload is too large"},
+		{"extension not allowed", fmt.Errorf("%w: .exe", storage.ErrExtensionNotAllowed), http.StatusUnsupportedMediaType, "extension_not_allowed", "This type of file is not allowed"},
+		// the message of unknown errors is never sent
+		{"unknown error", errors.New("dial tcp: connection refused"), http.StatusInternalServerError, "internal_error", "Something went wrong"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpErr := AsHTTPError(tt.err)
+			assert.Equal(t, tt.status, httpErr.Status)
+			assert.Equal(t, tt.code, httpErr.Code)
+			assert.Equal(t, tt.message, httpErr.Message)
+			// the cause is kept, an HTTPError is returned as it is
+			assert.True(t, errors.Is(httpErr, tt.err) || errors.Is(tt.err, httpErr))
+		})
+	}
+
+	t.Run("validation fields", func(t *testing.T) {
+		assert.Equal(t, fields, AsHTTPError(validator.NewValidationError(fields)).Fields)
+	})
+	t.Run("shared errors are not changed", func(t *testing.T) {
+		AsHTTPError(errors.New("unknown"))
+		assert.Nil(t, ErrInternal.Err)
+	})
+}
+
+func TestNewPaginator(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		perPage int
+		page    int
+		want    int
+	}{
+		{name: "defaults", perPage: 20, page: 1, want: 20},
+		{name: "no default per page", page: 1, want: 15},
+		{name: "page and per page", query: "page=3&per_page=10", perPage: 20, page: 3, want: 10},
+		{name: "per page clamped", query: "per_page=1000", perPage: 20, page: 1, want: maxPerPage},
+		{name: "zero per page", query: "per_page=0", perPage: 20, page: 1, want: 20},
+		{name: "negative per page", query: "per_page=-5", perPage: 20, page: 1, want: 20},
+		{name: "per page not a number", query: "per_page=all", perPage: 20, page: 1, want: 20},
+		{name: "page zero", query: "page=0", perPage: 20, page: 1, want: 20},
+		{name: "negative page", query: "page=-2", perPage: 20, page: 1, want: 20},
+		{name: "page not a number", query: "page=last", perPage: 20, page: 1, want: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPaginator(httptest.NewRequest(http.MethodGet, "/items?"+tt.query, nil), tt.perPage)
+			assert.Equal(t, tt.page, p.Page)
+			assert.Equal(t, tt.want, p.PerPage)
+			assert.Equal(t, tt.want, p.Limit())
+			assert.Equal(t, (tt.page-1)*tt.want, p.Offset())
+		})
+	}
+}
+
+func TestPaginator_SetTotal(t *testing.T) {
+	p := NewPaginator(httptest.NewRequest(http.MethodGet, "/items?per_page=10", nil), 20)
+	assert.Equal(t, 0, p.SetTotal(0).TotalPages)
+	assert.Equal(t, 1, p.SetTotal(10).TotalPages)
+	assert.Equal(t, 3, p.SetTotal(21).TotalPages)
+	assert.Equal(t, 21, p.Total)
+}
@@ -0,0 +1,54 @@
+package sauri
+
+import (
+	"github.com/haskekareem/sauri/auth"
+	"github.com/haskekareem/sauri/jwt"
+	"time"
+)
+
+// initAuth sets up the session and API token authentication of the application over the
+// users and tokens tables of sauri make auth, when a database is in use. AUTH_HASHER picks
+// the hasher of new passwords.
+func (s *Sauri) initAuth() {
+	var provider interface {
+		auth.UserProvider
+		auth.TokenStore
+	}
+	switch {
+	case s.DBConn.PgxConnPool != nil:
+		provider = &auth.PgxProvider{Pool: s.DBConn.PgxConnPool}
+	case s.DBConn.SqlConnPool != nil:
+		provider = auth.NewSQLProvider(s.DBConn.SqlConnPool, s.DBConn.DatabaseType)
+	default:
+		return
+	}
+
+	s.Auth = auth.New(s.Session, provider)
+	s.Auth.Tokens = provider
+	if s.Config.Get("AUTH_HASHER") == "argon2id" {
+		s.Auth.Hasher = auth.Argon2id{}
+	}
+	s.Auth.Secure = s.config.cookie.secure == "true"
+	s.Auth.LoginPath = s.Config.Get("AUTH_LOGIN_PATH")
+	s.Auth.HomePath = s.Config.Get("AUTH_HOME_PATH")
+	if days := s.Config.GetInt("AUTH_REMEMBER_DAYS", 0); days > 0 {
+		s.Auth.RememberFor = time.Duration(days) * 24 * time.Hour
+	}
+}
+
+// initJWT creates the JWT manager the JWT_* variables configure, its access tokens are also
+// accepted by Auth.AuthenticateToken
+func (s *Sauri) initJWT() {
+	manager, err := jwt.FromConfig(s.Config, s.RootPath, s.AppName)
+	if err != nil {
+		s.ErrorLog.Println("cannot set up JWT:", err)
+		return
+	}
+	if manager == nil {
+		return
+	}
+	s.JWT = manager
+	if s.Auth != nil {
+		s.Auth.Verifier = manager
+	}
+}
@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/alexedwards/scs/v2"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SessionKey is the session key holding the ID of the logged-in user
+const SessionKey = "userID"
+
+// intendedKey is the session key holding the URL a guest was sent away from by RequireAuth
+const intendedKey = "auth.intended"
+
+var (
+	// ErrInvalidCredentials is returned by Attempt for an unknown email or a wrong password
+	ErrInvalidCredentials = errors.New("auth: invalid credentials")
+	// ErrInactive is returned by Attempt for a user that is not active
+	ErrInactive = errors.New("auth: user is not active")
+)
+
+// Auth logs users in and out of the scs session
+type Auth struct {
+	Session *scs.SessionManager
+	Users   UserProvider
+	Hasher  Hasher // hashes new passwords, both bcrypt and argon2id hashes are verified
+
+	Tokens   TokenStore    // keeps the API tokens, see IssueToken
+	Verifier TokenVerifier // verifies the JWT bearer tokens, optional
+
+	RememberCookie string        // "remember_token" when empty
+	RememberFor    time.Duration // 30 days when zero
+	Secure         bool          // sets the Secure flag of the remember cookie
+
+	LoginPath string // where RequireAuth sends guests, "/login" when empty
+	HomePath  string // where Guest sends logged-in users, "/" when empty
+}
+
+// New returns an Auth with the defaults, hashing passwords with bcrypt
+func New(session *scs.SessionManager, users UserProvider) *Auth {
+	return &Auth{Session: session, Users: users, Hasher: Bcrypt{}}
+}
+
+func (a *Auth) rememberCookie() string {
+	if a.RememberCookie == "" {
+		return "remember_token"
+	}
+	return a.RememberCookie
+}
+
+func (a *Auth) rememberFor() time.Duration {
+	if a.RememberFor == 0 {
+		return 30 * 24 * time.Hour
+	}
+	return a.RememberFor
+}
+
+// Hash hashes a password with the configured hasher
+func (a *Auth) Hash(password string) (string, error) {
+	if a.Hasher == nil {
+		return Bcrypt{}.Hash(password)
+	}
+	return a.Hasher.Hash(password)
+}
+
+// Attempt checks the credentials and logs the user in when they match
+func (a *Auth) Attempt(w http.ResponseWriter, r *http.Request, email, password string, remember bool) (*User, error) {
+	user, err := a.CheckCredentials(r.Context(), email, password)
+	if err != nil {
+		return nil, err
+	}
+	return user, a.Login(w, r, user, remember)
+}
+
+// CheckCredentials returns the user of the credentials without logging it in, for the APIs
+// issuing tokens. It fails with ErrInvalidCredentials when they do not match and ErrInactive
+// when the user is not active.
+func (a *Auth) CheckCredentials(ctx context.Context, email, password string) (*User, error) {
+	user, err := a.Users.UserByEmail(ctx, email)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	ok, err := VerifyPassword(user.Password, password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	if !user.Active {
+		return nil, ErrInactive
+	}
+	return user, nil
+}
+
+// Login logs the user in, renewing the session token against fixation. With remember set a
+// remember-me cookie keeps the user logged in after the session expired.
+func (a *Auth) Login(w http.ResponseWriter, r *http.Request, user *User, remember bool) error {
+	if err := a.Session.RenewToken(r.Context()); err != nil {
+		return fmt.Errorf("cannot renew the session token: %w", err)
+	}
+	a.Session.Put(r.Context(), SessionKey, user.ID)
+	if !remember {
+		return nil
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	if err := a.Users.SaveRememberToken(r.Context(), user.ID, hashToken(token)); err != nil {
+		return fmt.Errorf("cannot save the remember token: %w", err)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     a.rememberCookie(),
+		Value:    token,
+		Path:     "/",
+		Expires:  time.Now().Add(a.rememberFor()),
+		MaxAge:   int(a.rememberFor().Seconds()),
+		HttpOnly: true,
+		Secure:   a.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// Logout logs the user out and forgets the remember-me token of the browser
+func (a *Auth) Logout(w http.ResponseWriter, r *http.Request) error {
+	if c, err := r.Cookie(a.rememberCookie()); err == nil && c.Value != "" {
+		if err := a.Users.DeleteRememberToken(r.Context(), hashToken(c.Value)); err != nil {
+			return fmt.Errorf("cannot delete the remember token: %w", err)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     a.rememberCookie(),
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   a.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	a.Session.Remove(r.Context(), SessionKey)
+	return a.Session.RenewToken(r.Context())
+}
+
+// UserID returns the ID of the logged-in user, zero for guests
+func (a *Auth) UserID(r *http.Request) int {
+	return a.Session.GetInt(r.Context(), SessionKey)
+}
+
+// Check reports whether a user is logged in
+func (a *Auth) Check(r *http.Request) bool {
+	return a.Session.Exists(r.Context(), SessionKey)
+}
+
+// User returns the logged-in user, nil for guests
+func (a *Auth) User(r *http.Request) (*User, error) {
+	if !a.Check(r) {
+		return nil, nil
+	}
+	user, err := a.Users.UserByID(r.Context(), a.UserID(r))
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, nil
+	}
+	return user, err
+}
+
+// Intended returns the URL a guest asked for before RequireAuth sent them to the login page,
+// the fallback when there is none. Redirect there after a successful login.
+func (a *Auth) Intended(r *http.Request, fallback string) string {
+	if url := a.Session.PopString(r.Context(), intendedKey); url != "" {
+		return url
+	}
+	return fallback
+}
+
+// viaRemember logs the user of a valid remember-me cookie back in
+func (a *Auth) viaRemember(r *http.Request) bool {
+	c, err := r.Cookie(a.rememberCookie())
+	if err != nil || c.Value == "" {
+		return false
+	}
+	user, err := a.Users.UserByRememberToken(r.Context(), hashToken(c.Value))
+	if err != nil || !user.Active {
+		return false
+	}
+	if err := a.Session.RenewToken(r.Context()); err != nil {
+		return false
+	}
+	a.Session.Put(r.Context(), SessionKey, user.ID)
+	return true
+}
+
+// RequireAuth lets logged-in users through. Guests get 401 from JSON requests and are
+// redirected to the login page otherwise.
+func (a *Auth) RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.Check(r) || a.viaRemember(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if wantsJSON(r) {
+			writeError(w, http.StatusUnauthorized, "unauthenticated")
+			return
+		}
+		if r.Method == http.MethodGet {
+			a.Session.Put(r.Context(), intendedKey, r.URL.RequestURI())
+		}
+		loginPath := a.LoginPath
+		if loginPath == "" {
+			loginPath = "/login"
+		}
+		http.Redirect(w, r, loginPath, http.StatusSeeOther)
+	})
+}
+
+// Guest lets only guests through, logged-in users are redirected to the home page, e.g.
+// away from the login and register pages
+func (a *Auth) Guest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Check(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		homePath := a.HomePath
+		if homePath == "" {
+			homePath = "/"
+		}
+		http.Redirect(w, r, homePath, http.StatusSeeOther)
+	})
+}
+
+// randomToken returns a new remember-me token
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("cannot generate a remember token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is what the providers store of a remember-me token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// wantsJSON reports whether the client asked for a JSON answer
+func wantsJSON(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+			return true
+		}
+	}
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+}
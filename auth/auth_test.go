@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"context"
+	"github.com/alexedwards/scs/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryUsers is a UserProvider over a map
+type memoryUsers struct {
+	users  map[int]*User
+	tokens map[string]int
+}
+
+func (m *memoryUsers) UserByID(_ context.Context, id int) (*User, error) {
+	if u, ok := m.users[id]; ok {
+		return u, nil
+	}
+	return nil, ErrUserNotFound
+}
+
+func (m *memoryUsers) UserByEmail(_ context.Context, email string) (*User, error) {
+	for _, u := range m.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (m *memoryUsers) SaveRememberToken(_ context.Context, userID int, tokenHash string) error {
+	m.tokens[tokenHash] = userID
+	return nil
+}
+
+func (m *memoryUsers) UserByRememberToken(ctx context.Context, tokenHash string) (*User, error) {
+	id, ok := m.tokens[tokenHash]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return m.UserByID(ctx, id)
+}
+
+func (m *memoryUsers) DeleteRememberToken(_ context.Context, tokenHash string) error {
+	delete(m.tokens, tokenHash)
+	return nil
+}
+
+func TestHashers(t *testing.T) {
+	for _, hasher := range []Hasher{Bcrypt{Cost: 4}, Argon2id{Memory: 1024}} {
+		hash, err := hasher.Hash("secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := hasher.Verify(hash, "secret"); !ok || err != nil {
+			t.Errorf("%T: the password does not verify: %v", hasher, err)
+		}
+		if ok, _ := VerifyPassword(hash, "wrong"); ok {
+			t.Errorf("%T: a wrong password verifies", hasher)
+		}
+		if ok, _ := VerifyPassword(hash, "secret"); !ok {
+			t.Errorf("%T: VerifyPassword does not detect the hash", hasher)
+		}
+	}
+}
+
+func newTestAuth(t *testing.T) (*Auth, *memoryUsers) {
+	t.Helper()
+	hash, err := Bcrypt{Cost: 4}.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := &memoryUsers{
+		users: map[int]*User{
+			1: {ID: 1, Email: "jane@example.com", Password: hash, Active: true},
+			2: {ID: 2, Email: "john@example.com", Password: hash},
+		},
+		tokens: map[string]int{},
+	}
+	return New(scs.New(), users), users
+}
+
+func TestAttemptAndMiddleware(t *testing.T) {
+	a, users := newTestAuth(t)
+
+	var attemptErr error
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		_, attemptErr = a.Attempt(w, r, r.URL.Query().Get("email"), r.URL.Query().Get("password"), true)
+	})
+	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		_ = a.Logout(w, r)
+	})
+	mux.Handle("/private", a.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	})))
+	handler := a.Session.LoadAndSave(mux)
+
+	do := func(path string, cookies []*http.Cookie, header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		if len(header) == 2 {
+			r.Header.Set(header[0], header[1])
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := do("/private", nil); w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login" {
+		t.Fatalf("guest: got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := do("/private", nil, "Accept", "application/json"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("JSON guest: got %d", w.Code)
+	}
+
+	do("/login?email=jane@example.com&password=wrong", nil)
+	if attemptErr != ErrInvalidCredentials {
+		t.Fatalf("wrong password: got %v", attemptErr)
+	}
+	do("/login?email=john@example.com&password=secret", nil)
+	if attemptErr != ErrInactive {
+		t.Fatalf("inactive user: got %v", attemptErr)
+	}
+
+	w := do("/login?email=jane@example.com&password=secret", nil)
+	if attemptErr != nil {
+		t.Fatal(attemptErr)
+	}
+	cookies := w.Result().Cookies()
+	if w := do("/private", cookies); w.Code != http.StatusOK || w.Body.String() != "hello" {
+		t.Fatalf("logged in: got %d", w.Code)
+	}
+	if len(users.tokens) != 1 {
+		t.Fatalf("expected a remember token, got %d", len(users.tokens))
+	}
+
+	// the remember cookie alone logs the user back in
+	var remember []*http.Cookie
+	for _, c := range cookies {
+		if c.Name == "remember_token" {
+			remember = append(remember, c)
+		}
+	}
+	if w := do("/private", remember); w.Code != http.StatusOK {
+		t.Fatalf("remember me: got %d", w.Code)
+	}
+
+	w = do("/logout", cookies)
+	if len(users.tokens) != 0 {
+		t.Fatal("the remember token survived the logout")
+	}
+	if w := do("/private", w.Result().Cookies()); w.Code != http.StatusSeeOther {
+		t.Fatalf("logged out: got %d", w.Code)
+	}
+}
+
+func TestCheckCredentials(t *testing.T) {
+	a, users := newTestAuth(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		email, password string
+		want            error
+	}{
+		{"jane@example.com", "secret", nil},
+		{"jane@example.com", "wrong", ErrInvalidCredentials},
+		{"nobody@example.com", "secret", ErrInvalidCredentials},
+		{"john@example.com", "secret", ErrInactive},
+	}
+	for _, tt := range tests {
+		user, err := a.CheckCredentials(ctx, tt.email, tt.password)
+		if err != tt.want {
+			t.Errorf("%s/%s: got %v, expected %v", tt.email, tt.password, err, tt.want)
+		}
+		if err == nil && user.ID != 1 {
+			t.Errorf("%s: got user %d", tt.email, user.ID)
+		}
+	}
+	if len(users.tokens) != 0 {
+		t.Error("checking the credentials saved a remember token")
+	}
+}
+
+func TestGuest(t *testing.T) {
+	a, _ := newTestAuth(t)
+	handler := a.Session.LoadAndSave(a.Guest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	r := httptest.NewRequest(http.MethodGet, "/login", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("guest: got %d", w.Code)
+	}
+}
+
+func TestSQLProviderPlaceholders(t *testing.T) {
+	p := NewSQLProvider(nil, "mysql")
+	if q := p.query("INSERT INTO remember_tokens (user_id, remember_token) VALUES ($1, $2)"); strings.Contains(q, "$") {
+		t.Errorf("placeholders left in %q", q)
+	}
+	if q := NewSQLProvider(nil, "postgres").query("SELECT $1"); q != "SELECT $1" {
+		t.Errorf("postgres query rewritten to %q", q)
+	}
+}
+
+// memoryTokens is a TokenStore over a map
+type memoryTokens map[string]*Token
+
+func (m memoryTokens) SaveToken(_ context.Context, token *Token, hash []byte) error {
+	saved := *token
+	saved.PlainText = ""
+	m[string(hash)] = &saved
+	return nil
+}
+
+func (m memoryTokens) TokenByHash(_ context.Context, hash []byte) (*Token, error) {
+	if t, ok := m[string(hash)]; ok {
+		return t, nil
+	}
+	return nil, ErrTokenNotFound
+}
+
+func (m memoryTokens) DeleteToken(_ context.Context, hash []byte) error {
+	delete(m, string(hash))
+	return nil
+}
+
+func (m memoryTokens) DeleteUserTokens(_ context.Context, userID int) error {
+	for hash, t := range m {
+		if t.UserID == userID {
+			delete(m, hash)
+		}
+	}
+	return nil
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	a, users := newTestAuth(t)
+	tokens := memoryTokens{}
+	a.Tokens = tokens
+
+	token, err := a.IssueToken(context.Background(), users.users[1], time.Hour, "posts:read")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tokens[string(tokenHash(token.PlainText))]; !ok {
+		t.Fatal("the token is not stored by its hash")
+	}
+	expired, err := a.IssueToken(context.Background(), users.users[1], -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var seen *User
+	handler := a.AuthenticateToken(RequireScopes("posts:read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = UserFromContext(r.Context())
+	})))
+	writeHandler := a.AuthenticateToken(RequireScopes("posts:write")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	do := func(h http.Handler, bearer string) int {
+		r := httptest.NewRequest(http.MethodGet, "/api", nil)
+		if bearer != "" {
+			r.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := do(handler, ""); code != http.StatusUnauthorized {
+		t.Errorf("no token: got %d", code)
+	}
+	if code := do(handler, "nope"); code != http.StatusUnauthorized {
+		t.Errorf("unknown token: got %d", code)
+	}
+	if code := do(handler, expired.PlainText); code != http.StatusUnauthorized {
+		t.Errorf("expired token: got %d", code)
+	}
+	if code := do(handler, token.PlainText); code != http.StatusOK || seen == nil || seen.ID != 1 {
+		t.Errorf("valid token: got %d and user %v", code, seen)
+	}
+	if code := do(writeHandler, token.PlainText); code != http.StatusForbidden {
+		t.Errorf("missing scope: got %d", code)
+	}
+
+	if err := a.RevokeToken(context.Background(), token.PlainText); err != nil {
+		t.Fatal(err)
+	}
+	if code := do(handler, token.PlainText); code != http.StatusUnauthorized {
+		t.Errorf("revoked token: got %d", code)
+	}
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// Hasher hashes and verifies passwords
+type Hasher interface {
+	Hash(password string) (string, error)
+	// Verify reports whether the password matches the hash
+	Verify(hash, password string) (bool, error)
+}
+
+// Bcrypt hashes passwords with bcrypt
+type Bcrypt struct {
+	Cost int // bcrypt.DefaultCost when zero
+}
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	cost := b.Cost
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", fmt.Errorf("cannot hash the password: %w", err)
+	}
+	return string(hash), nil
+}
+
+func (b Bcrypt) Verify(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Argon2id hashes passwords with argon2id in the PHC string format,
+// $argon2id$v=19$m=65536,t=1,p=4$<salt>$<key>. The hashes are about 100 characters long.
+type Argon2id struct {
+	Time    uint32 // 1 when zero
+	Memory  uint32 // in KiB, 64 MiB when zero
+	Threads uint8  // 4 when zero
+	KeyLen  uint32 // 32 when zero
+}
+
+// params returns the parameters with the defaults filled in
+func (a Argon2id) params() Argon2id {
+	if a.Time == 0 {
+		a.Time = 1
+	}
+	if a.Memory == 0 {
+		a.Memory = 64 * 1024
+	}
+	if a.Threads == 0 {
+		a.Threads = 4
+	}
+	if a.KeyLen == 0 {
+		a.KeyLen = 32
+	}
+	return a
+}
+
+func (a Argon2id) Hash(password string) (string, error) {
+	p := a.params()
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("cannot generate a salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a Argon2id) Verify(hash, password string) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false, errors.New("not an argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, fmt.Errorf("unsupported argon2id version %q", parts[2])
+	}
+	var p Argon2id
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return false, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, fmt.Errorf("invalid argon2id key: %w", err)
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, computed) == 1, nil
+}
+
+// VerifyPassword checks a password against a bcrypt or argon2id hash, whichever it is
+func VerifyPassword(hash, password string) (bool, error) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		return Argon2id{}.Verify(hash, password)
+	}
+	return Bcrypt{}.Verify(hash, password)
+}
@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"strconv"
+	"strings"
+)
+
+// ErrUserNotFound is returned by the providers when there is no such user
+var ErrUserNotFound = errors.New("auth: user not found")
+
+// User is an account as the auth package sees it
+type User struct {
+	ID        int
+	FirstName string
+	LastName  string
+	Email     string
+	Password  string // the password hash
+	Active    bool
+}
+
+// UserProvider finds users and keeps their remember-me tokens, implement it to authenticate
+// against something other than the tables of sauri make auth
+type UserProvider interface {
+	UserByID(ctx context.Context, id int) (*User, error)
+	UserByEmail(ctx context.Context, email string) (*User, error)
+	// SaveRememberToken stores the hash of a remember-me token of the user
+	SaveRememberToken(ctx context.Context, userID int, tokenHash string) error
+	UserByRememberToken(ctx context.Context, tokenHash string) (*User, error)
+	DeleteRememberToken(ctx context.Context, tokenHash string) error
+}
+
+// userColumns are the columns of the users table read by the providers
+const userColumns = "id, first_name, last_name, email, password, user_active"
+
+// tokenColumns are the columns of the tokens table read by the providers
+const tokenColumns = "id, user_id, first_name, email, scopes, expiry, created_at"
+
+// scanToken reads a token row
+func scanToken(row interface{ Scan(dest ...any) error }) (*Token, error) {
+	t := &Token{}
+	var scopes string
+	err := row.Scan(&t.ID, &t.UserID, &t.FirstName, &t.Email, &scopes, &t.Expiry, &t.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.Scopes = splitScopes(scopes)
+	return t, nil
+}
+
+// SQLProvider reads the users, remember_tokens and tokens tables of sauri make auth with
+// database/sql. Placeholders are $1 style unless Question is set, for MySQL.
+type SQLProvider struct {
+	DB       *sql.DB
+	Question bool
+}
+
+// NewSQLProvider returns a provider for the database type, e.g. postgres or mysql
+func NewSQLProvider(db *sql.DB, databaseType string) *SQLProvider {
+	return &SQLProvider{DB: db, Question: databaseType == "mysql" || databaseType == "mariadb"}
+}
+
+// query rewrites the $n placeholders of a query for MySQL
+func (p *SQLProvider) query(q string) string {
+	if !p.Question {
+		return q
+	}
+	for i := 9; i > 0; i-- {
+		q = strings.ReplaceAll(q, "$"+strconv.Itoa(i), "?")
+	}
+	return q
+}
+
+// scanUser reads a user row
+func scanUser(row interface{ Scan(dest ...any) error }) (*User, error) {
+	u := &User{}
+	var active int
+	err := row.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.Password, &active)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.Active = active == 1
+	return u, nil
+}
+
+func (p *SQLProvider) UserByID(ctx context.Context, id int) (*User, error) {
+	return scanUser(p.DB.QueryRowContext(ctx, p.query("SELECT "+userColumns+" FROM users WHERE id = $1"), id))
+}
+
+func (p *SQLProvider) UserByEmail(ctx context.Context, email string) (*User, error) {
+	return scanUser(p.DB.QueryRowContext(ctx, p.query("SELECT "+userColumns+" FROM users WHERE email = $1"), email))
+}
+
+func (p *SQLProvider) SaveRememberToken(ctx context.Context, userID int, tokenHash string) error {
+	_, err := p.DB.ExecContext(ctx, p.query("INSERT INTO remember_tokens (user_id, remember_token) VALUES ($1, $2)"), userID, tokenHash)
+	return err
+}
+
+func (p *SQLProvider) UserByRememberToken(ctx context.Context, tokenHash string) (*User, error) {
+	return scanUser(p.DB.QueryRowContext(ctx, p.query("SELECT u.id, u.first_name, u.last_name, u.email, u.password, u.user_active "+
+		"FROM users u JOIN remember_tokens t ON t.user_id = u.id WHERE t.remember_token = $1"), tokenHash))
+}
+
+func (p *SQLProvider) DeleteRememberToken(ctx context.Context, tokenHash string) error {
+	_, err := p.DB.ExecContext(ctx, p.query("DELETE FROM remember_tokens WHERE remember_token = $1"), tokenHash)
+	return err
+}
+
+const insertToken = "INSERT INTO tokens (user_id, first_name, email, token, token_hash, scopes, expiry, created_at, updated_at) " +
+	"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)"
+
+func (p *SQLProvider) SaveToken(ctx context.Context, t *Token, hash []byte) error {
+	_, err := p.DB.ExecContext(ctx, p.query(insertToken), t.UserID, t.FirstName, t.Email, tokenPrefix(t.PlainText), hash,
+		joinScopes(t.Scopes), t.Expiry, t.CreatedAt, t.CreatedAt)
+	return err
+}
+
+func (p *SQLProvider) TokenByHash(ctx context.Context, hash []byte) (*Token, error) {
+	return scanToken(p.DB.QueryRowContext(ctx, p.query("SELECT "+tokenColumns+" FROM tokens WHERE token_hash = $1"), hash))
+}
+
+func (p *SQLProvider) DeleteToken(ctx context.Context, hash []byte) error {
+	_, err := p.DB.ExecContext(ctx, p.query("DELETE FROM tokens WHERE token_hash = $1"), hash)
+	return err
+}
+
+func (p *SQLProvider) DeleteUserTokens(ctx context.Context, userID int) error {
+	_, err := p.DB.ExecContext(ctx, p.query("DELETE FROM tokens WHERE user_id = $1"), userID)
+	return err
+}
+
+// PgxProvider reads the users, remember_tokens and tokens tables of sauri make auth with a pgx pool
+type PgxProvider struct {
+	Pool *pgxpool.Pool
+}
+
+func (p *PgxProvider) UserByID(ctx context.Context, id int) (*User, error) {
+	return scanUser(p.Pool.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", id))
+}
+
+func (p *PgxProvider) UserByEmail(ctx context.Context, email string) (*User, error) {
+	return scanUser(p.Pool.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE email = $1", email))
+}
+
+func (p *PgxProvider) SaveRememberToken(ctx context.Context, userID int, tokenHash string) error {
+	_, err := p.Pool.Exec(ctx, "INSERT INTO remember_tokens (user_id, remember_token) VALUES ($1, $2)", userID, tokenHash)
+	return err
+}
+
+func (p *PgxProvider) UserByRememberToken(ctx context.Context, tokenHash string) (*User, error) {
+	return scanUser(p.Pool.QueryRow(ctx, "SELECT u.id, u.first_name, u.last_name, u.email, u.password, u.user_active "+
+		"FROM users u JOIN remember_tokens t ON t.user_id = u.id WHERE t.remember_token = $1", tokenHash))
+}
+
+func (p *PgxProvider) DeleteRememberToken(ctx context.Context, tokenHash string) error {
+	_, err := p.Pool.Exec(ctx, "DELETE FROM remember_tokens WHERE remember_token = $1", tokenHash)
+	return err
+}
+
+func (p *PgxProvider) SaveToken(ctx context.Context, t *Token, hash []byte) error {
+	_, err := p.Pool.Exec(ctx, insertToken, t.UserID, t.FirstName, t.Email, tokenPrefix(t.PlainText), hash,
+		joinScopes(t.Scopes), t.Expiry, t.CreatedAt, t.CreatedAt)
+	return err
+}
+
+func (p *PgxProvider) TokenByHash(ctx context.Context, hash []byte) (*Token, error) {
+	return scanToken(p.Pool.QueryRow(ctx, "SELECT "+tokenColumns+" FROM tokens WHERE token_hash = $1", hash))
+}
+
+func (p *PgxProvider) DeleteToken(ctx context.Context, hash []byte) error {
+	_, err := p.Pool.Exec(ctx, "DELETE FROM tokens WHERE token_hash = $1", hash)
+	return err
+}
+
+func (p *PgxProvider) DeleteUserTokens(ctx context.Context, userID int) error {
+	_, err := p.Pool.Exec(ctx, "DELETE FROM tokens WHERE user_id = $1", userID)
+	return err
+}
@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrTokenNotFound is returned by the token stores for an unknown token
+var ErrTokenNotFound = errors.New("auth: token not found")
+
+// Token is an API token of a user, sent as "Authorization: Bearer <token>"
+type Token struct {
+	ID        int
+	UserID    int
+	FirstName string
+	Email     string
+	PlainText string // only known right after IssueToken, the stores keep its hash
+	Scopes    []string
+	Expiry    time.Time
+	CreatedAt time.Time
+}
+
+// Can reports whether the token was issued with the scope, "*" grants every scope
+func (t *Token) Can(scope string) bool {
+	return slices.Contains(t.Scopes, scope) || slices.Contains(t.Scopes, "*")
+}
+
+// TokenStore keeps the API tokens, by the sha256 hash of the token
+type TokenStore interface {
+	SaveToken(ctx context.Context, token *Token, hash []byte) error
+	TokenByHash(ctx context.Context, hash []byte) (*Token, error)
+	DeleteToken(ctx context.Context, hash []byte) error
+	DeleteUserTokens(ctx context.Context, userID int) error
+}
+
+// TokenVerifier verifies self-contained bearer tokens, such as JWTs, that are not kept in
+// a TokenStore
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, raw string) (*Token, error)
+}
+
+// tokenPrefixLength is how much of a token is kept in clear in the token column, enough to
+// tell the tokens of a user apart
+const tokenPrefixLength = 8
+
+// IssueToken creates an API token for the user, valid for ttl and limited to the scopes
+func (a *Auth) IssueToken(ctx context.Context, user *User, ttl time.Duration, scopes ...string) (*Token, error) {
+	if a.Tokens == nil {
+		return nil, errors.New("auth: no token store")
+	}
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("cannot generate a token: %w", err)
+	}
+	now := time.Now()
+	token := &Token{
+		UserID:    user.ID,
+		FirstName: user.FirstName,
+		Email:     user.Email,
+		PlainText: base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b),
+		Scopes:    scopes,
+		Expiry:    now.Add(ttl),
+		CreatedAt: now,
+	}
+	if err := a.Tokens.SaveToken(ctx, token, tokenHash(token.PlainText)); err != nil {
+		return nil, fmt.Errorf("cannot save the token: %w", err)
+	}
+	return token, nil
+}
+
+// RevokeToken deletes an API token
+func (a *Auth) RevokeToken(ctx context.Context, plainText string) error {
+	return a.Tokens.DeleteToken(ctx, tokenHash(plainText))
+}
+
+// RevokeUserTokens deletes every API token of a user, e.g. after a password change
+func (a *Auth) RevokeUserTokens(ctx context.Context, userID int) error {
+	return a.Tokens.DeleteUserTokens(ctx, userID)
+}
+
+// VerifyToken returns the token of a bearer value when it is valid and not expired. Values
+// that look like JWTs go to the Verifier when there is one.
+func (a *Auth) VerifyToken(ctx context.Context, raw string) (*Token, error) {
+	if a.Verifier != nil && strings.Count(raw, ".") == 2 {
+		return a.Verifier.VerifyToken(ctx, raw)
+	}
+	if a.Tokens == nil {
+		return nil, ErrTokenNotFound
+	}
+	token, err := a.Tokens.TokenByHash(ctx, tokenHash(raw))
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(token.Expiry) {
+		return nil, errors.New("auth: token expired")
+	}
+	return token, nil
+}
+
+// BearerToken returns the token of the Authorization header, empty when there is none
+func BearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// contextKey is the type of the request context keys of the package
+type contextKey int
+
+const (
+	userKey contextKey = iota
+	tokenKey
+)
+
+// UserFromContext returns the user loaded by AuthenticateToken, nil when there is none
+func UserFromContext(ctx context.Context) *User {
+	user, _ := ctx.Value(userKey).(*User)
+	return user
+}
+
+// TokenFromContext returns the token checked by AuthenticateToken, nil when there is none
+func TokenFromContext(ctx context.Context) *Token {
+	token, _ := ctx.Value(tokenKey).(*Token)
+	return token
+}
+
+// AuthenticateToken lets through the requests bearing a valid API token of an active user,
+// loading the user and the token into the request context. Other requests get 401.
+func (a *Auth) AuthenticateToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw := BearerToken(r)
+		if raw == "" {
+			unauthorized(w, "missing bearer token")
+			return
+		}
+		token, err := a.VerifyToken(r.Context(), raw)
+		if err != nil {
+			unauthorized(w, "invalid or expired token")
+			return
+		}
+		user, err := a.Users.UserByID(r.Context(), token.UserID)
+		if err != nil || !user.Active {
+			unauthorized(w, "invalid or expired token")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userKey, user)
+		ctx = context.WithValue(ctx, tokenKey, token)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireScopes lets through the requests whose token has all the scopes, others get 403.
+// Use it after AuthenticateToken.
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := TokenFromContext(r.Context())
+			if token == nil {
+				unauthorized(w, "missing bearer token")
+				return
+			}
+			for _, scope := range scopes {
+				if !token.Can(scope) {
+					writeError(w, http.StatusForbidden, "the token lacks the "+scope+" scope")
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// unauthorized answers 401 with the challenge of the bearer scheme
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	writeError(w, http.StatusUnauthorized, message)
+}
+
+// writeError writes the JSON error payload of the middleware
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+	}{true, message})
+}
+
+// tokenHash is what the stores keep of an API token
+func tokenHash(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// tokenPrefix is the part of an API token kept in clear
+func tokenPrefix(token string) string {
+	if len(token) > tokenPrefixLength {
+		return token[:tokenPrefixLength]
+	}
+	return token
+}
+
+// joinScopes and splitScopes store the scopes space separated, as OAuth does
+func joinScopes(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+func splitScopes(scopes string) []string {
+	return strings.Fields(scopes)
+}
@@ -0,0 +1,80 @@
+package sauri
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/haskekareem/sauri/validator"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// maxBindMemory is the memory used to parse multipart forms, the rest goes to temp files
+const maxBindMemory = 32 << 20
+
+// BindAndValidate parses the form or JSON body of the request, fills the struct dst points
+// to and validates the input. Without rules the validate tags of the struct are used.
+// When the input is invalid a *validator.ValidationError is returned, ready for WriteJSON
+// or TemplateData.Errors.
+func (s *Sauri) BindAndValidate(r *http.Request, dst any, rules map[string][]string) error {
+	if rules == nil {
+		rules = validator.Metadata.Struct(reflect.TypeOf(dst)).RuleSet()
+	}
+
+	v := s.NewValidator(url.Values{}, nil, rules, s.DBConn.SqlConnPool, s.DBConn.PgxConnPool)
+	v.SetContext(r.Context(), 0)
+
+	var bindErr error
+	if isJSONRequest(r) {
+		body, data, err := readJSONBody(r)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(body, dst); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				return fmt.Errorf("invalid JSON body: %w", err)
+			}
+			bindErr = validator.NewValidationError(validator.ErrorContainer{
+				typeErr.Field: []string{fmt.Sprintf("The %s field must be a valid %s", typeErr.Field, typeErr.Type)},
+			})
+		}
+		v.SetJSONData(data)
+	} else {
+		if err := r.ParseMultipartForm(maxBindMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			return fmt.Errorf("cannot parse form: %w", err)
+		}
+		if r.MultipartForm != nil {
+			v.SetMultipartForm(r.MultipartForm)
+		}
+		v.Data = r.Form
+		bindErr = validator.Bind(dst, r.Form)
+	}
+
+	var conversionErr *validator.ValidationError
+	if bindErr != nil && !errors.As(bindErr, &conversionErr) {
+		return bindErr
+	}
+
+	if v.Validate() && conversionErr == nil {
+		return nil
+	}
+
+	// values that could not be converted are reported with the failed rules
+	if conversionErr != nil {
+		for field, messages := range conversionErr.Errors {
+			if _, exists := v.Errors[field]; !exists {
+				v.Errors[field] = messages
+			}
+		}
+	}
+	return validator.NewValidationError(v.Errors)
+}
+
+// isJSONRequest reports whether the request body is JSON
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/json"
+}
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"github.com/dgraph-io/badger/v3"
-	"log"
 	"strings"
 )
 
@@ -60,7 +59,7 @@ func (b *BadgerCache) emptyWithRetries(
 				}
 
 				if deleted == 0 {
-					log.Println("No more keys to delete")
+					logger().Debug("no more keys to delete")
 					return nil // Stop if no more keys are deleted
 				}
 				return nil
@@ -69,7 +68,7 @@ func (b *BadgerCache) emptyWithRetries(
 			if err != nil {
 				if errors.Is(err, badger.ErrConflict) {
 					retries++
-					log.Printf("Transaction conflict occurred. Retrying... (%d/%d)", retries, maxRetries)
+					logger().Warn("transaction conflict, retrying", "retry", retries, "max_retries", maxRetries)
 					continue // Retry the transaction
 				}
 				return fmt.Errorf("failed to empty keys: %w", err) // Return on non-conflict errors
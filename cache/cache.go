@@ -1,9 +1,21 @@
 package cache
 
 import (
+	"log/slog"
 	"time"
 )
 
+// Logger receives the errors of the caches, slog.Default when nil
+var Logger *slog.Logger
+
+// logger returns the logger of the package
+func logger() *slog.Logger {
+	if Logger == nil {
+		return slog.Default()
+	}
+	return Logger
+}
+
 type Cache interface {
 	Exists(keyStr string) (bool, error)
 	Get(keyStr string) (interface{}, error)
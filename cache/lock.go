@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/gomodule/redigo/redis"
+	"time"
+)
+
+// Locker is implemented by caches that can hold distributed locks. A lock belongs to an
+// owner and expires after its ttl unless the owner locks it again to extend it.
+type Locker interface {
+	Lock(keyStr, owner string, ttl time.Duration) (bool, error)
+	Unlock(keyStr, owner string) error
+}
+
+// ============================ redis ============================
+
+// lockScript takes the lock when it is free and extends it when the owner already holds it
+var lockScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0`)
+
+// unlockScript releases the lock only when it is held by the owner
+var unlockScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Lock acquires or extends the lock stored under keyStr for the owner.
+func (rc *RedisCache) Lock(keyStr, owner string, ttl time.Duration) (bool, error) {
+	conn := rc.Conn.Get()
+	defer func(conn redis.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	acquired, err := redis.Int(lockScript.Do(conn, rc.prefixedKey(keyStr), owner, ttl.Milliseconds()))
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	return acquired == 1, nil
+}
+
+// Unlock releases the lock stored under keyStr if the owner holds it.
+func (rc *RedisCache) Unlock(keyStr, owner string) error {
+	conn := rc.Conn.Get()
+	defer func(conn redis.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	if _, err := unlockScript.Do(conn, rc.prefixedKey(keyStr), owner); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// ============================ badger ============================
+
+// Lock acquires or extends the lock stored under keyStr for the owner. Badger is embedded,
+// so the lock only coordinates goroutines and tasks of the process that opened it.
+func (b *BadgerCache) Lock(keyStr, owner string, ttl time.Duration) (bool, error) {
+	prefixedKey := b.prefixedKey(keyStr)
+	acquired := false
+
+	// the owner is stored like any other cache value so that Get and GetAll can read it
+	encoded, err := encodeValue(EntryCache{prefixedKey: owner})
+	if err != nil {
+		return false, err
+	}
+
+	err = b.DBConn.Update(func(txn *badger.Txn) error {
+		holder, err := b.lockHolder(txn, prefixedKey)
+		if err != nil {
+			return err
+		}
+		if holder != "" && holder != owner {
+			return nil
+		}
+
+		acquired = true
+		return txn.SetEntry(badger.NewEntry([]byte(prefixedKey), encoded).WithTTL(ttl))
+	})
+	if errors.Is(err, badger.ErrConflict) {
+		// another caller took the lock at the same time
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	return acquired, nil
+}
+
+// Unlock releases the lock stored under keyStr if the owner holds it.
+func (b *BadgerCache) Unlock(keyStr, owner string) error {
+	prefixedKey := b.prefixedKey(keyStr)
+
+	return b.DBConn.Update(func(txn *badger.Txn) error {
+		holder, err := b.lockHolder(txn, prefixedKey)
+		if err != nil || holder != owner {
+			return err
+		}
+		return txn.Delete([]byte(prefixedKey))
+	})
+}
+
+// lockHolder returns the owner of the lock stored under prefixedKey, empty when it is free.
+func (b *BadgerCache) lockHolder(txn *badger.Txn, prefixedKey string) (string, error) {
+	item, err := txn.Get([]byte(prefixedKey))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	value, err := item.ValueCopy(nil)
+	if err != nil {
+		return "", err
+	}
+	decoded, err := decodeValue(value)
+	if err != nil {
+		return "", err
+	}
+	holder, _ := decoded[prefixedKey].(string)
+	return holder, nil
+}
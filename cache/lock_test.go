@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLocker(t *testing.T, locker Locker) {
+	ok, err := locker.Lock("leader", "instance-a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok, "first owner should get the lock")
+
+	ok, err = locker.Lock("leader", "instance-b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok, "second owner should not get a held lock")
+
+	ok, err = locker.Lock("leader", "instance-a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok, "owner should be able to extend its lock")
+
+	require.NoError(t, locker.Unlock("leader", "instance-b"))
+	ok, err = locker.Lock("leader", "instance-b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok, "unlock by another owner must not release the lock")
+
+	require.NoError(t, locker.Unlock("leader", "instance-a"))
+	ok, err = locker.Lock("leader", "instance-b", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok, "released lock should be free")
+
+	require.NoError(t, locker.Unlock("leader", "instance-b"))
+}
+
+func TestRedisCache_Lock(t *testing.T) {
+	testLocker(t, &testRedisCache)
+}
+
+func TestBadgerCache_Lock(t *testing.T) {
+	testLocker(t, &testBadgerCache)
+}
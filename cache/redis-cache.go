@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"github.com/gomodule/redigo/redis"
-	"log"
 	"time"
 )
 
@@ -53,7 +52,7 @@ func (rc *RedisCache) Set(keyStr string, value interface{}, expires ...time.Dura
 	}
 
 	if err != nil {
-		log.Printf("Error setting cache for key %s: %v", keyStr, err)
+		logger().Error("cannot set the cache key", "key", keyStr, "error", err)
 		return fmt.Errorf("failed to set cache: %w", err)
 	}
 
@@ -75,7 +74,7 @@ func (rc *RedisCache) Get(keyStr string) (interface{}, error) {
 	if errors.Is(err, redis.ErrNil) {
 		return nil, nil // Cache miss
 	} else if err != nil {
-		log.Printf("Error getting cache for key %s: %v", keyStr, err)
+		logger().Error("cannot get the cache key", "key", keyStr, "error", err)
 		return nil, fmt.Errorf("failed to get cache: %w", err)
 	}
 
@@ -105,7 +104,7 @@ func (rc *RedisCache) Exists(keyStr string) (bool, error) {
 	// check for the existence of a key
 	exists, err := redis.Bool(conn.Do("EXISTS", prefixedKey))
 	if err != nil {
-		log.Printf("Error checking existence of key %s: %v", keyStr, err)
+		logger().Error("cannot check the cache key exists", "key", keyStr, "error", err)
 		return false, fmt.Errorf("failed to check existence: %w", err)
 	}
 	// return true if it exists
@@ -165,7 +164,7 @@ func (rc *RedisCache) Delete(keyStr string) error {
 	// delete something from the cache
 	_, err := conn.Do("DEL", prefixedKey)
 	if err != nil {
-		log.Printf("Error deleting cache for key %s: %v", keyStr, err)
+		logger().Error("cannot delete the cache key", "key", keyStr, "error", err)
 		return fmt.Errorf("failed to delete cache: %w", err)
 	}
 
@@ -184,7 +183,7 @@ func (rc *RedisCache) Expire(keyStr string, expiration time.Duration) error {
 	// set expiration time settings
 	_, err := conn.Do("EXPIRE", prefixedKey, int(expiration.Minutes()))
 	if err != nil {
-		log.Printf("Error setting expiration for key %s: %v", keyStr, err)
+		logger().Error("cannot set the cache key expiration", "key", keyStr, "error", err)
 		return fmt.Errorf("failed to set expiration: %w", err)
 	}
 
@@ -203,7 +202,7 @@ func (rc *RedisCache) TTL(keyStr string) (time.Duration, error) {
 	// set expiration time settings
 	ttl, err := redis.Int(conn.Do("TTL", prefixedKey))
 	if err != nil {
-		log.Printf("Error retrieving TTL for key %s: %v", keyStr, err)
+		logger().Error("cannot get the cache key TTL", "key", keyStr, "error", err)
 		return 0, fmt.Errorf("failed to retrieve TTL: %w", err)
 	}
 
@@ -0,0 +1,81 @@
+package sauri
+
+import (
+	"github.com/haskekareem/sauri/chaos"
+	"time"
+)
+
+// chaosSettings holds the fault injection settings read from the .env file
+type chaosSettings struct {
+	http             chaos.Config
+	cacheFailureRate float64
+	dbFailureRate    float64
+	latency          time.Duration
+}
+
+// chaosFromEnv reads the CHAOS_* variables. Fault injection is only ever enabled in
+// debug mode unless CHAOS_ALLOW_PRODUCTION is explicitly set to true.
+func (s *Sauri) chaosFromEnv() (chaosSettings, bool) {
+	enabled := s.Config.GetBool("CHAOS_ENABLED", false)
+	allowProduction := s.Config.GetBool("CHAOS_ALLOW_PRODUCTION", false)
+	if !enabled || (!s.IsDebugMode() && !allowProduction) {
+		return chaosSettings{}, false
+	}
+
+	latency := s.Config.GetDuration("CHAOS_LATENCY", time.Millisecond, 0)
+	errorStatus := s.Config.GetInt("CHAOS_ERROR_STATUS", 0)
+	routes := s.Config.GetStrings("CHAOS_ROUTES")
+
+	return chaosSettings{
+		http: chaos.Config{
+			Routes:      routes,
+			Latency:     latency,
+			LatencyRate: s.chaosRate("CHAOS_LATENCY_RATE"),
+			ErrorRate:   s.chaosRate("CHAOS_ERROR_RATE"),
+			ErrorStatus: errorStatus,
+			DropRate:    s.chaosRate("CHAOS_DROP_RATE"),
+		},
+		cacheFailureRate: s.chaosRate("CHAOS_CACHE_FAILURE_RATE"),
+		dbFailureRate:    s.chaosRate("CHAOS_DB_FAILURE_RATE"),
+		latency:          latency,
+	}, true
+}
+
+// chaosRate reads a probability between 0 and 1 from the configuration
+func (s *Sauri) chaosRate(key string) float64 {
+	rate := s.Config.GetFloat(key, 0)
+	if rate < 0 {
+		return 0
+	}
+	if rate > 1 {
+		return 1
+	}
+	return rate
+}
+
+// enableChaos wraps the cache and the database/sql pool with the fault injecting wrappers
+func (s *Sauri) enableChaos() {
+	settings, ok := s.chaosFromEnv()
+	if !ok {
+		return
+	}
+	s.InfoLog.Println("CHAOS_ENABLED is set, faults will be injected")
+
+	if s.Cache != nil && settings.cacheFailureRate > 0 {
+		s.Cache = &chaos.Cache{
+			Cache:       s.Cache,
+			FailureRate: settings.cacheFailureRate,
+			Latency:     settings.latency,
+		}
+	}
+
+	if s.DBConn.SqlConnPool != nil && settings.dbFailureRate > 0 {
+		// the wrapper opens its own connections, the pool it replaces is closed
+		pool := s.DBConn.SqlConnPool
+		s.DBConn.SqlConnPool = chaos.OpenDB(pool, s.config.dBConfig.dsn, settings.dbFailureRate, settings.latency)
+		s.dbPoolFromConfig().apply(s.DBConn.SqlConnPool)
+		if err := pool.Close(); err != nil {
+			s.ErrorLog.Println("cannot close the database pool replaced by chaos:", err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package chaos
+
+import (
+	"context"
+	"github.com/haskekareem/sauri/cache"
+	"time"
+)
+
+// Cache wraps a cache.Cache and makes a share of the calls fail or slow down
+type Cache struct {
+	Cache       cache.Cache
+	FailureRate float64
+	Latency     time.Duration
+}
+
+// WithContext returns the cache with the context passed on to the wrapped cache when it
+// takes one, e.g. a tracing.Cache
+func (c *Cache) WithContext(ctx context.Context) cache.Cache {
+	inner, ok := c.Cache.(interface {
+		WithContext(context.Context) cache.Cache
+	})
+	if !ok {
+		return c
+	}
+	wrapped := *c
+	wrapped.Cache = inner.WithContext(ctx)
+	return &wrapped
+}
+
+// inject sleeps and fails according to the configuration
+func (c *Cache) inject() error {
+	if c.Latency > 0 {
+		time.Sleep(c.Latency)
+	}
+	if hit(c.FailureRate) {
+		return ErrInjected
+	}
+	return nil
+}
+
+func (c *Cache) Exists(keyStr string) (bool, error) {
+	if err := c.inject(); err != nil {
+		return false, err
+	}
+	return c.Cache.Exists(keyStr)
+}
+
+func (c *Cache) Get(keyStr string) (interface{}, error) {
+	if err := c.inject(); err != nil {
+		return nil, err
+	}
+	return c.Cache.Get(keyStr)
+}
+
+func (c *Cache) Set(keyStr string, value interface{}, expires ...time.Duration) error {
+	if err := c.inject(); err != nil {
+		return err
+	}
+	return c.Cache.Set(keyStr, value, expires...)
+}
+
+func (c *Cache) Delete(keyStr string) error {
+	if err := c.inject(); err != nil {
+		return err
+	}
+	return c.Cache.Delete(keyStr)
+}
+
+func (c *Cache) EmptyByMatch(keyStr string) error {
+	if err := c.inject(); err != nil {
+		return err
+	}
+	return c.Cache.EmptyByMatch(keyStr)
+}
+
+func (c *Cache) Empty() error {
+	if err := c.inject(); err != nil {
+		return err
+	}
+	return c.Cache.Empty()
+}
+
+func (c *Cache) Keys(patternOrKey ...string) ([]string, error) {
+	if err := c.inject(); err != nil {
+		return nil, err
+	}
+	return c.Cache.Keys(patternOrKey...)
+}
+
+func (c *Cache) Expire(keyStr string, expiration time.Duration) error {
+	if err := c.inject(); err != nil {
+		return err
+	}
+	return c.Cache.Expire(keyStr, expiration)
+}
+
+func (c *Cache) TTL(keyStr string) (time.Duration, error) {
+	if err := c.inject(); err != nil {
+		return 0, err
+	}
+	return c.Cache.TTL(keyStr)
+}
+
+func (c *Cache) Update(keyStr string, value interface{}, expires ...time.Duration) error {
+	if err := c.inject(); err != nil {
+		return err
+	}
+	return c.Cache.Update(keyStr, value, expires...)
+}
+
+func (c *Cache) KeysWithBatchSize(batchSize int, patternOrKey ...string) ([]string, error) {
+	if err := c.inject(); err != nil {
+		return nil, err
+	}
+	return c.Cache.KeysWithBatchSize(batchSize, patternOrKey...)
+}
@@ -0,0 +1,90 @@
+package chaos
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"path"
+	"time"
+)
+
+// ErrInjected is returned by every failure injected by the chaos wrappers
+var ErrInjected = errors.New("chaos: injected failure")
+
+// Config describes which faults to inject and how often. Rates are probabilities
+// between 0 and 1.
+type Config struct {
+	Routes      []string      // path patterns (path.Match syntax) to target, empty targets every route
+	Latency     time.Duration // delay added to matched requests
+	LatencyRate float64       // share of matched requests that are delayed
+	ErrorRate   float64       // share of matched requests answered with ErrorStatus
+	ErrorStatus int           // status used for injected errors, defaults to 500
+	DropRate    float64       // share of matched requests whose connection is dropped
+}
+
+// hit reports whether a fault with the given rate should be injected
+func hit(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
+
+// matches reports whether the path is targeted by the config
+func (c Config) matches(urlPath string) bool {
+	if len(c.Routes) == 0 {
+		return true
+	}
+	for _, pattern := range c.Routes {
+		if ok, _ := path.Match(pattern, urlPath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware injects latency, errors and dropped connections on matched routes
+func Middleware(cfg Config) func(http.Handler) http.Handler {
+	if cfg.ErrorStatus == 0 {
+		cfg.ErrorStatus = http.StatusInternalServerError
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !cfg.matches(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if hit(cfg.LatencyRate) {
+				select {
+				case <-time.After(cfg.Latency):
+				case <-r.Context().Done():
+					return
+				}
+			}
+
+			if hit(cfg.DropRate) {
+				dropConnection(w)
+				return
+			}
+
+			if hit(cfg.ErrorRate) {
+				w.Header().Set("X-Chaos-Injected", "error")
+				http.Error(w, http.StatusText(cfg.ErrorStatus), cfg.ErrorStatus)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// dropConnection closes the underlying connection without writing a response
+func dropConnection(w http.ResponseWriter) {
+	if hj, ok := w.(http.Hijacker); ok {
+		if conn, _, err := hj.Hijack(); err == nil {
+			_ = conn.Close()
+			return
+		}
+	}
+	// the server aborts the response silently for this panic value
+	panic(http.ErrAbortHandler)
+}
@@ -0,0 +1,151 @@
+package chaos
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+)
+
+// Connector wraps a database/sql driver so that a share of the queries fail or slow down
+type Connector struct {
+	Base        driver.Driver
+	DSN         string
+	FailureRate float64
+	Latency     time.Duration
+}
+
+// OpenDB opens a *sql.DB using the same driver and dsn as a regular pool but with
+// faults injected into connections, queries and transactions
+func OpenDB(db *sql.DB, dsn string, failureRate float64, latency time.Duration) *sql.DB {
+	return sql.OpenDB(&Connector{
+		Base:        db.Driver(),
+		DSN:         dsn,
+		FailureRate: failureRate,
+		Latency:     latency,
+	})
+}
+
+// inject sleeps and fails according to the configuration
+func (c *Connector) inject(ctx context.Context) error {
+	if c.Latency > 0 {
+		select {
+		case <-time.After(c.Latency):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if hit(c.FailureRate) {
+		return ErrInjected
+	}
+	return nil
+}
+
+// Connect implements driver.Connector
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := c.inject(ctx); err != nil {
+		return nil, err
+	}
+	conn, err := c.Base.Open(c.DSN)
+	if err != nil {
+		return nil, err
+	}
+	return &chaosConn{conn: conn, connector: c}, nil
+}
+
+// Driver implements driver.Connector
+func (c *Connector) Driver() driver.Driver {
+	return c.Base
+}
+
+// chaosConn forwards to the wrapped connection after injecting faults
+type chaosConn struct {
+	conn      driver.Conn
+	connector *Connector
+}
+
+func (cc *chaosConn) Prepare(query string) (driver.Stmt, error) {
+	return cc.PrepareContext(context.Background(), query)
+}
+
+func (cc *chaosConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := cc.connector.inject(ctx); err != nil {
+		return nil, err
+	}
+	if p, ok := cc.conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return cc.conn.Prepare(query)
+}
+
+func (cc *chaosConn) Close() error {
+	return cc.conn.Close()
+}
+
+func (cc *chaosConn) Begin() (driver.Tx, error) {
+	return cc.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (cc *chaosConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := cc.connector.inject(ctx); err != nil {
+		return nil, err
+	}
+	if b, ok := cc.conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	// fallback for drivers without BeginTx
+	return cc.conn.Begin()
+}
+
+func (cc *chaosConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := cc.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := cc.connector.inject(ctx); err != nil {
+		return nil, err
+	}
+	return e.ExecContext(ctx, query, args)
+}
+
+func (cc *chaosConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := cc.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := cc.connector.inject(ctx); err != nil {
+		return nil, err
+	}
+	return q.QueryContext(ctx, query, args)
+}
+
+func (cc *chaosConn) Ping(ctx context.Context) error {
+	if err := cc.connector.inject(ctx); err != nil {
+		return err
+	}
+	if p, ok := cc.conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (cc *chaosConn) ResetSession(ctx context.Context) error {
+	if r, ok := cc.conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (cc *chaosConn) IsValid() bool {
+	if v, ok := cc.conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (cc *chaosConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if c, ok := cc.conn.(driver.NamedValueChecker); ok {
+		return c.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
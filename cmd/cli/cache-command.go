@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/gomodule/redigo/redis"
+	"github.com/haskekareem/sauri/cache"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// cacheCommands are the commands working on the cache of the CACHE setting
+func cacheCommands() []*command {
+	clear := newCommand("cache:clear", "[pattern]", "delete every entry of the cache, or the ones of a pattern such as user:*")
+	clear.app = true
+	clear.run = func(args []string) error {
+		if len(args) > 1 {
+			return usageErrorf("cache:clear takes a single pattern")
+		}
+		return withCache(func(c cache.Cache) error {
+			return doCacheClear(c, args)
+		})
+	}
+
+	forget := newCommand("cache:forget", "<key...>", "delete entries of the cache")
+	forget.app = true
+	forget.run = func(args []string) error {
+		if len(args) == 0 {
+			return usageErrorf("must give the key to forget")
+		}
+		return withCache(func(c cache.Cache) error {
+			return doCacheForget(c, args)
+		})
+	}
+
+	keys := newCommand("cache:keys", "[pattern]", "list the keys of the cache, or the ones of a pattern such as user:*")
+	keys.app = true
+	keys.run = func(args []string) error {
+		if len(args) > 1 {
+			return usageErrorf("cache:keys takes a single pattern")
+		}
+		return withCache(func(c cache.Cache) error {
+			return doCacheKeys(c, args)
+		})
+	}
+
+	stats := newCommand("cache:stats", "", "show the number of keys and the memory or disk used by the cache")
+	stats.app = true
+	stats.run = func(args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("cache:stats takes no arguments")
+		}
+		return withCache(doCacheStats)
+	}
+
+	return []*command{clear, forget, keys, stats}
+}
+
+// withCache opens the cache of the application, with the settings of its .env file, for the
+// time of fn
+func withCache(fn func(c cache.Cache) error) error {
+	c, err := sauri2.OpenCache()
+	if err != nil {
+		if os.Getenv("CACHE") == "badger" {
+			return fmt.Errorf("%w, badger is opened by one process at a time, stop the application first", err)
+		}
+		return err
+	}
+	defer func() {
+		if closer, ok := c.(io.Closer); ok {
+			_ = closer.Close()
+		}
+	}()
+	return fn(c)
+}
+
+// doCacheClear deletes the entries of the prefix of the application, or of a pattern
+func doCacheClear(c cache.Cache, args []string) error {
+	if len(args) == 1 {
+		if err := c.EmptyByMatch(args[0]); err != nil {
+			return err
+		}
+		color.Green("the entries matching %s were deleted", args[0])
+		return nil
+	}
+	if err := c.Empty(); err != nil {
+		return err
+	}
+	color.Green("the cache was cleared")
+	return nil
+}
+
+// doCacheForget deletes keys, the ones missing are reported
+func doCacheForget(c cache.Cache, keys []string) error {
+	for _, key := range keys {
+		exists, err := c.Exists(key)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			color.Yellow("%s is not in the cache", key)
+			continue
+		}
+		if err := c.Delete(key); err != nil {
+			return err
+		}
+		color.Green("%s was deleted", key)
+	}
+	return nil
+}
+
+// doCacheKeys prints the keys without the prefix of the application, as the application
+// sees them
+func doCacheKeys(c cache.Cache, args []string) error {
+	keys, err := c.Keys(args...)
+	if err != nil {
+		return err
+	}
+	prefix := os.Getenv("REDIS_PREFIX") + ":"
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, prefix)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Println(key)
+	}
+	color.Yellow("%d keys", len(keys))
+	return nil
+}
+
+// doCacheStats prints the number of keys of the application and what the store reports
+func doCacheStats(c cache.Cache) error {
+	keys, err := c.Keys()
+	if err != nil {
+		return err
+	}
+	color.Yellow("Cache: %s", os.Getenv("CACHE"))
+	color.White("    prefix:      %s", os.Getenv("REDIS_PREFIX"))
+	color.White("    keys:        %d", len(keys))
+
+	switch store := c.(type) {
+	case *cache.RedisCache:
+		return redisStats(store)
+	case *cache.BadgerCache:
+		lsm, vlog, err := store.Size()
+		if err != nil {
+			return err
+		}
+		color.White("    lsm size:    %s", formatBytes(lsm))
+		color.White("    vlog size:   %s", formatBytes(vlog))
+	}
+	return nil
+}
+
+// redisStats prints the server wide statistics of redis
+func redisStats(store *cache.RedisCache) error {
+	conn := store.Conn.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	total, err := redis.Int(conn.Do("DBSIZE"))
+	if err != nil {
+		return err
+	}
+	info, err := redis.String(conn.Do("INFO"))
+	if err != nil {
+		return err
+	}
+	values := parseRedisInfo(info)
+
+	color.White("    total keys:  %d (all the applications of the database)", total)
+	color.White("    version:     %s", values["redis_version"])
+	color.White("    memory used: %s", values["used_memory_human"])
+	color.White("    hits:        %s", values["keyspace_hits"])
+	color.White("    misses:      %s", values["keyspace_misses"])
+	color.White("    evicted:     %s", values["evicted_keys"])
+	return nil
+}
+
+// parseRedisInfo returns the fields of the reply of INFO
+func parseRedisInfo(info string) map[string]string {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// formatBytes returns a size in bytes in the largest unit it fills
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
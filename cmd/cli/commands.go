@@ -0,0 +1,279 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/fatih/color"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// the exit codes of the cli
+const (
+	exitOK    = 0
+	exitError = 1
+	// exitUsage is for a wrong command line, an unknown command, flag or argument
+	exitUsage = 2
+)
+
+// command is a command of the cli, e.g. migrate, or a subcommand of one, e.g. make model
+type command struct {
+	name    string
+	aliases []string
+	// usage is the arguments following the name, e.g. "<name> [column:type...]"
+	usage   string
+	summary string
+	// app commands run in the folder of an application, see setUp
+	app bool
+	// flags are declared by the command and parsed wherever they are in the arguments
+	flags *flag.FlagSet
+	// run gets the arguments left once the flags are parsed
+	run         func(args []string) error
+	subcommands []*command
+}
+
+// newCommand returns a command with an empty flag set
+func newCommand(name, usage, summary string) *command {
+	cmd := &command{name: name, usage: usage, summary: summary}
+	cmd.flags = flag.NewFlagSet(name, flag.ContinueOnError)
+	cmd.flags.SetOutput(io.Discard)
+	return cmd
+}
+
+// usageError is an error of the command line rather than of the command, the usage of the
+// command is printed with it
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// usageErrorf returns a usageError
+func usageErrorf(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// execute runs the command of the arguments, os.Args[1:], and returns the exit code
+func execute(commands []*command, args []string) int {
+	if len(args) == 0 {
+		printCommands(os.Stderr, "sauri", commands)
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "help", "-h", "--help", "-help":
+		return doHelp(commands, args[1:])
+	}
+
+	cmd, path, args, err := findCommand(commands, args)
+	if err != nil {
+		printError(err)
+		return exitUsage
+	}
+
+	positional, err := parseFlags(cmd.flags, args)
+	if errors.Is(err, flag.ErrHelp) {
+		printUsage(os.Stdout, path, cmd)
+		return exitOK
+	}
+	if err != nil {
+		printError(err)
+		printUsage(os.Stderr, path, cmd)
+		return exitUsage
+	}
+
+	if cmd.app {
+		setUp(cmd.name)
+	}
+	if err := cmd.run(positional); err != nil {
+		printError(err)
+		var usageErr *usageError
+		if errors.As(err, &usageErr) {
+			printUsage(os.Stderr, path, cmd)
+			return exitUsage
+		}
+		return exitError
+	}
+	return exitOK
+}
+
+// findCommand returns the command of the arguments, going down the subcommands, with its
+// path, e.g. "sauri make model", and the arguments left
+func findCommand(commands []*command, args []string) (*command, string, []string, error) {
+	path := "sauri"
+	for {
+		cmd := lookupCommand(commands, args[0])
+		if cmd == nil {
+			return nil, path, nil, unknownCommand(path, args[0], commands)
+		}
+		path += " " + cmd.name
+		args = args[1:]
+		if len(cmd.subcommands) == 0 {
+			return cmd, path, args, nil
+		}
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+			printCommands(os.Stderr, path, cmd.subcommands)
+			return nil, path, nil, usageErrorf("%s needs a subcommand", path)
+		}
+		commands = cmd.subcommands
+	}
+}
+
+// lookupCommand returns the command of a name or alias, nil when there is none
+func lookupCommand(commands []*command, name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+		for _, alias := range cmd.aliases {
+			if alias == name {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// unknownCommand returns the error of a command that does not exist, with the commands of a
+// close name
+func unknownCommand(path, name string, commands []*command) error {
+	var close []string
+	for _, cmd := range commands {
+		if strings.HasPrefix(cmd.name, name) || editDistance(cmd.name, name) <= 2 {
+			close = append(close, cmd.name)
+		}
+	}
+	if len(close) == 0 {
+		return usageErrorf("unknown command %q, see `%s help`", strings.TrimPrefix(path+" "+name, "sauri "), path)
+	}
+	return usageErrorf("unknown command %q, did you mean %s?", strings.TrimPrefix(path+" "+name, "sauri "), strings.Join(close, " or "))
+}
+
+// editDistance returns the Levenshtein distance of two names
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// parseFlags parses the flags wherever they are among the arguments, flag.Parse stops at
+// the first argument that is not a flag, and returns the other arguments. The arguments
+// after -- are never flags.
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := flags.Args()
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// doHelp prints the commands, or the usage of the command of args
+func doHelp(commands []*command, args []string) int {
+	if len(args) == 0 {
+		printCommands(os.Stdout, "sauri", commands)
+		return exitOK
+	}
+	cmd, path, _, err := findCommand(commands, args)
+	if err != nil {
+		printError(err)
+		return exitUsage
+	}
+	printUsage(os.Stdout, path, cmd)
+	return exitOK
+}
+
+// printCommands prints a list of commands with their summaries
+func printCommands(w io.Writer, path string, commands []*command) {
+	_, _ = fmt.Fprintf(w, "Usage: %s <command> [arguments] [flags]\n\nCommands:\n", path)
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.summary)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintf(w, "\nRun `%s help <command>` or `%s <command> --help` for the usage of a command.\n",
+		strings.Fields(path)[0], path)
+}
+
+// printUsage prints the usage of a command, its subcommands and its flags
+func printUsage(w io.Writer, path string, cmd *command) {
+	if len(cmd.subcommands) > 0 {
+		_, _ = fmt.Fprintf(w, "%s\n\n", cmd.summary)
+		printCommands(w, path, cmd.subcommands)
+		return
+	}
+
+	line := path
+	if cmd.usage != "" {
+		line += " " + cmd.usage
+	}
+	hasFlags := false
+	cmd.flags.VisitAll(func(*flag.Flag) {
+		hasFlags = true
+	})
+	if hasFlags {
+		line += " [flags]"
+	}
+	_, _ = fmt.Fprintf(w, "Usage: %s\n\n%s\n", line, cmd.summary)
+	if len(cmd.aliases) > 0 {
+		_, _ = fmt.Fprintf(w, "\nAliases: %s\n", strings.Join(cmd.aliases, ", "))
+	}
+	if !hasFlags {
+		return
+	}
+
+	_, _ = fmt.Fprintln(w, "\nFlags:")
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	var names []string
+	cmd.flags.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	sort.Strings(names)
+	for _, name := range names {
+		f := cmd.flags.Lookup(name)
+		valueName, usage := flag.UnquoteUsage(f)
+		flagLine := "--" + f.Name
+		if valueName != "" {
+			flagLine += "=" + valueName
+		}
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+			usage += fmt.Sprintf(" (default %s)", f.DefValue)
+		}
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\n", flagLine, usage)
+	}
+	_ = tw.Flush()
+}
+
+// printError prints an error of a command on stderr
+func printError(err error) {
+	_, _ = color.New(color.FgRed).Fprintf(os.Stderr, "Error: %v\n", err)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/haskekareem/sauri"
+	"net/http"
+	"os"
+	"time"
+)
+
+// doDBPool reads the live pool stats from the metrics endpoint of the running application
+func doDBPool() error {
+	port := os.Getenv("PORT")
+	if port == "" {
+		return errors.New("PORT is not set in the .env file")
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://localhost:%s/sauri/metrics", port))
+	if err != nil {
+		return fmt.Errorf("could not reach the application, is it running? %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errors.New("metrics endpoint not found, set METRICS_ENABLED=true in the .env file")
+	}
+
+	var payload struct {
+		Database sauri.DBPoolStats `json:"database"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return fmt.Errorf("invalid metrics response: %w", err)
+	}
+
+	color.Yellow("Database type: %s", payload.Database.DatabaseType)
+	for _, pool := range []*sauri.PoolStats{payload.Database.SQL, payload.Database.PGX} {
+		if pool == nil {
+			continue
+		}
+		color.Yellow("\n  %s", pool.Driver)
+		color.White("    open:          %d/%d", pool.Open, pool.MaxOpen)
+		color.White("    in use:        %d", pool.InUse)
+		color.White("    idle:          %d", pool.Idle)
+		color.White("    wait count:    %d", pool.WaitCount)
+		color.White("    wait duration: %s", pool.WaitDuration)
+	}
+
+	if payload.Database.SQL == nil && payload.Database.PGX == nil {
+		color.Yellow("  no database connection is open")
+	}
+
+	return nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fatih/color"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// deployDatabase is the database service of docker-compose.yml
+type deployDatabase struct {
+	Image       string
+	Port        string
+	Env         [][2]string
+	DataDir     string
+	Healthcheck string
+}
+
+// deployData fills the deploy templates
+type deployData struct {
+	Name          string // APP_NAME made fit for an image, a user and a binary
+	GoVersion     string
+	Port          string
+	Dirs          []string // folders the binary reads at runtime
+	Database      *deployDatabase
+	Redis         bool
+	RedisPassword bool
+	Worker        bool // the jobs are on redis, so a worker process can run them
+	StopTimeout   int  // seconds, SHUTDOWN_TIMEOUT with a margin
+}
+
+// deployUnit fills the systemd unit template
+type deployUnit struct {
+	deployData
+	Unit        string
+	Description string
+	Dir         string
+	Args        []string
+	After       []string
+}
+
+// deployFile is a file of make deploy and the data of its template
+type deployFile struct {
+	template, target string
+	data             any
+}
+
+// unsafeNameChars are the characters of APP_NAME replaced in the deploy names
+var unsafeNameChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// makeDeployCommand creates the files to run the application with docker or systemd
+func makeDeployCommand() *command {
+	cmd := newCommand("deploy", "", "create a Dockerfile, a docker-compose.yml with the database and redis of .env, and systemd units")
+	cmd.app = true
+	force := cmd.flags.Bool("force", false, "overwrite the files that exist already")
+	cmd.run = func(args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("make deploy takes no arguments")
+		}
+		return doDeploy(*force)
+	}
+	return cmd
+}
+
+// doDeploy writes the deploy files from the settings of .env. The secrets are not written,
+// docker compose reads them from .env and systemd runs the application next to its .env.
+func doDeploy(force bool) error {
+	data, err := deployDataFromEnv()
+	if err != nil {
+		return err
+	}
+
+	files := []deployFile{
+		{"templates/deploy/Dockerfile.txt", "Dockerfile", data},
+		{"templates/deploy/dockerignore.txt", ".dockerignore", data},
+		{"templates/deploy/docker-compose.yml.txt", "docker-compose.yml", data},
+		{"templates/deploy/service.txt", filepath.Join("deploy", data.Name+".service"), deployUnitOf(data, false)},
+	}
+	if data.Worker {
+		files = append(files, deployFile{"templates/deploy/service.txt", filepath.Join("deploy", data.Name+"-worker.service"), deployUnitOf(data, true)})
+	}
+
+	if err := os.MkdirAll(filepath.Join(sauri2.RootPath, "deploy"), 0755); err != nil {
+		return err
+	}
+	for _, file := range files {
+		target := filepath.Join(sauri2.RootPath, file.target)
+		if fileExists(target) && !force {
+			color.Yellow("   -%s exists already, kept (--force overwrites it)", file.target)
+			continue
+		}
+		content, err := renderDeployTemplate(file.template, file.data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.target, err)
+		}
+		if err := copyDataToFile(content, target); err != nil {
+			return err
+		}
+		color.Yellow("   -%s created", file.target)
+	}
+
+	if !data.Worker {
+		color.Yellow("   -no worker service, the jobs run in the application unless JOBS_QUEUE=redis")
+	}
+	return nil
+}
+
+// deployDataFromEnv reads the settings of the deploy files from .env and go.mod
+func deployDataFromEnv() (deployData, error) {
+	name := unsafeNameChars.ReplaceAllString(strings.ToLower(os.Getenv("APP_NAME")), "-")
+	name = strings.Trim(name, "-")
+	if name == "" {
+		name = strings.ToLower(filepath.Base(sauri2.RootPath))
+	}
+
+	data := deployData{
+		Name:      name,
+		GoVersion: strings.TrimPrefix(runtime.Version(), "go"),
+		Port:      os.Getenv("PORT"),
+	}
+	if data.Port == "" {
+		data.Port = "4000"
+	}
+	// the application gets SHUTDOWN_TIMEOUT to stop, it is killed a bit later
+	seconds, err := strconv.Atoi(os.Getenv("SHUTDOWN_TIMEOUT"))
+	if err != nil || seconds <= 0 {
+		seconds = 30
+	}
+	data.StopTimeout = seconds + 10
+	if content, err := os.ReadFile(filepath.Join(sauri2.RootPath, "go.mod")); err == nil {
+		if match := goDirective.FindSubmatch(content); match != nil {
+			data.GoVersion = string(match[1])
+		}
+	}
+
+	migrationDir, err := filepath.Rel(sauri2.RootPath, sauri2.MigrationDir())
+	if err != nil {
+		migrationDir = filepath.Join("internal", "migration")
+	}
+	for _, dir := range []string{"config", "public", "resources", migrationDir} {
+		if fileExists(filepath.Join(sauri2.RootPath, dir)) {
+			data.Dirs = append(data.Dirs, filepath.ToSlash(dir))
+		}
+	}
+
+	if os.Getenv("DATABASE_TYPE") != "" {
+		database, err := deployDatabaseOf(os.Getenv("DATABASE_TYPE"))
+		if err != nil {
+			return deployData{}, err
+		}
+		data.Database = database
+	}
+
+	jobsQueue := os.Getenv("JOBS_QUEUE")
+	data.Redis = os.Getenv("CACHE") == "redis" || os.Getenv("SESSION_STORE_TYPE") == "redis" || jobsQueue == "redis"
+	data.RedisPassword = os.Getenv("REDIS_PASSWORD") != ""
+	data.Worker = jobsQueue == "redis" || (jobsQueue == "" && data.Redis)
+	return data, nil
+}
+
+// deployDatabaseOf returns the service of a database type, its credentials are the
+// DATABASE_* settings read by compose from .env
+func deployDatabaseOf(dbType string) (*deployDatabase, error) {
+	switch strings.ToLower(dbType) {
+	case "postgres", "postgresql", "pgx":
+		return &deployDatabase{
+			Image: "postgres:16-alpine",
+			Port:  "5432",
+			Env: [][2]string{
+				{"POSTGRES_USER", "${DATABASE_USER}"},
+				{"POSTGRES_PASSWORD", "${DATABASE_PASS}"},
+				{"POSTGRES_DB", "${DATABASE_NAME}"},
+			},
+			DataDir:     "/var/lib/postgresql/data",
+			Healthcheck: `["CMD-SHELL", "pg_isready -U $${POSTGRES_USER} -d $${POSTGRES_DB}"]`,
+		}, nil
+	case "mysql":
+		return &deployDatabase{
+			Image: "mysql:8.4",
+			Port:  "3306",
+			Env: [][2]string{
+				{"MYSQL_USER", "${DATABASE_USER}"},
+				{"MYSQL_PASSWORD", "${DATABASE_PASS}"},
+				{"MYSQL_DATABASE", "${DATABASE_NAME}"},
+				{"MYSQL_RANDOM_ROOT_PASSWORD", `"yes"`},
+			},
+			DataDir:     "/var/lib/mysql",
+			Healthcheck: `["CMD", "mysqladmin", "ping", "-h", "localhost"]`,
+		}, nil
+	case "mariadb":
+		return &deployDatabase{
+			Image: "mariadb:11",
+			Port:  "3306",
+			Env: [][2]string{
+				{"MARIADB_USER", "${DATABASE_USER}"},
+				{"MARIADB_PASSWORD", "${DATABASE_PASS}"},
+				{"MARIADB_DATABASE", "${DATABASE_NAME}"},
+				{"MARIADB_RANDOM_ROOT_PASSWORD", `"yes"`},
+			},
+			DataDir:     "/var/lib/mysql",
+			Healthcheck: `["CMD", "healthcheck.sh", "--connect", "--innodb_initialized"]`,
+		}, nil
+	}
+	return nil, fmt.Errorf("no docker service for the %s database, expected postgres, mysql or mariadb", dbType)
+}
+
+// deployUnitOf returns the systemd unit of the application, or of its worker
+func deployUnitOf(data deployData, worker bool) deployUnit {
+	unit := deployUnit{
+		deployData:  data,
+		Unit:        data.Name,
+		Description: data.Name,
+		Dir:         "/opt/" + data.Name,
+	}
+	if worker {
+		unit.Unit += "-worker"
+		unit.Description += " job worker"
+		unit.Args = []string{"queue:work"}
+	}
+	// the services of the same machine, the unit still starts when they run elsewhere
+	if data.Database != nil {
+		switch {
+		case strings.HasPrefix(data.Database.Image, "postgres"):
+			unit.After = append(unit.After, "postgresql.service")
+		case strings.HasPrefix(data.Database.Image, "mysql"):
+			unit.After = append(unit.After, "mysql.service")
+		default:
+			unit.After = append(unit.After, "mariadb.service")
+		}
+	}
+	if data.Redis {
+		unit.After = append(unit.After, "redis.service")
+	}
+	return unit
+}
+
+// renderDeployTemplate executes a deploy template
+func renderDeployTemplate(name string, data any) ([]byte, error) {
+	content, err := templateFS.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(filepath.Base(name)).Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// dialects are the databases the migration templates are written for
+var dialects = []string{"postgres", "mysql", "sqlite"}
+
+// sqlDialect returns the dialect of the migrations of a DATABASE_TYPE value, the aliases of
+// each database are all handled here
+func sqlDialect(dbType string) string {
+	switch strings.ToLower(dbType) {
+	case "postgres", "postgresql", "pgx":
+		return "postgres"
+	case "mysql", "mariadb":
+		return "mysql"
+	case "sqlite", "sqlite3":
+		return "sqlite"
+	}
+	return strings.ToLower(dbType)
+}
+
+// migrationDialect returns the dialect of the application database, an error when no
+// migration templates are written for it
+func migrationDialect() (string, error) {
+	dbType := sauri2.DBConn.DatabaseType
+	if dbType == "" {
+		return "", fmt.Errorf("DATABASE_TYPE is not set, expected one of %s", strings.Join(dialects, ", "))
+	}
+	dialect := sqlDialect(dbType)
+	for _, supported := range dialects {
+		if dialect == supported {
+			return dialect, nil
+		}
+	}
+	return "", fmt.Errorf("no migration templates for the %s database, expected one of %s", dbType, strings.Join(dialects, ", "))
+}
+
+// migrationTemplates returns the up and down templates of a kind of migration, such as
+// auth_table, for a dialect
+func migrationTemplates(kind, dialect string) (string, string, error) {
+	up := "templates/migrations/" + kind + "." + dialect + ".up.sql"
+	down := "templates/migrations/" + kind + "." + dialect + ".down.sql"
+	for _, template := range []string{up, down} {
+		if _, err := fs.Stat(templateFS, template); err != nil {
+			return "", "", fmt.Errorf("no %s migration template for the %s database", kind, dialect)
+		}
+	}
+	return up, down, nil
+}
+
+// migrationFiles returns the up and down files of a new migration
+func migrationFiles(fileName, dialect string) (string, string) {
+	dir := sauri2.MigrationDir()
+	return filepath.Join(dir, fileName+"."+dialect+".up.sql"), filepath.Join(dir, fileName+"."+dialect+".down.sql")
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/haskekareem/sauri/config"
+	"github.com/haskekareem/sauri/renderer"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// finding statuses, a failure makes doctor exit with 1
+const (
+	statusOK = iota
+	statusWarning
+	statusFailure
+)
+
+// finding is what a check of doctor found, with how to fix it when it is not ok
+type finding struct {
+	status  int
+	message string
+	fix     string
+}
+
+// passed returns the finding of a check that went well
+func passed(format string, args ...any) finding {
+	return finding{status: statusOK, message: fmt.Sprintf(format, args...)}
+}
+
+// warning returns the finding of something that works but should be fixed
+func warning(fix, format string, args ...any) finding {
+	return finding{status: statusWarning, message: fmt.Sprintf(format, args...), fix: fix}
+}
+
+// failure returns the finding of something that stops the application from working
+func failure(fix, format string, args ...any) finding {
+	return finding{status: statusFailure, message: fmt.Sprintf(format, args...), fix: fix}
+}
+
+// doctorCheck is a check of doctor, in the order they run
+type doctorCheck struct {
+	name string
+	run  func() []finding
+}
+
+// doctorCommand checks that the application can run in the current environment
+func doctorCommand() *command {
+	cmd := newCommand("doctor", "", "check the .env settings, database, cache, storage folders, templates and Go toolchain")
+	cmd.app = true
+	cmd.run = func(args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("doctor takes no arguments")
+		}
+		return doDoctor()
+	}
+	return cmd
+}
+
+// doDoctor runs every check and prints what it found, it fails when one of them failed
+func doDoctor() error {
+	checks := []doctorCheck{
+		{"environment", checkEnvironment},
+		{"go toolchain", checkGoToolchain},
+		{"database", checkDatabase},
+		{"cache", checkCache},
+		{"storage", checkStorage},
+		{"templates", checkTemplates},
+	}
+
+	failures, warnings := 0, 0
+	for _, check := range checks {
+		color.White(check.name)
+		for _, f := range check.run() {
+			switch f.status {
+			case statusOK:
+				color.Green("  [ok]   %s", f.message)
+			case statusWarning:
+				warnings++
+				color.Yellow("  [warn] %s", f.message)
+			default:
+				failures++
+				color.Red("  [fail] %s", f.message)
+			}
+			if f.fix != "" {
+				fmt.Printf("         fix: %s\n", f.fix)
+			}
+		}
+	}
+
+	fmt.Println()
+	if failures > 0 {
+		return fmt.Errorf("doctor found %d problems and %d warnings", failures, warnings)
+	}
+	if warnings > 0 {
+		color.Yellow("no problems found, %d warnings", warnings)
+		return nil
+	}
+	color.Green("no problems found")
+	return nil
+}
+
+// checkEnvironment checks the .env file and the settings the enabled features need
+func checkEnvironment() []finding {
+	var findings []finding
+	if fileExists(filepath.Join(sauri2.RootPath, ".env")) {
+		findings = append(findings, passed(".env found"))
+	} else if os.Getenv("APP_NAME") == "" {
+		return []finding{failure("run sauri doctor in the root of the application, or create its .env file",
+			"no .env file in %s", sauri2.RootPath)}
+	} else {
+		findings = append(findings, passed("no .env file, the settings come from the environment"))
+	}
+
+	err := sauri2.ValidateConfig()
+	var missing *config.MissingError
+	switch {
+	case errors.As(err, &missing):
+		findings = append(findings, failure("set them in .env", "missing settings: %s", strings.Join(missing.Keys, ", ")))
+	case err != nil:
+		findings = append(findings, failure("fix the setting in .env", "%v", err))
+	default:
+		findings = append(findings, passed("the settings of the enabled features are set"))
+	}
+
+	if os.Getenv("APP_NAME") == "" {
+		findings = append(findings, warning("set APP_NAME in .env", "APP_NAME is not set"))
+	}
+	if os.Getenv("KEY") == "" {
+		findings = append(findings, failure("run sauri key:generate", "KEY is not set, cookies and sessions cannot be encrypted"))
+	}
+	// older .env files name it DEBUG, which the framework does not read
+	if _, ok := sauri2.Config.Lookup("DEBUG_MODE"); !ok && os.Getenv("DEBUG") != "" {
+		findings = append(findings, warning("rename DEBUG to DEBUG_MODE in .env", "DEBUG is set but debug mode is read from DEBUG_MODE"))
+	}
+	if os.Getenv("APP_ENV") == "production" && sauri2.Config.GetBool("DEBUG_MODE", false) {
+		findings = append(findings, warning("set DEBUG_MODE=false", "debug mode is on in production, errors show their details"))
+	}
+	return findings
+}
+
+// goDirective matches the go line of go.mod
+var goDirective = regexp.MustCompile(`(?m)^go\s+(\S+)`)
+
+// checkGoToolchain checks that go is installed and as recent as the go.mod of the
+// application requires
+func checkGoToolchain() []finding {
+	// a go.mod asking for a newer go would make go switch toolchains, the installed one is
+	// the one to check
+	cmd := exec.Command("go", "env", "GOVERSION")
+	cmd.Env = append(os.Environ(), "GOTOOLCHAIN=local")
+	out, err := cmd.Output()
+	if err != nil {
+		return []finding{failure("install Go from https://go.dev/dl", "go not found: %v", err)}
+	}
+	installed := strings.TrimPrefix(strings.TrimSpace(string(out)), "go")
+
+	content, err := os.ReadFile(filepath.Join(sauri2.RootPath, "go.mod"))
+	if err != nil {
+		return []finding{
+			passed("go %s", installed),
+			warning("run sauri doctor in the root of the application", "no go.mod found"),
+		}
+	}
+	match := goDirective.FindSubmatch(content)
+	if match == nil {
+		return []finding{passed("go %s, go.mod does not require a version", installed)}
+	}
+	required := string(match[1])
+	if compareVersions(installed, required) < 0 {
+		return []finding{failure("install Go "+required+" or newer from https://go.dev/dl",
+			"go %s is older than the go %s go.mod requires", installed, required)}
+	}
+	return []finding{passed("go %s, go.mod requires %s", installed, required)}
+}
+
+// checkDatabase connects to the database of DATABASE_USE and looks for pending migrations
+func checkDatabase() []finding {
+	if !sauri2.Config.GetBool("DATABASE_USE", false) {
+		return []finding{passed("not in use, DATABASE_USE is false")}
+	}
+	dbType := sauri2.Config.Get("DATABASE_TYPE")
+	dsn, err := sauri2.BuildDSN()
+	if err != nil {
+		return []finding{failure("check the DATABASE_* settings", "cannot build the connection string: %v", err)}
+	}
+
+	// the drivers can take long to give up on an unreachable host
+	type result struct{ err error }
+	done := make(chan result, 1)
+	go func() {
+		sqlDB, pgxPool, err := sauri2.OpenDBConnectionPool(dbType, dsn)
+		if err == nil {
+			_ = sqlDB.Close()
+			if pgxPool != nil {
+				pgxPool.Close()
+			}
+		}
+		done <- result{err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return []finding{failure("check that the database is running and the DATABASE_* settings",
+				"cannot connect to %s at %s:%s: %v", dbType, os.Getenv("DATABASE_HOST"), os.Getenv("DATABASE_PORT"), r.err)}
+		}
+	case <-time.After(15 * time.Second):
+		return []finding{failure("check that the database is running and reachable from here",
+			"no answer from %s at %s:%s after 15s", dbType, os.Getenv("DATABASE_HOST"), os.Getenv("DATABASE_PORT"))}
+	}
+	findings := []finding{passed("connected to %s at %s:%s", dbType, os.Getenv("DATABASE_HOST"), os.Getenv("DATABASE_PORT"))}
+
+	migrationDSN, err := getDSN()
+	if err != nil {
+		return findings
+	}
+	migrations, _, dirty, err := sauri2.MigrationStatus(migrationDSN)
+	if err != nil {
+		return append(findings, warning("run sauri migrate status", "cannot read the migrations: %v", err))
+	}
+	if dirty {
+		return append(findings, failure("fix the database by hand, then clear the dirty flag of schema_migrations",
+			"the last migration failed halfway"))
+	}
+	pending := 0
+	for _, migration := range migrations {
+		if !migration.Applied {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return append(findings, warning("run sauri migrate", "%d pending migrations", pending))
+	}
+	return append(findings, passed("%d migrations, all applied", len(migrations)))
+}
+
+// checkCache opens the cache of CACHE and reads from it
+func checkCache() []finding {
+	store := sauri2.Config.Get("CACHE")
+	if store == "" {
+		return []finding{passed("not in use, CACHE is empty")}
+	}
+	if store != "redis" && store != "badger" {
+		return []finding{failure("set CACHE to redis or badger", "unknown cache %q", store)}
+	}
+
+	c, err := sauri2.OpenCache()
+	if err == nil {
+		_, err = c.Exists("sauri:doctor")
+		if closer, ok := c.(io.Closer); ok {
+			_ = closer.Close()
+		}
+	}
+	if err != nil {
+		fix := "check that redis runs at REDIS_HOST and that REDIS_PASSWORD is right"
+		if store == "badger" {
+			fix = "stop the application, badger is opened by one process at a time"
+		}
+		return []finding{failure(fix, "cannot use the %s cache: %v", store, err)}
+	}
+	if store == "redis" {
+		return []finding{passed("redis answers at %s", os.Getenv("REDIS_HOST"))}
+	}
+	return []finding{passed("badger opens in storage/badger")}
+}
+
+// storageDirs are the folders the application writes to, relative to its root
+var storageDirs = []string{"storage/framework", "storage/logs", "storage/uploads", "tmp"}
+
+// checkStorage checks that the folders the application writes to are writable
+func checkStorage() []finding {
+	dirs := storageDirs
+	if disk := sauri2.Config.Get("STORAGE_DISK"); disk == "" || disk == "local" {
+		dirs = append([]string{sauri2.Config.Get("STORAGE_ROOT", "storage/app")}, dirs...)
+	}
+
+	var findings []finding
+	for _, dir := range dirs {
+		path := dir
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(sauri2.RootPath, dir)
+		}
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			findings = append(findings, warning("the application creates it when it starts, or mkdir -p "+dir, "%s does not exist", dir))
+			continue
+		}
+		if err != nil {
+			findings = append(findings, failure("check the permissions of its parent folders", "cannot read %s: %v", dir, err))
+			continue
+		}
+		if !info.IsDir() {
+			findings = append(findings, failure("remove the file or move it away", "%s is a file, not a folder", dir))
+			continue
+		}
+		file, err := os.CreateTemp(path, ".doctor-*")
+		if err != nil {
+			findings = append(findings, failure("give the user running the application write access, e.g. chmod u+w "+dir,
+				"%s is not writable", dir))
+			continue
+		}
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		findings = append(findings, passed("%s is writable", dir))
+	}
+	return findings
+}
+
+// checkTemplates compiles the go templates of resources/views, the pages with the layouts
+// as the renderer does, and the jet templates
+func checkTemplates() []finding {
+	viewsDir := filepath.Join(sauri2.RootPath, "resources", "views")
+	if !fileExists(viewsDir) {
+		return []finding{passed("no resources/views folder")}
+	}
+
+	var findings []finding
+	pages, _ := filepath.Glob(filepath.Join(viewsDir, "pages", "*.gohtml"))
+	if len(pages) > 0 {
+		r := &renderer.Renderer{TemplatesRootPath: filepath.Join(sauri2.RootPath, "resources")}
+		// the routes are not registered outside of the application
+		r.AddCustomFuncs(template.FuncMap{"route": func(string, ...interface{}) string { return "" }})
+		if err := r.ParseTemplates(); err != nil {
+			findings = append(findings, failure("fix the template, the page fails to render until then", "%v", err))
+		} else {
+			findings = append(findings, passed("%d go pages compile", len(pages)))
+		}
+	}
+
+	var jetFiles []string
+	err := filepath.WalkDir(viewsDir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() && filepath.Ext(path) == ".jet" {
+			rel, _ := filepath.Rel(viewsDir, path)
+			jetFiles = append(jetFiles, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if err != nil {
+		return append(findings, failure("check the permissions of resources/views", "%v", err))
+	}
+	if len(jetFiles) > 0 {
+		views, err := sauri2.InitializeJetSet(viewsDir, "")
+		if err != nil {
+			return append(findings, failure("check resources/views", "%v", err))
+		}
+		views.AddGlobal("route", func(string, ...interface{}) string { return "" })
+		broken := 0
+		for _, name := range jetFiles {
+			if _, err := views.GetTemplate(name); err != nil {
+				broken++
+				findings = append(findings, failure("fix the template, the page fails to render until then", "%s: %v", name, err))
+			}
+		}
+		if broken == 0 {
+			findings = append(findings, passed("%d jet templates compile", len(jetFiles)))
+		}
+	}
+
+	if len(findings) == 0 {
+		findings = append(findings, passed("no templates in resources/views"))
+	}
+	return findings
+}
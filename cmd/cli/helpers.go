@@ -1,146 +1,68 @@
 package main
 
 import (
-	"fmt"
+	"errors"
 	"github.com/fatih/color"
 	_ "github.com/go-sql-driver/mysql"
-	"io"
+	"github.com/haskekareem/sauri/config"
 	"path/filepath"
 	"strings"
 
 	"os"
 )
 
-func setUp(arg2 string) {
-	// do not need a .env file when running the new, help and version cmd so don't initialize the .env
-	if arg2 != "new" && arg2 != "help" && arg2 != "version" {
-		path, err := os.Getwd()
-		if err != nil {
-			exitGracefully(err)
-		}
-		sauri2.RootPath = path
-
-		pathToSearch := filepath.Join(sauri2.RootPath, ".env")
-
-		// 	load .env file
-		err = sauri2.LoadAndSetEnv(pathToSearch)
-		if err != nil {
-			exitGracefully(err)
-		}
-
-		sauri2.DBConn.DatabaseType = os.Getenv("DATABASE_TYPE")
+// setUp loads the .env files and the config of the application in the working directory,
+// for the commands that run in one
+func setUp(name string) {
+	path, err := os.Getwd()
+	if err != nil {
+		exitGracefully(err)
 	}
+	sauri2.RootPath = path
 
-}
-
-func getDSN() (string, error) {
-	dbType := sauri2.DBConn.DatabaseType
-
-	// dsn holds the connection string
-	var dsn string
-
-	// Retrieve environment variables
-	host := os.Getenv("DATABASE_HOST")
-	port := os.Getenv("DATABASE_PORT")
-	user := os.Getenv("DATABASE_USER")
-	password := os.Getenv("DATABASE_PASS")
-	dbname := os.Getenv("DATABASE_NAME")
-	sslMode := os.Getenv("DATABASE_SSL_MODE")
-
-	// convert my default jackc driver to the package used by the migrate package
-	if dbType == "pgx" {
-		dbType = "postgres"
+	// env:decrypt creates the .env file
+	if name == "env:decrypt" {
+		return
 	}
 
-	// check database type and build a connection string
-	switch dbType {
-	case "postgresql", "postgres":
-		// Use default ssl mode if not set
-		if sslMode == "" {
-			sslMode = "disable"
-		}
-
-		// with password configuration
-		if password != "" {
-			dsn = fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s", user, password, host, port, dbname, sslMode)
-		} else {
-			// without password configuration
-			dsn = fmt.Sprintf("postgres://%s@%s:%s/%s?sslmode=%s", user, host, port, dbname, sslMode)
-
-		}
-	case "mysql", "mariadb":
-		// Use default ssl mode if not set
-		if sslMode == "" {
-			sslMode = "false"
-		}
-		// Build MySQL DSN
-		dsn = fmt.Sprintf("mysql://%s:%s@/%s?parseTime=True&loc=Local", user, password, dbname)
+	// 	load the .env files
+	err = sauri2.LoadAndSetEnv(config.EnvFiles(sauri2.RootPath)...)
+	if err != nil {
+		exitGracefully(err)
+	}
 
-	default:
-		// Unsupported database type
-		return "", fmt.Errorf("unsupported database type: %s", dbType)
+	// the config/*.yaml files fill in the settings the environment leaves out
+	sauri2.Config = config.New()
+	err = sauri2.Config.LoadYAML(filepath.Join(sauri2.RootPath, "config"))
+	if err != nil {
+		exitGracefully(err)
 	}
 
-	return dsn, nil
+	sauri2.DBConn.DatabaseType = os.Getenv("DATABASE_TYPE")
 }
 
-func showHelp() {
-	color.Yellow(`Available commands:
-
-	help                      -show the help command
-	version                   -show the version command
-	migrate                   -run all up migration that have not been previously run
-	migrate down              -reverse the most recently run migration
-	migrate down all          -remove all migration previously run
-	migrate reset             -run all down migration in reverse order then run run all up migration
-	make migration <name>     -create two files, one for up migration and the other for down migration
-	make controllers <name>   -create a stub controller in the controllers folder
-	make models <name>        -create a new model in the data folder
-	make auth 				  -create and run migration for authentication tables, models and middlewares
-	make controllers          -create a stub controllers in the controllers folder
-	make models				  -create a new models in the data folder
-	make session              -create a table in the database to be used as a session store
-
-`)
+// getDSN returns the url of the database for the migrations, built the same way as the
+// application does with MIGRATE_ON_START
+func getDSN() (string, error) {
+	if sqlDialect(sauri2.DBConn.DatabaseType) == "sqlite" {
+		return "", errors.New("migrate does not run sqlite migrations yet, apply the files of internal/migration with the sqlite3 tool")
+	}
+	return sauri2.MigrationDSN()
 }
 
-// exitGracefully Helper function to handle errors gracefully
+// exitGracefully Helper function to handle errors gracefully, the cli exits with 1 on an error
+// and 0 otherwise
 func exitGracefully(err error, msg ...string) {
-	message := ""
-	if len(msg) > 0 {
-		message = msg[0]
-	}
 	if err != nil {
-		color.Blue("Error: %v\n", err)
+		printError(err)
+		os.Exit(exitError)
 	}
-	if len(message) > 0 {
-		color.Yellow(message)
+	if len(msg) > 0 && len(msg[0]) > 0 {
+		color.Yellow(msg[0])
 	} else {
 		color.Green("finished!")
 	}
-	os.Exit(0)
-}
-
-// copyFile Helper function to copy files
-func copyFile(sourcePath, destPath string) error {
-	source, err := os.Open(sourcePath)
-	if err != nil {
-		return err
-	}
-	defer func(source *os.File) {
-		_ = source.Close()
-	}(source)
-
-	dest, err := os.Create(destPath)
-	if err != nil {
-		return err
-	}
-	defer func(dest *os.File) {
-		_ = dest.Close()
-	}(dest)
-
-	_, err = io.Copy(dest, source)
-	return err
+	os.Exit(exitOK)
 }
 
 // walkFuncUpdateSourceFiles scans through files in a directory tree, looks for Go source files (*.go), and replaces
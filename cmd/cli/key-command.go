@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/haskekareem/sauri"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// keyGenerateCommand writes a new KEY to .env
+func keyGenerateCommand() *command {
+	cmd := newCommand("key:generate", "", "write a new KEY to .env, keeping the old one for decryption")
+	cmd.app = true
+	replace := cmd.flags.Bool("replace", false, "drop the current key instead of keeping it for decryption")
+	show := cmd.flags.Bool("show", false, "print a key without changing the .env file")
+	cmd.run = func(args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("key:generate takes no arguments")
+		}
+		return doKeyGenerate(*replace, *show)
+	}
+	return cmd
+}
+
+// envEncryptCommand encrypts .env to .env.encrypted
+func envEncryptCommand() *command {
+	cmd := newCommand("env:encrypt", "", "encrypt .env to "+sauri.EncryptedEnvFile+" with "+sauri.EnvKeyVariable+" or --key")
+	cmd.app = true
+	key := cmd.flags.String("key", "", "key of the encrypted file, "+sauri.EnvKeyVariable+" or else generated when empty")
+	cmd.run = func(args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("env:encrypt takes no arguments")
+		}
+		// the .env files may set the key, they are loaded once the flags are parsed
+		if *key == "" {
+			*key = os.Getenv(sauri.EnvKeyVariable)
+		}
+		return doEnvEncrypt(*key)
+	}
+	return cmd
+}
+
+// envDecryptCommand decrypts .env.encrypted to .env
+func envDecryptCommand() *command {
+	cmd := newCommand("env:decrypt", "", "decrypt "+sauri.EncryptedEnvFile+" to .env with "+sauri.EnvKeyVariable+" or --key")
+	cmd.app = true
+	key := cmd.flags.String("key", "", "key of the encrypted file, "+sauri.EnvKeyVariable+" when empty")
+	force := cmd.flags.Bool("force", false, "overwrite an existing .env file")
+	cmd.run = func(args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("env:decrypt takes no arguments")
+		}
+		if *key == "" {
+			*key = os.Getenv(sauri.EnvKeyVariable)
+		}
+		return doEnvDecrypt(*key, *force)
+	}
+	return cmd
+}
+
+// doKeyGenerate writes a new encryption key to the KEY variable of the .env file. The
+// current key moves to PREVIOUS_KEYS and KEY_VERSION increases so that the data it
+// encrypted can still be decrypted, unless --replace is given.
+func doKeyGenerate(replace, show bool) error {
+	key := sauri2.GenerateRandomString(32)
+	if show {
+		color.White(key)
+		return nil
+	}
+
+	envPath := filepath.Join(sauri2.RootPath, ".env")
+	content, err := os.ReadFile(envPath)
+	if err != nil {
+		return err
+	}
+	env := string(content)
+
+	current := envValue(env, "KEY")
+	if current != "" && !replace {
+		version := 1
+		if v, err := strconv.Atoi(envValue(env, "KEY_VERSION")); err == nil && v > 0 {
+			version = v
+		}
+		if version == 255 {
+			return errors.New("KEY_VERSION cannot go past 255, use --replace once the old keys are unused")
+		}
+		previous := envValue(env, "PREVIOUS_KEYS")
+		if previous != "" {
+			previous += ","
+		}
+		env = setEnvValue(env, "PREVIOUS_KEYS", previous+fmt.Sprintf("%d:%s", version, current))
+		env = setEnvValue(env, "KEY_VERSION", strconv.Itoa(version+1))
+	}
+	env = setEnvValue(env, "KEY", key)
+
+	if err := os.WriteFile(envPath, []byte(env), 0600); err != nil {
+		return err
+	}
+	if current != "" && !replace {
+		color.Yellow("The previous key was kept in PREVIOUS_KEYS, remove it once the data it encrypted is encrypted again")
+	}
+	color.Green("A new KEY was written to .env")
+	return nil
+}
+
+// doEnvEncrypt writes the .env file encrypted to .env.encrypted, with the key of --key or
+// SAURI_ENV_KEY, or a new one that is printed
+func doEnvEncrypt(key string) error {
+	generated := key == ""
+	if generated {
+		key = sauri2.GenerateRandomString(32)
+	}
+	content, err := os.ReadFile(filepath.Join(sauri2.RootPath, ".env"))
+	if err != nil {
+		return err
+	}
+	encrypted, err := sauri.EncryptEnv(content, key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(sauri2.RootPath, sauri.EncryptedEnvFile), encrypted, 0644); err != nil {
+		return err
+	}
+
+	color.Green("The environment was encrypted to %s", sauri.EncryptedEnvFile)
+	if generated {
+		color.Yellow("Set %s on the servers to this key, it is not stored anywhere:", sauri.EnvKeyVariable)
+		color.White(key)
+	}
+	return nil
+}
+
+// doEnvDecrypt writes .env.encrypted back to the .env file, which is only overwritten
+// with --force
+func doEnvDecrypt(key string, force bool) error {
+	envPath := filepath.Join(sauri2.RootPath, ".env")
+	if fileExists(envPath) && !force {
+		return errors.New(".env already exists, use --force to overwrite it")
+	}
+	encrypted, err := os.ReadFile(filepath.Join(sauri2.RootPath, sauri.EncryptedEnvFile))
+	if err != nil {
+		return err
+	}
+	content, err := sauri.DecryptEnv(encrypted, key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(envPath, content, 0600); err != nil {
+		return err
+	}
+	color.Green("%s was decrypted to .env", sauri.EncryptedEnvFile)
+	return nil
+}
+
+// envLine matches the line of a variable in an env file
+func envLine(name string) *regexp.Regexp {
+	return regexp.MustCompile(`(?m)^[ \t]*` + regexp.QuoteMeta(name) + `[ \t]*=.*$`)
+}
+
+// envValue returns the value of a variable in the content of an env file
+func envValue(env, name string) string {
+	line := envLine(name).FindString(env)
+	if line == "" {
+		return ""
+	}
+	_, value, _ := strings.Cut(line, "=")
+	return strings.TrimSpace(value)
+}
+
+// setEnvValue sets a variable in the content of an env file, appending it when missing
+func setEnvValue(env, name, value string) string {
+	line := name + "=" + value
+	re := envLine(name)
+	if re.MatchString(env) {
+		return re.ReplaceAllLiteralString(env, line)
+	}
+	if env != "" && !strings.HasSuffix(env, "\n") {
+		env += "\n"
+	}
+	return env + line + "\n"
+}
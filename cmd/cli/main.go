@@ -1,7 +1,6 @@
 package main
 
 import (
-	"errors"
 	"github.com/fatih/color"
 	"github.com/haskekareem/sauri"
 	"os"
@@ -11,72 +10,62 @@ const version = "1.0.0"
 
 var sauri2 sauri.Sauri
 
-// Main entry point for the command line tool
+// Main entry point for the command line tool, the exit code is 0 on success, 1 when the
+// command failed and 2 when the command line is wrong
 func main() {
-	var message string
+	os.Exit(execute(commands(), os.Args[1:]))
+}
 
-	// arg 1 = ./sauri: load the command line arguments
-	arg2, arg3, arg4, err := validateInputs()
-	if err != nil {
-		exitGracefully(errors.New("you must select any of the above commands"))
+// commands returns the commands of the cli, in the order of the help
+func commands() []*command {
+	commands := []*command{
+		versionCommand(),
+		upgradeCommand(),
+		newAppCommand(),
+		serveCommand(),
+		migrateCommand(),
+		seedCommand(),
+		tinkerCommand(),
+		doctorCommand(),
+		taskCommand(),
+		makeCommand(),
 	}
+	commands = append(commands, cacheCommands()...)
+	commands = append(commands, queueCommands()...)
+	return append(commands,
+		appCommand("db:pool", "show the live database connection pool stats of the running app", doDBPool),
+		appCommand("routes", "list the routes of the running app (debug mode only)", doRoutes),
+		appCommand("schedule:list", "list the scheduled tasks of the running app (debug mode only)", doScheduleList),
+		keyGenerateCommand(),
+		envEncryptCommand(),
+		envDecryptCommand(),
+		downCommand(),
+		appCommand("up", "take the app out of maintenance mode", doUp),
+	)
+}
 
-	// load setup
-	setUp(arg2)
-
-	switch arg2 {
-	case "help":
-		showHelp()
-	case "new":
-		if arg3 == "" {
-			exitGracefully(errors.New("new require an application name"))
+// versionCommand prints the version of the cli
+func versionCommand() *command {
+	cmd := newCommand("version", "", "show the version of the cli")
+	cmd.run = func(args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("version takes no arguments")
 		}
-		doNew(arg3)
-	case "version":
 		color.Yellow("Application version: " + version)
-	case "make":
-		if arg3 == "" {
-			exitGracefully(errors.New("make required a subcommand: (migration|handlers)"))
-		}
-		err = doMake(arg3, arg4)
-		if err != nil {
-			exitGracefully(err)
-		}
-	case "migrate":
-		//push the migration files to the database
-		// migrate up as the default setting
-		if arg3 == "" {
-			arg3 = "up"
-		}
-		err = doMigrate(arg3, arg4)
-		if err != nil {
-			exitGracefully(err)
-		}
-		message = "migrations complete!"
-	default:
-		showHelp()
+		return nil
 	}
-	exitGracefully(nil, message)
+	return cmd
 }
 
-func validateInputs() (string, string, string, error) {
-	var arg2, arg3, arg4 string
-
-	if len(os.Args) > 1 {
-		arg2 = os.Args[1]
-
-		if len(os.Args) >= 3 {
-			arg3 = os.Args[2]
+// appCommand returns a command of the application without arguments nor flags
+func appCommand(name, summary string, run func() error) *command {
+	cmd := newCommand(name, "", summary)
+	cmd.app = true
+	cmd.run = func(args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("%s takes no arguments", name)
 		}
-		if len(os.Args) >= 4 {
-			arg4 = os.Args[3]
-		}
-	} else {
-		// first argument in the command line
-		color.Red("Error: command required")
-		showHelp()
-		return "", "", "", errors.New("command required")
-
+		return run()
 	}
-	return arg2, arg3, arg4, nil
+	return cmd
 }
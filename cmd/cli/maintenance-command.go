@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/fatih/color"
+	"github.com/haskekareem/sauri"
+	"strings"
+)
+
+// downCommand puts the application in maintenance mode, e.g.
+// sauri down --message="Back at 10" --retry=600 --allow=10.0.0.0/8 --secret=let-me-in
+func downCommand() *command {
+	cmd := newCommand("down", "", "put the app in maintenance mode")
+	cmd.app = true
+	message := cmd.flags.String("message", "", "message shown on the maintenance page")
+	retry := cmd.flags.Int("retry", 0, "seconds sent in the Retry-After header")
+	allow := cmd.flags.String("allow", "", "comma separated IPs or CIDR ranges let through")
+	secret := cmd.flags.String("secret", "", "path that lets a browser through, generated when empty")
+	cmd.run = func(args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("down takes no arguments")
+		}
+		return doDown(*message, *retry, *allow, *secret)
+	}
+	return cmd
+}
+
+// doDown puts the application in maintenance mode
+func doDown(message string, retry int, allow, secret string) error {
+	if secret == "" {
+		b := make([]byte, 12)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		secret = hex.EncodeToString(b)
+	}
+
+	var allowed []string
+	for _, ip := range strings.Split(allow, ",") {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			allowed = append(allowed, ip)
+		}
+	}
+
+	err := sauri.Down(sauri2.RootPath, sauri.MaintenanceMode{
+		Message: message,
+		Retry:   retry,
+		Secret:  secret,
+		Allow:   allowed,
+	})
+	if err != nil {
+		return err
+	}
+
+	color.Yellow("The application is down for maintenance")
+	color.White("Bypass it by visiting /%s", secret)
+	return nil
+}
+
+// doUp takes the application out of maintenance mode
+func doUp() error {
+	if err := sauri.Up(sauri2.RootPath); err != nil {
+		return err
+	}
+	color.Green("The application is up")
+	return nil
+}
@@ -1,36 +1,112 @@
 package main
 
-// doMake build the make command
-func doMake(arg3, arg4 string) error {
-	switch arg3 {
-	case "migration":
-		err := doMigration(arg4)
-		if err != nil {
-			exitGracefully(err)
+import (
+	"flag"
+	"strings"
+)
+
+// makeCommand build the make command, a generator for each subcommand
+func makeCommand() *command {
+	cmd := newCommand("make", "", "generate the migrations, models, controllers and other files of the app")
+	cmd.subcommands = []*command{
+		makeMigrationCommand(),
+		makeAuthCommand(),
+		makeNameCommand("controller", "create a stub controller in the controllers folder", doControllers, "controllers"),
+		makeModelCommand("model", "create a model with its fields, CRUD methods and migration", doModels, "models"),
+		makeModelCommand("resource", "create a model, its migration, a RESTful controller, its routes and views", doResource),
+		makeNameCommand("seeder", "create a seeder in the seeder folder", doSeeder),
+		makeCommandOf("session", "create a table in the database to be used as a session store", doSessionTable),
+		makeNameCommand("middleware", "create a middleware in the middleware folder", doMiddleware),
+		makeNameCommand("request", "create a form request with its validation rules in the request folder", doRequest),
+		makeNameCommand("job", "create a background job in the job folder", doJob),
+		makeNameCommand("mail", "create a mail in the mail folder and its templates in resources/mails", doMail),
+		makeDeployCommand(),
+	}
+	return cmd
+}
+
+// makeMigrationCommand creates two files, one for up migration and the other for down
+// migration, or the migrations of a new table with --create
+func makeMigrationCommand() *command {
+	cmd := newCommand("migration", "<name> [column:type[:unique|:index|:nullable]...]",
+		"create two files, one for up migration and the other for down migration")
+	cmd.app = true
+	table := cmd.flags.String("create", "", "`table` the migrations create with the columns")
+	cmd.run = func(args []string) error {
+		if len(args) == 0 && *table == "" {
+			return usageErrorf("must give the migration a name")
 		}
+		return doMigration(args, strings.ToLower(*table))
+	}
+	return cmd
+}
 
-	case "auth":
-		err := doAuth()
-		if err != nil {
-			exitGracefully(err)
+// makeAuthCommand creates the authentication tables, models and middlewares, or the token
+// controllers of an API with --api
+func makeAuthCommand() *command {
+	cmd := newCommand("auth", "", "create and run migration for authentication tables, models and middlewares, safe to run again")
+	cmd.app = true
+	api := cmd.flags.Bool("api", false, "create the token login, logout and user controllers and their routes instead of the web middleware")
+	cmd.run = func(args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("make auth takes no arguments")
 		}
+		return doAuth(*api)
+	}
+	return cmd
+}
 
-	case "controller":
-		err := doControllers(arg4)
-		if err != nil {
-			exitGracefully(err)
+// makeCommandOf returns a make subcommand without arguments
+func makeCommandOf(name, summary string, run func() error) *command {
+	cmd := newCommand(name, "", summary)
+	cmd.app = true
+	cmd.run = func(args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("make %s takes no arguments", name)
 		}
-	case "model":
-		err := doModels(arg4)
-		if err != nil {
-			exitGracefully(err)
+		return run()
+	}
+	return cmd
+}
+
+// makeNameCommand returns a make subcommand taking the name of the file to generate
+func makeNameCommand(name, summary string, run func(string) error, aliases ...string) *command {
+	cmd := newCommand(name, "<name>", summary)
+	cmd.aliases = aliases
+	cmd.app = true
+	cmd.run = func(args []string) error {
+		switch len(args) {
+		case 0:
+			return usageErrorf("must give the %s a name", name)
+		case 1:
+			return run(args[0])
 		}
-	case "session":
-		err := doSessionTable()
-		if err != nil {
-			exitGracefully(err)
+		return usageErrorf("make %s takes a single name", name)
+	}
+	return cmd
+}
+
+// makeModelCommand returns make model or make resource, which take a name, the columns and
+// the options of the table
+func makeModelCommand(name, summary string, run func([]string, tableOptions) error, aliases ...string) *command {
+	cmd := newCommand(name, "<name> [column:type[:unique|:index|:nullable]...]", summary)
+	cmd.aliases = aliases
+	cmd.app = true
+	options := addTableFlags(cmd.flags)
+	cmd.run = func(args []string) error {
+		if len(args) == 0 {
+			return usageErrorf("must give the %s a name", name)
 		}
+		return run(args, *options)
 	}
+	return cmd
+}
 
-	return nil
+// addTableFlags declares the options of a table on a flag set
+func addTableFlags(flags *flag.FlagSet) *tableOptions {
+	options := &tableOptions{}
+	flags.BoolVar(&options.timestamps, "timestamps", false, "add the created_at and updated_at columns")
+	flags.BoolVar(&options.softDeletes, "soft-deletes", false, "add the deleted_at column, deleting only sets it")
+	flags.BoolVar(&options.uuidPK, "uuid-pk", false, "use a uuid id set by the application instead of an auto-incremented one")
+	return options
 }
@@ -1,100 +1,241 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"github.com/fatih/color"
 	"github.com/gertd/go-pluralize"
+	"github.com/golang-migrate/migrate/v4"
+	"go/format"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 )
 
-// doAuth build the subcommand of authentication for make command
-func doAuth() error {
-	// make migration
-	dbType := sauri2.DBConn.DatabaseType
-	fileName := fmt.Sprintf("%d_create_auth_table", time.Now().UnixMicro())
-
-	targetUpFilePath := filepath.Join(sauri2.RootPath, "internal", "migration", fileName+"."+dbType+".up.sql")
-	targetDownFilePath := filepath.Join(sauri2.RootPath, "internal", "migration", fileName+"."+dbType+".down.sql")
-
-	// templates for the migration (existing contents embed to be copied to the target folders
-	tempPathUp := "templates/migrations/auth_table." + dbType + ".up.sql"
-	tempPathDown := "drop table if exists users cascade; drop table if exists tokens cascade; drop table if exists remember_tokens;"
-
-	err := copyFilesFromTemplate(tempPathUp, targetUpFilePath)
+// doAuth build the subcommand of authentication for make command. It can be run again: the
+// auth migration is only created once, the files already there are kept and the models are
+// only registered once. With api the token controllers and their routes replace the web
+// middleware.
+func doAuth(api bool) error {
+	dialect, err := migrationDialect()
 	if err != nil {
-		exitGracefully(err)
+		return err
 	}
 
-	err = copyDataToFile([]byte(tempPathDown), targetDownFilePath)
+	existing, err := filepath.Glob(filepath.Join(sauri2.MigrationDir(), "*_create_auth_table."+dialect+".up.sql"))
 	if err != nil {
-		exitGracefully(err)
+		return err
+	}
+	if len(existing) > 0 {
+		color.Yellow("   -the auth migration %s exists already", filepath.Base(existing[0]))
+	} else {
+		fileName := fmt.Sprintf("%d_create_auth_table", time.Now().UnixMicro())
+		targetUpFilePath, targetDownFilePath := migrationFiles(fileName, dialect)
+
+		// templates for the migration (existing contents embed to be copied to the target folders
+		tempPathUp, tempPathDown, err := migrationTemplates("auth_table", dialect)
+		if err != nil {
+			return err
+		}
+		if err := copyFilesFromTemplate(tempPathUp, targetUpFilePath); err != nil {
+			return err
+		}
+		if err := copyFilesFromTemplate(tempPathDown, targetDownFilePath); err != nil {
+			return err
+		}
+		color.Yellow("   -users, tokens and remember_tokens migration created")
 	}
 
-	//run up migration by adding migrate command directly
-	err = doMigrate("up", "")
-	if err != nil {
-		exitGracefully(err)
+	// run up migration by adding migrate command directly, the auth migration may be applied
+	// already
+	if err := doMigrate("up", "", false); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
 	}
+	color.Yellow("   -migrations up to date")
 
 	targetDir := filepath.Join(sauri2.RootPath, "internal")
-
-	// copy the data models and its methods and also make sure existing files don't overwrite
-	err = copyFilesFromTemplate("templates/data/user.go.txt", filepath.Join(targetDir, "model", "user.go"))
-	if err != nil {
-		exitGracefully(err)
+	files := [][2]string{
+		{"templates/data/user.go.txt", filepath.Join(targetDir, "model", "user.go")},
+		{"templates/data/token.go.txt", filepath.Join(targetDir, "model", "token.go")},
+		{"templates/middleware/auth-token.go.txt", filepath.Join(targetDir, "middleware", "auth-token.go")},
+		// the type the middlewares hang off, make middleware may have written it already
+		{"templates/middleware/middleware.go.txt", filepath.Join(targetDir, "middleware", "middleware.go")},
+	}
+	if api {
+		files = append(files, [2]string{"templates/controllers/auth-api.go.txt", filepath.Join(targetDir, "controller", "auth-api.go")})
+	} else {
+		files = append(files, [2]string{"templates/middleware/auth-web.go.txt", filepath.Join(targetDir, "middleware", "auth-web.go")})
+	}
+	for _, file := range files {
+		rel, _ := filepath.Rel(sauri2.RootPath, file[1])
+		if fileExists(file[1]) {
+			color.Yellow("   -%s exists already, kept", rel)
+			continue
+		}
+		if err := copyFilesFromTemplate(file[0], file[1]); err != nil {
+			return err
+		}
+		color.Yellow("   -%s created", rel)
 	}
 
-	err = copyFilesFromTemplate("templates/data/token.go.txt", filepath.Join(targetDir, "model", "token.go"))
-	if err != nil {
-		exitGracefully(err)
+	if err := registerAuthModels(filepath.Join(targetDir, "model", "models.go")); err != nil {
+		return err
 	}
 
-	//copy the  middleware
-	err = copyFilesFromTemplate("templates/middleware/auth-web.go.txt", filepath.Join(targetDir, "middleware", "auth-web.go"))
-	if err != nil {
-		exitGracefully(err)
+	if api {
+		added, err := addAuthRoutes()
+		if err != nil {
+			return err
+		}
+		if !added {
+			color.Red(" -add the API routes to your routes:")
+			fmt.Print(authAPIRoutes)
+		}
+		return nil
 	}
+	color.Yellow("")
+	color.Yellow(" -add the AuthWeb or AuthToken middleware to the routes to protect")
+	return nil
+}
+
+// authModels are the fields make auth registers in the Models struct
+var authModels = [][2]string{{"Users", "User"}, {"Tokens", "Token"}}
 
-	err = copyFilesFromTemplate("templates/middleware/auth-token.go.txt", filepath.Join(targetDir, "middleware", "auth-token.go"))
+// registerAuthModels adds the user and token models to the Models struct of models.go,
+// writing the file first when the application has none
+func registerAuthModels(file string) error {
+	if !fileExists(file) {
+		if err := copyFilesFromTemplate("templates/data/models.go.txt", file); err != nil {
+			return err
+		}
+	}
+	content, err := os.ReadFile(file)
 	if err != nil {
-		exitGracefully(err)
+		return err
 	}
 
-	//display message feedback to end users
-	color.Yellow("   -users, tokens and remember_tokens migration created and executed")
-	color.Yellow("   -user and token models created!!")
-	color.Yellow("   -auth middleware created!!")
-	color.Yellow("")
-	color.Red(" -dont forget to add user and token models in internal/model/models.go " +
-		"and add appropriate middleware to your routes")
+	start := bytes.Index(content, []byte("type Models struct {"))
+	if start < 0 {
+		color.Red(" -no Models struct in internal/model/models.go, add the User and Token models to yours")
+		return nil
+	}
+	end := bytes.Index(content[start:], []byte("\n}"))
+	if end < 0 {
+		return errors.New("internal/model/models.go: the Models struct is not closed")
+	}
+	end += start + 1
+
+	var fields strings.Builder
+	for _, model := range authModels {
+		field := regexp.MustCompile(`(?m)^\s*` + model[0] + `\s+` + model[1] + `\b`)
+		if field.Match(content[start:end]) {
+			continue
+		}
+		fields.WriteString("\t" + model[0] + " " + model[1] + "\n")
+	}
+	if fields.Len() == 0 {
+		color.Yellow("   -the user and token models are registered already")
+		return nil
+	}
 
+	updated := append(append(append([]byte{}, content[:end]...), fields.String()...), content[end:]...)
+	if formatted, err := format.Source(updated); err == nil {
+		updated = formatted
+	}
+	if err := copyDataToFile(updated, file); err != nil {
+		return err
+	}
+	color.Yellow("   -user and token models registered in internal/model/models.go")
 	return nil
 }
 
+// authAPIRoutes are the routes of the token controllers of make auth --api, exempt from
+// CSRF as they are authenticated by the Authorization header
+const authAPIRoutes = `	app.API("/api/auth", func(g *sauri.RouteGroup) {
+		g.Post("/login", c.APILogin).Name("api.login")
+		g.Group(func(g *sauri.RouteGroup) {
+			g.Use(app.Auth.AuthenticateToken)
+			g.Post("/logout", c.APILogout).Name("api.logout")
+			g.Get("/user", c.APIUser).Name("api.user")
+		})
+	})
+`
+
+// addAuthRoutes adds the routes of the token controllers unless routes.go has them already
+func addAuthRoutes() (bool, error) {
+	file := filepath.Join(sauri2.RootPath, "internal", "route", "routes.go")
+	if content, err := os.ReadFile(file); err == nil && bytes.Contains(content, []byte("c.APILogin")) {
+		color.Yellow("   -the API routes are registered already")
+		return true, nil
+	}
+	added, err := addRoutes(authAPIRoutes)
+	if added {
+		color.Yellow("   -API routes added to internal/route/routes.go")
+	}
+	return added, err
+}
+
 // doMigration build the subcommand of migration for make command that create two files for up and down
-// migrations
-func doMigration(arg4 string) error {
-	dbType := sauri2.DBConn.DatabaseType
+// migrations. With --create=<table> followed by name:type columns the files create the table,
+// make migration create_users --create=users name:string email:string:unique age:int
+func doMigration(args []string, table string) error {
+	dialect, err := migrationDialect()
+	if err != nil {
+		return err
+	}
+
+	var name string
+	var specs []string
+	for _, arg := range args {
+		switch {
+		case strings.Contains(arg, ":"):
+			specs = append(specs, arg)
+		case name == "":
+			name = arg
+		}
+	}
+	if name == "" && table != "" {
+		name = "create_" + table + "_table"
+	}
+
 	// checking for migration name
-	if arg4 == "" {
+	if name == "" {
 		exitGracefully(errors.New("must give the migration a name"))
 	}
 
-	migrationFileName := fmt.Sprintf("%d_%s", time.Now().UnixMicro(), arg4)
+	migrationFileName := fmt.Sprintf("%d_%s", time.Now().UnixMicro(), name)
 
 	// path the up and down migration folders
-	targetUpFilePath := filepath.Join(sauri2.RootPath, "internal", "migration", migrationFileName+"."+dbType+".up.sql")
-	targetDownFilePath := filepath.Join(sauri2.RootPath, "internal", "migration", migrationFileName+"."+dbType+".down.sql")
+	targetUpFilePath, targetDownFilePath := migrationFiles(migrationFileName, dialect)
+
+	if table != "" {
+		columns, err := parseColumns(specs)
+		if err != nil {
+			return err
+		}
+		up, down, err := createTableSQL(dialect, table, columns, tableOptions{timestamps: true})
+		if err != nil {
+			return err
+		}
+		if err := copyDataToFile([]byte(up), targetUpFilePath); err != nil {
+			return err
+		}
+		return copyDataToFile([]byte(down), targetDownFilePath)
+	}
+	if len(specs) > 0 {
+		return errors.New("columns need --create=<table>")
+	}
 
 	// templates for the migration (existing contents embed to be copied to the target folders
-	tempPathUp := "templates/migrations/migration." + dbType + ".up.sql"
-	tempPathDown := "templates/migrations/migration." + dbType + ".down.sql"
+	tempPathUp, tempPathDown, err := migrationTemplates("migration", dialect)
+	if err != nil {
+		return err
+	}
 
-	err := copyFilesFromTemplate(tempPathUp, targetUpFilePath)
+	err = copyFilesFromTemplate(tempPathUp, targetUpFilePath)
 	if err != nil {
 		exitGracefully(err)
 	}
@@ -153,19 +294,51 @@ func doControllers(arg4 string) error {
 	return nil
 }
 
-// doModels build the subcommand of models for make command
-func doModels(arg4 string) error {
+// doModels build the subcommand of models for make command. The name is followed by the
+// columns, name:type[:unique|:index|:nullable] as for make migration --create, and the
+// --timestamps, --soft-deletes and --uuid-pk options; the migration creating the table is
+// made too. Without any the model only has its id and timestamps.
+func doModels(args []string, options tableOptions) error {
+	name, columns, options, err := parseModelArgs(args, options)
+	if err != nil {
+		return err
+	}
+	_, err = makeModel(name, columns, options)
+	return err
+}
+
+// parseModelArgs returns the name, the columns and the options following make model and
+// make resource
+func parseModelArgs(args []string, options tableOptions) (string, []column, tableOptions, error) {
+	var arg4 string
+	var specs []string
+	for _, arg := range args {
+		switch {
+		case strings.Contains(arg, ":"):
+			specs = append(specs, arg)
+		case arg4 == "":
+			arg4 = arg
+		}
+	}
+
 	// checking for model name
 	if arg4 == "" {
 		exitGracefully(errors.New("must give the model a name"))
 	}
 
-	data, err := templateFS.ReadFile("templates/data/model.go.txt")
+	columns, err := parseColumns(specs)
 	if err != nil {
-		exitGracefully(err)
+		return "", nil, tableOptions{}, err
+	}
+	if len(columns) == 0 && options == (tableOptions{}) {
+		options.timestamps = true
 	}
-	model := string(data)
+	return arg4, columns, options, nil
+}
 
+// makeModel writes the model of a name, singular or plural, and the migration creating its
+// table, and returns the data of the model
+func makeModel(arg4 string, columns []column, options tableOptions) (modelData, error) {
 	plur := pluralize.NewClient()
 
 	var modelName = arg4
@@ -180,7 +353,7 @@ func doModels(arg4 string) error {
 
 	// target file
 	// Convert input to proper CamelCase
-	caseModelName := convertInput(arg4)
+	caseModelName := convertInput(modelName)
 	fileName := modelName + ".go"
 
 	targetFile := filepath.Join(sauri2.RootPath, "internal", "model", strings.ToLower(fileName))
@@ -189,56 +362,270 @@ func doModels(arg4 string) error {
 	}
 
 	// final version of data going to the target file
-	model = strings.ReplaceAll(model, "$MODELNAME$", caseModelName)
-	model = strings.ReplaceAll(model, "$TABLENAME$", tableName)
+	data := newModelData(caseModelName, tableName, columns, options)
+	model, err := renderModel(data)
+	if err != nil {
+		return data, err
+	}
 
 	// copy data to the files
-	err = copyDataToFile([]byte(model), targetFile)
+	err = copyDataToFile(model, targetFile)
 	if err != nil {
 		exitGracefully(err)
 	}
 
+	// the connection the models run their queries on, shared by all of them
+	connFile := filepath.Join(sauri2.RootPath, "internal", "model", "conn.go")
+	if !fileExists(connFile) {
+		conn, err := templateFS.ReadFile("templates/data/conn.go.txt")
+		if err != nil {
+			exitGracefully(err)
+		}
+		if err := copyDataToFile(conn, connFile); err != nil {
+			exitGracefully(err)
+		}
+	}
+
+	// the migration creating the table, it needs to know the database
+	dialect, err := migrationDialect()
+	if err != nil {
+		color.Yellow("   -no migration created: %v", err)
+		return data, nil
+	}
+	up, down, err := createTableSQL(dialect, tableName, columns, options)
+	if err != nil {
+		return data, err
+	}
+	targetUpFilePath, targetDownFilePath := migrationFiles(fmt.Sprintf("%d_create_%s_table", time.Now().UnixMicro(), tableName), dialect)
+	if err := copyDataToFile([]byte(up), targetUpFilePath); err != nil {
+		return data, err
+	}
+	if err := copyDataToFile([]byte(down), targetDownFilePath); err != nil {
+		return data, err
+	}
+	color.Yellow("   -%s model and the migration of the %s table created", caseModelName, tableName)
+
+	return data, nil
+}
+
+// doSeeder build the subcommand of seeders for make command, the seeder still has to be added
+// in internal/seeder/seeder.go
+func doSeeder(arg4 string) error {
+	// checking for seeder name
+	if arg4 == "" {
+		exitGracefully(errors.New("must give the seeder a name"))
+	}
+
+	// users, users_seeder and UsersSeeder all make the users seeder
+	name := strings.Trim(normalizeSeparators(strings.TrimSuffix(strings.ToLower(arg4), "seeder")), "-")
+	if name == "" {
+		exitGracefully(errors.New("must give the seeder a name"))
+	}
+
+	targetFile := filepath.Join(sauri2.RootPath, "internal", "seeder", name+"-seeder.go")
+	if fileExists(targetFile) {
+		exitGracefully(errors.New(targetFile + " file already exists"))
+	}
+
+	data, err := templateFS.ReadFile("templates/seeders/seeder.go.txt")
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	plur := pluralize.NewClient()
+	tableName := strings.ReplaceAll(name, "-", "_")
+	if !plur.IsPlural(tableName) {
+		tableName = plur.Plural(tableName)
+	}
+	seederName := toCamelCase(name) + "Seeder"
+
+	seeder := strings.ReplaceAll(string(data), "$SEEDERNAME$", seederName)
+	seeder = strings.ReplaceAll(seeder, "$TABLENAME$", tableName)
+	seeder = strings.ReplaceAll(seeder, "$SEEDER$", name)
+
+	if err := os.MkdirAll(filepath.Dir(targetFile), 0755); err != nil {
+		exitGracefully(err)
+	}
+	err = copyDataToFile([]byte(seeder), targetFile)
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	color.Yellow("   -%s created", seederName)
+	color.Red(" -dont forget to add it in internal/seeder/seeder.go: app.AddSeeder(%q, %s{})", name, seederName)
 	return nil
 }
 
 // doSessionTable build the subcommand for session store for make command
 func doSessionTable() error {
-	dbType := sauri2.DBConn.DatabaseType
-
-	// configuring database type
-	switch dbType {
-	case "postgres", "postgresql":
-		dbType = "postgres"
-
-	case "mysql", "mariadb":
-		dbType = "mysql"
+	dialect, err := migrationDialect()
+	if err != nil {
+		return err
 	}
 
 	fileName := fmt.Sprintf("%d_create_session_table", time.Now().UnixMicro())
 
 	// path the up and down migration folders
-	targetUpFilePath := filepath.Join(sauri2.RootPath, "internal", "migration", fileName+"."+dbType+".up.sql")
-	targetDownFilePath := filepath.Join(sauri2.RootPath, "internal", "migration", fileName+"."+dbType+".down.sql")
+	targetUpFilePath, targetDownFilePath := migrationFiles(fileName, dialect)
 
 	// templates for the migration (existing contents embed to be copied to the target folders
-	tempPathUp := "templates/migrations/session_table." + dbType + ".up.sql"
-	tempPathDown := "drop table session"
+	tempPathUp, tempPathDown, err := migrationTemplates("session_table", dialect)
+	if err != nil {
+		return err
+	}
 
-	err := copyFilesFromTemplate(tempPathUp, targetUpFilePath)
+	err = copyFilesFromTemplate(tempPathUp, targetUpFilePath)
 	if err != nil {
 		exitGracefully(err)
 	}
 
-	err = copyDataToFile([]byte(tempPathDown), targetDownFilePath)
+	err = copyFilesFromTemplate(tempPathDown, targetDownFilePath)
 	if err != nil {
 		exitGracefully(err)
 	}
 
 	//run up migration by adding migrate command directly
-	err = doMigrate("up", "")
+	err = doMigrate("up", "", false)
 	if err != nil {
 		exitGracefully(err)
 	}
 
 	return nil
 }
+
+// stubNames returns the Go name and the file name of the thing a make subcommand creates,
+// without suffix: CheckAdmin, check_admin and check-admin-middleware are all CheckAdmin in
+// check-admin.go
+func stubNames(arg4, suffix string) (string, string) {
+	name := strings.ToLower(normalizeSeparators(splitWords(arg4)))
+	name = strings.Trim(strings.TrimSuffix(name, suffix), "-")
+	return toCamelCase(name), name
+}
+
+// writeStub writes a template to a new file, replacing the placeholders
+func writeStub(templatePath, targetFile string, placeholders ...string) error {
+	if fileExists(targetFile) {
+		exitGracefully(errors.New(targetFile + " file already exists"))
+	}
+
+	data, err := templateFS.ReadFile(templatePath)
+	if err != nil {
+		return err
+	}
+	content := strings.NewReplacer(placeholders...).Replace(string(data))
+
+	if err := os.MkdirAll(filepath.Dir(targetFile), 0755); err != nil {
+		return err
+	}
+	return copyDataToFile([]byte(content), targetFile)
+}
+
+// doMiddleware build the subcommand of middlewares for make command, the middleware is a
+// method of the Middleware type of internal/middleware
+func doMiddleware(arg4 string) error {
+	if arg4 == "" {
+		exitGracefully(errors.New("must give the middleware a name"))
+	}
+	middlewareName, fileName := stubNames(arg4, "middleware")
+	if middlewareName == "" {
+		exitGracefully(errors.New("must give the middleware a name"))
+	}
+
+	targetDir := filepath.Join(sauri2.RootPath, "internal", "middleware")
+	err := writeStub("templates/middleware/middleware-stub.go.txt", filepath.Join(targetDir, fileName+".go"),
+		"$MIDDLEWARENAME$", middlewareName)
+	if err != nil {
+		return err
+	}
+
+	// the type the middlewares hang off, shared by all of them
+	baseFile := filepath.Join(targetDir, "middleware.go")
+	if !fileExists(baseFile) {
+		if err := copyFilesFromTemplate("templates/middleware/middleware.go.txt", baseFile); err != nil {
+			return err
+		}
+	}
+
+	color.Yellow("   -%s middleware created", middlewareName)
+	return nil
+}
+
+// doRequest build the subcommand of form requests for make command, a struct of the input
+// with its validation rules
+func doRequest(arg4 string) error {
+	if arg4 == "" {
+		exitGracefully(errors.New("must give the request a name"))
+	}
+	requestName, fileName := stubNames(arg4, "request")
+	if requestName == "" {
+		exitGracefully(errors.New("must give the request a name"))
+	}
+
+	targetFile := filepath.Join(sauri2.RootPath, "internal", "request", fileName+".go")
+	if err := writeStub("templates/requests/request.go.txt", targetFile, "$REQUESTNAME$", requestName); err != nil {
+		return err
+	}
+
+	color.Yellow("   -%s request created", requestName)
+	return nil
+}
+
+// doJob build the subcommand of background jobs for make command, the job still has to be
+// registered with app.Jobs.Register
+func doJob(arg4 string) error {
+	if arg4 == "" {
+		exitGracefully(errors.New("must give the job a name"))
+	}
+	jobName, fileName := stubNames(arg4, "job")
+	if jobName == "" {
+		exitGracefully(errors.New("must give the job a name"))
+	}
+
+	targetFile := filepath.Join(sauri2.RootPath, "internal", "job", fileName+".go")
+	err := writeStub("templates/jobs/job.go.txt", targetFile,
+		"$JOBNAME$", jobName,
+		"$JOB$", strings.ReplaceAll(fileName, "-", "_"))
+	if err != nil {
+		return err
+	}
+
+	color.Yellow("   -%s job created", jobName)
+	color.Red(" -dont forget to register it: app.Jobs.Register(&job.%s{})", jobName)
+	return nil
+}
+
+// doMail build the subcommand of mails for make command, the mailable in internal/mail and
+// its html and plain text templates in resources/mails
+func doMail(arg4 string) error {
+	if arg4 == "" {
+		exitGracefully(errors.New("must give the mail a name"))
+	}
+	mailName, fileName := stubNames(arg4, "mail")
+	if mailName == "" {
+		exitGracefully(errors.New("must give the mail a name"))
+	}
+
+	placeholders := []string{
+		"$MAILNAME$", mailName,
+		"$MAIL$", fileName,
+		"$SUBJECT$", capitalizeFirst(strings.ReplaceAll(fileName, "-", " ")),
+	}
+	files := map[string]string{
+		"templates/mails/mail.go.txt":           filepath.Join(sauri2.RootPath, "internal", "mail", fileName+".go"),
+		"templates/mails/mail.html.gohtml.txt":  filepath.Join(sauri2.RootPath, "resources", "mails", fileName+".html.gohtml"),
+		"templates/mails/mail.plain.gohtml.txt": filepath.Join(sauri2.RootPath, "resources", "mails", fileName+".plain.gohtml"),
+	}
+	for _, targetFile := range files {
+		if fileExists(targetFile) {
+			exitGracefully(errors.New(targetFile + " file already exists"))
+		}
+	}
+	for templatePath, targetFile := range files {
+		if err := writeStub(templatePath, targetFile, placeholders...); err != nil {
+			return err
+		}
+	}
+
+	color.Yellow("   -%s mail and its templates created", mailName)
+	return nil
+}
@@ -1,12 +1,89 @@
 package main
 
-// doMigrate build the migrate command to running up and down migration to the database
-func doMigrate(arg3, arg4 string) error {
+import (
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/haskekareem/sauri"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// migrateCommand runs the migrations, up by default
+func migrateCommand() *command {
+	cmd := newCommand("migrate", "[up|down [n|all]|reset|fresh|status|version|to <version>]",
+		"run the pending migrations, or reverse, reset, list or move them")
+	cmd.app = true
+	seed := cmd.flags.Bool("seed", false, "run the seeders once the migrations are done")
+	dryRun := cmd.flags.Bool("dry-run", false, "print the SQL the command would run without running it")
+
+	cmd.run = func(args []string) error {
+		if len(args) > 2 {
+			return usageErrorf("too many arguments")
+		}
+		// migrate up as the default setting
+		arg3, arg4 := "up", ""
+		if len(args) > 0 {
+			arg3 = args[0]
+		}
+		if len(args) > 1 {
+			arg4 = args[1]
+		}
+
+		if err := doMigrate(arg3, arg4, *dryRun); err != nil {
+			return err
+		}
+		switch {
+		case *dryRun:
+			color.Yellow("dry run, nothing was migrated")
+			return nil
+		case arg3 == "status" || arg3 == "version":
+			return nil
+		}
+		// --seed runs the seeders once the migrations are done
+		if *seed {
+			if err := doSeed(); err != nil {
+				return err
+			}
+			color.Yellow("migrations and seeders complete!")
+			return nil
+		}
+		color.Yellow("migrations complete!")
+		return nil
+	}
+	return cmd
+}
+
+// doMigrate build the migrate command to running up and down migration to the database. With
+// dryRun the SQL the command would run is printed instead.
+func doMigrate(arg3, arg4 string, dryRun bool) error {
 	dsn, err := getDSN()
 	if err != nil {
 		return err
 	}
 
+	switch arg3 {
+	case "status":
+		return doMigrateStatus(dsn)
+	case "version":
+		version, dirty, err := sauri2.MigrationVersion(dsn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			color.Red("version %d (dirty: the migration failed halfway, fix the database and force it)", version)
+			return nil
+		}
+		color.Yellow("version %d", version)
+		return nil
+	}
+
+	if dryRun {
+		return doMigrateDryRun(arg3, arg4, dsn)
+	}
+
 	switch arg3 {
 	case "up":
 		err := sauri2.UpMigrate(dsn)
@@ -21,8 +98,12 @@ func doMigrate(arg3, arg4 string) error {
 				return err
 			}
 		} else {
-			// drop the most current added migration
-			err := sauri2.StepsMigrate(-1, dsn)
+			// drop the n most current added migrations, one by default
+			n, err := parseSteps(arg4)
+			if err != nil {
+				return err
+			}
+			err = sauri2.StepsMigrate(-n, dsn)
 			if err != nil {
 				return err
 			}
@@ -37,8 +118,171 @@ func doMigrate(arg3, arg4 string) error {
 		if err != nil {
 			return err
 		}
+	case "fresh":
+		// drop every table and run all the migrations again
+		err := sauri2.FreshMigrate(dsn)
+		if err != nil {
+			return err
+		}
+	case "to":
+		version, err := parseVersion(arg4)
+		if err != nil {
+			return err
+		}
+		err = sauri2.ToMigrate(version, dsn)
+		if err != nil {
+			return err
+		}
+	default:
+		return usageErrorf("unknown migrate command %q", arg3)
+	}
+	return nil
+}
+
+// doMigrateStatus prints every migration with whether it is applied
+func doMigrateStatus(dsn string) error {
+	migrations, version, dirty, err := sauri2.MigrationStatus(dsn)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tNAME\tCREATED\tSTATUS")
+	pending := 0
+	for _, migration := range migrations {
+		status := "applied"
+		switch {
+		case migration.Version == version && dirty:
+			status = "dirty"
+		case !migration.Applied:
+			status = "pending"
+			pending++
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", migration.Version, migration.Name,
+			formatRunTime(migration.CreatedAt), status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	color.Yellow("\nversion %d, %d applied, %d pending", version, len(migrations)-pending, pending)
+	return nil
+}
+
+// doMigrateDryRun prints the SQL a migrate command would run
+func doMigrateDryRun(arg3, arg4, dsn string) error {
+	migrations, version, _, err := sauri2.MigrationStatus(dsn)
+	if err != nil {
+		return err
+	}
+
+	var target uint
+	switch arg3 {
+	case "up":
+		if len(migrations) > 0 {
+			target = max(version, migrations[len(migrations)-1].Version)
+		}
+	case "down":
+		if arg4 != "all" {
+			n, err := parseSteps(arg4)
+			if err != nil {
+				return err
+			}
+			target = previousVersion(migrations, version, n)
+		}
+	case "reset":
+		// every down migration, then every up one
+		steps, err := sauri2.PlanMigrate(0, dsn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range migrations {
+			steps = append(steps, sauri.MigrationStep{Migration: migration, Direction: "up"})
+		}
+		return printMigrationSteps(steps)
+	case "fresh":
+		// every table is dropped, then every up migration runs
+		color.Red("-- drop every table of the database")
+		var steps []sauri.MigrationStep
+		for _, migration := range migrations {
+			steps = append(steps, sauri.MigrationStep{Migration: migration, Direction: "up"})
+		}
+		return printMigrationSteps(steps)
+	case "to":
+		if target, err = parseVersion(arg4); err != nil {
+			return err
+		}
 	default:
-		showHelp()
+		return fmt.Errorf("migrate %s has no dry run", arg3)
+	}
+
+	steps, err := sauri2.PlanMigrate(target, dsn)
+	if err != nil {
+		return err
+	}
+	return printMigrationSteps(steps)
+}
+
+// previousVersion returns the version n migrations before the current one, 0 when there are
+// not that many
+func previousVersion(migrations []sauri.Migration, version uint, n int) uint {
+	var applied []uint
+	for _, migration := range migrations {
+		if migration.Version <= version {
+			applied = append(applied, migration.Version)
+		}
+	}
+	if n >= len(applied) {
+		return 0
+	}
+	return applied[len(applied)-1-n]
+}
+
+// printMigrationSteps prints the SQL of the steps, reading the files of the steps without it
+func printMigrationSteps(steps []sauri.MigrationStep) error {
+	if len(steps) == 0 {
+		color.Yellow("nothing to migrate")
+		return nil
+	}
+	for _, step := range steps {
+		if step.SQL == "" {
+			file := step.Up
+			if step.Direction == "down" {
+				file = step.Down
+			}
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			step.SQL = string(content)
+		}
+		color.Yellow("-- %d_%s (%s)", step.Version, step.Name, step.Direction)
+		fmt.Println(strings.TrimSpace(step.SQL))
+		fmt.Println()
 	}
 	return nil
 }
+
+// parseVersion parses the version of migrate to
+func parseVersion(arg string) (uint, error) {
+	if arg == "" {
+		return 0, errors.New("migrate to requires a version, see migrate status")
+	}
+	version, err := strconv.ParseUint(arg, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid migration version %q", arg)
+	}
+	return uint(version), nil
+}
+
+// parseSteps parses the number of migrations of migrate down, one when it is empty
+func parseSteps(arg string) (int, error) {
+	if arg == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid number of migrations %q, expected a positive number or all", arg)
+	}
+	return n, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"strings"
+	"text/template"
+)
+
+// modelField is a field of a generated model
+type modelField struct {
+	Name   string
+	Type   string
+	Column string
+	// Kind is the column type, string, timestamp...
+	Kind     string
+	Nullable bool
+}
+
+// modelData fills templates/data/model.go.txt
+type modelData struct {
+	Model       string
+	Table       string
+	IDType      string
+	OrderBy     string
+	Order       string
+	Fields      []modelField
+	Timestamps  bool
+	SoftDeletes bool
+	UUID        bool
+	JSON        bool
+	Time        bool
+}
+
+// goTypes are the Go types of the column types
+var goTypes = map[string]string{
+	"string":    "string",
+	"text":      "string",
+	"int":       "int",
+	"bigint":    "int64",
+	"bool":      "bool",
+	"float":     "float64",
+	"decimal":   "float64",
+	"date":      "time.Time",
+	"timestamp": "time.Time",
+	"json":      "json.RawMessage",
+	"uuid":      "string",
+}
+
+// initialisms are the parts of column names written in capitals in Go names
+var initialisms = map[string]bool{"id": true, "url": true, "uuid": true, "ip": true, "api": true, "json": true, "html": true, "http": true}
+
+// newModelData returns the data of a model with its columns and options
+func newModelData(model, table string, columns []column, options tableOptions) modelData {
+	data := modelData{
+		Model:       model,
+		Table:       table,
+		IDType:      "int64",
+		OrderBy:     "id DESC",
+		Order:       "the newest first",
+		Timestamps:  options.timestamps,
+		SoftDeletes: options.softDeletes,
+		UUID:        options.uuidPK,
+		Time:        options.timestamps || options.softDeletes,
+	}
+	if options.uuidPK {
+		data.IDType = "string"
+		// uuids are in no order, the rows are by creation time when it is known
+		data.OrderBy, data.Order = "id", "by id"
+		if options.timestamps {
+			data.OrderBy, data.Order = "created_at DESC", "the newest first"
+		}
+	}
+
+	for _, col := range columns {
+		goType := goTypes[col.kind]
+		switch goType {
+		case "time.Time":
+			data.Time = true
+		case "json.RawMessage":
+			data.JSON = true
+		}
+		// json.RawMessage is nil already for NULL
+		if col.nullable && goType != "json.RawMessage" {
+			goType = "*" + goType
+		}
+		data.Fields = append(data.Fields, modelField{Name: goFieldName(col.name), Type: goType, Column: col.name, Kind: col.kind, Nullable: col.nullable})
+	}
+	return data
+}
+
+// goFieldName returns the Go name of a column, user_id is UserID
+func goFieldName(columnName string) string {
+	parts := strings.Split(normalizeSeparators(strings.ToLower(columnName)), "-")
+	for i, part := range parts {
+		if initialisms[part] {
+			parts[i] = strings.ToUpper(part)
+		} else {
+			parts[i] = capitalizeFirst(part)
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// renderModel executes the model template and formats the result
+func renderModel(data modelData) ([]byte, error) {
+	content, err := templateFS.ReadFile("templates/data/model.go.txt")
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New("model").Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
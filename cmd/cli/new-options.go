@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/fatih/color"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// newOptions are the choices of sauri new, given as flags or asked for
+type newOptions struct {
+	minimal bool
+	db      string
+	cache   string
+	engine  string
+	git     bool
+}
+
+// the values of the sauri new flags, the first one is the default
+var (
+	newDatabases = []string{"none", "postgres", "mysql", "sqlite"}
+	newCaches    = []string{"none", "redis", "badger", "memory"}
+	newEngines   = []string{"go", "jet"}
+)
+
+// newAppCommand creates an application, the options left out are asked for when the cli
+// runs in a terminal and take their default otherwise
+func newAppCommand() *command {
+	cmd := newCommand("new", "<name>", "create a new application from the skeleton repository")
+	options := newOptions{}
+	cmd.flags.BoolVar(&options.minimal, "minimal", false, "create the application offline from the embedded skeleton")
+	cmd.flags.StringVar(&options.db, "db", "", "`database` of the .env file: "+strings.Join(newDatabases, ", "))
+	cmd.flags.StringVar(&options.cache, "cache", "", "`cache` of the .env file: "+strings.Join(newCaches, ", "))
+	cmd.flags.StringVar(&options.engine, "engine", "", "template `engine`: "+strings.Join(newEngines, ", "))
+	gitFlag := cmd.flags.Bool("git", false, "initialize a git repository, the default")
+	noGit := cmd.flags.Bool("no-git", false, "do not initialize a git repository")
+
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			return usageErrorf("new requires an application name")
+		}
+		for _, choice := range []struct {
+			flag    string
+			value   *string
+			choices []string
+		}{
+			{"--db", &options.db, newDatabases},
+			{"--cache", &options.cache, newCaches},
+			{"--engine", &options.engine, newEngines},
+		} {
+			if *choice.value == "" {
+				continue
+			}
+			value, err := newChoice(choice.flag, *choice.value, choice.choices)
+			if err != nil {
+				return err
+			}
+			*choice.value = value
+		}
+		if *gitFlag && *noGit {
+			return usageErrorf("--git and --no-git cannot be used together")
+		}
+		options.git = !*noGit
+
+		options.ask(*gitFlag || *noGit)
+		doNew(args[0], options)
+		return nil
+	}
+	return cmd
+}
+
+// ask asks for the options left out when the cli runs in a terminal, they take their
+// default otherwise
+func (o *newOptions) ask(gitSet bool) {
+	reader := bufio.NewReader(os.Stdin)
+	interactive := isTerminal(os.Stdin)
+	if o.db == "" {
+		o.db = askChoice(reader, interactive, "Database", newDatabases)
+	}
+	if o.cache == "" {
+		o.cache = askChoice(reader, interactive, "Cache", newCaches)
+	}
+	if o.engine == "" {
+		o.engine = askChoice(reader, interactive, "Template engine", newEngines)
+	}
+	if !gitSet && interactive {
+		o.git = askChoice(reader, interactive, "Initialize a git repository", []string{"yes", "no"}) == "yes"
+	}
+}
+
+// newChoice checks the value of a flag
+func newChoice(flag, value string, choices []string) (string, error) {
+	value = strings.ToLower(value)
+	if !slices.Contains(choices, value) {
+		return "", usageErrorf("%s must be one of %s", flag, strings.Join(choices, ", "))
+	}
+	return value, nil
+}
+
+// askChoice asks for one of the choices, the first one when the answer is empty or the cli
+// is not in a terminal
+func askChoice(reader *bufio.Reader, interactive bool, question string, choices []string) string {
+	if !interactive {
+		return choices[0]
+	}
+	for {
+		fmt.Printf("%s (%s) [%s]: ", question, strings.Join(choices, ", "), choices[0])
+		answer, err := reader.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer == "" {
+			return choices[0]
+		}
+		if slices.Contains(choices, answer) {
+			return answer
+		}
+		if err != nil {
+			return choices[0]
+		}
+		color.Red("   please answer %s", strings.Join(choices, ", "))
+	}
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe or a file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// envSettings returns the .env settings of the options
+func (o newOptions) envSettings(appName string) [][2]string {
+	var settings [][2]string
+	switch o.db {
+	case "postgres":
+		settings = append(settings, [][2]string{
+			{"DATABASE_USE", "true"}, {"DATABASE_TYPE", "postgres"}, {"DATABASE_HOST", "localhost"},
+			{"DATABASE_PORT", "5432"}, {"DATABASE_USER", "postgres"}, {"DATABASE_NAME", appName},
+			{"DATABASE_SSL_MODE", "disable"},
+		}...)
+	case "mysql":
+		settings = append(settings, [][2]string{
+			{"DATABASE_USE", "true"}, {"DATABASE_TYPE", "mysql"}, {"DATABASE_HOST", "localhost"},
+			{"DATABASE_PORT", "3306"}, {"DATABASE_USER", "root"}, {"DATABASE_NAME", appName},
+		}...)
+	case "sqlite":
+		// the migrations are written for sqlite, the server does not connect to it yet
+		settings = append(settings, [][2]string{
+			{"DATABASE_TYPE", "sqlite"}, {"DATABASE_NAME", appName + ".db"},
+		}...)
+	}
+
+	switch o.cache {
+	case "redis":
+		settings = append(settings, [2]string{"CACHE", "redis"}, [2]string{"REDIS_HOST", "localhost:6379"})
+	case "badger":
+		settings = append(settings, [2]string{"CACHE", "badger"})
+	case "memory":
+		// no cache server, the jobs stay in the process
+		settings = append(settings, [2]string{"JOBS_QUEUE", "memory"})
+	}
+
+	return append(settings, [2]string{"RENDER_ENGINE", o.engine})
+}
+
+// setEnv sets the value of a key of a .env file, the line is added when the file has none
+func setEnv(env, key, value string) string {
+	line := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(key) + `=.*$`)
+	if line.MatchString(env) {
+		return line.ReplaceAllLiteralString(env, key+"="+value)
+	}
+	return strings.TrimRight(env, "\n") + "\n" + key + "=" + value + "\n"
+}
+
+// useJetViews turns the home page of the skeleton into jet views, it does nothing when the
+// application does not have the home page of the skeleton
+func useJetViews(appName string) error {
+	controllerFile := filepath.Join(appName, "internal", "controller", "controller.go")
+	controller, err := os.ReadFile(controllerFile)
+	if err != nil || !strings.Contains(string(controller), `"home.gohtml"`) {
+		return nil
+	}
+
+	viewsDir := filepath.Join(appName, "resources", "views")
+	views := map[string]string{
+		"templates/views/base.jet.txt": filepath.Join(viewsDir, "layouts", "base.jet"),
+		"templates/views/home.jet.txt": filepath.Join(viewsDir, "home.jet"),
+	}
+	for templatePath, target := range views {
+		content, err := templateFS.ReadFile(templatePath)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := copyDataToFile([]byte(strings.ReplaceAll(string(content), "${APP_NAME}", appName)), target); err != nil {
+			return err
+		}
+	}
+	_ = os.Remove(filepath.Join(viewsDir, "pages", "home.gohtml"))
+	_ = os.Remove(filepath.Join(viewsDir, "pages"))
+	_ = os.Remove(filepath.Join(viewsDir, "layouts", "base.layout.gohtml"))
+
+	controller = []byte(strings.Replace(string(controller), `"home.gohtml"`, `"home"`, 1))
+	return copyDataToFile(controller, controllerFile)
+}
@@ -4,16 +4,18 @@ import (
 	"fmt"
 	"github.com/fatih/color"
 	"github.com/go-git/go-git/v5"
-	"io"
 	"os"
 	"os/exec"
-	"runtime"
+	"path/filepath"
 	"strings"
 )
 
 var appURL string
 
-func doNew(appName string) {
+// doNew scaffolds a new application, either by cloning the skeleton repository or, with
+// minimal set, offline from the skeleton embedded in the cli. The database, cache and
+// template engine options fill in the .env file.
+func doNew(appName string, options newOptions) {
 	//todo Sanitize the Application Name:
 	//Ensures that the app name is in lowercase
 	//and extracts the name if it's in a URL format.
@@ -26,25 +28,18 @@ func doNew(appName string) {
 		appName = exploded[(len(exploded) - 1)]
 	}
 
-	//todo  Clone the skeleton repository
-	color.Green("\tcloning project repository.....")
-	// Clones the repository into the given dir, just as a normal git clone does
-	_, err := git.PlainClone("./"+appName, false, &git.CloneOptions{
-		URL:      "https://github.com/haskekareem/bare-sauri.git",
-		Progress: os.Stdout,
-		Depth:    1,
-	})
+	if options.minimal {
+		newMinimal(appName)
+	} else {
+		newFromRepository(appName)
+	}
 
+	color.Yellow("\tCreating sauri.yaml file")
+	tasks, err := templateFS.ReadFile("templates/sauri.yaml.txt")
 	if err != nil {
 		exitGracefully(err)
 	}
-
-	// remove the .git repository
-	// when repository is cloned there will be a git repository indicating that all the codes
-	// belong to a remote repository and that is wrong
-	// remove .git directory
-	color.Yellow("\tRemoving .git directory...")
-	err = os.RemoveAll(fmt.Sprintf("./%s/.git", appName))
+	err = copyDataToFile([]byte(strings.ReplaceAll(string(tasks), "${APP_NAME}", appName)), filepath.Join(appName, taskFile))
 	if err != nil {
 		exitGracefully(err)
 	}
@@ -58,66 +53,94 @@ func doNew(appName string) {
 	env := string(d)
 	env = strings.ReplaceAll(env, "${APP_NAME}", appName)
 	env = strings.ReplaceAll(env, "${KEY}", sauri2.GenerateRandomString(32))
+	for _, setting := range options.envSettings(appName) {
+		env = setEnv(env, setting[0], setting[1])
+	}
 
 	err = copyDataToFile([]byte(env), fmt.Sprintf("./%s/.env", appName))
 	if err != nil {
 		exitGracefully(err)
 	}
 
-	/* OS-specific Makefile handling
-	var makefileSource string
-	if runtime.GOOS == "windows" {
-		makefileSource = fmt.Sprintf("./%s/Makefile", appName)
-	} else {
-		makefileSource = fmt.Sprintf("./%s/Makefile.mac", appName)
-	}
-	err = copyFile(makefileSource, fmt.Sprintf("./%s/Makefile", appName))
-	if err != nil {
-		exitGracefully(err)
-	} */
-	// Clean up OS-specific files
-	color.Yellow("\tCleaning up OS-specific Makefiles...")
-	// create a makefile
-	if runtime.GOOS == "windows" {
-		source, err := os.Open(fmt.Sprintf("./%s/Makefile", appName))
-		if err != nil {
+	if options.engine == "jet" {
+		if err := useJetViews(appName); err != nil {
 			exitGracefully(err)
 		}
-		defer source.Close()
+	}
 
-		destination, err := os.Create(fmt.Sprintf("./%s/Makefile", appName))
-		if err != nil {
-			exitGracefully(err)
-		}
-		defer destination.Close()
+	//update the existing go files with the correct imports/name
+	color.Yellow("\tupdate the existing go files with the correct imports names....")
+	_ = os.Chdir("./" + appName)
+	updateSource()
 
-		_, err = io.Copy(destination, source)
-		if err != nil {
-			exitGracefully(err)
-		}
-	} else {
-		source, err := os.Open(fmt.Sprintf("./%s/Makefile.mac", appName))
-		if err != nil {
-			exitGracefully(err)
-		}
-		defer source.Close()
+	//run go mod tidy in the project directory, to the end so that the module is ready
+	color.Yellow("\tRunning go mod tidy....")
+	cmd := exec.Command("go", "mod", "tidy")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		exitGracefully(fmt.Errorf("go mod tidy: %w", err))
+	}
 
-		destination, err := os.Create(fmt.Sprintf("./%s/Makefile", appName))
-		if err != nil {
+	if options.git {
+		color.Yellow("\tInitializing the git repository....")
+		if _, err := git.PlainInit(".", false); err != nil {
 			exitGracefully(err)
 		}
-		defer destination.Close()
+	}
+	if options.db == "sqlite" {
+		color.Red(" -the migrations are written for sqlite, the server only connects to postgres and mysql for now")
+	}
+
+	// final message to the user of the package
+	color.Green("Done building " + appURL)
+	color.Green("Good luck with project")
+}
 
-		_, err = io.Copy(destination, source)
-		if err != nil {
+// newMinimal creates the application from the embedded minimal skeleton, which already
+// comes with its go.mod file
+func newMinimal(appName string) {
+	color.Green("\tcreating project from the embedded minimal skeleton.....")
+	err := copySkeleton(appName)
+	if err != nil {
+		exitGracefully(err)
+	}
+}
+
+// newFromRepository clones the skeleton repository and replaces its Makefiles and go.mod file
+func newFromRepository(appName string) {
+	//todo  Clone the skeleton repository
+	color.Green("\tcloning project repository.....")
+	// Clones the repository into the given dir, just as a normal git clone does
+	_, err := git.PlainClone("./"+appName, false, &git.CloneOptions{
+		URL:      "https://github.com/haskekareem/bare-sauri.git",
+		Progress: os.Stdout,
+		Depth:    1,
+	})
+
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	// remove the .git repository
+	// when repository is cloned there will be a git repository indicating that all the codes
+	// belong to a remote repository and that is wrong
+	// remove .git directory
+	color.Yellow("\tRemoving .git directory...")
+	err = os.RemoveAll(fmt.Sprintf("./%s/.git", appName))
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	// the skeleton comes with a Makefile for windows and one for the other systems, the
+	// tasks of sauri.yaml replace both
+	color.Yellow("\tRemoving the Makefiles...")
+	for _, makefile := range []string{"Makefile", "Makefile.mac"} {
+		if err := os.Remove(filepath.Join(appName, makefile)); err != nil && !os.IsNotExist(err) {
 			exitGracefully(err)
 		}
 	}
-	_ = os.Remove("./" + appName + "/Makefile.mac")
-	_ = os.Remove("./" + appName + "/Makefile")
-
-	/*_ = os.Remove(fmt.Sprintf("./%s/Makefile", appName))
-	_ = os.Remove(fmt.Sprintf("./%s/Makefile.mac", appName))*/
 
 	//todo update the go mod file
 	// delete the go mod file that came with the cloning and create the appropriate mod file
@@ -126,7 +149,7 @@ func doNew(appName string) {
 	if err != nil {
 		exitGracefully(err)
 	}
-	d, err = templateFS.ReadFile("templates/go.mod.txt")
+	d, err := templateFS.ReadFile("templates/go.mod.txt")
 	if err != nil {
 		exitGracefully(err)
 	}
@@ -137,22 +160,4 @@ func doNew(appName string) {
 	if err != nil {
 		exitGracefully(err)
 	}
-
-	//update the existing go files with the correct imports/name
-	color.Yellow("\tupdate the existing go files with the correct imports names....")
-	_ = os.Chdir("./" + appName)
-	updateSource()
-
-	//run go mod tidy in the project directory
-	color.Yellow("\tRunning go mod tidy....")
-	cmd := exec.Command("go", "mod", "tidy")
-	err = cmd.Start()
-
-	if err != nil {
-		exitGracefully(err)
-	}
-
-	// final message to the user of the package
-	color.Green("Done building " + appURL)
-	color.Green("Good luck with project")
 }
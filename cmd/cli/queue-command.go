@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"github.com/fatih/color"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+// queueCommands are the commands of the job queue, they run in the application which
+// registers the jobs, see Sauri.QueueCommand
+func queueCommands() []*command {
+	work := newCommand("queue:work", "", "run workers taking jobs from the queues until SIGINT or SIGTERM")
+	work.app = true
+	queues := work.flags.String("queue", "", "comma separated `queues` to work on in priority order, JOBS_QUEUES or default when empty")
+	concurrency := work.flags.Int("concurrency", 0, "number of workers, JOBS_CONCURRENCY when 0")
+	work.run = func(args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("queue:work takes no arguments")
+		}
+		if *concurrency < 0 {
+			return usageErrorf("--concurrency must not be negative")
+		}
+		return doQueueWork(*queues, *concurrency)
+	}
+
+	retry := newCommand("queue:retry", "<id...|all>", "queue failed jobs again, with their attempts reset")
+	retry.app = true
+	retry.run = func(args []string) error {
+		if len(args) == 0 {
+			return usageErrorf("must give the id of the job to retry, or all")
+		}
+		return runApp("the queue commands", append([]string{"queue:retry"}, args...)...)
+	}
+
+	failed := newCommand("queue:failed", "", "list the jobs that failed every attempt")
+	failed.app = true
+	failed.run = func(args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("queue:failed takes no arguments")
+		}
+		return runApp("the queue commands", "queue:failed")
+	}
+
+	mailRetry := newCommand("mail:retry", "<id...|all>", "queue the failed emails again, with their attempts reset")
+	mailRetry.app = true
+	mailRetry.run = func(args []string) error {
+		if len(args) == 0 {
+			return usageErrorf("must give the id of the email to retry, or all")
+		}
+		return runApp("the queue commands", append([]string{"mail:retry"}, args...)...)
+	}
+
+	mailFailed := newCommand("mail:failed", "", "list the queued emails that failed every attempt")
+	mailFailed.app = true
+	mailFailed.run = func(args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("mail:failed takes no arguments")
+		}
+		return runApp("the queue commands", "mail:failed")
+	}
+
+	return []*command{work, retry, failed, mailRetry, mailFailed}
+}
+
+// doQueueWork builds the application and runs its workers. The binary is run rather than go
+// run so that SIGTERM, e.g. from systemd or docker stop, reaches the workers and they can let
+// the running jobs finish.
+func doQueueWork(queues string, concurrency int) error {
+	if !fileExists(filepath.Join(sauri2.RootPath, "cmd", "server")) {
+		return errors.New("cmd/server not found, the workers run through the application main package")
+	}
+
+	binary := filepath.Join(sauri2.RootPath, "tmp", "sauri-worker")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	color.Yellow("building cmd/server...")
+	build := exec.Command("go", "build", "-o", binary, "./cmd/server")
+	build.Dir = sauri2.RootPath
+	build.Stdout = os.Stdout
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		return errors.New("the build failed")
+	}
+
+	args := []string{"queue:work", "--concurrency=" + strconv.Itoa(concurrency)}
+	if queues != "" {
+		args = append(args, "--queue="+queues)
+	}
+	cmd := exec.Command(binary, args...)
+	cmd.Dir = sauri2.RootPath
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	for {
+		select {
+		case err := <-done:
+			return err
+		case sig := <-signals:
+			color.Yellow("stopping the workers, the running jobs finish first")
+			// windows has no signal to send to another process
+			if runtime.GOOS == "windows" || cmd.Process.Signal(sig) != nil {
+				_ = cmd.Process.Kill()
+			}
+		}
+	}
+}
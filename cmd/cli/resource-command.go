@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/gertd/go-pluralize"
+	"go/format"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// resourceData fills the templates of make resource
+type resourceData struct {
+	modelData
+	// Module is the module path of the application, from its go.mod
+	Module string
+	// Var and Plural are the variable names of one record and of a page of them
+	Var    string
+	Plural string
+	// Path is the url path of the resource, the table name with dashes
+	Path string
+	// IndexView, ShowView and FormView are the templates the controller renders
+	IndexView string
+	ShowView  string
+	FormView  string
+}
+
+// reservedNames are the names the generated controller uses already
+var reservedNames = map[string]bool{
+	"c": true, "w": true, "r": true, "id": true, "td": true, "err": true, "ok": true, "page": true,
+	"action": true, "changed": true, "invalid": true, "model": true, "db": true, "errors": true,
+	"fmt": true, "http": true, "strconv": true, "validator": true,
+}
+
+// doResource build the resource subcommand of make: the model and the migration of make model,
+// a controller with the seven RESTful actions, the routes and the views of the RENDER_ENGINE.
+// It takes the same arguments as make model.
+func doResource(args []string, options tableOptions) error {
+	name, columns, options, err := parseModelArgs(args, options)
+	if err != nil {
+		return err
+	}
+
+	module, err := appModule()
+	if err != nil {
+		return err
+	}
+
+	engine := strings.ToLower(os.Getenv("RENDER_ENGINE"))
+	if engine != "jet" {
+		engine = "go"
+	}
+	model, table := resourceNames(name)
+	data := newResourceData(newModelData(model, table, columns, options), module, engine)
+
+	controllerFile := filepath.Join(sauri2.RootPath, "internal", "controller", strings.ReplaceAll(data.Table, "_", "-")+".go")
+	views := resourceViews(data, engine)
+	// nothing is written when one of the files exists already
+	for _, file := range append([]string{controllerFile}, viewFiles(views)...) {
+		if fileExists(file) {
+			exitGracefully(errors.New(file + " file already exists"))
+		}
+	}
+
+	if _, err := makeModel(name, columns, options); err != nil {
+		return err
+	}
+
+	controller, err := renderResourceController(data)
+	if err != nil {
+		return err
+	}
+	if err := copyDataToFile(controller, controllerFile); err != nil {
+		return err
+	}
+
+	for file, content := range views {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		if err := copyDataToFile([]byte(content), file); err != nil {
+			return err
+		}
+	}
+
+	routes := resourceRoutes(data)
+	added, err := addRoutes(routes)
+	if err != nil {
+		return err
+	}
+	if !added {
+		color.Yellow("   -add the routes of the %s to internal/route/routes.go:\n\n%s", data.Table, routes)
+	}
+
+	color.Yellow("   -%s controller, views and routes created", data.Model)
+	return nil
+}
+
+// resourceNames returns the model and the table names of a resource name, singular or plural,
+// as make model does
+func resourceNames(name string) (string, string) {
+	singular, plural := name, name
+	plur := pluralize.NewClient()
+	if plur.IsPlural(name) {
+		singular = plur.Singular(name)
+	} else {
+		plural = plur.Plural(name)
+	}
+	return convertInput(singular), strings.ToLower(plural)
+}
+
+// newResourceData returns the data of the templates of a resource
+func newResourceData(model modelData, module, engine string) resourceData {
+	data := resourceData{
+		modelData: model,
+		Module:    module,
+		Var:       goVarName(model.Model, "item"),
+		Path:      strings.ReplaceAll(model.Table, "_", "-"),
+	}
+	data.Plural = goVarName(goFieldName(model.Table), "items")
+	if data.Plural == data.Var {
+		data.Plural = "items"
+	}
+
+	switch engine {
+	case "jet":
+		data.IndexView = data.Path + "/index"
+		data.ShowView = data.Path + "/show"
+		data.FormView = data.Path + "/form"
+	default:
+		data.IndexView = data.Path + "-index.gohtml"
+		data.ShowView = data.Path + "-show.gohtml"
+		data.FormView = data.Path + "-form.gohtml"
+	}
+	return data
+}
+
+// goVarName returns a Go name starting in lower case, or fallback when it is a keyword or a
+// name the generated code uses
+func goVarName(name, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+	name = strings.ToLower(name[:1]) + name[1:]
+	if token.IsKeyword(name) || reservedNames[name] {
+		return fallback
+	}
+	return name
+}
+
+// appModule returns the module path of the application from its go.mod
+func appModule() (string, error) {
+	file, err := os.Open(filepath.Join(sauri2.RootPath, "go.mod"))
+	if err != nil {
+		return "", fmt.Errorf("cannot read the module of the application: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if module, ok := strings.CutPrefix(line, "module "); ok {
+			return strings.Trim(strings.TrimSpace(module), `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.New("go.mod has no module line")
+}
+
+// renderResourceController executes the controller template and formats the result
+func renderResourceController(data resourceData) ([]byte, error) {
+	content, err := templateFS.ReadFile("templates/resources/controller.go.txt")
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New("controller").Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// resourceRoutes returns the routes of a resource. Browsers only send forms with GET and POST,
+// so update and destroy are reachable with POST too.
+func resourceRoutes(data resourceData) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\tapp.Route(\"/%s\", func(g *sauri.RouteGroup) {\n", data.Path)
+	routes := []struct{ method, pattern, action, name string }{
+		{"Get", "/", "Index", "index"},
+		{"Get", "/create", "Create", "create"},
+		{"Post", "/", "Store", "store"},
+		{"Get", "/{id}", "Show", "show"},
+		{"Get", "/{id}/edit", "Edit", "edit"},
+		{"Put", "/{id}", "Update", "update"},
+		{"Delete", "/{id}", "Destroy", "destroy"},
+	}
+	for _, route := range routes {
+		fmt.Fprintf(&b, "\t\tg.%s(%q, c.%s%s).Name(\"%s.%s\")\n", route.method, route.pattern, data.Model, route.action, data.Table, route.name)
+	}
+	b.WriteString("\t\t// html forms only send GET and POST\n")
+	fmt.Fprintf(&b, "\t\tg.Post(\"/{id}\", c.%sUpdate)\n", data.Model)
+	fmt.Fprintf(&b, "\t\tg.Post(\"/{id}/delete\", c.%sDestroy)\n", data.Model)
+	b.WriteString("\t})\n")
+	return b.String()
+}
+
+// routesAnchor is the line of the skeleton routes file the resource routes go before
+const routesAnchor = "\n\t// static files"
+
+// addRoutes adds the routes to internal/route/routes.go and reports whether it could, the
+// file may be gone or changed beyond recognition
+func addRoutes(routes string) (bool, error) {
+	file := filepath.Join(sauri2.RootPath, "internal", "route", "routes.go")
+	content, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !bytes.Contains(content, []byte(routesAnchor)) {
+		return false, nil
+	}
+	updated := strings.Replace(string(content), routesAnchor, "\n"+routes+routesAnchor, 1)
+	return true, copyDataToFile([]byte(updated), file)
+}
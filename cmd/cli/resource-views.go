@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// inputTypes are the html input types of the column types, text is the default
+var inputTypes = map[string]string{
+	"int":       "number",
+	"bigint":    "number",
+	"float":     "number",
+	"decimal":   "number",
+	"date":      "date",
+	"timestamp": "datetime-local",
+}
+
+// timeLayouts are the layouts of the date and time inputs
+var timeLayouts = map[string]string{
+	"date":      "2006-01-02",
+	"timestamp": "2006-01-02T15:04",
+}
+
+// resourceViews returns the views of a resource by file, index, show and the form of create
+// and edit, for the go or the jet engine
+func resourceViews(data resourceData, engine string) map[string]string {
+	viewsDir := filepath.Join(sauri2.RootPath, "resources", "views")
+	files := map[string]string{
+		"index": filepath.Join(viewsDir, "pages", data.IndexView),
+		"show":  filepath.Join(viewsDir, "pages", data.ShowView),
+		"form":  filepath.Join(viewsDir, "pages", data.FormView),
+	}
+	if engine == "jet" {
+		for view := range files {
+			files[view] = filepath.Join(viewsDir, data.Path, view+".jet")
+		}
+	}
+
+	var headers, cells, fields, inputs []string
+	for _, field := range data.Fields {
+		label := capitalizeFirst(strings.ReplaceAll(field.Column, "_", " "))
+		if engine == "jet" {
+			headers = append(headers, fmt.Sprintf("            <th>%s</th>", label))
+			cells = append(cells, fmt.Sprintf("            <td>%s</td>", jetValue(field)))
+			fields = append(fields, fmt.Sprintf("    <dt>%s</dt>\n    <dd>%s</dd>", label, jetValue(field)))
+			inputs = append(inputs, jetInput(field, label))
+		} else {
+			headers = append(headers, fmt.Sprintf("                <th>%s</th>", label))
+			cells = append(cells, fmt.Sprintf("                <td>%s</td>", goValue(field)))
+			fields = append(fields, fmt.Sprintf("        <dt>%s</dt>\n        <dd>%s</dd>", label, goValue(field)))
+			inputs = append(inputs, goInput(field, label, data.Var))
+		}
+	}
+
+	title := capitalizeFirst(strings.ReplaceAll(data.Table, "_", " "))
+	label := strings.ToLower(strings.ReplaceAll(splitWords(data.Model), "-", " "))
+	replacer := strings.NewReplacer(
+		"$TITLE$", title,
+		"$TITLE_LOWER$", strings.ToLower(title),
+		"$LABEL$", label,
+		"$LABEL_TITLE$", capitalizeFirst(label),
+		"$PATH$", data.Path,
+		"$VAR$", data.Var,
+		"$PLURAL$", data.Plural,
+		"$COLUMNS$", strconv.Itoa(len(data.Fields)+1),
+		"$HEADERS$", strings.Join(headers, "\n"),
+		"$CELLS$", strings.Join(cells, "\n"),
+		"$FIELDS$", strings.Join(fields, "\n"),
+		"$INPUTS$", strings.Join(inputs, "\n"),
+	)
+
+	views := make(map[string]string, len(files))
+	for view, file := range files {
+		content, err := templateFS.ReadFile(fmt.Sprintf("templates/resources/views/%s.%s.txt", view, viewExtension(engine)))
+		if err != nil {
+			exitGracefully(err)
+		}
+		views[file] = replacer.Replace(string(content))
+	}
+	return views
+}
+
+// viewFiles returns the files of the views in order
+func viewFiles(views map[string]string) []string {
+	files := make([]string, 0, len(views))
+	for file := range views {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// viewExtension returns the extension of the view templates of an engine
+func viewExtension(engine string) string {
+	if engine == "jet" {
+		return "jet"
+	}
+	return "gohtml"
+}
+
+// splitWords returns a CamelCase name with its words split by dashes, BlogPost is
+// Blog-Post
+func splitWords(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			b.WriteByte('-')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// goValue returns the go template action printing a field of the record in dot
+func goValue(field modelField) string {
+	switch {
+	case timeLayouts[field.Kind] != "" && field.Nullable:
+		return fmt.Sprintf(`{{with .%s}}{{.Format %q}}{{end}}`, field.Name, timeLayouts[field.Kind])
+	case timeLayouts[field.Kind] != "":
+		return fmt.Sprintf(`{{.%s.Format %q}}`, field.Name, timeLayouts[field.Kind])
+	case field.Kind == "json":
+		return fmt.Sprintf(`{{printf "%%s" .%s}}`, field.Name)
+	case field.Nullable:
+		return fmt.Sprintf(`{{with .%s}}{{.}}{{end}}`, field.Name)
+	}
+	return fmt.Sprintf(`{{.%s}}`, field.Name)
+}
+
+// goInput returns the form input of a field for the go engine, filled with the input of a
+// failed submission or else the record being edited
+func goInput(field modelField, label, record string) string {
+	var input string
+	switch field.Kind {
+	case "bool":
+		input = fmt.Sprintf(`<input id="%[1]s" name="%[1]s" type="checkbox" value="true"{{if .Old}}{{if .Old.Has "%[1]s"}} checked{{end}}{{else}}{{with .GenericData.%[2]s}}{{if .%[3]s}} checked{{end}}{{end}}{{end}}>`,
+			field.Column, record, field.Name)
+	case "text", "json":
+		input = fmt.Sprintf(`<textarea id="%[1]s" name="%[1]s">{{if .Old}}{{.Old.Get "%[1]s"}}{{else}}{{with .GenericData.%[2]s}}%[3]s{{end}}{{end}}</textarea>`,
+			field.Column, record, goValue(field))
+	default:
+		input = fmt.Sprintf(`<input id="%[1]s" name="%[1]s" type="%[2]s"%[3]s value="{{if .Old}}{{.Old.Get "%[1]s"}}{{else}}{{with .GenericData.%[4]s}}%[5]s{{end}}{{end}}">`,
+			field.Column, inputType(field.Kind), stepAttribute(field.Kind), record, goValue(field))
+	}
+	return fmt.Sprintf(`        <div>
+            <label for="%s">%s</label>
+            %s
+            {{with .Errors.First "%s"}}<small>{{.}}</small>{{end}}
+        </div>`, field.Column, label, input, field.Column)
+}
+
+// jetValue returns the jet expression printing a field of item
+func jetValue(field modelField) string {
+	switch {
+	case timeLayouts[field.Kind] != "" && field.Nullable:
+		return fmt.Sprintf(`{{ if item.%[1]s }}{{ item.%[1]s.Format(%[2]q) }}{{ end }}`, field.Name, timeLayouts[field.Kind])
+	case timeLayouts[field.Kind] != "":
+		return fmt.Sprintf(`{{ item.%s.Format(%q) }}`, field.Name, timeLayouts[field.Kind])
+	case field.Kind == "json":
+		return fmt.Sprintf(`{{ item.%s | json }}`, field.Name)
+	case field.Nullable:
+		return fmt.Sprintf(`{{ if item.%[1]s }}{{ item.%[1]s }}{{ end }}`, field.Name)
+	}
+	return fmt.Sprintf(`{{ item.%s }}`, field.Name)
+}
+
+// jetInput returns the form input of a field for the jet engine, filled with the input of a
+// failed submission or else the record being edited
+func jetInput(field modelField, label string) string {
+	var input string
+	switch field.Kind {
+	case "bool":
+		input = fmt.Sprintf(`<input id="%[1]s" name="%[1]s" type="checkbox" value="true"{{ if .Old }}{{ if .Old.Has("%[1]s") }} checked{{ end }}{{ else if item && item.%[2]s }} checked{{ end }}>`,
+			field.Column, field.Name)
+	case "text", "json":
+		input = fmt.Sprintf(`<textarea id="%[1]s" name="%[1]s">{{ if .Old }}{{ .Old.Get("%[1]s") }}{{ else if item }}%[2]s{{ end }}</textarea>`,
+			field.Column, jetValue(field))
+	default:
+		input = fmt.Sprintf(`<input id="%[1]s" name="%[1]s" type="%[2]s"%[3]s value="{{ if .Old }}{{ .Old.Get("%[1]s") }}{{ else if item }}%[4]s{{ end }}">`,
+			field.Column, inputType(field.Kind), stepAttribute(field.Kind), jetValue(field))
+	}
+	return fmt.Sprintf(`    <div>
+        <label for="%s">%s</label>
+        %s
+        {{ if .Errors.Has("%s") }}<small>{{ .Errors.First("%s") }}</small>{{ end }}
+    </div>`, field.Column, label, input, field.Column, field.Column)
+}
+
+// inputType returns the html input type of a column type
+func inputType(kind string) string {
+	if inputType, ok := inputTypes[kind]; ok {
+		return inputType
+	}
+	return "text"
+}
+
+// stepAttribute lets the number inputs of decimal columns take fractions
+func stepAttribute(kind string) string {
+	if kind == "float" || kind == "decimal" {
+		return ` step="any"`
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/haskekareem/sauri"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// doRoutes prints the routes of the running application, read from its route list endpoint
+func doRoutes() error {
+	port := os.Getenv("PORT")
+	if port == "" {
+		return errors.New("PORT is not set in the .env file")
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://localhost:%s/sauri/routes", port))
+	if err != nil {
+		return fmt.Errorf("could not reach the application, is it running? %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errors.New("routes endpoint not found, the application must run in debug mode")
+	}
+
+	var payload struct {
+		Routes []sauri.RouteInfo `json:"routes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return fmt.Errorf("invalid routes response: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "METHOD\tPATTERN\tNAME\tHANDLER\tMIDDLEWARES")
+	for _, route := range payload.Routes {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", route.Method, route.Pattern, route.Name, route.Handler,
+			strings.Join(route.Middlewares, ", "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	color.Yellow("\n%d routes", len(payload.Routes))
+	return nil
+}
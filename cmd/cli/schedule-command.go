@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/haskekareem/sauri/scheduler"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// doScheduleList prints the scheduled tasks of the running application, read from its task
+// list endpoint
+func doScheduleList() error {
+	port := os.Getenv("PORT")
+	if port == "" {
+		return errors.New("PORT is not set in the .env file")
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://localhost:%s/sauri/schedule", port))
+	if err != nil {
+		return fmt.Errorf("could not reach the application, is it running? %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errors.New("schedule endpoint not found, the application must run in debug mode")
+	}
+
+	var payload struct {
+		Tasks []scheduler.TaskInfo `json:"tasks"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return fmt.Errorf("invalid schedule response: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tSCHEDULE\tNEXT RUN\tLAST RUN\tSTATUS")
+	for _, task := range payload.Tasks {
+		status := "ok"
+		switch {
+		case task.Running:
+			status = "running"
+		case task.LastError != "":
+			status = "failed: " + task.LastError
+		case task.LastRun.IsZero():
+			status = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", task.Name, task.Spec, formatRunTime(task.Next),
+			formatRunTime(task.LastRun), status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	color.Yellow("\n%d scheduled tasks", len(payload.Tasks))
+	return nil
+}
+
+// formatRunTime formats the time of a run, a dash when there is none
+func formatRunTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
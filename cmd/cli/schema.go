@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// column is a column of `make migration --create=<table>`, written name:type followed by the
+// unique, index or nullable modifiers, such as email:string:unique
+type column struct {
+	name     string
+	kind     string
+	unique   bool
+	index    bool
+	nullable bool
+}
+
+// columnTypes are the column types of each dialect
+var columnTypes = map[string]map[string]string{
+	"postgres": {
+		"string":    "VARCHAR(255)",
+		"text":      "TEXT",
+		"int":       "INTEGER",
+		"bigint":    "BIGINT",
+		"bool":      "BOOLEAN",
+		"float":     "DOUBLE PRECISION",
+		"decimal":   "NUMERIC(10, 2)",
+		"date":      "DATE",
+		"timestamp": "TIMESTAMP",
+		"json":      "JSONB",
+		"uuid":      "UUID",
+	},
+	"sqlite": {
+		"string":    "TEXT",
+		"text":      "TEXT",
+		"int":       "INTEGER",
+		"bigint":    "INTEGER",
+		"bool":      "INTEGER",
+		"float":     "REAL",
+		"decimal":   "NUMERIC",
+		"date":      "DATE",
+		"timestamp": "DATETIME",
+		"json":      "TEXT",
+		"uuid":      "TEXT",
+	},
+	"mysql": {
+		"string":    "VARCHAR(255)",
+		"text":      "TEXT",
+		"int":       "INT",
+		"bigint":    "BIGINT",
+		"bool":      "TINYINT(1)",
+		"float":     "DOUBLE",
+		"decimal":   "DECIMAL(10, 2)",
+		"date":      "DATE",
+		"timestamp": "TIMESTAMP",
+		"json":      "JSON",
+		"uuid":      "CHAR(36)",
+	},
+}
+
+// columnAliases are the other names accepted for the column types
+var columnAliases = map[string]string{
+	"varchar":  "string",
+	"integer":  "int",
+	"boolean":  "bool",
+	"double":   "float",
+	"numeric":  "decimal",
+	"datetime": "timestamp",
+	"time":     "timestamp",
+}
+
+// parseColumns parses the column arguments of make migration
+func parseColumns(specs []string) ([]column, error) {
+	var columns []column
+	for _, spec := range specs {
+		parts := strings.Split(spec, ":")
+		if len(parts) < 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid column %q, expected name:type[:unique|:index|:nullable]", spec)
+		}
+
+		col := column{name: strings.ToLower(parts[0]), kind: strings.ToLower(parts[1])}
+		if alias, ok := columnAliases[col.kind]; ok {
+			col.kind = alias
+		}
+		if _, ok := columnTypes["postgres"][col.kind]; !ok {
+			return nil, fmt.Errorf("unknown type %q of column %s", parts[1], col.name)
+		}
+		for _, modifier := range parts[2:] {
+			switch strings.ToLower(modifier) {
+			case "unique":
+				col.unique = true
+			case "index":
+				col.index = true
+			case "nullable", "null":
+				col.nullable = true
+			default:
+				return nil, fmt.Errorf("unknown modifier %q of column %s", modifier, col.name)
+			}
+		}
+		columns = append(columns, col)
+	}
+	return columns, nil
+}
+
+// tableOptions are the columns a table gets besides its own
+type tableOptions struct {
+	timestamps  bool // created_at and updated_at
+	softDeletes bool // deleted_at
+	uuidPK      bool // a uuid id set by the application instead of an auto-incremented one
+}
+
+// createTableSQL returns the up and down migrations creating a table with an id, the columns
+// and the columns of the options
+func createTableSQL(dialect, table string, columns []column, options tableOptions) (string, string, error) {
+	types, ok := columnTypes[dialect]
+	if !ok {
+		return "", "", fmt.Errorf("make migration --create does not support the %s database", dialect)
+	}
+
+	var up strings.Builder
+	switch dialect {
+	case "postgres":
+		lines := []string{"id BIGSERIAL PRIMARY KEY"}
+		if options.uuidPK {
+			lines = []string{"id UUID PRIMARY KEY"}
+		}
+		for _, col := range columns {
+			line := col.name + " " + types[col.kind] + notNull(col)
+			if col.unique {
+				line += " UNIQUE"
+			}
+			lines = append(lines, line)
+		}
+		if options.timestamps {
+			lines = append(lines, "created_at TIMESTAMP NOT NULL DEFAULT NOW()", "updated_at TIMESTAMP NOT NULL DEFAULT NOW()")
+		}
+		if options.softDeletes {
+			lines = append(lines, "deleted_at TIMESTAMP")
+		}
+
+		_, _ = fmt.Fprintf(&up, "CREATE TABLE %s (\n    %s\n);\n", table, strings.Join(lines, ",\n    "))
+		writeIndexes(&up, table, columns, options)
+		if options.timestamps {
+			_, _ = fmt.Fprintf(&up, `
+-- keep updated_at up to date
+CREATE OR REPLACE FUNCTION trigger_set_timestamp()
+RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at = NOW();
+RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON %s
+    FOR EACH ROW
+    EXECUTE PROCEDURE trigger_set_timestamp();
+`, table)
+		}
+		return up.String(), fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE;\n", table), nil
+
+	case "sqlite":
+		lines := []string{"id INTEGER PRIMARY KEY AUTOINCREMENT"}
+		if options.uuidPK {
+			lines = []string{"id TEXT PRIMARY KEY"}
+		}
+		for _, col := range columns {
+			line := col.name + " " + types[col.kind] + notNull(col)
+			if col.unique {
+				line += " UNIQUE"
+			}
+			lines = append(lines, line)
+		}
+		if options.timestamps {
+			lines = append(lines, "created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP", "updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP")
+		}
+		if options.softDeletes {
+			lines = append(lines, "deleted_at DATETIME")
+		}
+
+		_, _ = fmt.Fprintf(&up, "CREATE TABLE %s (\n    %s\n);\n", table, strings.Join(lines, ",\n    "))
+		writeIndexes(&up, table, columns, options)
+		if options.timestamps {
+			_, _ = fmt.Fprintf(&up, `
+-- keep updated_at up to date
+CREATE TRIGGER %[1]s_set_timestamp
+    AFTER UPDATE ON %[1]s
+    FOR EACH ROW
+BEGIN
+    UPDATE %[1]s SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id;
+END;
+`, table)
+		}
+		return up.String(), fmt.Sprintf("DROP TABLE IF EXISTS %s;\n", table), nil
+
+	default:
+		lines := []string{"`id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT"}
+		if options.uuidPK {
+			lines = []string{"`id` CHAR(36) NOT NULL"}
+		}
+		for _, col := range columns {
+			lines = append(lines, "`"+col.name+"` "+types[col.kind]+notNull(col))
+		}
+		if options.timestamps {
+			lines = append(lines,
+				"`created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP",
+				"`updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP")
+		}
+		if options.softDeletes {
+			lines = append(lines, "`deleted_at` TIMESTAMP NULL")
+		}
+		lines = append(lines, "PRIMARY KEY (`id`)")
+		for _, col := range columns {
+			switch {
+			case col.unique:
+				lines = append(lines, fmt.Sprintf("UNIQUE KEY `%s_%s_unique` (`%s`)", table, col.name, col.name))
+			case col.index:
+				lines = append(lines, fmt.Sprintf("KEY `%s_%s_index` (`%s`)", table, col.name, col.name))
+			}
+		}
+		if options.softDeletes {
+			lines = append(lines, fmt.Sprintf("KEY `%s_deleted_at_index` (`deleted_at`)", table))
+		}
+
+		_, _ = fmt.Fprintf(&up, "CREATE TABLE `%s` (\n    %s\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;\n", table, strings.Join(lines, ",\n    "))
+		return up.String(), fmt.Sprintf("DROP TABLE IF EXISTS `%s`;\n", table), nil
+	}
+}
+
+// writeIndexes writes the CREATE INDEX statements of the indexed columns, the unique ones
+// have theirs already
+func writeIndexes(up *strings.Builder, table string, columns []column, options tableOptions) {
+	for _, col := range columns {
+		if col.index && !col.unique {
+			_, _ = fmt.Fprintf(up, "\nCREATE INDEX %s_%s_index ON %s (%s);\n", table, col.name, table, col.name)
+		}
+	}
+	if options.softDeletes {
+		_, _ = fmt.Fprintf(up, "\nCREATE INDEX %s_deleted_at_index ON %s (deleted_at);\n", table, table)
+	}
+}
+
+// notNull returns the NOT NULL of the columns without the nullable modifier
+func notNull(col column) string {
+	if col.nullable {
+		return ""
+	}
+	return " NOT NULL"
+}
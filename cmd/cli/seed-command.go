@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"github.com/fatih/color"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// seedCommand runs the seeders of the application
+func seedCommand() *command {
+	cmd := newCommand("db:seed", "[name...]", "run the seeders of the app in order, or only the named ones")
+	cmd.app = true
+	cmd.run = func(args []string) error {
+		if err := doSeed(args...); err != nil {
+			return err
+		}
+		color.Yellow("seeders complete!")
+		return nil
+	}
+	return cmd
+}
+
+// doSeed runs the seeders of the application. They are registered in the application code,
+// so the application itself is run with the db:seed argument, see Sauri.SeedCommand.
+func doSeed(names ...string) error {
+	return runApp("the seeders", append([]string{"db:seed"}, names...)...)
+}
+
+// runApp runs the main package of the application with arguments, for the commands the
+// application code handles itself
+func runApp(what string, args ...string) error {
+	if !fileExists(filepath.Join(sauri2.RootPath, "cmd", "server")) {
+		return errors.New("cmd/server not found, " + what + " run through the application main package")
+	}
+
+	cmd := exec.Command("go", append([]string{"run", "./cmd/server"}, args...)...)
+	cmd.Dir = sauri2.RootPath
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"errors"
+	"github.com/fatih/color"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// rebuildExtensions are the files the application is rebuilt for, the others the watcher
+// sees only restart it
+var rebuildExtensions = map[string]bool{".go": true, ".mod": true, ".sum": true}
+
+// restartExtensions are the templates and the config the application reads when it starts
+var restartExtensions = map[string]bool{".gohtml": true, ".jet": true, ".html": true, ".yaml": true, ".yml": true}
+
+// skippedDirs are the folders the watcher does not go into, along with the hidden ones
+var skippedDirs = map[string]bool{"tmp": true, "vendor": true, "node_modules": true, "logs": true, "storage": true}
+
+// serveCommand builds and runs the application, restarting it when its files change
+func serveCommand() *command {
+	cmd := newCommand("serve", "[-- app arguments...]",
+		"build and run cmd/server, rebuilding and restarting it when a .go, template or config file changes")
+	cmd.app = true
+	interval := cmd.flags.Duration("interval", 500*time.Millisecond, "how often the files are checked for changes")
+	cmd.run = func(args []string) error {
+		if *interval <= 0 {
+			return usageErrorf("--interval must be positive")
+		}
+		return doServe(*interval, args)
+	}
+	return cmd
+}
+
+// devServer is the application run by serve
+type devServer struct {
+	binary string
+	args   []string
+	cmd    *exec.Cmd
+	// done receives the result of the process once it exits
+	done chan error
+}
+
+// doServe builds and runs the application until an interrupt, checking the files every
+// interval. The running application is kept when a build fails.
+func doServe(interval time.Duration, args []string) error {
+	if !fileExists(filepath.Join(sauri2.RootPath, "cmd", "server")) {
+		return errors.New("cmd/server not found, serve builds the application main package")
+	}
+
+	binary := filepath.Join(sauri2.RootPath, "tmp", "sauri-serve")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	server := &devServer{binary: binary, args: args}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	files, err := watchedFiles(sauri2.RootPath)
+	if err != nil {
+		return err
+	}
+	if err := server.build(); err != nil {
+		printError(err)
+	} else if err := server.start(); err != nil {
+		return err
+	}
+	color.Yellow("watching for changes, press Ctrl+C to stop")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-interrupt:
+			server.stop()
+			return nil
+		case err := <-server.done:
+			server.cmd, server.done = nil, nil
+			if err != nil {
+				color.Red("the application exited: %v", err)
+			} else {
+				color.Yellow("the application exited")
+			}
+		case <-ticker.C:
+			current, err := watchedFiles(sauri2.RootPath)
+			if err != nil {
+				return err
+			}
+			changed, rebuild := changedFiles(files, current)
+			files = current
+			if len(changed) == 0 {
+				continue
+			}
+
+			color.Yellow("%s changed", strings.Join(changed, ", "))
+			if rebuild {
+				if err := server.build(); err != nil {
+					printError(err)
+					continue
+				}
+			} else if !fileExists(binary) {
+				continue
+			}
+			server.stop()
+			if err := server.start(); err != nil {
+				printError(err)
+			}
+		}
+	}
+}
+
+// build compiles cmd/server, the compiler errors are printed as they come
+func (d *devServer) build() error {
+	color.Yellow("building cmd/server...")
+	cmd := exec.Command("go", "build", "-o", d.binary, "./cmd/server")
+	cmd.Dir = sauri2.RootPath
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return errors.New("the build failed, waiting for changes")
+	}
+	return nil
+}
+
+// start runs the binary in the root of the application
+func (d *devServer) start() error {
+	cmd := exec.Command(d.binary, d.args...)
+	cmd.Dir = sauri2.RootPath
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	d.cmd, d.done = cmd, done
+	color.Green("the application is running")
+	return nil
+}
+
+// stop interrupts the application so that it shuts down gracefully, and kills it when it is
+// still running after 10 seconds
+func (d *devServer) stop() {
+	if d.cmd == nil {
+		return
+	}
+	// windows has no interrupt to send to another process
+	if runtime.GOOS == "windows" || d.cmd.Process.Signal(os.Interrupt) != nil {
+		_ = d.cmd.Process.Kill()
+	}
+	select {
+	case <-d.done:
+	case <-time.After(10 * time.Second):
+		_ = d.cmd.Process.Kill()
+		<-d.done
+	}
+	d.cmd, d.done = nil, nil
+}
+
+// watchedFiles returns the modification times of the files serve watches
+func watchedFiles(root string) (map[string]time.Time, error) {
+	files := make(map[string]time.Time)
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// a file removed during the walk
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			if path != root && (skippedDirs[name] || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !rebuildExtensions[filepath.Ext(name)] && !restartExtensions[filepath.Ext(name)] && !strings.HasPrefix(name, ".env") {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		files[path] = info.ModTime()
+		return nil
+	})
+	return files, err
+}
+
+// changedFiles returns the files added, changed or removed, relative to the root of the
+// application, and whether one of them needs a build
+func changedFiles(previous, current map[string]time.Time) ([]string, bool) {
+	var changed []string
+	rebuild := false
+	add := func(path string) {
+		if rel, err := filepath.Rel(sauri2.RootPath, path); err == nil {
+			path = rel
+		}
+		changed = append(changed, path)
+		if rebuildExtensions[filepath.Ext(path)] {
+			rebuild = true
+		}
+	}
+	for path, modTime := range current {
+		if before, ok := previous[path]; !ok || !before.Equal(modTime) {
+			add(path)
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			add(path)
+		}
+	}
+	sort.Strings(changed)
+	return changed, rebuild
+}
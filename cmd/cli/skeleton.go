@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// skeletonRoot is the embedded minimal skeleton used by `sauri new --minimal`
+const skeletonRoot = "templates/skeleton"
+
+// copySkeleton writes the embedded minimal skeleton into the appName folder without any
+// network access. Every file is stored with an extra .txt extension so that it is not
+// compiled with the cli, and gitignore.txt becomes .gitignore.
+func copySkeleton(appName string) error {
+	if fileExists(appName) {
+		return fmt.Errorf("%s already exists", appName)
+	}
+
+	return fs.WalkDir(templateFS, skeletonRoot, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath := strings.TrimPrefix(strings.TrimPrefix(filePath, skeletonRoot), "/")
+		target := filepath.Join(appName, filepath.FromSlash(relPath))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		target = strings.TrimSuffix(target, ".txt")
+		if path.Base(relPath) == "gitignore.txt" {
+			target = filepath.Join(filepath.Dir(target), ".gitignore")
+		}
+
+		content, err := templateFS.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		data := strings.ReplaceAll(string(content), "${APP_NAME}", appName)
+		data = strings.ReplaceAll(data, "${APP_URL}", appURL)
+
+		return copyDataToFile([]byte(data), target)
+	})
+}
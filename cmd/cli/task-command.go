@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v3"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// taskFile is the file of the tasks of an application, at its root
+const taskFile = "sauri.yaml"
+
+// task is a task of sauri.yaml, its deps run before its commands
+type task struct {
+	Description string   `yaml:"description"`
+	Deps        []string `yaml:"deps"`
+	Run         []string `yaml:"run"`
+}
+
+// taskCommand runs the tasks of sauri.yaml. The commands are run without a shell so that a
+// task works the same on every system, which a Makefile does not.
+func taskCommand() *command {
+	cmd := newCommand("task", "[name] [-- args...]", "run a task of sauri.yaml, such as build, run, test or migrate, or list the tasks")
+	cmd.app = true
+	cmd.run = func(args []string) error {
+		tasks, err := loadTasks()
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			listTasks(tasks)
+			return nil
+		}
+		if _, ok := tasks[args[0]]; !ok {
+			return usageErrorf("%s has no task %s", taskFile, args[0])
+		}
+		return newTaskRunner(tasks).run(args[0], args[1:])
+	}
+	return cmd
+}
+
+// loadTasks reads the tasks of sauri.yaml
+func loadTasks() (map[string]*task, error) {
+	content, err := os.ReadFile(filepath.Join(sauri2.RootPath, taskFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s not found, the tasks of the application are defined in it", taskFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Tasks map[string]*task `yaml:"tasks"`
+	}
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", taskFile, err)
+	}
+	for name, t := range file.Tasks {
+		if t == nil || (len(t.Run) == 0 && len(t.Deps) == 0) {
+			return nil, fmt.Errorf("%s: task %s has nothing to run", taskFile, name)
+		}
+		for _, dep := range t.Deps {
+			if _, ok := file.Tasks[dep]; !ok {
+				return nil, fmt.Errorf("%s: task %s depends on the unknown task %s", taskFile, name, dep)
+			}
+		}
+	}
+	return file.Tasks, nil
+}
+
+// listTasks prints the tasks with their description, in name order
+func listTasks(tasks map[string]*task) {
+	names := make([]string, 0, len(tasks))
+	width := 0
+	for name := range tasks {
+		names = append(names, name)
+		width = max(width, len(name))
+	}
+	sort.Strings(names)
+
+	color.Yellow("Tasks of %s:", taskFile)
+	for _, name := range names {
+		color.White("    %-*s  %s", width, name, tasks[name].Description)
+	}
+}
+
+// taskRunner runs tasks, each task once whatever the number of tasks depending on it
+type taskRunner struct {
+	tasks   map[string]*task
+	done    map[string]bool
+	running map[string]bool
+}
+
+// newTaskRunner returns a runner of the tasks
+func newTaskRunner(tasks map[string]*task) *taskRunner {
+	return &taskRunner{
+		tasks:   tasks,
+		done:    make(map[string]bool),
+		running: make(map[string]bool),
+	}
+}
+
+// run runs the deps of a task then its commands, args are added to its last command
+func (r *taskRunner) run(name string, args []string) error {
+	if r.done[name] {
+		return nil
+	}
+	if r.running[name] {
+		return fmt.Errorf("%s: task %s depends on itself", taskFile, name)
+	}
+	r.running[name] = true
+	defer delete(r.running, name)
+
+	t := r.tasks[name]
+	for _, dep := range t.Deps {
+		if err := r.run(dep, nil); err != nil {
+			return err
+		}
+	}
+	for i, line := range t.Run {
+		words, err := splitCommand(line)
+		if err != nil {
+			return fmt.Errorf("%s: task %s: %w", taskFile, name, err)
+		}
+		if i == len(t.Run)-1 {
+			words = append(words, args...)
+		}
+		if err := runTaskCommand(words); err != nil {
+			return fmt.Errorf("task %s: %w", name, err)
+		}
+	}
+	r.done[name] = true
+	return nil
+}
+
+// runTaskCommand runs a command of a task in the folder of the application, sauri runs this
+// binary so that the task does not depend on the PATH
+func runTaskCommand(words []string) error {
+	for i, word := range words {
+		words[i] = os.Expand(word, taskVariable)
+	}
+	color.Yellow("> %s", strings.Join(words, " "))
+
+	name := words[0]
+	if name == "sauri" {
+		executable, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		name = executable
+	}
+	cmd := exec.Command(name, words[1:]...)
+	cmd.Dir = sauri2.RootPath
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// taskVariable returns the value of ${name} in a command: the settings of .env and of the
+// environment, and EXE which is the extension of the binaries of the system
+func taskVariable(name string) string {
+	if name == "EXE" {
+		if runtime.GOOS == "windows" {
+			return ".exe"
+		}
+		return ""
+	}
+	return os.Getenv(name)
+}
+
+// splitCommand splits a command line into its words on spaces, a single or double quoted
+// part is kept in one word. Backslashes are kept as they are, they separate the folders on
+// windows.
+func splitCommand(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	for _, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				word.WriteRune(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", line)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	if len(words) == 0 {
+		return nil, errors.New("empty command")
+	}
+	return words, nil
+}
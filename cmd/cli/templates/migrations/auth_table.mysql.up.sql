@@ -6,7 +6,7 @@ CREATE TABLE `users` (
                          `last_name` varchar(255) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
                          `user_active` int(11) NOT NULL,
                          `email` varchar(255) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
-                         `password` char(60) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+                         `password` varchar(255) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
                          `created_at` timestamp NULL DEFAULT NULL,
                          `updated_at` timestamp NULL DEFAULT NULL,
                          PRIMARY KEY (`id`),
@@ -33,10 +33,11 @@ drop table if exists tokens cascade;
 CREATE TABLE `tokens` (
       `id` int(11) NOT NULL AUTO_INCREMENT,
       `user_id` int(11) unsigned NOT NULL,
-      `name` varchar(255) NOT NULL,
+      `first_name` varchar(255) NOT NULL,
       `email` varchar(255) NOT NULL,
       `token` varchar(255) NOT NULL,
       `token_hash` varbinary(255) DEFAULT NULL,
+      `scopes` varchar(255) NOT NULL DEFAULT '',
       `created_at` datetime NOT NULL DEFAULT current_timestamp(),
       `updated_at` datetime NOT NULL DEFAULT current_timestamp(),
       `expiry` datetime NOT NULL,
@@ -0,0 +1 @@
+drop table if exists remember_tokens; drop table if exists tokens; drop table if exists users;
\ No newline at end of file
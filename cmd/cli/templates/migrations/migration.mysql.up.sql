@@ -0,0 +1,7 @@
+-- CREATE TABLE `some_table` (
+--     `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+--     `some_field` VARCHAR(255) NOT NULL,
+--     `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+--     `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+--     PRIMARY KEY (`id`)
+-- ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
\ No newline at end of file
@@ -0,0 +1 @@
+-- drop table if exists some_table;
\ No newline at end of file
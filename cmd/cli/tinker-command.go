@@ -0,0 +1,17 @@
+package main
+
+// tinkerCommand opens the console of the application, or runs a single console command
+func tinkerCommand() *command {
+	cmd := newCommand("tinker", "[command [arguments...]]",
+		"open the console of the app to query its database and cache, or run one of its commands")
+	cmd.app = true
+	cmd.run = doTinker
+	return cmd
+}
+
+// doTinker runs the application with the tinker argument, the console and the commands it
+// registers live in the application code, see Sauri.ConsoleCommand. The arguments run as a
+// single command, e.g. sauri tinker sql "SELECT count(*) FROM users".
+func doTinker(args []string) error {
+	return runApp("the console commands", append([]string{"tinker"}, args...)...)
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// releasesURL is the GitHub API of the releases of the cli
+const releasesURL = "https://api.github.com/repos/haskekareem/sauri/releases"
+
+// checksumsAsset is the release file listing the sha256 sums of the binaries, in the
+// format of sha256sum
+const checksumsAsset = "checksums.txt"
+
+// release is a GitHub release, with the files attached to it
+type release struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// assetURL returns the download url of a file of the release, empty when it has none
+func (r release) assetURL(name string) string {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset.URL
+		}
+	}
+	return ""
+}
+
+// upgradeCommand replaces the cli by the binary of a newer release
+func upgradeCommand() *command {
+	cmd := newCommand("upgrade", "", "download the latest release of the cli and replace this binary with it")
+	check := cmd.flags.Bool("check", false, "only report whether a newer release exists")
+	tag := cmd.flags.String("version", "", "`tag` of the release to install instead of the latest, e.g. v1.2.0")
+	force := cmd.flags.Bool("force", false, "install the release even when it is not newer")
+	cmd.run = func(args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("upgrade takes no arguments")
+		}
+		return doUpgrade(*tag, *check, *force)
+	}
+	return cmd
+}
+
+// doUpgrade installs a release of the cli. The releases attach a binary for each platform,
+// sauri_<os>_<arch> with .exe on windows, and checksums.txt; the binary is only installed
+// when its sha256 sum matches.
+func doUpgrade(tag string, check, force bool) error {
+	latest, err := fetchRelease(tag)
+	if err != nil {
+		return err
+	}
+
+	newer := compareVersions(latest.TagName, version) > 0
+	if check {
+		if newer {
+			color.Yellow("sauri %s is available, you have %s, run sauri upgrade", latest.TagName, version)
+		} else {
+			color.Green("sauri %s is the latest release", version)
+		}
+		return nil
+	}
+	if !newer && !force {
+		color.Green("sauri %s is up to date, --force installs %s anyway", version, latest.TagName)
+		return nil
+	}
+
+	asset := fmt.Sprintf("sauri_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		asset += ".exe"
+	}
+	binaryURL := latest.assetURL(asset)
+	if binaryURL == "" {
+		return fmt.Errorf("release %s has no binary for %s/%s", latest.TagName, runtime.GOOS, runtime.GOARCH)
+	}
+	checksumsURL := latest.assetURL(checksumsAsset)
+	if checksumsURL == "" {
+		return fmt.Errorf("release %s has no %s, the binary cannot be verified", latest.TagName, checksumsAsset)
+	}
+
+	sum, err := fetchChecksum(checksumsURL, asset)
+	if err != nil {
+		return err
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return err
+	}
+
+	// the new binary is written next to the current one so that the rename stays on the same
+	// file system
+	color.Yellow("downloading %s %s...", asset, latest.TagName)
+	downloaded := executable + ".new"
+	if err := download(binaryURL, downloaded, sum); err != nil {
+		_ = os.Remove(downloaded)
+		return err
+	}
+	if err := replaceExecutable(executable, downloaded); err != nil {
+		_ = os.Remove(downloaded)
+		return err
+	}
+
+	color.Green("sauri was upgraded from %s to %s", version, latest.TagName)
+	return nil
+}
+
+// fetchRelease returns a release by tag, the latest one when tag is empty
+func fetchRelease(tag string) (release, error) {
+	url := releasesURL + "/latest"
+	if tag != "" {
+		url = releasesURL + "/tags/" + tag
+	}
+
+	resp, err := githubGet(url, 30*time.Second)
+	if err != nil {
+		return release{}, fmt.Errorf("could not reach the releases: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	var latest release
+	if err := json.NewDecoder(resp.Body).Decode(&latest); err != nil {
+		return release{}, fmt.Errorf("invalid release response: %w", err)
+	}
+	return latest, nil
+}
+
+// fetchChecksum returns the sha256 sum of a file of the checksums
+func fetchChecksum(url, name string) (string, error) {
+	resp, err := githubGet(url, 30*time.Second)
+	if err != nil {
+		return "", fmt.Errorf("could not download %s: %w", checksumsAsset, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// sha256sum marks the binary files with a star
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s has no sum for %s", checksumsAsset, name)
+}
+
+// download writes a file to path and checks its sha256 sum
+func download(url, path, sum string) error {
+	resp, err := githubGet(url, 10*time.Minute)
+	if err != nil {
+		return fmt.Errorf("could not download the binary: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(file, hash), resp.Body); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if got := hex.EncodeToString(hash.Sum(nil)); got != sum {
+		return fmt.Errorf("the checksum of the binary does not match, expected %s and got %s", sum, got)
+	}
+	return nil
+}
+
+// replaceExecutable moves the downloaded binary over the running one. Windows does not let a
+// running binary be overwritten but lets it be renamed, so it is moved aside first.
+func replaceExecutable(executable, downloaded string) error {
+	if runtime.GOOS == "windows" {
+		old := executable + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(executable, old); err != nil {
+			return err
+		}
+		if err := os.Rename(downloaded, executable); err != nil {
+			_ = os.Rename(old, executable)
+			return err
+		}
+		return nil
+	}
+	return os.Rename(downloaded, executable)
+}
+
+// githubGet sends a GET request to GitHub, with GITHUB_TOKEN when it is set to raise the
+// rate limit, and fails on a response other than 200
+func githubGet(url string, timeout time.Duration) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "sauri-cli/"+version)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := (&http.Client{Timeout: timeout}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, errors.New("release not found")
+		}
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// compareVersions compares two versions such as v1.2.0 and 1.10.3 part by part, it returns
+// a positive number when a is newer than b
+func compareVersions(a, b string) int {
+	partsA := versionParts(a)
+	partsB := versionParts(b)
+	for i := 0; i < max(len(partsA), len(partsB)); i++ {
+		var x, y int
+		if i < len(partsA) {
+			x = partsA[i]
+		}
+		if i < len(partsB) {
+			y = partsB[i]
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
+// versionParts returns the numbers of a version, without the v prefix and the pre-release
+// suffix
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, _, _ = strings.Cut(v, "-")
+	var parts []int
+	for _, part := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
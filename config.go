@@ -0,0 +1,99 @@
+package sauri
+
+import (
+	"context"
+	"fmt"
+	"github.com/haskekareem/sauri/config"
+	"github.com/haskekareem/sauri/logging"
+	"github.com/haskekareem/sauri/validator"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ValidateConfig checks that the settings the enabled features depend on are set, so that a
+// missing one stops the application at startup rather than at the first request. The
+// application adds its own with CONFIG_REQUIRED, a comma separated list. A missing setting
+// gives a *config.MissingError.
+func (s *Sauri) ValidateConfig() error {
+	required := s.Config.GetStrings("CONFIG_REQUIRED")
+	if s.Config.GetBool("DATABASE_USE", false) {
+		required = append(required, "DATABASE_TYPE", "DATABASE_HOST", "DATABASE_PORT", "DATABASE_USER", "DATABASE_NAME")
+	}
+	if s.Config.Get("CACHE") == "redis" || s.Config.Get("SESSION_STORE_TYPE") == "redis" {
+		required = append(required, "REDIS_HOST")
+	}
+	if err := s.Config.Require(required...); err != nil {
+		return err
+	}
+
+	if key := s.Config.Get("KEY"); key != "" && len(key) != 16 && len(key) != 24 && len(key) != 32 {
+		return fmt.Errorf("KEY must be 16, 24 or 32 characters long, it is %d", len(key))
+	}
+	return nil
+}
+
+// watchConfig applies the settings safe to change at runtime when the process gets SIGHUP,
+// and when the .env files or config/*.yaml change if CONFIG_WATCH_INTERVAL (seconds) is
+// set. LOG_LEVEL and DEBUG_MODE are applied by the framework, CONFIG_RELOADABLE lists the
+// settings of the application, read on every use or applied by hooks registered with
+// s.Config.OnChange. Everything else needs a restart. CONFIG_WATCH=false turns it off.
+//
+// A reload of DEBUG_MODE changes IsDebugMode, the log level, the parsing of the Go templates
+// and the validation metadata cache. What is set up with the server still needs a restart:
+// the debug routes (/sauri/routes, /sauri/schedule, the mail previews), the request logging,
+// the development mode of Jet and the fault injection of CHAOS_ENABLED.
+func (s *Sauri) watchConfig() {
+	if !s.Config.GetBool("CONFIG_WATCH", true) {
+		return
+	}
+	s.Config.OnChange("LOG_LEVEL", func(string) {
+		s.setLogLevel()
+	})
+	s.Config.OnChange("DEBUG_MODE", func(value string) {
+		debug, _ := strconv.ParseBool(value)
+		s.debug.Store(debug)
+		validator.Metadata.SetDevMode(debug)
+		if s.Renderer != nil {
+			s.Renderer.SetDevelopmentMode(debug)
+		}
+		s.setLogLevel()
+	})
+	s.Config.Reloadable(s.Config.GetStrings("CONFIG_RELOADABLE")...)
+
+	files := config.EnvFiles(s.RootPath)
+	yamlFiles, _ := filepath.Glob(filepath.Join(s.RootPath, "config", "*.y*ml"))
+	files = append(files, yamlFiles...)
+	interval := s.Config.GetDuration("CONFIG_WATCH_INTERVAL", time.Second, 0)
+
+	s.OnStart(func(ctx context.Context) error {
+		go s.Config.Watch(s.Context(), interval, func(changed []string, err error) {
+			if err != nil {
+				s.log().Error("cannot reload the configuration", "error", err)
+				return
+			}
+			if len(changed) > 0 {
+				s.log().Info("configuration reloaded", "changed", changed)
+			}
+		}, files...)
+		return nil
+	})
+}
+
+// IsDebugMode reports whether the application is in debug mode, DEBUG_MODE as last reloaded
+func (s *Sauri) IsDebugMode() bool {
+	return s.debug.Load()
+}
+
+// setLogLevel applies LOG_LEVEL, defaulting to debug in debug mode and info otherwise
+func (s *Sauri) setLogLevel() {
+	level, err := logging.ParseLevel(s.Config.Get("LOG_LEVEL"))
+	if err != nil {
+		level = slog.LevelInfo
+		if s.IsDebugMode() {
+			level = slog.LevelDebug
+		}
+	}
+	s.logLevel.Set(level)
+}
@@ -0,0 +1,205 @@
+// Package config gives typed access to the settings of an application. The real environment
+// and the .env files come first, then the config/*.yaml files, then the defaults of the callers.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config reads settings from the environment. A nil *Config reads the environment only.
+type Config struct {
+	mu         sync.RWMutex
+	sources    map[string]string // the yaml file each setting came from
+	reloadable map[string][]Hook // the settings a reload may change, with their hooks
+}
+
+// New returns an empty configuration
+func New() *Config {
+	return &Config{sources: map[string]string{}}
+}
+
+// LoadYAML reads the *.yaml and *.yml files of dir in name order. Nested keys are joined with
+// underscores and upper cased, so that database: {host: db} is DATABASE_HOST. Settings already
+// in the environment win, the others are exported to it so that the packages reading the
+// environment see them too. A missing dir is not an error.
+func (c *Config) LoadYAML(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.y*ml"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, file := range files {
+		if ext := filepath.Ext(file); ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var tree map[string]any
+		if err := yaml.Unmarshal(content, &tree); err != nil {
+			return fmt.Errorf("cannot parse %s: %w", file, err)
+		}
+		values := map[string]string{}
+		flatten("", tree, values)
+		for key, value := range values {
+			if _, set := os.LookupEnv(key); set {
+				continue
+			}
+			if err := setLoaded(key, value); err != nil {
+				return err
+			}
+			c.sources[key] = file
+		}
+	}
+	return nil
+}
+
+// flatten adds the leaves of a yaml tree to values, lists become comma separated values
+func flatten(prefix string, node any, values map[string]string) {
+	switch n := node.(type) {
+	case map[string]any:
+		for key, child := range n {
+			name := strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+			if prefix != "" {
+				name = prefix + "_" + name
+			}
+			flatten(name, child, values)
+		}
+	case []any:
+		items := make([]string, 0, len(n))
+		for _, item := range n {
+			items = append(items, fmt.Sprint(item))
+		}
+		values[prefix] = strings.Join(items, ",")
+	case nil:
+		values[prefix] = ""
+	default:
+		values[prefix] = fmt.Sprint(n)
+	}
+}
+
+// Lookup returns a setting and whether it is set
+func (c *Config) Lookup(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+// Get returns a setting, the default when it is empty
+func (c *Config) Get(key string, def ...string) string {
+	if value := os.Getenv(key); value != "" || len(def) == 0 {
+		return value
+	}
+	return def[0]
+}
+
+// GetInt returns an integer setting, the default when it is empty or not a number
+func (c *Config) GetInt(key string, def int) int {
+	value, err := strconv.Atoi(strings.TrimSpace(os.Getenv(key)))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// GetFloat returns a number setting, the default when it is empty or not a number
+func (c *Config) GetFloat(key string, def float64) float64 {
+	value, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv(key)), 64)
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// GetBool returns a boolean setting, the default when it is empty or not a boolean
+func (c *Config) GetBool(key string, def bool) bool {
+	value, err := strconv.ParseBool(strings.TrimSpace(os.Getenv(key)))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// GetDuration returns a duration setting such as 1m30s. A plain number is taken in the given
+// unit, e.g. GetDuration("JOBS_TIMEOUT", time.Second, 0) reads 30 as 30 seconds.
+func (c *Config) GetDuration(key string, unit, def time.Duration) time.Duration {
+	value := strings.TrimSpace(os.Getenv(key))
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(n * float64(unit))
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return d
+	}
+	return def
+}
+
+// GetStrings returns a comma separated setting, without the empty entries
+func (c *Config) GetStrings(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Set changes a setting for the running process
+func (c *Config) Set(key, value string) error {
+	if c != nil {
+		c.mu.Lock()
+		delete(c.sources, key)
+		c.mu.Unlock()
+	}
+	return os.Setenv(key, value)
+}
+
+// Source returns the yaml file a setting came from, empty for the environment
+func (c *Config) Source(key string) string {
+	if c == nil {
+		return ""
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sources[key]
+}
+
+// MissingError lists the required settings that are not set
+type MissingError struct {
+	Keys []string
+}
+
+func (e *MissingError) Error() string {
+	return "missing required settings: " + strings.Join(e.Keys, ", ")
+}
+
+// Require returns a *MissingError when any of the settings is empty
+func (c *Config) Require(keys ...string) error {
+	var missing []string
+	for _, key := range keys {
+		if strings.TrimSpace(os.Getenv(key)) == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingError{Keys: missing}
+	}
+	return nil
+}
+
+// IsMissing reports whether err is a *MissingError
+func IsMissing(err error) bool {
+	var missing *MissingError
+	return errors.As(err, &missing)
+}
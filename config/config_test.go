@@ -0,0 +1,84 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetters(t *testing.T) {
+	t.Setenv("CFG_INT", "42")
+	t.Setenv("CFG_BOOL", "true")
+	t.Setenv("CFG_BAD", "nope")
+	t.Setenv("CFG_SECONDS", "30")
+	t.Setenv("CFG_DURATION", "1m30s")
+	t.Setenv("CFG_LIST", "a, b,,c")
+
+	var c *Config // a nil configuration reads the environment
+	if got := c.Get("CFG_MISSING", "fallback"); got != "fallback" {
+		t.Errorf("Get default: got %q", got)
+	}
+	if got := c.GetInt("CFG_INT", 0); got != 42 {
+		t.Errorf("GetInt: got %d", got)
+	}
+	if got := c.GetInt("CFG_BAD", 7); got != 7 {
+		t.Errorf("GetInt default: got %d", got)
+	}
+	if !c.GetBool("CFG_BOOL", false) || !c.GetBool("CFG_BAD", true) {
+		t.Error("GetBool")
+	}
+	if got := c.GetDuration("CFG_SECONDS", time.Second, 0); got != 30*time.Second {
+		t.Errorf("GetDuration number: got %s", got)
+	}
+	if got := c.GetDuration("CFG_DURATION", time.Second, 0); got != 90*time.Second {
+		t.Errorf("GetDuration: got %s", got)
+	}
+	if got := c.GetStrings("CFG_LIST"); len(got) != 3 || got[2] != "c" {
+		t.Errorf("GetStrings: got %v", got)
+	}
+}
+
+func TestLoadYAML(t *testing.T) {
+	dir := t.TempDir()
+	content := "cfgtest:\n  host: db.local\n  port: 5432\n  tags: [a, b]\n"
+	if err := os.WriteFile(filepath.Join(dir, "database.yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CFGTEST_PORT", "6543") // the environment wins
+	t.Cleanup(func() {
+		_ = os.Unsetenv("CFGTEST_HOST")
+		_ = os.Unsetenv("CFGTEST_TAGS")
+	})
+
+	c := New()
+	if err := c.LoadYAML(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Get("CFGTEST_HOST"); got != "db.local" {
+		t.Errorf("CFGTEST_HOST: got %q", got)
+	}
+	if got := c.GetInt("CFGTEST_PORT", 0); got != 6543 {
+		t.Errorf("CFGTEST_PORT: got %d", got)
+	}
+	if got := c.GetStrings("CFGTEST_TAGS"); len(got) != 2 {
+		t.Errorf("CFGTEST_TAGS: got %v", got)
+	}
+	if c.Source("CFGTEST_HOST") == "" || c.Source("CFGTEST_PORT") != "" {
+		t.Error("wrong sources")
+	}
+	if err := c.LoadYAML(filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("a missing directory: %v", err)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	t.Setenv("CFG_SET", "x")
+	err := New().Require("CFG_SET", "CFG_UNSET_1", "CFG_UNSET_2")
+	if !IsMissing(err) || len(err.(*MissingError).Keys) != 2 {
+		t.Errorf("got %v", err)
+	}
+	if err := New().Require("CFG_SET"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// loaded holds the settings exported from the env and yaml files. The other settings of the
+// environment come from the process environment, no file overrides them.
+var loaded = struct {
+	sync.Mutex
+	keys map[string]bool
+}{keys: map[string]bool{}}
+
+// fromEnvironment reports whether a setting was set by the process environment
+func fromEnvironment(key string) bool {
+	if _, set := os.LookupEnv(key); !set {
+		return false
+	}
+	loaded.Lock()
+	defer loaded.Unlock()
+	return !loaded.keys[key]
+}
+
+// setLoaded exports a setting read from a file
+func setLoaded(key, value string) error {
+	loaded.Lock()
+	loaded.keys[key] = true
+	loaded.Unlock()
+	return os.Setenv(key, value)
+}
+
+// EnvFiles returns the env files of dir in loading order: .env, .env.local, .env.<APP_ENV>
+// and .env.<APP_ENV>.local. APP_ENV comes from the environment or the .env file, .env.local
+// is skipped when it is testing so that the tests do not depend on the machine. The layers
+// are only listed when they exist, .env always is.
+func EnvFiles(dir string) []string {
+	base := filepath.Join(dir, ".env")
+	appEnv := os.Getenv("APP_ENV")
+	if appEnv == "" {
+		if file, err := os.Open(base); err == nil {
+			values, _ := ParseEnv(file)
+			_ = file.Close()
+			appEnv = values["APP_ENV"]
+		}
+	}
+
+	layers := []string{base + ".local"}
+	if appEnv == "testing" {
+		layers = nil
+	}
+	if appEnv != "" {
+		layers = append(layers, base+"."+appEnv, base+"."+appEnv+".local")
+	}
+
+	files := []string{base}
+	for _, layer := range layers {
+		if _, err := os.Stat(layer); err == nil {
+			files = append(files, layer)
+		}
+	}
+	return files
+}
+
+// LoadEnv reads the env files in order and exports their settings, later files win. The
+// settings of the process environment are never overridden.
+func LoadEnv(files ...string) error {
+	for _, name := range files {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		values, err := ParseEnv(file)
+		_ = file.Close()
+		if err != nil {
+			return fmt.Errorf("cannot parse %s: %w", name, err)
+		}
+		if err := SetEnv(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetEnv exports settings read from a file, except the ones of the process environment
+func SetEnv(values map[string]string) error {
+	for key, value := range values {
+		if fromEnvironment(key) {
+			continue
+		}
+		if err := setLoaded(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseEnv reads the KEY=value lines of an env file. Blank lines and # comments are skipped
+// and the export prefix of shell files is allowed. Values may be quoted: single quotes keep
+// the value as is, double quotes allow \n, \t, \", \\ and \$ escapes and span several lines.
+// ${VAR}, ${VAR:-default} and $VAR are expanded in unquoted and double quoted values, from the
+// settings above in the file and then from the environment.
+func ParseEnv(r io.Reader) (map[string]string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	lookup := func(name string) (string, bool) {
+		if value, ok := values[name]; ok {
+			return value, true
+		}
+		return os.LookupEnv(name)
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		number := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "export "); ok {
+			line = strings.TrimSpace(rest)
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated single quote", number)
+			}
+			values[key] = value[1 : end+1]
+		case strings.HasPrefix(value, `"`):
+			raw := value[1:]
+			end := closingQuote(raw)
+			for end < 0 && i+1 < len(lines) {
+				i++
+				raw += "\n" + lines[i]
+				end = closingQuote(raw)
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated double quote", number)
+			}
+			if values[key], err = expand(raw[:end], true, lookup); err != nil {
+				return nil, fmt.Errorf("line %d: %w", number, err)
+			}
+		default:
+			// an inline comment needs a space before the #
+			if at := strings.Index(value, " #"); at >= 0 {
+				value = strings.TrimSpace(value[:at])
+			}
+			if values[key], err = expand(value, false, lookup); err != nil {
+				return nil, fmt.Errorf("line %d: %w", number, err)
+			}
+		}
+	}
+	return values, nil
+}
+
+// closingQuote returns the index of the unescaped double quote ending a value, -1 if none
+func closingQuote(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// escapes are the characters a backslash stands for in double quoted values
+var escapes = map[byte]byte{'n': '\n', 'r': '\r', 't': '\t', '"': '"', '\\': '\\', '$': '$'}
+
+// expand replaces the variables of a value, and the escapes when asked for
+func expand(s string, unescape bool, lookup func(string) (string, bool)) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && unescape && i+1 < len(s):
+			if escaped, ok := escapes[s[i+1]]; ok {
+				b.WriteByte(escaped)
+				i++
+				continue
+			}
+			b.WriteByte(c)
+		case c == '$' && i+1 < len(s) && s[i+1] == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return "", errors.New("unterminated ${")
+			}
+			name, def, _ := strings.Cut(s[i+2:i+end], ":-")
+			if value, ok := lookup(name); ok && value != "" {
+				b.WriteString(value)
+			} else {
+				b.WriteString(def)
+			}
+			i += end
+		case c == '$' && i+1 < len(s) && isNameByte(s[i+1], true):
+			end := i + 1
+			for end < len(s) && isNameByte(s[end], false) {
+				end++
+			}
+			value, _ := lookup(s[i+1 : end])
+			b.WriteString(value)
+			i = end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// isNameByte reports whether c may be part of a variable name, digits cannot start one
+func isNameByte(c byte, first bool) bool {
+	return c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || !first && c >= '0' && c <= '9'
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadForTest exports settings as if read from a file, they are removed after the test
+func loadForTest(t *testing.T, values map[string]string) {
+	t.Helper()
+	if err := SetEnv(values); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		loaded.Lock()
+		defer loaded.Unlock()
+		for key := range values {
+			delete(loaded.keys, key)
+			_ = os.Unsetenv(key)
+		}
+	})
+}
+
+func TestParseEnv(t *testing.T) {
+	t.Setenv("PARSE_HOME", "/home/app")
+	content := `# comment
+
+export A = 1
+B=x=y # inline comment
+C=a#b
+SINGLE='kept ${A} \n as is'
+DOUBLE="line\none \"quoted\" \$A"
+MULTI="first
+second"
+EXPANDED=${A}-$A-${PARSE_HOME}/data
+DEFAULT=${PARSE_MISSING:-fallback}
+not a setting
+`
+	values, err := ParseEnv(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"A":        "1",
+		"B":        "x=y",
+		"C":        "a#b",
+		"SINGLE":   `kept ${A} \n as is`,
+		"DOUBLE":   "line\none \"quoted\" $A",
+		"MULTI":    "first\nsecond",
+		"EXPANDED": "1-1-/home/app/data",
+		"DEFAULT":  "fallback",
+	}
+	if len(values) != len(want) {
+		t.Errorf("got %v", values)
+	}
+	for key, value := range want {
+		if values[key] != value {
+			t.Errorf("%s: got %q, want %q", key, values[key], value)
+		}
+	}
+
+	if _, err := ParseEnv(strings.NewReader("A=\"open\n")); err == nil {
+		t.Error("an unterminated quote must fail")
+	}
+}
+
+func TestEnvFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".env":               "APP_ENV=staging\n",
+		".env.local":         "",
+		".env.staging":       "",
+		".env.testing":       "",
+		".env.testing.local": "",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := strings.Join(EnvFiles(dir), ",")
+	want := strings.Join([]string{filepath.Join(dir, ".env"), filepath.Join(dir, ".env.local"), filepath.Join(dir, ".env.staging")}, ",")
+	if got != want {
+		t.Errorf("staging: got %s", got)
+	}
+
+	// the environment wins over .env, .env.local is skipped when testing
+	t.Setenv("APP_ENV", "testing")
+	got = strings.Join(EnvFiles(dir), ",")
+	want = strings.Join([]string{filepath.Join(dir, ".env"), filepath.Join(dir, ".env.testing"), filepath.Join(dir, ".env.testing.local")}, ",")
+	if got != want {
+		t.Errorf("testing: got %s", got)
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	dir := t.TempDir()
+	base, local := filepath.Join(dir, ".env"), filepath.Join(dir, ".env.local")
+	if err := os.WriteFile(base, []byte("LOADENV_A=base\nLOADENV_B=base\nLOADENV_REAL=base\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(local, []byte("LOADENV_B=local-${LOADENV_A}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("LOADENV_REAL", "real")
+	t.Cleanup(func() {
+		loaded.Lock()
+		defer loaded.Unlock()
+		for _, key := range []string{"LOADENV_A", "LOADENV_B"} {
+			delete(loaded.keys, key)
+			_ = os.Unsetenv(key)
+		}
+	})
+
+	if err := LoadEnv(base, local); err != nil {
+		t.Fatal(err)
+	}
+	if got := os.Getenv("LOADENV_A"); got != "base" {
+		t.Errorf("LOADENV_A: got %q", got)
+	}
+	if got := os.Getenv("LOADENV_B"); got != "local-base" {
+		t.Errorf("LOADENV_B: got %q", got)
+	}
+	if got := os.Getenv("LOADENV_REAL"); got != "real" {
+		t.Errorf("the real environment must win, got %q", got)
+	}
+
+	if err := LoadEnv(filepath.Join(dir, ".env.missing")); err == nil {
+		t.Error("a missing file must fail")
+	}
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Hook is called with the new value of a setting changed by a reload
+type Hook func(value string)
+
+// Reloadable marks settings as safe to change while the application runs, a reload updates
+// them in the environment. Settings read on every use need nothing more, the others register
+// a hook with OnChange.
+func (c *Config) Reloadable(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.reloadable == nil {
+		c.reloadable = map[string][]Hook{}
+	}
+	for _, key := range keys {
+		if _, ok := c.reloadable[key]; !ok {
+			c.reloadable[key] = nil
+		}
+	}
+}
+
+// OnChange registers a hook called when a reload changes the setting, the setting becomes
+// reloadable
+func (c *Config) OnChange(key string, hook Hook) {
+	c.Reloadable(key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reloadable[key] = append(c.reloadable[key], hook)
+}
+
+// Reload reads the files again and applies the reloadable settings that changed, the other
+// settings need a restart. The .env style files win over the yaml ones as at startup, missing
+// files and settings and the settings of the process environment are left alone. The hooks run after every setting is updated, the
+// changed keys are returned sorted.
+func (c *Config) Reload(files ...string) ([]string, error) {
+	values, err := readFiles(files)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	var (
+		changed []string
+		hooks   []func()
+	)
+	for key, keyHooks := range c.reloadable {
+		value, ok := values[key]
+		if !ok || fromEnvironment(key) || value == os.Getenv(key) {
+			continue
+		}
+		if err := setLoaded(key, value); err != nil {
+			c.mu.Unlock()
+			return changed, err
+		}
+		changed = append(changed, key)
+		for _, hook := range keyHooks {
+			hooks = append(hooks, func() { hook(value) })
+		}
+	}
+	c.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// Watch reloads the files on SIGHUP and when one of them changes on disk, checked every
+// interval, no polling when it is 0. report is called after every reload, it may be nil.
+// Watch returns when ctx is done.
+func (c *Config) Watch(ctx context.Context, interval time.Duration, report func(changed []string, err error), files ...string) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	modTimes := statFiles(files)
+
+	reload := func() {
+		changed, err := c.Reload(files...)
+		if report != nil {
+			report(changed, err)
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			modTimes = statFiles(files)
+			reload()
+		case <-tick:
+			current := statFiles(files)
+			if !sameModTimes(modTimes, current) {
+				modTimes = current
+				reload()
+			}
+		}
+	}
+}
+
+// statFiles returns the modification time of each file, zero for the missing ones
+func statFiles(files []string) []time.Time {
+	modTimes := make([]time.Time, len(files))
+	for i, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			modTimes[i] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+func sameModTimes(a, b []time.Time) bool {
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// readFiles reads the settings of the yaml and .env style files, the latter win
+func readFiles(files []string) (map[string]string, error) {
+	yamlValues, envValues := map[string]string{}, map[string]string{}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if ext := filepath.Ext(file); ext == ".yaml" || ext == ".yml" {
+			var tree map[string]any
+			if err := yaml.Unmarshal(content, &tree); err != nil {
+				return nil, fmt.Errorf("cannot parse %s: %w", file, err)
+			}
+			flatten("", tree, yamlValues)
+			continue
+		}
+		values, err := ParseEnv(strings.NewReader(string(content)))
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %s: %w", file, err)
+		}
+		for key, value := range values {
+			envValues[key] = value
+		}
+	}
+
+	for key, value := range envValues {
+		yamlValues[key] = value
+	}
+	return yamlValues, nil
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	env := filepath.Join(dir, ".env")
+	yamlFile := filepath.Join(dir, "app.yaml")
+	loadForTest(t, map[string]string{"RELOAD_LEVEL": "info", "RELOAD_RATE": "10", "RELOAD_PORT": "4000"})
+	t.Setenv("RELOAD_REAL", "kept")
+
+	c := New()
+	var hooked []string
+	c.OnChange("RELOAD_LEVEL", func(value string) {
+		hooked = append(hooked, value)
+	})
+	c.Reloadable("RELOAD_RATE", "RELOAD_REAL")
+
+	if err := os.WriteFile(env, []byte("RELOAD_LEVEL=debug\nRELOAD_PORT=5000\nRELOAD_REAL=file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(yamlFile, []byte("reload:\n  level: warn\n  rate: 20\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := c.Reload(env, yamlFile, filepath.Join(dir, "missing.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(changed, ",") != "RELOAD_LEVEL,RELOAD_RATE" {
+		t.Errorf("changed: got %v", changed)
+	}
+	// the .env file wins over the yaml files
+	if got := c.Get("RELOAD_LEVEL"); got != "debug" {
+		t.Errorf("RELOAD_LEVEL: got %q", got)
+	}
+	if got := c.GetInt("RELOAD_RATE", 0); got != 20 {
+		t.Errorf("RELOAD_RATE: got %d", got)
+	}
+	// not reloadable, it needs a restart
+	if got := c.Get("RELOAD_PORT"); got != "4000" {
+		t.Errorf("RELOAD_PORT: got %q", got)
+	}
+	// the process environment wins
+	if got := c.Get("RELOAD_REAL"); got != "kept" {
+		t.Errorf("RELOAD_REAL: got %q", got)
+	}
+	if len(hooked) != 1 || hooked[0] != "debug" {
+		t.Errorf("hooks: got %v", hooked)
+	}
+
+	// nothing changed, no hook runs
+	if changed, _ := c.Reload(env, yamlFile); len(changed) != 0 || len(hooked) != 1 {
+		t.Errorf("second reload: changed %v, hooks %v", changed, hooked)
+	}
+}
+
+func TestWatchPollsFiles(t *testing.T) {
+	env := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(env, []byte("WATCH_MODE=off\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loadForTest(t, map[string]string{"WATCH_MODE": "off"})
+
+	c := New()
+	values := make(chan string, 1)
+	c.OnChange("WATCH_MODE", func(value string) {
+		values <- value
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Watch(ctx, 10*time.Millisecond, nil, env)
+
+	// a later modification time than the one Watch started with
+	time.Sleep(20 * time.Millisecond)
+	if err := os.WriteFile(env, []byte("WATCH_MODE=on\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(env, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case value := <-values:
+		if value != "on" {
+			t.Errorf("got %q", value)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the change was not picked up")
+	}
+}
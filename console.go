@@ -0,0 +1,373 @@
+package sauri
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// consoleCommand is the argument running the console instead of the application, see
+// ConsoleCommand
+const consoleCommand = "tinker"
+
+// ConsoleFunc is a command of the console, it gets the arguments following its name and
+// writes its output to w
+type ConsoleFunc func(ctx context.Context, w io.Writer, args []string) error
+
+// namedConsoleCommand is a command registered with AddConsoleCommand
+type namedConsoleCommand struct {
+	name    string
+	summary string
+	run     ConsoleFunc
+}
+
+// AddConsoleCommand registers a command of the console under a name, e.g. a command looking
+// up a user by email. A name of a built-in command replaces it.
+func (s *Sauri) AddConsoleCommand(name, summary string, run ConsoleFunc) {
+	for i, cmd := range s.console {
+		if cmd.name == name {
+			s.console[i] = namedConsoleCommand{name: name, summary: summary, run: run}
+			return
+		}
+	}
+	s.console = append(s.console, namedConsoleCommand{name: name, summary: summary, run: run})
+}
+
+// Console reads commands line by line from in and writes their output to out until exit or
+// the end of in. It runs in the booted application, with its settings, databases and cache.
+// A failing command prints its error and the console goes on.
+func (s *Sauri) Console(ctx context.Context, in io.Reader, out io.Writer) error {
+	_, _ = fmt.Fprintf(out, "%s console, type help for the commands and exit to leave\n", s.AppName)
+	scanner := bufio.NewScanner(in)
+	for {
+		_, _ = fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			_, _ = fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		switch line {
+		case "":
+			continue
+		case "exit", "quit":
+			return nil
+		}
+		if err := s.RunConsoleCommand(ctx, out, line); err != nil {
+			_, _ = fmt.Fprintf(out, "error: %v\n", err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// RunConsoleCommand runs one line of the console, a command name followed by its arguments.
+// The built-in commands are:
+//
+//	sql <query>                   print the rows of a query on the default database
+//	exec <statement>              run a statement on the default database
+//	cache get|delete <key>        read or delete a cache entry
+//	cache set <key> <value> [ttl] write a cache entry
+//	cache keys [pattern]          list the cache keys
+//	config <NAME>                 print a setting
+//	help                          list the commands
+func (s *Sauri) RunConsoleCommand(ctx context.Context, out io.Writer, line string) error {
+	name, rest, _ := strings.Cut(strings.TrimSpace(line), " ")
+	rest = strings.TrimSpace(rest)
+
+	registered := false
+	for _, cmd := range s.console {
+		registered = registered || cmd.name == name
+	}
+	// the text of sql and exec is taken as it is, quotes included
+	if !registered {
+		switch name {
+		case "sql":
+			return s.consoleQuery(ctx, out, rest)
+		case "exec":
+			return s.consoleExec(ctx, out, rest)
+		}
+	}
+
+	args, err := splitConsoleArgs(rest)
+	if err != nil {
+		return err
+	}
+	for _, cmd := range s.console {
+		if cmd.name == name {
+			return cmd.run(ctx, out, args)
+		}
+	}
+
+	switch name {
+	case "help":
+		return s.consoleHelp(out)
+	case "cache":
+		return s.consoleCache(out, args)
+	case "config":
+		if len(args) != 1 {
+			return errors.New("usage: config <NAME>")
+		}
+		value, ok := s.Config.Lookup(args[0])
+		if !ok {
+			_, _ = fmt.Fprintf(out, "%s is not set\n", args[0])
+			return nil
+		}
+		_, _ = fmt.Fprintln(out, value)
+		return nil
+	}
+	return fmt.Errorf("unknown command %q, type help for the commands", name)
+}
+
+// ConsoleCommand runs the console when args, usually os.Args[1:], start with tinker and
+// reports whether it did, so that main exits instead of serving. The arguments following
+// tinker run as a single command instead of the interactive console, `sauri tinker` runs
+// the application this way:
+//
+//	if ran, err := app.ConsoleCommand(os.Args[1:]); ran {
+//		...
+//	}
+func (s *Sauri) ConsoleCommand(args []string) (bool, error) {
+	if len(args) == 0 || args[0] != consoleCommand {
+		return false, nil
+	}
+	defer func() {
+		_ = s.Shutdown(context.Background())
+	}()
+
+	if len(args) > 1 {
+		return true, s.RunConsoleCommand(context.Background(), os.Stdout, quoteConsoleArgs(args[1:]))
+	}
+	return true, s.Console(context.Background(), os.Stdin, os.Stdout)
+}
+
+// consoleHelp lists the built-in and the registered commands
+func (s *Sauri) consoleHelp(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	builtins := [][2]string{
+		{"sql <query>", "print the rows of a query on the default database"},
+		{"exec <statement>", "run a statement on the default database"},
+		{"cache get|delete <key>", "read or delete a cache entry"},
+		{"cache set <key> <value> [ttl]", "write a cache entry"},
+		{"cache keys [pattern]", "list the cache keys"},
+		{"config <NAME>", "print a setting"},
+		{"exit", "leave the console"},
+	}
+	for _, builtin := range builtins {
+		_, _ = fmt.Fprintf(w, "  %s\t%s\n", builtin[0], builtin[1])
+	}
+	for _, cmd := range s.console {
+		_, _ = fmt.Fprintf(w, "  %s\t%s\n", cmd.name, cmd.summary)
+	}
+	return w.Flush()
+}
+
+// consoleDB returns the default database of the console
+func (s *Sauri) consoleDB() (*DB, error) {
+	conn := s.DB()
+	if conn == nil {
+		return nil, errors.New("no database is configured")
+	}
+	return conn, nil
+}
+
+// consoleQuery prints the rows of a query as a table
+func (s *Sauri) consoleQuery(ctx context.Context, out io.Writer, query string) error {
+	if query == "" {
+		return errors.New("usage: sql <query>")
+	}
+	conn, err := s.consoleDB()
+	if err != nil {
+		return err
+	}
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, strings.Join(columns, "\t"))
+
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	count := 0
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		cells := make([]string, len(values))
+		for i, value := range values {
+			cells[i] = consoleValue(value)
+		}
+		_, _ = fmt.Fprintln(w, strings.Join(cells, "\t"))
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(out, "(%d rows)\n", count)
+	return nil
+}
+
+// consoleValue returns a column value as the console prints it
+func consoleValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+	return fmt.Sprint(value)
+}
+
+// consoleExec runs a statement and prints the rows it affected
+func (s *Sauri) consoleExec(ctx context.Context, out io.Writer, statement string) error {
+	if statement == "" {
+		return errors.New("usage: exec <statement>")
+	}
+	conn, err := s.consoleDB()
+	if err != nil {
+		return err
+	}
+	result, err := conn.ExecContext(ctx, statement)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		// not every driver counts the rows, the statement ran anyway
+		_, _ = fmt.Fprintln(out, "ok")
+		return nil
+	}
+	_, _ = fmt.Fprintf(out, "%d rows affected\n", affected)
+	return nil
+}
+
+// consoleCache runs the cache commands
+func (s *Sauri) consoleCache(out io.Writer, args []string) error {
+	if s.Cache == nil {
+		return errors.New("no cache is configured")
+	}
+	if len(args) == 0 {
+		return errors.New("usage: cache get|set|delete|keys")
+	}
+
+	switch action := args[0]; {
+	case action == "get" && len(args) == 2:
+		value, err := s.Cache.Get(args[1])
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(out, "%v\n", value)
+	case action == "set" && (len(args) == 3 || len(args) == 4):
+		var expires []time.Duration
+		if len(args) == 4 {
+			ttl, err := time.ParseDuration(args[3])
+			if err != nil {
+				return fmt.Errorf("invalid ttl %q: %w", args[3], err)
+			}
+			expires = append(expires, ttl)
+		}
+		if err := s.Cache.Set(args[1], args[2], expires...); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(out, "ok")
+	case action == "delete" && len(args) == 2:
+		if err := s.Cache.Delete(args[1]); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(out, "ok")
+	case action == "keys" && len(args) <= 2:
+		keys, err := s.Cache.Keys(args[1:]...)
+		if err != nil {
+			return err
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			_, _ = fmt.Fprintln(out, key)
+		}
+	default:
+		return errors.New("usage: cache get <key>, cache set <key> <value> [ttl], cache delete <key> or cache keys [pattern]")
+	}
+	return nil
+}
+
+// splitConsoleArgs splits a line on spaces, the single or double quoted parts stay whole
+func splitConsoleArgs(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// quoteConsoleArgs joins the arguments of the command line back into a console line, the
+// shell already split them so the ones with spaces are quoted again
+func quoteConsoleArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		// the text of sql and exec is taken as it is
+		if i > 0 && (args[0] == "sql" || args[0] == "exec") {
+			quoted[i] = arg
+			continue
+		}
+		switch {
+		case arg == "":
+			quoted[i] = `""`
+		case !strings.ContainsAny(arg, " \t'\""):
+			quoted[i] = arg
+		case !strings.Contains(arg, `"`):
+			quoted[i] = `"` + arg + `"`
+		default:
+			quoted[i] = "'" + arg + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,210 @@
+package sauri
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCookie is returned for an encrypted cookie that was tampered with or cannot be read
+var ErrInvalidCookie = errors.New("invalid cookie")
+
+// maxCookieSize is the size browsers are required to keep for a cookie
+const maxCookieSize = 4096
+
+// CookieOptions changes the defaults of the encrypted cookies: path /, HttpOnly, a session
+// cookie, and SameSite and Secure as COOKIE_SAME_SITE and COOKIE_SECURE say
+type CookieOptions struct {
+	MaxAge      time.Duration // a session cookie when zero
+	Path        string
+	Domain      string
+	SameSite    http.SameSite
+	AllowScript bool // drops HttpOnly so that JavaScript can read the cookie
+}
+
+// secureCookies encrypts and signs cookie values with the encryption key of the application
+type secureCookies struct {
+	enc      *Encryption
+	secure   bool
+	sameSite http.SameSite
+}
+
+// secureCookies returns the cookie codec of the application
+func (s *Sauri) secureCookies() secureCookies {
+	return secureCookies{
+		enc:      s.Encrypter(),
+		secure:   s.config.cookie.secure == "true",
+		sameSite: s.config.cookie.sameSite,
+	}
+}
+
+// parseSameSite reads COOKIE_SAME_SITE, lax by default
+func parseSameSite(value string) http.SameSite {
+	switch strings.ToLower(value) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// signature is the HMAC of the cookie name and value, the name is signed too so that the
+// value of one cookie cannot be replayed as another
+func signature(encryptionKey []byte, name, value string) []byte {
+	// a key of its own for the HMAC, derived from the encryption key
+	key := sha256.Sum256(append([]byte("sauri-cookie-signature:"), encryptionKey...))
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write([]byte(name + "|" + value))
+	return mac.Sum(nil)
+}
+
+// encode marshals the value to JSON, encrypts it and appends the signature
+func (c secureCookies) encode(name string, value any) (string, error) {
+	if c.enc == nil || len(c.enc.Key) == 0 {
+		return "", errors.New("cannot encrypt cookies, KEY is not set")
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("cannot encode the cookie %s: %w", name, err)
+	}
+	encrypted, err := c.enc.Encrypt(string(content))
+	if err != nil {
+		return "", fmt.Errorf("cannot encrypt the cookie %s: %w", name, err)
+	}
+	encoded := encrypted + "." + base64.RawURLEncoding.EncodeToString(signature(c.enc.Key, name, encrypted))
+	if len(name)+len(encoded) > maxCookieSize {
+		return "", fmt.Errorf("the cookie %s is larger than %d bytes", name, maxCookieSize)
+	}
+	return encoded, nil
+}
+
+// verify checks the signature of a value with the current key, then with the earlier ones
+// so that the cookies set before a key rotation stay valid
+func (c secureCookies) verify(name, value, sig string) bool {
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	if hmac.Equal(mac, signature(c.enc.Key, name, value)) {
+		return true
+	}
+	for _, key := range c.enc.Keys {
+		if hmac.Equal(mac, signature(key, name, value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// decode checks the signature, decrypts the value and unmarshals it into dst
+func (c secureCookies) decode(name, encoded string, dst any) error {
+	encrypted, sig, ok := strings.Cut(encoded, ".")
+	if !ok {
+		return ErrInvalidCookie
+	}
+	if c.enc == nil {
+		return ErrInvalidCookie
+	}
+	if !c.verify(name, encrypted, sig) {
+		return ErrInvalidCookie
+	}
+	content, err := c.enc.Decrypt(encrypted)
+	if err != nil {
+		return ErrInvalidCookie
+	}
+	if err := json.Unmarshal([]byte(content), dst); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCookie, err)
+	}
+	return nil
+}
+
+// cookie builds the cookie with the defaults of the application
+func (c secureCookies) cookie(name, value string, opts []CookieOptions) *http.Cookie {
+	var options CookieOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     options.Path,
+		Domain:   options.Domain,
+		HttpOnly: !options.AllowScript,
+		Secure:   c.secure,
+		SameSite: options.SameSite,
+	}
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	if cookie.SameSite == 0 {
+		cookie.SameSite = c.sameSite
+	}
+	// browsers reject SameSite=None cookies that are not secure
+	if cookie.SameSite == http.SameSiteNoneMode {
+		cookie.Secure = true
+	}
+	if options.MaxAge > 0 {
+		cookie.MaxAge = int(options.MaxAge.Seconds())
+		cookie.Expires = time.Now().Add(options.MaxAge)
+	}
+	return cookie
+}
+
+// SetEncryptedCookie sets a cookie holding the value as encrypted and signed JSON, so that
+// the client can neither read nor change it
+func (s *Sauri) SetEncryptedCookie(w http.ResponseWriter, name string, value any, opts ...CookieOptions) error {
+	codec := s.secureCookies()
+	encoded, err := codec.encode(name, value)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, codec.cookie(name, encoded, opts))
+	return nil
+}
+
+// GetEncryptedCookie reads a cookie set by SetEncryptedCookie into dst. It returns
+// http.ErrNoCookie when the cookie is missing and ErrInvalidCookie when it was tampered with.
+func (s *Sauri) GetEncryptedCookie(r *http.Request, name string, dst any) error {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return err
+	}
+	return s.secureCookies().decode(name, c.Value, dst)
+}
+
+// DeleteCookie expires a cookie, the path and domain must be the ones it was set with
+func (s *Sauri) DeleteCookie(w http.ResponseWriter, name string, opts ...CookieOptions) {
+	cookie := s.secureCookies().cookie(name, "", opts)
+	cookie.MaxAge = -1
+	cookie.Expires = time.Unix(0, 0)
+	http.SetCookie(w, cookie)
+}
+
+// SetEncryptedCookie sets an encrypted and signed cookie on the response, see
+// Sauri.SetEncryptedCookie
+func (r *Response) SetEncryptedCookie(name string, value any, opts ...CookieOptions) error {
+	encoded, err := r.cookies.encode(name, value)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(r.Rw, r.cookies.cookie(name, encoded, opts))
+	return nil
+}
+
+// GetEncryptedCookie reads an encrypted cookie of the request into dst, see
+// Sauri.GetEncryptedCookie
+func (r *Response) GetEncryptedCookie(req *http.Request, name string, dst any) error {
+	c, err := req.Cookie(name)
+	if err != nil {
+		return err
+	}
+	return r.cookies.decode(name, c.Value, dst)
+}
@@ -0,0 +1,222 @@
+package sauri
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/haskekareem/sauri/db"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"strings"
+	"sync/atomic"
+)
+
+// defaultDatabase is the name of the connection set up with the DATABASE_* settings
+const defaultDatabase = "default"
+
+// DB is a named database connection with its read replicas. Writes and transactions always
+// go to the primary. With SplitReads the SELECT statements go to the replicas in turn,
+// Reader picks one explicitly otherwise.
+type DB struct {
+	Name       string
+	Type       string
+	Primary    *sql.DB
+	Pgx        *pgxpool.Pool // nil for MySQL
+	Replicas   []*sql.DB
+	SplitReads bool
+	next       atomic.Uint64
+}
+
+// primaryKey marks the contexts whose reads must see the writes made before them
+type primaryKey struct{}
+
+// OnPrimary returns a context sending the reads to the primary, for the requests reading
+// back what they just wrote before the replicas caught up
+func OnPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// Writer returns the primary pool
+func (db *DB) Writer() *sql.DB {
+	return db.Primary
+}
+
+// Reader returns the next replica, the primary when there is none or ctx is OnPrimary
+func (db *DB) Reader(ctx context.Context) *sql.DB {
+	if len(db.Replicas) == 0 {
+		return db.Primary
+	}
+	if onPrimary, _ := ctx.Value(primaryKey{}).(bool); onPrimary {
+		return db.Primary
+	}
+	return db.Replicas[(db.next.Add(1)-1)%uint64(len(db.Replicas))]
+}
+
+// route returns the pool a query runs on
+func (db *DB) route(ctx context.Context, query string) *sql.DB {
+	if db.SplitReads && isReadQuery(query) {
+		return db.Reader(ctx)
+	}
+	return db.Primary
+}
+
+// ExecContext runs a statement on the primary
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.Primary.ExecContext(ctx, query, args...)
+}
+
+// QueryContext runs a query, on a replica when it is a SELECT and SplitReads is on
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return db.route(ctx, query).QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext runs a query returning one row, on a replica when it is a SELECT and
+// SplitReads is on
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.route(ctx, query).QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction on the primary
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return db.Primary.BeginTx(ctx, opts)
+}
+
+// Close closes the pools of the connection
+func (db *DB) Close() error {
+	var errs []error
+	for _, replica := range db.Replicas {
+		errs = append(errs, replica.Close())
+	}
+	if db.Primary != nil {
+		errs = append(errs, db.Primary.Close())
+	}
+	if db.Pgx != nil {
+		db.Pgx.Close()
+	}
+	return errors.Join(errs...)
+}
+
+// isReadQuery reports whether a statement only reads, locking reads go to the primary
+func isReadQuery(query string) bool {
+	query = strings.ToLower(strings.TrimSpace(query))
+	return strings.HasPrefix(query, "select") && !strings.Contains(query, " for update") && !strings.Contains(query, " for share")
+}
+
+// DB returns a database connection by name, the default one set up with the DATABASE_*
+// settings when no name is given. It is nil when the connection is not configured, see
+// DATABASE_CONNECTIONS.
+func (s *Sauri) DB(name ...string) *DB {
+	key := defaultDatabase
+	if len(name) > 0 && name[0] != "" {
+		key = strings.ToLower(name[0])
+	}
+	return s.databases[key]
+}
+
+// Query returns the query builder of a database connection, see DB. The queries run through
+// the connection, so the SELECT statements go to its replicas when it splits reads. It is
+// nil when the connection is not configured.
+func (s *Sauri) Query(name ...string) *db.Conn {
+	conn := s.DB(name...)
+	if conn == nil {
+		return nil
+	}
+	return db.New(conn, db.DialectOf(conn.Type))
+}
+
+// openDatabases sets up the named connections listed in DATABASE_CONNECTIONS, each one read
+// from the DATABASE_<NAME>_* settings falling back to the DATABASE_* ones, and the read
+// replicas of every connection listed in DATABASE_READ_HOSTS or DATABASE_<NAME>_READ_HOSTS
+// as host:port pairs. DATABASE_READ_SPLIT sends the SELECT statements to the replicas.
+func (s *Sauri) openDatabases() error {
+	s.databases = map[string]*DB{}
+	if s.DBConn.SqlConnPool != nil {
+		primary := &DB{
+			Name:    defaultDatabase,
+			Type:    s.DBConn.DatabaseType,
+			Primary: s.DBConn.SqlConnPool,
+			Pgx:     s.DBConn.PgxConnPool,
+		}
+		// the pools of the default connection are closed with s.DBConn
+		if err := s.openReplicas(primary, false); err != nil {
+			return err
+		}
+		s.databases[defaultDatabase] = primary
+	}
+
+	for _, name := range s.Config.GetStrings("DATABASE_CONNECTIONS") {
+		name = strings.ToLower(name)
+		if name == defaultDatabase {
+			continue
+		}
+		setting := s.dbSettings(name)
+		dsn, err := s.buildDSN(setting, "")
+		if err != nil {
+			return fmt.Errorf("database %s: %w", name, err)
+		}
+		sqlDB, pgxPool, err := s.OpenDBConnectionPool(setting("TYPE"), dsn)
+		if err != nil {
+			return fmt.Errorf("database %s: %w", name, err)
+		}
+		conn := &DB{Name: name, Type: setting("TYPE"), Primary: sqlDB, Pgx: pgxPool}
+		s.databases[name] = conn
+		s.OnShutdown(func(ctx context.Context) error {
+			return conn.Close()
+		})
+		if err := s.openReplicas(conn, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openReplicas opens the read replicas of a connection
+func (s *Sauri) openReplicas(conn *DB, named bool) error {
+	setting := s.dbSettings(conn.Name)
+	prefix := "DATABASE_"
+	if named {
+		prefix = "DATABASE_" + strings.ToUpper(conn.Name) + "_"
+	}
+	conn.SplitReads = s.Config.GetBool(prefix+"READ_SPLIT", false)
+
+	for _, hostPort := range s.Config.GetStrings(prefix + "READ_HOSTS") {
+		dsn, err := s.buildDSN(setting, hostPort)
+		if err != nil {
+			return fmt.Errorf("database %s replica %s: %w", conn.Name, hostPort, err)
+		}
+		replica, err := s.openSQLPool(setting("TYPE"), dsn)
+		if err != nil {
+			return fmt.Errorf("database %s replica %s: %w", conn.Name, hostPort, err)
+		}
+		conn.Replicas = append(conn.Replicas, replica)
+		if !named {
+			s.OnShutdown(func(ctx context.Context) error {
+				return replica.Close()
+			})
+		}
+	}
+	return nil
+}
+
+// openSQLPool opens a database/sql pool only, for the read replicas
+func (s *Sauri) openSQLPool(dbDriverType, dsn string) (*sql.DB, error) {
+	driverName := "mysql"
+	switch dbDriverType {
+	case "postgresql", "postgres", "pgx":
+		driverName = "pgx"
+	case "mariadb", "mysql":
+	default:
+		return nil, fmt.Errorf("unsupported database driver type: %s", dbDriverType)
+	}
+
+	pool, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	s.dbPoolFromConfig().apply(pool)
+	if err := pool.Ping(); err != nil {
+		_ = pool.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+	return pool, nil
+}
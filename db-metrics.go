@@ -0,0 +1,147 @@
+package sauri
+
+import (
+	"context"
+	"github.com/haskekareem/sauri/querylog"
+	"net/http"
+	"time"
+)
+
+// PoolStats holds a snapshot of a single connection pool
+type PoolStats struct {
+	Driver       string        `json:"driver"`
+	MaxOpen      int           `json:"max_open"`
+	Open         int           `json:"open"`
+	InUse        int           `json:"in_use"`
+	Idle         int           `json:"idle"`
+	WaitCount    int64         `json:"wait_count"`
+	WaitDuration time.Duration `json:"wait_duration"`
+}
+
+// DBPoolStats holds the live statistics of every database pool opened by the app
+type DBPoolStats struct {
+	DatabaseType string             `json:"database_type"`
+	SQL          *PoolStats         `json:"sql,omitempty"`
+	PGX          *PoolStats         `json:"pgx,omitempty"`
+	Queries      *querylog.Snapshot `json:"queries,omitempty"` // see DATABASE_SLOW_QUERY
+}
+
+// DBPoolStats returns the current stats of both the database/sql and pgx pools
+func (s *Sauri) DBPoolStats() DBPoolStats {
+	stats := DBPoolStats{DatabaseType: s.DBConn.DatabaseType}
+
+	if s.DBConn.SqlConnPool != nil {
+		st := s.DBConn.SqlConnPool.Stats()
+		stats.SQL = &PoolStats{
+			Driver:       "database/sql",
+			MaxOpen:      st.MaxOpenConnections,
+			Open:         st.OpenConnections,
+			InUse:        st.InUse,
+			Idle:         st.Idle,
+			WaitCount:    st.WaitCount,
+			WaitDuration: st.WaitDuration,
+		}
+	}
+
+	if s.DBConn.PgxConnPool != nil {
+		st := s.DBConn.PgxConnPool.Stat()
+		stats.PGX = &PoolStats{
+			Driver:  "pgx",
+			MaxOpen: int(st.MaxConns()),
+			Open:    int(st.TotalConns()),
+			InUse:   int(st.AcquiredConns()),
+			Idle:    int(st.IdleConns()),
+			// pgx counts an acquire as waiting when the pool had no idle connection
+			WaitCount:    st.EmptyAcquireCount(),
+			WaitDuration: st.EmptyAcquireWaitTime(),
+		}
+	}
+
+	if s.queryLog != nil {
+		queries := s.queryLog.Stats.Snapshot()
+		stats.Queries = &queries
+	}
+
+	return stats
+}
+
+// DBPoolMetrics is the handler for the metrics endpoint, it writes the
+// pool stats as JSON
+func (s *Sauri) DBPoolMetrics(w http.ResponseWriter, r *http.Request) {
+	payload := struct {
+		Database DBPoolStats `json:"database"`
+	}{
+		Database: s.DBPoolStats(),
+	}
+
+	_ = s.WriteJSON(w, http.StatusOK, payload)
+}
+
+// monitorDBPool periodically checks the pools and logs a warning when the time
+// spent waiting for a connection in the last interval crosses the threshold, until ctx is done
+func (s *Sauri) monitorDBPool(ctx context.Context, interval, threshold time.Duration) {
+	var lastSQL, lastPGX time.Duration
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		stats := s.DBPoolStats()
+
+		if stats.SQL != nil {
+			if waited := stats.SQL.WaitDuration - lastSQL; waited > threshold {
+				s.ErrorLog.Printf("database/sql pool: waited %s for connections in the last %s (in use %d/%d)",
+					waited, interval, stats.SQL.InUse, stats.SQL.MaxOpen)
+			}
+			lastSQL = stats.SQL.WaitDuration
+		}
+
+		if stats.PGX != nil {
+			if waited := stats.PGX.WaitDuration - lastPGX; waited > threshold {
+				s.ErrorLog.Printf("pgx pool: waited %s for connections in the last %s (in use %d/%d)",
+					waited, interval, stats.PGX.InUse, stats.PGX.MaxOpen)
+			}
+			lastPGX = stats.PGX.WaitDuration
+		}
+	}
+}
+
+// startDBPoolMonitor starts the pool monitor using DATABASE_POOL_WAIT_WARN (milliseconds)
+// as the threshold; a value of 0 or less disables it
+func (s *Sauri) startDBPoolMonitor() {
+	if s.DBConn.SqlConnPool == nil && s.DBConn.PgxConnPool == nil {
+		return
+	}
+
+	// Default to half a second of waiting per interval
+	threshold := s.Config.GetDuration("DATABASE_POOL_WAIT_WARN", time.Millisecond, 500*time.Millisecond)
+	if threshold <= 0 {
+		return
+	}
+
+	s.goBackground(func(ctx context.Context) {
+		s.monitorDBPool(ctx, time.Minute, threshold)
+	})
+}
+
+// queryLogger returns the logger of the database queries, created once and shared by the
+// pools. DATABASE_LOG_QUERIES logs every query, it defaults to DEBUG_MODE, and
+// DATABASE_SLOW_QUERY (milliseconds) logs the queries taking longer as warnings. It is nil
+// when neither is set.
+func (s *Sauri) queryLogger() *querylog.Logger {
+	if s.queryLog != nil {
+		return s.queryLog
+	}
+	all := s.Config.GetBool("DATABASE_LOG_QUERIES", s.Config.GetBool("DEBUG_MODE", false))
+	threshold := s.Config.GetDuration("DATABASE_SLOW_QUERY", time.Millisecond, 0)
+	if !all && threshold <= 0 {
+		return nil
+	}
+	s.queryLog = &querylog.Logger{Log: s.moduleLogger("database"), Threshold: threshold, All: all}
+	return s.queryLog
+}
@@ -0,0 +1,153 @@
+// Package db is a small query builder running on database/sql or pgx. Queries are built
+// fluently, Table("users").Where("active = ?", true).OrderBy("name").Paginate(1, 20), and
+// their rows are scanned into structs with All, First and Paged.
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"strings"
+)
+
+// ErrNotFound is returned by First when the query has no rows
+var ErrNotFound = errors.New("db: no rows found")
+
+// ErrNoConditions is returned by Update and Delete without a Where, which would change
+// every row of the table
+var ErrNoConditions = errors.New("db: update or delete without conditions")
+
+// Dialect is the SQL flavour of a database
+type Dialect int
+
+const (
+	Postgres Dialect = iota // $1 placeholders
+	MySQL                   // ? placeholders
+)
+
+// DialectOf returns the dialect of a DATABASE_TYPE value
+func DialectOf(dbType string) Dialect {
+	switch strings.ToLower(dbType) {
+	case "mysql", "mariadb":
+		return MySQL
+	}
+	return Postgres
+}
+
+// Executor runs statements through database/sql, *sql.DB, *sql.Tx and *sauri.DB are ones
+type Executor interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// PgxExecutor runs statements through pgx, *pgxpool.Pool, *pgx.Conn and pgx.Tx are ones
+type PgxExecutor interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// Conn runs the queries built with Table
+type Conn struct {
+	run     runner
+	dialect Dialect
+}
+
+// New returns a connection running on database/sql
+func New(exec Executor, dialect Dialect) *Conn {
+	return &Conn{run: sqlRunner{db: exec}, dialect: dialect}
+}
+
+// NewPgx returns a connection running on pgx, always with the Postgres dialect
+func NewPgx(exec PgxExecutor) *Conn {
+	return &Conn{run: pgxRunner{db: exec}, dialect: Postgres}
+}
+
+// Dialect returns the dialect of the connection
+func (c *Conn) Dialect() Dialect {
+	return c.dialect
+}
+
+// Table starts a query on a table. Table and column names are written as given, they must
+// not come from user input; values are always passed as arguments.
+func (c *Conn) Table(name string) *Query {
+	return &Query{conn: c, table: name}
+}
+
+// rows is the part of *sql.Rows and pgx.Rows the queries use
+type rows interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+	Close() error
+}
+
+// runner runs the statements of a connection
+type runner interface {
+	query(ctx context.Context, query string, args []any) (rows, error)
+	// exec returns the rows affected and the id of an inserted row, when the driver has it
+	exec(ctx context.Context, query string, args []any) (affected int64, lastID int64, err error)
+}
+
+type sqlRunner struct {
+	db Executor
+}
+
+func (r sqlRunner) query(ctx context.Context, query string, args []any) (rows, error) {
+	return r.db.QueryContext(ctx, query, args...)
+}
+
+func (r sqlRunner) exec(ctx context.Context, query string, args []any) (int64, int64, error) {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+	// Postgres has no last insert id, the inserts read it with RETURNING instead
+	lastID, _ := result.LastInsertId()
+	return affected, lastID, nil
+}
+
+type pgxRunner struct {
+	db PgxExecutor
+}
+
+func (r pgxRunner) query(ctx context.Context, query string, args []any) (rows, error) {
+	result, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgxRows{Rows: result}, nil
+}
+
+func (r pgxRunner) exec(ctx context.Context, query string, args []any) (int64, int64, error) {
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, 0, err
+	}
+	return tag.RowsAffected(), 0, nil
+}
+
+// pgxRows gives pgx.Rows the methods of *sql.Rows
+type pgxRows struct {
+	pgx.Rows
+}
+
+func (r pgxRows) Columns() ([]string, error) {
+	fields := r.FieldDescriptions()
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = field.Name
+	}
+	return columns, nil
+}
+
+func (r pgxRows) Close() error {
+	r.Rows.Close()
+	return r.Rows.Err()
+}
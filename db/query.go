@@ -0,0 +1,355 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Query is a statement on a table under construction, its methods return the query so that
+// they can be chained. A query is not safe for concurrent use.
+type Query struct {
+	conn    *Conn
+	table   string
+	columns []string
+	joins   []string
+	where   []condition
+	orders  []string
+	groups  []string
+	limit   int
+	offset  int
+	page    int // set by Paginate, read by Paged
+	perPage int
+}
+
+// condition is one part of the WHERE clause
+type condition struct {
+	or   bool
+	sql  string
+	args []any
+}
+
+// Select sets the selected columns, all of them by default
+func (q *Query) Select(columns ...string) *Query {
+	q.columns = append(q.columns, columns...)
+	return q
+}
+
+// Join adds a join, e.g. Join("JOIN roles ON roles.id = users.role_id")
+func (q *Query) Join(clause string) *Query {
+	q.joins = append(q.joins, clause)
+	return q
+}
+
+// Where adds a condition joined with AND, its values are given as ? placeholders:
+// Where("email = ? AND active = ?", email, true)
+func (q *Query) Where(sql string, args ...any) *Query {
+	q.where = append(q.where, condition{sql: sql, args: args})
+	return q
+}
+
+// OrWhere adds a condition joined with OR
+func (q *Query) OrWhere(sql string, args ...any) *Query {
+	q.where = append(q.where, condition{or: true, sql: sql, args: args})
+	return q
+}
+
+// WhereIn adds a column IN (values) condition, no values match no rows
+func (q *Query) WhereIn(column string, values ...any) *Query {
+	if len(values) == 0 {
+		return q.Where("1 = 0")
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	return q.Where(column+" IN ("+placeholders+")", values...)
+}
+
+// WhereNull adds a column IS NULL condition
+func (q *Query) WhereNull(column string) *Query {
+	return q.Where(column + " IS NULL")
+}
+
+// OrderBy adds sort columns, with their direction if any: OrderBy("last_name", "id DESC")
+func (q *Query) OrderBy(columns ...string) *Query {
+	q.orders = append(q.orders, columns...)
+	return q
+}
+
+// GroupBy adds grouping columns
+func (q *Query) GroupBy(columns ...string) *Query {
+	q.groups = append(q.groups, columns...)
+	return q
+}
+
+// Limit sets the maximum number of rows, no limit when 0
+func (q *Query) Limit(n int) *Query {
+	q.limit = n
+	return q
+}
+
+// Offset sets the number of rows skipped
+func (q *Query) Offset(n int) *Query {
+	q.offset = n
+	return q
+}
+
+// Paginate selects a page of perPage rows, pages count from 1
+func (q *Query) Paginate(page, perPage int) *Query {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = 1
+	}
+	q.page, q.perPage = page, perPage
+	return q.Limit(perPage).Offset((page - 1) * perPage)
+}
+
+// ToSQL returns the SELECT statement of the query and its arguments
+func (q *Query) ToSQL() (string, []any) {
+	columns := "*"
+	if len(q.columns) > 0 {
+		columns = strings.Join(q.columns, ", ")
+	}
+	var b strings.Builder
+	b.WriteString("SELECT " + columns + " FROM " + q.table)
+	for _, join := range q.joins {
+		b.WriteString(" " + join)
+	}
+	args := q.writeWhere(&b)
+	if len(q.groups) > 0 {
+		b.WriteString(" GROUP BY " + strings.Join(q.groups, ", "))
+	}
+	if len(q.orders) > 0 {
+		b.WriteString(" ORDER BY " + strings.Join(q.orders, ", "))
+	}
+	if q.limit > 0 {
+		b.WriteString(" LIMIT " + strconv.Itoa(q.limit))
+	}
+	if q.offset > 0 {
+		b.WriteString(" OFFSET " + strconv.Itoa(q.offset))
+	}
+	return q.rebind(b.String()), args
+}
+
+// writeWhere writes the WHERE clause and returns its arguments
+func (q *Query) writeWhere(b *strings.Builder) []any {
+	var args []any
+	for i, cond := range q.where {
+		switch {
+		case i == 0:
+			b.WriteString(" WHERE ")
+		case cond.or:
+			b.WriteString(" OR ")
+		default:
+			b.WriteString(" AND ")
+		}
+		b.WriteString("(" + cond.sql + ")")
+		args = append(args, cond.args...)
+	}
+	return args
+}
+
+// rebind turns the ? placeholders into $n ones for Postgres, skipping quoted strings
+func (q *Query) rebind(query string) string {
+	if q.conn == nil || q.conn.dialect != Postgres {
+		return query
+	}
+	var (
+		b      strings.Builder
+		n      int
+		quoted bool
+	)
+	for _, c := range query {
+		switch {
+		case c == '\'':
+			quoted = !quoted
+		case c == '?' && !quoted:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Count returns the number of rows matching the conditions, or of groups with GroupBy,
+// ignoring the order and limits
+func (q *Query) Count(ctx context.Context) (int64, error) {
+	var b strings.Builder
+	if len(q.groups) > 0 {
+		b.WriteString("SELECT COUNT(*) FROM (SELECT 1 FROM " + q.table)
+	} else {
+		b.WriteString("SELECT COUNT(*) FROM " + q.table)
+	}
+	for _, join := range q.joins {
+		b.WriteString(" " + join)
+	}
+	args := q.writeWhere(&b)
+	if len(q.groups) > 0 {
+		b.WriteString(" GROUP BY " + strings.Join(q.groups, ", ") + ") AS grouped")
+	}
+
+	rs, err := q.conn.run.query(ctx, q.rebind(b.String()), args)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = rs.Close()
+	}()
+	var count int64
+	if rs.Next() {
+		if err := rs.Scan(&count); err != nil {
+			return 0, err
+		}
+	}
+	return count, rs.Err()
+}
+
+// Exists reports whether a row matches the conditions
+func (q *Query) Exists(ctx context.Context) (bool, error) {
+	count, err := q.Count(ctx)
+	return count > 0, err
+}
+
+// Insert inserts a row and returns its id, read from the id column on Postgres
+func (q *Query) Insert(ctx context.Context, values map[string]any) (int64, error) {
+	query, args := q.insertSQL(values)
+	if q.conn.dialect == MySQL {
+		_, id, err := q.conn.run.exec(ctx, query, args)
+		return id, err
+	}
+	rs, err := q.conn.run.query(ctx, query+" RETURNING id", args)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = rs.Close()
+	}()
+	var id int64
+	if rs.Next() {
+		if err := rs.Scan(&id); err != nil {
+			return 0, err
+		}
+	}
+	return id, rs.Err()
+}
+
+// InsertRow inserts a row without reading back its id, for the tables whose id is not an
+// auto-incremented number, such as a uuid set by the application
+func (q *Query) InsertRow(ctx context.Context, values map[string]any) error {
+	query, args := q.insertSQL(values)
+	_, _, err := q.conn.run.exec(ctx, query, args)
+	return err
+}
+
+// insertSQL returns the INSERT statement of values
+func (q *Query) insertSQL(values map[string]any) (string, []any) {
+	columns, args := sortedValues(values)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	return q.rebind(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", q.table, strings.Join(columns, ", "), placeholders)), args
+}
+
+// Update changes the rows matching the conditions and returns how many there were
+func (q *Query) Update(ctx context.Context, values map[string]any) (int64, error) {
+	if len(q.where) == 0 {
+		return 0, ErrNoConditions
+	}
+	columns, args := sortedValues(values)
+	sets := make([]string, len(columns))
+	for i, column := range columns {
+		sets[i] = column + " = ?"
+	}
+	var b strings.Builder
+	b.WriteString("UPDATE " + q.table + " SET " + strings.Join(sets, ", "))
+	args = append(args, q.writeWhere(&b)...)
+
+	affected, _, err := q.conn.run.exec(ctx, q.rebind(b.String()), args)
+	return affected, err
+}
+
+// Delete deletes the rows matching the conditions and returns how many there were
+func (q *Query) Delete(ctx context.Context) (int64, error) {
+	if len(q.where) == 0 {
+		return 0, ErrNoConditions
+	}
+	var b strings.Builder
+	b.WriteString("DELETE FROM " + q.table)
+	args := q.writeWhere(&b)
+
+	affected, _, err := q.conn.run.exec(ctx, q.rebind(b.String()), args)
+	return affected, err
+}
+
+// sortedValues returns the columns of values in name order, so that statements are stable
+func sortedValues(values map[string]any) ([]string, []any) {
+	columns := make([]string, 0, len(values))
+	for column := range values {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	args := make([]any, len(columns))
+	for i, column := range columns {
+		args[i] = values[column]
+	}
+	return columns, args
+}
+
+// All runs the query and scans its rows into a slice of T, a struct, a pointer to one or a
+// single column type such as int64
+func All[T any](ctx context.Context, q *Query) ([]T, error) {
+	query, args := q.ToSQL()
+	rs, err := q.conn.run.query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rs.Close()
+	}()
+	return scanAll[T](rs)
+}
+
+// First returns the first row of the query, ErrNotFound when there is none
+func First[T any](ctx context.Context, q *Query) (T, error) {
+	items, err := All[T](ctx, q.Limit(1))
+	if err != nil || len(items) == 0 {
+		var zero T
+		if err == nil {
+			err = ErrNotFound
+		}
+		return zero, err
+	}
+	return items[0], nil
+}
+
+// Page is a page of rows with the totals needed to link to the others
+type Page[T any] struct {
+	Items    []T   `json:"items"`
+	Page     int   `json:"page"`
+	PerPage  int   `json:"per_page"`
+	Total    int64 `json:"total"`
+	LastPage int   `json:"last_page"`
+}
+
+// Paged runs a query set up with Paginate and counts the rows of every page
+func Paged[T any](ctx context.Context, q *Query) (*Page[T], error) {
+	if q.perPage == 0 {
+		q.Paginate(1, 15)
+	}
+	total, err := q.Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items, err := All[T](ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	lastPage := int((total + int64(q.perPage) - 1) / int64(q.perPage))
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	return &Page[T]{Items: items, Page: q.page, PerPage: q.perPage, Total: total, LastPage: lastPage}, nil
+}
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// fakeRunner records the statements and answers the queries with fixed rows
+type fakeRunner struct {
+	queries []string
+	args    [][]any
+	columns []string
+	values  [][]any
+	lastID  int64
+}
+
+func (f *fakeRunner) query(_ context.Context, query string, args []any) (rows, error) {
+	f.queries = append(f.queries, query)
+	f.args = append(f.args, args)
+	return &fakeRows{columns: f.columns, values: f.values, at: -1}, nil
+}
+
+func (f *fakeRunner) exec(_ context.Context, query string, args []any) (int64, int64, error) {
+	f.queries = append(f.queries, query)
+	f.args = append(f.args, args)
+	return 1, f.lastID, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]any
+	at      int
+}
+
+func (r *fakeRows) Columns() ([]string, error) { return r.columns, nil }
+func (r *fakeRows) Next() bool                 { r.at++; return r.at < len(r.values) }
+func (r *fakeRows) Err() error                 { return nil }
+func (r *fakeRows) Close() error               { return nil }
+
+func (r *fakeRows) Scan(dest ...any) error {
+	for i, d := range dest {
+		reflect.ValueOf(d).Elem().Set(reflect.ValueOf(r.values[r.at][i]))
+	}
+	return nil
+}
+
+func TestToSQL(t *testing.T) {
+	pg := &Conn{dialect: Postgres}
+	query, args := pg.Table("users").
+		Select("id", "email").
+		Where("active = ?", true).
+		Where("email LIKE '%?%' OR name = ?", "kim").
+		OrWhere("role = ?", "admin").
+		OrderBy("last_name", "id DESC").
+		Paginate(3, 20).
+		ToSQL()
+	want := "SELECT id, email FROM users WHERE (active = $1) AND (email LIKE '%?%' OR name = $2) OR (role = $3) ORDER BY last_name, id DESC LIMIT 20 OFFSET 40"
+	if query != want {
+		t.Errorf("got  %s\nwant %s", query, want)
+	}
+	if len(args) != 3 || args[2] != "admin" {
+		t.Errorf("args: got %v", args)
+	}
+
+	my := &Conn{dialect: MySQL}
+	query, args = my.Table("posts").WhereIn("id", 1, 2, 3).WhereNull("deleted_at").ToSQL()
+	if query != "SELECT * FROM posts WHERE (id IN (?, ?, ?)) AND (deleted_at IS NULL)" || len(args) != 3 {
+		t.Errorf("mysql: got %s %v", query, args)
+	}
+	if query, _ = my.Table("posts").WhereIn("id").ToSQL(); query != "SELECT * FROM posts WHERE (1 = 0)" {
+		t.Errorf("empty IN: got %s", query)
+	}
+}
+
+func TestWrites(t *testing.T) {
+	ctx := context.Background()
+	run := &fakeRunner{columns: []string{"id"}, values: [][]any{{int64(7)}}}
+	pg := &Conn{run: run, dialect: Postgres}
+
+	id, err := pg.Table("users").Insert(ctx, map[string]any{"name": "kim", "email": "kim@example.com"})
+	if err != nil || id != 7 {
+		t.Fatalf("insert: got %d, %v", id, err)
+	}
+	if run.queries[0] != "INSERT INTO users (email, name) VALUES ($1, $2) RETURNING id" {
+		t.Errorf("insert: got %s", run.queries[0])
+	}
+
+	if _, err := pg.Table("users").Where("id = ?", 7).Update(ctx, map[string]any{"name": "lee"}); err != nil {
+		t.Fatal(err)
+	}
+	if run.queries[1] != "UPDATE users SET name = $1 WHERE (id = $2)" || run.args[1][1] != 7 {
+		t.Errorf("update: got %s %v", run.queries[1], run.args[1])
+	}
+
+	if _, err := pg.Table("users").Delete(ctx); !errors.Is(err, ErrNoConditions) {
+		t.Errorf("delete without conditions: got %v", err)
+	}
+	if _, err := pg.Table("users").Update(ctx, map[string]any{"name": "x"}); !errors.Is(err, ErrNoConditions) {
+		t.Errorf("update without conditions: got %v", err)
+	}
+
+	if err := pg.Table("tags").InsertRow(ctx, map[string]any{"id": "a1", "name": "go"}); err != nil {
+		t.Fatal(err)
+	}
+	if run.queries[2] != "INSERT INTO tags (id, name) VALUES ($1, $2)" {
+		t.Errorf("insert row: got %s", run.queries[2])
+	}
+
+	my := &Conn{run: &fakeRunner{lastID: 12}, dialect: MySQL}
+	if id, err := my.Table("users").Insert(ctx, map[string]any{"name": "kim"}); err != nil || id != 12 {
+		t.Errorf("mysql insert: got %d, %v", id, err)
+	}
+}
+
+type base struct {
+	ID        int64
+	CreatedAt time.Time
+}
+
+type user struct {
+	base
+	FirstName string
+	Email     string         `db:"email_address"`
+	Nickname  sql.NullString `db:"nickname"`
+	Secret    string         `db:"-"`
+}
+
+func TestAllScansStructs(t *testing.T) {
+	now := time.Now()
+	run := &fakeRunner{
+		columns: []string{"id", "first_name", "email_address", "created_at", "nickname", "unknown"},
+		values: [][]any{
+			{int64(1), "Kim", "kim@example.com", now, sql.NullString{String: "k", Valid: true}, "x"},
+			{int64(2), "Lee", "lee@example.com", now, sql.NullString{}, "y"},
+		},
+	}
+	conn := &Conn{run: run, dialect: Postgres}
+
+	users, err := All[user](context.Background(), conn.Table("users"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[1].ID != 2 || users[1].FirstName != "Lee" || users[0].Email != "kim@example.com" ||
+		!users[0].CreatedAt.Equal(now) || users[0].Nickname.String != "k" {
+		t.Errorf("got %+v", users)
+	}
+
+	first, err := First[*user](context.Background(), conn.Table("users"))
+	if err != nil || first.ID != 1 {
+		t.Errorf("First: got %+v, %v", first, err)
+	}
+
+	run.columns, run.values = []string{"id"}, [][]any{{int64(4)}, {int64(5)}}
+	ids, err := All[int64](context.Background(), conn.Table("users").Select("id"))
+	if err != nil || len(ids) != 2 || ids[1] != 5 {
+		t.Errorf("single column: got %v, %v", ids, err)
+	}
+
+	run.values = nil
+	if _, err := First[user](context.Background(), conn.Table("users")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("First without rows: got %v", err)
+	}
+}
+
+func TestPaged(t *testing.T) {
+	run := &fakeRunner{columns: []string{"count"}, values: [][]any{{int64(41)}}}
+	conn := &Conn{run: run, dialect: MySQL}
+
+	page, err := Paged[int64](context.Background(), conn.Table("users").Where("active = ?", true).Paginate(2, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 41 || page.LastPage != 3 || page.Page != 2 || page.PerPage != 20 {
+		t.Errorf("got %+v", page)
+	}
+	if run.queries[0] != "SELECT COUNT(*) FROM users WHERE (active = ?)" {
+		t.Errorf("count: got %s", run.queries[0])
+	}
+	if run.queries[1] != "SELECT * FROM users WHERE (active = ?) LIMIT 20 OFFSET 20" {
+		t.Errorf("page: got %s", run.queries[1])
+	}
+}
+
+func TestNewUUID(t *testing.T) {
+	id := NewUUID()
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id) {
+		t.Errorf("got %s", id)
+	}
+	if id == NewUUID() {
+		t.Error("two uuids are the same")
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{"ID": "id", "UserID": "user_id", "CreatedAt": "created_at", "HTTPStatus": "http_status"} {
+		if got := snakeCase(name); got != want {
+			t.Errorf("%s: got %s, want %s", name, got, want)
+		}
+	}
+}
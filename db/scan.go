@@ -0,0 +1,131 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// fieldCache holds the column to field mapping of every struct type scanned
+var fieldCache sync.Map // reflect.Type -> map[string][]int
+
+// scanAll scans the rows into a slice of T
+func scanAll[T any](rs rows) ([]T, error) {
+	columns, err := rs.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	typ := reflect.TypeFor[T]()
+	pointer := typ.Kind() == reflect.Pointer && typ.Elem().Kind() == reflect.Struct
+	structType := typ
+	if pointer {
+		structType = typ.Elem()
+	}
+	// time.Time and the sql.Null types scan as a single column
+	isStruct := structType.Kind() == reflect.Struct && !scansItself(structType)
+
+	var items []T
+	for rs.Next() {
+		var item T
+		if !isStruct {
+			if err := rs.Scan(&item); err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			continue
+		}
+
+		value := reflect.ValueOf(&item).Elem()
+		if pointer {
+			value.Set(reflect.New(structType))
+			value = value.Elem()
+		}
+		if err := rs.Scan(targets(value, columns)...); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rs.Err()
+}
+
+// targets returns the scan destinations of the columns in a struct, the columns without a
+// field are discarded
+func targets(value reflect.Value, columns []string) []any {
+	fields := fieldsOf(value.Type())
+	dest := make([]any, len(columns))
+	for i, column := range columns {
+		index, ok := fields[strings.ToLower(column)]
+		if !ok {
+			dest[i] = new(any)
+			continue
+		}
+		dest[i] = value.FieldByIndex(index).Addr().Interface()
+	}
+	return dest
+}
+
+// fieldsOf maps the columns of a struct to its fields. The column is the name of the db tag,
+// e.g. `db:"created_at,omitempty"`, or the snake case of the field name; `db:"-"` skips a
+// field. Embedded structs without a tag add their fields.
+func fieldsOf(typ reflect.Type) map[string][]int {
+	if cached, ok := fieldCache.Load(typ); ok {
+		return cached.(map[string][]int)
+	}
+	fields := map[string][]int{}
+	addFields(typ, nil, fields)
+	fieldCache.Store(typ, fields)
+	return fields
+}
+
+func addFields(typ reflect.Type, parent []int, fields map[string][]int) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("db"), ",")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		index := append(append([]int{}, parent...), i)
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			addFields(field.Type, index, fields)
+			continue
+		}
+		if tag == "" {
+			tag = snakeCase(field.Name)
+		}
+		tag = strings.ToLower(tag)
+		// the fields of the outer struct win over the embedded ones
+		if _, taken := fields[tag]; !taken || len(parent) == 0 {
+			fields[tag] = index
+		}
+	}
+}
+
+// scansItself reports whether a struct is scanned as one value, such as time.Time
+func scansItself(typ reflect.Type) bool {
+	if typ == reflect.TypeFor[time.Time]() {
+		return true
+	}
+	type scanner interface{ Scan(src any) error }
+	return reflect.PointerTo(typ).Implements(reflect.TypeFor[scanner]())
+}
+
+// snakeCase turns a field name into a column name, UserID becomes user_id
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// a new word starts after a lower case letter, or at the last capital of an acronym
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -3,6 +3,8 @@ package sauri
 import (
 	"database/sql"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"net/http"
+	"time"
 )
 
 // initializedFoldersPath used to list the folders in the current working directory
@@ -26,12 +28,23 @@ type sauriConfigs struct {
 	sessionStoreType string
 	dBConfig         dataBaseConfig
 	redis            redisConfig
+	metricsEnabled   string
+	problemDetails   bool // answer errors with RFC 7807 problem details to JSON clients
+	tls              tlsConfig
 }
 type dataBaseConfig struct {
 	dsn          string
 	dataBaseType string
 }
 
+// dbPoolConfig sizes the database connection pools, see dbPoolFromConfig
+type dbPoolConfig struct {
+	maxOpen      int
+	maxIdle      int
+	connLifetime time.Duration
+	connIdleTime time.Duration
+}
+
 type DatabaseConn struct {
 	DatabaseType string
 	SqlConnPool  *sql.DB
@@ -45,4 +58,5 @@ type cookieConfig struct {
 	persist  string
 	secure   string
 	domain   string
+	sameSite http.SameSite // of the encrypted cookies
 }
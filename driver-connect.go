@@ -6,14 +6,52 @@ import (
 	"fmt"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/gomodule/redigo/redis"
+	"github.com/haskekareem/sauri/querylog"
+	"github.com/haskekareem/sauri/tracing"
 	_ "github.com/jackc/pgconn"
-	_ "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/stdlib"
-	"os"
+	"net"
+	"strings"
 	"time"
 )
 
+// dbPoolFromConfig reads DATABASE_MAX_OPEN, DATABASE_MAX_IDLE, DATABASE_CONN_LIFETIME and
+// DATABASE_CONN_IDLE_TIME, the durations take minutes or values such as 90s. Out of range
+// values fall back to the defaults: 10 connections, all of them kept idle, replaced after
+// 30 minutes or 10 idle minutes.
+func (s *Sauri) dbPoolFromConfig() dbPoolConfig {
+	pool := dbPoolConfig{
+		maxOpen:      s.Config.GetInt("DATABASE_MAX_OPEN", 10),
+		maxIdle:      s.Config.GetInt("DATABASE_MAX_IDLE", -1),
+		connLifetime: s.Config.GetDuration("DATABASE_CONN_LIFETIME", time.Minute, 30*time.Minute),
+		connIdleTime: s.Config.GetDuration("DATABASE_CONN_IDLE_TIME", time.Minute, 10*time.Minute),
+	}
+	if pool.maxOpen < 1 {
+		pool.maxOpen = 10
+	}
+	if pool.maxIdle < 0 || pool.maxIdle > pool.maxOpen {
+		pool.maxIdle = pool.maxOpen
+	}
+	if pool.connLifetime <= 0 {
+		pool.connLifetime = 30 * time.Minute
+	}
+	if pool.connIdleTime <= 0 {
+		pool.connIdleTime = 10 * time.Minute
+	}
+	return pool
+}
+
+// apply sets the limits of a database/sql pool
+func (p dbPoolConfig) apply(db *sql.DB) {
+	db.SetMaxOpenConns(p.maxOpen)
+	db.SetMaxIdleConns(p.maxIdle)
+	db.SetConnMaxLifetime(p.connLifetime)
+	db.SetConnMaxIdleTime(p.connIdleTime)
+}
+
 // OpenDBConnectionPool opens a database connection pool using pgx and the standard sql package.
 func (s *Sauri) OpenDBConnectionPool(dbDriverType, connStr string) (*sql.DB, *pgxpool.Pool, error) {
 	switch dbDriverType {
@@ -23,6 +61,8 @@ func (s *Sauri) OpenDBConnectionPool(dbDriverType, connStr string) (*sql.DB, *pg
 		dbDriverType = "mysql"
 	}
 
+	pool := s.dbPoolFromConfig()
+
 	// driver configuration and database connection pool creation
 	if dbDriverType == "pgx" {
 		// Configure pgx pool with connection string
@@ -30,11 +70,26 @@ func (s *Sauri) OpenDBConnectionPool(dbDriverType, connStr string) (*sql.DB, *pg
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to parse config: %w", err)
 		}
-		// Set additional pool configuration if needed
-		poolConfig.MaxConnLifetime = time.Minute * 30
-		poolConfig.MaxConnIdleTime = time.Minute * 10
-		poolConfig.MaxConns = 10
+		// pgxpool has no idle limit, DATABASE_MAX_IDLE applies to the database/sql pool only
+		poolConfig.MaxConnLifetime = pool.connLifetime
+		poolConfig.MaxConnIdleTime = pool.connIdleTime
+		poolConfig.MaxConns = int32(pool.maxOpen)
 		poolConfig.HealthCheckPeriod = time.Minute * 3
+		// query spans and logs for both the pool and the database/sql pool built from the same config
+		var tracers []pgx.QueryTracer
+		if tracing.Enabled() {
+			tracers = append(tracers, &tracing.QueryTracer{})
+		}
+		if logger := s.queryLogger(); logger != nil {
+			tracers = append(tracers, &querylog.QueryTracer{Logger: logger})
+		}
+		switch len(tracers) {
+		case 0:
+		case 1:
+			poolConfig.ConnConfig.Tracer = tracers[0]
+		default:
+			poolConfig.ConnConfig.Tracer = multitracer.New(tracers...)
+		}
 
 		// Open a connection pool
 		connPool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
@@ -48,6 +103,7 @@ func (s *Sauri) OpenDBConnectionPool(dbDriverType, connStr string) (*sql.DB, *pg
 		// Create a *sql.DB instance using stdlib.OpenDB with pgx.ConnConfig
 		// Wrap the pool in a sql.DB instance
 		db := stdlib.OpenDB(*poolConfig.ConnConfig)
+		pool.apply(db)
 
 		// Optionally test the connection
 		if err := db.Ping(); err != nil {
@@ -68,16 +124,19 @@ func (s *Sauri) OpenDBConnectionPool(dbDriverType, connStr string) (*sql.DB, *pg
 			return nil, nil, fmt.Errorf("failed to open MySQL database: %w", err)
 		}
 
-		// Optionally set additional pool configuration for MySQL
-		db.SetConnMaxLifetime(time.Minute * 30)
-		db.SetMaxIdleConns(10)
-		db.SetMaxOpenConns(10)
+		pool.apply(db)
 
 		// Optionally test the connection
 		if err := db.Ping(); err != nil {
 			_ = db.Close()
 			return nil, nil, fmt.Errorf("failed to ping MySQL database: %w", err)
 		}
+		if logger := s.queryLogger(); logger != nil {
+			logged := querylog.OpenDB(db, connStr, logger)
+			pool.apply(logged)
+			_ = db.Close()
+			db = logged
+		}
 		return db, nil, nil
 	}
 
@@ -86,17 +145,44 @@ func (s *Sauri) OpenDBConnectionPool(dbDriverType, connStr string) (*sql.DB, *pg
 
 // BuildDSN build a connection string to connect to a database
 func (s *Sauri) BuildDSN() (string, error) {
+	return s.buildDSN(s.dbSettings(defaultDatabase), "")
+}
+
+// dbSettings returns the reader of the DATABASE_<NAME>_* settings of a named connection,
+// which fall back to the DATABASE_* ones of the default connection
+func (s *Sauri) dbSettings(name string) func(key string) string {
+	prefix := "DATABASE_"
+	if name != defaultDatabase {
+		prefix = "DATABASE_" + strings.ToUpper(name) + "_"
+	}
+	return func(key string) string {
+		if value := s.Config.Get(prefix + key); value != "" {
+			return value
+		}
+		return s.Config.Get("DATABASE_" + key)
+	}
+}
+
+// buildDSN builds the connection string of a connection, hostPort replaces its host and
+// port for the read replicas
+func (s *Sauri) buildDSN(setting func(key string) string, hostPort string) (string, error) {
 	// dsn holds the connection string
 	var dsn string
 
 	// Retrieve environment variables
-	host := os.Getenv("DATABASE_HOST")
-	port := os.Getenv("DATABASE_PORT")
-	user := os.Getenv("DATABASE_USER")
-	password := os.Getenv("DATABASE_PASS")
-	dbname := os.Getenv("DATABASE_NAME")
-	sslMode := os.Getenv("DATABASE_SSL_MODE")
-	dbDriverType := os.Getenv("DATABASE_TYPE")
+	host := setting("HOST")
+	port := setting("PORT")
+	user := setting("USER")
+	password := setting("PASS")
+	dbname := setting("NAME")
+	sslMode := setting("SSL_MODE")
+	dbDriverType := setting("TYPE")
+	if hostPort != "" {
+		host = hostPort
+		if h, p, err := net.SplitHostPort(hostPort); err == nil {
+			host, port = h, p
+		}
+	}
 
 	// Check mandatory environment variables
 	if host == "" || port == "" || user == "" || dbname == "" || dbDriverType == "" {
@@ -146,12 +232,14 @@ func (s *Sauri) BuildDSN() (string, error) {
 	return dsn, nil
 }
 
-// NewRedisConnPool initializes and maintain a pool of connection
+// NewRedisConnPool initializes and maintain a pool of connection, with the REDIS_* settings
+// read when it is created
 func (s *Sauri) NewRedisConnPool() *redis.Pool {
+	settings := s.redisSettings()
 	return &redis.Pool{
 		Dial: func() (redis.Conn, error) {
-			conn, err := redis.Dial("tcp", s.config.redis.host,
-				redis.DialPassword(s.config.redis.password))
+			conn, err := redis.Dial("tcp", settings.host,
+				redis.DialPassword(settings.password))
 			if err != nil {
 				return nil, err
 			}
@@ -6,51 +6,145 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
+	"strconv"
+	"strings"
 )
 
+// gcmPrefix starts the AES-GCM ciphertexts, it is not in the base64 alphabet so that they are
+// told apart from the AES-CFB ones of earlier versions
+const gcmPrefix = "~"
+
+// ErrDecrypt is returned for a ciphertext that was tampered with or whose key is unknown
+var ErrDecrypt = errors.New("cannot decrypt, the ciphertext is invalid or its key is unknown")
+
+// Encryption encrypts with AES-GCM, so that tampered ciphertexts fail to decrypt. The version
+// of the key is written in front of every ciphertext, keys of earlier versions are kept in
+// Keys to decrypt while the data is encrypted again with the new key.
 type Encryption struct {
-	Key []byte
+	Key     []byte          // encrypts, and decrypts the ciphertexts of its version
+	Version byte            // version of Key, 1 when zero
+	Keys    map[byte][]byte // earlier keys by version, only used to decrypt
+	// NoLegacy refuses the unauthenticated AES-CFB ciphertexts of earlier versions, which
+	// are otherwise decrypted with Key
+	NoLegacy bool
 }
 
-// Encrypt encrypts the plaintext using AES and returns the
-// ciphertext as a base64 encoded string
-func (e *Encryption) Encrypt(text string) (string, error) {
+// encryptionFromEnv returns the encryption of the application: KEY is the current key and
+// KEY_VERSION its version, PREVIOUS_KEYS lists the earlier ones as version:key pairs
+// separated by commas
+func (s *Sauri) encryptionFromEnv() (Encryption, error) {
+	enc := Encryption{Key: []byte(s.Config.Get("KEY")), Keys: map[byte][]byte{}}
+	if version := s.Config.Get("KEY_VERSION"); version != "" {
+		v, err := strconv.ParseUint(version, 10, 8)
+		if err != nil || v == 0 {
+			return enc, fmt.Errorf("invalid KEY_VERSION %q, it must be between 1 and 255", version)
+		}
+		enc.Version = byte(v)
+	}
+	for _, entry := range s.Config.GetStrings("PREVIOUS_KEYS") {
+		version, key, ok := strings.Cut(entry, ":")
+		v, err := strconv.ParseUint(version, 10, 8)
+		if !ok || err != nil || v == 0 {
+			return enc, fmt.Errorf("invalid version %q in PREVIOUS_KEYS, expected version:key pairs", version)
+		}
+		enc.Keys[byte(v)] = []byte(key)
+	}
+	return enc, nil
+}
 
-	plainText := []byte(text)
+func (e *Encryption) version() byte {
+	if e.Version == 0 {
+		return 1
+	}
+	return e.Version
+}
 
-	//todo Key Initialization
+// key returns the key of a version
+func (e *Encryption) key(version byte) []byte {
+	if version == e.version() {
+		return e.Key
+	}
+	return e.Keys[version]
+}
 
-	// Create a new AES cipher with the provided key
-	block, err := aes.NewCipher(e.Key)
+// gcm returns the AES-GCM cipher of a key
+func gcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", err // Return an error if cipher creation fails
+		return nil, err
 	}
+	return cipher.NewGCM(block)
+}
 
-	// Create a byte slice for the ciphertext, which is the size of the AES block plus the length of the plaintext
-	ciphertext := make([]byte, aes.BlockSize+len(plainText))
-
-	// Create an initialization vector (IV) from the first part of the ciphertext
-	iv := ciphertext[:aes.BlockSize]
+// Encrypt encrypts the plaintext with AES-GCM and returns the version of the key, the nonce
+// and the ciphertext as a prefixed base64 string
+func (e *Encryption) Encrypt(text string) (string, error) {
+	aead, err := gcm(e.Key)
+	if err != nil {
+		return "", err
+	}
 
-	// Fill the IV with random bytes
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		return "", err // Return an error if IV generation fails
+	// version byte, then a random nonce, then the sealed text
+	out := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(text)+aead.Overhead())
+	out[0] = e.version()
+	if _, err := io.ReadFull(rand.Reader, out[1:]); err != nil {
+		return "", err
 	}
+	// the version is authenticated too, so that it cannot be swapped
+	out = aead.Seal(out, out[1:], []byte(text), out[:1])
+	return gcmPrefix + base64.RawURLEncoding.EncodeToString(out), nil
+}
 
-	// Create a new CFB encrypter with the cipher block and IV
-	stream := cipher.NewCFBEncrypter(block, iv)
+// Decrypt decrypts a ciphertext of Encrypt with the key of its version. Ciphertexts of earlier
+// versions of the framework, AES-CFB without the prefix, are decrypted with Key unless
+// NoLegacy is set.
+func (e *Encryption) Decrypt(ciphertext string) (string, error) {
+	encoded, ok := strings.CutPrefix(ciphertext, gcmPrefix)
+	if !ok {
+		if e.NoLegacy {
+			return "", ErrDecrypt
+		}
+		return e.decryptLegacy(ciphertext)
+	}
 
-	// Encrypt the plaintext by XORing it with the key stream
-	stream.XORKeyStream(ciphertext[aes.BlockSize:], plainText)
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(data) < 1 {
+		return "", ErrDecrypt
+	}
+	key := e.key(data[0])
+	if key == nil {
+		return "", ErrDecrypt
+	}
+	aead, err := gcm(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < 1+aead.NonceSize()+aead.Overhead() {
+		return "", ErrDecrypt
+	}
+	nonce, sealed := data[1:1+aead.NonceSize()], data[1+aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, sealed, data[:1])
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plain), nil
+}
 
-	// Return the ciphertext as a base64 encoded string
-	return base64.URLEncoding.EncodeToString(ciphertext), nil
+// NeedsRotation reports whether a ciphertext was not encrypted with the current key, so that
+// it should be encrypted again
+func (e *Encryption) NeedsRotation(ciphertext string) bool {
+	encoded, ok := strings.CutPrefix(ciphertext, gcmPrefix)
+	if !ok {
+		return true
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	return err != nil || len(data) == 0 || data[0] != e.version()
 }
 
-// Decrypt function decrypts the base64 encoded ciphertext using AES
-// and returns the plaintext
-func (e *Encryption) Decrypt(ciphertext string) (string, error) {
+// decryptLegacy decrypts the base64 encoded AES-CFB ciphertexts of earlier versions
+func (e *Encryption) decryptLegacy(ciphertext string) (string, error) {
 	// Decode the base64 encoded ciphertext
 	ciphertextBytes, err := base64.URLEncoding.DecodeString(ciphertext)
 	if err != nil {
@@ -82,3 +176,12 @@ func (e *Encryption) Decrypt(ciphertext string) (string, error) {
 	// Return the decrypted plaintext
 	return string(ciphertextBytes), nil
 }
+
+// Encrypter returns the encryption of the application, see KEY, KEY_VERSION and PREVIOUS_KEYS
+func (s *Sauri) Encrypter() *Encryption {
+	enc := s.encryption
+	if len(enc.Key) == 0 {
+		enc.Key = []byte(s.EncryptionKey)
+	}
+	return &enc
+}
@@ -0,0 +1,187 @@
+package sauri
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/haskekareem/sauri/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testKeyV1 = []byte("0123456789abcdef0123456789abcdef")
+	testKeyV2 = []byte("fedcba9876543210fedcba9876543210")
+)
+
+// legacyEncrypt encrypts like the AES-CFB Encrypt of earlier versions
+func legacyEncrypt(t *testing.T, key []byte, text string) string {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	out := make([]byte, aes.BlockSize+len(text))
+	_, err = rand.Read(out[:aes.BlockSize])
+	require.NoError(t, err)
+	cipher.NewCFBEncrypter(block, out[:aes.BlockSize]).XORKeyStream(out[aes.BlockSize:], []byte(text))
+	return base64.URLEncoding.EncodeToString(out)
+}
+
+// tamper flips a bit of the byte at i of the decoded ciphertext
+func tamper(t *testing.T, ciphertext string, i int) string {
+	t.Helper()
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(ciphertext, gcmPrefix))
+	require.NoError(t, err)
+	if i < 0 {
+		i += len(data)
+	}
+	data[i] ^= 1
+	return gcmPrefix + base64.RawURLEncoding.EncodeToString(data)
+}
+
+func TestEncryption_RoundTrip(t *testing.T) {
+	enc := &Encryption{Key: testKeyV1}
+
+	ciphertext, err := enc.Encrypt("secret message")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(ciphertext, gcmPrefix))
+	assert.NotContains(t, ciphertext, "secret message")
+
+	plain, err := enc.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "secret message", plain)
+	assert.False(t, enc.NeedsRotation(ciphertext))
+
+	// a random nonce each time
+	again, err := enc.Encrypt("secret message")
+	require.NoError(t, err)
+	assert.NotEqual(t, ciphertext, again)
+}
+
+func TestEncryption_Tampered(t *testing.T) {
+	enc := &Encryption{Key: testKeyV2, Version: 2, Keys: map[byte][]byte{1: testKeyV1}}
+	ciphertext, err := enc.Encrypt("secret message")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		ciphertext string
+	}{
+		{"sealed text", tamper(t, ciphertext, -1)},
+		{"nonce", tamper(t, ciphertext, 1)},
+		// version 2 becomes 3, which has no key
+		{"version byte", tamper(t, ciphertext, 0)},
+		{"truncated", ciphertext[:len(ciphertext)-4]},
+		{"not base64", gcmPrefix + "!!!"},
+		{"empty", gcmPrefix},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := enc.Decrypt(tt.ciphertext)
+			assert.ErrorIs(t, err, ErrDecrypt)
+		})
+	}
+}
+
+func TestEncryption_SwappedVersion(t *testing.T) {
+	// both versions have a key, the version is authenticated so it cannot be swapped
+	enc := &Encryption{Key: testKeyV1, Version: 1, Keys: map[byte][]byte{0: testKeyV1}}
+	ciphertext, err := enc.Encrypt("secret message")
+	require.NoError(t, err)
+
+	_, err = enc.Decrypt(tamper(t, ciphertext, 0))
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestEncryption_PreviousKeys(t *testing.T) {
+	old := &Encryption{Key: testKeyV1}
+	ciphertext, err := old.Encrypt("secret message")
+	require.NoError(t, err)
+
+	rotated := &Encryption{Key: testKeyV2, Version: 2, Keys: map[byte][]byte{1: testKeyV1}}
+	plain, err := rotated.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "secret message", plain)
+	assert.True(t, rotated.NeedsRotation(ciphertext))
+
+	// once the key is dropped, its ciphertexts are refused
+	dropped := &Encryption{Key: testKeyV2, Version: 2}
+	_, err = dropped.Decrypt(ciphertext)
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestEncryption_Legacy(t *testing.T) {
+	ciphertext := legacyEncrypt(t, testKeyV1, "secret message")
+
+	enc := &Encryption{Key: testKeyV1}
+	plain, err := enc.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "secret message", plain)
+	assert.True(t, enc.NeedsRotation(ciphertext))
+
+	enc.NoLegacy = true
+	_, err = enc.Decrypt(ciphertext)
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestSecureCookies_RotatedKey(t *testing.T) {
+	old := secureCookies{enc: &Encryption{Key: testKeyV1}}
+	encoded, err := old.encode("prefs", map[string]string{"theme": "dark"})
+	require.NoError(t, err)
+
+	rotated := secureCookies{enc: &Encryption{Key: testKeyV2, Version: 2, Keys: map[byte][]byte{1: testKeyV1}}}
+	var prefs map[string]string
+	require.NoError(t, rotated.decode("prefs", encoded, &prefs))
+	assert.Equal(t, "dark", prefs["theme"])
+
+	// the signature covers the name
+	assert.ErrorIs(t, rotated.decode("session", encoded, &prefs), ErrInvalidCookie)
+
+	dropped := secureCookies{enc: &Encryption{Key: testKeyV2, Version: 2}}
+	assert.ErrorIs(t, dropped.decode("prefs", encoded, &prefs), ErrInvalidCookie)
+}
+
+func TestEncryptionFromEnv(t *testing.T) {
+	tests := []struct {
+		name         string
+		version      string
+		previousKeys string
+		want         Encryption
+		wantErr      string
+	}{
+		{
+			name: "current key only",
+			want: Encryption{Key: []byte("current"), Keys: map[byte][]byte{}},
+		},
+		{
+			name:         "previous keys",
+			version:      "3",
+			previousKeys: "1:first, 2:second,",
+			want:         Encryption{Key: []byte("current"), Version: 3, Keys: map[byte][]byte{1: []byte("first"), 2: []byte("second")}},
+		},
+		{name: "version zero", version: "0", wantErr: "invalid KEY_VERSION"},
+		{name: "version too large", version: "256", wantErr: "invalid KEY_VERSION"},
+		{name: "version not a number", version: "v2", wantErr: "invalid KEY_VERSION"},
+		{name: "pair without version", previousKeys: "first", wantErr: "invalid version"},
+		{name: "previous version zero", previousKeys: "0:first", wantErr: "invalid version"},
+		{name: "previous version not a number", previousKeys: "one:first", wantErr: "invalid version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("KEY", "current")
+			t.Setenv("KEY_VERSION", tt.version)
+			t.Setenv("PREVIOUS_KEYS", tt.previousKeys)
+
+			enc, err := (&Sauri{Config: config.New()}).encryptionFromEnv()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, enc)
+		})
+	}
+}
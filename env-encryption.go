@@ -0,0 +1,81 @@
+package sauri
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EncryptedEnvFile is the encrypted copy of the .env file written by sauri env:encrypt
+const EncryptedEnvFile = ".env.encrypted"
+
+// EnvKeyVariable holds the key of the encrypted env file. It must be set in the real
+// environment of the server, never in a file next to the encrypted one.
+const EnvKeyVariable = "SAURI_ENV_KEY"
+
+// envEncryption returns the encryption of the env files for a key
+func envEncryption(key string) (*Encryption, error) {
+	switch len(key) {
+	case 16, 24, 32:
+		return &Encryption{Key: []byte(key), NoLegacy: true}, nil
+	case 0:
+		return nil, fmt.Errorf("no key for the encrypted env file, set %s", EnvKeyVariable)
+	default:
+		return nil, errors.New("the env file key must be 16, 24 or 32 characters long")
+	}
+}
+
+// EncryptEnv encrypts the content of an env file
+func EncryptEnv(content []byte, key string) ([]byte, error) {
+	enc, err := envEncryption(key)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := enc.Encrypt(string(content))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(encrypted + "\n"), nil
+}
+
+// DecryptEnv decrypts an env file encrypted by EncryptEnv
+func DecryptEnv(encrypted []byte, key string) ([]byte, error) {
+	enc, err := envEncryption(key)
+	if err != nil {
+		return nil, err
+	}
+	content, err := enc.Decrypt(strings.TrimSpace(string(encrypted)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt the env file, check %s: %w", EnvKeyVariable, err)
+	}
+	return []byte(content), nil
+}
+
+// LoadEncryptedEnv decrypts an encrypted env file with the key and sets its variables
+func (s *Sauri) LoadEncryptedEnv(filePath, key string) error {
+	encrypted, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	content, err := DecryptEnv(encrypted, key)
+	if err != nil {
+		return err
+	}
+	return setEnvFrom(strings.NewReader(string(content)))
+}
+
+// loadEncryptedEnv loads the encrypted env file of the application, when there is one and
+// SAURI_ENV_KEY is set. Its variables override the ones of the .env files, not the real environment.
+func (s *Sauri) loadEncryptedEnv(rootPath string) error {
+	key := s.Config.Get(EnvKeyVariable)
+	file := filepath.Join(rootPath, EncryptedEnvFile)
+	if key == "" {
+		return nil
+	}
+	if _, err := os.Stat(file); err != nil {
+		return nil
+	}
+	return s.LoadEncryptedEnv(file, key)
+}
@@ -0,0 +1,167 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/haskekareem/sauri/jobs"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Event is an emitted event
+type Event struct {
+	Name string
+	// Payload is the emitted value for the listeners, the queued ones get it as
+	// json.RawMessage, use Decode to read it the same way in both
+	Payload interface{}
+}
+
+// Decode decodes the payload into v
+func (e Event) Decode(v interface{}) error {
+	raw, ok := e.Payload.(json.RawMessage)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(e.Payload); err != nil {
+			return fmt.Errorf("cannot encode the payload of %s: %w", e.Name, err)
+		}
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("cannot decode the payload of %s: %w", e.Name, err)
+	}
+	return nil
+}
+
+// Listener handles an event
+type Listener func(ctx context.Context, event Event) error
+
+// queuedEvent is the job payload of a queued listener
+type queuedEvent struct {
+	Name    string          `json:"name"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// registration is a listener of a pattern
+type registration struct {
+	pattern  string
+	listener Listener
+	job      string // job name of a queued listener
+}
+
+// Bus delivers the emitted events to their listeners. Patterns are event names or end with
+// ".*" to match every event under a prefix, "*" matches them all.
+type Bus struct {
+	jobs   *jobs.Manager
+	logger *slog.Logger
+
+	mu            sync.RWMutex
+	registrations []registration
+}
+
+// New creates a bus, queued listeners run as jobs of the manager
+func New(manager *jobs.Manager, logger *slog.Logger) *Bus {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Bus{jobs: manager, logger: logger}
+}
+
+// Listen registers a listener run by Emit before it returns
+func (b *Bus) Listen(pattern string, listener Listener) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.registrations = append(b.registrations, registration{pattern: pattern, listener: listener})
+}
+
+// ListenQueued registers a listener run in the background as a job, with the retries of the
+// job manager. The name identifies the listener in the queue, it must be unique and stay the
+// same across deployments.
+func (b *Bus) ListenQueued(pattern, name string, listener Listener) error {
+	if b.jobs == nil {
+		return errors.New("events: queued listeners need a job manager")
+	}
+
+	job := "event:" + name
+	b.jobs.HandleFunc(job, func(ctx context.Context, payload json.RawMessage) error {
+		var queued queuedEvent
+		if err := json.Unmarshal(payload, &queued); err != nil {
+			return fmt.Errorf("cannot decode the event: %w", err)
+		}
+		return listener(ctx, Event{Name: queued.Name, Payload: queued.Payload})
+	})
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.registrations = append(b.registrations, registration{pattern: pattern, job: job})
+	return nil
+}
+
+// Emit delivers an event, running the listeners in registration order and queueing the
+// queued ones. Every listener runs even when one fails, the errors are returned together.
+func (b *Bus) Emit(name string, payload interface{}) error {
+	return b.EmitContext(context.Background(), name, payload)
+}
+
+// EmitContext delivers an event, the listeners get ctx, e.g. the request context
+func (b *Bus) EmitContext(ctx context.Context, name string, payload interface{}) error {
+	b.mu.RLock()
+	var matched []registration
+	for _, r := range b.registrations {
+		if match(r.pattern, name) {
+			matched = append(matched, r)
+		}
+	}
+	b.mu.RUnlock()
+
+	event := Event{Name: name, Payload: payload}
+	var (
+		errs    []error
+		encoded json.RawMessage
+	)
+	for _, r := range matched {
+		if r.job == "" {
+			if err := call(ctx, r.listener, event); err != nil {
+				b.logger.Error("event listener failed", "event", name, "error", err)
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		if encoded == nil {
+			content, err := json.Marshal(payload)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("cannot encode the payload of %s: %w", name, err))
+				break
+			}
+			encoded = content
+		}
+		if err := b.jobs.DispatchPayload(ctx, r.job, queuedEvent{Name: name, Payload: encoded}); err != nil {
+			b.logger.Error("cannot queue the event listener", "event", name, "listener", r.job, "error", err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// match reports whether an event name matches a pattern
+func match(pattern, name string) bool {
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasSuffix(pattern, ".*"):
+		return strings.HasPrefix(name, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == name
+}
+
+// call runs a listener, turning a panic into an error
+func call(ctx context.Context, listener Listener, event Event) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("listener of %s panicked: %v", event.Name, rec)
+		}
+	}()
+	return listener(ctx, event)
+}
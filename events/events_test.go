@@ -0,0 +1,79 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"github.com/haskekareem/sauri/jobs"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+type registered struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+func TestBus_RunsListenersInOrder(t *testing.T) {
+	bus := New(nil, nil)
+	var calls []string
+	bus.Listen("user.registered", func(ctx context.Context, e Event) error {
+		calls = append(calls, "exact")
+		return errors.New("mail server down")
+	})
+	bus.Listen("user.*", func(ctx context.Context, e Event) error {
+		calls = append(calls, "prefix")
+		panic("boom")
+	})
+	bus.Listen("*", func(ctx context.Context, e Event) error {
+		calls = append(calls, "all")
+		return nil
+	})
+	bus.Listen("order.paid", func(ctx context.Context, e Event) error {
+		calls = append(calls, "other")
+		return nil
+	})
+
+	err := bus.Emit("user.registered", registered{UserID: 1})
+	assert.Equal(t, []string{"exact", "prefix", "all"}, calls)
+	assert.ErrorContains(t, err, "mail server down")
+	assert.ErrorContains(t, err, "boom")
+}
+
+func TestBus_QueuedListeners(t *testing.T) {
+	manager := jobs.New(jobs.NewMemoryQueue(), jobs.Config{PollInterval: 5 * time.Millisecond})
+	bus := New(manager, nil)
+
+	var (
+		mu       sync.Mutex
+		received registered
+	)
+	assert.NoError(t, bus.ListenQueued("user.registered", "send-welcome-email", func(ctx context.Context, e Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return e.Decode(&received)
+	}))
+
+	assert.NoError(t, manager.Start(context.Background()))
+	defer func() { _ = manager.Shutdown(context.Background()) }()
+
+	assert.NoError(t, bus.Emit("user.registered", registered{UserID: 7, Email: "ada@example.com"}))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return received.UserID == 7
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "ada@example.com", received.Email)
+}
+
+func TestBus_QueuedListenersNeedAManager(t *testing.T) {
+	bus := New(nil, nil)
+	assert.Error(t, bus.ListenQueued("user.registered", "welcome", func(ctx context.Context, e Event) error { return nil }))
+}
+
+func TestEvent_Decode(t *testing.T) {
+	var payload registered
+	assert.NoError(t, Event{Name: "user.registered", Payload: registered{UserID: 3}}.Decode(&payload))
+	assert.Equal(t, 3, payload.UserID)
+}
@@ -0,0 +1,152 @@
+package sauri
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// csvFlushEvery is the number of CSV rows written between two flushes to the client
+const csvFlushEvery = 100
+
+// CSV streams rows as a CSV download named filename, rows is either a [][]string or a slice
+// of structs whose exported fields become the columns, named by their csv tag
+func (r *Response) CSV(rows interface{}, filename string) error {
+	records, err := csvRecords(rows)
+	if err != nil {
+		http.Error(r.Rw, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+
+	r.Header(contentType, "text/csv; charset=utf-8")
+	if filename != "" {
+		r.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	r.writeHeaders(http.StatusOK)
+
+	writer := csv.NewWriter(r.Rw)
+	for i := 0; i < records.len; i++ {
+		if err := writer.Write(records.row(i)); err != nil {
+			return err
+		}
+		if i%csvFlushEvery == csvFlushEvery-1 {
+			writer.Flush()
+			r.flush()
+		}
+	}
+	writer.Flush()
+	r.flush()
+	return writer.Error()
+}
+
+// NDJSON streams newline delimited JSON, every value encoded by stream is sent right away
+func (r *Response) NDJSON(stream func(enc *json.Encoder) error) error {
+	r.Header(contentType, "application/x-ndjson")
+	r.writeHeaders(http.StatusOK)
+
+	enc := json.NewEncoder(flushWriter{r})
+	return stream(enc)
+}
+
+// writeHeaders writes the headers of the response and the status code
+func (r *Response) writeHeaders(statusCode int) {
+	for key, values := range r.Hd {
+		for _, value := range values {
+			r.Rw.Header().Add(key, value)
+		}
+	}
+	r.Rw.WriteHeader(statusCode)
+}
+
+// flush sends what has been written so far to the client
+func (r *Response) flush() {
+	_ = http.NewResponseController(r.Rw).Flush()
+}
+
+// flushWriter flushes the response after every write
+type flushWriter struct {
+	r *Response
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.r.Rw.Write(p)
+	f.r.flush()
+	return n, err
+}
+
+// csvRows gives the CSV records one at a time so that struct rows are formatted as they
+// are written
+type csvRows struct {
+	len int
+	row func(i int) []string
+}
+
+// csvRecords returns the CSV records of a [][]string or of a slice of structs, the header
+// row of the structs included
+func csvRecords(rows interface{}) (csvRows, error) {
+	if records, ok := rows.([][]string); ok {
+		return csvRows{len: len(records), row: func(i int) []string { return records[i] }}, nil
+	}
+
+	value := reflect.ValueOf(rows)
+	if value.Kind() != reflect.Slice {
+		return csvRows{}, fmt.Errorf("csv rows must be a slice, got %T", rows)
+	}
+
+	elem := value.Type().Elem()
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return csvRows{}, errors.New("csv rows must be a [][]string or a slice of structs")
+	}
+
+	var header []string
+	var fields [][]int
+	for i := 0; i < elem.NumField(); i++ {
+		field := elem.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("csv"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		header = append(header, name)
+		fields = append(fields, field.Index)
+	}
+
+	return csvRows{len: value.Len() + 1, row: func(i int) []string {
+		if i == 0 {
+			return header
+		}
+		row := reflect.Indirect(value.Index(i - 1))
+		record := make([]string, len(fields))
+		if row.IsValid() {
+			for j, index := range fields {
+				record[j] = csvValue(row.FieldByIndex(index))
+			}
+		}
+		return record
+	}}, nil
+}
+
+// csvValue formats a field value for a CSV cell, nil pointers give an empty cell
+func csvValue(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,120 @@
+package sauri
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type csvOrder struct {
+	ID       int       `csv:"id"`
+	Customer string    `csv:"customer,omitempty"`
+	Note     *string   `csv:"note"`
+	Secret   string    `csv:"-"`
+	Placed   time.Time `csv:"placed_at"`
+	Total    float64
+	internal string
+}
+
+// recordsOf reads all the records of csvRecords
+func recordsOf(t *testing.T, rows interface{}) [][]string {
+	t.Helper()
+	records, err := csvRecords(rows)
+	require.NoError(t, err)
+	var all [][]string
+	for i := 0; i < records.len; i++ {
+		all = append(all, records.row(i))
+	}
+	return all
+}
+
+func TestCSVRecords(t *testing.T) {
+	note := "leave at the door"
+	placed := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	header := []string{"id", "customer", "note", "placed_at", "Total"}
+
+	t.Run("strings", func(t *testing.T) {
+		rows := [][]string{{"a", "b"}, {"c", "d"}}
+		assert.Equal(t, rows, recordsOf(t, rows))
+	})
+
+	t.Run("structs", func(t *testing.T) {
+		rows := []csvOrder{
+			{ID: 1, Customer: "Ada", Note: &note, Secret: "hidden", Placed: placed, Total: 9.5, internal: "x"},
+			{ID: 2, Customer: "Bo"},
+		}
+		assert.Equal(t, [][]string{
+			header,
+			{"1", "Ada", "leave at the door", "2024-03-01T09:30:00Z", "9.5"},
+			{"2", "Bo", "", "0001-01-01T00:00:00Z", "0"},
+		}, recordsOf(t, rows))
+	})
+
+	t.Run("pointers", func(t *testing.T) {
+		rows := []*csvOrder{{ID: 1, Customer: "Ada"}, nil}
+		assert.Equal(t, [][]string{
+			header,
+			{"1", "Ada", "", "0001-01-01T00:00:00Z", "0"},
+			// a nil row is an empty record
+			{"", "", "", "", ""},
+		}, recordsOf(t, rows))
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		assert.Equal(t, [][]string{header}, recordsOf(t, []csvOrder{}))
+	})
+
+	t.Run("not a slice", func(t *testing.T) {
+		_, err := csvRecords(csvOrder{})
+		assert.ErrorContains(t, err, "csv rows must be a slice")
+	})
+
+	t.Run("not structs", func(t *testing.T) {
+		_, err := csvRecords([]int{1, 2})
+		assert.ErrorContains(t, err, "csv rows must be a [][]string or a slice of structs")
+	})
+}
+
+func TestResponse_CSV(t *testing.T) {
+	rec := httptest.NewRecorder()
+	r := &Response{Rw: rec, Hd: http.Header{}}
+	require.NoError(t, r.CSV([]csvOrder{{ID: 1, Customer: "Ada, Ltd"}}, "orders.csv"))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=orders.csv`, rec.Header().Get("Content-Disposition"))
+	assert.Equal(t, "id,customer,note,placed_at,Total\n1,\"Ada, Ltd\",,0001-01-01T00:00:00Z,0\n", rec.Body.String())
+}
+
+func TestResponse_CSVInvalidRows(t *testing.T) {
+	rec := httptest.NewRecorder()
+	r := &Response{Rw: rec, Hd: http.Header{}}
+	assert.Error(t, r.CSV("not rows", "orders.csv"))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Disposition"))
+}
+
+func TestResponse_NDJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	r := &Response{Rw: rec, Hd: http.Header{}}
+	err := r.NDJSON(func(enc *json.Encoder) error {
+		for _, id := range []int{1, 2} {
+			if err := enc.Encode(map[string]int{"id": id}); err != nil {
+				return err
+			}
+			// every value is flushed as soon as it is encoded
+			assert.True(t, rec.Flushed)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"id\":1}\n{\"id\":2}\n", rec.Body.String())
+}
@@ -0,0 +1,35 @@
+package sauri
+
+import (
+	"fmt"
+	"github.com/haskekareem/sauri/storage"
+	"path/filepath"
+)
+
+// createStorage creates the disk files are stored on, chosen with STORAGE_DISK
+func (s *Sauri) createStorage(rootPath string) (storage.Disk, error) {
+	switch disk := s.Config.Get("STORAGE_DISK"); disk {
+	case "", "local":
+		root := s.Config.Get("STORAGE_ROOT", filepath.Join("storage", "app"))
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(rootPath, root)
+		}
+		baseURL := s.Config.Get("STORAGE_URL", "/storage")
+		public := s.Config.GetBool("STORAGE_PUBLIC", false)
+		return storage.NewLocal(root, baseURL, public, []byte(s.Config.Get("KEY"))), nil
+
+	case "s3":
+		s3 := storage.NewS3(s.Config.Get("S3_KEY"), s.Config.Get("S3_SECRET"), s.Config.Get("S3_REGION"),
+			s.Config.Get("S3_BUCKET"), s.Config.Get("S3_ENDPOINT"))
+		s3.PublicURL = s.Config.Get("S3_URL")
+		return s3, nil
+
+	case "gcs":
+		gcs := storage.NewGCS(s.Config.Get("GCS_KEY"), s.Config.Get("GCS_SECRET"), s.Config.Get("GCS_BUCKET"))
+		gcs.PublicURL = s.Config.Get("GCS_URL")
+		return gcs, nil
+
+	default:
+		return nil, fmt.Errorf("unknown storage disk %q", disk)
+	}
+}
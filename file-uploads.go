@@ -0,0 +1,244 @@
+package sauri
+
+import (
+	"errors"
+	"fmt"
+	"github.com/haskekareem/sauri/storage"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// FileNaming sets how uploaded files are named on the disk
+type FileNaming int
+
+const (
+	// SanitizedName keeps the sanitized client name, adding -1, -2... to avoid overwrites
+	SanitizedName FileNaming = iota
+	// RandomName names files with 32 random hex characters and their extension
+	RandomName
+	// UUIDName names files with a random UUID and their extension
+	UUIDName
+)
+
+// UploadOptions restricts and names the uploaded files
+type UploadOptions struct {
+	AllowedExtensions []string // e.g. []string{"jpg", "png"}, empty allows every extension
+	Naming            FileNaming
+	Overwrite         bool // replace an existing file with the same sanitized name
+}
+
+// UploadedFile describes a stored upload
+type UploadedFile struct {
+	Path         string // path on the storage disk
+	OriginalName string // name sent by the client, only use it for display
+	Size         int64
+	ContentType  string // content type sent by the client
+}
+
+// UploadFile stores the file of the form field under uploadDir on the storage disk. The client
+// name is sanitized so that it cannot leave uploadDir and existing files are never replaced
+// unless asked for. A disallowed extension gives storage.ErrExtensionNotAllowed.
+func (r *Response) UploadFile(fieldName, uploadDir string, req *http.Request, opts UploadOptions) (*UploadedFile, error) {
+	file, fileHeader, err := req.FormFile(fieldName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	name := storage.SanitizeFilename(fileHeader.Filename)
+	if !storage.AllowedExtension(name, opts.AllowedExtensions) {
+		return nil, fmt.Errorf("%w: %s", storage.ErrExtensionNotAllowed, path.Ext(name))
+	}
+
+	filePath, err := r.storeUpload(req, uploadDir, name, opts, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UploadedFile{
+		Path:         filePath,
+		OriginalName: fileHeader.Filename,
+		Size:         fileHeader.Size,
+		ContentType:  fileHeader.Header.Get(contentType),
+	}, nil
+}
+
+// storeUpload stores an uploaded file under the name given by the options and returns its
+// path, a sanitized name is only taken when no file exists at it
+func (r *Response) storeUpload(req *http.Request, uploadDir, name string, opts UploadOptions, content io.Reader) (string, error) {
+	var filePath string
+	switch {
+	case opts.Naming == RandomName:
+		filePath = path.Join(uploadDir, storage.RandomName(name))
+	case opts.Naming == UUIDName:
+		filePath = path.Join(uploadDir, storage.UUIDName(name))
+	case opts.Overwrite:
+		filePath = path.Join(uploadDir, name)
+	default:
+		return storage.PutUnique(req.Context(), r.disk(), uploadDir, name, content)
+	}
+	return filePath, r.disk().Put(req.Context(), filePath, content)
+}
+
+// defaultMaxUploadSize limits the body of streamed uploads when no limit is set
+const defaultMaxUploadSize = 32 << 20
+
+// maxFormValueSize limits the size of a single form value of a streamed upload
+const maxFormValueSize = 1 << 20
+
+// ErrUploadTooLarge is returned when an upload goes over its size limits
+var ErrUploadTooLarge = errors.New("upload too large")
+
+// UploadProgress reports how far a streamed upload has got
+type UploadProgress struct {
+	Field    string
+	Filename string // sanitized name of the file being stored
+	Written  int64  // bytes of the file stored so far
+	Received int64  // bytes of the request body read so far
+	Total    int64  // length of the request body, -1 when unknown
+}
+
+// StreamOptions restricts, names and follows the files of a streamed upload
+type StreamOptions struct {
+	UploadOptions
+	MaxSize     int64 // limit of the whole request body, 32MB when 0
+	MaxFileSize int64 // limit of each file, 0 for none
+	Progress    func(UploadProgress)
+}
+
+// StreamUpload reads a multipart request part by part and streams every file to the storage
+// disk under uploadDir, nothing is buffered in memory or parsed beforehand. The other form
+// values are returned along with the stored files. When anything fails the files already
+// stored are deleted, uploads over the limits give ErrUploadTooLarge.
+func (r *Response) StreamUpload(req *http.Request, uploadDir string, opts StreamOptions) ([]*UploadedFile, url.Values, error) {
+	maxSize := opts.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxUploadSize
+	}
+	body := &countingReader{reader: http.MaxBytesReader(r.Rw, req.Body, maxSize)}
+	req.Body = body
+
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx := req.Context()
+	disk := r.disk()
+	values := url.Values{}
+	var stored []*UploadedFile
+
+	fail := func(err error) ([]*UploadedFile, url.Values, error) {
+		for _, file := range stored {
+			_ = disk.Delete(ctx, file.Path)
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			err = fmt.Errorf("%w: the request is over %d bytes", ErrUploadTooLarge, maxSize)
+		}
+		return nil, nil, err
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+
+		field := part.FormName()
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, maxFormValueSize))
+			if err != nil {
+				return fail(err)
+			}
+			values.Add(field, string(value))
+			continue
+		}
+
+		file, err := r.streamPart(req, part, uploadDir, body, opts)
+		if file != nil {
+			stored = append(stored, file)
+		}
+		if err != nil {
+			return fail(err)
+		}
+	}
+	return stored, values, nil
+}
+
+// streamPart stores a single file part of a streamed upload
+func (r *Response) streamPart(req *http.Request, part *multipart.Part, uploadDir string, body *countingReader, opts StreamOptions) (*UploadedFile, error) {
+	name := storage.SanitizeFilename(part.FileName())
+	if !storage.AllowedExtension(name, opts.AllowedExtensions) {
+		return nil, fmt.Errorf("%w: %s", storage.ErrExtensionNotAllowed, path.Ext(name))
+	}
+
+	content := &progressReader{
+		reader:  part,
+		limit:   opts.MaxFileSize,
+		body:    body,
+		total:   req.ContentLength,
+		field:   part.FormName(),
+		name:    name,
+		onWrite: opts.Progress,
+	}
+	filePath, err := r.storeUpload(req, uploadDir, name, opts.UploadOptions, content)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UploadedFile{
+		Path:         filePath,
+		OriginalName: part.FileName(),
+		Size:         content.written,
+		ContentType:  part.Header.Get(contentType),
+	}, nil
+}
+
+// countingReader counts the bytes read from the request body
+type countingReader struct {
+	reader io.ReadCloser
+	read   int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.read += int64(n)
+	return n, err
+}
+
+func (c *countingReader) Close() error {
+	return c.reader.Close()
+}
+
+// progressReader enforces the size limit of a file and reports the progress of its upload
+type progressReader struct {
+	reader  io.Reader
+	limit   int64
+	written int64
+	body    *countingReader
+	total   int64
+	field   string
+	name    string
+	onWrite func(UploadProgress)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	p.written += int64(n)
+	if p.limit > 0 && p.written > p.limit {
+		return n, fmt.Errorf("%w: %s is over %d bytes", ErrUploadTooLarge, p.name, p.limit)
+	}
+	if n > 0 && p.onWrite != nil {
+		p.onWrite(UploadProgress{Field: p.field, Filename: p.name, Written: p.written, Received: p.body.read, Total: p.total})
+	}
+	return n, err
+}
@@ -0,0 +1,179 @@
+package sauri
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/haskekareem/sauri/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newUploadRequest builds a multipart request, the files are given by field name
+func newUploadRequest(t *testing.T, values map[string]string, files map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for field, value := range values {
+		require.NoError(t, writer.WriteField(field, value))
+	}
+	for field, content := range files {
+		part, err := writer.CreateFormFile(field, field+".txt")
+		require.NoError(t, err)
+		_, err = io.WriteString(part, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestHandleFileUpload(t *testing.T) {
+	disk := storage.NewLocal(t.TempDir(), "", false, nil)
+	r := &Response{Rw: httptest.NewRecorder(), Hd: http.Header{}, Disk: disk}
+
+	// a relative directory is on the storage disk
+	filePath, err := r.HandleFileUpload("avatar", "uploads", newUploadRequest(t, nil, map[string]string{"avatar": "on the disk"}))
+	require.NoError(t, err)
+	assert.Equal(t, "uploads/avatar.txt", filePath)
+	exists, err := disk.Exists(context.Background(), filePath)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// an absolute directory is on the file system, as before the disks
+	dir := t.TempDir()
+	filePath, err = r.HandleFileUpload("avatar", dir, newUploadRequest(t, nil, map[string]string{"avatar": "on the file system"}))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "avatar.txt"), filePath)
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "on the file system", string(content))
+
+	rec := httptest.NewRecorder()
+	r = &Response{Rw: rec, Hd: http.Header{}, Disk: disk}
+	require.NoError(t, r.DownloadFile(dir, "avatar.txt", httptest.NewRequest(http.MethodGet, "/", nil)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "on the file system", rec.Body.String())
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "attachment")
+}
+
+// newStreamRequest builds a multipart request of file parts in order, as field and content pairs
+func newStreamRequest(t *testing.T, values map[string]string, files ...[2]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for field, value := range values {
+		require.NoError(t, writer.WriteField(field, value))
+	}
+	for _, file := range files {
+		part, err := writer.CreateFormFile(file[0], file[0]+".txt")
+		require.NoError(t, err)
+		_, err = io.WriteString(part, file[1])
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// storedFiles lists the files under the root of a local disk
+func storedFiles(t *testing.T, root string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			files = append(files, path)
+		}
+		return err
+	})
+	require.NoError(t, err)
+	return files
+}
+
+func TestStreamUpload(t *testing.T) {
+	root := t.TempDir()
+	r := &Response{Rw: httptest.NewRecorder(), Hd: http.Header{}, Disk: storage.NewLocal(root, "", false, nil)}
+	avatar := strings.Repeat("a", 64<<10)
+	req := newStreamRequest(t, map[string]string{"title": "holiday"}, [2]string{"avatar", avatar}, [2]string{"cover", "cover"})
+
+	var progress []UploadProgress
+	files, values, err := r.StreamUpload(req, "uploads", StreamOptions{
+		MaxFileSize: int64(len(avatar)),
+		Progress:    func(p UploadProgress) { progress = append(progress, p) },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "holiday", values.Get("title"))
+	require.Len(t, files, 2)
+	assert.Equal(t, "uploads/avatar.txt", files[0].Path)
+	assert.Equal(t, int64(len(avatar)), files[0].Size)
+	assert.Equal(t, "uploads/cover.txt", files[1].Path)
+	assert.Equal(t, int64(len("cover")), files[1].Size)
+	content, err := os.ReadFile(filepath.Join(root, "uploads", "avatar.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, avatar, string(content))
+
+	// the progress grows with every read, up to the size of each file
+	require.NotEmpty(t, progress)
+	var written, received int64
+	for _, p := range progress {
+		assert.Equal(t, req.ContentLength, p.Total)
+		assert.GreaterOrEqual(t, p.Received, received)
+		received = p.Received
+		if p.Field == "avatar" {
+			assert.Equal(t, "avatar.txt", p.Filename)
+			assert.Greater(t, p.Written, written)
+			written = p.Written
+		}
+	}
+	assert.Equal(t, int64(len(avatar)), written)
+	last := progress[len(progress)-1]
+	assert.Equal(t, "cover", last.Field)
+	assert.Equal(t, int64(len("cover")), last.Written)
+}
+
+func TestStreamUpload_MaxFileSize(t *testing.T) {
+	root := t.TempDir()
+	r := &Response{Rw: httptest.NewRecorder(), Hd: http.Header{}, Disk: storage.NewLocal(root, "", false, nil)}
+	req := newStreamRequest(t, nil, [2]string{"small", "small"}, [2]string{"large", strings.Repeat("l", 100)})
+
+	files, values, err := r.StreamUpload(req, "uploads", StreamOptions{MaxFileSize: 50})
+	assert.ErrorIs(t, err, ErrUploadTooLarge)
+	assert.ErrorContains(t, err, "large.txt is over 50 bytes")
+	assert.Nil(t, files)
+	assert.Nil(t, values)
+	// the file stored before the failure and the partial one are both removed
+	assert.Empty(t, storedFiles(t, root))
+}
+
+func TestStreamUpload_MaxSize(t *testing.T) {
+	root := t.TempDir()
+	r := &Response{Rw: httptest.NewRecorder(), Hd: http.Header{}, Disk: storage.NewLocal(root, "", false, nil)}
+	req := newStreamRequest(t, nil, [2]string{"small", "small"}, [2]string{"large", strings.Repeat("l", 1000)})
+
+	_, _, err := r.StreamUpload(req, "uploads", StreamOptions{MaxSize: 500})
+	assert.ErrorIs(t, err, ErrUploadTooLarge)
+	assert.ErrorContains(t, err, "the request is over 500 bytes")
+	assert.Empty(t, storedFiles(t, root))
+}
+
+func TestStreamUpload_DisallowedExtension(t *testing.T) {
+	root := t.TempDir()
+	r := &Response{Rw: httptest.NewRecorder(), Hd: http.Header{}, Disk: storage.NewLocal(root, "", false, nil)}
+	req := newStreamRequest(t, nil, [2]string{"notes", "notes"}, [2]string{"script", "script"})
+
+	_, _, err := r.StreamUpload(req, "uploads", StreamOptions{UploadOptions: UploadOptions{AllowedExtensions: []string{"png"}}})
+	assert.ErrorIs(t, err, storage.ErrExtensionNotAllowed)
+	assert.Empty(t, storedFiles(t, root))
+}
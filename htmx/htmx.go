@@ -0,0 +1,175 @@
+package htmx
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// request headers sent by htmx
+const (
+	HeaderRequest        = "HX-Request"
+	HeaderBoosted        = "HX-Boosted"
+	HeaderCurrentURL     = "HX-Current-URL"
+	HeaderHistoryRestore = "HX-History-Restore-Request"
+	HeaderPrompt         = "HX-Prompt"
+	HeaderTarget         = "HX-Target"
+	HeaderTriggerName    = "HX-Trigger-Name"
+	HeaderTrigger        = "HX-Trigger"
+)
+
+// response headers understood by htmx
+const (
+	HeaderLocation           = "HX-Location"
+	HeaderPushURL            = "HX-Push-Url"
+	HeaderRedirect           = "HX-Redirect"
+	HeaderRefresh            = "HX-Refresh"
+	HeaderReplaceURL         = "HX-Replace-Url"
+	HeaderReswap             = "HX-Reswap"
+	HeaderRetarget           = "HX-Retarget"
+	HeaderReselect           = "HX-Reselect"
+	HeaderTriggerAfterSettle = "HX-Trigger-After-Settle"
+	HeaderTriggerAfterSwap   = "HX-Trigger-After-Swap"
+)
+
+// CSRFHeader is the header nosurf reads the CSRF token from on htmx requests
+const CSRFHeader = "X-CSRF-Token"
+
+// ============================ request detection ============================
+
+// IsRequest reports whether the request was issued by htmx
+func IsRequest(r *http.Request) bool {
+	return r.Header.Get(HeaderRequest) == "true"
+}
+
+// IsBoosted reports whether the request came from an element using hx-boost
+func IsBoosted(r *http.Request) bool {
+	return r.Header.Get(HeaderBoosted) == "true"
+}
+
+// IsHistoryRestore reports whether the request is for history restoration after a miss in the local cache
+func IsHistoryRestore(r *http.Request) bool {
+	return r.Header.Get(HeaderHistoryRestore) == "true"
+}
+
+// IsPartial reports whether only a fragment of the page should be rendered, that is an
+// htmx request which is neither boosted nor a history restore
+func IsPartial(r *http.Request) bool {
+	return IsRequest(r) && !IsBoosted(r) && !IsHistoryRestore(r)
+}
+
+// CurrentURL returns the current URL of the browser
+func CurrentURL(r *http.Request) string {
+	return r.Header.Get(HeaderCurrentURL)
+}
+
+// Prompt returns the user response to an hx-prompt
+func Prompt(r *http.Request) string {
+	return r.Header.Get(HeaderPrompt)
+}
+
+// Target returns the id of the target element if it exists
+func Target(r *http.Request) string {
+	return r.Header.Get(HeaderTarget)
+}
+
+// TriggerName returns the name of the triggered element if it exists
+func TriggerName(r *http.Request) string {
+	return r.Header.Get(HeaderTriggerName)
+}
+
+// TriggerID returns the id of the triggered element if it exists
+func TriggerID(r *http.Request) string {
+	return r.Header.Get(HeaderTrigger)
+}
+
+// ============================ response helpers ============================
+
+// Trigger triggers one or more client side events as soon as the response is received
+func Trigger(w http.ResponseWriter, events ...string) {
+	w.Header().Set(HeaderTrigger, strings.Join(events, ", "))
+}
+
+// TriggerWithDetails triggers client side events carrying a detail payload each
+func TriggerWithDetails(w http.ResponseWriter, events map[string]any) error {
+	return setJSONHeader(w, HeaderTrigger, events)
+}
+
+// TriggerAfterSwap triggers client side events after the swap step
+func TriggerAfterSwap(w http.ResponseWriter, events ...string) {
+	w.Header().Set(HeaderTriggerAfterSwap, strings.Join(events, ", "))
+}
+
+// TriggerAfterSettle triggers client side events after the settle step
+func TriggerAfterSettle(w http.ResponseWriter, events ...string) {
+	w.Header().Set(HeaderTriggerAfterSettle, strings.Join(events, ", "))
+}
+
+// Redirect does a client side redirect to a new location with a full page reload
+func Redirect(w http.ResponseWriter, url string) {
+	w.Header().Set(HeaderRedirect, url)
+}
+
+// Location does a client side redirect without a full page reload
+func Location(w http.ResponseWriter, url string) {
+	w.Header().Set(HeaderLocation, url)
+}
+
+// PushURL pushes a new url into the browser history stack
+func PushURL(w http.ResponseWriter, url string) {
+	w.Header().Set(HeaderPushURL, url)
+}
+
+// ReplaceURL replaces the current url in the location bar
+func ReplaceURL(w http.ResponseWriter, url string) {
+	w.Header().Set(HeaderReplaceURL, url)
+}
+
+// Refresh makes the client do a full refresh of the page
+func Refresh(w http.ResponseWriter) {
+	w.Header().Set(HeaderRefresh, "true")
+}
+
+// Retarget changes the element the response content is swapped into (a css selector)
+func Retarget(w http.ResponseWriter, selector string) {
+	w.Header().Set(HeaderRetarget, selector)
+}
+
+// Reswap changes how the response will be swapped, e.g. "outerHTML"
+func Reswap(w http.ResponseWriter, strategy string) {
+	w.Header().Set(HeaderReswap, strategy)
+}
+
+// Reselect chooses which part of the response is used to be swapped in (a css selector)
+func Reselect(w http.ResponseWriter, selector string) {
+	w.Header().Set(HeaderReselect, selector)
+}
+
+// VaryOnRequest tells caches that the response differs between htmx and normal requests
+func VaryOnRequest(w http.ResponseWriter) {
+	w.Header().Add("Vary", HeaderRequest)
+}
+
+// StopPolling responds with the status code htmx uses to stop polling an element
+func StopPolling(w http.ResponseWriter) {
+	w.WriteHeader(286)
+}
+
+// ============================ CSRF integration ============================
+
+// CSRFHeaders returns the JSON value for an hx-headers attribute carrying the CSRF token, so
+// htmx requests pass the NoSurf middleware, e.g. <body hx-headers='{{ .HTMXHeaders }}'>
+func CSRFHeaders(token string) string {
+	content, _ := json.Marshal(map[string]string{CSRFHeader: token})
+	return string(content)
+}
+
+// setJSONHeader sets the header to the JSON encoding of value
+func setJSONHeader(w http.ResponseWriter, key string, value any) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	w.Header().Set(key, string(content))
+	return nil
+}
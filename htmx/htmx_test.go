@@ -0,0 +1,46 @@
+package htmx
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestDetection(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.False(t, IsRequest(req))
+	assert.False(t, IsPartial(req))
+
+	req.Header.Set(HeaderRequest, "true")
+	req.Header.Set(HeaderTarget, "content")
+	assert.True(t, IsRequest(req))
+	assert.True(t, IsPartial(req))
+	assert.Equal(t, "content", Target(req))
+
+	// boosted requests expect the full page
+	req.Header.Set(HeaderBoosted, "true")
+	assert.False(t, IsPartial(req))
+}
+
+func TestResponseHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	Trigger(w, "saved", "refresh-list")
+	PushURL(w, "/users/1")
+	Redirect(w, "/login")
+	VaryOnRequest(w)
+
+	assert.Equal(t, "saved, refresh-list", w.Header().Get(HeaderTrigger))
+	assert.Equal(t, "/users/1", w.Header().Get(HeaderPushURL))
+	assert.Equal(t, "/login", w.Header().Get(HeaderRedirect))
+	assert.Equal(t, HeaderRequest, w.Header().Get("Vary"))
+
+	err := TriggerWithDetails(w, map[string]any{"notify": map[string]string{"level": "info"}})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"notify":{"level":"info"}}`, w.Header().Get(HeaderTrigger))
+}
+
+func TestCSRFHeaders(t *testing.T) {
+	assert.Equal(t, `{"X-CSRF-Token":"abc"}`, CSRFHeaders("abc"))
+}
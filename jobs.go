@@ -0,0 +1,219 @@
+package sauri
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/haskekareem/sauri/jobs"
+	"github.com/haskekareem/sauri/mailer"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// the arguments running the queue commands instead of the application, see QueueCommand
+const (
+	queueWorkCommand   = "queue:work"
+	queueRetryCommand  = "queue:retry"
+	queueFailedCommand = "queue:failed"
+	mailRetryCommand   = "mail:retry"
+	mailFailedCommand  = "mail:failed"
+)
+
+// initJobs creates the job manager. Jobs are kept in Redis when JOBS_QUEUE is redis, or
+// when it is empty and Redis is in use, and in memory otherwise. The workers start with the
+// server and drain on shutdown, they run the jobs of the JOBS_QUEUES queues, the default one
+// when it is empty.
+func (s *Sauri) initJobs() {
+	var queue jobs.Queue
+	switch s.Config.Get("JOBS_QUEUE") {
+	case "redis":
+		if myRedisCache != nil {
+			queue = jobs.NewRedisQueue(myRedisCache.Conn, s.config.redis.prefix)
+			break
+		}
+		pool := s.NewRedisConnPool()
+		s.OnShutdown(func(ctx context.Context) error {
+			return pool.Close()
+		})
+		queue = jobs.NewRedisQueue(pool, s.config.redis.prefix)
+	case "memory":
+		queue = jobs.NewMemoryQueue()
+	default:
+		if myRedisCache != nil {
+			queue = jobs.NewRedisQueue(myRedisCache.Conn, s.config.redis.prefix)
+		} else {
+			queue = jobs.NewMemoryQueue()
+		}
+	}
+
+	s.Jobs = jobs.New(queue, jobs.Config{
+		Concurrency: s.Config.GetInt("JOBS_CONCURRENCY", 0),
+		Queues:      s.Config.GetStrings("JOBS_QUEUES"),
+		MaxAttempts: s.Config.GetInt("JOBS_MAX_ATTEMPTS", 0),
+		Timeout:     s.Config.GetDuration("JOBS_TIMEOUT", time.Second, 0),
+		Logger:      s.moduleLogger("jobs"),
+	})
+
+	s.OnStart(func(ctx context.Context) error {
+		return s.Jobs.Start(s.Context())
+	})
+	s.OnShutdown(s.Jobs.Shutdown)
+}
+
+// QueueCommand runs a queue command when args, usually os.Args[1:], start with one and
+// reports whether it did, so that main exits instead of serving. The jobs are registered by
+// the application code, `sauri queue:work`, `sauri queue:retry` and `sauri queue:failed` run
+// the application this way:
+//
+//	if ran, err := app.QueueCommand(os.Args[1:]); ran {
+//		...
+//	}
+//
+// queue:work [--queue=a,b] [--concurrency=n] runs workers without the server until SIGINT or
+// SIGTERM, then lets the running jobs finish within SHUTDOWN_TIMEOUT. queue:failed lists the
+// failed jobs and queue:retry <id...|all> queues them again; mail:failed and mail:retry do
+// the same for the emails queued with Mailer.UseJobs.
+func (s *Sauri) QueueCommand(args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+	switch args[0] {
+	case queueWorkCommand:
+		return true, s.queueWork(args[1:])
+	case queueRetryCommand:
+		return true, s.queueRetry(context.Background(), os.Stdout, args[1:], "")
+	case queueFailedCommand:
+		return true, s.queueFailed(context.Background(), os.Stdout)
+	case mailRetryCommand:
+		return true, s.queueRetry(context.Background(), os.Stdout, args[1:], mailer.SendJob)
+	case mailFailedCommand:
+		return true, s.mailFailed(context.Background(), os.Stdout)
+	}
+	return false, nil
+}
+
+// queueWork runs workers until the process is interrupted or terminated
+func (s *Sauri) queueWork(args []string) error {
+	flags := flag.NewFlagSet(queueWorkCommand, flag.ContinueOnError)
+	queues := flags.String("queue", "", "comma separated queues, in priority order")
+	concurrency := flags.Int("concurrency", 0, "number of workers")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if _, ok := s.Jobs.Queue().(*jobs.MemoryQueue); ok {
+		return errors.New("the workers need a queue shared with the application, set JOBS_QUEUE=redis")
+	}
+
+	var names []string
+	for _, name := range strings.Split(*queues, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		names = s.Config.GetStrings("JOBS_QUEUES")
+	}
+
+	ctx, stop := signal.NotifyContext(s.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := s.Jobs.StartWorkers(s.Context(), *concurrency, names...); err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		names = []string{jobs.DefaultQueue}
+	}
+	s.log().Info("workers started", "queues", names)
+
+	<-ctx.Done()
+	s.log().Info("stopping the workers, waiting for the running jobs")
+	s.shutdown()
+	return nil
+}
+
+// queueRetry queues failed jobs again, all of them with all, or all the ones of the job name
+// when it is not empty
+func (s *Sauri) queueRetry(ctx context.Context, out io.Writer, ids []string, name string) error {
+	if len(ids) == 0 {
+		return errors.New("usage: queue:retry <id...|all>")
+	}
+	if len(ids) == 1 && ids[0] == "all" {
+		failed, err := s.Jobs.Queue().Failed(ctx)
+		if err != nil {
+			return err
+		}
+		ids = ids[:0]
+		for _, e := range failed {
+			if name == "" || e.Name == name {
+				ids = append(ids, e.ID)
+			}
+		}
+	}
+
+	var errs []error
+	for _, id := range ids {
+		if err := s.Jobs.Retry(ctx, id); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+			continue
+		}
+		_, _ = fmt.Fprintf(out, "%s queued again\n", id)
+	}
+	return errors.Join(errs...)
+}
+
+// queueFailed lists the failed jobs, the oldest first
+func (s *Sauri) queueFailed(ctx context.Context, out io.Writer) error {
+	failed, err := s.Jobs.Queue().Failed(ctx)
+	if err != nil {
+		return err
+	}
+	if len(failed) == 0 {
+		_, _ = fmt.Fprintln(out, "no failed jobs")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tQUEUE\tJOB\tATTEMPTS\tFAILED AT\tERROR")
+	for _, e := range failed {
+		queue := e.Queue
+		if queue == "" {
+			queue = jobs.DefaultQueue
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", e.ID, queue, e.Name, e.Attempts,
+			e.FailedAt.Format(time.DateTime), e.LastError)
+	}
+	return w.Flush()
+}
+
+// mailFailed lists the queued emails that failed every attempt, the oldest first
+func (s *Sauri) mailFailed(ctx context.Context, out io.Writer) error {
+	failed, err := mailer.FailedEmails(ctx, s.Jobs.Queue())
+	if err != nil {
+		return err
+	}
+	if len(failed) == 0 {
+		_, _ = fmt.Fprintln(out, "no failed emails")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tTO\tSUBJECT\tATTEMPTS\tFAILED AT\tERROR")
+	for _, email := range failed {
+		to, subject := "?", "?"
+		if email.Message != nil {
+			addresses := make([]string, len(email.Message.To))
+			for i, address := range email.Message.To {
+				addresses[i] = address.Address
+			}
+			to, subject = strings.Join(addresses, ","), email.Message.Subject
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", email.ID, to, subject, email.Attempts,
+			email.FailedAt.Format(time.DateTime), email.Error)
+	}
+	return w.Flush()
+}
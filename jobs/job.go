@@ -0,0 +1,94 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Job is a unit of work run by the workers. It is stored as JSON in the queue, so its
+// exported fields are all the handler gets back.
+type Job interface {
+	// Name identifies the job type, it must be registered with Manager.Register
+	Name() string
+	Handle(ctx context.Context) error
+}
+
+// DefaultQueue is the queue of the jobs dispatched without OnQueue
+const DefaultQueue = "default"
+
+// Envelope is a job as stored in the queue
+type Envelope struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Queue       string          `json:"queue,omitempty"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	AvailableAt time.Time       `json:"available_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+	LastError   string          `json:"last_error,omitempty"`
+	FailedAt    time.Time       `json:"failed_at,omitempty"`
+}
+
+// Option changes how a job is dispatched
+type Option func(e *Envelope)
+
+// Delay runs the job once d has passed
+func Delay(d time.Duration) Option {
+	return func(e *Envelope) {
+		e.AvailableAt = time.Now().Add(d)
+	}
+}
+
+// At runs the job at t
+func At(t time.Time) Option {
+	return func(e *Envelope) {
+		e.AvailableAt = t
+	}
+}
+
+// MaxAttempts overrides the number of attempts of the manager for the job
+func MaxAttempts(n int) Option {
+	return func(e *Envelope) {
+		if n > 0 {
+			e.MaxAttempts = n
+		}
+	}
+}
+
+// OnQueue puts the job on a named queue, run by the workers of that queue, e.g. a queue of
+// emails run by workers of their own
+func OnQueue(name string) Option {
+	return func(e *Envelope) {
+		if name != "" {
+			e.Queue = name
+		}
+	}
+}
+
+// queueOf returns the queue of a job, the jobs queued before the named queues existed are on
+// the default one
+func queueOf(e *Envelope) string {
+	if e.Queue == "" {
+		return DefaultQueue
+	}
+	return e.Queue
+}
+
+// queueNames returns the queues of a Pop or a Len, the default one when none is given
+func queueNames(queues []string) []string {
+	if len(queues) == 0 {
+		return []string{DefaultQueue}
+	}
+	return queues
+}
+
+// newID returns a random job ID
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,213 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/alicebob/miniredis"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countJob counts its runs and fails the first FailTimes ones
+type countJob struct {
+	Key       string
+	FailTimes int32
+}
+
+var runs = map[string]*atomic.Int32{}
+
+func (j *countJob) Name() string {
+	return "count"
+}
+
+func (j *countJob) Handle(ctx context.Context) error {
+	if runs[j.Key].Add(1) <= j.FailTimes {
+		return errors.New("not yet")
+	}
+	return nil
+}
+
+// panicJob always panics
+type panicJob struct{}
+
+func (panicJob) Name() string {
+	return "panic"
+}
+
+func (panicJob) Handle(ctx context.Context) error {
+	panic("boom")
+}
+
+func newTestManager(queue Queue) *Manager {
+	m := New(queue, Config{
+		Concurrency:  2,
+		MaxAttempts:  3,
+		PollInterval: 5 * time.Millisecond,
+		Backoff:      func(int) time.Duration { return time.Millisecond },
+	})
+	m.Register(&countJob{}, panicJob{})
+	return m
+}
+
+func TestManager_RetriesThenSucceeds(t *testing.T) {
+	runs["retry"] = &atomic.Int32{}
+	queue := NewMemoryQueue()
+	m := newTestManager(queue)
+	assert.NoError(t, m.Start(context.Background()))
+	defer func() { _ = m.Shutdown(context.Background()) }()
+
+	assert.NoError(t, m.Dispatch(&countJob{Key: "retry", FailTimes: 2}))
+	assert.Eventually(t, func() bool { return runs["retry"].Load() == 3 }, time.Second, 5*time.Millisecond)
+
+	failed, _ := queue.Failed(context.Background())
+	assert.Empty(t, failed)
+}
+
+func TestManager_BuriesAfterMaxAttempts(t *testing.T) {
+	queue := NewMemoryQueue()
+	m := newTestManager(queue)
+	assert.NoError(t, m.Start(context.Background()))
+	defer func() { _ = m.Shutdown(context.Background()) }()
+
+	assert.NoError(t, m.Dispatch(panicJob{}, MaxAttempts(2)))
+	assert.Eventually(t, func() bool {
+		failed, _ := queue.Failed(context.Background())
+		return len(failed) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	failed, _ := queue.Failed(context.Background())
+	assert.Equal(t, 2, failed[0].Attempts)
+	assert.Contains(t, failed[0].LastError, "boom")
+}
+
+func TestManager_BuriesPermanentErrors(t *testing.T) {
+	queue := NewMemoryQueue()
+	m := newTestManager(queue)
+	var calls atomic.Int32
+	m.HandleFunc("invalid", func(ctx context.Context, payload json.RawMessage) error {
+		calls.Add(1)
+		return Permanent(errors.New("invalid payload"))
+	})
+	assert.NoError(t, m.Start(context.Background()))
+	defer func() { _ = m.Shutdown(context.Background()) }()
+
+	assert.NoError(t, m.DispatchPayload(context.Background(), "invalid", "x"))
+	assert.Eventually(t, func() bool {
+		failed, _ := queue.Failed(context.Background())
+		return len(failed) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Equal(t, int32(1), calls.Load())
+	failed, _ := queue.Failed(context.Background())
+	assert.Equal(t, "invalid payload", failed[0].LastError)
+}
+
+func TestManager_RunsItsQueuesOnly(t *testing.T) {
+	runs["emails"] = &atomic.Int32{}
+	runs["other"] = &atomic.Int32{}
+	queue := NewMemoryQueue()
+	m := newTestManager(queue)
+	assert.NoError(t, m.StartWorkers(context.Background(), 1, "emails"))
+	defer func() { _ = m.Shutdown(context.Background()) }()
+
+	assert.NoError(t, m.Dispatch(&countJob{Key: "other"}))
+	assert.NoError(t, m.Dispatch(&countJob{Key: "emails"}, OnQueue("emails")))
+	assert.Eventually(t, func() bool { return runs["emails"].Load() == 1 }, time.Second, 5*time.Millisecond)
+
+	assert.Equal(t, int32(0), runs["other"].Load())
+	n, _ := queue.Len(context.Background())
+	assert.Equal(t, 1, n)
+}
+
+func TestManager_Retry(t *testing.T) {
+	queue := NewMemoryQueue()
+	m := newTestManager(queue)
+	ctx := context.Background()
+	assert.NoError(t, queue.Bury(ctx, &Envelope{ID: "dead", Name: "count", Queue: "emails", Attempts: 3,
+		LastError: "boom", FailedAt: time.Now()}))
+
+	assert.ErrorIs(t, m.Retry(ctx, "unknown"), ErrNotFailed)
+	assert.NoError(t, m.Retry(ctx, "dead"))
+	failed, _ := queue.Failed(ctx)
+	assert.Empty(t, failed)
+
+	e, err := queue.Pop(ctx, "emails")
+	assert.NoError(t, err)
+	if assert.NotNil(t, e) {
+		assert.Equal(t, 0, e.Attempts)
+		assert.Empty(t, e.LastError)
+	}
+}
+
+func TestManager_RejectsUnregisteredJobs(t *testing.T) {
+	m := New(NewMemoryQueue(), Config{})
+	assert.Error(t, m.Dispatch(panicJob{}))
+}
+
+func TestMemoryQueue_DelaysJobs(t *testing.T) {
+	queue := NewMemoryQueue()
+	ctx := context.Background()
+	assert.NoError(t, queue.Push(ctx, &Envelope{ID: "later", AvailableAt: time.Now().Add(time.Hour)}))
+
+	e, err := queue.Pop(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, e)
+	n, _ := queue.Len(ctx)
+	assert.Equal(t, 1, n)
+}
+
+func TestRedisQueue(t *testing.T) {
+	server, err := miniredis.Run()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer server.Close()
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) { return redis.Dial("tcp", server.Addr()) }}
+	defer func() { _ = pool.Close() }()
+
+	queue := NewRedisQueue(pool, "test")
+	ctx := context.Background()
+	assert.NoError(t, queue.Push(ctx, &Envelope{ID: "now", Name: "count", AvailableAt: time.Now()}))
+	assert.NoError(t, queue.Push(ctx, &Envelope{ID: "later", Name: "count", AvailableAt: time.Now().Add(time.Hour)}))
+
+	e, err := queue.Pop(ctx)
+	assert.NoError(t, err)
+	if assert.NotNil(t, e) {
+		assert.Equal(t, "now", e.ID)
+	}
+	e, err = queue.Pop(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, e)
+
+	assert.NoError(t, queue.Bury(ctx, &Envelope{ID: "dead", Name: "count"}))
+	failed, err := queue.Failed(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, failed, 1) {
+		assert.Equal(t, "dead", failed[0].ID)
+	}
+	n, _ := queue.Len(ctx)
+	assert.Equal(t, 1, n)
+
+	assert.NoError(t, queue.Push(ctx, &Envelope{ID: "email", Name: "count", Queue: "emails", AvailableAt: time.Now()}))
+	e, err = queue.Pop(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, e)
+	e, err = queue.Pop(ctx, "high", "emails")
+	assert.NoError(t, err)
+	if assert.NotNil(t, e) {
+		assert.Equal(t, "email", e.ID)
+	}
+
+	e, err = queue.Forget(ctx, "dead")
+	assert.NoError(t, err)
+	if assert.NotNil(t, e) {
+		assert.Equal(t, "dead", e.ID)
+	}
+	e, err = queue.Forget(ctx, "dead")
+	assert.NoError(t, err)
+	assert.Nil(t, e)
+}
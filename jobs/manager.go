@@ -0,0 +1,373 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ErrStopped is returned by Start once the manager was shut down
+var ErrStopped = errors.New("jobs: the manager is shut down")
+
+// ErrNotFailed is returned by Retry when no failed job has the id
+var ErrNotFailed = errors.New("jobs: no failed job with this id")
+
+// permanentError is the error of a job that fails the same way on every attempt
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks the error of a job that would fail again, e.g. a payload that cannot be
+// decoded, so that the job goes to the failed jobs without using its attempts left
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Config sets up a Manager, the zero value gives the defaults
+type Config struct {
+	Concurrency  int           // workers, 4 when zero
+	Queues       []string      // queues the workers take jobs from in priority order, the default one when empty
+	MaxAttempts  int           // attempts before a job goes to the dead letters, 3 when zero
+	Timeout      time.Duration // time a job may run, unlimited when zero
+	PollInterval time.Duration // wait when the queue is empty, a second when zero
+	// Backoff returns the wait before the next attempt of a job that failed its attempt-th
+	// try, an exponential one from a second up to an hour when nil
+	Backoff func(attempt int) time.Duration
+	Logger  *slog.Logger // slog.Default when nil
+}
+
+// Manager dispatches jobs to a queue and runs them on a pool of workers
+type Manager struct {
+	queue  Queue
+	config Config
+
+	mu       sync.RWMutex
+	types    map[string]reflect.Type
+	handlers map[string]HandlerFunc
+	started  bool
+	stopped  bool
+	stop     chan struct{}
+	workers  sync.WaitGroup
+	// cancels the jobs still running when the shutdown deadline is reached
+	cancelJobs context.CancelFunc
+}
+
+// New returns a manager using the queue, workers start with Start
+func New(queue Queue, config Config) *Manager {
+	if config.Concurrency <= 0 {
+		config.Concurrency = 4
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.Backoff == nil {
+		config.Backoff = ExponentialBackoff(time.Second, time.Hour)
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	return &Manager{
+		queue:    queue,
+		config:   config,
+		types:    make(map[string]reflect.Type),
+		handlers: make(map[string]HandlerFunc),
+		stop:     make(chan struct{}),
+	}
+}
+
+// ExponentialBackoff doubles the wait after every attempt, from base up to max
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		wait := time.Duration(float64(base) * math.Pow(2, float64(attempt-1)))
+		if wait > max || wait <= 0 {
+			return max
+		}
+		return wait
+	}
+}
+
+// Queue returns the queue of the manager
+func (m *Manager) Queue() Queue {
+	return m.queue
+}
+
+// Register registers job types so that the workers can decode them, pass a zero value of
+// each, e.g. m.Register(&SendWelcomeEmail{})
+func (m *Manager) Register(jobs ...Job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range jobs {
+		t := reflect.TypeOf(job)
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		m.types[job.Name()] = t
+	}
+}
+
+// HandlerFunc handles the jobs of a name registered with HandleFunc, payload is the JSON
+// given to DispatchPayload
+type HandlerFunc func(ctx context.Context, payload json.RawMessage) error
+
+// HandleFunc registers a function handling the jobs dispatched under name with
+// DispatchPayload, for handlers that need more than the job content such as a closure over
+// the application
+func (m *Manager) HandleFunc(name string, fn HandlerFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[name] = fn
+}
+
+// DispatchPayload queues a job for the function registered under name, payload is encoded
+// as JSON
+func (m *Manager) DispatchPayload(ctx context.Context, name string, payload interface{}, options ...Option) error {
+	m.mu.RLock()
+	_, ok := m.handlers[name]
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("jobs: handler %s is not registered", name)
+	}
+
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("jobs: cannot encode job %s: %w", name, err)
+	}
+	return m.push(ctx, name, content, options)
+}
+
+// Dispatch queues a job, by default to run as soon as a worker is free
+func (m *Manager) Dispatch(job Job, options ...Option) error {
+	return m.DispatchContext(context.Background(), job, options...)
+}
+
+// DispatchContext queues a job using ctx for the queue call
+func (m *Manager) DispatchContext(ctx context.Context, job Job, options ...Option) error {
+	m.mu.RLock()
+	_, ok := m.types[job.Name()]
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("jobs: job %s is not registered", job.Name())
+	}
+
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("jobs: cannot encode job %s: %w", job.Name(), err)
+	}
+	return m.push(ctx, job.Name(), payload, options)
+}
+
+// push queues the envelope of a job
+func (m *Manager) push(ctx context.Context, name string, payload json.RawMessage, options []Option) error {
+	now := time.Now()
+	e := &Envelope{
+		ID:          newID(),
+		Name:        name,
+		Payload:     payload,
+		Queue:       DefaultQueue,
+		MaxAttempts: m.config.MaxAttempts,
+		AvailableAt: now,
+		CreatedAt:   now,
+	}
+	for _, option := range options {
+		option(e)
+	}
+	return m.queue.Push(ctx, e)
+}
+
+// Start starts the workers, they stop with Shutdown or when ctx is done
+func (m *Manager) Start(ctx context.Context) error {
+	return m.StartWorkers(ctx, m.config.Concurrency, m.config.Queues...)
+}
+
+// StartWorkers starts the workers like Start, with the number of workers and the queues given
+// instead of the ones of the config, for the worker processes of sauri queue:work
+func (m *Manager) StartWorkers(ctx context.Context, concurrency int, queues ...string) error {
+	if concurrency <= 0 {
+		concurrency = m.config.Concurrency
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		return ErrStopped
+	}
+	if m.started {
+		return nil
+	}
+	m.started = true
+
+	// jobs run on their own context so that a shutdown lets them finish
+	jobsCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	m.cancelJobs = cancel
+	for i := 0; i < concurrency; i++ {
+		m.workers.Add(1)
+		go m.work(ctx, jobsCtx, queues)
+	}
+	return nil
+}
+
+// Shutdown stops fetching jobs and waits for the running ones to finish. When ctx is done
+// first the running jobs are cancelled and ctx.Err() is returned.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return nil
+	}
+	m.stopped = true
+	close(m.stop)
+	cancel := m.cancelJobs
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		if cancel != nil {
+			cancel()
+		}
+		return ctx.Err()
+	}
+}
+
+// work runs the jobs of the queues until the manager stops
+func (m *Manager) work(ctx, jobsCtx context.Context, queues []string) {
+	defer m.workers.Done()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		e, err := m.queue.Pop(ctx, queues...)
+		if err != nil {
+			m.config.Logger.Error("cannot fetch a job", "error", err)
+		}
+		if e == nil {
+			select {
+			case <-m.stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-time.After(m.config.PollInterval):
+			}
+			continue
+		}
+		m.process(jobsCtx, e)
+	}
+}
+
+// Retry moves a failed job back to its queue with its attempts reset, to run as soon as a
+// worker is free. It returns ErrNotFailed when no failed job has the id.
+func (m *Manager) Retry(ctx context.Context, id string) error {
+	e, err := m.queue.Forget(ctx, id)
+	if err != nil {
+		return err
+	}
+	if e == nil {
+		return ErrNotFailed
+	}
+	e.Attempts = 0
+	e.LastError = ""
+	e.FailedAt = time.Time{}
+	e.AvailableAt = time.Now()
+	return m.queue.Push(ctx, e)
+}
+
+// process runs a job, retrying it later or burying it when it fails
+func (m *Manager) process(ctx context.Context, e *Envelope) {
+	e.Attempts++
+	err := m.run(ctx, e)
+	if err == nil {
+		m.config.Logger.Debug("job done", "job", e.Name, "id", e.ID, "attempt", e.Attempts)
+		return
+	}
+	e.LastError = err.Error()
+
+	// the queue calls must not be lost to the cancelled context of a job
+	queueCtx := context.WithoutCancel(ctx)
+	maxAttempts := e.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = m.config.MaxAttempts
+	}
+	var permanent *permanentError
+	if e.Attempts >= maxAttempts || errors.As(err, &permanent) {
+		e.FailedAt = time.Now()
+		m.config.Logger.Error("job failed", "job", e.Name, "id", e.ID, "attempts", e.Attempts, "error", err)
+		if err := m.queue.Bury(queueCtx, e); err != nil {
+			m.config.Logger.Error("cannot bury the job", "job", e.Name, "id", e.ID, "error", err)
+		}
+		return
+	}
+
+	wait := m.config.Backoff(e.Attempts)
+	e.AvailableAt = time.Now().Add(wait)
+	m.config.Logger.Warn("job failed, retrying", "job", e.Name, "id", e.ID, "attempt", e.Attempts, "retry_in", wait, "error", err)
+	if err := m.queue.Push(queueCtx, e); err != nil {
+		m.config.Logger.Error("cannot queue the job again", "job", e.Name, "id", e.ID, "error", err)
+	}
+}
+
+// run decodes and handles a job, a panic is turned into an error
+func (m *Manager) run(ctx context.Context, e *Envelope) (err error) {
+	m.mu.RLock()
+	t, ok := m.types[e.Name]
+	handler := m.handlers[e.Name]
+	m.mu.RUnlock()
+
+	if handler == nil {
+		if !ok {
+			return fmt.Errorf("job %s is not registered", e.Name)
+		}
+		job, ok := reflect.New(t).Interface().(Job)
+		if !ok {
+			return fmt.Errorf("*%s does not implement Job", t)
+		}
+		if err := json.Unmarshal(e.Payload, job); err != nil {
+			return fmt.Errorf("cannot decode job %s: %w", e.Name, err)
+		}
+		handler = func(ctx context.Context, _ json.RawMessage) error {
+			return job.Handle(ctx)
+		}
+	}
+
+	if m.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.config.Timeout)
+		defer cancel()
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("job panicked: %v", rec)
+		}
+	}()
+	return handler(ctx, e.Payload)
+}
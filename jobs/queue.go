@@ -0,0 +1,110 @@
+package jobs
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Queue stores the jobs waiting to run and the ones that failed every attempt. The jobs wait
+// on named queues, the default one unless dispatched with OnQueue.
+type Queue interface {
+	// Push adds a job to its queue, it becomes available at its AvailableAt time
+	Push(ctx context.Context, e *Envelope) error
+	// Pop takes the next available job of the queues, tried in order, or of the default queue
+	// when none is given. It returns nil when there is none.
+	Pop(ctx context.Context, queues ...string) (*Envelope, error)
+	// Bury moves a job to the dead letter storage
+	Bury(ctx context.Context, e *Envelope) error
+	// Failed lists the jobs in the dead letter storage
+	Failed(ctx context.Context) ([]*Envelope, error)
+	// Forget removes a job from the dead letter storage and returns it, nil when it is not there
+	Forget(ctx context.Context, id string) (*Envelope, error)
+	// Len returns the number of jobs waiting on the queues, the default one when none is
+	// given, delayed ones included
+	Len(ctx context.Context, queues ...string) (int, error)
+}
+
+// MemoryQueue keeps the jobs in memory, they are lost when the process stops. It is the
+// fallback when no Redis server is configured.
+type MemoryQueue struct {
+	mu     sync.Mutex
+	jobs   []*Envelope
+	failed []*Envelope
+}
+
+// NewMemoryQueue returns an empty in-memory queue
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{}
+}
+
+func (q *MemoryQueue) Push(_ context.Context, e *Envelope) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.jobs = append(q.jobs, e)
+	// earliest first, the order of dispatch is kept for jobs available at the same time
+	sort.SliceStable(q.jobs, func(i, j int) bool {
+		return q.jobs[i].AvailableAt.Before(q.jobs[j].AvailableAt)
+	})
+	return nil
+}
+
+func (q *MemoryQueue) Pop(_ context.Context, queues ...string) (*Envelope, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	for _, queue := range queueNames(queues) {
+		// the jobs are sorted, the first one of the queue is the earliest
+		for i, e := range q.jobs {
+			if queueOf(e) != queue {
+				continue
+			}
+			if e.AvailableAt.After(now) {
+				break
+			}
+			q.jobs = append(q.jobs[:i], q.jobs[i+1:]...)
+			return e, nil
+		}
+	}
+	return nil, nil
+}
+
+func (q *MemoryQueue) Bury(_ context.Context, e *Envelope) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.failed = append(q.failed, e)
+	return nil
+}
+
+func (q *MemoryQueue) Failed(_ context.Context) ([]*Envelope, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]*Envelope(nil), q.failed...), nil
+}
+
+func (q *MemoryQueue) Forget(_ context.Context, id string) (*Envelope, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, e := range q.failed {
+		if e.ID == id {
+			q.failed = append(q.failed[:i], q.failed[i+1:]...)
+			return e, nil
+		}
+	}
+	return nil, nil
+}
+
+func (q *MemoryQueue) Len(_ context.Context, queues ...string) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, queue := range queueNames(queues) {
+		for _, e := range q.jobs {
+			if queueOf(e) == queue {
+				n++
+			}
+		}
+	}
+	return n, nil
+}
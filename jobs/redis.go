@@ -0,0 +1,194 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"strconv"
+	"time"
+)
+
+// RedisQueue stores the jobs in Redis so that they survive restarts and are shared by every
+// instance. Jobs waiting on the default queue are in the <prefix>:jobs:delayed sorted set,
+// scored by the time they become available, the ones of another queue in
+// <prefix>:jobs:<queue>:delayed, and the dead letters of every queue in the
+// <prefix>:jobs:failed list.
+type RedisQueue struct {
+	Pool   *redis.Pool
+	Prefix string
+}
+
+// NewRedisQueue returns a queue using the pool, keys start with the prefix
+func NewRedisQueue(pool *redis.Pool, prefix string) *RedisQueue {
+	return &RedisQueue{Pool: pool, Prefix: prefix}
+}
+
+// key returns a key of the queue
+func (q *RedisQueue) key(name string) string {
+	if q.Prefix == "" {
+		return "jobs:" + name
+	}
+	return q.Prefix + ":jobs:" + name
+}
+
+// delayedKey returns the sorted set of the jobs waiting on a queue
+func (q *RedisQueue) delayedKey(queue string) string {
+	if queue == DefaultQueue {
+		return q.key("delayed")
+	}
+	return q.key(queue + ":delayed")
+}
+
+func (q *RedisQueue) Push(ctx context.Context, e *Envelope) error {
+	content, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("cannot encode job %s: %w", e.Name, err)
+	}
+	conn, err := q.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func(conn redis.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	_, err = conn.Do("ZADD", q.delayedKey(queueOf(e)), e.AvailableAt.UnixMilli(), content)
+	return err
+}
+
+// Pop takes the earliest available job of the first queue having one. The job is claimed
+// with ZREM, so when several workers see the same job only one of them gets it.
+func (q *RedisQueue) Pop(ctx context.Context, queues ...string) (*Envelope, error) {
+	conn, err := q.Pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func(conn redis.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	for _, queue := range queueNames(queues) {
+		e, err := q.claim(conn, q.delayedKey(queue), now)
+		if err != nil || e != nil {
+			return e, err
+		}
+	}
+	return nil, nil
+}
+
+// claim takes the earliest job of a sorted set available at now, nil when there is none
+func (q *RedisQueue) claim(conn redis.Conn, key, now string) (*Envelope, error) {
+	for {
+		members, err := redis.ByteSlices(conn.Do("ZRANGEBYSCORE", key, "-inf", now, "LIMIT", 0, 1))
+		if err != nil || len(members) == 0 {
+			return nil, err
+		}
+		removed, err := redis.Int(conn.Do("ZREM", key, members[0]))
+		if err != nil {
+			return nil, err
+		}
+		if removed == 0 {
+			// claimed by another worker, try the next one
+			continue
+		}
+
+		e := &Envelope{}
+		if err := json.Unmarshal(members[0], e); err != nil {
+			return nil, fmt.Errorf("cannot decode job: %w", err)
+		}
+		return e, nil
+	}
+}
+
+func (q *RedisQueue) Bury(ctx context.Context, e *Envelope) error {
+	content, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("cannot encode job %s: %w", e.Name, err)
+	}
+	conn, err := q.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func(conn redis.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	_, err = conn.Do("RPUSH", q.key("failed"), content)
+	return err
+}
+
+func (q *RedisQueue) Failed(ctx context.Context) ([]*Envelope, error) {
+	conn, err := q.Pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func(conn redis.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	members, err := redis.ByteSlices(conn.Do("LRANGE", q.key("failed"), 0, -1))
+	if err != nil && !errors.Is(err, redis.ErrNil) {
+		return nil, err
+	}
+	failed := make([]*Envelope, 0, len(members))
+	for _, member := range members {
+		e := &Envelope{}
+		if err := json.Unmarshal(member, e); err != nil {
+			return nil, fmt.Errorf("cannot decode job: %w", err)
+		}
+		failed = append(failed, e)
+	}
+	return failed, nil
+}
+
+// Forget removes the dead letter with LREM, so when two processes forget the same job only
+// one of them gets it
+func (q *RedisQueue) Forget(ctx context.Context, id string) (*Envelope, error) {
+	conn, err := q.Pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func(conn redis.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	members, err := redis.ByteSlices(conn.Do("LRANGE", q.key("failed"), 0, -1))
+	if err != nil && !errors.Is(err, redis.ErrNil) {
+		return nil, err
+	}
+	for _, member := range members {
+		e := &Envelope{}
+		if err := json.Unmarshal(member, e); err != nil || e.ID != id {
+			continue
+		}
+		removed, err := redis.Int(conn.Do("LREM", q.key("failed"), 1, member))
+		if err != nil || removed == 0 {
+			return nil, err
+		}
+		return e, nil
+	}
+	return nil, nil
+}
+
+func (q *RedisQueue) Len(ctx context.Context, queues ...string) (int, error) {
+	conn, err := q.Pool.GetContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func(conn redis.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	total := 0
+	for _, queue := range queueNames(queues) {
+		n, err := redis.Int(conn.Do("ZCARD", q.delayedKey(queue)))
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
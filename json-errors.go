@@ -0,0 +1,75 @@
+package sauri
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// JSONOptions changes how ReadJSON decodes the request body
+type JSONOptions struct {
+	MaxBytes              int64 // one megabyte when 0
+	DisallowUnknownFields bool  // reject keys that do not match a field of the destination
+}
+
+// JSONError is a request body that could not be decoded, its message can be shown to the client
+type JSONError struct {
+	Message string
+	Field   string // field with the wrong type or unknown key, if any
+	Offset  int64  // byte offset of the error in the body, if known
+	Err     error
+}
+
+// Error implements the error interface
+func (e *JSONError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the decoder error
+func (e *JSONError) Unwrap() error {
+	return e.Err
+}
+
+// jsonError turns a decoder error into a JSONError with a readable message
+func jsonError(err error, maxBytes int64) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+	var invalidErr *json.InvalidUnmarshalError
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		return &JSONError{Message: fmt.Sprintf("body contains badly-formed JSON (at byte %d)", syntaxErr.Offset),
+			Offset: syntaxErr.Offset, Err: err}
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &JSONError{Message: "body contains badly-formed JSON", Err: err}
+
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			return &JSONError{Message: fmt.Sprintf("body contains an incorrect JSON type for field %q, expected %s (at byte %d)", typeErr.Field, typeErr.Type, typeErr.Offset),
+				Field: typeErr.Field, Offset: typeErr.Offset, Err: err}
+		}
+		return &JSONError{Message: fmt.Sprintf("body contains an incorrect JSON type (at byte %d)", typeErr.Offset),
+			Offset: typeErr.Offset, Err: err}
+
+	case errors.Is(err, io.EOF):
+		return &JSONError{Message: "body must not be empty", Err: err}
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// the decoder has no error type for unknown fields
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &JSONError{Message: fmt.Sprintf("body contains unknown key %q", field), Field: field, Err: err}
+
+	case errors.As(err, &maxBytesErr):
+		return &JSONError{Message: fmt.Sprintf("body must not be larger than %d bytes", maxBytes), Err: err}
+
+	case errors.As(err, &invalidErr):
+		// a programming error, the destination is not a non-nil pointer
+		return err
+	}
+	return &JSONError{Message: "body could not be decoded", Err: err}
+}
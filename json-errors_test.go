@@ -0,0 +1,71 @@
+package sauri
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type jsonItem struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestReadJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		opts    JSONOptions
+		message string
+		field   string
+		offset  int64
+	}{
+		{name: "syntax", body: `{"name": "pen",}`, message: "body contains badly-formed JSON (at byte 16)", offset: 16},
+		{name: "truncated", body: `{"name": "pen"`, message: "body contains badly-formed JSON"},
+		{name: "field type", body: `{"count": "two"}`, message: `body contains an incorrect JSON type for field "count", expected int (at byte 15)`, field: "count", offset: 15},
+		{name: "value type", body: `["pen"]`, message: "body contains an incorrect JSON type (at byte 1)", offset: 1},
+		{name: "empty", body: "", message: "body must not be empty"},
+		{name: "unknown field", body: `{"name": "pen", "colour": "red"}`, opts: JSONOptions{DisallowUnknownFields: true},
+			message: `body contains unknown key "colour"`, field: "colour"},
+		{name: "oversized", body: `{"name": "` + strings.Repeat("p", 100) + `"}`, opts: JSONOptions{MaxBytes: 64},
+			message: "body must not be larger than 64 bytes"},
+		{name: "trailing value", body: `{"name": "pen"} {"name": "pencil"}`, message: "body must only contain a single JSON value"},
+		{name: "trailing garbage", body: `{"name": "pen"} }`, message: "body must only contain a single JSON value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(tt.body))
+			var item jsonItem
+			err := (&Sauri{}).ReadJSON(httptest.NewRecorder(), req, &item, tt.opts)
+
+			var jsonErr *JSONError
+			require.ErrorAs(t, err, &jsonErr)
+			assert.Equal(t, tt.message, jsonErr.Message)
+			assert.Equal(t, tt.field, jsonErr.Field)
+			assert.Equal(t, tt.offset, jsonErr.Offset)
+		})
+	}
+}
+
+func TestReadJSON_Valid(t *testing.T) {
+	var item jsonItem
+	req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{"name": "pen", "count": 2, "colour": "red"}`))
+	require.NoError(t, (&Sauri{}).ReadJSON(httptest.NewRecorder(), req, &item))
+	// unknown keys are ignored unless disallowed
+	assert.Equal(t, jsonItem{Name: "pen", Count: 2}, item)
+}
+
+func TestReadJSON_InvalidDestination(t *testing.T) {
+	var item jsonItem
+	req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{"name": "pen"}`))
+	err := (&Sauri{}).ReadJSON(httptest.NewRecorder(), req, item)
+	require.Error(t, err)
+	// a programming error, not a message for the client
+	var jsonErr *JSONError
+	assert.False(t, errors.As(err, &jsonErr))
+}
@@ -0,0 +1,387 @@
+// Package jwt issues and verifies JSON Web Tokens for API backends, signed with HS256 or
+// RS256 and with older keys kept for verification while they rotate out.
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// token types, in the typ claim
+const (
+	Access  = "access"
+	Refresh = "refresh"
+)
+
+var (
+	// ErrInvalidToken is returned for a token that is malformed, expired or badly signed
+	ErrInvalidToken = errors.New("jwt: invalid token")
+	// ErrWrongType is returned when an access token is used as a refresh token or the reverse
+	ErrWrongType = errors.New("jwt: wrong token type")
+)
+
+// Key is a signing key. Only the first key of a Manager signs, the others verify the tokens
+// signed before a rotation.
+type Key struct {
+	ID      string // the kid header, derived from the key by the constructors
+	Secret  []byte // HS256
+	Private *rsa.PrivateKey
+	Public  *rsa.PublicKey // RS256, the private key signs and the public key verifies
+}
+
+// HMACKey returns an HS256 key
+func HMACKey(secret []byte) Key {
+	sum := sha256.Sum256(secret)
+	return Key{ID: hex.EncodeToString(sum[:8]), Secret: secret}
+}
+
+// RSAKey returns an RS256 key, private may be nil for a verification only key
+func RSAKey(private *rsa.PrivateKey, public *rsa.PublicKey) (Key, error) {
+	if public == nil && private != nil {
+		public = &private.PublicKey
+	}
+	if public == nil {
+		return Key{}, errors.New("jwt: an RSA key needs a public key")
+	}
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return Key{}, err
+	}
+	sum := sha256.Sum256(der)
+	return Key{ID: hex.EncodeToString(sum[:8]), Private: private, Public: public}, nil
+}
+
+func (k Key) method() gojwt.SigningMethod {
+	if k.Public != nil {
+		return gojwt.SigningMethodRS256
+	}
+	return gojwt.SigningMethodHS256
+}
+
+func (k Key) signingKey() any {
+	if k.Public != nil {
+		return k.Private
+	}
+	return k.Secret
+}
+
+func (k Key) verifyingKey() any {
+	if k.Public != nil {
+		return k.Public
+	}
+	return k.Secret
+}
+
+// Claims are the claims of the tokens of a Manager
+type Claims struct {
+	gojwt.RegisteredClaims
+	Type  string         `json:"typ,omitempty"`
+	Scope string         `json:"scope,omitempty"` // space separated
+	Data  map[string]any `json:"data,omitempty"`
+}
+
+// NewClaims starts the claims of a token for the subject, usually a user ID
+func NewClaims(subject string) *Claims {
+	return &Claims{RegisteredClaims: gojwt.RegisteredClaims{Subject: subject}}
+}
+
+// WithScopes sets the scopes of the token
+func (c *Claims) WithScopes(scopes ...string) *Claims {
+	c.Scope = strings.Join(scopes, " ")
+	return c
+}
+
+// WithAudience sets the audience of the token
+func (c *Claims) WithAudience(audience ...string) *Claims {
+	c.Audience = audience
+	return c
+}
+
+// ExpiresIn sets the lifetime of the token, the TTL of its type is used otherwise
+func (c *Claims) ExpiresIn(ttl time.Duration) *Claims {
+	c.ExpiresAt = gojwt.NewNumericDate(time.Now().Add(ttl))
+	return c
+}
+
+// Set adds a custom claim under data
+func (c *Claims) Set(key string, value any) *Claims {
+	if c.Data == nil {
+		c.Data = map[string]any{}
+	}
+	c.Data[key] = value
+	return c
+}
+
+// Scopes returns the scopes of the token
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// Can reports whether the token has the scope, "*" grants every scope
+func (c *Claims) Can(scope string) bool {
+	for _, s := range c.Scopes() {
+		if s == scope || s == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Manager signs and verifies tokens
+type Manager struct {
+	Issuer     string
+	AccessTTL  time.Duration // 15 minutes when zero
+	RefreshTTL time.Duration // 30 days when zero
+	keys       []Key
+}
+
+// New returns a manager signing with the first key, the other keys only verify
+func New(issuer string, keys ...Key) (*Manager, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("jwt: no key")
+	}
+	if keys[0].method() == gojwt.SigningMethodRS256 && keys[0].Private == nil {
+		return nil, errors.New("jwt: the signing RSA key has no private key")
+	}
+	return &Manager{Issuer: issuer, keys: keys}, nil
+}
+
+// Settings reads the settings of the application, *config.Config implements it
+type Settings interface {
+	Get(key string, def ...string) string
+	GetInt(key string, def int) int
+	GetStrings(key string) []string
+}
+
+// FromConfig returns the manager the JWT_* settings configure, nil when JWT_SECRET and
+// JWT_PRIVATE_KEY are both empty:
+//
+//	JWT_ALGORITHM        HS256 (default) or RS256
+//	JWT_SECRET           the HS256 signing secret
+//	JWT_PREVIOUS_SECRETS comma separated HS256 secrets still accepted after a rotation
+//	JWT_PRIVATE_KEY      the PEM file of the RS256 signing key, relative to the root
+//	JWT_PUBLIC_KEYS      comma separated PEM files of RS256 public keys still accepted
+//	JWT_ACCESS_TTL       minutes an access token lasts
+//	JWT_REFRESH_DAYS     days a refresh token lasts
+func FromConfig(settings Settings, rootPath, issuer string) (*Manager, error) {
+	var keys []Key
+	switch algorithm := strings.ToUpper(settings.Get("JWT_ALGORITHM")); algorithm {
+	case "", "HS256":
+		secret := settings.Get("JWT_SECRET")
+		if secret == "" {
+			return nil, nil
+		}
+		keys = append(keys, HMACKey([]byte(secret)))
+		for _, secret := range settings.GetStrings("JWT_PREVIOUS_SECRETS") {
+			keys = append(keys, HMACKey([]byte(secret)))
+		}
+	case "RS256":
+		privateKey := settings.Get("JWT_PRIVATE_KEY")
+		if privateKey == "" {
+			return nil, nil
+		}
+		content, err := os.ReadFile(resolve(rootPath, privateKey))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read JWT_PRIVATE_KEY: %w", err)
+		}
+		private, err := gojwt.ParseRSAPrivateKeyFromPEM(content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_PRIVATE_KEY: %w", err)
+		}
+		key, err := RSAKey(private, nil)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		for _, file := range settings.GetStrings("JWT_PUBLIC_KEYS") {
+			content, err := os.ReadFile(resolve(rootPath, file))
+			if err != nil {
+				return nil, fmt.Errorf("cannot read the JWT public key %s: %w", file, err)
+			}
+			public, err := gojwt.ParseRSAPublicKeyFromPEM(content)
+			if err != nil {
+				return nil, fmt.Errorf("invalid JWT public key %s: %w", file, err)
+			}
+			key, err := RSAKey(nil, public)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALGORITHM %q", algorithm)
+	}
+
+	m, err := New(issuer, keys...)
+	if err != nil {
+		return nil, err
+	}
+	if minutes := settings.GetInt("JWT_ACCESS_TTL", 0); minutes > 0 {
+		m.AccessTTL = time.Duration(minutes) * time.Minute
+	}
+	if days := settings.GetInt("JWT_REFRESH_DAYS", 0); days > 0 {
+		m.RefreshTTL = time.Duration(days) * 24 * time.Hour
+	}
+	return m, nil
+}
+
+// resolve makes a relative path relative to the root of the application
+func resolve(rootPath, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(rootPath, path)
+}
+
+func (m *Manager) accessTTL() time.Duration {
+	if m.AccessTTL == 0 {
+		return 15 * time.Minute
+	}
+	return m.AccessTTL
+}
+
+func (m *Manager) refreshTTL() time.Duration {
+	if m.RefreshTTL == 0 {
+		return 30 * 24 * time.Hour
+	}
+	return m.RefreshTTL
+}
+
+// Sign signs the claims as an access token unless their type is set, filling in the issuer,
+// the dates and a token ID
+func (m *Manager) Sign(claims *Claims) (string, error) {
+	c := *claims
+	if c.Type == "" {
+		c.Type = Access
+	}
+	now := time.Now()
+	if c.ExpiresAt == nil {
+		ttl := m.accessTTL()
+		if c.Type == Refresh {
+			ttl = m.refreshTTL()
+		}
+		c.ExpiresAt = gojwt.NewNumericDate(now.Add(ttl))
+	}
+	c.IssuedAt = gojwt.NewNumericDate(now)
+	c.NotBefore = gojwt.NewNumericDate(now)
+	if c.Issuer == "" {
+		c.Issuer = m.Issuer
+	}
+	if c.ID == "" {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return "", fmt.Errorf("cannot generate a token ID: %w", err)
+		}
+		c.ID = hex.EncodeToString(id)
+	}
+
+	key := m.keys[0]
+	token := gojwt.NewWithClaims(key.method(), &c)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signingKey())
+}
+
+// Parse verifies a token and returns its claims
+func (m *Manager) Parse(token string) (*Claims, error) {
+	claims := &Claims{}
+	options := []gojwt.ParserOption{
+		gojwt.WithValidMethods([]string{m.keys[0].method().Alg()}),
+		gojwt.WithExpirationRequired(),
+	}
+	if m.Issuer != "" {
+		options = append(options, gojwt.WithIssuer(m.Issuer))
+	}
+	_, err := gojwt.ParseWithClaims(token, claims, func(t *gojwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		for _, key := range m.keys {
+			if key.ID == kid {
+				return key.verifyingKey(), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}, options...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+// ParseAccess verifies an access token
+func (m *Manager) ParseAccess(token string) (*Claims, error) {
+	claims, err := m.Parse(token)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != Access {
+		return nil, ErrWrongType
+	}
+	return claims, nil
+}
+
+// Pair is an access token with the refresh token that renews it
+type Pair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // seconds
+}
+
+// IssuePair signs an access token and a refresh token for the claims
+func (m *Manager) IssuePair(claims *Claims) (*Pair, error) {
+	access := *claims
+	access.Type = Access
+	access.ExpiresAt = nil
+	accessToken, err := m.Sign(&access)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh := *claims
+	refresh.Type = Refresh
+	refresh.ExpiresAt = nil
+	refreshToken, err := m.Sign(&refresh)
+	if err != nil {
+		return nil, err
+	}
+	return &Pair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(m.accessTTL().Seconds()),
+	}, nil
+}
+
+// Refresh issues a new pair for a refresh token. Refresh tokens stay valid until they expire,
+// keep their ID in a deny list to revoke them earlier.
+func (m *Manager) Refresh(refreshToken string) (*Pair, error) {
+	claims, err := m.Parse(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != Refresh {
+		return nil, ErrWrongType
+	}
+	next := NewClaims(claims.Subject).WithAudience(claims.Audience...)
+	next.Scope, next.Data = claims.Scope, claims.Data
+	return m.IssuePair(next)
+}
+
+// contextKey is the type of the request context key of the claims
+type contextKey struct{}
+
+// ClaimsFromContext returns the claims loaded by the middleware, nil when there are none
+func ClaimsFromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(contextKey{}).(*Claims)
+	return claims
+}
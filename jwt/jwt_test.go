@@ -0,0 +1,121 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignAndParse(t *testing.T) {
+	m, err := New("sauri", HMACKey([]byte("secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := m.Sign(NewClaims("42").WithScopes("posts:read", "posts:write").Set("role", "admin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := m.ParseAccess(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "42" || !claims.Can("posts:write") || claims.Can("users:delete") || claims.Data["role"] != "admin" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	other, _ := New("sauri", HMACKey([]byte("other")))
+	if _, err := other.Parse(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("a token of another key was accepted: %v", err)
+	}
+	expired, _ := m.Sign(NewClaims("42").ExpiresIn(-time.Minute))
+	if _, err := m.Parse(expired); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("an expired token was accepted: %v", err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old, _ := New("sauri", HMACKey([]byte("old")))
+	token, err := old.Sign(NewClaims("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, _ := New("sauri", HMACKey([]byte("new")), HMACKey([]byte("old")))
+	if _, err := rotated.Parse(token); err != nil {
+		t.Errorf("a token of the previous key was rejected: %v", err)
+	}
+}
+
+func TestRS256(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := RSAKey(private, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _ := New("sauri", key)
+	token, err := m.Sign(NewClaims("7"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public, _ := RSAKey(nil, &private.PublicKey)
+	verifier := &Manager{Issuer: "sauri", keys: []Key{public}}
+	if claims, err := verifier.Parse(token); err != nil || claims.Subject != "7" {
+		t.Errorf("the public key does not verify: %v", err)
+	}
+	if _, err := New("sauri", public); err == nil {
+		t.Error("a public key was accepted for signing")
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	m, _ := New("sauri", HMACKey([]byte("secret")))
+	pair, err := m.IssuePair(NewClaims("42").WithScopes("posts:read"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Refresh(pair.AccessToken); !errors.Is(err, ErrWrongType) {
+		t.Errorf("an access token refreshed: %v", err)
+	}
+	if _, err := m.ParseAccess(pair.RefreshToken); !errors.Is(err, ErrWrongType) {
+		t.Errorf("a refresh token was accepted as access token: %v", err)
+	}
+	renewed, err := m.Refresh(pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := m.ParseAccess(renewed.AccessToken)
+	if err != nil || claims.Subject != "42" || !claims.Can("posts:read") {
+		t.Errorf("unexpected renewed claims %+v: %v", claims, err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	m, _ := New("sauri", HMACKey([]byte("secret")))
+	token, _ := m.Sign(NewClaims("42"))
+
+	var subject string
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject = ClaimsFromContext(r.Context()).Subject
+	}))
+	for bearer, want := range map[string]int{"": http.StatusUnauthorized, "junk": http.StatusUnauthorized, token: http.StatusOK} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if bearer != "" {
+			r.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != want {
+			t.Errorf("bearer %q: got %d, want %d", bearer, w.Code, want)
+		}
+	}
+	if subject != "42" {
+		t.Errorf("the claims are not in the context, subject %q", subject)
+	}
+}
@@ -0,0 +1,63 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/haskekareem/sauri/auth"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Middleware lets through the requests bearing a valid access token, with its claims in the
+// request context. Other requests get 401.
+func (m *Manager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			unauthorized(w, "missing bearer token")
+			return
+		}
+		claims, err := m.ParseAccess(strings.TrimSpace(token))
+		if err != nil {
+			unauthorized(w, "invalid or expired token")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, claims)))
+	})
+}
+
+// VerifyToken implements auth.TokenVerifier, so that auth.AuthenticateToken accepts the access
+// tokens of the manager. The subject of the tokens must be the user ID.
+func (m *Manager) VerifyToken(_ context.Context, raw string) (*auth.Token, error) {
+	claims, err := m.ParseAccess(raw)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	token := &auth.Token{UserID: userID, Scopes: claims.Scopes()}
+	if claims.ExpiresAt != nil {
+		token.Expiry = claims.ExpiresAt.Time
+	}
+	if claims.IssuedAt != nil {
+		token.CreatedAt = claims.IssuedAt.Time
+	}
+	return token, nil
+}
+
+// ensure the manager keeps implementing auth.TokenVerifier
+var _ auth.TokenVerifier = (*Manager)(nil)
+
+// unauthorized answers 401 with the challenge of the bearer scheme
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	_ = json.NewEncoder(w).Encode(struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+	}{true, message})
+}
@@ -0,0 +1,155 @@
+package sauri
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/haskekareem/sauri/cache"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// LeaderElector elects a single leader among the app instances sharing a cache. The leader
+// holds a lock that it keeps extending, if it dies the lock expires after TTL and another
+// instance takes over.
+type LeaderElector struct {
+	Locker   cache.Locker
+	Key      string
+	ID       string
+	TTL      time.Duration
+	ErrorLog *log.Logger
+	leader   atomic.Bool
+}
+
+// NewLeaderElector creates a LeaderElector with a unique id for this instance
+func NewLeaderElector(locker cache.Locker, key string, ttl time.Duration) *LeaderElector {
+	return &LeaderElector{
+		Locker: locker,
+		Key:    key,
+		ID:     instanceID(),
+		TTL:    ttl,
+	}
+}
+
+// IsLeader reports whether this instance currently holds the leadership
+func (le *LeaderElector) IsLeader() bool {
+	return le.leader.Load()
+}
+
+// Run campaigns for the leadership until the context is cancelled, then resigns
+func (le *LeaderElector) Run(ctx context.Context) {
+	le.campaign()
+
+	// renew well before the lock expires so a slow tick does not lose the leadership
+	ticker := time.NewTicker(le.TTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			le.campaign()
+		case <-ctx.Done():
+			le.resign()
+			return
+		}
+	}
+}
+
+// campaign takes the lock when it is free or extends it when this instance holds it
+func (le *LeaderElector) campaign() {
+	acquired, err := le.Locker.Lock(le.Key, le.ID, le.TTL)
+	if err != nil {
+		// without the lock another instance may take over, so step down
+		acquired = false
+		if le.ErrorLog != nil {
+			le.ErrorLog.Println("leader election:", err)
+		}
+	}
+	le.leader.Store(acquired)
+}
+
+// resign releases the lock so another instance can take over right away
+func (le *LeaderElector) resign() {
+	if !le.leader.Swap(false) {
+		return
+	}
+	if err := le.Locker.Unlock(le.Key, le.ID); err != nil && le.ErrorLog != nil {
+		le.ErrorLog.Println("leader election:", err)
+	}
+}
+
+// instanceID builds an id that is unique across hosts and processes
+func instanceID() string {
+	hostname, _ := os.Hostname()
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(b))
+}
+
+// OnOneServer wraps a task so that it only runs on the elected leader. Without a shared
+// cache there is no election and the task always runs.
+func (s *Sauri) OnOneServer(fn func()) func() {
+	return func() {
+		if s.Leader != nil && !s.Leader.IsLeader() {
+			return
+		}
+		fn()
+	}
+}
+
+// RunEvery runs a maintenance task at every interval on the leader only
+func (s *Sauri) RunEvery(interval time.Duration, fn func()) {
+	task := s.OnOneServer(fn)
+	s.goBackground(func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				task()
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+}
+
+// startLeaderElection elects a leader through the redis or badger cache when one is used.
+// LEADER_ELECTION_TTL sets in seconds how long a dead leader keeps the leadership.
+func (s *Sauri) startLeaderElection() {
+	var locker cache.Locker
+	switch {
+	case myRedisCache != nil:
+		locker = myRedisCache
+	case myBadgerCache != nil:
+		locker = myBadgerCache
+	default:
+		return
+	}
+
+	ttl := s.Config.GetDuration("LEADER_ELECTION_TTL", time.Second, 30*time.Second)
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+
+	s.Leader = NewLeaderElector(locker, "sauri:leader", ttl)
+	s.Leader.ErrorLog = s.ErrorLog
+	s.goBackground(s.Leader.Run)
+}
+
+// startMaintenance schedules the periodic maintenance tasks of the framework
+func (s *Sauri) startMaintenance() {
+	if myBadgerCache != nil {
+		// reclaim badger disk space once a day
+		s.RunEvery(24*time.Hour, func() {
+			if err := myBadgerCache.RunGC(0.7); err != nil && !errors.Is(err, badger.ErrNoRewrite) {
+				s.ErrorLog.Println("badger garbage collection:", err)
+			}
+		})
+	}
+}
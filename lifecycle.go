@@ -0,0 +1,144 @@
+package sauri
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Hook is a function run when the server starts or shuts down
+type Hook func(ctx context.Context) error
+
+// lifecycle holds the hooks and the background work that must stop on shutdown
+type lifecycle struct {
+	mu         sync.Mutex
+	onStart    []Hook
+	onShutdown []Hook
+	ctx        context.Context
+	cancel     context.CancelFunc
+	background sync.WaitGroup
+	stopped    bool
+}
+
+// OnStart registers a hook run before the server starts listening, e.g. to start a queue
+// worker. An error stops the server from starting.
+func (s *Sauri) OnStart(hook Hook) {
+	s.lifecycle.mu.Lock()
+	defer s.lifecycle.mu.Unlock()
+	s.lifecycle.onStart = append(s.lifecycle.onStart, hook)
+}
+
+// OnShutdown registers a hook run when the server shuts down, after the requests in flight
+// are done and before the database and cache connections are closed. Hooks run in reverse
+// order of registration.
+func (s *Sauri) OnShutdown(hook Hook) {
+	s.lifecycle.mu.Lock()
+	defer s.lifecycle.mu.Unlock()
+	s.lifecycle.onShutdown = append(s.lifecycle.onShutdown, hook)
+}
+
+// Context returns a context cancelled when the application shuts down, for background work
+func (s *Sauri) Context() context.Context {
+	s.lifecycle.mu.Lock()
+	defer s.lifecycle.mu.Unlock()
+	if s.lifecycle.ctx == nil {
+		s.lifecycle.ctx, s.lifecycle.cancel = context.WithCancel(context.Background())
+	}
+	return s.lifecycle.ctx
+}
+
+// goBackground runs fn in a goroutine that Shutdown waits for, fn must return once ctx is done
+func (s *Sauri) goBackground(fn func(ctx context.Context)) {
+	ctx := s.Context()
+	s.lifecycle.background.Add(1)
+	go func() {
+		defer s.lifecycle.background.Done()
+		fn(ctx)
+	}()
+}
+
+// runStartHooks runs the OnStart hooks in order of registration
+func (s *Sauri) runStartHooks(ctx context.Context) error {
+	s.lifecycle.mu.Lock()
+	hooks := append([]Hook(nil), s.lifecycle.onStart...)
+	s.lifecycle.mu.Unlock()
+
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			return fmt.Errorf("start hook failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// Shutdown releases what the application holds: websocket connections, background tasks,
+// the OnShutdown hooks, then the database pools and the caches. It runs once, later calls
+// do nothing.
+func (s *Sauri) Shutdown(ctx context.Context) error {
+	s.lifecycle.mu.Lock()
+	if s.lifecycle.stopped {
+		s.lifecycle.mu.Unlock()
+		return nil
+	}
+	s.lifecycle.stopped = true
+	hooks := append([]Hook(nil), s.lifecycle.onShutdown...)
+	cancel := s.lifecycle.cancel
+	s.lifecycle.mu.Unlock()
+
+	var errs []error
+	if err := s.ShutdownWebSockets(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	// stop the leader election and the maintenance tasks, the leader resigns on the way out
+	if cancel != nil {
+		cancel()
+	}
+	done := make(chan struct{})
+	go func() {
+		s.lifecycle.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("background tasks did not stop: %w", ctx.Err()))
+	}
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown hook failed: %w", err))
+		}
+	}
+
+	if s.DBConn.SqlConnPool != nil {
+		if err := s.DBConn.SqlConnPool.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("cannot close the database pool: %w", err))
+		}
+	}
+	if s.DBConn.PgxConnPool != nil {
+		s.DBConn.PgxConnPool.Close()
+	}
+	if myRedisCache != nil {
+		if err := myRedisCache.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("cannot close redis: %w", err))
+		}
+	}
+	if myBadgerCache != nil {
+		if err := myBadgerCache.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("cannot close badger: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// shutdownTimeout reads SHUTDOWN_TIMEOUT, in seconds or as a duration, 30 seconds by default
+func (s *Sauri) shutdownTimeout() time.Duration {
+	if timeout := s.Config.GetDuration("SHUTDOWN_TIMEOUT", time.Second, 0); timeout > 0 {
+		return timeout
+	}
+	return 30 * time.Second
+}
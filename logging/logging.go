@@ -0,0 +1,176 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Logger is the structured logger of the framework, args are key/value pairs as in log/slog
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+	// With returns a logger adding the key/value pairs to every record
+	With(args ...any) Logger
+	// Module returns the child logger of a part of the application, e.g. "cache"
+	Module(name string) Logger
+	// Enabled reports whether records of the level are written
+	Enabled(level slog.Level) bool
+	// Slog returns the underlying slog.Logger, for the packages taking one
+	Slog() *slog.Logger
+}
+
+// Config sets up a logger, see FromEnv
+type Config struct {
+	Level      slog.Level
+	Leveler    slog.Leveler // used instead of Level when set, e.g. a *slog.LevelVar changed at runtime
+	Format     string       // text or json
+	Output     string       // stderr, file or both
+	Dir        string       // directory of the log file
+	File       string       // name of the log file, sauri.log when empty
+	MaxSize    int64        // size in bytes the log file is rotated at
+	MaxBackups int          // rotated files kept
+}
+
+// FromEnv reads LOG_LEVEL, LOG_FORMAT, LOG_OUTPUT, LOG_MAX_SIZE (megabytes) and
+// LOG_MAX_BACKUPS. The level defaults to debug in debug mode and info otherwise, the log
+// file is written to storage/logs under rootPath.
+func FromEnv(rootPath string, debug bool) Config {
+	config := Config{
+		Level:      slog.LevelInfo,
+		Format:     strings.ToLower(os.Getenv("LOG_FORMAT")),
+		Output:     strings.ToLower(os.Getenv("LOG_OUTPUT")),
+		Dir:        filepath.Join(rootPath, "storage", "logs"),
+		MaxSize:    10 << 20,
+		MaxBackups: 5,
+	}
+	if debug {
+		config.Level = slog.LevelDebug
+	}
+	if level, err := ParseLevel(os.Getenv("LOG_LEVEL")); err == nil {
+		config.Level = level
+	}
+	if size, err := strconv.ParseInt(os.Getenv("LOG_MAX_SIZE"), 10, 64); err == nil && size > 0 {
+		config.MaxSize = size << 20
+	}
+	if backups, err := strconv.Atoi(os.Getenv("LOG_MAX_BACKUPS")); err == nil && backups >= 0 {
+		config.MaxBackups = backups
+	}
+	return config
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(level string) (slog.Level, error) {
+	var parsed slog.Level
+	if strings.TrimSpace(level) == "" {
+		return parsed, fmt.Errorf("empty log level")
+	}
+	err := parsed.UnmarshalText([]byte(strings.TrimSpace(level)))
+	return parsed, err
+}
+
+// New creates a logger. The returned closer closes the log file, it is a no-op when
+// logging to stderr only.
+func New(config Config) (Logger, io.Closer, error) {
+	var (
+		out    io.Writer = os.Stderr
+		closer io.Closer = nopCloser{}
+	)
+	switch config.Output {
+	case "", "stderr":
+	case "file", "both":
+		name := config.File
+		if name == "" {
+			name = "sauri.log"
+		}
+		file, err := NewRotatingFile(filepath.Join(config.Dir, name), config.MaxSize, config.MaxBackups)
+		if err != nil {
+			return nil, nil, err
+		}
+		out, closer = file, file
+		if config.Output == "both" {
+			out = io.MultiWriter(os.Stderr, file)
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported LOG_OUTPUT %q", config.Output)
+	}
+
+	options := &slog.HandlerOptions{Level: config.Level}
+	if config.Leveler != nil {
+		options.Level = config.Leveler
+	}
+	var handler slog.Handler
+	switch config.Format {
+	case "", "text":
+		handler = slog.NewTextHandler(out, options)
+	case "json":
+		handler = slog.NewJSONHandler(out, options)
+	default:
+		_ = closer.Close()
+		return nil, nil, fmt.Errorf("unsupported LOG_FORMAT %q", config.Format)
+	}
+	return Wrap(slog.New(handler)), closer, nil
+}
+
+// nopCloser is the closer of the loggers without a log file
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// Wrap returns the Logger of a slog.Logger
+func Wrap(l *slog.Logger) Logger {
+	return &slogLogger{logger: l}
+}
+
+// slogLogger implements Logger on top of log/slog
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l *slogLogger) Debug(msg string, args ...any) {
+	l.logger.Debug(msg, args...)
+}
+
+func (l *slogLogger) Info(msg string, args ...any) {
+	l.logger.Info(msg, args...)
+}
+
+func (l *slogLogger) Warn(msg string, args ...any) {
+	l.logger.Warn(msg, args...)
+}
+
+func (l *slogLogger) Error(msg string, args ...any) {
+	l.logger.Error(msg, args...)
+}
+
+func (l *slogLogger) With(args ...any) Logger {
+	return &slogLogger{logger: l.logger.With(args...)}
+}
+
+func (l *slogLogger) Module(name string) Logger {
+	return l.With("module", name)
+}
+
+func (l *slogLogger) Enabled(level slog.Level) bool {
+	return l.logger.Enabled(context.Background(), level)
+}
+
+func (l *slogLogger) Slog() *slog.Logger {
+	return l.logger
+}
+
+// StdLogger returns a *log.Logger writing every line as a record of the given level, for
+// the code written against the standard logger such as Sauri.InfoLog and Sauri.ErrorLog
+func StdLogger(l Logger, level slog.Level) *log.Logger {
+	return slog.NewLogLogger(l.Slog().Handler(), level)
+}
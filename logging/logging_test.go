@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogger_ModuleAndStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Wrap(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	logger.Module("cache").Info("hit", "key", "users")
+	StdLogger(logger, slog.LevelError).Println("cannot connect")
+	logger.Debug("not written")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+		assert.Equal(t, "cache", record["module"])
+		assert.Equal(t, "users", record["key"])
+
+		assert.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+		assert.Equal(t, "ERROR", record["level"])
+		assert.Equal(t, "cannot connect", record["msg"])
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("warn")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, level)
+
+	_, err = ParseLevel("loud")
+	assert.Error(t, err)
+}
+
+func TestRotatingFile_RotatesAndKeepsBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	file, err := NewRotatingFile(path, 10, 2)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for i := 0; i < 5; i++ {
+		_, err := file.Write([]byte("12345678\n"))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, file.Close())
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "app-*.log"))
+	assert.Len(t, backups, 2)
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "12345678\n", string(content))
+}
@@ -0,0 +1,129 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotatingFile is an io.Writer appending to a file that is renamed with a timestamp once
+// it reaches its maximum size, the oldest rotated files being removed
+type RotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// NewRotatingFile opens the file, creating its directory when needed. A maxSize of zero
+// never rotates.
+func NewRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	rf := &RotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// open opens the log file for appending
+func (rf *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(rf.path), 0755); err != nil {
+		return fmt.Errorf("cannot create the log directory: %w", err)
+	}
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("cannot open the log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("cannot open the log file: %w", err)
+	}
+	rf.file, rf.size = file, info.Size()
+	return nil
+}
+
+// Write implements io.Writer, a record is never split across two files
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		// written after Close, e.g. by a shutdown hook
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Close closes the log file
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
+
+// rotate renames the current file, e.g. sauri.log to sauri-20240102T150405.000000000.log,
+// and opens a new one
+func (rf *RotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	rf.file = nil
+
+	ext := filepath.Ext(rf.path)
+	base := strings.TrimSuffix(rf.path, ext)
+	stamp := time.Now().Format("20060102T150405.000000000")
+	rotated := base + "-" + stamp + ext
+	// rotations within the same millisecond get a counter
+	for i := 1; fileExists(rotated); i++ {
+		rotated = fmt.Sprintf("%s-%s.%d%s", base, stamp, i, ext)
+	}
+	if err := os.Rename(rf.path, rotated); err != nil {
+		return fmt.Errorf("cannot rotate the log file: %w", err)
+	}
+	if err := rf.open(); err != nil {
+		return err
+	}
+	rf.removeOldBackups(base, ext)
+	return nil
+}
+
+// removeOldBackups keeps the newest maxBackups rotated files
+func (rf *RotatingFile) removeOldBackups(base, ext string) {
+	backups, err := filepath.Glob(base + "-*" + ext)
+	if err != nil || len(backups) <= rf.maxBackups {
+		return
+	}
+	// the timestamps sort in time order
+	sort.Strings(backups)
+	for _, backup := range backups[:len(backups)-rf.maxBackups] {
+		_ = os.Remove(backup)
+	}
+}
+
+// fileExists reports whether the path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
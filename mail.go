@@ -0,0 +1,95 @@
+package sauri
+
+import (
+	"context"
+	"github.com/haskekareem/sauri/mailer"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// initMail creates the mailer of the MAIL_* variables when MAIL_DRIVER is set, its templates
+// are in resources/mails unless MAIL_TEMPLATES says otherwise. The queued emails are jobs of
+// the MAIL_QUEUE queue when it is set, otherwise the listener of the mailer sends them from
+// the start of the server and drains them on shutdown.
+func (s *Sauri) initMail() {
+	driver := s.Config.Get("MAIL_DRIVER")
+	if driver == "" {
+		return
+	}
+	config := mailer.LoadConfig(s.RootPath)
+	config.Driver = driver
+	transport, err := mailer.NewTransport(config)
+	if err != nil {
+		s.ErrorLog.Println("cannot set up the mailer:", err)
+		return
+	}
+
+	s.Mail = &mailer.Mailer{
+		Config:     config,
+		Transport:  transport,
+		EmailQueue: make(chan *mailer.Message, 100),
+	}
+	if queue := s.Config.Get("MAIL_QUEUE"); queue != "" {
+		s.Mail.UseJobs(s.Jobs, queue, s.Config.GetInt("MAIL_MAX_ATTEMPTS", 0))
+	} else {
+		s.OnStart(func(ctx context.Context) error {
+			s.Mail.ListenForEmails()
+			return nil
+		})
+	}
+	s.OnShutdown(s.Mail.Shutdown)
+
+	s.mountMailTracking(config)
+	s.mountMailWebhooks(config)
+
+	// the templates of AddPreview rendered in the browser, in debug mode only
+	if s.IsDebugMode() {
+		s.Router.Mount("/sauri/mail/preview", s.Mail.PreviewHandler())
+	}
+}
+
+// mountMailTracking tracks the opens and clicks of the emails when MAIL_TRACKING_URL is set,
+// its routes are mounted on the path of the URL and the links are signed with KEY
+func (s *Sauri) mountMailTracking(config *mailer.Config) {
+	if config.TrackingURL == "" {
+		return
+	}
+	trackingURL, err := url.Parse(config.TrackingURL)
+	if err != nil || !trackingURL.IsAbs() {
+		s.ErrorLog.Printf("cannot track the emails, MAIL_TRACKING_URL %q is not an absolute URL", config.TrackingURL)
+		return
+	}
+	if s.EncryptionKey == "" {
+		s.ErrorLog.Println("cannot track the emails, KEY signing the links is not set")
+		return
+	}
+
+	var store mailer.TrackingStore
+	if s.Cache != nil {
+		store = mailer.NewCacheTrackingStore(s.Cache, 90*24*time.Hour)
+	}
+	s.Mail.Tracker = mailer.NewTracker(config.TrackingURL, []byte(s.EncryptionKey), store)
+	s.Mail.Tracker.Webhooks = config.TrackingWebhooks
+	s.Router.Mount("/"+strings.Trim(trackingURL.Path, "/"), s.Mail.Tracker.Handler())
+}
+
+// mountMailWebhooks serves the bounce and complaint webhooks of the mail APIs on
+// MAIL_WEBHOOKS_PATH when it is set, the addresses they report are suppressed
+func (s *Sauri) mountMailWebhooks(config *mailer.Config) {
+	path := s.Config.Get("MAIL_WEBHOOKS_PATH")
+	if path == "" {
+		return
+	}
+
+	var suppressions mailer.SuppressionList = mailer.NewMemorySuppressionList()
+	if s.Cache != nil {
+		suppressions = mailer.NewCacheSuppressionList(s.Cache)
+	}
+	s.Mail.Suppressions = suppressions
+	webhooks := mailer.NewBounceWebhooks(config, suppressions, s.Events)
+	path = "/" + strings.Trim(path, "/")
+	s.Router.Mount(path, webhooks.Handler())
+	// the providers post without a CSRF token, their signatures are checked instead
+	s.exemptFromCSRF(path + "/*")
+}
@@ -0,0 +1,197 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// The errors of the mail APIs, an *APIError wraps one of them so that callers can tell
+// with errors.Is whether sending again may succeed
+var (
+	// ErrUnauthorized is returned when the API refuses the key or the sending domain
+	ErrUnauthorized = errors.New("the mail API refused the credentials")
+	// ErrRejected is returned when the API refuses the message, sending it again fails too
+	ErrRejected = errors.New("the mail API rejected the message")
+	// ErrRateLimited is returned when too many messages were sent, sending later succeeds
+	ErrRateLimited = errors.New("the mail API rate limit was reached")
+	// ErrUnavailable is returned when the API fails on its side
+	ErrUnavailable = errors.New("the mail API is unavailable")
+)
+
+// APIError is an error response of a mail API
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Code       string // the error code of the provider, when it gives one
+	Message    string
+	Err        error // ErrUnauthorized, ErrRejected, ErrRateLimited or ErrUnavailable
+}
+
+func (e *APIError) Error() string {
+	message := e.Message
+	if e.Code != "" {
+		message = e.Code + ": " + message
+	}
+	return fmt.Sprintf("%s: %d: %s", e.Provider, e.StatusCode, message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Temporary reports whether sending the message again later may succeed
+func (e *APIError) Temporary() bool {
+	return errors.Is(e.Err, ErrRateLimited) || errors.Is(e.Err, ErrUnavailable)
+}
+
+// NewTransport returns the transport of config.Driver: smtp, the HTTP API of sendgrid,
+// mailgun, ses or postmark, or for development log, which writes the messages to the mail
+// logger, and file, which writes them to .eml files
+func NewTransport(config *Config) (MailTransport, error) {
+	switch strings.ToLower(config.Driver) {
+	case "", "smtp":
+		return NewSMTPMailTransport(config)
+	case "sendgrid":
+		return NewSendGridTransport(config)
+	case "mailgun":
+		return NewMailgunTransport(config)
+	case "ses":
+		return NewSESTransport(config)
+	case "postmark":
+		return NewPostmarkTransport(config)
+	case "log":
+		return NewLogTransport(config), nil
+	case "file":
+		return NewFileTransport(config)
+	}
+	return nil, fmt.Errorf("unknown MAIL_DRIVER %q, expected smtp, sendgrid, mailgun, ses, postmark, log or file", config.Driver)
+}
+
+// statusError returns the error of a status code, for the responses the provider does not
+// describe better
+func statusError(status int) error {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrUnauthorized
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status >= 500:
+		return ErrUnavailable
+	}
+	return ErrRejected
+}
+
+// apiClient returns the HTTP client of the API transports
+func apiClient(config *Config) *http.Client {
+	timeout := config.SendTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// apiEndpoint returns config.Endpoint without its trailing slash, or the default URL
+func apiEndpoint(config *Config, defaultURL string) string {
+	if config.Endpoint != "" {
+		return strings.TrimSuffix(config.Endpoint, "/")
+	}
+	return defaultURL
+}
+
+// jsonRequest creates a POST request with a JSON body, cancelled with ctx
+func jsonRequest(ctx context.Context, url string, payload any) (*http.Request, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+// doAPIRequest sends req and hands the body of an error response to apiError, which maps it
+// to the error of the provider
+func doAPIRequest(client *http.Client, provider string, req *http.Request, apiError func(resp *http.Response, body []byte) *APIError) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		// a request given up by the caller is not an outage of the API
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			return fmt.Errorf("%s: %w", provider, ctxErr)
+		}
+		return fmt.Errorf("%s: %w: %w", provider, ErrUnavailable, err)
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 300 {
+		return nil
+	}
+
+	e := apiError(resp, body)
+	e.Provider = provider
+	e.StatusCode = resp.StatusCode
+	if e.Err == nil {
+		e.Err = statusError(resp.StatusCode)
+	}
+	if e.Message == "" {
+		e.Message = strings.TrimSpace(string(body))
+	}
+	if e.Message == "" {
+		e.Message = http.StatusText(resp.StatusCode)
+	}
+	return e
+}
+
+// sendEach sends the messages one by one, the failures are logged and returned together. It
+// stops when ctx is done.
+func sendEach(ctx context.Context, t MailTransport, emails []*Message) error {
+	var errs []error
+	for _, m := range emails {
+		if err := ctx.Err(); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		if err := t.Send(ctx, m); err != nil {
+			ErrorLogger.Printf("Failed to send email to %v: %v", m.To, err)
+			errs = append(errs, err)
+		} else {
+			InfoLogger.Printf("Email sent successfully to %v", m.To)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fromOf returns the sender of m, the From of the config when m has none
+func fromOf(m *Message, config *Config) EmailAddress {
+	if m.From.Address != "" {
+		return m.From
+	}
+	return config.From
+}
+
+// formatAddress returns an address in the form of a header, "Name" <address>
+func formatAddress(a EmailAddress) string {
+	return (&mail.Address{Name: a.Name, Address: a.Address}).String()
+}
+
+// formatAddresses returns the addresses in the form of a header, separated by commas
+func formatAddresses(addresses []EmailAddress) string {
+	formatted := make([]string, len(addresses))
+	for i, a := range addresses {
+		formatted[i] = formatAddress(a)
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,246 @@
+package mailer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// apiServer records the requests and answers them with status and body
+func apiServer(t *testing.T, status int, body string, header ...string) (*httptest.Server, *[]*http.Request, *[][]byte) {
+	t.Helper()
+	var requests []*http.Request
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := io.ReadAll(r.Body)
+		// the multipart form is parsed from the copy of the body
+		r.Body = io.NopCloser(strings.NewReader(string(content)))
+		_ = r.ParseMultipartForm(1 << 20)
+		requests = append(requests, r)
+		bodies = append(bodies, content)
+		for i := 0; i+1 < len(header); i += 2 {
+			w.Header().Set(header[i], header[i+1])
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests, &bodies
+}
+
+func testMessage() *Message {
+	m := &Message{
+		To:       []EmailAddress{{"ada@example.com", "Ada"}},
+		Bcc:      []EmailAddress{{"audit@example.com", ""}},
+		Subject:  "Welcome",
+		Body:     "hello",
+		HTMLBody: "<p>hello</p>",
+		Headers:  map[string]string{"X-Campaign": "welcome"},
+		Metadata: map[string]string{"user": "42"},
+	}
+	m.AddAttachment("logo.png", []byte("png"), "image/png", true)
+	return m
+}
+
+func testConfig(endpoint string) *Config {
+	return &Config{
+		APIKey:    "key",
+		APISecret: "secret",
+		Domain:    "mg.example.com",
+		Endpoint:  endpoint,
+		From:      EmailAddress{Address: "app@example.com", Name: "App"},
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	for _, driver := range []string{"sendgrid", "mailgun", "ses", "postmark"} {
+		config := testConfig("")
+		config.Driver = driver
+		transport, err := NewTransport(config)
+		require.NoError(t, err, driver)
+		assert.NotNil(t, transport)
+	}
+
+	_, err := NewTransport(&Config{Driver: "pigeon"})
+	assert.ErrorContains(t, err, "unknown MAIL_DRIVER")
+
+	_, err = NewTransport(&Config{Driver: "mailgun", APIKey: "key"})
+	assert.ErrorContains(t, err, "MAIL_DOMAIN")
+}
+
+func TestSendGridTransport(t *testing.T) {
+	server, requests, bodies := apiServer(t, http.StatusAccepted, "")
+	config := testConfig(server.URL)
+	config.Sandbox = true
+	transport, err := NewSendGridTransport(config)
+	require.NoError(t, err)
+
+	require.NoError(t, transport.Send(context.Background(), testMessage()))
+	require.Len(t, *requests, 1)
+	req := (*requests)[0]
+	assert.Equal(t, "/v3/mail/send", req.URL.Path)
+	assert.Equal(t, "Bearer key", req.Header.Get("Authorization"))
+
+	var payload sendGridMessage
+	require.NoError(t, json.Unmarshal((*bodies)[0], &payload))
+	assert.Equal(t, "app@example.com", payload.From.Email)
+	assert.Equal(t, "ada@example.com", payload.Personalizations[0].To[0].Email)
+	assert.Equal(t, "audit@example.com", payload.Personalizations[0].Bcc[0].Email)
+	assert.Equal(t, []sendGridContent{{"text/plain", "hello"}, {"text/html", "<p>hello</p>"}}, payload.Content)
+	assert.Equal(t, map[string]string{"X-Campaign": "welcome"}, payload.Headers)
+	assert.Equal(t, "inline", payload.Attachments[0].Disposition)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("png")), payload.Attachments[0].Content)
+	assert.True(t, payload.MailSettings.SandboxMode.Enable)
+}
+
+func TestSendGridErrors(t *testing.T) {
+	server, _, _ := apiServer(t, http.StatusBadRequest, `{"errors":[{"message":"invalid email","field":"personalizations.0.to"}]}`)
+	transport, err := NewSendGridTransport(testConfig(server.URL))
+	require.NoError(t, err)
+
+	err = transport.Send(context.Background(), testMessage())
+	assert.ErrorIs(t, err, ErrRejected)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "sendgrid", apiErr.Provider)
+	assert.Equal(t, "personalizations.0.to: invalid email", apiErr.Message)
+	assert.False(t, apiErr.Temporary())
+
+	server, _, _ = apiServer(t, http.StatusTooManyRequests, `{"errors":[{"message":"too many requests"}]}`)
+	transport, _ = NewSendGridTransport(testConfig(server.URL))
+	err = transport.Send(context.Background(), testMessage())
+	assert.ErrorIs(t, err, ErrRateLimited)
+	require.ErrorAs(t, err, &apiErr)
+	assert.True(t, apiErr.Temporary())
+}
+
+func TestMailgunTransport(t *testing.T) {
+	server, requests, _ := apiServer(t, http.StatusOK, `{"id":"<1@mg.example.com>","message":"Queued. Thank you."}`)
+	config := testConfig(server.URL)
+	config.Sandbox = true
+	transport, err := NewMailgunTransport(config)
+	require.NoError(t, err)
+
+	require.NoError(t, transport.Send(context.Background(), testMessage()))
+	req := (*requests)[0]
+	assert.Equal(t, "/v3/mg.example.com/messages", req.URL.Path)
+	user, password, _ := req.BasicAuth()
+	assert.Equal(t, "api", user)
+	assert.Equal(t, "key", password)
+
+	form := req.MultipartForm
+	require.NotNil(t, form)
+	assert.Equal(t, []string{`"App" <app@example.com>`}, form.Value["from"])
+	assert.Equal(t, []string{`"Ada" <ada@example.com>`}, form.Value["to"])
+	assert.Equal(t, []string{"welcome"}, form.Value["h:X-Campaign"])
+	assert.Equal(t, []string{"42"}, form.Value["v:user"])
+	assert.Equal(t, []string{"yes"}, form.Value["o:testmode"])
+	require.Len(t, form.File["inline"], 1)
+	assert.Equal(t, "logo.png", form.File["inline"][0].Filename)
+
+	server, _, _ = apiServer(t, http.StatusUnauthorized, "Forbidden")
+	transport, _ = NewMailgunTransport(testConfig(server.URL))
+	err = transport.Send(context.Background(), testMessage())
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	assert.ErrorContains(t, err, "Forbidden")
+}
+
+func TestPostmarkTransport(t *testing.T) {
+	server, requests, bodies := apiServer(t, http.StatusOK, `{"ErrorCode":0,"Message":"OK"}`)
+	config := testConfig(server.URL)
+	config.Sandbox = true
+	transport, err := NewPostmarkTransport(config)
+	require.NoError(t, err)
+
+	require.NoError(t, transport.Send(context.Background(), testMessage()))
+	assert.Equal(t, postmarkTestToken, (*requests)[0].Header.Get("X-Postmark-Server-Token"))
+	var payload postmarkMessage
+	require.NoError(t, json.Unmarshal((*bodies)[0], &payload))
+	assert.Equal(t, `"Ada" <ada@example.com>`, payload.To)
+	assert.Equal(t, "<audit@example.com>", payload.Bcc)
+	assert.Equal(t, "cid:logo.png", payload.Attachments[0].ContentID)
+
+	server, _, _ = apiServer(t, http.StatusUnprocessableEntity, `{"ErrorCode":10,"Message":"Bad or missing API token"}`)
+	transport, _ = NewPostmarkTransport(testConfig(server.URL))
+	err = transport.Send(context.Background(), testMessage())
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	assert.EqualError(t, err, "postmark: 422: 10: Bad or missing API token")
+
+	server, _, _ = apiServer(t, http.StatusUnprocessableEntity, `{"ErrorCode":406,"Message":"inactive recipient"}`)
+	transport, _ = NewPostmarkTransport(testConfig(server.URL))
+	assert.ErrorIs(t, transport.Send(context.Background(), testMessage()), ErrRejected)
+}
+
+func TestSESTransport(t *testing.T) {
+	server, requests, bodies := apiServer(t, http.StatusOK, `{"MessageId":"1"}`)
+	config := testConfig(server.URL)
+	config.Region = "eu-west-1"
+	transport, err := NewSESTransport(config)
+	require.NoError(t, err)
+
+	require.NoError(t, transport.Send(context.Background(), testMessage()))
+	req := (*requests)[0]
+	assert.Equal(t, "/v2/email/outbound-emails", req.URL.Path)
+	assert.Contains(t, req.Header.Get("Authorization"), "Credential=key/")
+	assert.Contains(t, req.Header.Get("Authorization"), "/eu-west-1/ses/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=")
+
+	var payload sesMessage
+	require.NoError(t, json.Unmarshal((*bodies)[0], &payload))
+	assert.Equal(t, `"App" <app@example.com>`, payload.FromEmailAddress)
+	assert.Equal(t, []string{"<audit@example.com>"}, payload.Destination.BccAddresses)
+	raw, err := base64.StdEncoding.DecodeString(payload.Content.Raw.Data)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), "Subject: Welcome")
+	assert.Contains(t, string(raw), "X-Campaign: welcome")
+
+	config.Sandbox = true
+	require.NoError(t, transport.Send(context.Background(), testMessage()))
+	var sandboxed sesMessage
+	require.NoError(t, json.Unmarshal((*bodies)[1], &sandboxed))
+	assert.Equal(t, sesDestination{ToAddresses: []string{sesSimulator}}, sandboxed.Destination)
+
+	server, _, _ = apiServer(t, http.StatusBadRequest, `{"message":"Email address is not verified."}`,
+		"X-Amzn-ErrorType", "MessageRejected:http://internal.amazon.com/coral/com.amazonaws.sesv2/")
+	transport, _ = NewSESTransport(testConfig(server.URL))
+	err = transport.Send(context.Background(), testMessage())
+	assert.ErrorIs(t, err, ErrRejected)
+	assert.EqualError(t, err, "ses: 400: MessageRejected: Email address is not verified.")
+}
+
+func TestSESSignature(t *testing.T) {
+	transport, err := NewSESTransport(&Config{APIKey: "AKIDEXAMPLE", APISecret: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, transport.endpoint, strings.NewReader("{}"))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	now, err := time.Parse("20060102T150405Z", "20150830T123600Z")
+	require.NoError(t, err)
+	transport.sign(req, []byte("{}"), now)
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Regexp(t, `^AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/ses/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=[0-9a-f]{64}$`,
+		req.Header.Get("Authorization"))
+}
+
+func TestSendWithRetryStopsOnRejectedMessages(t *testing.T) {
+	SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	server, requests, _ := apiServer(t, http.StatusBadRequest, `{"errors":[{"message":"invalid"}]}`)
+	transport, err := NewSendGridTransport(testConfig(server.URL))
+	require.NoError(t, err)
+
+	m := &Mailer{Transport: transport}
+	err = m.sendWithRetry(context.Background(), testMessage())
+	assert.True(t, errors.Is(err, ErrRejected))
+	assert.Len(t, *requests, 1)
+}
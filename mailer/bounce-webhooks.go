@@ -0,0 +1,505 @@
+package mailer
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/haskekareem/sauri/events"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the events emitted for the bounces, their payload is a Bounce
+const (
+	EventBounced    = "mail.bounced"
+	EventComplained = "mail.complained"
+)
+
+// snsHost matches the hosts of Amazon SNS, of its signing certificates and subscribe URLs
+var snsHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// errSignature is returned for a webhook request which is not signed by the provider
+var errSignature = errors.New("invalid webhook signature")
+
+// errNoWebhookKey is returned for the requests of a provider whose key is not set
+var errNoWebhookKey = errors.New("the webhook key of the provider is not set")
+
+// webhookMaxAge is the age of the oldest signed timestamp accepted, so that a signed event
+// cannot be replayed later
+const webhookMaxAge = 5 * time.Minute
+
+// BounceWebhooks receives the bounces and complaints posted by the mail APIs: the hard
+// bounces and complaints are added to the suppression list, and every bounce is emitted on
+// the bus as EventBounced or EventComplained so that the application can deactivate the
+// address. The routes of Handler are given to the providers:
+//
+//	POST /ses        the HTTPS subscription of the SNS topic of the SES notifications
+//	POST /sendgrid   the event webhook of SendGrid, with the bounce and spam report events
+//	POST /mailgun    the webhooks of Mailgun for the failed and complained events
+type BounceWebhooks struct {
+	Suppressions SuppressionList
+	Events       *events.Bus // no events when nil
+
+	// the keys verifying the requests, the requests of a provider without key are refused;
+	// the SNS messages are always verified with the certificate of AWS
+	MailgunSigningKey string // the HTTP webhook signing key of Mailgun
+	SendGridPublicKey string // the verification key of the signed event webhook of SendGrid
+	Client            *http.Client
+
+	snsCertificates sync.Map // the certificates of SNS by URL
+}
+
+// NewBounceWebhooks creates the webhooks adding the addresses to suppressions and emitting
+// the bounces on bus, the keys verifying the requests are the ones of config
+func NewBounceWebhooks(config *Config, suppressions SuppressionList, bus *events.Bus) *BounceWebhooks {
+	return &BounceWebhooks{
+		Suppressions:      suppressions,
+		Events:            bus,
+		MailgunSigningKey: config.MailgunWebhookKey,
+		SendGridPublicKey: config.SendGridWebhookKey,
+		Client:            &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Handler returns the routes of the providers, to be mounted e.g. on /webhooks/mail. The
+// routes of SendGrid and Mailgun are only mounted with their key, unsigned events would let
+// anyone suppress an address.
+func (h *BounceWebhooks) Handler() http.Handler {
+	mux := chi.NewRouter()
+	mux.Post("/ses", h.HandleSES)
+	if h.SendGridPublicKey != "" {
+		mux.Post("/sendgrid", h.HandleSendGrid)
+	}
+	if h.MailgunSigningKey != "" {
+		mux.Post("/mailgun", h.HandleMailgun)
+	}
+	return mux
+}
+
+// Record adds the address of a bounce to the suppression list when it suppresses it and
+// emits the bounce
+func (h *BounceWebhooks) Record(ctx context.Context, bounce Bounce) error {
+	bounce.Address = normalizeAddress(bounce.Address)
+	if bounce.At.IsZero() {
+		bounce.At = time.Now().UTC()
+	}
+	if bounce.Suppresses() && h.Suppressions != nil {
+		if err := h.Suppressions.Suppress(ctx, bounce); err != nil {
+			return err
+		}
+	}
+	if h.Events == nil {
+		return nil
+	}
+	name := EventBounced
+	if bounce.Type == Complaint {
+		name = EventComplained
+	}
+	return h.Events.EmitContext(ctx, name, bounce)
+}
+
+// HandleSES handles the notifications of SES posted by SNS, it confirms the subscription of
+// the topic first
+func (h *BounceWebhooks) HandleSES(w http.ResponseWriter, r *http.Request) {
+	var message snsMessage
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&message); err != nil {
+		http.Error(w, "invalid SNS message", http.StatusBadRequest)
+		return
+	}
+	if err := h.verifySNS(&message); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	switch message.Type {
+	case "SubscriptionConfirmation":
+		if err := h.confirmSNS(r.Context(), message); err != nil {
+			errorLogger().Printf("cannot confirm the SNS subscription of %s: %v", message.TopicArn, err)
+			http.Error(w, "cannot confirm the subscription", http.StatusBadGateway)
+			return
+		}
+	case "Notification":
+		bounces, err := sesBounces(message.Message)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.recordAll(w, r, bounces)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleSendGrid handles the events of the event webhook of SendGrid
+func (h *BounceWebhooks) HandleSendGrid(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 5<<20))
+	if err != nil {
+		http.Error(w, "cannot read the events", http.StatusBadRequest)
+		return
+	}
+	if h.SendGridPublicKey == "" {
+		http.Error(w, errNoWebhookKey.Error(), http.StatusForbidden)
+		return
+	}
+	if err := verifySendGrid(h.SendGridPublicKey, r.Header, body); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	var sent []struct {
+		Email     string `json:"email"`
+		Event     string `json:"event"`
+		Type      string `json:"type"`
+		Reason    string `json:"reason"`
+		MessageID string `json:"sg_message_id"`
+		Timestamp int64  `json:"timestamp"`
+	}
+	if err := json.Unmarshal(body, &sent); err != nil {
+		http.Error(w, "invalid SendGrid events", http.StatusBadRequest)
+		return
+	}
+
+	var bounces []Bounce
+	for _, e := range sent {
+		bounce := Bounce{Address: e.Email, Provider: "sendgrid", Reason: e.Reason, MessageID: e.MessageID, At: time.Unix(e.Timestamp, 0).UTC()}
+		switch {
+		case e.Event == "spamreport":
+			bounce.Type = Complaint
+		case e.Event == "bounce" && e.Type == "blocked":
+			bounce.Type = BounceSoft
+		case e.Event == "bounce":
+			bounce.Type = BounceHard
+		default:
+			continue
+		}
+		bounces = append(bounces, bounce)
+	}
+	h.recordAll(w, r, bounces)
+}
+
+// HandleMailgun handles the failed and complained events of the webhooks of Mailgun
+func (h *BounceWebhooks) HandleMailgun(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Signature struct {
+			Timestamp string `json:"timestamp"`
+			Token     string `json:"token"`
+			Signature string `json:"signature"`
+		} `json:"signature"`
+		EventData struct {
+			Event     string  `json:"event"`
+			Severity  string  `json:"severity"`
+			Recipient string  `json:"recipient"`
+			Reason    string  `json:"reason"`
+			Timestamp float64 `json:"timestamp"`
+			Delivery  struct {
+				Description string `json:"description"`
+				Message     string `json:"message"`
+			} `json:"delivery-status"`
+			Message struct {
+				Headers struct {
+					MessageID string `json:"message-id"`
+				} `json:"headers"`
+			} `json:"message"`
+		} `json:"event-data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&payload); err != nil {
+		http.Error(w, "invalid Mailgun event", http.StatusBadRequest)
+		return
+	}
+	if err := verifyMailgun(h.MailgunSigningKey, payload.Signature.Timestamp, payload.Signature.Token, payload.Signature.Signature); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	e := payload.EventData
+	bounce := Bounce{Address: e.Recipient, Provider: "mailgun", MessageID: e.Message.Headers.MessageID, At: time.Unix(int64(e.Timestamp), 0).UTC()}
+	bounce.Reason = e.Delivery.Description
+	if bounce.Reason == "" {
+		bounce.Reason = e.Delivery.Message
+	}
+	if bounce.Reason == "" {
+		bounce.Reason = e.Reason
+	}
+	switch {
+	case e.Event == "complained":
+		bounce.Type = Complaint
+	case e.Event == "failed" && e.Severity == "permanent":
+		bounce.Type = BounceHard
+	case e.Event == "failed":
+		bounce.Type = BounceSoft
+	default:
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	h.recordAll(w, r, []Bounce{bounce})
+}
+
+// recordAll records the bounces and answers the provider, with an error for it to post them
+// again when one cannot be recorded
+func (h *BounceWebhooks) recordAll(w http.ResponseWriter, r *http.Request, bounces []Bounce) {
+	for _, bounce := range bounces {
+		if err := h.Record(r.Context(), bounce); err != nil {
+			errorLogger().Printf("cannot record the %s bounce of %s: %v", bounce.Type, bounce.Address, err)
+			http.Error(w, "cannot record the bounce", http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// snsMessage is a message of Amazon SNS
+type snsMessage struct {
+	Type             string `json:"Type"`
+	MessageID        string `json:"MessageId"`
+	Token            string `json:"Token"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject"`
+	Message          string `json:"Message"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+	SubscribeURL     string `json:"SubscribeURL"`
+}
+
+// signedString returns the string signed by SNS, the fields of the message type in order
+func (m *snsMessage) signedString() string {
+	fields := [][2]string{{"Message", m.Message}, {"MessageId", m.MessageID}}
+	if m.Type == "Notification" {
+		if m.Subject != "" {
+			fields = append(fields, [2]string{"Subject", m.Subject})
+		}
+	} else {
+		fields = append(fields, [2]string{"SubscribeURL", m.SubscribeURL})
+	}
+	fields = append(fields, [2]string{"Timestamp", m.Timestamp})
+	if m.Type != "Notification" {
+		fields = append(fields, [2]string{"Token", m.Token})
+	}
+	fields = append(fields, [2]string{"TopicArn", m.TopicArn}, [2]string{"Type", m.Type})
+
+	var b strings.Builder
+	for _, field := range fields {
+		b.WriteString(field[0] + "\n" + field[1] + "\n")
+	}
+	return b.String()
+}
+
+// verifySNS checks the signature of an SNS message with the certificate of AWS
+func (h *BounceWebhooks) verifySNS(m *snsMessage) error {
+	certURL, err := url.Parse(m.SigningCertURL)
+	if err != nil || certURL.Scheme != "https" || !snsHost.MatchString(certURL.Hostname()) {
+		return fmt.Errorf("%w: the certificate %q is not one of SNS", errSignature, m.SigningCertURL)
+	}
+	certificate, err := h.snsCertificate(m.SigningCertURL)
+	if err != nil {
+		return err
+	}
+	key, ok := certificate.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: the SNS certificate has no RSA key", errSignature)
+	}
+	signature, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return errSignature
+	}
+
+	signed := []byte(m.signedString())
+	switch m.SignatureVersion {
+	case "1":
+		digest := sha1.Sum(signed)
+		err = rsa.VerifyPKCS1v15(key, crypto.SHA1, digest[:], signature)
+	case "2":
+		digest := sha256.Sum256(signed)
+		err = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+	default:
+		return fmt.Errorf("%w: unknown SNS signature version %q", errSignature, m.SignatureVersion)
+	}
+	if err != nil {
+		return errSignature
+	}
+	return nil
+}
+
+// snsCertificate returns the certificate of the URL, downloaded once
+func (h *BounceWebhooks) snsCertificate(certURL string) (*x509.Certificate, error) {
+	if certificate, ok := h.snsCertificates.Load(certURL); ok {
+		return certificate.(*x509.Certificate), nil
+	}
+
+	resp, err := h.client().Get(certURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot download the SNS certificate: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	content, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot download the SNS certificate: %s", resp.Status)
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("the SNS certificate is not a PEM block")
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SNS certificate: %w", err)
+	}
+	h.snsCertificates.Store(certURL, certificate)
+	return certificate, nil
+}
+
+// confirmSNS confirms the subscription of a topic by visiting its subscribe URL
+func (h *BounceWebhooks) confirmSNS(ctx context.Context, m snsMessage) error {
+	subscribeURL, err := url.Parse(m.SubscribeURL)
+	if err != nil || subscribeURL.Scheme != "https" || !snsHost.MatchString(subscribeURL.Hostname()) {
+		return fmt.Errorf("the subscribe URL %q is not one of SNS", m.SubscribeURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.SubscribeURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := h.client().Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("SNS answered %s", resp.Status)
+	}
+	return nil
+}
+
+func (h *BounceWebhooks) client() *http.Client {
+	if h.Client == nil {
+		return http.DefaultClient
+	}
+	return h.Client
+}
+
+// sesBounces returns the bounces of an SES notification, of a topic of the identity or of
+// the event destination of a configuration set
+func sesBounces(message string) ([]Bounce, error) {
+	var notification struct {
+		NotificationType string `json:"notificationType"`
+		EventType        string `json:"eventType"`
+		Bounce           struct {
+			BounceType string `json:"bounceType"`
+			Recipients []struct {
+				EmailAddress   string `json:"emailAddress"`
+				DiagnosticCode string `json:"diagnosticCode"`
+			} `json:"bouncedRecipients"`
+			Timestamp time.Time `json:"timestamp"`
+		} `json:"bounce"`
+		Complaint struct {
+			FeedbackType string `json:"complaintFeedbackType"`
+			Recipients   []struct {
+				EmailAddress string `json:"emailAddress"`
+			} `json:"complainedRecipients"`
+			Timestamp time.Time `json:"timestamp"`
+		} `json:"complaint"`
+		Mail struct {
+			MessageID string `json:"messageId"`
+		} `json:"mail"`
+	}
+	if err := json.Unmarshal([]byte(message), &notification); err != nil {
+		return nil, errors.New("invalid SES notification")
+	}
+
+	kind := notification.NotificationType
+	if kind == "" {
+		kind = notification.EventType
+	}
+	var bounces []Bounce
+	switch kind {
+	case "Bounce":
+		bounceType := BounceSoft
+		if notification.Bounce.BounceType == "Permanent" {
+			bounceType = BounceHard
+		}
+		for _, recipient := range notification.Bounce.Recipients {
+			bounces = append(bounces, Bounce{
+				Address: recipient.EmailAddress, Type: bounceType, Provider: "ses",
+				Reason: recipient.DiagnosticCode, MessageID: notification.Mail.MessageID, At: notification.Bounce.Timestamp,
+			})
+		}
+	case "Complaint":
+		for _, recipient := range notification.Complaint.Recipients {
+			bounces = append(bounces, Bounce{
+				Address: recipient.EmailAddress, Type: Complaint, Provider: "ses",
+				Reason: notification.Complaint.FeedbackType, MessageID: notification.Mail.MessageID, At: notification.Complaint.Timestamp,
+			})
+		}
+	}
+	return bounces, nil
+}
+
+// verifyMailgun verifies the HMAC signature of a Mailgun event and the age of its timestamp
+func verifyMailgun(key, timestamp, token, signature string) error {
+	if key == "" {
+		return errNoWebhookKey
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(timestamp + token))
+	if !hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(signature)) {
+		return errSignature
+	}
+	return checkTimestamp(timestamp)
+}
+
+// checkTimestamp checks that a signed timestamp, in seconds, is within webhookMaxAge of now
+func checkTimestamp(timestamp string) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errSignature
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age > webhookMaxAge || age < -webhookMaxAge {
+		return errors.New("the webhook signature expired")
+	}
+	return nil
+}
+
+// verifySendGrid checks the signature of the signed event webhook of SendGrid, an ECDSA
+// signature of the timestamp followed by the body, and the age of the timestamp
+func verifySendGrid(publicKey string, header http.Header, body []byte) error {
+	der, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return fmt.Errorf("invalid SendGrid verification key: %w", err)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return fmt.Errorf("invalid SendGrid verification key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return errors.New("the SendGrid verification key is not an ECDSA key")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(header.Get("X-Twilio-Email-Event-Webhook-Signature"))
+	if err != nil {
+		return errSignature
+	}
+	timestamp := header.Get("X-Twilio-Email-Event-Webhook-Timestamp")
+	digest := sha256.Sum256(append([]byte(timestamp), body...))
+	if !ecdsa.VerifyASN1(key, digest[:], signature) {
+		return errSignature
+	}
+	return checkTimestamp(timestamp)
+}
@@ -0,0 +1,237 @@
+package mailer
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haskekareem/sauri/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bounceServer serves the webhooks and records the events emitted
+func bounceServer(t *testing.T, config *Config) (*BounceWebhooks, *MemorySuppressionList, *httptest.Server, func() []events.Event) {
+	t.Helper()
+	var mu sync.Mutex
+	var emitted []events.Event
+	bus := events.New(nil, nil)
+	bus.Listen("mail.*", func(_ context.Context, event events.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		emitted = append(emitted, event)
+		return nil
+	})
+
+	suppressions := NewMemorySuppressionList()
+	webhooks := NewBounceWebhooks(config, suppressions, bus)
+	server := httptest.NewServer(webhooks.Handler())
+	t.Cleanup(server.Close)
+	return webhooks, suppressions, server, func() []events.Event {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]events.Event(nil), emitted...)
+	}
+}
+
+func post(t *testing.T, url, body string, header ...string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestSendGridBounces(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	_, suppressions, server, emitted := bounceServer(t, &Config{SendGridWebhookKey: base64.StdEncoding.EncodeToString(public)})
+
+	body := `[{"email":"Gone@example.com","event":"bounce","type":"bounce","reason":"550 no mailbox","timestamp":1700000000},
+		{"email":"full@example.com","event":"bounce","type":"blocked"},
+		{"email":"angry@example.com","event":"spamreport"},
+		{"email":"ada@example.com","event":"delivered"}]`
+	signed := func(timestamp int64) int {
+		digest := sha256.Sum256([]byte(fmt.Sprint(timestamp) + body))
+		signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		require.NoError(t, err)
+		return post(t, server.URL+"/sendgrid", body,
+			"X-Twilio-Email-Event-Webhook-Signature", base64.StdEncoding.EncodeToString(signature),
+			"X-Twilio-Email-Event-Webhook-Timestamp", fmt.Sprint(timestamp))
+	}
+
+	assert.Equal(t, http.StatusForbidden, post(t, server.URL+"/sendgrid", body))
+	assert.Equal(t, http.StatusForbidden, signed(time.Now().Add(-time.Hour).Unix()), "a replayed event")
+	assert.Equal(t, http.StatusOK, signed(time.Now().Unix()))
+
+	ctx := context.Background()
+	gone, err := suppressions.Suppressed(ctx, "gone@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, gone)
+	assert.Equal(t, BounceHard, gone.Type)
+	assert.Equal(t, "550 no mailbox", gone.Reason)
+	full, _ := suppressions.Suppressed(ctx, "full@example.com")
+	assert.Nil(t, full)
+	angry, _ := suppressions.Suppressed(ctx, "angry@example.com")
+	assert.NotNil(t, angry)
+
+	names := make([]string, 0, 3)
+	for _, event := range emitted() {
+		names = append(names, event.Name)
+	}
+	assert.Equal(t, []string{EventBounced, EventBounced, EventComplained}, names)
+}
+
+func TestMailgunBounces(t *testing.T) {
+	_, suppressions, server, _ := bounceServer(t, &Config{MailgunWebhookKey: "key"})
+	event := func(timestamp int64, signature string) string {
+		if signature == "" {
+			mac := hmac.New(sha256.New, []byte("key"))
+			mac.Write([]byte(fmt.Sprint(timestamp) + "token"))
+			signature = hex.EncodeToString(mac.Sum(nil))
+		}
+		return fmt.Sprintf(`{"signature":{"timestamp":"%d","token":"token","signature":"%s"},
+		"event-data":{"event":"failed","severity":"permanent","recipient":"gone@example.com","delivery-status":{"description":"No such user"}}}`, timestamp, signature)
+	}
+	now := time.Now().Unix()
+	assert.Equal(t, http.StatusForbidden, post(t, server.URL+"/mailgun", event(now, "forged")))
+	assert.Equal(t, http.StatusForbidden, post(t, server.URL+"/mailgun", event(now-3600, "")), "a replayed event")
+	assert.Equal(t, http.StatusOK, post(t, server.URL+"/mailgun", event(now, "")))
+
+	gone, err := suppressions.Suppressed(context.Background(), "gone@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, gone)
+	assert.Equal(t, "No such user", gone.Reason)
+	assert.Equal(t, "mailgun", gone.Provider)
+}
+
+func TestWebhooksWithoutKeys(t *testing.T) {
+	webhooks, suppressions, server, emitted := bounceServer(t, &Config{})
+	event := `{"signature":{"timestamp":"1","token":"t","signature":"s"},
+		"event-data":{"event":"complained","recipient":"ada@example.com"}}`
+	assert.Equal(t, http.StatusNotFound, post(t, server.URL+"/mailgun", event))
+	assert.Equal(t, http.StatusNotFound, post(t, server.URL+"/sendgrid", `[{"email":"ada@example.com","event":"spamreport"}]`))
+
+	// the handlers mounted by hand refuse the events too
+	rec := httptest.NewRecorder()
+	webhooks.HandleMailgun(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(event)))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = httptest.NewRecorder()
+	webhooks.HandleSendGrid(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[{"email":"ada@example.com","event":"spamreport"}]`)))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	suppressed, err := suppressions.Suppressed(context.Background(), "ada@example.com")
+	require.NoError(t, err)
+	assert.Nil(t, suppressed)
+	assert.Empty(t, emitted())
+}
+
+func TestSESBounces(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "sns"}, NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	var subscribed atomic.Bool
+	aws := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/subscribe" {
+			subscribed.Store(true)
+			return
+		}
+		_ = pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}))
+	t.Cleanup(aws.Close)
+	host := snsHost
+	snsHost = regexp.MustCompile(`^127\.0\.0\.1$`)
+	t.Cleanup(func() { snsHost = host })
+
+	webhooks, suppressions, server, emitted := bounceServer(t, &Config{})
+	webhooks.Client = aws.Client()
+	send := func(m snsMessage) int {
+		m.SignatureVersion = "2"
+		m.SigningCertURL = aws.URL + "/cert.pem"
+		digest := sha256.Sum256([]byte(m.signedString()))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+		m.Signature = base64.StdEncoding.EncodeToString(signature)
+		body, err := json.Marshal(m)
+		require.NoError(t, err)
+		return post(t, server.URL+"/ses", string(body))
+	}
+
+	assert.Equal(t, http.StatusOK, send(snsMessage{Type: "SubscriptionConfirmation", Token: "t", TopicArn: "arn", SubscribeURL: aws.URL + "/subscribe", Timestamp: "2024-01-01T00:00:00Z"}))
+	assert.True(t, subscribed.Load())
+
+	notification := `{"notificationType":"Complaint","complaint":{"complaintFeedbackType":"abuse","complainedRecipients":[{"emailAddress":"angry@example.com"}]},"mail":{"messageId":"m1"}}`
+	assert.Equal(t, http.StatusOK, send(snsMessage{Type: "Notification", MessageID: "1", TopicArn: "arn", Message: notification, Timestamp: "2024-01-01T00:00:00Z"}))
+	angry, err := suppressions.Suppressed(context.Background(), "angry@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, angry)
+	assert.Equal(t, Complaint, angry.Type)
+	assert.Equal(t, "m1", angry.MessageID)
+	require.Len(t, emitted(), 1)
+
+	// a message changed after its signature is refused
+	forged := snsMessage{Type: "Notification", MessageID: "2", TopicArn: "arn", Message: notification, SignatureVersion: "2", SigningCertURL: aws.URL + "/cert.pem", Signature: "AAAA"}
+	body, _ := json.Marshal(forged)
+	assert.Equal(t, http.StatusForbidden, post(t, server.URL+"/ses", string(body)))
+	forged.SigningCertURL = "https://evil.example.com/cert.pem"
+	body, _ = json.Marshal(forged)
+	assert.Equal(t, http.StatusForbidden, post(t, server.URL+"/ses", string(body)))
+}
+
+func TestSuppressedRecipientsAreNotSent(t *testing.T) {
+	transport := &stubTransport{}
+	m, _ := newQueuedMailer(t, transport)
+	m.Suppressions = NewMemorySuppressionList()
+	ctx := context.Background()
+	require.NoError(t, m.Suppressions.Suppress(ctx, Bounce{Address: "gone@example.com", Type: BounceHard}))
+
+	message := testMessage()
+	message.To = append(message.To, EmailAddress{Address: "Gone@Example.com"})
+	require.NoError(t, m.QueueEmail(message))
+	assert.Eventually(t, func() bool { return transport.sentCount() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []EmailAddress{{"ada@example.com", "Ada"}}, transport.sent[0].To)
+
+	only := &Message{Subject: "Hi", To: []EmailAddress{{Address: "gone@example.com"}}}
+	_, err := m.withoutSuppressed(ctx, only)
+	assert.True(t, errors.Is(err, ErrSuppressed))
+	require.NoError(t, m.QueueEmail(only))
+	var failed []FailedEmail
+	assert.Eventually(t, func() bool {
+		failed, _ = m.FailedEmails(ctx)
+		return len(failed) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, ErrSuppressed.Error(), failed[0].Error)
+	assert.Equal(t, 1, failed[0].Attempts)
+}
@@ -0,0 +1,220 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	htmlTemplate "html/template"
+	"strings"
+	"sync"
+	textTemplate "text/template"
+	"time"
+)
+
+// batchSizes are the default batch sizes of the drivers, under the number of messages a
+// connection or an API call of the provider accepts
+var batchSizes = map[string]int{
+	"smtp":     100,
+	"ses":      50,
+	"sendgrid": 1000,
+	"mailgun":  1000,
+	"postmark": 500,
+}
+
+// BulkOptions sets how SendBulk sends the emails
+type BulkOptions struct {
+	RatePerSecond float64       // the emails sent per second, no limit when zero
+	Connections   int           // the emails sent at once, 1 when zero
+	BatchSize     int           // the emails sent before each pause, the limit of the driver when zero
+	BatchPause    time.Duration // the pause between the batches, none when zero
+
+	// MergeVars personalizes the emails: the subject and bodies of an email are templates
+	// executed with the vars of its first recipient, keyed by address, e.g. {{.FirstName}}
+	MergeVars map[string]map[string]any
+
+	// OnProgress is called after each email with the number of emails done and the total
+	OnProgress func(done, total int)
+}
+
+// BulkFailure is an email SendBulk could not send
+type BulkFailure struct {
+	Message *Message
+	Err     error
+}
+
+// BulkReport is the result of SendBulk
+type BulkReport struct {
+	Total      int
+	Sent       int
+	Suppressed int // the emails not sent since all their recipients are suppressed
+	Failures   []BulkFailure
+	Duration   time.Duration
+}
+
+// Failed returns the number of emails that failed
+func (r *BulkReport) Failed() int {
+	return len(r.Failures)
+}
+
+// SendBulk sends many emails, e.g. a newsletter, without tripping the throttles of the
+// provider: the emails are sent in batches by a few connections at a limited rate. A failed
+// email is not tried again, it is in the failures of the report. Cancelling ctx stops the
+// sending, the emails not sent are left out of the report.
+func (m *Mailer) SendBulk(ctx context.Context, messages []*Message, opts BulkOptions) (*BulkReport, error) {
+	m.Init()
+	start := time.Now()
+	report := &BulkReport{Total: len(messages)}
+
+	connections := opts.Connections
+	if connections <= 0 {
+		connections = 1
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 && m.Config != nil {
+		batchSize = batchSizes[m.Config.Driver]
+	}
+	if batchSize <= 0 {
+		batchSize = len(messages)
+	}
+
+	var tick <-chan time.Time
+	if opts.RatePerSecond > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.RatePerSecond))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	batches := make(chan []*Message)
+	go func() {
+		defer close(batches)
+		for i := 0; i < len(messages); i += batchSize {
+			batch := messages[i:min(i+batchSize, len(messages))]
+			if i > 0 && opts.BatchPause > 0 && !sleep(ctx, opts.BatchPause) {
+				return
+			}
+			select {
+			case batches <- batch:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	done := 0
+	var wg sync.WaitGroup
+	for i := 0; i < connections; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				for _, message := range batch {
+					if tick != nil {
+						select {
+						case <-tick:
+						case <-ctx.Done():
+						}
+					}
+					if ctx.Err() != nil {
+						break
+					}
+					err := m.sendBulkEmail(ctx, message, opts.MergeVars)
+
+					mu.Lock()
+					switch {
+					case err == nil:
+						report.Sent++
+					case errors.Is(err, ErrSuppressed):
+						report.Suppressed++
+					default:
+						report.Failures = append(report.Failures, BulkFailure{Message: message, Err: err})
+					}
+					done++
+					if opts.OnProgress != nil {
+						opts.OnProgress(done, report.Total)
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	report.Duration = time.Since(start)
+	InfoLogger.Printf("Bulk sending done: %d sent, %d suppressed, %d failed of %d emails in %s",
+		report.Sent, report.Suppressed, report.Failed(), report.Total, report.Duration)
+	return report, ctx.Err()
+}
+
+// sendBulkEmail personalizes and sends one email of SendBulk, once
+func (m *Mailer) sendBulkEmail(ctx context.Context, message *Message, mergeVars map[string]map[string]any) error {
+	if len(message.To) > 0 {
+		if vars, ok := mergeVars[normalizeAddress(message.To[0].Address)]; ok {
+			personalized, err := personalize(message, vars)
+			if err != nil {
+				return err
+			}
+			message = personalized
+		}
+	}
+
+	m.track(message)
+	message, err := m.withoutSuppressed(ctx, message)
+	if err != nil {
+		return err
+	}
+	return traceSend(ctx, message, m.Transport.Send)
+}
+
+// personalize returns a copy of the message with its subject and bodies executed as
+// templates with the vars
+func personalize(message *Message, vars map[string]any) (*Message, error) {
+	personalized := *message
+	var err error
+	if personalized.Subject, err = executeText(message.Subject, vars); err != nil {
+		return nil, err
+	}
+	if personalized.Body, err = executeText(message.Body, vars); err != nil {
+		return nil, err
+	}
+	if strings.Contains(message.HTMLBody, "{{") {
+		t, err := htmlTemplate.New("html").Parse(message.HTMLBody)
+		if err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		if err := t.Execute(&b, vars); err != nil {
+			return nil, err
+		}
+		personalized.HTMLBody = b.String()
+	}
+	return &personalized, nil
+}
+
+// executeText executes s as a text template with the vars, s has no action most of the time
+func executeText(s string, vars map[string]any) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	t, err := textTemplate.New("text").Parse(s)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, vars); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// sleep waits for d, it returns false when ctx is done first
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
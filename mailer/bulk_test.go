@@ -0,0 +1,92 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bulkMessages(n int) []*Message {
+	messages := make([]*Message, n)
+	for i := range messages {
+		messages[i] = &Message{
+			To:       []EmailAddress{{Address: fmt.Sprintf("user%d@example.com", i)}},
+			Subject:  "Hi {{.Name}}",
+			HTMLBody: "<p>Hello {{.Name}}</p>",
+		}
+	}
+	return messages
+}
+
+func TestSendBulk(t *testing.T) {
+	transport := &stubTransport{fail: []error{errors.New("refused")}}
+	m := &Mailer{Transport: transport, Suppressions: NewMemorySuppressionList()}
+	ctx := context.Background()
+	require.NoError(t, m.Suppressions.Suppress(ctx, Bounce{Address: "user4@example.com", Type: BounceHard}))
+
+	var progress []int
+	report, err := m.SendBulk(ctx, bulkMessages(5), BulkOptions{
+		MergeVars: map[string]map[string]any{
+			"user1@example.com": {"Name": "<Ada>"},
+			"user2@example.com": {"Name": "Grace"},
+			"user3@example.com": {"Name": "Alan"},
+		},
+		OnProgress: func(done, total int) {
+			assert.Equal(t, 5, total)
+			progress = append(progress, done)
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 5, report.Total)
+	assert.Equal(t, 3, report.Sent)
+	assert.Equal(t, 1, report.Suppressed)
+	require.Equal(t, 1, report.Failed())
+	assert.Equal(t, "user0@example.com", report.Failures[0].Message.To[0].Address)
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, progress)
+
+	require.Len(t, transport.sent, 3)
+	assert.Equal(t, "Hi <Ada>", transport.sent[0].Subject)
+	assert.Equal(t, "<p>Hello &lt;Ada&gt;</p>", transport.sent[0].HTMLBody)
+	assert.Equal(t, "Hi Grace", transport.sent[1].Subject)
+}
+
+func TestSendBulkLimitsTheRate(t *testing.T) {
+	transport := &stubTransport{}
+	m := &Mailer{Transport: transport}
+
+	// a tick every 10ms shared by the connections
+	report, err := m.SendBulk(context.Background(), bulkMessages(6), BulkOptions{RatePerSecond: 100, Connections: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 6, report.Sent)
+	assert.GreaterOrEqual(t, report.Duration, 60*time.Millisecond)
+
+	// a pause after the first and second batch
+	report, err = m.SendBulk(context.Background(), bulkMessages(6), BulkOptions{BatchSize: 2, BatchPause: 50 * time.Millisecond})
+	require.NoError(t, err)
+	assert.Equal(t, 6, report.Sent)
+	assert.GreaterOrEqual(t, report.Duration, 100*time.Millisecond)
+	assert.Equal(t, 12, transport.sentCount())
+}
+
+func TestSendBulkStopsWhenCancelled(t *testing.T) {
+	transport := &stubTransport{}
+	m := &Mailer{Transport: transport}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	report, err := m.SendBulk(ctx, bulkMessages(100), BulkOptions{
+		RatePerSecond: 50,
+		OnProgress: func(done, _ int) {
+			if done == 2 {
+				cancel()
+			}
+		},
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 2, report.Sent)
+	assert.Equal(t, 2, transport.sentCount())
+}
@@ -0,0 +1,118 @@
+package mailer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// the methods of the calendar invites
+const (
+	CalendarRequest = "REQUEST" // invites the attendees, or updates the event
+	CalendarCancel  = "CANCEL"  // cancels the event
+	CalendarPublish = "PUBLISH" // shares the event without asking for replies
+)
+
+// CalendarEvent is the event of a calendar invite, see Message.AddCalendarEvent
+type CalendarEvent struct {
+	UID         string // identifies the event, an update or a cancellation has the UID of the invite
+	Method      string // CalendarRequest when empty
+	Sequence    int    // increases with every update of the event
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	Organizer   EmailAddress
+	Attendees   []EmailAddress
+}
+
+// ICS returns the event as an iCalendar file
+func (e *CalendarEvent) ICS() []byte {
+	method := e.method()
+	status := "CONFIRMED"
+	if method == CalendarCancel {
+		status = "CANCELLED"
+	}
+
+	var b strings.Builder
+	line := func(name, value string) {
+		writeICSLine(&b, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("PRODID", "-//sauri//mailer//EN")
+	line("VERSION", "2.0")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", method)
+	line("BEGIN", "VEVENT")
+	line("UID", escapeICS(e.UID))
+	line("DTSTAMP", icsTime(time.Now()))
+	line("DTSTART", icsTime(e.Start))
+	if !e.End.IsZero() {
+		line("DTEND", icsTime(e.End))
+	}
+	line("SEQUENCE", fmt.Sprint(e.Sequence))
+	line("STATUS", status)
+	line("SUMMARY", escapeICS(e.Summary))
+	if e.Description != "" {
+		line("DESCRIPTION", escapeICS(e.Description))
+	}
+	if e.Location != "" {
+		line("LOCATION", escapeICS(e.Location))
+	}
+	if e.URL != "" {
+		line("URL", e.URL)
+	}
+	if e.Organizer.Address != "" {
+		line("ORGANIZER"+icsName(e.Organizer), "mailto:"+e.Organizer.Address)
+	}
+	for _, attendee := range e.Attendees {
+		line("ATTENDEE"+icsName(attendee)+";ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE", "mailto:"+attendee.Address)
+	}
+	line("END", "VEVENT")
+	line("END", "VCALENDAR")
+	return []byte(b.String())
+}
+
+func (e *CalendarEvent) method() string {
+	if e.Method == "" {
+		return CalendarRequest
+	}
+	return strings.ToUpper(e.Method)
+}
+
+// writeICSLine writes a content line, folded to lines of 75 octets at most as RFC 5545 wants
+func writeICSLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		// a multibyte character is not split
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = 74 // the space of a folded line counts
+	}
+	b.WriteString(line + "\r\n")
+}
+
+// icsEscaper escapes the characters of the text values of iCalendar
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeICS(s string) string {
+	return icsEscaper.Replace(s)
+}
+
+// icsName returns the CN parameter of an address, none without a name
+func icsName(a EmailAddress) string {
+	if a.Name == "" {
+		return ""
+	}
+	return `;CN="` + strings.NewReplacer(`"`, "'", "\r", "", "\n", " ").Replace(a.Name) + `"`
+}
+
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
@@ -0,0 +1,80 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendarEventICS(t *testing.T) {
+	event := &CalendarEvent{
+		UID:         "event-1@example.com",
+		Summary:     "Planning; Q3, budget",
+		Description: "Agenda:\nnumbers",
+		Start:       time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+		End:         time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Organizer:   EmailAddress{"grace@example.com", "Grace"},
+		Attendees:   []EmailAddress{{"ada@example.com", "Ada Lovelace, Countess"}},
+		Location:    strings.Repeat("Room ", 30),
+	}
+	ics := string(event.ICS())
+
+	assert.True(t, strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\n"))
+	assert.Contains(t, ics, "\r\nMETHOD:REQUEST\r\n")
+	assert.Contains(t, ics, "\r\nDTSTART:20240501T090000Z\r\n")
+	assert.Contains(t, ics, `SUMMARY:Planning\; Q3\, budget`)
+	assert.Contains(t, ics, `DESCRIPTION:Agenda:\nnumbers`)
+	assert.Contains(t, ics, `ORGANIZER;CN="Grace":mailto:grace@example.com`)
+	assert.Contains(t, ics, `ATTENDEE;CN="Ada Lovelace, Countess";ROLE=REQ-PARTICIPANT`)
+	for _, line := range strings.Split(ics, "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+	}
+	assert.Contains(t, strings.ReplaceAll(ics, "\r\n ", ""), "LOCATION:"+strings.Repeat("Room ", 30))
+
+	event.Method = CalendarCancel
+	assert.Contains(t, string(event.ICS()), "\r\nSTATUS:CANCELLED\r\n")
+}
+
+func TestMessageHeaders(t *testing.T) {
+	m := testMessage()
+	m.From = EmailAddress{Address: "app@example.com"}
+	m.SetListUnsubscribe("https://example.com/unsubscribe/1", "unsubscribe@example.com")
+	m.SetPriority(PriorityHigh)
+	id := m.SetMessageID("")
+
+	assert.Equal(t, "<mailto:unsubscribe@example.com>, <https://example.com/unsubscribe/1>", m.Headers["List-Unsubscribe"])
+	assert.Equal(t, "List-Unsubscribe=One-Click", m.Headers["List-Unsubscribe-Post"])
+	assert.Equal(t, "1 (Highest)", m.Headers["X-Priority"])
+	assert.Regexp(t, `^<\d{14}\.[0-9a-f]{32}@example\.com>$`, id)
+	assert.Equal(t, "<abc@example.com>", m.SetMessageID("abc@example.com"))
+}
+
+func TestMailInvite(t *testing.T) {
+	dir := t.TempDir()
+	transport, err := NewFileTransport(&Config{Path: dir})
+	require.NoError(t, err)
+	m := &Mailer{Config: &Config{From: EmailAddress{Address: "app@example.com"}}}
+
+	event := &CalendarEvent{Summary: "Kickoff", Start: time.Now().Add(time.Hour)}
+	message, err := NewMail().To("ada@example.com").Subject("Kickoff").Text("See you").
+		Invite(event).Priority(PriorityLow).Build(m)
+	require.NoError(t, err)
+	assert.Equal(t, "app@example.com", event.Organizer.Address)
+	assert.Equal(t, []EmailAddress{{Address: "ada@example.com"}}, event.Attendees)
+	assert.True(t, strings.HasSuffix(event.UID, "@example.com"))
+
+	require.NoError(t, transport.Send(context.Background(), message))
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	raw, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), "Content-Type: text/calendar; method=REQUEST; charset=UTF-8")
+	assert.Contains(t, string(raw), "X-Priority: 5 (Lowest)")
+}
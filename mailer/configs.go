@@ -3,24 +3,53 @@ package mailer
 import (
 	"crypto/tls"
 	mailpkg "github.com/xhit/go-simple-mail/v2"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
-// Config holds configuration for the SMTP server
+// Config holds configuration for the SMTP server or the mail API of the driver
 type Config struct {
+	Driver         string // smtp, sendgrid, mailgun, ses, postmark, log or file, see NewTransport
 	Host           string
 	Port           int
 	Username       string
 	Password       string
 	Encryption     mailpkg.Encryption
 	From           EmailAddress
+	ReplyTo        EmailAddress // the reply address of the emails built with NewMail, none when empty
 	KeepAlive      bool
 	ConnectTimeout time.Duration
 	SendTimeout    time.Duration
 	TLSConfig      *tls.Config
 	TemplatesDir   string
+	Templates      fs.FS // the email templates, e.g. an embed.FS, the TemplatesDir folder when nil
+
+	// the settings of the API drivers
+	APIKey    string // the API key, the access key id for ses
+	APISecret string // the secret access key for ses
+	Domain    string // the sending domain for mailgun
+	Region    string // the region for ses, eu for the EU region of mailgun
+	Endpoint  string // replaces the URL of the API, e.g. for a proxy
+	Sandbox   bool   // the API checks the messages without delivering them
+
+	Path string // the folder of the .eml files of the file driver
+
+	// the tracking of the opens and clicks, see Tracker
+	TrackingURL      string   // the URL of the tracking routes, e.g. https://example.com/email, no tracking when empty
+	TrackingWebhooks []string // the URLs receiving the tracking events
+
+	// the keys verifying the bounce webhooks, see BounceWebhooks
+	MailgunWebhookKey  string
+	SendGridWebhookKey string
+
+	// the DKIM signature of the smtp and ses drivers, no signature without a private key
+	DKIMPrivateKey string // the PEM of an RSA key, or the path of its file
+	DKIMDomain     string // the signing domain, the one of the sender when empty
+	DKIMSelector   string // the selector of the DNS record of the public key
 }
 
 // LoadConfig loads the SMTP configuration from environment variables
@@ -42,6 +71,7 @@ func LoadConfig(currRoot string) *Config {
 	}
 
 	config := &Config{
+		Driver:     getEnv("MAIL_DRIVER", "smtp"),
 		Host:       getEnv("MAIL_HOST", "smtp.example.com"),
 		Port:       port,
 		Username:   getEnv("MAIL_USERNAME", ""),
@@ -51,13 +81,34 @@ func LoadConfig(currRoot string) *Config {
 			Address: getEnv("MAIL_FROM_ADDRESS", "no-reply@example.com"),
 			Name:    getEnv("MAIL_FROM_NAME", "Example"),
 		},
+		ReplyTo: EmailAddress{
+			Address: getEnv("MAIL_REPLY_TO_ADDRESS", ""),
+			Name:    getEnv("MAIL_REPLY_TO_NAME", ""),
+		},
 		// KeepAlive:      getEnv("MAIL_KEEP_ALIVE", "false") == "true",
 		ConnectTimeout: 10 * time.Second,
 		SendTimeout:    10 * time.Second,
 		TLSConfig: &tls.Config{
 			InsecureSkipVerify: false,
 		},
-		TemplatesDir: currRoot + "/mails",
+		TemplatesDir: getEnv("MAIL_TEMPLATES", filepath.Join(currRoot, "resources", "mails")),
+		APIKey:       getEnv("MAIL_API_KEY", ""),
+		APISecret:    getEnv("MAIL_API_SECRET", ""),
+		Domain:       getEnv("MAIL_DOMAIN", ""),
+		Region:       getEnv("MAIL_REGION", ""),
+		Endpoint:     getEnv("MAIL_ENDPOINT", ""),
+		Sandbox:      getEnv("MAIL_SANDBOX", "false") == "true",
+		Path:         getEnv("MAIL_PATH", filepath.Join(currRoot, "storage", "emails")),
+
+		TrackingURL:      getEnv("MAIL_TRACKING_URL", ""),
+		TrackingWebhooks: splitList(getEnv("MAIL_TRACKING_WEBHOOKS", "")),
+
+		MailgunWebhookKey:  getEnv("MAIL_MAILGUN_WEBHOOK_KEY", ""),
+		SendGridWebhookKey: getEnv("MAIL_SENDGRID_WEBHOOK_KEY", ""),
+
+		DKIMPrivateKey: getEnv("MAIL_DKIM_PRIVATE_KEY", ""),
+		DKIMDomain:     getEnv("MAIL_DKIM_DOMAIN", ""),
+		DKIMSelector:   getEnv("MAIL_DKIM_SELECTOR", ""),
 	}
 
 	/*if config.Username == "" || config.Password == "" {
@@ -73,3 +124,14 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// splitList splits a comma separated list, without the empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,159 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LogTransport writes the messages to the mail logger instead of sending them, so that the
+// mails of development can be read without an SMTP server
+type LogTransport struct {
+	config *Config
+}
+
+// NewLogTransport creates a transport writing the messages to InfoLogger
+func NewLogTransport(config *Config) *LogTransport {
+	return &LogTransport{config: config}
+}
+
+// Send logs a single email message
+func (t *LogTransport) Send(ctx context.Context, m *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	logger := InfoLogger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Printf("Email not sent, MAIL_DRIVER is log\n%s", describeMessage(m, t.config))
+	return nil
+}
+
+// SendMultiple logs multiple email messages
+func (t *LogTransport) SendMultiple(ctx context.Context, emails []*Message) error {
+	for _, m := range emails {
+		if err := t.Send(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FileTransport writes each message to an .eml file of config.Path instead of sending it.
+// Mail clients open these files, which shows the message as it would be received.
+type FileTransport struct {
+	config *Config
+	now    func() time.Time
+}
+
+// NewFileTransport creates a transport writing the messages to config.Path, the folder is
+// created when it does not exist
+func NewFileTransport(config *Config) (*FileTransport, error) {
+	if config.Path == "" {
+		return nil, errors.New("the file driver needs MAIL_PATH")
+	}
+	if err := os.MkdirAll(config.Path, 0755); err != nil {
+		return nil, err
+	}
+	return &FileTransport{config: config, now: time.Now}, nil
+}
+
+// Send writes a single email message, the file is named after the time and the subject
+func (t *FileTransport) Send(ctx context.Context, m *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	message := *m
+	message.From = fromOf(m, t.config)
+	message.Bcc = nil
+	email := newSimpleMail(&message)
+	// the bcc are not in the headers of a sent message, they are kept in the file to check them
+	email.AddBccToHeader = true
+	for _, bcc := range m.Bcc {
+		email.AddBcc(formatAddress(bcc))
+	}
+	if email.Error != nil {
+		return email.Error
+	}
+
+	name := t.now().Format("20060102-150405.000000000")
+	if slug := fileSlug(m.Subject); slug != "" {
+		name += "-" + slug
+	}
+	path := filepath.Join(t.config.Path, name+".eml")
+	if err := os.WriteFile(path, []byte(email.GetMessage()), 0644); err != nil {
+		return err
+	}
+	if InfoLogger != nil {
+		InfoLogger.Printf("Email to %s written to %s", formatAddresses(m.To), path)
+	}
+	return nil
+}
+
+// SendMultiple writes multiple email messages
+func (t *FileTransport) SendMultiple(ctx context.Context, emails []*Message) error {
+	return sendEach(ctx, t, emails)
+}
+
+// unsafeFileChars are the characters of a subject left out of a file name
+var unsafeFileChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// fileSlug returns the subject in a form fit for a file name
+func fileSlug(subject string) string {
+	slug := strings.Trim(unsafeFileChars.ReplaceAllString(strings.ToLower(subject), "-"), "-")
+	if len(slug) > 50 {
+		slug = strings.TrimRight(slug[:50], "-")
+	}
+	return slug
+}
+
+// describeMessage returns m as a person reads it: the addresses, the subject, the headers,
+// the attachments and the bodies
+func describeMessage(m *Message, config *Config) string {
+	var b strings.Builder
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%-9s %s\n", name+":", value)
+		}
+	}
+	field("From", formatAddress(fromOf(m, config)))
+	field("To", formatAddresses(m.To))
+	field("Cc", formatAddresses(m.Cc))
+	field("Bcc", formatAddresses(m.Bcc))
+	if m.ReplyTo.Address != "" {
+		field("Reply-To", formatAddress(m.ReplyTo))
+	}
+	field("Subject", m.Subject)
+
+	names := make([]string, 0, len(m.Headers))
+	for name := range m.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field(name, m.Headers[name])
+	}
+	for _, attachment := range m.Attachments {
+		kind := "attached"
+		if attachment.Inline {
+			kind = "inline"
+		}
+		field("File", fmt.Sprintf("%s (%s, %d bytes, %s)", attachment.Name, attachment.MimeType, len(attachment.Data), kind))
+	}
+
+	if m.Body != "" {
+		b.WriteString("\n" + strings.TrimRight(m.Body, "\n") + "\n")
+	}
+	if m.HTMLBody != "" {
+		b.WriteString("\n--- html ---\n" + strings.TrimRight(m.HTMLBody, "\n") + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,61 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogTransport(t *testing.T) {
+	var out bytes.Buffer
+	InfoLogger = log.New(&out, "", 0)
+	t.Cleanup(func() { InfoLogger = nil })
+
+	transport, err := NewTransport(&Config{Driver: "log", From: EmailAddress{Address: "app@example.com"}})
+	require.NoError(t, err)
+	require.NoError(t, transport.Send(context.Background(), testMessage()))
+
+	logged := out.String()
+	assert.Contains(t, logged, "From:     <app@example.com>")
+	assert.Contains(t, logged, `To:       "Ada" <ada@example.com>`)
+	assert.Contains(t, logged, "Bcc:      <audit@example.com>")
+	assert.Contains(t, logged, "Subject:  Welcome")
+	assert.Contains(t, logged, "X-Campaign: welcome")
+	assert.Contains(t, logged, "File:     logo.png (image/png, 3 bytes, inline)")
+	assert.Contains(t, logged, "\nhello\n")
+	assert.Contains(t, logged, "--- html ---\n<p>hello</p>")
+}
+
+func TestFileTransport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "emails")
+	transport, err := NewFileTransport(&Config{Path: dir, From: EmailAddress{Address: "app@example.com", Name: "App"}})
+	require.NoError(t, err)
+	transport.now = func() time.Time { return time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC) }
+
+	m := testMessage()
+	m.Subject = "Welcome to the App!"
+	require.NoError(t, transport.Send(context.Background(), m))
+
+	path := filepath.Join(dir, "20240501-103000.000000000-welcome-to-the-app.eml")
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, `"App" <app@example.com>`, parsed.Header.Get("From"))
+	assert.Equal(t, `"Ada" <ada@example.com>`, parsed.Header.Get("To"))
+	assert.Equal(t, "<audit@example.com>", parsed.Header.Get("Bcc"))
+	assert.Equal(t, "Welcome to the App!", parsed.Header.Get("Subject"))
+	assert.Equal(t, "welcome", parsed.Header.Get("X-Campaign"))
+
+	_, err = NewFileTransport(&Config{})
+	assert.ErrorContains(t, err, "MAIL_PATH")
+}
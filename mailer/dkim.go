@@ -0,0 +1,93 @@
+package mailer
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/toorop/go-dkim"
+	mailpkg "github.com/xhit/go-simple-mail/v2"
+	"os"
+	"strings"
+)
+
+// dkimHeaders are the headers covered by the DKIM signature, the ones a message does not
+// have are left out of it
+var dkimHeaders = []string{
+	"from", "reply-to", "subject", "date", "to", "cc", "message-id",
+	"mime-version", "content-type", "list-unsubscribe",
+}
+
+// dkimOptions returns the options signing the messages with the DKIM settings of config, nil
+// when no private key is set. The domain is the one of the sender of each message when
+// config.DKIMDomain is empty.
+func dkimOptions(config *Config) (*dkim.SigOptions, error) {
+	if config.DKIMPrivateKey == "" {
+		return nil, nil
+	}
+	if config.DKIMSelector == "" {
+		return nil, errors.New("DKIM signing needs MAIL_DKIM_SELECTOR, the selector of the DNS record of the public key")
+	}
+	key, err := loadDKIMKey(config.DKIMPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	options := dkim.NewSigOptions()
+	options.PrivateKey = key
+	options.Domain = config.DKIMDomain
+	options.Selector = config.DKIMSelector
+	options.Canonicalization = "relaxed/relaxed"
+	options.Headers = dkimHeaders
+	return &options, nil
+}
+
+// loadDKIMKey returns the PEM of an RSA private key given as is, with \n for the line breaks
+// since a .env value is a single line, or as the path of its file
+func loadDKIMKey(value string) ([]byte, error) {
+	key := []byte(strings.ReplaceAll(value, `\n`, "\n"))
+	if !strings.Contains(value, "-----BEGIN") {
+		content, err := os.ReadFile(value)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read the DKIM private key: %w", err)
+		}
+		key = content
+	}
+
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return nil, errors.New("the DKIM private key is not a PEM block")
+	}
+	if _, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DKIM private key: %w", err)
+	}
+	if _, ok := parsed.(*rsa.PrivateKey); !ok {
+		return nil, errors.New("the DKIM private key must be an RSA key")
+	}
+	return key, nil
+}
+
+// signDKIM signs the built message, the signature is sent with it
+func signDKIM(email *mailpkg.Email, options *dkim.SigOptions, from EmailAddress) {
+	if options == nil {
+		return
+	}
+	signing := *options
+	if signing.Domain == "" {
+		_, signing.Domain, _ = strings.Cut(from.Address, "@")
+	}
+	email.SetDkim(signing)
+}
+
+// rawMessage returns the message as sent, with its DKIM signature when it was signed
+func rawMessage(email *mailpkg.Email) string {
+	if email.DkimMsg != "" {
+		return email.DkimMsg
+	}
+	return email.GetMessage()
+}
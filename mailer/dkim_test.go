@@ -0,0 +1,93 @@
+package mailer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toorop/go-dkim"
+)
+
+// dkimKey returns the PEM of a new RSA key and the TXT record of its public key
+func dkimKey(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	private := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return string(private), "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(public)
+}
+
+func TestDKIMSignature(t *testing.T) {
+	private, record := dkimKey(t)
+	server, _, bodies := apiServer(t, http.StatusOK, `{"MessageId":"1"}`)
+	config := testConfig(server.URL)
+	config.DKIMPrivateKey = strings.ReplaceAll(private, "\n", `\n`)
+	config.DKIMSelector = "mail"
+	transport, err := NewSESTransport(config)
+	require.NoError(t, err)
+
+	require.NoError(t, transport.Send(context.Background(), testMessage()))
+	var payload sesMessage
+	require.NoError(t, json.Unmarshal((*bodies)[0], &payload))
+	raw, err := base64.StdEncoding.DecodeString(payload.Content.Raw.Data)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(raw), "DKIM-Signature: "))
+
+	// the domain is the one of the sender
+	var looked string
+	status, err := dkim.Verify(&raw, dkim.DNSOptLookupTXT(func(name string) ([]string, error) {
+		looked = name
+		return []string{record}, nil
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, dkim.SUCCESS, status)
+	assert.Equal(t, "mail._domainkey.example.com", looked)
+}
+
+func TestDKIMOptions(t *testing.T) {
+	options, err := dkimOptions(&Config{})
+	require.NoError(t, err)
+	assert.Nil(t, options)
+
+	private, _ := dkimKey(t)
+	_, err = dkimOptions(&Config{DKIMPrivateKey: private})
+	assert.ErrorContains(t, err, "MAIL_DKIM_SELECTOR")
+
+	path := filepath.Join(t.TempDir(), "dkim.pem")
+	require.NoError(t, os.WriteFile(path, []byte(private), 0600))
+	options, err = dkimOptions(&Config{DKIMPrivateKey: path, DKIMDomain: "example.org", DKIMSelector: "s1"})
+	require.NoError(t, err)
+	assert.Equal(t, "example.org", options.Domain)
+	assert.Equal(t, "relaxed/relaxed", options.Canonicalization)
+
+	_, err = dkimOptions(&Config{DKIMPrivateKey: filepath.Join(t.TempDir(), "missing.pem"), DKIMSelector: "s1"})
+	assert.ErrorContains(t, err, "cannot read the DKIM private key")
+}
+
+func TestLoadDKIMKeyNeedsRSA(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	_, err = loadDKIMKey(string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})))
+	assert.ErrorContains(t, err, "must be an RSA key")
+	_, err = loadDKIMKey("-----BEGIN nothing")
+	assert.ErrorContains(t, err, "not a PEM block")
+}
@@ -1,23 +1,47 @@
 package mailer
 
 import (
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 )
 
 var (
 	InfoLogger  *log.Logger
 	ErrorLogger *log.Logger
+	// loggerSet keeps InitLogger from replacing the loggers given to SetLogger
+	loggerSet atomic.Bool
 )
 
-func InitLogger() {
+// SetLogger makes the mail loggers write to l, e.g. the mailer child logger of the
+// application, instead of storage/logs/mail.log
+func SetLogger(l *slog.Logger) {
+	InfoLogger = slog.NewLogLogger(l.Handler(), slog.LevelInfo)
+	ErrorLogger = slog.NewLogLogger(l.Handler(), slog.LevelError)
+	loggerSet.Store(true)
+}
+
+// InitLogger sets up the mail loggers writing to storage/logs/mail.log. When the log
+// file cannot be opened the loggers write to stderr and the error is returned.
+func InitLogger() error {
+	if loggerSet.Load() {
+		return nil
+	}
+	var out io.Writer = os.Stderr
 	file, err := os.OpenFile(filepath.Join("storage", "logs", "mail.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		log.Fatal(err)
+	if err == nil {
+		out = file
 	}
 
-	InfoLogger = log.New(file, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
-	ErrorLogger = log.New(file, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
+	InfoLogger = log.New(out, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
+	ErrorLogger = log.New(out, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
 
+	if err != nil {
+		return fmt.Errorf("cannot open mail log file: %w", err)
+	}
+	return nil
 }
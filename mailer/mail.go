@@ -1,7 +1,14 @@
 package mailer
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/haskekareem/sauri/jobs"
+	"github.com/haskekareem/sauri/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"sync"
 	"time"
 )
@@ -9,32 +16,104 @@ import (
 type Mailer struct {
 	Config     *Config
 	Transport  MailTransport
-	Scheduler  *Scheduler
-	initOnce   sync.Once //
+	Scheduler  *Scheduler // sends the emails of ScheduleEmail, Init queues them by default
+	initOnce   sync.Once  //
 	EmailQueue chan *Message
+	Tracker    *Tracker // tracks the opens and clicks of the emails sent, none when nil
+
+	// the addresses the emails are not sent to, see BounceWebhooks
+	Suppressions SuppressionList
+
+	// the functions of the email templates, see AddFuncs
+	funcs map[string]any
+
+	// the job queue of QueueEmail, see UseJobs
+	jobs        *jobs.Manager
+	queue       string
+	maxAttempts int
+
+	// the listener of the EmailQueue channel, see ListenForEmails and Shutdown
+	queueMu        sync.RWMutex
+	queueClosed    bool
+	listening      sync.WaitGroup
+	cancelListener context.CancelFunc
+
+	previewsMu sync.RWMutex
+	previews   map[string]any // the sample data of the templates, see AddPreview
 }
 
+// ErrMailerClosed is returned when an email is queued after Shutdown
+var ErrMailerClosed = errors.New("the mailer is shut down")
+
 // Init initializes the Mailer
 func (m *Mailer) Init() {
 	m.initOnce.Do(func() {
-		InitLogger()
-		m.Scheduler.Start()
+		if err := InitLogger(); err != nil {
+			ErrorLogger.Println(err)
+		}
+		if m.Scheduler == nil {
+			m.Scheduler = &Scheduler{Send: m.QueueEmailContext}
+		}
 	})
 }
 
 // SendEmail sends a single email
 func (m *Mailer) SendEmail(message *Message) error {
+	return m.SendEmailContext(context.Background(), message)
+}
+
+// SendEmailContext sends a single email, traced as a child of the span in ctx. The send is
+// given up when ctx is done, e.g. at its deadline. The suppressed recipients are left out, it
+// returns ErrSuppressed when none is left.
+func (m *Mailer) SendEmailContext(ctx context.Context, message *Message) error {
 	m.Init()
-	return m.sendWithRetry(message)
+	m.track(message)
+	message, err := m.withoutSuppressed(ctx, message)
+	if err != nil {
+		return err
+	}
+	return traceSend(ctx, message, m.sendWithRetry)
+}
+
+// traceSend sends a message with send in a span child of the one of ctx
+func traceSend(ctx context.Context, message *Message, send func(ctx context.Context, message *Message) error) error {
+	ctx, span := tracing.Tracer().Start(ctx, "mail.send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.Int("mail.recipients", len(message.To)+len(message.Cc)+len(message.Bcc)),
+			attribute.Int("mail.attachments", len(message.Attachments)),
+		),
+	)
+	defer span.End()
+
+	err := send(ctx, message)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// track prepares the message for the tracker, if any
+func (m *Mailer) track(message *Message) {
+	if m.Tracker != nil {
+		m.Tracker.Track(message)
+	}
 }
 
 // ListenForEmails listens for incoming emails on the emailQueue channel and
-// sends them
+// sends them, until Shutdown
 func (m *Mailer) ListenForEmails() {
 	m.Init()
+	ctx, cancel := context.WithCancel(context.Background())
+	m.queueMu.Lock()
+	m.cancelListener = cancel
+	m.queueMu.Unlock()
+	m.listening.Add(1)
 	go func() {
+		defer m.listening.Done()
 		for msg := range m.EmailQueue {
-			if err := m.SendEmail(msg); err != nil {
+			if err := m.SendEmailContext(ctx, msg); err != nil {
 				ErrorLogger.Printf("Failed to send email: %v", err)
 			} else {
 				InfoLogger.Printf("Email sent successfully to %v", msg.To)
@@ -43,37 +122,96 @@ func (m *Mailer) ListenForEmails() {
 	}()
 }
 
-// QueueEmail queues an email to be sent
-func (m *Mailer) QueueEmail(message *Message) {
-	m.EmailQueue <- message
+// Shutdown stops the mailer: the scheduled emails not sent yet are dropped, the EmailQueue
+// channel is closed and the emails queued on it are sent. The sends still going when ctx is
+// done are cancelled.
+func (m *Mailer) Shutdown(ctx context.Context) error {
+	if m.Scheduler != nil {
+		m.Scheduler.Stop()
+	}
+
+	m.queueMu.Lock()
+	if !m.queueClosed && m.EmailQueue != nil {
+		close(m.EmailQueue)
+	}
+	m.queueClosed = true
+	cancel := m.cancelListener
+	m.queueMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.listening.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		if cancel != nil {
+			cancel()
+		}
+		return ctx.Err()
+	}
+}
+
+// QueueEmail queues an email to be sent, as a job when UseJobs was called and on the
+// EmailQueue channel of ListenForEmails otherwise
+func (m *Mailer) QueueEmail(message *Message) error {
+	return m.QueueEmailContext(context.Background(), message)
 }
 
 // SendMultipleEmails sends multiple emails using the same SMTP connection
 func (m *Mailer) SendMultipleEmails(messages []*Message) error {
+	return m.SendMultipleEmailsContext(context.Background(), messages)
+}
+
+// SendMultipleEmailsContext sends multiple emails like SendMultipleEmails, it stops when ctx
+// is done
+func (m *Mailer) SendMultipleEmailsContext(ctx context.Context, messages []*Message) error {
 	m.Init()
-	return m.Transport.SendMultiple(messages)
+	return m.Transport.SendMultiple(ctx, messages)
 }
 
-// sendWithRetry sends an email with retry logic
-func (m *Mailer) sendWithRetry(message *Message) error {
+// sendWithRetry sends an email with retry logic, it gives up when ctx is done
+func (m *Mailer) sendWithRetry(ctx context.Context, message *Message) error {
 	const maxRetries = 3
+	var err error
 	for i := 0; i < maxRetries; i++ {
-		err := m.Transport.Send(message)
+		err = m.Transport.Send(ctx, message)
 		if err == nil {
 			return nil
 		}
 		ErrorLogger.Printf("Failed to send email, attempt %d/%d: %v", i+1, maxRetries, err)
-		time.Sleep(2 * time.Second)
-
+		// a message the API refused is refused again
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && !apiErr.Temporary() {
+			return err
+		}
+		if ctx.Err() != nil || !sleep(ctx, 2*time.Second) {
+			return err
+		}
 	}
-	return fmt.Errorf("failed to send email after %d attempts", maxRetries)
+	return fmt.Errorf("failed to send email after %d attempts: %w", maxRetries, err)
 }
 
-// ScheduleEmail schedules an email to be sent at a specific time
-func (m *Mailer) ScheduleEmail(message *Message, sendTime time.Time) error {
+// ScheduleEmail schedules an email to be sent once at a specific time, it returns the id of
+// the scheduled email for CancelScheduledEmail
+func (m *Mailer) ScheduleEmail(message *Message, sendTime time.Time) (string, error) {
 	m.Init()
-	_, err := m.Scheduler.ScheduleEmail(message, sendTime)
-	return err
+	return m.Scheduler.ScheduleEmail(message, sendTime)
+}
+
+// CancelScheduledEmail cancels an email of ScheduleEmail not sent yet, it returns
+// ErrNotScheduled when there is none with the id
+func (m *Mailer) CancelScheduledEmail(id string) error {
+	m.Init()
+	return m.Scheduler.CancelEmail(id)
+}
+
+// ScheduledEmails returns the emails of ScheduleEmail not sent yet, the earliest first
+func (m *Mailer) ScheduledEmails() []ScheduledEmail {
+	m.Init()
+	return m.Scheduler.Pending()
 }
 
 // SetBodyFromTemplate sets the email body from a template
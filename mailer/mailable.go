@@ -0,0 +1,240 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+)
+
+// Mailable is an email of the application, e.g. a welcome email built from a user, that
+// builds its message for a mailer, see Mailer.Send and Mailer.Queue
+type Mailable interface {
+	Build(m *Mailer) (*Message, error)
+}
+
+// Mail builds a message with a fluent API, it is a Mailable:
+//
+//	mailer.NewMail().To("ada@example.com").Subject("Welcome").Template("welcome", data)
+//
+// The addresses are either plain or with a name, "Ada <ada@example.com>". The first error,
+// e.g. an invalid address or a missing file, is returned by Build.
+type Mail struct {
+	message  Message
+	template string
+	data     any
+	events   []*CalendarEvent
+	err      error
+}
+
+// NewMail starts a new email
+func NewMail() *Mail {
+	return &Mail{}
+}
+
+// From sets the sender, the From of the config by default
+func (b *Mail) From(address string) *Mail {
+	b.message.From = b.address(address)
+	return b
+}
+
+// ReplyTo sets the address of the replies, the ReplyTo of the config by default
+func (b *Mail) ReplyTo(address string) *Mail {
+	b.message.ReplyTo = b.address(address)
+	return b
+}
+
+// To adds recipients
+func (b *Mail) To(addresses ...string) *Mail {
+	for _, address := range addresses {
+		b.message.To = append(b.message.To, b.address(address))
+	}
+	return b
+}
+
+// Cc adds carbon copy recipients
+func (b *Mail) Cc(addresses ...string) *Mail {
+	for _, address := range addresses {
+		b.message.Cc = append(b.message.Cc, b.address(address))
+	}
+	return b
+}
+
+// Bcc adds blind carbon copy recipients
+func (b *Mail) Bcc(addresses ...string) *Mail {
+	for _, address := range addresses {
+		b.message.Bcc = append(b.message.Bcc, b.address(address))
+	}
+	return b
+}
+
+// Subject sets the subject
+func (b *Mail) Subject(subject string) *Mail {
+	b.message.Subject = subject
+	return b
+}
+
+// Text sets the plain text body, it replaces the one of the template
+func (b *Mail) Text(body string) *Mail {
+	b.message.Body = body
+	return b
+}
+
+// HTML sets the HTML body, it replaces the one of the template
+func (b *Mail) HTML(body string) *Mail {
+	b.message.HTMLBody = body
+	return b
+}
+
+// Template renders the bodies from the templates name.html.gohtml, or name.md.gohtml for
+// Markdown, and name.plain.gohtml of the email templates with data, the plain text is made
+// from the HTML when the email has no plain template
+func (b *Mail) Template(name string, data any) *Mail {
+	b.template = name
+	b.data = data
+	return b
+}
+
+// Attach attaches the files of the paths
+func (b *Mail) Attach(paths ...string) *Mail {
+	for _, path := range paths {
+		b.attachFile(path, false)
+	}
+	return b
+}
+
+// Embed attaches the files of the paths inline, the HTML shows them with cid:<file name>
+func (b *Mail) Embed(paths ...string) *Mail {
+	for _, path := range paths {
+		b.attachFile(path, true)
+	}
+	return b
+}
+
+// AttachData attaches data as a file named name
+func (b *Mail) AttachData(name string, data []byte, mimeType string) *Mail {
+	if err := b.message.AddAttachmentFromBytes(name, data, mimeType, false); err != nil {
+		b.fail(err)
+	}
+	return b
+}
+
+// Header sets a header of the email
+func (b *Mail) Header(name, value string) *Mail {
+	if b.message.Headers == nil {
+		b.message.Headers = make(map[string]string)
+	}
+	b.message.Headers[name] = value
+	return b
+}
+
+// Tag sets metadata of the email, sent to the mail APIs which have them
+func (b *Mail) Tag(name, value string) *Mail {
+	if b.message.Metadata == nil {
+		b.message.Metadata = make(map[string]string)
+	}
+	b.message.Metadata[name] = value
+	return b
+}
+
+// Invite adds an invite of the event, see Message.AddCalendarEvent
+func (b *Mail) Invite(event *CalendarEvent) *Mail {
+	b.events = append(b.events, event)
+	return b
+}
+
+// Priority sets the priority of the email
+func (b *Mail) Priority(priority Priority) *Mail {
+	b.message.SetPriority(priority)
+	return b
+}
+
+// ListUnsubscribe sets the unsubscribe URL and address of the email, see
+// Message.SetListUnsubscribe
+func (b *Mail) ListUnsubscribe(url, mailto string) *Mail {
+	b.message.SetListUnsubscribe(url, mailto)
+	return b
+}
+
+// Build returns the message, with the default sender and reply address of the config of m
+// and the bodies rendered from the template
+func (b *Mail) Build(m *Mailer) (*Message, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.message.To)+len(b.message.Cc)+len(b.message.Bcc) == 0 {
+		return nil, fmt.Errorf("the email %q has no recipient", b.message.Subject)
+	}
+
+	message := b.message
+	if m.Config != nil {
+		if message.From.Address == "" {
+			message.From = m.Config.From
+		}
+		if message.ReplyTo.Address == "" {
+			message.ReplyTo = m.Config.ReplyTo
+		}
+	}
+	if b.template != "" {
+		html, plain, err := m.renderTemplate(b.template, b.data)
+		if err != nil {
+			return nil, err
+		}
+		if message.HTMLBody == "" {
+			message.HTMLBody = html
+		}
+		if message.Body == "" {
+			message.Body = plain
+		}
+	}
+	if message.Body == "" && message.HTMLBody != "" {
+		message.Body = htmlToText(message.HTMLBody)
+	}
+	if len(b.events) > 0 {
+		message.Attachments = append([]Attachment(nil), message.Attachments...)
+		for _, event := range b.events {
+			message.AddCalendarEvent(event)
+		}
+	}
+	return &message, nil
+}
+
+// address parses an address, plain or with a name
+func (b *Mail) address(address string) EmailAddress {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		b.fail(fmt.Errorf("invalid email address %q: %w", address, err))
+		return EmailAddress{Address: address}
+	}
+	return EmailAddress{Address: parsed.Address, Name: parsed.Name}
+}
+
+func (b *Mail) attachFile(path string, inline bool) {
+	if err := b.message.AddAttachmentFromFile(path, inline); err != nil {
+		b.fail(err)
+	}
+}
+
+// fail keeps the first error of the builder
+func (b *Mail) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Send builds the mailable and sends it
+func (m *Mailer) Send(ctx context.Context, mailable Mailable) error {
+	message, err := mailable.Build(m)
+	if err != nil {
+		return err
+	}
+	return m.SendEmailContext(ctx, message)
+}
+
+// Queue builds the mailable and queues it, see QueueEmail
+func (m *Mailer) Queue(ctx context.Context, mailable Mailable) error {
+	message, err := mailable.Build(m)
+	if err != nil {
+		return err
+	}
+	return m.QueueEmailContext(ctx, message)
+}
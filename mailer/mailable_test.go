@@ -0,0 +1,98 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// templatesMailer returns a mailer rendering the templates of files
+func templatesMailer(t *testing.T, files map[string]string) *Mailer {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return &Mailer{Config: &Config{
+		TemplatesDir: dir,
+		From:         EmailAddress{Address: "app@example.com", Name: "App"},
+		ReplyTo:      EmailAddress{Address: "support@example.com"},
+	}}
+}
+
+func TestMailBuild(t *testing.T) {
+	m := templatesMailer(t, map[string]string{
+		"welcome.html.gohtml": `{{define "body"}}<h1>Hello {{.}}</h1><p>Read the <a href="https://example.com/docs">docs</a>.</p>{{end}}`,
+	})
+
+	message, err := NewMail().
+		To("Ada <ada@example.com>", "bob@example.com").
+		Bcc("audit@example.com").
+		Subject("Welcome").
+		Template("welcome", "Ada").
+		AttachData("terms.txt", []byte("terms"), "text/plain").
+		Header("X-Campaign", "welcome").
+		Build(m)
+	require.NoError(t, err)
+
+	assert.Equal(t, EmailAddress{Address: "app@example.com", Name: "App"}, message.From)
+	assert.Equal(t, "support@example.com", message.ReplyTo.Address)
+	assert.Equal(t, []EmailAddress{{"ada@example.com", "Ada"}, {"bob@example.com", ""}}, message.To)
+	assert.Contains(t, message.HTMLBody, "<h1>Hello Ada</h1>")
+	assert.Equal(t, "Hello Ada\n\nRead the docs (https://example.com/docs).", message.Body)
+	assert.Equal(t, "terms.txt", message.Attachments[0].Name)
+	assert.Equal(t, "welcome", message.Headers["X-Campaign"])
+}
+
+func TestMailBuildWithPlainTemplate(t *testing.T) {
+	m := templatesMailer(t, map[string]string{
+		"reset.html.gohtml":  `{{define "body"}}<p>html</p>{{end}}`,
+		"reset.plain.gohtml": `{{define "body"}}plain {{.}}{{end}}`,
+	})
+
+	message, err := NewMail().From("Team <team@example.com>").To("ada@example.com").Template("reset", 42).Build(m)
+	require.NoError(t, err)
+	assert.Equal(t, "plain 42", message.Body)
+	assert.Equal(t, EmailAddress{Address: "team@example.com", Name: "Team"}, message.From)
+}
+
+func TestMailBuildErrors(t *testing.T) {
+	m := templatesMailer(t, nil)
+
+	_, err := NewMail().To("not an address").Build(m)
+	assert.ErrorContains(t, err, `invalid email address "not an address"`)
+
+	_, err = NewMail().Subject("Hi").Build(m)
+	assert.ErrorContains(t, err, "has no recipient")
+
+	_, err = NewMail().To("ada@example.com").Attach(filepath.Join(t.TempDir(), "missing.pdf")).Build(m)
+	assert.Error(t, err)
+
+	_, err = NewMail().To("ada@example.com").Template("missing", nil).Build(m)
+	assert.ErrorContains(t, err, "email template missing not found, searched missing.html.gohtml, missing.html.tmpl, missing.md.gohtml")
+}
+
+func TestMailerQueue(t *testing.T) {
+	transport := &stubTransport{}
+	m, _ := newQueuedMailer(t, transport)
+	m.Config = &Config{From: EmailAddress{Address: "app@example.com"}}
+
+	require.NoError(t, m.Queue(context.Background(), NewMail().To("ada@example.com").Subject("Hi").HTML("<p>hi</p>")))
+	assert.Eventually(t, func() bool { return transport.sentCount() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "hi", transport.sent[0].Body)
+	assert.Equal(t, "app@example.com", transport.sent[0].From.Address)
+}
+
+func TestHTMLToText(t *testing.T) {
+	text := htmlToText(`<html><head><style>p{}</style></head><body>
+		<p>Hi <b>Ada</b>,</p>
+		<ul><li>one</li><li>two</li></ul>
+		line<br>break
+	</body></html>`)
+	assert.Equal(t, "Hi Ada,\n\n- one\n- two\n\nline\nbreak", text)
+}
@@ -0,0 +1,130 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+// MailgunTransport sends the messages with the messages API of Mailgun
+type MailgunTransport struct {
+	config   *Config
+	endpoint string
+	client   *http.Client
+}
+
+// NewMailgunTransport creates a Mailgun transport, config.APIKey is the API key and
+// config.Domain the sending domain, config.Region eu uses the EU region. In sandbox mode
+// the messages are sent in the test mode of Mailgun, which accepts them without delivering
+// them.
+func NewMailgunTransport(config *Config) (*MailgunTransport, error) {
+	if config.APIKey == "" || config.Domain == "" {
+		return nil, errors.New("the mailgun driver needs MAIL_API_KEY and MAIL_DOMAIN")
+	}
+	base := "https://api.mailgun.net"
+	if strings.EqualFold(config.Region, "eu") {
+		base = "https://api.eu.mailgun.net"
+	}
+	return &MailgunTransport{
+		config:   config,
+		endpoint: apiEndpoint(config, base) + "/v3/" + url.PathEscape(config.Domain) + "/messages",
+		client:   apiClient(config),
+	}, nil
+}
+
+// Send sends a single email message
+func (t *MailgunTransport) Send(ctx context.Context, m *Message) error {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	fields := [][2]string{
+		{"from", formatAddress(fromOf(m, t.config))},
+		{"subject", m.Subject},
+	}
+	for _, to := range m.To {
+		fields = append(fields, [2]string{"to", formatAddress(to)})
+	}
+	for _, cc := range m.Cc {
+		fields = append(fields, [2]string{"cc", formatAddress(cc)})
+	}
+	for _, bcc := range m.Bcc {
+		fields = append(fields, [2]string{"bcc", formatAddress(bcc)})
+	}
+	if m.Body != "" {
+		fields = append(fields, [2]string{"text", m.Body})
+	}
+	if m.HTMLBody != "" {
+		fields = append(fields, [2]string{"html", m.HTMLBody})
+	}
+	if m.ReplyTo.Address != "" {
+		fields = append(fields, [2]string{"h:Reply-To", formatAddress(m.ReplyTo)})
+	}
+	for name, value := range m.Headers {
+		fields = append(fields, [2]string{"h:" + name, value})
+	}
+	for name, value := range m.Metadata {
+		fields = append(fields, [2]string{"v:" + name, value})
+	}
+	if t.config.Sandbox {
+		fields = append(fields, [2]string{"o:testmode", "yes"})
+	}
+	for _, field := range fields {
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+
+	// an inline file is referenced in the html by its name, cid:<name>
+	for _, attachment := range m.Attachments {
+		field := "attachment"
+		if attachment.Inline {
+			field = "inline"
+		}
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", `form-data; name="`+field+`"; filename="`+escapeQuotes(attachment.Name)+`"`)
+		header.Set("Content-Type", attachment.MimeType)
+		part, err := form.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(attachment.Data); err != nil {
+			return err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.SetBasicAuth("api", t.config.APIKey)
+	return doAPIRequest(t.client, "mailgun", req, mailgunError)
+}
+
+// SendMultiple sends multiple email messages, one request each
+func (t *MailgunTransport) SendMultiple(ctx context.Context, emails []*Message) error {
+	return sendEach(ctx, t, emails)
+}
+
+// mailgunError reads the message of a response, {"message": "..."}
+func mailgunError(_ *http.Response, body []byte) *APIError {
+	var response struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(body, &response)
+	return &APIError{Message: response.Message}
+}
+
+// escapeQuotes escapes the quotes and backslashes of a file name of a multipart header
+func escapeQuotes(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
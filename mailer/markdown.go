@@ -0,0 +1,100 @@
+package mailer
+
+import (
+	"bytes"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	htmlTemplate "html/template"
+	"path"
+	textTemplate "text/template"
+)
+
+// markdownExtensions are the extensions of the files searched for the Markdown template of
+// an email, in order
+var markdownExtensions = []string{".md.gohtml", ".md.tmpl", ".md"}
+
+// markdown converts the Markdown of the emails with the GitHub extensions, tables, strike
+// through and links, the raw HTML of the Markdown is left out
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// markdownLayout is the default "markdown" layout of the Markdown emails, a layout or
+// partial of the email templates defining "markdown" replaces it. Its data is a
+// MarkdownData.
+const markdownLayout = `{{define "markdown"}}<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<style>
+body { margin: 0; padding: 24px 12px; background: #f4f5f7; color: #1f2933; font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; font-size: 16px; line-height: 1.6; }
+.content { max-width: 600px; margin: 0 auto; padding: 32px; background: #ffffff; border-radius: 6px; }
+h1, h2, h3 { color: #111827; line-height: 1.3; margin: 0 0 16px; }
+p, ul, ol, table, pre, blockquote { margin: 0 0 16px; }
+a { color: #2563eb; }
+code { padding: 2px 4px; background: #f3f4f6; border-radius: 3px; font-family: Menlo, Consolas, monospace; font-size: 14px; }
+pre { padding: 12px; background: #f3f4f6; border-radius: 4px; overflow: auto; }
+blockquote { padding-left: 12px; border-left: 4px solid #e5e7eb; color: #4b5563; }
+table { border-collapse: collapse; }
+th, td { padding: 6px 12px; border: 1px solid #e5e7eb; text-align: left; }
+hr { border: 0; border-top: 1px solid #e5e7eb; }
+</style>
+</head>
+<body>
+<div class="content">{{.Content}}</div>
+</body>
+</html>{{end}}`
+
+// MarkdownData is the data of the "markdown" layout
+type MarkdownData struct {
+	Content htmlTemplate.HTML // the HTML of the Markdown
+	Data    any               // the data of the email
+}
+
+// buildMarkdownMessage renders the Markdown template of the email and returns its HTML in the
+// "markdown" layout with the CSS inlined, and the plain text made from it
+func (m *Mailer) buildMarkdownMessage(templateName string, data interface{}) (string, string, error) {
+	fsys := m.templates()
+	files, err := templateFiles(fsys, templateName, markdownExtensions)
+	if err != nil {
+		return "", "", err
+	}
+
+	main := path.Base(files[len(files)-1])
+	t, err := textTemplate.New(main).Funcs(m.funcs).ParseFS(fsys, files...)
+	if err != nil {
+		return "", "", err
+	}
+	source, err := executeTemplate(t, main, t.Lookup("body") != nil, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	var content bytes.Buffer
+	if err = markdown.Convert([]byte(source), &content); err != nil {
+		return "", "", err
+	}
+
+	layout, err := htmlTemplate.New("markdown-layout").Funcs(m.funcs).Parse(markdownLayout)
+	if err != nil {
+		return "", "", err
+	}
+	shared, err := sharedFiles(fsys, htmlExtensions)
+	if err != nil {
+		return "", "", err
+	}
+	if len(shared) > 0 {
+		if layout, err = layout.ParseFS(fsys, shared...); err != nil {
+			return "", "", err
+		}
+	}
+
+	var body bytes.Buffer
+	if err = layout.ExecuteTemplate(&body, "markdown", MarkdownData{Content: htmlTemplate.HTML(content.String()), Data: data}); err != nil {
+		return "", "", err
+	}
+	htmlBody, err := m.inlineCSS(body.String())
+	if err != nil {
+		return "", "", err
+	}
+	return htmlBody, htmlToText(content.String()), nil
+}
@@ -1,5 +1,10 @@
 package mailer
 
+import (
+	"strings"
+	"time"
+)
+
 // EmailAddress represents an email address with a name
 type EmailAddress struct {
 	Address string
@@ -19,6 +24,7 @@ const (
 
 // Message represents an email message
 type Message struct {
+	ID          string // identifies the email in the tracking events, see Tracker.Track
 	From        EmailAddress
 	ReplyTo     EmailAddress
 	To          []EmailAddress
@@ -33,6 +39,18 @@ type Message struct {
 	Metadata    map[string]string
 }
 
+// Priority is the priority of an email shown by the mail clients
+type Priority int
+
+const (
+	// PriorityNormal is the priority of the emails without one
+	PriorityNormal Priority = iota
+	// PriorityHigh marks the email as important
+	PriorityHigh
+	// PriorityLow marks the email as not urgent
+	PriorityLow
+)
+
 // AddRecipient adds a recipient to the email
 func (m *Message) AddRecipient(email, name string) {
 	m.To = append(m.To, EmailAddress{email, name})
@@ -82,3 +100,96 @@ func (m *Message) AddAttachmentFromBytes(name string, data []byte, mimeType stri
 	m.Attachments = append(m.Attachments, *attachment)
 	return nil
 }
+
+// AddCalendarEvent adds an invite of the event to the email, a text/calendar part the mail
+// clients show with buttons to answer. The event gets a UID when it has none, keep it to
+// update or cancel the event later. The organizer is the sender and the attendees are the
+// recipients of the email when the event has none.
+func (m *Message) AddCalendarEvent(event *CalendarEvent) {
+	if event.Organizer.Address == "" {
+		event.Organizer = m.From
+	}
+	if len(event.Attendees) == 0 {
+		event.Attendees = append(append([]EmailAddress{}, m.To...), m.Cc...)
+	}
+	if event.UID == "" {
+		event.UID = newMessageID() + "@" + addressDomain(event.Organizer.Address)
+	}
+	m.Attachments = append(m.Attachments, Attachment{
+		Name:     "invite.ics",
+		Data:     event.ICS(),
+		MimeType: "text/calendar; method=" + event.method() + "; charset=UTF-8",
+	})
+}
+
+// SetListUnsubscribe sets the List-Unsubscribe header, with which the mail clients show an
+// unsubscribe button, to the URL and the mailto address, either may be empty. With an https
+// URL the clients unsubscribe with one click, a POST to the URL as RFC 8058 describes.
+func (m *Message) SetListUnsubscribe(url, mailto string) {
+	var targets []string
+	if mailto != "" {
+		targets = append(targets, "<mailto:"+strings.TrimPrefix(mailto, "mailto:")+">")
+	}
+	if url != "" {
+		targets = append(targets, "<"+url+">")
+	}
+	if len(targets) == 0 {
+		return
+	}
+	m.setHeader("List-Unsubscribe", strings.Join(targets, ", "))
+	if strings.HasPrefix(url, "https://") {
+		m.setHeader("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+}
+
+// SetPriority sets the priority headers of the email
+func (m *Message) SetPriority(priority Priority) {
+	switch priority {
+	case PriorityHigh:
+		m.setHeader("X-Priority", "1 (Highest)")
+		m.setHeader("Importance", "High")
+	case PriorityLow:
+		m.setHeader("X-Priority", "5 (Lowest)")
+		m.setHeader("Importance", "Low")
+	default:
+		m.setHeader("X-Priority", "3 (Normal)")
+		m.setHeader("Importance", "Normal")
+	}
+}
+
+// SetMessageID sets the Message-ID header, the replies refer to it. An empty id generates
+// one on the domain of the sender, see NewMessageID.
+func (m *Message) SetMessageID(id string) string {
+	if id == "" {
+		id = NewMessageID(addressDomain(m.From.Address))
+	}
+	if !strings.HasPrefix(id, "<") {
+		id = "<" + id + ">"
+	}
+	m.setHeader("Message-ID", id)
+	return id
+}
+
+// NewMessageID returns a new unique Message-ID on the domain, e.g. <1700000000.4f2a...@example.com>
+func NewMessageID(domain string) string {
+	if domain == "" {
+		domain = "localhost"
+	}
+	return "<" + strings.Join([]string{
+		time.Now().UTC().Format("20060102150405"),
+		newMessageID(),
+	}, ".") + "@" + domain + ">"
+}
+
+func (m *Message) setHeader(name, value string) {
+	if m.Headers == nil {
+		m.Headers = make(map[string]string)
+	}
+	m.Headers[name] = value
+}
+
+// addressDomain returns the domain of an address, empty when it has none
+func addressDomain(address string) string {
+	_, domain, _ := strings.Cut(address, "@")
+	return domain
+}
@@ -0,0 +1,130 @@
+package mailer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// postmarkTestToken is the server token with which Postmark accepts the messages without
+// delivering them
+const postmarkTestToken = "POSTMARK_API_TEST"
+
+// PostmarkTransport sends the messages with the email API of Postmark
+type PostmarkTransport struct {
+	config   *Config
+	endpoint string
+	client   *http.Client
+}
+
+// postmarkMessage is the body of the email API
+type postmarkMessage struct {
+	From        string               `json:"From"`
+	To          string               `json:"To"`
+	Cc          string               `json:"Cc,omitempty"`
+	Bcc         string               `json:"Bcc,omitempty"`
+	ReplyTo     string               `json:"ReplyTo,omitempty"`
+	Subject     string               `json:"Subject"`
+	TextBody    string               `json:"TextBody,omitempty"`
+	HTMLBody    string               `json:"HtmlBody,omitempty"`
+	Headers     []postmarkHeader     `json:"Headers,omitempty"`
+	Metadata    map[string]string    `json:"Metadata,omitempty"`
+	Attachments []postmarkAttachment `json:"Attachments,omitempty"`
+}
+
+type postmarkHeader struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+type postmarkAttachment struct {
+	Name        string `json:"Name"`
+	Content     string `json:"Content"`
+	ContentType string `json:"ContentType"`
+	ContentID   string `json:"ContentID,omitempty"`
+}
+
+// NewPostmarkTransport creates a Postmark transport, config.APIKey is the server token. In
+// sandbox mode the test token is sent instead, Postmark checks the messages and does not
+// deliver them.
+func NewPostmarkTransport(config *Config) (*PostmarkTransport, error) {
+	if config.APIKey == "" && !config.Sandbox {
+		return nil, errors.New("the postmark driver needs MAIL_API_KEY")
+	}
+	return &PostmarkTransport{
+		config:   config,
+		endpoint: apiEndpoint(config, "https://api.postmarkapp.com") + "/email",
+		client:   apiClient(config),
+	}, nil
+}
+
+// Send sends a single email message
+func (t *PostmarkTransport) Send(ctx context.Context, m *Message) error {
+	payload := postmarkMessage{
+		From:     formatAddress(fromOf(m, t.config)),
+		To:       formatAddresses(m.To),
+		Cc:       formatAddresses(m.Cc),
+		Bcc:      formatAddresses(m.Bcc),
+		Subject:  m.Subject,
+		TextBody: m.Body,
+		HTMLBody: m.HTMLBody,
+		Metadata: m.Metadata,
+	}
+	if m.ReplyTo.Address != "" {
+		payload.ReplyTo = formatAddress(m.ReplyTo)
+	}
+	for name, value := range m.Headers {
+		payload.Headers = append(payload.Headers, postmarkHeader{Name: name, Value: value})
+	}
+	for _, attachment := range m.Attachments {
+		a := postmarkAttachment{
+			Name:        attachment.Name,
+			Content:     base64.StdEncoding.EncodeToString(attachment.Data),
+			ContentType: attachment.MimeType,
+		}
+		if attachment.Inline {
+			a.ContentID = "cid:" + attachment.Name
+		}
+		payload.Attachments = append(payload.Attachments, a)
+	}
+
+	req, err := jsonRequest(ctx, t.endpoint, payload)
+	if err != nil {
+		return err
+	}
+	token := t.config.APIKey
+	if t.config.Sandbox {
+		token = postmarkTestToken
+	}
+	req.Header.Set("X-Postmark-Server-Token", token)
+	return doAPIRequest(t.client, "postmark", req, postmarkError)
+}
+
+// SendMultiple sends multiple email messages, one request each
+func (t *PostmarkTransport) SendMultiple(ctx context.Context, emails []*Message) error {
+	return sendEach(ctx, t, emails)
+}
+
+// postmarkError reads the error of a response, {"ErrorCode": 300, "Message": "..."}. Postmark
+// answers 422 to most errors, the code tells them apart.
+func postmarkError(_ *http.Response, body []byte) *APIError {
+	var response struct {
+		ErrorCode int    `json:"ErrorCode"`
+		Message   string `json:"Message"`
+	}
+	if json.Unmarshal(body, &response) != nil || response.ErrorCode == 0 {
+		return &APIError{Message: response.Message}
+	}
+
+	e := &APIError{Code: strconv.Itoa(response.ErrorCode), Message: response.Message}
+	// a bad token, a sender signature missing or not confirmed, an account out of credits or
+	// pending, the other codes are the ones of the message
+	switch response.ErrorCode {
+	case 10, 400, 401, 405, 412:
+		e.Err = ErrUnauthorized
+	}
+	return e
+}
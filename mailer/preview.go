@@ -0,0 +1,96 @@
+package mailer
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	htmlTemplate "html/template"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// AddPreview registers the sample data the template is rendered with by the preview routes,
+// see PreviewHandler
+func (m *Mailer) AddPreview(template string, data any) {
+	m.previewsMu.Lock()
+	defer m.previewsMu.Unlock()
+	if m.previews == nil {
+		m.previews = make(map[string]any)
+	}
+	m.previews[template] = data
+}
+
+// Previews returns the names of the templates with sample data, sorted
+func (m *Mailer) Previews() []string {
+	m.previewsMu.RLock()
+	defer m.previewsMu.RUnlock()
+	names := make([]string, 0, len(m.previews))
+	for name := range m.previews {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PreviewHandler renders the templates of AddPreview with their sample data, for development
+// only: / lists them, /{template} shows the HTML body and /{template}?format=plain the plain
+// text one. The templates are read on each request, the changes show on reload.
+func (m *Mailer) PreviewHandler() http.Handler {
+	mux := chi.NewRouter()
+	mux.Get("/", m.handlePreviewIndex)
+	mux.Get("/*", m.handlePreview)
+	return mux
+}
+
+// previewIndex is the page listing the templates of the previews
+var previewIndex = htmlTemplate.Must(htmlTemplate.New("index").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Email previews</title></head>
+<body><h1>Email previews</h1>
+{{range .}}<p>{{.}}: <a href="{{.}}">HTML</a> | <a href="{{.}}?format=plain">plain text</a></p>
+{{else}}<p>No template has sample data, see Mailer.AddPreview.</p>
+{{end}}</body></html>
+`))
+
+func (m *Mailer) handlePreviewIndex(w http.ResponseWriter, r *http.Request) {
+	// the links are relative to the folder of the previews
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		http.Redirect(w, r, r.URL.Path+"/", http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := previewIndex.Execute(w, m.Previews()); err != nil {
+		errorLogger().Println("cannot write the email previews:", err)
+	}
+}
+
+func (m *Mailer) handlePreview(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "*")
+	m.previewsMu.RLock()
+	data, ok := m.previews[name]
+	m.previewsMu.RUnlock()
+	if !ok {
+		http.Error(w, "no preview of the email template "+name, http.StatusNotFound)
+		return
+	}
+
+	htmlBody, plainBody, err := m.renderTemplate(name, data)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, fs.ErrNotExist) {
+			status = http.StatusNotFound
+		}
+		// the error is what the designer needs to fix the template
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if r.URL.Query().Get("format") == "plain" || htmlBody == "" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(plainBody))
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(htmlBody))
+}
@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreviewHandler(t *testing.T) {
+	m := fsMailer(fstest.MapFS{
+		"auth/welcome.html.gohtml": {Data: []byte(`<h1>Hi {{.Name}}</h1>`)},
+		"auth/welcome.plain.tmpl":  {Data: []byte(`Hi {{.Name}}`)},
+	})
+	m.AddPreview("auth/welcome", map[string]string{"Name": "Ada"})
+	m.AddPreview("missing", nil)
+	handler := m.PreviewHandler()
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := get("/auth/welcome")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "<h1>Hi Ada</h1>")
+
+	rec = get("/auth/welcome?format=plain")
+	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "Hi Ada", rec.Body.String())
+
+	rec = get("/")
+	assert.Contains(t, rec.Body.String(), `<a href="auth/welcome?format=plain">`)
+	assert.Equal(t, http.StatusNotFound, get("/missing").Code)
+	assert.Equal(t, http.StatusNotFound, get("/auth/unknown").Code)
+}
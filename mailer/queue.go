@@ -0,0 +1,121 @@
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/haskekareem/sauri/jobs"
+	"time"
+)
+
+// SendJob is the name of the jobs sending the queued emails
+const SendJob = "mail.send"
+
+// FailedEmail is a queued email that failed every attempt
+type FailedEmail struct {
+	ID       string // the id of the job, for RetryEmail
+	Message  *Message
+	Attempts int
+	FailedAt time.Time
+	Error    string
+}
+
+// UseJobs makes QueueEmail dispatch the emails as jobs of manager on the named queue, the
+// default one when empty, instead of the in-memory EmailQueue channel, so that they outlive
+// a crash when the manager keeps its jobs in Redis. An email is tried up to maxAttempts
+// times, the attempts of the manager when zero, waiting longer after every failure, then it
+// is kept with the failed jobs, see FailedEmails and RetryEmail. A message refused by a mail
+// API is not tried again.
+func (m *Mailer) UseJobs(manager *jobs.Manager, queue string, maxAttempts int) {
+	m.jobs = manager
+	m.queue = queue
+	m.maxAttempts = maxAttempts
+	manager.HandleFunc(SendJob, m.handleSendJob)
+}
+
+// QueueEmailContext queues an email to be sent like QueueEmail, using ctx for the queue call
+func (m *Mailer) QueueEmailContext(ctx context.Context, message *Message) error {
+	m.track(message)
+	if m.jobs == nil {
+		m.queueMu.RLock()
+		defer m.queueMu.RUnlock()
+		if m.queueClosed {
+			return ErrMailerClosed
+		}
+		select {
+		case m.EmailQueue <- message:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return m.jobs.DispatchPayload(ctx, SendJob, message, jobs.OnQueue(m.queue), jobs.MaxAttempts(m.maxAttempts))
+}
+
+// handleSendJob sends a queued email once, the job manager retries it on failure
+func (m *Mailer) handleSendJob(ctx context.Context, payload json.RawMessage) error {
+	var message Message
+	if err := json.Unmarshal(payload, &message); err != nil {
+		return jobs.Permanent(fmt.Errorf("cannot decode the email: %w", err))
+	}
+
+	filtered, err := m.withoutSuppressed(ctx, &message)
+	if errors.Is(err, ErrSuppressed) {
+		return jobs.Permanent(err)
+	} else if err != nil {
+		return err
+	}
+
+	err = traceSend(ctx, filtered, m.Transport.Send)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && !apiErr.Temporary() {
+		return jobs.Permanent(err)
+	}
+	return err
+}
+
+// FailedEmails returns the queued emails that failed every attempt, the oldest first
+func (m *Mailer) FailedEmails(ctx context.Context) ([]FailedEmail, error) {
+	if m.jobs == nil {
+		return nil, errors.New("the emails are not queued as jobs, see UseJobs")
+	}
+	return FailedEmails(ctx, m.jobs.Queue())
+}
+
+// RetryEmail queues a failed email again with its attempts reset, it returns
+// jobs.ErrNotFailed when no failed email has the id
+func (m *Mailer) RetryEmail(ctx context.Context, id string) error {
+	if m.jobs == nil {
+		return errors.New("the emails are not queued as jobs, see UseJobs")
+	}
+	return m.jobs.Retry(ctx, id)
+}
+
+// FailedEmails returns the emails of the failed jobs of queue, for the commands working on
+// the queue of an application without its mailer
+func FailedEmails(ctx context.Context, queue jobs.Queue) ([]FailedEmail, error) {
+	failed, err := queue.Failed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []FailedEmail
+	for _, e := range failed {
+		if e.Name != SendJob {
+			continue
+		}
+		email := FailedEmail{
+			ID:       e.ID,
+			Message:  &Message{},
+			Attempts: e.Attempts,
+			FailedAt: e.FailedAt,
+			Error:    e.LastError,
+		}
+		if err := json.Unmarshal(e.Payload, email.Message); err != nil {
+			email.Message = nil
+		}
+		emails = append(emails, email)
+	}
+	return emails, nil
+}
@@ -0,0 +1,120 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haskekareem/sauri/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubTransport records the messages and fails with the errors of fail, one per send
+type stubTransport struct {
+	mu   sync.Mutex
+	sent []*Message
+	fail []error
+}
+
+func (s *stubTransport) Send(_ context.Context, m *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.fail) > 0 {
+		err := s.fail[0]
+		s.fail = s.fail[1:]
+		return err
+	}
+	s.sent = append(s.sent, m)
+	return nil
+}
+
+func (s *stubTransport) SendMultiple(ctx context.Context, emails []*Message) error {
+	return sendEach(ctx, s, emails)
+}
+
+func (s *stubTransport) sentCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sent)
+}
+
+func newQueuedMailer(t *testing.T, transport MailTransport) (*Mailer, jobs.Queue) {
+	t.Helper()
+	queue := jobs.NewMemoryQueue()
+	manager := jobs.New(queue, jobs.Config{
+		MaxAttempts:  3,
+		PollInterval: 5 * time.Millisecond,
+		Backoff:      func(int) time.Duration { return time.Millisecond },
+	})
+	m := &Mailer{Transport: transport}
+	m.UseJobs(manager, "mail", 0)
+	require.NoError(t, manager.StartWorkers(context.Background(), 1, "mail"))
+	t.Cleanup(func() { _ = manager.Shutdown(context.Background()) })
+	return m, queue
+}
+
+func TestQueueEmailRetriesThenSends(t *testing.T) {
+	transport := &stubTransport{fail: []error{&APIError{Err: ErrUnavailable}}}
+	m, _ := newQueuedMailer(t, transport)
+
+	require.NoError(t, m.QueueEmail(testMessage()))
+	assert.Eventually(t, func() bool { return transport.sentCount() == 1 }, time.Second, 5*time.Millisecond)
+
+	sent := transport.sent[0]
+	assert.Equal(t, "Welcome", sent.Subject)
+	assert.Equal(t, []byte("png"), sent.Attachments[0].Data)
+}
+
+func TestQueueEmailKeepsFailedEmails(t *testing.T) {
+	rejected := &APIError{Provider: "sendgrid", StatusCode: 400, Message: "invalid", Err: ErrRejected}
+	transport := &stubTransport{fail: []error{rejected, rejected, rejected}}
+	m, _ := newQueuedMailer(t, transport)
+	ctx := context.Background()
+
+	require.NoError(t, m.QueueEmail(testMessage()))
+	var failed []FailedEmail
+	assert.Eventually(t, func() bool {
+		failed, _ = m.FailedEmails(ctx)
+		return len(failed) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	// a rejected message is not tried again
+	assert.Equal(t, 1, failed[0].Attempts)
+	assert.Equal(t, "sendgrid: 400: invalid", failed[0].Error)
+	assert.Equal(t, "Welcome", failed[0].Message.Subject)
+
+	transport.mu.Lock()
+	transport.fail = nil
+	transport.mu.Unlock()
+	require.NoError(t, m.RetryEmail(ctx, failed[0].ID))
+	assert.Eventually(t, func() bool { return transport.sentCount() == 1 }, time.Second, 5*time.Millisecond)
+	assert.True(t, errors.Is(m.RetryEmail(ctx, failed[0].ID), jobs.ErrNotFailed))
+}
+
+func TestQueueEmailWithoutJobs(t *testing.T) {
+	m := &Mailer{EmailQueue: make(chan *Message, 1)}
+	require.NoError(t, m.QueueEmail(testMessage()))
+	assert.Len(t, m.EmailQueue, 1)
+
+	_, err := m.FailedEmails(context.Background())
+	assert.Error(t, err)
+}
+
+func TestShutdownSendsTheQueuedEmails(t *testing.T) {
+	SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil))) // the listener logs each email
+	transport := &stubTransport{}
+	m := &Mailer{Transport: transport, EmailQueue: make(chan *Message, 2)}
+	require.NoError(t, m.QueueEmail(testMessage()))
+	require.NoError(t, m.QueueEmail(testMessage()))
+	m.ListenForEmails()
+
+	require.NoError(t, m.Shutdown(context.Background()))
+	assert.Equal(t, 2, transport.sentCount())
+	assert.ErrorIs(t, m.QueueEmail(testMessage()), ErrMailerClosed)
+	assert.NoError(t, m.Shutdown(context.Background()))
+}
@@ -1,58 +1,115 @@
 package mailer
 
 import (
-	"github.com/robfig/cron/v3"
-	"log"
+	"context"
+	"errors"
+	"sort"
+	"sync"
 	"time"
 )
 
-// Scheduler schedules emails to be sent at a later time
+// ErrNotScheduled is returned when no pending scheduled email has the id
+var ErrNotScheduled = errors.New("no pending scheduled email has the id")
+
+// ScheduledEmail is an email waiting for its send time
+type ScheduledEmail struct {
+	ID      string
+	Message *Message
+	SendAt  time.Time
+}
+
+// Scheduler sends emails once at a later time. The pending emails are kept in memory with a
+// timer each, they are lost on restart.
 type Scheduler struct {
-	C         *cron.Cron
-	Queue     chan *Message
-	Transport MailTransport
+	// Send sends an email when its time comes, the Mailer queues it
+	Send func(ctx context.Context, message *Message) error
+
+	mu      sync.Mutex
+	pending map[string]*scheduledEmail
+	stopped bool
 }
 
-// NewScheduler creates a new Scheduler
-func NewScheduler(t MailTransport) *Scheduler {
-	return &Scheduler{
-		C:         cron.New(cron.WithSeconds()), // Ensure we support second-level granularity
-		Queue:     make(chan *Message, 100),
-		Transport: t,
-	}
+type scheduledEmail struct {
+	ScheduledEmail
+	timer *time.Timer
 }
 
-// ScheduleEmail schedules an email to be sent at a specific time
-func (s *Scheduler) ScheduleEmail(message *Message, sendTime time.Time) (cron.EntryID, error) {
-	// Convert sendTime to cron expression with second-level granularity
-	cronExpr := sendTime.Format("05 04 15 02 Jan Mon")
+// NewScheduler creates a Scheduler sending the emails with t
+func NewScheduler(t MailTransport) *Scheduler {
+	return &Scheduler{Send: t.Send}
+}
 
-	id, err := s.C.AddFunc(cronExpr, func() {
-		s.Queue <- message
-		log.Printf("Scheduled email sent to %v", message.To)
-	})
-	if err != nil {
-		return 0, err
+// ScheduleEmail schedules an email to be sent once at sendTime, right away when it is past,
+// it returns the id of the scheduled email for CancelEmail
+func (s *Scheduler) ScheduleEmail(message *Message, sendTime time.Time) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return "", errors.New("the scheduler is stopped")
 	}
+	if s.pending == nil {
+		s.pending = make(map[string]*scheduledEmail)
+	}
+
+	id := newMessageID()
+	email := &scheduledEmail{ScheduledEmail: ScheduledEmail{ID: id, Message: message, SendAt: sendTime}}
+	email.timer = time.AfterFunc(time.Until(sendTime), func() { s.fire(id) })
+	s.pending[id] = email
 	return id, nil
 }
 
-// Start starts the scheduler
-func (s *Scheduler) Start() {
-	go func() {
-		for msg := range s.Queue {
-			if err := s.Transport.Send(msg); err != nil {
-				log.Printf("Failed to send scheduled email to %v: %v", msg.To, err)
-			} else {
-				log.Printf("Scheduled email sent successfully to %v", msg.To)
-			}
-		}
-	}()
-	//s.C.Start()
+// CancelEmail cancels a pending scheduled email, it returns ErrNotScheduled when it was sent
+// or cancelled already
+func (s *Scheduler) CancelEmail(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	email, ok := s.pending[id]
+	if !ok || !email.timer.Stop() {
+		return ErrNotScheduled
+	}
+	delete(s.pending, id)
+	return nil
 }
 
-// Stop stops the scheduler
+// Pending returns the scheduled emails not sent yet, the earliest first
+func (s *Scheduler) Pending() []ScheduledEmail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	emails := make([]ScheduledEmail, 0, len(s.pending))
+	for _, email := range s.pending {
+		emails = append(emails, email.ScheduledEmail)
+	}
+	sort.Slice(emails, func(i, j int) bool {
+		return emails[i].SendAt.Before(emails[j].SendAt)
+	})
+	return emails
+}
+
+// Start is kept for compatibility, the emails are sent by their timers
+func (s *Scheduler) Start() {}
+
+// Stop stops the scheduler, the pending emails are dropped
 func (s *Scheduler) Stop() {
-	s.C.Stop()
-	close(s.Queue)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	for id, email := range s.pending {
+		email.timer.Stop()
+		delete(s.pending, id)
+	}
+}
+
+// fire sends the scheduled email with the id
+func (s *Scheduler) fire(id string) {
+	s.mu.Lock()
+	email, ok := s.pending[id]
+	delete(s.pending, id)
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	if err := s.Send(context.Background(), email.Message); err != nil {
+		errorLogger().Printf("Failed to send scheduled email %s to %v: %v", id, email.Message.To, err)
+	}
 }
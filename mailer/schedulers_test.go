@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleEmailSendsOnce(t *testing.T) {
+	transport := &stubTransport{}
+	m, _ := newQueuedMailer(t, transport)
+
+	id, err := m.ScheduleEmail(testMessage(), time.Now().Add(30*time.Millisecond))
+	require.NoError(t, err)
+	pending := m.ScheduledEmails()
+	require.Len(t, pending, 1)
+	assert.Equal(t, id, pending[0].ID)
+	assert.Equal(t, 0, transport.sentCount())
+
+	assert.Eventually(t, func() bool { return transport.sentCount() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Empty(t, m.ScheduledEmails())
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, transport.sentCount())
+}
+
+func TestCancelScheduledEmail(t *testing.T) {
+	transport := &stubTransport{}
+	s := NewScheduler(transport)
+	later, err := s.ScheduleEmail(testMessage(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	soon, err := s.ScheduleEmail(testMessage(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	pending := s.Pending()
+	require.Len(t, pending, 2)
+	assert.Equal(t, soon, pending[0].ID)
+	assert.Equal(t, later, pending[1].ID)
+
+	require.NoError(t, s.CancelEmail(later))
+	assert.True(t, errors.Is(s.CancelEmail(later), ErrNotScheduled))
+	assert.Len(t, s.Pending(), 1)
+
+	s.Stop()
+	assert.Empty(t, s.Pending())
+	_, err = s.ScheduleEmail(testMessage(), time.Now())
+	assert.Error(t, err)
+	assert.Equal(t, 0, transport.sentCount())
+}
@@ -0,0 +1,163 @@
+package mailer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// SendGridTransport sends the messages with the v3 mail send API of SendGrid
+type SendGridTransport struct {
+	config   *Config
+	endpoint string
+	client   *http.Client
+}
+
+// sendGridAddress is an address of the SendGrid API
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+// sendGridMessage is the body of the mail send API
+type sendGridMessage struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	ReplyTo          *sendGridAddress          `json:"reply_to,omitempty"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+	Attachments      []sendGridAttachment      `json:"attachments,omitempty"`
+	Headers          map[string]string         `json:"headers,omitempty"`
+	CustomArgs       map[string]string         `json:"custom_args,omitempty"`
+	MailSettings     *sendGridMailSettings     `json:"mail_settings,omitempty"`
+}
+
+type sendGridPersonalization struct {
+	To  []sendGridAddress `json:"to"`
+	Cc  []sendGridAddress `json:"cc,omitempty"`
+	Bcc []sendGridAddress `json:"bcc,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridAttachment struct {
+	Content     string `json:"content"`
+	Type        string `json:"type"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition"`
+	ContentID   string `json:"content_id,omitempty"`
+}
+
+type sendGridMailSettings struct {
+	SandboxMode struct {
+		Enable bool `json:"enable"`
+	} `json:"sandbox_mode"`
+}
+
+// NewSendGridTransport creates a SendGrid transport, config.APIKey is the API key. In
+// sandbox mode SendGrid validates the messages and does not deliver them.
+func NewSendGridTransport(config *Config) (*SendGridTransport, error) {
+	if config.APIKey == "" {
+		return nil, errors.New("the sendgrid driver needs MAIL_API_KEY")
+	}
+	return &SendGridTransport{
+		config:   config,
+		endpoint: apiEndpoint(config, "https://api.sendgrid.com") + "/v3/mail/send",
+		client:   apiClient(config),
+	}, nil
+}
+
+// Send sends a single email message
+func (t *SendGridTransport) Send(ctx context.Context, m *Message) error {
+	from := fromOf(m, t.config)
+	payload := sendGridMessage{
+		Personalizations: []sendGridPersonalization{{
+			To:  sendGridAddresses(m.To),
+			Cc:  sendGridAddresses(m.Cc),
+			Bcc: sendGridAddresses(m.Bcc),
+		}},
+		From:       sendGridAddress{Email: from.Address, Name: from.Name},
+		Subject:    m.Subject,
+		Headers:    m.Headers,
+		CustomArgs: m.Metadata,
+	}
+	if m.ReplyTo.Address != "" {
+		payload.ReplyTo = &sendGridAddress{Email: m.ReplyTo.Address, Name: m.ReplyTo.Name}
+	}
+	// SendGrid wants the plain text first
+	if m.Body != "" {
+		payload.Content = append(payload.Content, sendGridContent{Type: "text/plain", Value: m.Body})
+	}
+	if m.HTMLBody != "" {
+		payload.Content = append(payload.Content, sendGridContent{Type: "text/html", Value: m.HTMLBody})
+	}
+	for _, attachment := range m.Attachments {
+		a := sendGridAttachment{
+			Content:     base64.StdEncoding.EncodeToString(attachment.Data),
+			Type:        attachment.MimeType,
+			Filename:    attachment.Name,
+			Disposition: "attachment",
+		}
+		if attachment.Inline {
+			a.Disposition = "inline"
+			a.ContentID = attachment.Name
+		}
+		payload.Attachments = append(payload.Attachments, a)
+	}
+	if t.config.Sandbox {
+		payload.MailSettings = &sendGridMailSettings{}
+		payload.MailSettings.SandboxMode.Enable = true
+	}
+
+	req, err := jsonRequest(ctx, t.endpoint, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.config.APIKey)
+	return doAPIRequest(t.client, "sendgrid", req, sendGridError)
+}
+
+// SendMultiple sends multiple email messages, one request each
+func (t *SendGridTransport) SendMultiple(ctx context.Context, emails []*Message) error {
+	return sendEach(ctx, t, emails)
+}
+
+// sendGridAddresses converts addresses to the ones of the API
+func sendGridAddresses(addresses []EmailAddress) []sendGridAddress {
+	if len(addresses) == 0 {
+		return nil
+	}
+	converted := make([]sendGridAddress, len(addresses))
+	for i, a := range addresses {
+		converted[i] = sendGridAddress{Email: a.Address, Name: a.Name}
+	}
+	return converted
+}
+
+// sendGridError reads the errors of a response, {"errors": [{"message", "field"}]}
+func sendGridError(_ *http.Response, body []byte) *APIError {
+	var response struct {
+		Errors []struct {
+			Message string `json:"message"`
+			Field   string `json:"field"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &response) != nil || len(response.Errors) == 0 {
+		return &APIError{}
+	}
+
+	messages := make([]string, len(response.Errors))
+	for i, e := range response.Errors {
+		messages[i] = e.Message
+		if e.Field != "" {
+			messages[i] = e.Field + ": " + e.Message
+		}
+	}
+	return &APIError{Message: strings.Join(messages, "; ")}
+}
@@ -0,0 +1,218 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/toorop/go-dkim"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sesSimulator is the mailbox simulator of SES, it accepts the messages without delivering
+// them anywhere
+const sesSimulator = "success@simulator.amazonses.com"
+
+// SESTransport sends the messages with the v2 API of Amazon SES, signed with AWS Signature
+// Version 4
+type SESTransport struct {
+	config   *Config
+	region   string
+	endpoint string
+	client   *http.Client
+	dkim     *dkim.SigOptions
+}
+
+// sesMessage is the body of the SendEmail API, the message is sent raw so that the
+// attachments and headers are kept
+type sesMessage struct {
+	FromEmailAddress string         `json:"FromEmailAddress"`
+	Destination      sesDestination `json:"Destination"`
+	Content          struct {
+		Raw struct {
+			Data string `json:"Data"`
+		} `json:"Raw"`
+	} `json:"Content"`
+	EmailTags []sesTag `json:"EmailTags,omitempty"`
+}
+
+type sesDestination struct {
+	ToAddresses  []string `json:"ToAddresses,omitempty"`
+	CcAddresses  []string `json:"CcAddresses,omitempty"`
+	BccAddresses []string `json:"BccAddresses,omitempty"`
+}
+
+type sesTag struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+// NewSESTransport creates an SES transport, config.APIKey and config.APISecret are the
+// access key of an IAM user allowed ses:SendEmail, config.Region its region, us-east-1 when
+// empty. SES has no test mode, in sandbox mode the messages go to its mailbox simulator
+// instead of the recipients. The messages are signed with the DKIM settings of config, if
+// any.
+func NewSESTransport(config *Config) (*SESTransport, error) {
+	if config.APIKey == "" || config.APISecret == "" {
+		return nil, errors.New("the ses driver needs MAIL_API_KEY and MAIL_API_SECRET")
+	}
+	signing, err := dkimOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	region := config.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &SESTransport{
+		config:   config,
+		region:   region,
+		endpoint: apiEndpoint(config, "https://email."+region+".amazonaws.com") + "/v2/email/outbound-emails",
+		client:   apiClient(config),
+		dkim:     signing,
+	}, nil
+}
+
+// Send sends a single email message
+func (t *SESTransport) Send(ctx context.Context, m *Message) error {
+	message := *m
+	message.From = fromOf(m, t.config)
+	email := newSimpleMail(&message)
+	signDKIM(email, t.dkim, message.From)
+	if email.Error != nil {
+		return email.Error
+	}
+	payload := sesMessage{
+		FromEmailAddress: formatAddress(message.From),
+		Destination: sesDestination{
+			ToAddresses:  sesAddresses(m.To),
+			CcAddresses:  sesAddresses(m.Cc),
+			BccAddresses: sesAddresses(m.Bcc),
+		},
+	}
+	if t.config.Sandbox {
+		payload.Destination = sesDestination{ToAddresses: []string{sesSimulator}}
+	}
+	payload.Content.Raw.Data = base64.StdEncoding.EncodeToString([]byte(rawMessage(email)))
+	for name, value := range m.Metadata {
+		payload.EmailTags = append(payload.EmailTags, sesTag{Name: name, Value: value})
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	t.sign(req, body, time.Now().UTC())
+	return doAPIRequest(t.client, "ses", req, sesError)
+}
+
+// SendMultiple sends multiple email messages, one request each
+func (t *SESTransport) SendMultiple(ctx context.Context, emails []*Message) error {
+	return sendEach(ctx, t, emails)
+}
+
+// sign adds the AWS Signature Version 4 of the request to its headers
+func (t *SESTransport) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	scope := day + "/" + t.region + "/ses/aws4_request"
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	headers := map[string]string{
+		"content-type": req.Header.Get("Content-Type"),
+		"host":         req.URL.Host,
+		"x-amz-date":   amzDate,
+	}
+	names := []string{"content-type", "host", "x-amz-date"}
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+t.config.APISecret), day)
+	key = hmacSHA256(key, t.region)
+	key = hmacSHA256(key, "ses")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		t.config.APIKey, scope, signedHeaders, signature))
+}
+
+// sesAddresses returns the addresses in the form of a header
+func sesAddresses(addresses []EmailAddress) []string {
+	if len(addresses) == 0 {
+		return nil
+	}
+	formatted := make([]string, len(addresses))
+	for i, a := range addresses {
+		formatted[i] = formatAddress(a)
+	}
+	return formatted
+}
+
+// sesError reads the error of a response, its type is in the X-Amzn-ErrorType header as
+// MessageRejected:http://internal.amazon.com/... and the message in the body
+func sesError(resp *http.Response, body []byte) *APIError {
+	// the field is message or Message depending on the error, json matches both
+	var response struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(body, &response)
+	e := &APIError{Message: response.Message}
+
+	code, _, _ := strings.Cut(resp.Header.Get("X-Amzn-ErrorType"), ":")
+	e.Code = code
+	switch code {
+	case "MessageRejected", "BadRequestException", "NotFoundException":
+		e.Err = ErrRejected
+	case "MailFromDomainNotVerifiedException", "AccountSuspendedException", "SendingPausedException",
+		"AccessDeniedException", "UnrecognizedClientException", "InvalidSignatureException",
+		"SignatureDoesNotMatch", "ExpiredTokenException":
+		e.Err = ErrUnauthorized
+	case "TooManyRequestsException", "ThrottlingException", "LimitExceededException":
+		e.Err = ErrRateLimited
+	}
+	return e
+}
+
+// sha256Hex returns the hex encoded sha256 of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
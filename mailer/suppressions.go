@@ -0,0 +1,179 @@
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/haskekareem/sauri/cache"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the types of the bounces
+const (
+	BounceHard = "hard"      // the address does not exist, it is suppressed
+	BounceSoft = "soft"      // the mailbox was full or the server down, it is not
+	Complaint  = "complaint" // the recipient marked the email as spam, it is suppressed
+)
+
+// ErrSuppressed is returned when every recipient of an email is on the suppression list
+var ErrSuppressed = errors.New("every recipient of the email is suppressed")
+
+// Bounce is a bounce or a complaint reported by the webhook of a mail API
+type Bounce struct {
+	Address   string    `json:"address"`
+	Type      string    `json:"type"` // BounceHard, BounceSoft or Complaint
+	Provider  string    `json:"provider"`
+	Reason    string    `json:"reason,omitempty"`
+	MessageID string    `json:"message_id,omitempty"` // the id of the email at the provider
+	At        time.Time `json:"at"`
+}
+
+// Suppresses reports whether the address should not get emails anymore
+func (b Bounce) Suppresses() bool {
+	return b.Type == BounceHard || b.Type == Complaint
+}
+
+// SuppressionList keeps the addresses the emails are not sent to anymore, a list of the
+// database of the application only has to implement it
+type SuppressionList interface {
+	Suppress(ctx context.Context, bounce Bounce) error
+	// Suppressed returns the bounce which suppressed the address, nil when it is not
+	Suppressed(ctx context.Context, address string) (*Bounce, error)
+	Unsuppress(ctx context.Context, address string) error
+}
+
+// MemorySuppressionList keeps the suppressed addresses in memory, they are lost on restart
+type MemorySuppressionList struct {
+	mu        sync.RWMutex
+	addresses map[string]Bounce
+}
+
+// NewMemorySuppressionList creates an empty memory list
+func NewMemorySuppressionList() *MemorySuppressionList {
+	return &MemorySuppressionList{addresses: make(map[string]Bounce)}
+}
+
+// Suppress adds the address of the bounce to the list
+func (l *MemorySuppressionList) Suppress(_ context.Context, bounce Bounce) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.addresses[normalizeAddress(bounce.Address)] = bounce
+	return nil
+}
+
+// Suppressed returns the bounce which suppressed the address, nil when it is not
+func (l *MemorySuppressionList) Suppressed(_ context.Context, address string) (*Bounce, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if bounce, ok := l.addresses[normalizeAddress(address)]; ok {
+		return &bounce, nil
+	}
+	return nil, nil
+}
+
+// Unsuppress removes the address from the list
+func (l *MemorySuppressionList) Unsuppress(_ context.Context, address string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.addresses, normalizeAddress(address))
+	return nil
+}
+
+// CacheSuppressionList keeps the suppressed addresses under the keys of a cache, e.g. the
+// Redis cache of the application
+type CacheSuppressionList struct {
+	Cache  cache.Cache
+	Prefix string // the prefix of the keys, mail-suppressed: when empty
+}
+
+// NewCacheSuppressionList creates a list keeping the addresses in c
+func NewCacheSuppressionList(c cache.Cache) *CacheSuppressionList {
+	return &CacheSuppressionList{Cache: c}
+}
+
+// Suppress adds the address of the bounce to the list
+func (l *CacheSuppressionList) Suppress(_ context.Context, bounce Bounce) error {
+	encoded, err := json.Marshal(bounce)
+	if err != nil {
+		return err
+	}
+	return l.Cache.Set(l.key(bounce.Address), string(encoded))
+}
+
+// Suppressed returns the bounce which suppressed the address, nil when it is not
+func (l *CacheSuppressionList) Suppressed(_ context.Context, address string) (*Bounce, error) {
+	// the badger cache fails to get a missing key
+	exists, err := l.Cache.Exists(l.key(address))
+	if err != nil || !exists {
+		return nil, err
+	}
+	value, err := l.Cache.Get(l.key(address))
+	if err != nil || value == nil {
+		return nil, err
+	}
+	encoded, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("the suppression of %s is a %T, not JSON", address, value)
+	}
+
+	var bounce Bounce
+	if err := json.Unmarshal([]byte(encoded), &bounce); err != nil {
+		return nil, err
+	}
+	return &bounce, nil
+}
+
+// Unsuppress removes the address from the list
+func (l *CacheSuppressionList) Unsuppress(_ context.Context, address string) error {
+	return l.Cache.Delete(l.key(address))
+}
+
+func (l *CacheSuppressionList) key(address string) string {
+	prefix := l.Prefix
+	if prefix == "" {
+		prefix = "mail-suppressed:"
+	}
+	return prefix + normalizeAddress(address)
+}
+
+// normalizeAddress returns the address compared with the suppressed ones
+func normalizeAddress(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
+// withoutSuppressed returns the message without its suppressed recipients, it returns
+// ErrSuppressed when none is left
+func (m *Mailer) withoutSuppressed(ctx context.Context, message *Message) (*Message, error) {
+	if m.Suppressions == nil {
+		return message, nil
+	}
+
+	filtered := *message
+	var err error
+	keep := func(addresses []EmailAddress) []EmailAddress {
+		var kept []EmailAddress
+		for _, address := range addresses {
+			bounce, suppressedErr := m.Suppressions.Suppressed(ctx, address.Address)
+			if suppressedErr != nil {
+				err = suppressedErr
+			}
+			if bounce == nil {
+				kept = append(kept, address)
+			}
+		}
+		return kept
+	}
+	filtered.To = keep(message.To)
+	filtered.Cc = keep(message.Cc)
+	filtered.Bcc = keep(message.Bcc)
+	if err != nil {
+		return nil, fmt.Errorf("cannot check the suppression list: %w", err)
+	}
+	if len(filtered.To)+len(filtered.Cc)+len(filtered.Bcc) == 0 {
+		return nil, ErrSuppressed
+	}
+	return &filtered, nil
+}
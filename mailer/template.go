@@ -2,27 +2,116 @@ package mailer
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"github.com/vanng822/go-premailer/premailer"
+	"golang.org/x/net/html"
 	htmlTemplate "html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strings"
 	textTemplate "text/template"
 )
 
+// htmlExtensions and plainExtensions are the extensions of the files searched for the HTML
+// and plain text templates of an email, in order
+var (
+	htmlExtensions  = []string{".html.gohtml", ".html.tmpl"}
+	plainExtensions = []string{".plain.gohtml", ".plain.tmpl", ".txt.tmpl"}
+)
+
+// sharedFolders are the folders of the templates parsed with every email, the layouts and
+// partials with the extension of the email
+var sharedFolders = []string{"layouts", "partials"}
+
+// TemplateNotFoundError is returned when none of the files of an email template exists
+type TemplateNotFoundError struct {
+	Name     string
+	Searched []string
+}
+
+func (e *TemplateNotFoundError) Error() string {
+	return fmt.Sprintf("email template %s not found, searched %s", e.Name, strings.Join(e.Searched, ", "))
+}
+
+// Is makes errors.Is(err, fs.ErrNotExist) true
+func (e *TemplateNotFoundError) Is(target error) bool {
+	return target == fs.ErrNotExist
+}
+
+// AddFuncs adds functions to the email templates
+func (m *Mailer) AddFuncs(funcs map[string]any) {
+	if m.funcs == nil {
+		m.funcs = make(map[string]any)
+	}
+	for name, fn := range funcs {
+		m.funcs[name] = fn
+	}
+}
+
+// templates returns the file system of the email templates
+func (m *Mailer) templates() fs.FS {
+	if m.Config.Templates != nil {
+		return m.Config.Templates
+	}
+	return os.DirFS(m.Config.TemplatesDir)
+}
+
+// renderTemplate renders the HTML and plain text bodies of the template name, the HTML of
+// its Markdown template when it has no HTML one. The plain text is made from the HTML when
+// there is no plain template.
+func (m *Mailer) renderTemplate(name string, data interface{}) (string, string, error) {
+	var text string // the plain text of a Markdown template
+	var missing *TemplateNotFoundError
+	htmlBody, err := m.buildHTMLMessage(name, data)
+	if errors.As(err, &missing) {
+		searched := missing.Searched
+		htmlBody, text, err = m.buildMarkdownMessage(name, data)
+		if errors.As(err, &missing) {
+			missing.Searched = append(searched, missing.Searched...)
+		}
+	}
+	if err != nil && !errors.As(err, &missing) {
+		return "", "", err
+	}
+
+	plainBody, plainErr := m.buildPlainTextMessage(name, data)
+	var missingPlain *TemplateNotFoundError
+	switch {
+	case plainErr == nil:
+	case !errors.As(plainErr, &missingPlain):
+		return "", "", plainErr
+	case err != nil:
+		return "", "", &TemplateNotFoundError{Name: name, Searched: append(missing.Searched, missingPlain.Searched...)}
+	case text != "":
+		plainBody = text
+	default:
+		plainBody = htmlToText(htmlBody)
+	}
+	return htmlBody, plainBody, nil
+}
+
 // buildHTMLMessage creates the HTML version of the message
 func (m *Mailer) buildHTMLMessage(templateName string, data interface{}) (string, error) {
-	templateToRender := fmt.Sprintf("%s/%s.html.gohtml", m.Config.TemplatesDir, templateName)
-
-	t, err := htmlTemplate.New("email-html").ParseFiles(templateToRender)
+	fsys := m.templates()
+	files, err := templateFiles(fsys, templateName, htmlExtensions)
 	if err != nil {
 		return "", err
 	}
 
-	var tpl bytes.Buffer
-	if err = t.ExecuteTemplate(&tpl, "body", data); err != nil {
+	main := path.Base(files[len(files)-1])
+	t, err := htmlTemplate.New(main).Funcs(m.funcs).ParseFS(fsys, files...)
+	if err != nil {
+		return "", err
+	}
+	formattedMessage, err := executeTemplate(t, main, t.Lookup("body") != nil, data)
+	if err != nil {
 		return "", err
 	}
 
-	formattedMessage := tpl.String()
 	formattedMessage, err = m.inlineCSS(formattedMessage)
 	if err != nil {
 		return "", err
@@ -33,18 +122,78 @@ func (m *Mailer) buildHTMLMessage(templateName string, data interface{}) (string
 
 // buildPlainTextMessage creates the plain text version of the message
 func (m *Mailer) buildPlainTextMessage(templateName string, data interface{}) (string, error) {
-	templateToRender := fmt.Sprintf("%s/%s.plain.gohtml", m.Config.TemplatesDir, templateName)
+	fsys := m.templates()
+	files, err := templateFiles(fsys, templateName, plainExtensions)
+	if err != nil {
+		return "", err
+	}
 
-	t, err := textTemplate.New("email-plain").ParseFiles(templateToRender)
+	main := path.Base(files[len(files)-1])
+	t, err := textTemplate.New(main).Funcs(m.funcs).ParseFS(fsys, files...)
 	if err != nil {
 		return "", err
 	}
+	return executeTemplate(t, main, t.Lookup("body") != nil, data)
+}
+
+// templateFiles returns the layouts and partials with one of the extensions followed by the
+// first file of the template name found
+func templateFiles(fsys fs.FS, name string, extensions []string) ([]string, error) {
+	var file string
+	searched := make([]string, 0, len(extensions))
+	for _, extension := range extensions {
+		candidate := name + extension
+		searched = append(searched, candidate)
+		if _, err := fs.Stat(fsys, candidate); err == nil {
+			file = candidate
+			break
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	if file == "" {
+		return nil, &TemplateNotFoundError{Name: name, Searched: searched}
+	}
+
+	files, err := sharedFiles(fsys, extensions)
+	if err != nil {
+		return nil, err
+	}
+	return append(files, file), nil
+}
+
+// sharedFiles returns the layouts and partials with one of the extensions
+func sharedFiles(fsys fs.FS, extensions []string) ([]string, error) {
+	var files []string
+	for _, folder := range sharedFolders {
+		for _, extension := range extensions {
+			matches, err := fs.Glob(fsys, folder+"/*"+extension)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, matches...)
+		}
+	}
+	return files, nil
+}
 
+// executeTemplate executes the template of the file of an email, a template using a layout
+// like {{template "layout" .}}, or its "body" block when the file has nothing else
+func executeTemplate(t interface {
+	ExecuteTemplate(w io.Writer, name string, data any) error
+}, main string, hasBody bool, data any) (string, error) {
 	var tpl bytes.Buffer
-	if err = t.ExecuteTemplate(&tpl, "body", data); err != nil {
+	if err := t.ExecuteTemplate(&tpl, main, data); err != nil {
 		return "", err
 	}
+	if strings.TrimSpace(tpl.String()) != "" || !hasBody {
+		return tpl.String(), nil
+	}
 
+	tpl.Reset()
+	if err := t.ExecuteTemplate(&tpl, "body", data); err != nil {
+		return "", err
+	}
 	return tpl.String(), nil
 }
 
@@ -68,3 +217,73 @@ func (m *Mailer) inlineCSS(s string) (string, error) {
 
 	return html, nil
 }
+
+// textBlocks are the elements separated from the rest of the text by blank lines
+var textBlocks = map[string]bool{
+	"p": true, "div": true, "table": true, "ul": true, "ol": true, "blockquote": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// blankLines matches the runs of blank lines of the text made from an HTML body
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// htmlToText returns the text of an HTML body for its plain text alternative: the blocks are
+// separated by blank lines, the items of the lists start with a dash and the links are
+// followed by their URL
+func htmlToText(s string) string {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return s
+	}
+
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			// the spaces are collapsed with the ones of the lines
+			b.WriteString(strings.ReplaceAll(n.Data, "\n", " "))
+			return
+		}
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "head", "style", "script", "title":
+				return
+			case "br":
+				b.WriteString("\n")
+			case "li":
+				b.WriteString("\n- ")
+			case "tr":
+				b.WriteString("\n")
+			case "hr":
+				b.WriteString("\n\n")
+			default:
+				if textBlocks[n.Data] {
+					b.WriteString("\n\n")
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type != html.ElementNode {
+			return
+		}
+		if textBlocks[n.Data] {
+			b.WriteString("\n\n")
+		}
+		if n.Data == "a" {
+			for _, attr := range n.Attr {
+				if attr.Key == "href" && strings.HasPrefix(attr.Val, "http") {
+					fmt.Fprintf(&b, " (%s)", attr.Val)
+				}
+			}
+		}
+	}
+	walk(doc)
+
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,86 @@
+package mailer
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fsMailer(files fstest.MapFS) *Mailer {
+	return &Mailer{Config: &Config{Templates: files, TemplatesDir: "unused"}}
+}
+
+func TestTemplatesWithLayout(t *testing.T) {
+	m := fsMailer(fstest.MapFS{
+		"layouts/base.html.gohtml":    {Data: []byte(`{{define "layout"}}<html><body>{{template "body" .}}{{template "footer" .}}</body></html>{{end}}`)},
+		"partials/footer.html.gohtml": {Data: []byte(`{{define "footer"}}<p>{{shout "bye"}}</p>{{end}}`)},
+		"layouts/base.plain.gohtml":   {Data: []byte(`{{define "layout"}}{{template "body" .}} -- the team{{end}}`)},
+		"auth/welcome.html.gohtml":    {Data: []byte(`{{template "layout" .}}{{define "body"}}<h1>Hi {{.}}</h1>{{end}}`)},
+		"auth/welcome.plain.tmpl":     {Data: []byte(`{{template "layout" .}}{{define "body"}}Hi {{.}}{{end}}`)},
+	})
+	m.AddFuncs(map[string]any{"shout": strings.ToUpper})
+
+	html, plain, err := m.renderTemplate("auth/welcome", "Ada")
+	require.NoError(t, err)
+	assert.Contains(t, html, "<body><h1>Hi Ada</h1><p>BYE</p></body>")
+	assert.Equal(t, "Hi Ada -- the team", plain)
+}
+
+func TestTemplatesBodyBlock(t *testing.T) {
+	m := fsMailer(fstest.MapFS{
+		"reset.plain.gohtml": {Data: []byte("\n{{define \"body\"}}code {{.}}{{end}}\n")},
+	})
+
+	html, plain, err := m.renderTemplate("reset", 42)
+	require.NoError(t, err)
+	assert.Empty(t, html)
+	assert.Equal(t, "code 42", plain)
+}
+
+func TestTemplateNotFound(t *testing.T) {
+	m := fsMailer(fstest.MapFS{})
+
+	_, err := m.buildHTMLMessage("invoice", nil)
+	var missing *TemplateNotFoundError
+	require.True(t, errors.As(err, &missing))
+	assert.Equal(t, []string{"invoice.html.gohtml", "invoice.html.tmpl"}, missing.Searched)
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+
+	_, _, err = m.renderTemplate("invoice", nil)
+	assert.EqualError(t, err, "email template invoice not found, searched invoice.html.gohtml, invoice.html.tmpl, invoice.md.gohtml, invoice.md.tmpl, invoice.md, invoice.plain.gohtml, invoice.plain.tmpl, invoice.txt.tmpl")
+}
+
+func TestMarkdownTemplate(t *testing.T) {
+	m := fsMailer(fstest.MapFS{
+		"invoice.md.gohtml": {Data: []byte("# Invoice {{.Number}}\n\nHello **{{.Name}}**, see [your account](https://example.com/account).\n\n| Item | Price |\n| --- | --- |\n| Plan | 10 |\n")},
+	})
+
+	html, plain, err := m.renderTemplate("invoice", map[string]any{"Number": 7, "Name": "<b>Ada</b>"})
+	require.NoError(t, err)
+	assert.Contains(t, html, "<h1")
+	assert.Contains(t, html, "Invoice 7</h1>")
+	assert.Contains(t, html, `<div class="content"`)
+	// the raw HTML of the data is left out and the styles are inlined
+	assert.NotContains(t, html, "<b>Ada</b>")
+	assert.Regexp(t, `<td style="[^"]*border:1px solid #e5e7eb`, html)
+	assert.Contains(t, plain, "Invoice 7\n\nHello Ada, see your account (https://example.com/account).")
+	assert.NotContains(t, plain, "border")
+}
+
+func TestMarkdownLayout(t *testing.T) {
+	m := fsMailer(fstest.MapFS{
+		"layouts/markdown.html.gohtml": {Data: []byte(`{{define "markdown"}}<main>{{.Content}}</main><footer>{{.Data}}</footer>{{end}}`)},
+		"note.md":                      {Data: []byte("Hi *{{.}}*")},
+		"note.plain.gohtml":            {Data: []byte("plain {{.}}")},
+	})
+
+	html, plain, err := m.renderTemplate("note", "Ada")
+	require.NoError(t, err)
+	assert.Contains(t, html, "<main><p>Hi <em>Ada</em></p>\n</main><footer>Ada</footer>")
+	assert.Equal(t, "plain Ada", plain)
+}
@@ -1,42 +1,212 @@
 package mailer
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"github.com/go-chi/chi/v5"
+	"html"
+	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 )
 
-// Tracker tracks email opens and clicks
+// the types of the tracking events
+const (
+	EventOpen  = "open"
+	EventClick = "click"
+)
+
+// TrackingEvent is an open or a click of a tracked email
+type TrackingEvent struct {
+	MessageID string    `json:"message_id"`
+	Type      string    `json:"type"`          // EventOpen or EventClick
+	URL       string    `json:"url,omitempty"` // the link clicked
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// pixel is a transparent 1x1 GIF, the image of the open tracking
+var pixel, _ = base64.StdEncoding.DecodeString("R0lGODlhAQABAIAAAAAAAP///yH5BAEAAAAALAAAAAABAAEAAAIBRAA7")
+
+// trackedLink matches the http links of an HTML body
+var trackedLink = regexp.MustCompile(`(?i)(<a\s[^>]*?href\s*=\s*")(https?://[^"]+)(")`)
+
+// Tracker tracks the opens and clicks of the emails: Track rewrites the links of an email to
+// go through the click route and adds an image loaded from the open route, the routes of
+// Handler record the events in the store and post them to the webhooks
 type Tracker struct {
-	BaseURL string
+	BaseURL  string        // the URL Handler is mounted on, e.g. https://example.com/email
+	Key      []byte        // signs the links so that the click route redirects to them only
+	Store    TrackingStore // keeps the events, NewTracker keeps them in memory by default
+	Webhooks []string      // the URLs receiving each event as a JSON POST, signed with Key
+	Client   *http.Client  // the client of the webhooks, with a 10 seconds timeout when nil
+}
+
+// NewTracker creates a tracker of the emails linking to baseURL, the routes of Handler, the
+// links are signed with key
+func NewTracker(baseURL string, key []byte, store TrackingStore) *Tracker {
+	if store == nil {
+		store = NewMemoryTrackingStore()
+	}
+	return &Tracker{BaseURL: strings.TrimSuffix(baseURL, "/"), Key: key, Store: store}
 }
 
-// NewTracker creates a new Tracker
-func NewTracker(baseURL string) *Tracker {
-	return &Tracker{BaseURL: baseURL}
+// Track gives the message an ID, when it has none, and rewrites its HTML body for the
+// tracking, the plain text body is left as is. A message tracked already is left as is.
+func (t *Tracker) Track(m *Message) {
+	if m.ID == "" {
+		m.ID = newMessageID()
+	}
+	if m.HTMLBody == "" || strings.Contains(m.HTMLBody, html.EscapeString(t.TrackOpen(m.ID))) {
+		return
+	}
+
+	body := trackedLink.ReplaceAllStringFunc(m.HTMLBody, func(link string) string {
+		parts := trackedLink.FindStringSubmatch(link)
+		target := html.UnescapeString(parts[2])
+		return parts[1] + html.EscapeString(t.TrackClick(m.ID, target)) + parts[3]
+	})
+
+	image := `<img src="` + html.EscapeString(t.TrackOpen(m.ID)) + `" width="1" height="1" alt="" style="display:none">`
+	if i := strings.LastIndex(strings.ToLower(body), "</body>"); i >= 0 {
+		body = body[:i] + image + body[i:]
+	} else {
+		body += image
+	}
+	m.HTMLBody = body
 }
 
 // TrackOpen generates a URL for tracking email opens
 func (t *Tracker) TrackOpen(emailID string) string {
-	return fmt.Sprintf("%s/open/%s", t.BaseURL, emailID)
+	return fmt.Sprintf("%s/open/%s", t.BaseURL, url.PathEscape(emailID))
+}
+
+// TrackClick generates a URL for tracking email clicks, it redirects to target
+func (t *Tracker) TrackClick(emailID, target string) string {
+	query := url.Values{"url": {target}, "sig": {t.sign(emailID, target)}}
+	return fmt.Sprintf("%s/click/%s?%s", t.BaseURL, url.PathEscape(emailID), query.Encode())
+}
+
+// Handler returns the open and click routes, /open/{id} and /click/{id}, to be mounted on
+// the BaseURL path
+func (t *Tracker) Handler() http.Handler {
+	mux := chi.NewRouter()
+	mux.Get("/open/{id}", t.HandleOpen)
+	mux.Get("/click/{id}", t.HandleClick)
+	return mux
 }
 
-// TrackClick generates a URL for tracking email clicks
-func (t *Tracker) TrackClick(emailID, url string) string {
-	return fmt.Sprintf("%s/click/%s?url=%s", t.BaseURL, emailID, url)
+// Events returns the events of an email, the oldest first
+func (t *Tracker) Events(ctx context.Context, messageID string) ([]TrackingEvent, error) {
+	return t.Store.Events(ctx, messageID)
 }
 
 // HandleOpen handles email open tracking
 func (t *Tracker) HandleOpen(w http.ResponseWriter, r *http.Request) {
-	emailID := r.URL.Path[len("/open/"):]
-	fmt.Printf("Email %s opened at %v\n", emailID, time.Now())
-	http.ServeFile(w, r, "pixel.png")
+	t.record(r, TrackingEvent{MessageID: chi.URLParam(r, "id"), Type: EventOpen})
+
+	w.Header().Set("Content-Type", "image/gif")
+	w.Header().Set("Cache-Control", "no-store, max-age=0")
+	_, _ = w.Write(pixel)
 }
 
 // HandleClick handles email click tracking
 func (t *Tracker) HandleClick(w http.ResponseWriter, r *http.Request) {
-	emailID := r.URL.Path[len("/click/"):]
-	url := r.URL.Query().Get("url")
-	fmt.Printf("Email %s link clicked: %s at %v\n", emailID, url, time.Now())
-	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+	emailID := chi.URLParam(r, "id")
+	target := r.URL.Query().Get("url")
+	// a link not signed by the tracker is not followed, the route is no open redirect
+	if !hmac.Equal([]byte(r.URL.Query().Get("sig")), []byte(t.sign(emailID, target))) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	t.record(r, TrackingEvent{MessageID: emailID, Type: EventClick, URL: target})
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// record stores the event of the request and posts it to the webhooks, it is not kept
+// from the reader when it fails
+func (t *Tracker) record(r *http.Request, event TrackingEvent) {
+	event.At = time.Now().UTC()
+	event.UserAgent = r.UserAgent()
+	event.IP = r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		event.IP = host
+	}
+
+	if err := t.Store.Record(r.Context(), event); err != nil {
+		errorLogger().Printf("cannot record the %s of email %s: %v", event.Type, event.MessageID, err)
+	}
+	if len(t.Webhooks) > 0 {
+		go t.notify(event)
+	}
+}
+
+// notify posts the event to the webhooks, the X-Signature header is the hex HMAC-SHA256 of
+// the body with the key
+func (t *Tracker) notify(event TrackingEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	mac := hmac.New(sha256.New, t.Key)
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	client := t.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	for _, webhook := range t.Webhooks {
+		req, err := http.NewRequest(http.MethodPost, webhook, bytes.NewReader(body))
+		if err != nil {
+			errorLogger().Printf("invalid tracking webhook %s: %v", webhook, err)
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Signature", signature)
+		resp, err := client.Do(req)
+		if err != nil {
+			errorLogger().Printf("cannot post the %s of email %s to %s: %v", event.Type, event.MessageID, webhook, err)
+			continue
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			errorLogger().Printf("the tracking webhook %s answered %s", webhook, resp.Status)
+		}
+	}
+}
+
+// sign returns the signature of a link of an email
+func (t *Tracker) sign(emailID, target string) string {
+	mac := hmac.New(sha256.New, t.Key)
+	mac.Write([]byte(emailID + "\n" + target))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// newMessageID returns a random ID of a message
+func newMessageID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// errorLogger returns ErrorLogger, or the standard logger before the mail loggers are set
+func errorLogger() *log.Logger {
+	if ErrorLogger == nil {
+		return log.Default()
+	}
+	return ErrorLogger
 }
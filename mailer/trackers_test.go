@@ -0,0 +1,137 @@
+package mailer
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// trackingServer mounts the routes of a tracker on /email of a test server
+func trackingServer(t *testing.T, store TrackingStore) (*Tracker, *httptest.Server) {
+	t.Helper()
+	mux := chi.NewRouter()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	tracker := NewTracker(server.URL+"/email/", []byte("secret"), store)
+	mux.Mount("/email", tracker.Handler())
+	return tracker, server
+}
+
+// noRedirect is a client returning the redirects instead of following them
+var noRedirect = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}}
+
+func TestTrackerTrack(t *testing.T) {
+	tracker := NewTracker("https://example.com/email", []byte("secret"), nil)
+	m := &Message{HTMLBody: `<html><body><a href="https://example.com/docs?a=1&amp;b=2">docs</a> <a href="mailto:ada@example.com">mail</a></body></html>`}
+
+	tracker.Track(m)
+	require.Len(t, m.ID, 32)
+	assert.Contains(t, m.HTMLBody, `<a href="https://example.com/email/click/`+m.ID+`?sig=`)
+	assert.Contains(t, m.HTMLBody, `url=https%3A%2F%2Fexample.com%2Fdocs%3Fa%3D1%26b%3D2`)
+	assert.Contains(t, m.HTMLBody, `<a href="mailto:ada@example.com">`)
+	assert.Contains(t, m.HTMLBody, `<img src="https://example.com/email/open/`+m.ID+`" width="1" height="1" alt="" style="display:none"></body>`)
+
+	// tracking twice changes nothing
+	body := m.HTMLBody
+	tracker.Track(m)
+	assert.Equal(t, body, m.HTMLBody)
+}
+
+func TestTrackerRoutes(t *testing.T) {
+	store := NewMemoryTrackingStore()
+	tracker, _ := trackingServer(t, store)
+	m := &Message{ID: "m1", HTMLBody: `<a href="https://example.com/pricing">pricing</a>`}
+	tracker.Track(m)
+
+	resp, err := http.Get(tracker.TrackOpen("m1"))
+	require.NoError(t, err)
+	pixelBody, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, "image/gif", resp.Header.Get("Content-Type"))
+	assert.Equal(t, pixel, pixelBody)
+
+	link := regexp.MustCompile(`href="([^"]+)"`).FindStringSubmatch(m.HTMLBody)[1]
+	resp, err = noRedirect.Get(html.UnescapeString(link))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "https://example.com/pricing", resp.Header.Get("Location"))
+
+	events, err := tracker.Events(context.Background(), "m1")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, EventOpen, events[0].Type)
+	assert.Equal(t, "127.0.0.1", events[0].IP)
+	assert.Equal(t, EventClick, events[1].Type)
+	assert.Equal(t, "https://example.com/pricing", events[1].URL)
+}
+
+func TestTrackerRefusesUnsignedLinks(t *testing.T) {
+	tracker, _ := trackingServer(t, nil)
+
+	resp, err := noRedirect.Get(tracker.BaseURL + "/click/m1?url=https://evil.example.com&sig=forged")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	events, err := tracker.Events(context.Background(), "m1")
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestTrackerWebhooks(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	t.Cleanup(webhook.Close)
+
+	tracker, _ := trackingServer(t, nil)
+	tracker.Webhooks = []string{webhook.URL}
+	resp, err := http.Get(tracker.TrackOpen("m1"))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	select {
+	case r := <-received:
+		body := <-bodies
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+		assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), r.Header.Get("X-Signature"))
+		var event TrackingEvent
+		require.NoError(t, json.Unmarshal(body, &event))
+		assert.Equal(t, "m1", event.MessageID)
+		assert.Equal(t, EventOpen, event.Type)
+	case <-time.After(time.Second):
+		t.Fatal("the webhook was not called")
+	}
+}
+
+func TestMailerTracksEmails(t *testing.T) {
+	transport := &stubTransport{}
+	m, _ := newQueuedMailer(t, transport)
+	m.Tracker = NewTracker("https://example.com/email", []byte("secret"), nil)
+
+	require.NoError(t, m.QueueEmail(&Message{To: []EmailAddress{{"ada@example.com", ""}}, HTMLBody: "<p>hi</p>"}))
+	assert.Eventually(t, func() bool { return transport.sentCount() == 1 }, time.Second, 5*time.Millisecond)
+	assert.NotEmpty(t, transport.sent[0].ID)
+	assert.Contains(t, transport.sent[0].HTMLBody, "https://example.com/email/open/"+transport.sent[0].ID)
+}
@@ -0,0 +1,108 @@
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/haskekareem/sauri/cache"
+	"sync"
+	"time"
+)
+
+// TrackingStore keeps the tracking events of the emails, a store of the database of the
+// application only has to implement it
+type TrackingStore interface {
+	Record(ctx context.Context, event TrackingEvent) error
+	Events(ctx context.Context, messageID string) ([]TrackingEvent, error)
+}
+
+// MemoryTrackingStore keeps the events in memory, they are lost on restart
+type MemoryTrackingStore struct {
+	mu     sync.Mutex
+	events map[string][]TrackingEvent
+}
+
+// NewMemoryTrackingStore creates an empty memory store
+func NewMemoryTrackingStore() *MemoryTrackingStore {
+	return &MemoryTrackingStore{events: make(map[string][]TrackingEvent)}
+}
+
+// Record keeps an event
+func (s *MemoryTrackingStore) Record(_ context.Context, event TrackingEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events[event.MessageID] = append(s.events[event.MessageID], event)
+	return nil
+}
+
+// Events returns the events of an email, the oldest first
+func (s *MemoryTrackingStore) Events(_ context.Context, messageID string) ([]TrackingEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]TrackingEvent(nil), s.events[messageID]...), nil
+}
+
+// CacheTrackingStore keeps the events of each email as JSON under a key of a cache, e.g.
+// the Redis cache of the application, for TTL after the last event
+type CacheTrackingStore struct {
+	Cache  cache.Cache
+	Prefix string        // the prefix of the keys, mail-tracking: when empty
+	TTL    time.Duration // no expiry when zero
+
+	mu sync.Mutex
+}
+
+// NewCacheTrackingStore creates a store keeping the events in c for ttl
+func NewCacheTrackingStore(c cache.Cache, ttl time.Duration) *CacheTrackingStore {
+	return &CacheTrackingStore{Cache: c, TTL: ttl}
+}
+
+// Record adds an event to the ones of its email
+func (s *CacheTrackingStore) Record(ctx context.Context, event TrackingEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events, err := s.Events(ctx, event.MessageID)
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(append(events, event))
+	if err != nil {
+		return err
+	}
+	if s.TTL > 0 {
+		return s.Cache.Set(s.key(event.MessageID), string(encoded), s.TTL)
+	}
+	return s.Cache.Set(s.key(event.MessageID), string(encoded))
+}
+
+// Events returns the events of an email, the oldest first
+func (s *CacheTrackingStore) Events(_ context.Context, messageID string) ([]TrackingEvent, error) {
+	// the badger cache fails to get a missing key
+	exists, err := s.Cache.Exists(s.key(messageID))
+	if err != nil || !exists {
+		return nil, err
+	}
+	value, err := s.Cache.Get(s.key(messageID))
+	if err != nil || value == nil {
+		return nil, err
+	}
+	encoded, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("the tracking events of email %s are a %T, not JSON", messageID, value)
+	}
+
+	var events []TrackingEvent
+	if err := json.Unmarshal([]byte(encoded), &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (s *CacheTrackingStore) key(messageID string) string {
+	prefix := s.Prefix
+	if prefix == "" {
+		prefix = "mail-tracking:"
+	}
+	return prefix + messageID
+}
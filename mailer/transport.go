@@ -1,26 +1,47 @@
 package mailer
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"github.com/toorop/go-dkim"
 	mailpkg "github.com/xhit/go-simple-mail/v2"
-	"log"
+	"net"
+	"os"
+	"strconv"
+	"time"
 )
 
-// MailTransport defines an interface for sending emails
+// MailTransport defines an interface for sending emails. A transport gives up sending when
+// its ctx is done, e.g. at its deadline or on shutdown, and returns the error of ctx.
 type MailTransport interface {
-	Send(m *Message) error
-	SendMultiple(emails []*Message) error
+	Send(ctx context.Context, m *Message) error
+	SendMultiple(ctx context.Context, emails []*Message) error
 }
 
 // SMTPMailTransport implements MailTransport using go-simple-mail
 type SMTPMailTransport struct {
 	server *mailpkg.SMTPServer
+	dkim   *dkim.SigOptions
+}
+
+// smtpConn is a connection to the SMTP server, the net.Conn is kept to bound its IO by the
+// deadline of a context
+type smtpConn struct {
 	client *mailpkg.SMTPClient
+	conn   net.Conn
 }
 
 // NewSMTPMailTransport creates a new SimpleMailTransport with
-// the given configuration
-func NewSMTPMailTransport(config *Config) *SMTPMailTransport {
+// the given configuration, it returns an error when the SMTP server cannot be reached or the
+// DKIM settings are invalid
+func NewSMTPMailTransport(config *Config) (*SMTPMailTransport, error) {
+	signing, err := dkimOptions(config)
+	if err != nil {
+		return nil, err
+	}
+
 	server := mailpkg.NewSMTPClient()
 	server.Host = config.Host
 	server.Port = config.Port
@@ -32,42 +53,139 @@ func NewSMTPMailTransport(config *Config) *SMTPMailTransport {
 	server.SendTimeout = config.SendTimeout
 	server.TLSConfig = config.TLSConfig
 
+	transport := &SMTPMailTransport{
+		server: server,
+		dkim:   signing,
+	}
+	// the server is checked once, each send opens its own connection
+	c, err := transport.connect(context.Background(), false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	_ = c.client.Quit()
+	_ = c.conn.Close()
+	return transport, nil
+}
+
+// Send sends a single email message on a connection of its own
+func (s *SMTPMailTransport) Send(ctx context.Context, m *Message) error {
+	email, err := s.build(m)
+	if err != nil {
+		return err
+	}
+
+	c, err := s.connect(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer func(conn net.Conn) {
+		_ = conn.Close()
+	}(c.conn)
+
+	stop := watchConn(ctx, c.conn)
+	defer stop()
+	if err := email.Send(c.client); err != nil {
+		return contextError(ctx, err)
+	}
+	return nil
+}
+
+// build returns the MIME message of m, signed when DKIM is set
+func (s *SMTPMailTransport) build(m *Message) (*mailpkg.Email, error) {
+	email := newSimpleMail(m)
+	signDKIM(email, s.dkim, m.From)
+	return email, email.Error
+}
+
+// connect opens a connection to the server, the dial, the hello and the authentication are
+// bounded by ctx and the connect timeout
+func (s *SMTPMailTransport) connect(ctx context.Context, keepAlive bool) (*smtpConn, error) {
+	if s.server.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.server.ConnectTimeout)
+		defer cancel()
+	}
+	address := net.JoinHostPort(s.server.Host, strconv.Itoa(s.server.Port))
+	var conn net.Conn
+	var err error
+	switch s.server.Encryption {
+	case mailpkg.EncryptionSSL, mailpkg.EncryptionSSLTLS:
+		config := s.server.TLSConfig
+		if config == nil {
+			config = &tls.Config{ServerName: s.server.Host}
+		}
+		conn, err = (&tls.Dialer{Config: config}).DialContext(ctx, "tcp", address)
+	default:
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stop := watchConn(ctx, conn)
+	defer stop()
+	server := *s.server
+	server.CustomConn = conn
+	server.ConnectTimeout = 0
+	server.KeepAlive = keepAlive
 	client, err := server.Connect()
 	if err != nil {
-		log.Fatalf("Failed to connect to SMTP server: %v", err)
+		_ = conn.Close()
+		return nil, contextError(ctx, err)
 	}
+	return &smtpConn{client: client, conn: conn}, nil
+}
 
-	return &SMTPMailTransport{
-		server: server,
-		client: client,
+// watchConn bounds the IO of conn by the deadline of ctx and interrupts it when ctx is
+// cancelled, the returned func stops watching
+func watchConn(ctx context.Context, conn net.Conn) func() {
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Unix(1, 0))
+	})
+	return func() {
+		stop()
+		_ = conn.SetDeadline(time.Time{})
+	}
+}
+
+// contextError returns the error of ctx when it is done, err failed because of it, and err
+// otherwise
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%w: %w", ctxErr, err)
+	}
+	// the connection may time out a moment before ctx
+	if deadline, ok := ctx.Deadline(); ok && errors.Is(err, os.ErrDeadlineExceeded) && !time.Now().Before(deadline) {
+		return fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
 	}
+	return err
 }
 
-// Send sends a single email message
-func (s *SMTPMailTransport) Send(m *Message) error {
+// newSimpleMail builds the MIME message of m, the SMTP transport sends it and the API
+// transports taking raw messages upload it
+func newSimpleMail(m *Message) *mailpkg.Email {
 	email := mailpkg.NewMSG()
-	email.SetFrom(m.From.Address).SetSubject(m.Subject)
+	email.SetFrom(formatAddress(m.From)).SetSubject(m.Subject)
 
 	if m.ReplyTo.Address != "" {
-		email.SetReplyTo(m.ReplyTo.Address)
+		email.SetReplyTo(formatAddress(m.ReplyTo))
 	}
 
-	if len(m.To) > 0 {
-		for _, recipient := range m.To {
-			email.AddTo(recipient.Address)
-		}
+	for _, recipient := range m.To {
+		email.AddTo(formatAddress(recipient))
 	}
-
-	if len(m.Cc) > 0 {
-		for _, cc := range m.Cc {
-			email.AddCc(cc.Address)
-		}
+	for _, cc := range m.Cc {
+		email.AddCc(formatAddress(cc))
+	}
+	for _, bcc := range m.Bcc {
+		email.AddBcc(formatAddress(bcc))
 	}
 
-	if len(m.Bcc) > 0 {
-		for _, bcc := range m.Bcc {
-			email.AddBcc(bcc.Address)
-		}
+	for name, value := range m.Headers {
+		email.AddHeader(name, value)
 	}
 
 	// Set the HTML and plain text bodies
@@ -78,52 +196,41 @@ func (s *SMTPMailTransport) Send(m *Message) error {
 		email.AddAlternative(mailpkg.TextPlain, m.Body)
 	}
 
-	if len(m.Attachments) > 0 {
-		for _, attachment := range m.Attachments {
-			file := mailpkg.File{
-				Name:     attachment.Name,
-				MimeType: attachment.MimeType,
-				Data:     attachment.Data,
-				Inline:   attachment.Inline,
-			}
-			email.Attach(&file)
+	for _, attachment := range m.Attachments {
+		file := mailpkg.File{
+			Name:     attachment.Name,
+			MimeType: attachment.MimeType,
+			Data:     attachment.Data,
+			Inline:   attachment.Inline,
 		}
+		email.Attach(&file)
 	}
+	return email
+}
 
-	// Add DKIM signature if provided
-	if dkimOptions, ok := m.Headers["DkimOptions"]; ok && dkimOptions != "" {
-		opts := dkim.NewSigOptions()
-		opts.PrivateKey = []byte(dkimOptions)
-		opts.Domain = "example.com"
-		opts.Selector = "default"
-		opts.SignatureExpireIn = 3600
-		opts.AddSignatureTimestamp = true
-		opts.Headers = []string{"from", "date", "mime-version", "received", "received"}
-		opts.Canonicalization = "relaxed/relaxed"
-	}
-
-	if email.Error != nil {
-		return email.Error
-	}
-
-	err := email.Send(s.client)
+// SendMultiple sends multiple email messages using the same SMTP connection, it stops when
+// ctx is done
+func (s *SMTPMailTransport) SendMultiple(ctx context.Context, emails []*Message) error {
+	// Keep the connection alive for sending multiple emails
+	c, err := s.connect(ctx, true)
 	if err != nil {
 		return err
 	}
+	defer func(c *smtpConn) {
+		_ = c.client.Quit()
+		_ = c.conn.Close()
+	}(c) // Ensure the connection is closed after sending all emails
 
-	return nil
-}
-
-// SendMultiple sends multiple email messages using the same SMTP connection
-func (s *SMTPMailTransport) SendMultiple(emails []*Message) error {
-	// Keep the connection alive for sending multiple emails
-	s.client.KeepAlive = true
-	defer func(client *mailpkg.SMTPClient) {
-		_ = client.Quit()
-	}(s.client) // Ensure the connection is closed after sending all emails
-
+	stop := watchConn(ctx, c.conn)
+	defer stop()
 	for _, m := range emails {
-		err := s.Send(m)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		email, err := s.build(m)
+		if err == nil {
+			err = email.Send(c.client)
+		}
 		if err != nil {
 			ErrorLogger.Printf("Failed to send email to %v: %v", m.To, err)
 		} else {
@@ -0,0 +1,149 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	mailpkg "github.com/xhit/go-simple-mail/v2"
+)
+
+// smtpServer is a fake SMTP server recording the messages, with stall it never answers DATA
+type smtpServer struct {
+	listener net.Listener
+	stall    bool
+
+	mu       sync.Mutex
+	messages []string
+}
+
+func newSMTPServer(t *testing.T, stall bool) *smtpServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &smtpServer{listener: listener, stall: stall}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch command := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(command, "EHLO"):
+			reply("250 localhost")
+		case command == "DATA":
+			if s.stall {
+				_, _ = r.ReadString(0) // until the client gives up
+				return
+			}
+			reply("354 go ahead")
+			var message strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				message.WriteString(line)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, message.String())
+			s.mu.Unlock()
+			reply("250 queued")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *smtpServer) config() *Config {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	number, _ := strconv.Atoi(port)
+	return &Config{Host: "127.0.0.1", Port: number, Encryption: mailpkg.EncryptionNone, ConnectTimeout: time.Second}
+}
+
+// smtpMessage returns the test message with a sender, the SMTP transport has no default one
+func smtpMessage() *Message {
+	m := testMessage()
+	m.From = EmailAddress{Address: "app@example.com"}
+	return m
+}
+
+func TestSMTPTransportSendsEachMessage(t *testing.T) {
+	server := newSMTPServer(t, false)
+	transport, err := NewSMTPMailTransport(server.config())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, transport.Send(ctx, smtpMessage()))
+	require.NoError(t, transport.Send(ctx, smtpMessage()))
+	SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil))) // SendMultiple logs each email
+	require.NoError(t, transport.SendMultiple(ctx, []*Message{smtpMessage(), smtpMessage()}))
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	require.Len(t, server.messages, 4)
+	assert.Contains(t, server.messages[0], "Subject: Welcome")
+}
+
+func TestSMTPTransportDeadline(t *testing.T) {
+	server := newSMTPServer(t, true)
+	transport, err := NewSMTPMailTransport(server.config())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = transport.Send(ctx, smtpMessage())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestAPITransportCancellation(t *testing.T) {
+	release := make(chan struct{})
+	server, _, _ := apiServer(t, http.StatusOK, `{}`)
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	t.Cleanup(func() { close(release) })
+	transport, err := NewPostmarkTransport(testConfig(server.URL))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	err = transport.Send(ctx, testMessage())
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, ErrUnavailable)
+}
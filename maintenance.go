@@ -0,0 +1,237 @@
+package sauri
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maintenancePage is the page rendered in maintenance mode when the views have one
+const maintenancePage = "503.gohtml"
+
+// maintenanceCookie holds the bypass of a browser that visited the secret path
+const maintenanceCookie = "sauri_maintenance"
+
+// MaintenanceMode is the content of the maintenance flag written by sauri down
+type MaintenanceMode struct {
+	Since   time.Time `json:"since"`
+	Message string    `json:"message,omitempty"`
+	Retry   int       `json:"retry,omitempty"`  // seconds sent in Retry-After
+	Secret  string    `json:"secret,omitempty"` // visiting /<secret> lets a browser through
+	Allow   []string  `json:"allow,omitempty"`  // IPs or CIDR ranges let through
+}
+
+// defaultMaintenancePage is rendered when the application has no 503.gohtml page
+var defaultMaintenancePage = template.Must(template.New("503").Parse(`<!doctype html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Down for Maintenance</title></head>
+<body>
+<h1>Down for maintenance</h1>
+<p>{{if .}}{{.}}{{else}}We are performing scheduled maintenance and will be back shortly.{{end}}</p>
+</body>
+</html>
+`))
+
+// MaintenanceFile returns the path of the maintenance flag of the application
+func MaintenanceFile(rootPath string) string {
+	return filepath.Join(rootPath, "storage", "framework", "down")
+}
+
+// Down puts the application in maintenance mode
+func Down(rootPath string, mode MaintenanceMode) error {
+	if mode.Since.IsZero() {
+		mode.Since = time.Now()
+	}
+	for _, allowed := range mode.Allow {
+		if _, _, err := net.ParseCIDR(allowed); err != nil && net.ParseIP(allowed) == nil {
+			return fmt.Errorf("invalid IP or CIDR range %q", allowed)
+		}
+	}
+
+	content, err := json.MarshalIndent(mode, "", "  ")
+	if err != nil {
+		return err
+	}
+	file := MaintenanceFile(rootPath)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("cannot create %s: %w", filepath.Dir(file), err)
+	}
+	return os.WriteFile(file, content, 0644)
+}
+
+// Up takes the application out of maintenance mode
+func Up(rootPath string) error {
+	err := os.Remove(MaintenanceFile(rootPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// maintenanceState caches the flag, it is read again when it changes on disk
+type maintenanceState struct {
+	mu      sync.Mutex
+	modTime time.Time
+	mode    *MaintenanceMode
+}
+
+// maintenanceMode returns the current maintenance mode, nil when the application is up
+func (s *Sauri) maintenanceMode() *MaintenanceMode {
+	file := MaintenanceFile(s.RootPath)
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil
+	}
+
+	s.maintenance.mu.Lock()
+	defer s.maintenance.mu.Unlock()
+	if s.maintenance.mode != nil && info.ModTime().Equal(s.maintenance.modTime) {
+		return s.maintenance.mode
+	}
+
+	mode := &MaintenanceMode{}
+	content, err := os.ReadFile(file)
+	if err == nil {
+		err = json.Unmarshal(content, mode)
+	}
+	if err != nil {
+		// a flag that cannot be read still means down
+		s.ErrorLog.Println("cannot read the maintenance flag:", err)
+		mode = &MaintenanceMode{}
+	}
+	s.maintenance.mode, s.maintenance.modTime = mode, info.ModTime()
+	return mode
+}
+
+// Maintenance answers every request with 503 while the application is down, see sauri down.
+// Allowed IPs and browsers holding the bypass cookie are let through. The allowed IPs are
+// matched against the address of the connection, never the X-Forwarded-For or X-Real-IP
+// headers a client can set: behind a proxy, allow the address of the proxy.
+func (s *Sauri) Maintenance(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mode := s.maintenanceMode()
+		if mode == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if mode.Secret != "" {
+			bypass := secretHash(mode.Secret)
+			if r.URL.Path == "/"+mode.Secret {
+				http.SetCookie(w, &http.Cookie{
+					Name:     maintenanceCookie,
+					Value:    bypass,
+					Path:     "/",
+					HttpOnly: true,
+					Secure:   isHTTPS(r),
+					SameSite: http.SameSiteLaxMode,
+					MaxAge:   12 * 60 * 60,
+				})
+				http.Redirect(w, r, "/", http.StatusFound)
+				return
+			}
+			if c, err := r.Cookie(maintenanceCookie); err == nil && subtle.ConstantTimeCompare([]byte(c.Value), []byte(bypass)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		if ipAllowed(connAddr(r), mode.Allow) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if mode.Retry > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(mode.Retry))
+		}
+		s.renderMaintenance(w, r, mode)
+	})
+}
+
+// connAddrKey is the context key of the address of the connection, see keepConnAddr
+type connAddrKey struct{}
+
+// keepConnAddr keeps the address of the connection before middleware.RealIP replaces
+// RemoteAddr with the forwarded one
+func keepConnAddr(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), connAddrKey{}, r.RemoteAddr)))
+	})
+}
+
+// connAddr returns the address of the connection of the request, RemoteAddr outside of
+// keepConnAddr
+func connAddr(r *http.Request) string {
+	if addr, ok := r.Context().Value(connAddrKey{}).(string); ok {
+		return addr
+	}
+	return r.RemoteAddr
+}
+
+// renderMaintenance answers with 503, JSON to API clients and the maintenance page otherwise
+func (s *Sauri) renderMaintenance(w http.ResponseWriter, r *http.Request, mode *MaintenanceMode) {
+	if wantsJSON(r) {
+		if s.config.problemDetails {
+			s.errorStatus(w, r, http.StatusServiceUnavailable)
+			return
+		}
+		message := mode.Message
+		if message == "" {
+			message = "The service is down for maintenance"
+		}
+		_ = s.NewResponse().SetResponseWriter(w).Fail(http.StatusServiceUnavailable, "maintenance", message, nil)
+		return
+	}
+
+	if s.renderErrorPage(w, r, maintenancePage, http.StatusServiceUnavailable) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_ = defaultMaintenancePage.Execute(w, mode.Message)
+}
+
+// ipAllowed reports whether the address is one of the allowed IPs or ranges
+func ipAllowed(remoteAddr string, allow []string) bool {
+	if len(allow) == 0 {
+		return false
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, allowed := range allow {
+		if _, network, err := net.ParseCIDR(allowed); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if allowedIP := net.ParseIP(allowed); allowedIP != nil && allowedIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretHash is the bypass cookie value of a secret, so that the cookie does not hold it
+func secretHash(secret string) string {
+	sum := sha256.Sum256([]byte("sauri-maintenance:" + secret))
+	return hex.EncodeToString(sum[:])
+}
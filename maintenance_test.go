@@ -0,0 +1,45 @@
+package sauri
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenance_AllowsTheConnectionAddress(t *testing.T) {
+	s := &Sauri{RootPath: t.TempDir()}
+	require.NoError(t, Down(s.RootPath, MaintenanceMode{Allow: []string{"10.0.0.0/8"}}))
+
+	// the chain of defaultRouter: the forwarded headers are read after keepConnAddr
+	handler := keepConnAddr(middleware.RealIP(s.Maintenance(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       int
+	}{
+		{"allowed connection", "10.1.2.3:5000", "", http.StatusNoContent},
+		{"allowed proxy", "10.1.2.3:5000", "203.0.113.7", http.StatusNoContent},
+		{"other connection", "203.0.113.7:5000", "", http.StatusServiceUnavailable},
+		{"spoofed forwarded header", "203.0.113.7:5000", "10.1.2.3", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tt.want, rr.Code)
+		})
+	}
+}
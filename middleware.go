@@ -1,22 +1,27 @@
 package sauri
 
 import (
+	"github.com/haskekareem/sauri/sessions"
 	"github.com/justinas/nosurf"
 	"net/http"
+	"path"
 	"strconv"
+	"strings"
+	"sync"
 )
 
 // SessionLoad takes care of loading and committing session data to the session store, and
 // communicating the session token to/from the client in a cookie as necessary.
 func (s *Sauri) SessionLoad(next http.Handler) http.Handler {
-	return s.Session.LoadAndSave(next)
+	return sessions.LoadAndSave(s.Session, next)
 }
 
 func (s *Sauri) NoSurf(next http.Handler) http.Handler {
 	csrfHandler := nosurf.New(next)
 	secure, _ := strconv.ParseBool(s.config.cookie.secure)
 
-	//csrfHandler.ExemptGlob("/api/*")
+	// the API groups authenticate with tokens
+	csrfHandler.ExemptFunc(s.isCSRFExempt)
 
 	csrfHandler.SetBaseCookie(http.Cookie{
 		HttpOnly: true,
@@ -28,3 +33,35 @@ func (s *Sauri) NoSurf(next http.Handler) http.Handler {
 
 	return csrfHandler
 }
+
+// csrfExemptions lists the paths registered as exempt from the CSRF check
+type csrfExemptions struct {
+	mu       sync.RWMutex
+	patterns []string
+}
+
+// exemptFromCSRF exempts the paths matching the patterns, path.Match syntax
+func (s *Sauri) exemptFromCSRF(patterns ...string) {
+	s.csrfExempt.mu.Lock()
+	defer s.csrfExempt.mu.Unlock()
+	s.csrfExempt.patterns = append(s.csrfExempt.patterns, patterns...)
+}
+
+// isCSRFExempt reports whether the request is exempt from the CSRF check
+func (s *Sauri) isCSRFExempt(r *http.Request) bool {
+	s.csrfExempt.mu.RLock()
+	defer s.csrfExempt.mu.RUnlock()
+	for _, pattern := range s.csrfExempt.patterns {
+		if pattern == r.URL.Path {
+			return true
+		}
+		// a trailing wildcard covers everything below, path.Match stops at slashes
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+		if ok, _ := path.Match(pattern, r.URL.Path); ok {
+			return true
+		}
+	}
+	return false
+}
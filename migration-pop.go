@@ -29,7 +29,7 @@ func (s *Sauri) CreatePopMigration(up, down []byte, migrationName string, migrat
 	}
 
 	// Define the migration path
-	migrationPath := filepath.Join(s.RootPath, "migrations")
+	migrationPath := s.migrationDirFor(popDriver)
 
 	// Ensure the migration directory exists
 	if _, err := os.Stat(migrationPath); os.IsNotExist(err) {
@@ -99,7 +99,7 @@ func writeFile(filePath string, content []byte) error {
 }
 
 func (s *Sauri) RunUpPopMigration(txn *pop.Connection) error {
-	var migrationPath = s.RootPath + "/migrations"
+	var migrationPath = s.migrationDirFor(popDriver)
 
 	fileMigrator, err := pop.NewFileMigrator(migrationPath, txn)
 	if err != nil {
@@ -115,7 +115,7 @@ func (s *Sauri) RunUpPopMigration(txn *pop.Connection) error {
 }
 
 func (s *Sauri) RunDownPopMigration(txn *pop.Connection, steps ...int) error {
-	var migrationPath = s.RootPath + "/migrations"
+	var migrationPath = s.migrationDirFor(popDriver)
 
 	step := 1
 	if len(steps) > 0 {
@@ -135,7 +135,7 @@ func (s *Sauri) RunDownPopMigration(txn *pop.Connection, steps ...int) error {
 }
 
 func (s *Sauri) RunResetPopMigration(txn *pop.Connection) error {
-	var migrationPath = s.RootPath + "/migrations"
+	var migrationPath = s.migrationDirFor(popDriver)
 
 	fileMigrator, err := pop.NewFileMigrator(migrationPath, txn)
 	if err != nil {
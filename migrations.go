@@ -1,16 +1,24 @@
 package sauri
 
 import (
+	"errors"
+	"fmt"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/mysql"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/haskekareem/sauri/cache"
 	"log"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
+	"time"
 )
 
 // formatMigrationPath adjusts the migration path based on the user's operating system
@@ -38,19 +46,12 @@ func formatMigrationPath(rootPath string) (string, error) {
 
 // UpMigrate applying all up migrations.
 func (s *Sauri) UpMigrate(dsn string) error {
-	// Format the migration path based on the OS and check if it's valid
-	migrationPath, err := formatMigrationPath(filepath.Join(s.RootPath, "internal", "migration"))
+	m, err := s.Migrator(dsn)
 	if err != nil {
 		return err
 	}
-
-	m, err := migrate.New(migrationPath, dsn)
-	if err != nil {
-		return err
-	}
-
-	defer func(m *migrate.Migrate) {
-		_, _ = m.Close()
+	defer func(m Migrator) {
+		_ = m.Close()
 	}(m)
 
 	// Migrate all the way up ...
@@ -63,18 +64,12 @@ func (s *Sauri) UpMigrate(dsn string) error {
 
 // DownMigrate applying all down migrations.
 func (s *Sauri) DownMigrate(dsn string) error {
-	// Format the migration path based on the OS and check if it's valid
-	migrationPath, err := formatMigrationPath(filepath.Join(s.RootPath, "internal", "migration"))
-	if err != nil {
-		return err
-	}
-
-	m, err := migrate.New(migrationPath, dsn)
+	m, err := s.Migrator(dsn)
 	if err != nil {
 		return err
 	}
-	defer func(m *migrate.Migrate) {
-		_, _ = m.Close()
+	defer func(m Migrator) {
+		_ = m.Close()
 	}(m)
 
 	// Migrate all the way down ...
@@ -85,19 +80,32 @@ func (s *Sauri) DownMigrate(dsn string) error {
 	return nil
 }
 
-// StepsMigrate It will migrate up if n > 0, and down if n < 0.
-func (s *Sauri) StepsMigrate(n int, dsn string) error {
-	// Format the migration path based on the OS and check if it's valid
-	migrationPath, err := formatMigrationPath(filepath.Join(s.RootPath, "internal", "migration"))
+// FreshMigrate drops every table of the database, the ones the migrations did not create
+// too, then runs all up migrations
+func (s *Sauri) FreshMigrate(dsn string) error {
+	m, err := s.Migrator(dsn)
 	if err != nil {
 		return err
 	}
-	m, err := migrate.New(migrationPath, dsn)
+	err = m.Drop()
+	_ = m.Close()
 	if err != nil {
+		log.Println("error dropping the tables")
 		return err
 	}
-	defer func(m *migrate.Migrate) {
-		_, _ = m.Close()
+
+	// the migrations table is gone too, a new migrator creates it again
+	return s.UpMigrate(dsn)
+}
+
+// StepsMigrate It will migrate up if n > 0, and down if n < 0.
+func (s *Sauri) StepsMigrate(n int, dsn string) error {
+	m, err := s.Migrator(dsn)
+	if err != nil {
+		return err
+	}
+	defer func(m Migrator) {
+		_ = m.Close()
 	}(m)
 
 	//  It will migrate up if n > 0, and down if n < 0. ...
@@ -111,17 +119,12 @@ func (s *Sauri) StepsMigrate(n int, dsn string) error {
 // ForceMigrate sets a migration version. It does not check any currently active version in database.
 // It resets the dirty state to false.
 func (s *Sauri) ForceMigrate(dsn string) error {
-	// Format the migration path based on the OS and check if it's valid
-	migrationPath, err := formatMigrationPath(filepath.Join(s.RootPath, "internal", "migration"))
+	m, err := s.Migrator(dsn)
 	if err != nil {
 		return err
 	}
-	m, err := migrate.New(migrationPath, dsn)
-	if err != nil {
-		return err
-	}
-	defer func(m *migrate.Migrate) {
-		_, _ = m.Close()
+	defer func(m Migrator) {
+		_ = m.Close()
 	}(m)
 
 	//  get rid of the last migration run ...
@@ -131,3 +134,249 @@ func (s *Sauri) ForceMigrate(dsn string) error {
 	}
 	return nil
 }
+
+// ToMigrate migrates up or down to a version, 0 reverses every migration
+func (s *Sauri) ToMigrate(version uint, dsn string) error {
+	if version == 0 {
+		return s.DownMigrate(dsn)
+	}
+	m, err := s.Migrator(dsn)
+	if err != nil {
+		return err
+	}
+	defer func(m Migrator) {
+		_ = m.Close()
+	}(m)
+
+	if err := m.To(version); err != nil {
+		log.Println("error migrating to version", version)
+		return err
+	}
+	return nil
+}
+
+// MigrationVersion returns the version of the last migration run, 0 when none has, and
+// whether it failed halfway, in which case the database needs fixing and ForceMigrate
+func (s *Sauri) MigrationVersion(dsn string) (uint, bool, error) {
+	m, err := s.Migrator(dsn)
+	if err != nil {
+		return 0, false, err
+	}
+	defer func(m Migrator) {
+		_ = m.Close()
+	}(m)
+
+	return m.Version()
+}
+
+// Migration is a migration of the migration folder
+type Migration struct {
+	Version   uint
+	Name      string
+	CreatedAt time.Time // read from the version, zero when it is not a timestamp
+	Applied   bool
+	Up        string // file of the up migration, empty when there is none
+	Down      string // file of the down migration, empty when there is none
+}
+
+// MigrationStatus returns the migrations in order with whether they are applied, the
+// current version and whether its migration failed halfway
+func (s *Sauri) MigrationStatus(dsn string) ([]Migration, uint, bool, error) {
+	version, dirty, err := s.MigrationVersion(dsn)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	migrations, err := s.readMigrations()
+	if err != nil {
+		return nil, 0, false, err
+	}
+	for i := range migrations {
+		migrations[i].Applied = migrations[i].Version <= version
+	}
+	return migrations, version, dirty, nil
+}
+
+// readMigrations lists the migration files in version order, the file names are parsed the
+// way golang-migrate does
+func (s *Sauri) readMigrations() ([]Migration, error) {
+	entries, err := os.ReadDir(s.MigrationDir())
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		parsed, err := source.Parse(entry.Name())
+		if err != nil {
+			continue
+		}
+		migration, ok := byVersion[parsed.Version]
+		if !ok {
+			migration = &Migration{Version: parsed.Version, Name: parsed.Identifier}
+			// the make commands name the migrations with a UnixMicro timestamp
+			if parsed.Version >= 1e15 {
+				migration.CreatedAt = time.UnixMicro(int64(parsed.Version))
+			}
+			byVersion[parsed.Version] = migration
+		}
+		path := filepath.Join(s.MigrationDir(), entry.Name())
+		if parsed.Direction == source.Up {
+			migration.Up = path
+		} else {
+			migration.Down = path
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// MigrationStep is a migration a dry run would run, with its SQL
+type MigrationStep struct {
+	Migration
+	Direction string // up or down
+	SQL       string
+}
+
+// PlanMigrate returns the migrations migrating to a version would run without running them,
+// for dry runs. The current version comes from the database of dsn.
+func (s *Sauri) PlanMigrate(version uint, dsn string) ([]MigrationStep, error) {
+	migrations, current, _, err := s.MigrationStatus(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []MigrationStep
+	if version >= current {
+		for _, migration := range migrations {
+			if migration.Version > current && migration.Version <= version {
+				steps = append(steps, MigrationStep{Migration: migration, Direction: "up"})
+			}
+		}
+	} else {
+		for i := len(migrations) - 1; i >= 0; i-- {
+			if migrations[i].Version <= current && migrations[i].Version > version {
+				steps = append(steps, MigrationStep{Migration: migrations[i], Direction: "down"})
+			}
+		}
+	}
+
+	for i, step := range steps {
+		file := step.Up
+		if step.Direction == "down" {
+			file = step.Down
+		}
+		if file == "" {
+			return nil, fmt.Errorf("migration %d has no %s file", step.Version, step.Direction)
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		steps[i].SQL = string(content)
+	}
+	return steps, nil
+}
+
+// MigrationDSN returns the url of the default database in the form the migrations expect,
+// postgres://... or mysql://..., built from the DATABASE_* settings
+func (s *Sauri) MigrationDSN() (string, error) {
+	setting := s.dbSettings(defaultDatabase)
+	host, port, dbname := setting("HOST"), setting("PORT"), setting("NAME")
+	if host == "" || port == "" || setting("USER") == "" || dbname == "" {
+		return "", fmt.Errorf("missing mandatory environment variables for DB")
+	}
+	user := url.User(setting("USER"))
+	if password := setting("PASS"); password != "" {
+		user = url.UserPassword(setting("USER"), password)
+	}
+	sslMode := setting("SSL_MODE")
+
+	switch setting("TYPE") {
+	case "postgresql", "postgres", "pgx":
+		if sslMode == "" {
+			sslMode = "disable"
+		}
+		return fmt.Sprintf("postgres://%s@%s/%s?sslmode=%s", user, net.JoinHostPort(host, port), dbname, sslMode), nil
+	case "mysql", "mariadb":
+		if sslMode == "" {
+			sslMode = "false"
+		}
+		return fmt.Sprintf("mysql://%s@tcp(%s)/%s?parseTime=True&loc=Local&tls=%s", user, net.JoinHostPort(host, port), dbname, sslMode), nil
+	}
+	return "", fmt.Errorf("unsupported database type: %s", setting("TYPE"))
+}
+
+// migrateOnStart runs the pending migrations when MIGRATE_ON_START is true, for the
+// deployments starting the application without running the cli first. With a redis or
+// badger cache the instances take a lock first, the ones waiting for it find the
+// migrations done; MIGRATE_LOCK_TIMEOUT sets in seconds how long they wait.
+func (s *Sauri) migrateOnStart() error {
+	if !s.Config.GetBool("MIGRATE_ON_START", false) {
+		return nil
+	}
+	if s.DBConn.SqlConnPool == nil {
+		return errors.New("MIGRATE_ON_START needs a database, see DATABASE_USE")
+	}
+	dsn, err := s.MigrationDSN()
+	if err != nil {
+		return err
+	}
+
+	var locker cache.Locker
+	switch {
+	case myRedisCache != nil:
+		locker = myRedisCache
+	case myBadgerCache != nil:
+		locker = myBadgerCache
+	}
+	if locker != nil {
+		const key = "sauri:migrate"
+		owner := instanceID()
+		timeout := s.Config.GetDuration("MIGRATE_LOCK_TIMEOUT", time.Second, time.Minute)
+		// the lock outlives the wait so that a slow migration is not run twice
+		if err := waitForLock(locker, key, owner, timeout+time.Minute, timeout); err != nil {
+			return err
+		}
+		defer func() {
+			_ = locker.Unlock(key, owner)
+		}()
+	}
+
+	start := time.Now()
+	if err := s.UpMigrate(dsn); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			return nil
+		}
+		return err
+	}
+	s.log().Info("migrations run on start", "duration", time.Since(start))
+	return nil
+}
+
+// waitForLock takes a cache lock, waiting up to timeout for its holder to release it
+func waitForLock(locker cache.Locker, key, owner string, ttl, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		acquired, err := locker.Lock(key, owner, ttl)
+		if err != nil {
+			return fmt.Errorf("cannot take the %s lock: %w", key, err)
+		}
+		if acquired {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the %s lock is still held after %s", key, timeout)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
@@ -0,0 +1,197 @@
+package sauri
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/golang-migrate/migrate/v4"
+	"path/filepath"
+	"strconv"
+)
+
+// Migrator runs the migrations of the migration folder. MIGRATION_DRIVER picks the backend,
+// golang-migrate by default or pop, and MIGRATION_DIR the folder. The migration files made
+// by the cli, <version>_<name>.<dialect>.up.sql, are read by both.
+type Migrator interface {
+	// Up runs the pending migrations
+	Up() error
+	// Down reverses every applied migration
+	Down() error
+	// Steps runs n migrations up when n is positive and reverses -n when it is negative
+	Steps(n int) error
+	// To migrates up or down to a version
+	To(version uint) error
+	// Version returns the version of the last migration run, 0 when none has, and whether
+	// it failed halfway
+	Version() (uint, bool, error)
+	// Force sets the version without running anything, -1 for none, after a failed migration
+	Force(version int) error
+	// Drop drops every table of the database
+	Drop() error
+	Close() error
+}
+
+// migrationDrivers are the MIGRATION_DRIVER values
+const (
+	migrateDriver = "migrate"
+	popDriver     = "pop"
+)
+
+// MigrationDir returns the folder of the migration files, MIGRATION_DIR relative to the root
+// of the application, internal/migration by default and migrations for pop
+func (s *Sauri) MigrationDir() string {
+	return s.migrationDirFor(s.migrationDriver())
+}
+
+// migrationDirFor returns the migration folder of a driver
+func (s *Sauri) migrationDirFor(driver string) string {
+	dir := s.Config.Get("MIGRATION_DIR")
+	if dir == "" {
+		dir = filepath.Join("internal", "migration")
+		if driver == popDriver {
+			dir = "migrations"
+		}
+	}
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(s.RootPath, dir)
+}
+
+// migrationDriver returns the MIGRATION_DRIVER setting
+func (s *Sauri) migrationDriver() string {
+	return s.Config.Get("MIGRATION_DRIVER", migrateDriver)
+}
+
+// Migrator opens the migrations of MigrationDir on the database of dsn, see MigrationDSN,
+// with the MIGRATION_DRIVER backend
+func (s *Sauri) Migrator(dsn string) (Migrator, error) {
+	switch driver := s.migrationDriver(); driver {
+	case migrateDriver:
+		// Format the migration path based on the OS and check if it's valid
+		migrationPath, err := formatMigrationPath(s.MigrationDir())
+		if err != nil {
+			return nil, err
+		}
+		m, err := migrate.New(migrationPath, dsn)
+		if err != nil {
+			return nil, err
+		}
+		return golangMigrator{m: m}, nil
+	case popDriver:
+		conn, err := pop.NewConnection(&pop.ConnectionDetails{URL: dsn})
+		if err != nil {
+			return nil, err
+		}
+		if err := conn.Open(); err != nil {
+			return nil, err
+		}
+		fm, err := pop.NewFileMigrator(s.MigrationDir(), conn)
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		return popMigrator{fm: fm, conn: conn}, nil
+	default:
+		return nil, fmt.Errorf("unknown MIGRATION_DRIVER %q, expected %s or %s", driver, migrateDriver, popDriver)
+	}
+}
+
+// golangMigrator runs the migrations with golang-migrate
+type golangMigrator struct {
+	m *migrate.Migrate
+}
+
+func (g golangMigrator) Up() error {
+	return g.m.Up()
+}
+
+func (g golangMigrator) Down() error {
+	return g.m.Down()
+}
+
+func (g golangMigrator) Steps(n int) error {
+	return g.m.Steps(n)
+}
+
+func (g golangMigrator) To(version uint) error {
+	return g.m.Migrate(version)
+}
+
+func (g golangMigrator) Version() (uint, bool, error) {
+	version, dirty, err := g.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+func (g golangMigrator) Force(version int) error {
+	return g.m.Force(version)
+}
+
+func (g golangMigrator) Drop() error {
+	return g.m.Drop()
+}
+
+func (g golangMigrator) Close() error {
+	sourceErr, dbErr := g.m.Close()
+	return errors.Join(sourceErr, dbErr)
+}
+
+// popMigrator runs the migrations with pop, which has no versions to migrate to nor dirty
+// state
+type popMigrator struct {
+	fm   pop.FileMigrator
+	conn *pop.Connection
+}
+
+func (p popMigrator) Up() error {
+	return p.fm.Up()
+}
+
+func (p popMigrator) Down() error {
+	// pop reverses every migration with a negative step
+	return p.fm.Down(-1)
+}
+
+func (p popMigrator) Steps(n int) error {
+	if n < 0 {
+		return p.fm.Down(-n)
+	}
+	_, err := p.fm.UpTo(n)
+	return err
+}
+
+func (p popMigrator) To(uint) error {
+	return fmt.Errorf("pop migrations cannot migrate to a version: %w", errors.ErrUnsupported)
+}
+
+func (p popMigrator) Version() (uint, bool, error) {
+	var version string
+	query := fmt.Sprintf("SELECT version FROM %s ORDER BY version DESC LIMIT 1", p.conn.MigrationTableName())
+	if err := p.conn.Store.Get(&version, query); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	parsed, err := strconv.ParseUint(version, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid migration version %q: %w", version, err)
+	}
+	return uint(parsed), false, nil
+}
+
+func (p popMigrator) Force(int) error {
+	return fmt.Errorf("pop migrations cannot be forced: %w", errors.ErrUnsupported)
+}
+
+func (p popMigrator) Drop() error {
+	return fmt.Errorf("pop migrations cannot drop the tables: %w", errors.ErrUnsupported)
+}
+
+func (p popMigrator) Close() error {
+	return p.conn.Close()
+}
@@ -0,0 +1,60 @@
+package sauri
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// problemContentType is the media type of RFC 7807 problem details
+const problemContentType = "application/problem+json"
+
+// Problem writes an RFC 7807 problem details response. The type is a URI identifying the
+// kind of problem ("about:blank" when empty) and the extensions are added as members.
+func (r *Response) Problem(status int, title, detail, problemType string, extensions map[string]interface{}) error {
+	problem := make(map[string]interface{}, len(extensions)+4)
+	for key, value := range extensions {
+		problem[key] = value
+	}
+
+	if problemType == "" {
+		problemType = "about:blank"
+	}
+	if title == "" {
+		title = http.StatusText(status)
+	}
+	problem["type"] = problemType
+	problem["title"] = title
+	problem["status"] = status
+	if detail != "" {
+		problem["detail"] = detail
+	}
+
+	content, err := json.Marshal(problem)
+	if err != nil {
+		http.Error(r.Rw, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+
+	r.Header(contentType, problemContentType)
+	return r.Send(content, status)
+}
+
+// errorStatus answers with the status, as problem details to clients asking for JSON when
+// PROBLEM_DETAILS is on and as plain text otherwise
+func (s *Sauri) errorStatus(w http.ResponseWriter, r *http.Request, status int) {
+	if s.config.problemDetails && wantsJSON(r) {
+		response := s.NewResponse().SetResponseWriter(w)
+		extensions := map[string]interface{}{"instance": r.URL.Path}
+		if id := RequestIDFrom(r); id != "" {
+			extensions["request_id"] = id
+		}
+		_ = response.Problem(status, "", "", "", extensions)
+		return
+	}
+
+	if id := RequestIDFrom(r); id != "" {
+		http.Error(w, http.StatusText(status)+"\nRequest ID: "+id, status)
+		return
+	}
+	s.ErrorStatus(w, status)
+}
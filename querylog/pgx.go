@@ -0,0 +1,36 @@
+package querylog
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// pgxQueryKey holds the query started in its context
+type pgxQueryKey struct{}
+
+// pgxQuery is a query in progress
+type pgxQuery struct {
+	sql   string
+	start time.Time
+}
+
+// QueryTracer records the queries of a pgx connection or pool, set it as the Tracer of the
+// pgx.ConnConfig
+type QueryTracer struct {
+	Logger *Logger
+}
+
+// TraceQueryStart implements pgx.QueryTracer
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, pgxQueryKey{}, pgxQuery{sql: data.SQL, start: time.Now()})
+}
+
+// TraceQueryEnd implements pgx.QueryTracer
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	query, ok := ctx.Value(pgxQueryKey{}).(pgxQuery)
+	if !ok {
+		return
+	}
+	t.Logger.Record(ctx, query.sql, time.Since(query.start), data.Err)
+}
@@ -0,0 +1,91 @@
+// Package querylog logs the database queries with their duration, flags the slow ones and
+// counts them for the metrics endpoint. It wraps database/sql drivers and pgx connections.
+package querylog
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Logger records the queries of one or more pools
+type Logger struct {
+	Log       *slog.Logger  // slog.Default() when nil
+	Threshold time.Duration // queries taking at least this long are logged as warnings, never when 0
+	All       bool          // log every query at debug level
+	Stats     Stats
+}
+
+// Stats counts the queries recorded by a Logger
+type Stats struct {
+	queries atomic.Int64
+	slow    atomic.Int64
+	errors  atomic.Int64
+	total   atomic.Int64 // nanoseconds
+	longest atomic.Int64 // nanoseconds
+}
+
+// Snapshot is a copy of the counters of Stats
+type Snapshot struct {
+	Queries       int64         `json:"queries"`
+	Slow          int64         `json:"slow"`
+	Errors        int64         `json:"errors"`
+	TotalDuration time.Duration `json:"total_duration"`
+	Longest       time.Duration `json:"longest"`
+}
+
+// Snapshot returns the current counters
+func (s *Stats) Snapshot() Snapshot {
+	return Snapshot{
+		Queries:       s.queries.Load(),
+		Slow:          s.slow.Load(),
+		Errors:        s.errors.Load(),
+		TotalDuration: time.Duration(s.total.Load()),
+		Longest:       time.Duration(s.longest.Load()),
+	}
+}
+
+// Record counts a query and logs it when it is slow, failed or every query is logged
+func (l *Logger) Record(ctx context.Context, query string, duration time.Duration, err error) {
+	l.Stats.queries.Add(1)
+	l.Stats.total.Add(int64(duration))
+	for {
+		longest := l.Stats.longest.Load()
+		if int64(duration) <= longest || l.Stats.longest.CompareAndSwap(longest, int64(duration)) {
+			break
+		}
+	}
+
+	slow := l.Threshold > 0 && duration >= l.Threshold
+	if slow {
+		l.Stats.slow.Add(1)
+	}
+	if err != nil {
+		l.Stats.errors.Add(1)
+	}
+	if !slow && !l.All {
+		return
+	}
+
+	log := l.Log
+	if log == nil {
+		log = slog.Default()
+	}
+	// the arguments are left out, they may hold personal data or secrets
+	args := []any{"query", compact(query), "duration", duration}
+	if err != nil {
+		args = append(args, "error", err)
+	}
+	if slow {
+		log.WarnContext(ctx, "slow query", append(args, "threshold", l.Threshold)...)
+		return
+	}
+	log.DebugContext(ctx, "query", args...)
+}
+
+// compact puts a query on one line
+func compact(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
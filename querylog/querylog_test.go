@@ -0,0 +1,103 @@
+package querylog
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecord_FlagsSlowQueries(t *testing.T) {
+	var out bytes.Buffer
+	logger := &Logger{Log: slog.New(slog.NewTextHandler(&out, nil)), Threshold: 100 * time.Millisecond}
+
+	logger.Record(context.Background(), "SELECT 1", 10*time.Millisecond, nil)
+	if out.Len() != 0 {
+		t.Errorf("a fast query was logged: %s", out.String())
+	}
+	logger.Record(context.Background(), "SELECT *\n  FROM users", 150*time.Millisecond, errors.New("boom"))
+	if !strings.Contains(out.String(), "slow query") || !strings.Contains(out.String(), `query="SELECT * FROM users"`) {
+		t.Errorf("the slow query was not logged: %s", out.String())
+	}
+
+	stats := logger.Stats.Snapshot()
+	if stats.Queries != 2 || stats.Slow != 1 || stats.Errors != 1 || stats.Longest != 150*time.Millisecond || stats.TotalDuration != 160*time.Millisecond {
+		t.Errorf("got %+v", stats)
+	}
+}
+
+func TestRecord_LogsEveryQueryWhenAsked(t *testing.T) {
+	var out bytes.Buffer
+	logger := &Logger{Log: slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug})), All: true}
+
+	logger.Record(context.Background(), "SELECT 1", time.Millisecond, nil)
+	if !strings.Contains(out.String(), "level=DEBUG msg=query") {
+		t.Errorf("got %s", out.String())
+	}
+}
+
+// fakeDriver answers every statement, waiting delay first
+type fakeDriver struct {
+	delay time.Duration
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return fakeConn(d), nil
+}
+
+type fakeConn fakeDriver
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	time.Sleep(c.delay)
+	return driver.RowsAffected(1), nil
+}
+
+func (c fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	time.Sleep(c.delay)
+	return fakeRows{}, nil
+}
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string         { return []string{"n"} }
+func (fakeRows) Close() error              { return nil }
+func (fakeRows) Next([]driver.Value) error { return io.EOF }
+
+type fakeConnector struct {
+	driver fakeDriver
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open("") }
+func (c fakeConnector) Driver() driver.Driver                        { return c.driver }
+
+func TestOpenDB_RecordsQueries(t *testing.T) {
+	base := sql.OpenDB(fakeConnector{driver: fakeDriver{delay: 5 * time.Millisecond}})
+	logger := &Logger{Log: slog.New(slog.NewTextHandler(io.Discard, nil)), Threshold: time.Millisecond}
+	db := OpenDB(base, "", logger)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	if _, err := db.ExecContext(context.Background(), "UPDATE users SET active = true"); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.QueryContext(context.Background(), "SELECT n FROM numbers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = rows.Close()
+
+	if stats := logger.Stats.Snapshot(); stats.Queries != 2 || stats.Slow != 2 {
+		t.Errorf("got %+v", stats)
+	}
+}
@@ -0,0 +1,225 @@
+package querylog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+)
+
+// Connector wraps a database/sql driver so that the queries and statements of its
+// connections are recorded by the Logger
+type Connector struct {
+	Base   driver.Driver
+	DSN    string
+	Logger *Logger
+}
+
+// OpenDB opens a *sql.DB using the same driver and dsn as a regular pool but with the
+// queries recorded
+func OpenDB(db *sql.DB, dsn string, logger *Logger) *sql.DB {
+	return sql.OpenDB(&Connector{
+		Base:   db.Driver(),
+		DSN:    dsn,
+		Logger: logger,
+	})
+}
+
+// Connect implements driver.Connector
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Base.Open(c.DSN)
+	if err != nil {
+		return nil, err
+	}
+	return &loggedConn{conn: conn, logger: c.Logger}, nil
+}
+
+// Driver implements driver.Connector. The driver opens logged connections too, so that
+// wrappers built on the pool's driver, such as the tracing one, keep the logging.
+func (c *Connector) Driver() driver.Driver {
+	return loggedDriver{connector: c}
+}
+
+// loggedDriver opens logged connections with the base driver
+type loggedDriver struct {
+	connector *Connector
+}
+
+func (ld loggedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := ld.connector.Base.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &loggedConn{conn: conn, logger: ld.connector.Logger}, nil
+}
+
+// loggedConn forwards to the wrapped connection and records the time of every query
+type loggedConn struct {
+	conn   driver.Conn
+	logger *Logger
+}
+
+func (lc *loggedConn) Prepare(query string) (driver.Stmt, error) {
+	return lc.PrepareContext(context.Background(), query)
+}
+
+func (lc *loggedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if p, ok := lc.conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = lc.conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &loggedStmt{stmt: stmt, query: query, logger: lc.logger}, nil
+}
+
+func (lc *loggedConn) Close() error {
+	return lc.conn.Close()
+}
+
+func (lc *loggedConn) Begin() (driver.Tx, error) {
+	return lc.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (lc *loggedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := lc.conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	// fallback for drivers without BeginTx
+	return lc.conn.Begin()
+}
+
+func (lc *loggedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := lc.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := e.ExecContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		lc.logger.Record(ctx, query, time.Since(start), err)
+	}
+	return result, err
+}
+
+func (lc *loggedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := lc.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		lc.logger.Record(ctx, query, time.Since(start), err)
+	}
+	return rows, err
+}
+
+func (lc *loggedConn) Ping(ctx context.Context) error {
+	if p, ok := lc.conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (lc *loggedConn) ResetSession(ctx context.Context) error {
+	if r, ok := lc.conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (lc *loggedConn) IsValid() bool {
+	if v, ok := lc.conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (lc *loggedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if c, ok := lc.conn.(driver.NamedValueChecker); ok {
+		return c.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// loggedStmt records every execution of a prepared statement
+type loggedStmt struct {
+	stmt   driver.Stmt
+	query  string
+	logger *Logger
+}
+
+func (ls *loggedStmt) Close() error {
+	return ls.stmt.Close()
+}
+
+func (ls *loggedStmt) NumInput() int {
+	return ls.stmt.NumInput()
+}
+
+func (ls *loggedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	start := time.Now()
+	result, err := ls.stmt.Exec(args)
+	ls.logger.Record(context.Background(), ls.query, time.Since(start), err)
+	return result, err
+}
+
+func (ls *loggedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := ls.stmt.Query(args)
+	ls.logger.Record(context.Background(), ls.query, time.Since(start), err)
+	return rows, err
+}
+
+func (ls *loggedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var (
+		result driver.Result
+		err    error
+	)
+	if e, ok := ls.stmt.(driver.StmtExecContext); ok {
+		result, err = e.ExecContext(ctx, args)
+	} else {
+		result, err = ls.stmt.Exec(values(args))
+	}
+	ls.logger.Record(ctx, ls.query, time.Since(start), err)
+	return result, err
+}
+
+func (ls *loggedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var (
+		rows driver.Rows
+		err  error
+	)
+	if q, ok := ls.stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		rows, err = ls.stmt.Query(values(args))
+	}
+	ls.logger.Record(ctx, ls.query, time.Since(start), err)
+	return rows, err
+}
+
+func (ls *loggedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if c, ok := ls.stmt.(driver.NamedValueChecker); ok {
+		return c.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// values converts the arguments for the statements without context support
+func values(args []driver.NamedValue) []driver.Value {
+	converted := make([]driver.Value, len(args))
+	for i, arg := range args {
+		converted[i] = arg.Value
+	}
+	return converted
+}
@@ -0,0 +1,136 @@
+package sauri
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+)
+
+// errorPage is the page rendered by Recoverer when the views have one
+const errorPage = "500.gohtml"
+
+// PanicHook receives the panics recovered by Recoverer, e.g. to report them to Sentry
+type PanicHook func(r *http.Request, recovered interface{}, stack []byte)
+
+// defaultErrorPage is rendered when the application has no 500.gohtml page
+var defaultErrorPage = template.Must(template.New("500").Parse(`<!doctype html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Server Error</title></head>
+<body>
+<h1>Something went wrong</h1>
+<p>The server ran into an error and could not complete your request.</p>
+{{if .}}<p>Request ID: <code>{{.}}</code></p>{{end}}
+</body>
+</html>
+`))
+
+// Recoverer recovers from panics in the handlers: the panic and its stack are logged with the
+// request ID, passed to PanicHook when set, and answered with the JSON error envelope or the
+// 500 page depending on what the client accepts.
+func (s *Sauri) Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// the server aborts the response quietly for this one
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			stack := debug.Stack()
+			s.ErrorLog.Printf("panic: %v request_id=%s %s %s\n%s", rec, RequestIDFrom(r), r.Method, r.URL.Path, stack)
+			s.reportPanic(r, rec, stack)
+
+			// too late to answer when the handler already started writing
+			if ww.Status() != 0 {
+				return
+			}
+			s.renderPanic(w, r)
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
+
+// reportPanic calls PanicHook, a failing hook is logged rather than crashing the recovery
+func (s *Sauri) reportPanic(r *http.Request, rec interface{}, stack []byte) {
+	if s.PanicHook == nil {
+		return
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			s.ErrorLog.Println("panic hook failed:", err)
+		}
+	}()
+	s.PanicHook(r, rec, stack)
+}
+
+// renderPanic answers a recovered panic with a 500
+func (s *Sauri) renderPanic(w http.ResponseWriter, r *http.Request) {
+	if wantsJSON(r) {
+		if s.config.problemDetails {
+			s.errorStatus(w, r, http.StatusInternalServerError)
+			return
+		}
+		_ = s.NewResponse().SetResponseWriter(w).Error(ErrInternal)
+		return
+	}
+
+	if s.renderErrorPage(w, r, errorPage, http.StatusInternalServerError) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	_ = defaultErrorPage.Execute(w, RequestIDFrom(r))
+}
+
+// renderErrorPage renders a page of the application such as 500.gohtml with the status, if
+// the application has the page
+func (s *Sauri) renderErrorPage(w http.ResponseWriter, r *http.Request, name string, status int) (rendered bool) {
+	if s.Renderer == nil || s.Renderer.RendererEngine != "go" {
+		return false
+	}
+	if _, err := os.Stat(filepath.Join(s.Renderer.TemplatesRootPath, "views", "pages", name)); err != nil {
+		return false
+	}
+
+	// a page failing to render falls back to the built-in one
+	defer func() {
+		if err := recover(); err != nil {
+			s.ErrorLog.Println("cannot render the error page:", err)
+			rendered = false
+		}
+	}()
+
+	// render to a buffer first so that the status is not lost to the renderer
+	page := &bufferedResponse{header: http.Header{}}
+	if err := s.Renderer.RenderGoPage(page, r, name, nil); err != nil {
+		s.ErrorLog.Println(fmt.Errorf("cannot render the error page: %w", err))
+		return false
+	}
+
+	w.Header().Set("Content-Type", page.header.Get("Content-Type"))
+	w.WriteHeader(status)
+	_, _ = page.body.WriteTo(w)
+	return true
+}
+
+// bufferedResponse keeps a response in memory
+type bufferedResponse struct {
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header         { return b.header }
+func (b *bufferedResponse) Write(p []byte) (int, error) { return b.body.Write(p) }
+func (b *bufferedResponse) WriteHeader(int)             {}
@@ -0,0 +1,19 @@
+package renderer
+
+import (
+	"context"
+	"net/http"
+)
+
+type cspNonceKey struct{}
+
+// WithCSPNonce returns a copy of the request carrying the Content-Security-Policy nonce
+func WithCSPNonce(r *http.Request, nonce string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), cspNonceKey{}, nonce))
+}
+
+// CSPNonce returns the nonce of the request, empty when SecureHeaders did not set one
+func CSPNonce(r *http.Request) string {
+	nonce, _ := r.Context().Value(cspNonceKey{}).(string)
+	return nonce
+}
@@ -0,0 +1,88 @@
+package renderer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// todo: third party template engine support
+
+// EngineFunc renders the named template using a third party engine (templ, amber, pongo2...)
+type EngineFunc func(w http.ResponseWriter, rr *http.Request, temName string, variable, data any) error
+
+// Component is anything that knows how to render itself, such as a templ component
+type Component interface {
+	Render(ctx context.Context, w io.Writer) error
+}
+
+// RegisterEngine registers a rendering engine under the given name so that it can be
+// selected with RENDER_ENGINE without modifying RenderPage.
+func (r *Renderer) RegisterEngine(name string, fn EngineFunc) error {
+	name = strings.ToLower(name)
+	if name == "go" || name == "jet" {
+		return fmt.Errorf("engine %s is built-in and cannot be replaced", name)
+	}
+	if fn == nil {
+		return fmt.Errorf("engine %s must not be nil", name)
+	}
+
+	r.engines.Store(name, fn)
+	return nil
+}
+
+// engine retrieves a registered engine by name
+func (r *Renderer) engine(name string) (EngineFunc, bool) {
+	fn, ok := r.engines.Load(strings.ToLower(name))
+	if !ok {
+		return nil, false
+	}
+	return fn.(EngineFunc), true
+}
+
+// RenderComponent renders a Component into a buffer and writes it to the browser
+func (r *Renderer) RenderComponent(w http.ResponseWriter, rr *http.Request, c Component) error {
+	buf := new(bytes.Buffer)
+	if err := c.Render(rr.Context(), buf); err != nil {
+		r.logger().Error("cannot render the component", "error", err)
+		http.Error(w, "Error rendering component.", http.StatusInternalServerError)
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if _, err := buf.WriteTo(w); err != nil {
+		r.logger().Error("cannot write the component to the browser", "error", err)
+		return err
+	}
+	return nil
+}
+
+// ComponentEngine adapts a function resolving a template name to a Component into an
+// EngineFunc, so component based engines like templ can be registered
+func (r *Renderer) ComponentEngine(resolve func(temName string, data any) (Component, error)) EngineFunc {
+	return func(w http.ResponseWriter, rr *http.Request, temName string, variable, data any) error {
+		c, err := resolve(temName, data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return r.RenderComponent(w, rr, c)
+	}
+}
+
+// HandlerEngine adapts a function resolving a template name to a raw http.Handler into an EngineFunc
+func (r *Renderer) HandlerEngine(resolve func(temName string, data any) (http.Handler, error)) EngineFunc {
+	return func(w http.ResponseWriter, rr *http.Request, temName string, variable, data any) error {
+		h, err := resolve(temName, data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		h.ServeHTTP(w, rr)
+		return nil
+	}
+}
@@ -0,0 +1,31 @@
+package renderer
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// FlashSessionKey is the session key of the messages of Sauri.Flash, a JSON object of the
+// messages by kind
+const FlashSessionKey = "sauri_flash"
+
+// addFlashes moves the flashed messages from the session to the template data, e.g.
+// {{.Flash.success}}, so that they are shown once
+func (r *Renderer) addFlashes(td *TemplateData, rr *http.Request) {
+	content := r.Session.PopString(rr.Context(), FlashSessionKey)
+	if content == "" {
+		return
+	}
+	var flashes map[string]string
+	if json.Unmarshal([]byte(content), &flashes) != nil {
+		return
+	}
+	if td.Flash == nil {
+		td.Flash = make(map[string]string, len(flashes))
+	}
+	for kind, message := range flashes {
+		if _, ok := td.Flash[kind]; !ok {
+			td.Flash[kind] = message
+		}
+	}
+}
@@ -3,9 +3,10 @@ package renderer
 import (
 	"bytes"
 	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/haskekareem/sauri/htmx"
 	"github.com/justinas/nosurf"
 	"html/template"
-	"log"
 	"net/http"
 	"path/filepath"
 )
@@ -53,7 +54,7 @@ func (r *Renderer) cacheTemplates() {
 	// Ensures the function inside is executed only once
 	r.once.Do(func() {
 		if err := r.ParseTemplates(); err != nil {
-			log.Printf("Failed to load and cache templates: %v\n", err)
+			r.logger().Error("cannot load and cache the templates", "error", err)
 		}
 
 	})
@@ -69,20 +70,45 @@ func (r *Renderer) AddDefaultsData(td *TemplateData, rr *http.Request) *Template
 	td.CSRFToken = nosurf.Token(rr)
 	td.Port = r.Port
 	td.Secure = r.Secure
+	td.IsHTMX = htmx.IsRequest(rr)
+	td.HTMXHeaders = htmx.CSRFHeaders(td.CSRFToken)
+	td.CSPNonce = CSPNonce(rr)
+	td.RequestID = middleware.GetReqID(rr.Context())
+
+	// error pages may be rendered before the session middleware
+	if r.hasSession(rr) {
+		if r.Session.Exists(rr.Context(), "userID") {
+			td.IsUserAuthenticated = true
+		}
 
-	if r.Session.Exists(rr.Context(), "userID") {
-		td.IsUserAuthenticated = true
+		r.addFlashedValidation(td, rr)
+		r.addFlashes(td, rr)
 	}
 
 	return td
 }
 
+// hasSession reports whether the session of the request was loaded, the session manager
+// panics on requests that did not go through it
+func (r *Renderer) hasSession(rr *http.Request) (loaded bool) {
+	if r.Session == nil {
+		return false
+	}
+	defer func() {
+		if recover() != nil {
+			loaded = false
+		}
+	}()
+	r.Session.Status(rr.Context())
+	return true
+}
+
 // getTemplate retrieves the specified template from the cache or loads it if in development mode.
 func (r *Renderer) getTemplate(tempName string) (*template.Template, error) {
-	if r.DevelopmentMode {
+	if r.InDevelopmentMode() {
 		// Reload templates on each request in development mode
 		if err := r.ParseTemplates(); err != nil {
-			log.Printf("error parsing templates: %v\n", err)
+			r.logger().Error("cannot parse the templates", "error", err)
 			return nil, err
 		}
 	} else {
@@ -103,6 +129,17 @@ func (r *Renderer) getTemplate(tempName string) (*template.Template, error) {
 // RenderGoPage retrieves the specified template from the cache or loads it
 // if in development mode and then executes it.
 func (r *Renderer) RenderGoPage(w http.ResponseWriter, rr *http.Request, tmpl string, data any) error {
+	return r.renderGoTemplate(w, rr, tmpl, "", data)
+}
+
+// RenderGoPartial executes only the named block (e.g. "content") of the specified
+// template, which is what htmx requests usually expect.
+func (r *Renderer) RenderGoPartial(w http.ResponseWriter, rr *http.Request, tmpl, block string, data any) error {
+	return r.renderGoTemplate(w, rr, tmpl, block, data)
+}
+
+// renderGoTemplate executes the whole template or just a block of it when block is not empty
+func (r *Renderer) renderGoTemplate(w http.ResponseWriter, rr *http.Request, tmpl, block string, data any) error {
 	// retrieve the specified template
 	tmp, err := r.getTemplate(tmpl)
 	if err != nil {
@@ -126,20 +163,23 @@ func (r *Renderer) RenderGoPage(w http.ResponseWriter, rr *http.Request, tmpl st
 
 	// Execute the template
 	buf := new(bytes.Buffer)
-	if err := tmp.Execute(buf, td); err != nil {
-		log.Printf("error executing template to buffer: %v\n", err)
+	if block == "" {
+		err = tmp.Execute(buf, td)
+	} else {
+		err = tmp.ExecuteTemplate(buf, block, td)
+	}
+	if err != nil {
+		r.logger().Error("cannot execute the template", "template", tmpl, "error", err)
 		http.Error(w, "Error buffer template.", http.StatusInternalServerError)
 		return err
 	}
 
 	// write the content to the web browser
+	// the security headers are set by the SecureHeaders middleware
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.Header().Set("X-XSS-Protection", "1; mode=block")
-	w.Header().Set("X-Frame-Options", "deny")
 
 	if _, err := buf.WriteTo(w); err != nil {
-		log.Printf("error writing template to the browser: %v\n", err)
+		r.logger().Error("cannot write the template to the browser", "template", tmpl, "error", err)
 		http.Error(w, "Error rendering template.", http.StatusInternalServerError)
 		return err
 	}
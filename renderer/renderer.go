@@ -1,13 +1,18 @@
 package renderer
 
 import (
+	"fmt"
 	"github.com/CloudyKit/jet/v6"
 	"github.com/alexedwards/scs/v2"
+	"github.com/haskekareem/sauri/htmx"
+	"github.com/haskekareem/sauri/validator"
 	"html/template"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // Renderer struct to hold templates and custom functions
@@ -22,8 +27,11 @@ type Renderer struct {
 	once              sync.Once
 	CustomFuncs       template.FuncMap
 	DefaultData       *TemplateData
-	DevelopmentMode   bool
+	DevelopmentMode   bool // the mode at startup, see SetDevelopmentMode
 	Session           *scs.SessionManager
+	engines           sync.Map
+	Logger            *slog.Logger // slog.Default when nil
+	development       atomic.Pointer[bool]
 }
 
 type TemplateData struct {
@@ -37,7 +45,27 @@ type TemplateData struct {
 	Port                string
 	ServerName          string
 	FormData            url.Values
-	Errors              map[string][]string
+	Errors              validator.ErrorContainer // e.g. {{.Errors.First "email"}}
+	Old                 validator.OldInput       // input of a failed submission, e.g. {{.Old.Get "email"}}
+	IsHTMX              bool                     // the request was issued by htmx
+	HTMXHeaders         string                   // hx-headers value carrying the CSRF token
+	CSPNonce            string                   // e.g. <script nonce="{{.CSPNonce}}">
+	RequestID           string                   // ID to quote when reporting a problem
+	Flash               map[string]string        // messages of Sauri.Flash by kind, e.g. {{.Flash.success}}
+}
+
+// SetDevelopmentMode turns the development mode on or off while requests are served
+func (r *Renderer) SetDevelopmentMode(on bool) {
+	r.development.Store(&on)
+}
+
+// InDevelopmentMode reports whether the templates are parsed again on every render, the
+// mode of SetDevelopmentMode or DevelopmentMode when it was not called
+func (r *Renderer) InDevelopmentMode() bool {
+	if on := r.development.Load(); on != nil {
+		return *on
+	}
+	return r.DevelopmentMode
 }
 
 // NewTemplateData returns a new instance of TemplateData with all maps initialized.
@@ -54,6 +82,11 @@ func (r *Renderer) NewTemplateData() *TemplateData {
 		ServerName:          "",
 		FormData:            nil,
 		Errors:              nil,
+		Old:                 nil,
+		IsHTMX:              false,
+		HTMXHeaders:         "",
+		CSPNonce:            "",
+		RequestID:           "",
 	}
 }
 
@@ -65,5 +98,34 @@ func (r *Renderer) RenderPage(w http.ResponseWriter, rr *http.Request, temName s
 	case "jet":
 		return r.RenderJetPage(w, rr, temName, variable, data)
 	}
-	return nil
+
+	// fall back to the engines registered at runtime
+	if fn, ok := r.engine(r.RendererEngine); ok {
+		return fn(w, rr, temName, variable, data)
+	}
+	return fmt.Errorf("renderer engine %s is not registered", r.RendererEngine)
+}
+
+// RenderHTMX renders the full page for normal requests and only the partial for htmx
+// requests. With the go engine the partial is a block of the page template, with the
+// other engines it is the name of a separate template.
+func (r *Renderer) RenderHTMX(w http.ResponseWriter, rr *http.Request, temName, partial string, variable, data any) error {
+	htmx.VaryOnRequest(w)
+
+	if !htmx.IsPartial(rr) || partial == "" {
+		return r.RenderPage(w, rr, temName, variable, data)
+	}
+
+	if strings.ToLower(r.RendererEngine) == "go" {
+		return r.RenderGoPartial(w, rr, temName, partial, data)
+	}
+	return r.RenderPage(w, rr, partial, variable, data)
+}
+
+// logger returns the logger of the renderer
+func (r *Renderer) logger() *slog.Logger {
+	if r.Logger == nil {
+		return slog.Default()
+	}
+	return r.Logger
 }
@@ -1,11 +1,13 @@
 package renderer
 
 import (
+	"context"
 	"fmt"
 	"github.com/CloudyKit/jet/v6"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"html/template"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -409,3 +411,60 @@ func Test_RenderGoPage_WithDefaultData(t *testing.T) {
 	defer os.Remove(filepath.Join(pageDir, "defaultdata.page.gohtml"))
 }
 */
+
+type testComponent struct {
+	text string
+}
+
+func (c testComponent) Render(ctx context.Context, w io.Writer) error {
+	_, err := io.WriteString(w, c.text)
+	return err
+}
+
+// Test_RenderPage_RegisteredEngine tests rendering through an engine registered at runtime.
+func Test_RenderPage_RegisteredEngine(t *testing.T) {
+	r := setTestRenderer("component", true, "resources-test")
+
+	err := r.RegisterEngine("component", r.ComponentEngine(func(temName string, data any) (Component, error) {
+		return testComponent{text: "component " + temName}, nil
+	}))
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	err = r.RenderPage(w, req, "home", nil, nil)
+	require.NoError(t, err)
+	assert.Contains(t, w.Body.String(), "component home")
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+
+	// built-in engines cannot be replaced
+	assert.Error(t, r.RegisterEngine("go", r.ComponentEngine(nil)))
+
+	// unknown engines return an error instead of rendering nothing
+	r.RendererEngine = "unknown"
+	assert.Error(t, r.RenderPage(httptest.NewRecorder(), req, "home", nil, nil))
+}
+
+func Test_CSPNonce(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Empty(t, CSPNonce(req))
+
+	req = WithCSPNonce(req, "abc123")
+	assert.Equal(t, "abc123", CSPNonce(req))
+}
+
+func Test_SetDevelopmentMode(t *testing.T) {
+	r := &Renderer{DevelopmentMode: true}
+	assert.True(t, r.InDevelopmentMode())
+
+	// the mode changes while pages are rendered
+	done := make(chan struct{})
+	go func() {
+		r.SetDevelopmentMode(false)
+		close(done)
+	}()
+	_ = r.InDevelopmentMode()
+	<-done
+	assert.False(t, r.InDevelopmentMode())
+}
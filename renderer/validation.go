@@ -0,0 +1,33 @@
+package renderer
+
+import (
+	"encoding/json"
+	"github.com/haskekareem/sauri/validator"
+	"net/http"
+	"net/url"
+)
+
+// session keys of the validation errors and the old input flashed by ValidateForm
+const (
+	ErrorsSessionKey   = "sauri_errors"
+	OldInputSessionKey = "sauri_old_input"
+)
+
+// addFlashedValidation moves the errors and the old input of a failed form submission from
+// the session to the template data, unless the handler already set them
+func (r *Renderer) addFlashedValidation(td *TemplateData, rr *http.Request) {
+	if content := r.Session.PopString(rr.Context(), ErrorsSessionKey); content != "" && td.Errors == nil {
+		var errs validator.ErrorContainer
+		if json.Unmarshal([]byte(content), &errs) == nil {
+			td.Errors = errs
+		}
+	}
+
+	if content := r.Session.PopString(rr.Context(), OldInputSessionKey); content != "" && td.Old == nil {
+		var old validator.OldInput
+		if json.Unmarshal([]byte(content), &old) == nil {
+			td.Old = old
+			td.FormData = url.Values(old)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package sauri
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID assigns every request an ID, reusing the one sent by a proxy when it looks sane.
+// The ID is put in the context, where middleware.GetReqID and RequestIDFrom find it, and
+// sent back in the X-Request-ID header.
+func (s *Sauri) RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), middleware.RequestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFrom returns the ID assigned to the request by RequestID
+func RequestIDFrom(r *http.Request) string {
+	return middleware.GetReqID(r.Context())
+}
+
+// RequestLogger logs one record per request with the method, path, status, bytes, duration,
+// request ID and, when known, the trace, user and session. Place it after SessionLoad so
+// that the session can be read.
+func (s *Sauri) RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			// a panic is answered with a 500 further up, log it as such and let it go on
+			rec := recover()
+			if rec != nil {
+				status = http.StatusInternalServerError
+				defer panic(rec)
+			}
+
+			fields := []any{
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", status,
+				"bytes", ww.BytesWritten(),
+				"duration", time.Since(start),
+				"request_id", RequestIDFrom(r),
+				"remote", r.RemoteAddr,
+			}
+			if span := trace.SpanContextFromContext(r.Context()); span.IsValid() {
+				fields = append(fields, "trace_id", span.TraceID().String())
+			}
+			fields = append(fields, s.sessionLogFields(r)...)
+
+			if status >= http.StatusInternalServerError {
+				s.log().Error("request", fields...)
+			} else {
+				s.log().Info("request", fields...)
+			}
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
+
+// sessionLogFields returns the user and session of the request, nothing outside SessionLoad
+func (s *Sauri) sessionLogFields(r *http.Request) (fields []any) {
+	if s.Session == nil {
+		return nil
+	}
+	// the session manager panics when the request did not go through LoadAndSave
+	defer func() {
+		if recover() != nil {
+			fields = nil
+		}
+	}()
+
+	if s.Session.Exists(r.Context(), "userID") {
+		fields = append(fields, "user_id", fmt.Sprint(s.Session.Get(r.Context(), "userID")))
+	}
+	if token := s.Session.Token(r.Context()); token != "" {
+		// a prefix is enough to correlate requests without leaking the token
+		fields = append(fields, "session", token[:min(len(token), 8)])
+	}
+	return fields
+}
+
+// validRequestID accepts short IDs made of letters, digits, dashes, dots and underscores
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random request ID
+func newRequestID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
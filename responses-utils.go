@@ -4,27 +4,43 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"errors"
+	"github.com/haskekareem/sauri/storage"
 	"io"
-	"mime/multipart"
+	"mime"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
+	"time"
 )
 
 const contentType = "Content-Type"
 
 // Response struct holds the http.ResponseWriter and a map of headers
 type Response struct {
-	Rw http.ResponseWriter
-	Hd http.Header
+	Rw   http.ResponseWriter
+	Hd   http.Header
+	Disk storage.Disk // where uploads go and downloads come from, the working directory when nil
+
+	cookies secureCookies
 }
 
 // NewResponse Initializes a new Response object.
 func (s *Sauri) NewResponse() *Response {
 	return &Response{
-		Hd: make(http.Header),
+		Hd:      make(http.Header),
+		Disk:    s.Storage,
+		cookies: s.secureCookies(),
+	}
+}
+
+// disk returns the storage disk of the response
+func (r *Response) disk() storage.Disk {
+	if r.Disk == nil {
+		return storage.NewLocal("", "", false, nil)
 	}
+	return r.Disk
 }
 
 // WriteJSON sets the content type to JSON, marshals the data,
@@ -59,18 +75,31 @@ func (s *Sauri) WriteJSON(w http.ResponseWriter, statusCode int, data interface{
 	return nil
 }
 
-func (s *Sauri) ReadJSON(w http.ResponseWriter, r *http.Request, data interface{}) error {
-	maxByte := 1048576 // one megabyte
-	r.Body = http.MaxBytesReader(w, r.Body, int64(maxByte))
+// ReadJSON decodes a single JSON value of at most one megabyte from the request body into
+// data. The limit and the handling of unknown fields can be changed with JSONOptions.
+// Decoding errors are returned as a *JSONError with a message fit for the client.
+func (s *Sauri) ReadJSON(w http.ResponseWriter, r *http.Request, data interface{}, opts ...JSONOptions) error {
+	var options JSONOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	maxBytes := options.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = 1048576 // one megabyte
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
 
 	dec := json.NewDecoder(r.Body)
+	if options.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
 	if err := dec.Decode(data); err != nil {
-		return err
+		return jsonError(err, maxBytes)
 	}
 
 	err := dec.Decode(&struct{}{})
 	if err != io.EOF {
-		return errors.New("invalid JSON: body must have a single json value")
+		return &JSONError{Message: "body must only contain a single JSON value", Err: err}
 	}
 
 	return nil
@@ -102,15 +131,8 @@ func (r *Response) WithHeaders(headers http.Header) *Response {
 // Send writes all headers and the content to the response.
 // It sets the status code and then writes the content.
 func (r *Response) Send(content []byte, statusCode int) error {
-
-	for key, values := range r.Hd {
-		for _, value := range values {
-			r.Rw.Header().Add(key, value)
-		}
-	}
-
-	// Write the HTTP status code to the response
-	r.Rw.WriteHeader(statusCode)
+	// Write the headers and the HTTP status code to the response
+	r.writeHeaders(statusCode)
 	// Write the response content
 	_, err := r.Rw.Write(content)
 	if err != nil {
@@ -244,20 +266,76 @@ func (r *Response) JSONP(data interface{}, callback string, statusCode int) erro
 	return nil
 }
 
-// DownloadFile method sets headers for downloading a file and
-// streams it to the client
+// DownloadFile method sets headers for downloading a file of the storage disk and
+// streams it to the client, range requests let clients resume interrupted downloads.
+// pathToFile is a directory of the storage disk; an absolute one is a directory of the file
+// system, as before the disks, while a relative one was the working directory then.
 func (r *Response) DownloadFile(pathToFile, fileName string, rr *http.Request) error {
-	// Open the file specified by filePath
-	filePath := path.Join(pathToFile, fileName)
-	fileToServe := filepath.Clean(filePath)
+	if filepath.IsAbs(pathToFile) {
+		return r.inDirectory(pathToFile).DownloadFile("", fileName, rr)
+	}
+	r.Rw.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	return r.serveFromDisk(path.Join(pathToFile, fileName), fileName, rr)
+}
 
-	r.Rw.Header().Set("Content-Disposition", "attachment; filename=\""+fileName+"\"")
+// ServeFile method streams a file of the storage disk to be displayed in the browser, with
+// range requests so that videos can be seeked and PDFs read page by page. An absolute
+// pathToFile is a directory of the file system, see DownloadFile.
+func (r *Response) ServeFile(pathToFile, fileName string, rr *http.Request) error {
+	if filepath.IsAbs(pathToFile) {
+		return r.inDirectory(pathToFile).ServeFile("", fileName, rr)
+	}
+	r.Rw.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": fileName}))
+	return r.serveFromDisk(path.Join(pathToFile, fileName), fileName, rr)
+}
 
-	http.ServeFile(r.Rw, rr, fileToServe)
+// serveFromDisk writes a file of the storage disk with http.ServeContent, which takes care of
+// Range, If-Modified-Since, Content-Type and Content-Length
+func (r *Response) serveFromDisk(filePath, fileName string, rr *http.Request) error {
+	for key, values := range r.Hd {
+		r.Rw.Header()[key] = values
+	}
 
+	file, err := r.disk().Get(rr.Context(), filePath)
+	if errors.Is(err, storage.ErrNotFound) {
+		r.Error404()
+		return err
+	}
+	if err != nil {
+		r.Error500()
+		return err
+	}
+	defer func(file io.ReadCloser) {
+		_ = file.Close()
+	}(file)
+
+	seeker, ok := file.(io.ReadSeeker)
+	if !ok {
+		// readers of other disks are streamed as a whole
+		if mimeType := mime.TypeByExtension(filepath.Ext(fileName)); mimeType != "" {
+			r.Rw.Header().Set(contentType, mimeType)
+		}
+		_, err = io.Copy(r.Rw, file)
+		return err
+	}
+
+	http.ServeContent(r.Rw, rr, fileName, modTime(file), seeker)
 	return nil
 }
 
+// modTime returns the modification time of a file of the storage disk when it is known
+func modTime(file io.Reader) time.Time {
+	switch f := file.(type) {
+	case *os.File:
+		if info, err := f.Stat(); err == nil {
+			return info.ModTime()
+		}
+	case interface{ ModTime() time.Time }:
+		return f.ModTime()
+	}
+	return time.Time{}
+}
+
 // StreamDownload method uses a callback function to stream data to the client
 // as a download
 func (r *Response) StreamDownload(callBack func(writer io.Writer), fileName string, headers map[string]string) error {
@@ -275,20 +353,41 @@ func (r *Response) StreamDownload(callBack func(writer io.Writer), fileName stri
 }
 
 // File method sets headers for displaying a file in the browser
-// and streams it to the client
+// and streams it to the client. Use ServeFile to answer range requests.
 func (r *Response) File(fileRoad, fileName string, headers map[string]string) error {
 	filePath := path.Join(fileRoad, fileName)
 	fileToShow := filepath.Clean(filePath)
 
 	file, err := os.Open(fileToShow)
 	if err != nil {
-		http.Error(r.Rw, "file not found", http.StatusInternalServerError)
+		http.Error(r.Rw, "file not found", http.StatusNotFound)
 		return err
 	}
 	defer func(file *os.File) {
 		_ = file.Close()
 	}(file)
 
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(r.Rw, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+
+	// the content type comes from the extension, or from the first bytes of the file
+	mimeType := mime.TypeByExtension(filepath.Ext(fileName))
+	if mimeType == "" {
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(file, head)
+		mimeType = http.DetectContentType(head[:n])
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			http.Error(r.Rw, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+	}
+	r.Rw.Header().Set(contentType, mimeType)
+	r.Rw.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	r.Rw.Header().Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+
 	for key, value := range headers {
 		r.Rw.Header().Set(key, value)
 	}
@@ -296,36 +395,35 @@ func (r *Response) File(fileRoad, fileName string, headers map[string]string) er
 	r.Rw.WriteHeader(http.StatusOK)
 
 	if _, err := io.Copy(r.Rw, file); err != nil {
-		http.Error(r.Rw, err.Error(), http.StatusInternalServerError)
 		return err
 	}
 	return nil
 }
 
-// HandleFileUpload handles file uploads and saves them to the specified directory
+// HandleFileUpload stores the uploaded file under uploadDir on the storage disk with its
+// sanitized name, see UploadFile, and returns its path on the disk. An absolute uploadDir is
+// a directory of the file system, as before the disks, and the file system path of the file
+// is returned; a relative one was the working directory then.
 func (r *Response) HandleFileUpload(fieldName, uploadDir string, req *http.Request) (string, error) {
-	file, fileHeader, err := req.FormFile(fieldName)
-	if err != nil {
-		return "", err
+	if filepath.IsAbs(uploadDir) {
+		filePath, err := r.inDirectory(uploadDir).HandleFileUpload(fieldName, "", req)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(uploadDir, filepath.FromSlash(filePath)), nil
 	}
-	defer func(file multipart.File) {
-		_ = file.Close()
-	}(file)
-
-	filePath := filepath.Join(uploadDir, fileHeader.Filename)
-	outFile, err := os.Create(filePath)
+	uploaded, err := r.UploadFile(fieldName, uploadDir, req, UploadOptions{})
 	if err != nil {
 		return "", err
 	}
-	defer func(outFile *os.File) {
-		_ = outFile.Close()
-	}(outFile)
+	return uploaded.Path, nil
+}
 
-	if _, err := io.Copy(outFile, file); err != nil {
-		http.Error(r.Rw, err.Error(), http.StatusInternalServerError)
-		return "", err
-	}
-	return filePath, nil
+// inDirectory returns a copy of the response whose disk is a directory of the file system
+func (r *Response) inDirectory(dir string) *Response {
+	local := *r
+	local.Disk = storage.NewLocal(dir, "", false, nil)
+	return &local
 }
 
 // =================== errors for the response =================
@@ -354,22 +452,22 @@ func (r *Response) errorStatus(status int) {
 
 // Error404 returns page not found response
 func (s *Sauri) Error404(w http.ResponseWriter, r *http.Request) {
-	s.ErrorStatus(w, http.StatusNotFound)
+	s.errorStatus(w, r, http.StatusNotFound)
 }
 
 // Error500 returns internal server error response
 func (s *Sauri) Error500(w http.ResponseWriter, r *http.Request) {
-	s.ErrorStatus(w, http.StatusInternalServerError)
+	s.errorStatus(w, r, http.StatusInternalServerError)
 }
 
 // ErrorUnauthorized sends an unauthorized status (client is not known)
 func (s *Sauri) ErrorUnauthorized(w http.ResponseWriter, r *http.Request) {
-	s.ErrorStatus(w, http.StatusUnauthorized)
+	s.errorStatus(w, r, http.StatusUnauthorized)
 }
 
 // ErrorForbidden returns a forbidden status message (client is known)
 func (s *Sauri) ErrorForbidden(w http.ResponseWriter, r *http.Request) {
-	s.ErrorStatus(w, http.StatusForbidden)
+	s.errorStatus(w, r, http.StatusForbidden)
 }
 
 // ErrorStatus returns a response with the supplied http status
@@ -3,21 +3,58 @@ package sauri
 import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/haskekareem/sauri/chaos"
+	"github.com/haskekareem/sauri/storage"
+	"github.com/haskekareem/sauri/tracing"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 // defaultRouter built-in routes for the package
 func (s *Sauri) defaultRouter() http.Handler {
 	mux := chi.NewRouter()
-	mux.Use(middleware.RequestID)
+	mux.Use(s.RequestID)
+	mux.Use(keepConnAddr) // the maintenance allow-list trusts no forwarded header
 	mux.Use(middleware.RealIP)
-	if s.DebugMode {
-		mux.Use(middleware.Logger)
+
+	// a span per request when OpenTelemetry tracing is enabled, outside the recoverer to see its 500
+	if s.tracer != nil {
+		mux.Use(tracing.Middleware(s.tracer))
+	}
+
+	mux.Use(s.Recoverer)
+	mux.Use(s.Maintenance) // sauri down
+	mux.Use(s.SecureHeaders(s.secureHeadersFromEnv()))
+
+	// fault injection for resilience testing, off unless CHAOS_ENABLED is set in debug mode
+	if settings, ok := s.chaosFromEnv(); ok {
+		mux.Use(chaos.Middleware(settings.http))
 	}
 
-	mux.Use(middleware.Recoverer)
 	mux.Use(s.SessionLoad) // load and save session data
 	mux.Use(s.NoSurf)
 
+	// one structured line per request, always in debug mode
+	if s.Config.GetBool("LOG_REQUESTS", false) || s.IsDebugMode() {
+		mux.Use(s.RequestLogger)
+	}
+
+	// expose the metrics endpoint only when asked for
+	if enabled, _ := strconv.ParseBool(s.config.metricsEnabled); enabled {
+		mux.Get("/sauri/metrics", s.DBPoolMetrics)
+	}
+
+	// the route and task lists read by sauri routes and sauri schedule:list, in debug mode only
+	if s.IsDebugMode() {
+		mux.Get("/sauri/routes", s.RoutesHandler)
+		mux.Get("/sauri/schedule", s.ScheduleHandler)
+	}
+
+	// serve the files of a local disk, private files only through temporary URLs
+	if local, ok := s.Storage.(*storage.Local); ok && strings.HasPrefix(local.BaseURL, "/") {
+		mux.Handle(local.BaseURL+"/*", http.StripPrefix(local.BaseURL, local.Handler()))
+	}
+
 	return mux
 }
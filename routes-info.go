@@ -0,0 +1,136 @@
+package sauri
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// closureSuffix matches the suffix the compiler gives to closures and method values
+var closureSuffix = regexp.MustCompile(`(\.func\d+)+$|-fm$`)
+
+// RouteInfo describes a mounted route
+type RouteInfo struct {
+	Method      string   `json:"method"`
+	Pattern     string   `json:"pattern"`
+	Name        string   `json:"name,omitempty"`
+	Handler     string   `json:"handler"`
+	Middlewares []string `json:"middlewares"`
+}
+
+// Routes lists every route of the router, the framework ones included, sorted by pattern
+func (s *Sauri) Routes() ([]RouteInfo, error) {
+	if s.Router == nil {
+		return nil, nil
+	}
+
+	names := make(map[string]string)
+	s.routeNames.mu.RLock()
+	for name, route := range s.routeNames.routes {
+		names[route.Method+" "+route.Pattern] = name
+	}
+	s.routeNames.mu.RUnlock()
+
+	var routes []RouteInfo
+	err := chi.Walk(s.Router, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		info := RouteInfo{
+			Method:      method,
+			Pattern:     route,
+			Name:        names[method+" "+route],
+			Handler:     handlerName(handler),
+			Middlewares: make([]string, 0, len(middlewares)),
+		}
+		for _, middleware := range middlewares {
+			info.Middlewares = append(info.Middlewares, funcName(middleware))
+		}
+		routes = append(routes, info)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot walk the routes: %w", err)
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return collapseAnyMethod(routes), nil
+}
+
+// anyMethodCount is the number of methods chi lists a route mounted with Handle under
+const anyMethodCount = 9
+
+// collapseAnyMethod lists the routes answering every method once, with the method ANY
+func collapseAnyMethod(routes []RouteInfo) []RouteInfo {
+	collapsed := make([]RouteInfo, 0, len(routes))
+	for i := 0; i < len(routes); {
+		j := i
+		for j < len(routes) && routes[j].Pattern == routes[i].Pattern && routes[j].Handler == routes[i].Handler {
+			j++
+		}
+		if j-i >= anyMethodCount {
+			route := routes[i]
+			route.Method = "ANY"
+			collapsed = append(collapsed, route)
+		} else {
+			collapsed = append(collapsed, routes[i:j]...)
+		}
+		i = j
+	}
+	return collapsed
+}
+
+// RoutesHandler serves the route list as JSON, used by sauri routes
+func (s *Sauri) RoutesHandler(w http.ResponseWriter, r *http.Request) {
+	routes, err := s.Routes()
+	if err != nil {
+		s.ErrorLog.Println(err)
+		s.Error500(w, r)
+		return
+	}
+
+	w.Header().Set(contentType, "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"routes": routes}); err != nil {
+		s.ErrorLog.Println("cannot write the routes:", err)
+	}
+}
+
+// handlerName returns the name of the function or type behind a handler
+func handlerName(handler http.Handler) string {
+	for {
+		switch h := handler.(type) {
+		case *chi.ChainHandler:
+			handler = h.Endpoint
+			continue
+		case http.HandlerFunc:
+			return funcName(h)
+		}
+		return strings.TrimPrefix(reflect.TypeOf(handler).String(), "*")
+	}
+}
+
+// funcName returns the short name of a function, e.g. controller.(*Controller).Home
+func funcName(fn interface{}) string {
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func || value.IsNil() {
+		return fmt.Sprintf("%T", fn)
+	}
+	f := runtime.FuncForPC(value.Pointer())
+	if f == nil {
+		return "unknown"
+	}
+
+	name := closureSuffix.ReplaceAllString(f.Name(), "")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
@@ -0,0 +1,230 @@
+package sauri
+
+import (
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// routeParam matches the {name} and {name:regexp} parameters of a route pattern
+var routeParam = regexp.MustCompile(`\{([^{}:]+)(?::[^{}]*(?:\{[^{}]*\}[^{}]*)*)?\}`)
+
+// routeNames maps the route names to their routes
+type routeNames struct {
+	mu     sync.RWMutex
+	routes map[string]*Route
+}
+
+// RouteGroup registers routes under a common prefix and middleware stack
+type RouteGroup struct {
+	app    *Sauri
+	router chi.Router
+	prefix string
+}
+
+// Route is a registered route, name it to generate its URL with Sauri.URL
+type Route struct {
+	app     *Sauri
+	Method  string
+	Pattern string // full pattern, including the prefix of the groups
+}
+
+// Name names the route, e.g. "users.show"
+func (rt *Route) Name(name string) *Route {
+	rt.app.routeNames.mu.Lock()
+	defer rt.app.routeNames.mu.Unlock()
+	if rt.app.routeNames.routes == nil {
+		rt.app.routeNames.routes = make(map[string]*Route)
+	}
+	rt.app.routeNames.routes[name] = rt
+	return rt
+}
+
+// root returns the group of the application router
+func (s *Sauri) root() *RouteGroup {
+	return &RouteGroup{app: s, router: s.Router}
+}
+
+// Get registers a GET route
+func (s *Sauri) Get(pattern string, handler http.HandlerFunc) *Route {
+	return s.root().Get(pattern, handler)
+}
+
+// Post registers a POST route
+func (s *Sauri) Post(pattern string, handler http.HandlerFunc) *Route {
+	return s.root().Post(pattern, handler)
+}
+
+// Put registers a PUT route
+func (s *Sauri) Put(pattern string, handler http.HandlerFunc) *Route {
+	return s.root().Put(pattern, handler)
+}
+
+// Patch registers a PATCH route
+func (s *Sauri) Patch(pattern string, handler http.HandlerFunc) *Route {
+	return s.root().Patch(pattern, handler)
+}
+
+// Delete registers a DELETE route
+func (s *Sauri) Delete(pattern string, handler http.HandlerFunc) *Route {
+	return s.root().Delete(pattern, handler)
+}
+
+// Route registers the routes added by fn under the prefix
+func (s *Sauri) Route(prefix string, fn func(g *RouteGroup)) {
+	s.root().Route(prefix, fn)
+}
+
+// Web registers the routes of the browser facing part of the application. They go through
+// the session and CSRF middlewares like every route, plus the given ones.
+func (s *Sauri) Web(fn func(g *RouteGroup), middlewares ...func(http.Handler) http.Handler) {
+	s.root().Group(func(g *RouteGroup) {
+		g.Use(middlewares...)
+		fn(g)
+	})
+}
+
+// API registers routes under the prefix, e.g. "/api", that are exempt from the CSRF check since
+// API clients authenticate with tokens rather than cookies
+func (s *Sauri) API(prefix string, fn func(g *RouteGroup), middlewares ...func(http.Handler) http.Handler) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	s.exemptFromCSRF(prefix, prefix+"/*")
+	s.root().Route(prefix, func(g *RouteGroup) {
+		g.Use(middlewares...)
+		fn(g)
+	})
+}
+
+// Use adds middlewares to the group, before its routes are registered
+func (g *RouteGroup) Use(middlewares ...func(http.Handler) http.Handler) {
+	if len(middlewares) > 0 {
+		g.router.Use(middlewares...)
+	}
+}
+
+// Route registers the routes added by fn under a prefix of the group
+func (g *RouteGroup) Route(prefix string, fn func(g *RouteGroup)) {
+	g.router.Route(prefix, func(r chi.Router) {
+		fn(&RouteGroup{app: g.app, router: r, prefix: joinPattern(g.prefix, prefix)})
+	})
+}
+
+// Group registers the routes added by fn with their own middlewares and no prefix
+func (g *RouteGroup) Group(fn func(g *RouteGroup)) {
+	g.router.Group(func(r chi.Router) {
+		fn(&RouteGroup{app: g.app, router: r, prefix: g.prefix})
+	})
+}
+
+// Get registers a GET route
+func (g *RouteGroup) Get(pattern string, handler http.HandlerFunc) *Route {
+	return g.Method(http.MethodGet, pattern, handler)
+}
+
+// Post registers a POST route
+func (g *RouteGroup) Post(pattern string, handler http.HandlerFunc) *Route {
+	return g.Method(http.MethodPost, pattern, handler)
+}
+
+// Put registers a PUT route
+func (g *RouteGroup) Put(pattern string, handler http.HandlerFunc) *Route {
+	return g.Method(http.MethodPut, pattern, handler)
+}
+
+// Patch registers a PATCH route
+func (g *RouteGroup) Patch(pattern string, handler http.HandlerFunc) *Route {
+	return g.Method(http.MethodPatch, pattern, handler)
+}
+
+// Delete registers a DELETE route
+func (g *RouteGroup) Delete(pattern string, handler http.HandlerFunc) *Route {
+	return g.Method(http.MethodDelete, pattern, handler)
+}
+
+// Method registers a route for any HTTP method
+func (g *RouteGroup) Method(method, pattern string, handler http.Handler) *Route {
+	g.router.Method(method, pattern, handler)
+	return &Route{app: g.app, Method: method, Pattern: joinPattern(g.prefix, pattern)}
+}
+
+// URL returns the path of a named route, the params are name/value pairs filling in the route
+// parameters, e.g. s.URL("users.show", "id", 42). Pairs not in the pattern become the query.
+func (s *Sauri) URL(name string, params ...interface{}) (string, error) {
+	s.routeNames.mu.RLock()
+	route, ok := s.routeNames.routes[name]
+	s.routeNames.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("route %q is not defined", name)
+	}
+	pattern := route.Pattern
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("route %q: params must be name/value pairs", name)
+	}
+
+	values := make(map[string]string, len(params)/2)
+	var order []string
+	for i := 0; i < len(params); i += 2 {
+		key := fmt.Sprint(params[i])
+		values[key] = fmt.Sprint(params[i+1])
+		order = append(order, key)
+	}
+
+	var missing []string
+	used := make(map[string]bool)
+	u := routeParam.ReplaceAllStringFunc(pattern, func(param string) string {
+		key := routeParam.FindStringSubmatch(param)[1]
+		value, ok := values[key]
+		if !ok {
+			missing = append(missing, key)
+			return param
+		}
+		used[key] = true
+		return url.PathEscape(value)
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("route %q: missing %s", name, strings.Join(missing, ", "))
+	}
+
+	// a trailing wildcard takes the "*" param, unescaped so that it can hold a path
+	if strings.HasSuffix(u, "*") {
+		used["*"] = true
+		u = strings.TrimSuffix(u, "*") + strings.TrimPrefix(values["*"], "/")
+	}
+
+	query := url.Values{}
+	for _, key := range order {
+		if !used[key] {
+			query.Add(key, values[key])
+		}
+	}
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u, nil
+}
+
+// routeURL is the route template helper, errors are logged and give an empty URL
+func (s *Sauri) routeURL(name string, params ...interface{}) string {
+	u, err := s.URL(name, params...)
+	if err != nil {
+		s.ErrorLog.Println(err)
+	}
+	return u
+}
+
+// joinPattern joins a group prefix and a pattern, keeping a trailing slash of the pattern
+func joinPattern(prefix, pattern string) string {
+	if prefix == "" {
+		return pattern
+	}
+	joined := path.Join(prefix, pattern)
+	if strings.HasSuffix(pattern, "/") && !strings.HasSuffix(joined, "/") {
+		joined += "/"
+	}
+	return joined
+}
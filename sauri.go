@@ -1,16 +1,30 @@
 package sauri
 
 import (
+	"fmt"
 	"github.com/CloudyKit/jet/v6"
 	"github.com/alexedwards/scs/v2"
 	"github.com/dgraph-io/badger/v3"
 	"github.com/go-chi/chi/v5"
+	"github.com/haskekareem/sauri/auth"
 	"github.com/haskekareem/sauri/cache"
+	"github.com/haskekareem/sauri/config"
+	"github.com/haskekareem/sauri/events"
+	"github.com/haskekareem/sauri/jobs"
+	"github.com/haskekareem/sauri/jwt"
+	"github.com/haskekareem/sauri/logging"
+	"github.com/haskekareem/sauri/mailer"
+	"github.com/haskekareem/sauri/querylog"
 	"github.com/haskekareem/sauri/renderer"
+	"github.com/haskekareem/sauri/scheduler"
+	"github.com/haskekareem/sauri/storage"
+	"github.com/haskekareem/sauri/websocket"
+	"go.opentelemetry.io/otel/trace"
 	"log"
-	"os"
+	"log/slog"
 	"path/filepath"
-	"strconv"
+	"sync"
+	"sync/atomic"
 )
 
 const version = "1.0.0"
@@ -21,11 +35,13 @@ var badgerPool *badger.DB
 
 type Sauri struct {
 	AppName       string
-	DebugMode     bool
+	DebugMode     bool // the debug mode at startup, IsDebugMode follows DEBUG_MODE reloads
 	Version       string
 	InfoLog       *log.Logger
-	ErrorLog      *log.Logger
+	ErrorLog      *log.Logger // InfoLog and ErrorLog write to Logger
+	Logger        logging.Logger
 	RootPath      string
+	Config        *config.Config // typed access to the settings, see config/*.yaml
 	config        sauriConfigs
 	EncryptionKey string
 	Cache         cache.Cache
@@ -35,7 +51,29 @@ type Sauri struct {
 	Session       *scs.SessionManager // session management
 	DBConn        DatabaseConn
 	Responses     *Response
-	//Mailer        *mails.Mailer
+	Leader        *LeaderElector       // nil when no shared cache is used
+	Storage       storage.Disk         // where uploads go, see STORAGE_DISK
+	PanicHook     PanicHook            // receives the panics recovered by Recoverer
+	Jobs          *jobs.Manager        // background jobs, see JOBS_QUEUE
+	Scheduler     *scheduler.Scheduler // cron tasks, see Schedule
+	Events        *events.Bus          // queued listeners run as jobs
+	Auth          *auth.Auth           // nil when no database is in use
+	JWT           *jwt.Manager         // nil unless JWT_SECRET or JWT_PRIVATE_KEY is set
+	Mail          *mailer.Mailer       // nil unless MAIL_DRIVER is set
+	lifecycle     lifecycle
+	routeNames    routeNames
+	csrfExempt    csrfExemptions
+	maintenance   maintenanceState
+	hubs          []*websocket.Hub
+	hubsMu        sync.Mutex
+	tracer        trace.Tracer   // nil unless the OTEL_* variables enable tracing
+	encryption    Encryption     // see Encrypter
+	databases     map[string]*DB // see DB
+	queryLog      *querylog.Logger
+	seeders       []namedSeeder         // see AddSeeder
+	console       []namedConsoleCommand // see AddConsoleCommand
+	logLevel      slog.LevelVar
+	debug         atomic.Bool // see IsDebugMode
 }
 
 // NewApp is the main project setup
@@ -57,6 +95,9 @@ func (s *Sauri) NewApp(currentRootPath string) error {
 			"pkg/utils",           // shared utility functions
 			"public",              // static files (CSS/JS/images)
 			"resources/views",     // template files
+			"resources/mails",     // email templates
+			"storage/app",         // files of the local storage disk
+			"storage/framework",   // framework state such as the maintenance flag
 			"storage/logs",        // log storage
 			"storage/uploads",     // file uploads
 			"test",                // test files
@@ -85,20 +126,40 @@ func (s *Sauri) NewApp(currentRootPath string) error {
 	}
 
 	// todo: if there is a .env file then read its content and put it in the env variable
-	err = s.LoadAndSetEnv(filepath.Join(currentRootPath, ".env"))
+	err = s.LoadAndSetEnv(config.EnvFiles(currentRootPath)...)
+	if err != nil {
+		return err
+	}
+	s.Config = config.New()
+	// secrets kept encrypted by sauri env:encrypt
+	err = s.loadEncryptedEnv(currentRootPath)
+	if err != nil {
+		return err
+	}
+	// the config/*.yaml files fill in the settings the environment leaves out
+	err = s.Config.LoadYAML(filepath.Join(currentRootPath, "config"))
+	if err != nil {
+		return err
+	}
+	err = s.ValidateConfig()
 	if err != nil {
 		return err
 	}
 
 	//todo: create customised loggers for the project
-	infoLog, errorLog := s.createLoggers()
+	infoLog, errorLog := s.createLoggers(currentRootPath)
 
-	s.Responses = s.NewResponse()
+	// the disk uploads are stored on
+	s.Storage, err = s.createStorage(currentRootPath)
+	if err != nil {
+		errorLog.Println("Cannot create storage:", err)
+		return err
+	}
 
 	// todo: call OpenDBConnectionPool to connect to the DB
 
 	// Check if the user wants to use the database
-	dbUse, _ := strconv.ParseBool(os.Getenv("DATABASE_USE"))
+	dbUse := s.Config.GetBool("DATABASE_USE", false)
 	// Initialize dsn and dbDriverType with safe defaults
 	var (
 		dsn          string
@@ -106,7 +167,7 @@ func (s *Sauri) NewApp(currentRootPath string) error {
 	)
 	// Only build DSN and connect to DB if user enabled it
 	if dbUse {
-		dbDriverType = os.Getenv("DATABASE_TYPE")
+		dbDriverType = s.Config.Get("DATABASE_TYPE")
 
 		// Build DSN based on environment variables
 		var err error
@@ -119,7 +180,7 @@ func (s *Sauri) NewApp(currentRootPath string) error {
 		sqlDB, pgxPool, err := s.OpenDBConnectionPool(dbDriverType, dsn)
 		if err != nil {
 			errorLog.Println("Cannot open DB connection pool:", err)
-			os.Exit(1)
+			return fmt.Errorf("cannot open database connection pool: %w", err)
 		}
 		// Populate database in the Sauri structure
 		s.DBConn = DatabaseConn{
@@ -133,25 +194,25 @@ func (s *Sauri) NewApp(currentRootPath string) error {
 		infoLog.Println("DATABASE_USE is set to false. Skipping database connection...")
 
 		// Still set DSN and DB type safely for config consistency
-		dbDriverType = os.Getenv("DATABASE_TYPE")
+		dbDriverType = s.Config.Get("DATABASE_TYPE")
 		dsn = "" // or a placeholder like "disabled"
 	}
 
 	// todo connect to redis server
-	if os.Getenv("CACHE") == "redis" || os.Getenv("SESSION_STORE_TYPE") == "redis" {
+	if s.Config.Get("CACHE") == "redis" || s.Config.Get("SESSION_STORE_TYPE") == "redis" {
 		myRedisCache = s.initializeClientRedisCache()
 		s.Cache = myRedisCache
 	}
 
 	// todo connect to badger database
-	if os.Getenv("CACHE") == "badger" {
-		myBadgerCache = s.initializeClientBadgerCache()
+	if s.Config.Get("CACHE") == "badger" {
+		myBadgerCache, err = s.initializeClientBadgerCache()
+		if err != nil {
+			errorLog.Println("Cannot open badger cache:", err)
+			return err
+		}
 		s.Cache = myBadgerCache
 		badgerPool = myBadgerCache.DBConn
-		// set periodic garbage collection once a day
-		//_, err = s.Mailer.Scheduler.C.AddFunc("@daily", func() {
-		//	_ = myBadgerCache.DBConn.RunValueLogGC(0.7)
-		//})
 	}
 
 	/*if err != nil {
@@ -160,38 +221,92 @@ func (s *Sauri) NewApp(currentRootPath string) error {
 
 	s.InfoLog = infoLog
 	s.ErrorLog = errorLog
-	s.DebugMode, _ = strconv.ParseBool(os.Getenv("DEBUG_MODE"))
+	s.DebugMode = s.Config.GetBool("DEBUG_MODE", false)
+	s.debug.Store(s.DebugMode)
+	// the validation metadata cache and the password blacklist
+	s.initValidator(currentRootPath)
 	s.Version = version
 	s.RootPath = currentRootPath
+	s.EncryptionKey = s.Config.Get("KEY")
+	if s.encryption, err = s.encryptionFromEnv(); err != nil {
+		errorLog.Println("Cannot read the encryption keys:", err)
+	}
+
+	// cookies are secure by default when serving HTTPS
+	tlsSettings := s.tlsFromEnv(currentRootPath)
+	cookieSecure := s.Config.Get("COOKIE_SECURE")
+	if cookieSecure == "" && tlsSettings.enabled() {
+		cookieSecure = "true"
+	}
 
 	//todo: populating the package configurations using values from env file
 	s.config = sauriConfigs{
-		port:           os.Getenv("PORT"),
-		rendererEngine: os.Getenv("RENDER_ENGINE"),
+		port:           s.Config.Get("PORT"),
+		rendererEngine: s.Config.Get("RENDER_ENGINE"),
 		cookie: cookieConfig{
-			name:     os.Getenv("COOKIE_NAME"),
-			lifetime: os.Getenv("COOKIE_LIFETIME"),
-			persist:  os.Getenv("COOKIE_PERSIST"),
-			secure:   os.Getenv("COOKIE_SECURE"),
-			domain:   os.Getenv("COOKIE_DOMAIN"),
+			name:     s.Config.Get("COOKIE_NAME"),
+			lifetime: s.Config.Get("COOKIE_LIFETIME"),
+			persist:  s.Config.Get("COOKIE_PERSIST"),
+			secure:   cookieSecure,
+			domain:   s.Config.Get("COOKIE_DOMAIN"),
+			sameSite: parseSameSite(s.Config.Get("COOKIE_SAME_SITE")),
 		},
-		sessionStoreType: os.Getenv("SESSION_STORE_TYPE"),
+		sessionStoreType: s.Config.Get("SESSION_STORE_TYPE"),
+		metricsEnabled:   s.Config.Get("METRICS_ENABLED"),
+		problemDetails:   s.Config.GetBool("PROBLEM_DETAILS", false),
+		tls:              tlsSettings,
 		dBConfig: dataBaseConfig{
 			dsn:          dsn,
 			dataBaseType: dbDriverType,
 		},
-		redis: redisConfig{
-			host:     os.Getenv("REDIS_HOST"),
-			password: os.Getenv("REDIS_PASSWORD"),
-			prefix:   os.Getenv("REDIS_PREFIX"),
-		},
+		redis: s.redisSettings(),
 	}
 
+	// after the key and the cookie settings, the response encrypts cookies with them
+	s.Responses = s.NewResponse()
+
+	// export traces when the OTEL_* variables ask for it, before chaos wraps the pools
+	s.enableTracing()
+
+	// inject faults for resilience testing when asked for
+	s.enableChaos()
+
+	// the background work starts once the settings of the package are populated
+	// warn about connection pool exhaustion
+	s.startDBPoolMonitor()
+
+	// elect a leader among instances so that maintenance tasks run on one server only
+	s.startLeaderElection()
+	s.startMaintenance()
+
+	// the named connections and the read replicas, see DATABASE_CONNECTIONS
+	if err = s.openDatabases(); err != nil {
+		errorLog.Println("Cannot open the databases:", err)
+		return err
+	}
+
+	// run the pending migrations before the server starts, see MIGRATE_ON_START
+	if err = s.migrateOnStart(); err != nil {
+		errorLog.Println("Cannot run the migrations:", err)
+		return err
+	}
+
+	// background jobs and scheduled tasks, both start with the server
+	s.initJobs()
+	s.initScheduler()
+	s.Events = events.New(s.Jobs, s.moduleLogger("events"))
+
 	// todo: router populate
 	s.Router = s.defaultRouter().(*chi.Mux)
 
 	// todo Session Initialization and setup
-	s.popSession()
+	if err = s.popSession(); err != nil {
+		errorLog.Println("Cannot set up the sessions:", err)
+		return err
+	}
+	s.initAuth()
+	s.initJWT()
+	s.initMail()
 
 	//setting the jet template engine
 	viewsDir := filepath.Join(currentRootPath, "resources", "views")
@@ -200,8 +315,8 @@ func (s *Sauri) NewApp(currentRootPath string) error {
 	// creates a new Renderer instance for Go template and initialize its fields
 	s.CreateRenderer()
 
-	// Listen for incoming emails on the emailQueue channel
-	//go s.Mailer.ListenForEmails()
+	// settings changed without a restart, see CONFIG_RELOAD
+	s.watchConfig()
 
 	return nil
 }
@@ -1,24 +1,32 @@
 package sauri
 
 import (
-	"bufio"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/CloudyKit/jet/v6"
 	"github.com/dgraph-io/badger/v3"
 	"github.com/haskekareem/sauri/cache"
+	"github.com/haskekareem/sauri/config"
+	"github.com/haskekareem/sauri/logging"
+	"github.com/haskekareem/sauri/mailer"
 	"github.com/haskekareem/sauri/renderer"
 	"github.com/haskekareem/sauri/sessions"
 	"github.com/haskekareem/sauri/validator"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"html/template"
+	"io"
 	"log"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -45,77 +53,72 @@ func (s *Sauri) checkDotEnvFile(pathToCheck string) error {
 	return nil
 }
 
-// LoadAndSetEnv loads the environment variables from the .env file.
+// LoadAndSetEnv loads the environment variables from the env files, later files win over the
+// earlier ones and the process environment wins over all of them. See config.EnvFiles for the
+// files of an application.
 func (s *Sauri) LoadAndSetEnv(filePath ...string) error {
-	//open the .env file
-	envFile, err := os.Open(filePath[0])
+	return config.LoadEnv(filePath...)
+}
+
+// setEnvFrom sets the KEY=value lines of an env file as environment variables
+func setEnvFrom(r io.Reader) error {
+	values, err := config.ParseEnv(r)
 	if err != nil {
 		return err
 	}
-	// Ensure the file is closed when the function exits
-	defer func(envFile *os.File) {
-		err := envFile.Close()
-		if err != nil {
-			_ = envFile.Close()
-		}
-	}(envFile)
-
-	// create a scanner to read the .env file line by line
-	scanner := bufio.NewScanner(envFile)
-
-	//read the file line by line
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		//remove any leading and trailing whitespaces
-		line = strings.TrimSpace(line)
-
-		//skip empty lines and those starting with "#"
-		if line == "" || strings.HasPrefix(line, "#") {
-			// Skips the current iteration of the loop and moves to the next line.
-			continue
-		}
-
-		// Split the line into key and value at the first '=' character
-		parts := strings.SplitN(line, "=", 2)
-		// Checks if the line was successfully split into exactly two parts
-		if len(parts) != 2 {
-			continue
-		}
+	return config.SetEnv(values)
+}
 
-		// Trim whitespace from key and value
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
+// createLoggers creates the structured logger of the application, see the LOG_* variables,
+// and the info and error loggers writing to it
+func (s *Sauri) createLoggers(rootPath string) (*log.Logger, *log.Logger) {
+	debug := s.Config.GetBool("DEBUG_MODE", false)
+	config := logging.FromEnv(rootPath, debug)
+	// LOG_LEVEL can change at runtime, see watchConfig
+	s.logLevel.Set(config.Level)
+	config.Leveler = &s.logLevel
 
-		// Set the environment variable
-		if err := os.Setenv(key, value); err != nil {
-			return err
-		}
-	}
-	// Check for errors that may have occurred during scanning
-	err = scanner.Err()
+	logger, closer, err := logging.New(config)
 	if err != nil {
-		return err
+		// a bad setting must not keep the application from logging
+		logger, closer, _ = logging.New(logging.Config{Level: config.Level})
+		logger.Error("cannot configure logging, writing to stderr", "error", err)
 	}
-	return nil
-}
+	s.Logger = logger
+	// registered first, so the log file is closed after every other hook ran
+	s.OnShutdown(func(ctx context.Context) error {
+		return closer.Close()
+	})
 
-// createLoggers creates a customized loggers
-func (s *Sauri) createLoggers() (*log.Logger, *log.Logger) {
-	var infoLogger *log.Logger
-	var errorLogger *log.Logger
+	// the packages logging on their own get a child logger
+	cache.Logger = logger.Module("cache").Slog()
+	mailer.SetLogger(logger.Module("mailer").Slog())
 
-	errorLogger = log.New(os.Stderr, "ERROR\t", log.Ltime|log.Ldate|log.Lshortfile)
-	infoLogger = log.New(os.Stderr, "INFO\t", log.Ltime|log.Ldate)
+	return logging.StdLogger(logger, slog.LevelInfo), logging.StdLogger(logger, slog.LevelError)
+}
 
-	return infoLogger, errorLogger
+// log returns the structured logger, the default slog logger before the loggers are created
+func (s *Sauri) log() logging.Logger {
+	if s.Logger == nil {
+		return logging.Wrap(slog.Default())
+	}
+	return s.Logger
+}
 
+// moduleLogger returns the child logger of a package, nil before the loggers are created
+func (s *Sauri) moduleLogger(name string) *slog.Logger {
+	if s.Logger == nil {
+		return nil
+	}
+	return s.Logger.Module(name).Slog()
 }
 
-// ListenAndServe creates a web server listening on the given port and serving
-func (s *Sauri) ListenAndServe() {
+// ListenAndServe creates a web server listening on the given port and serving until SIGINT
+// or SIGTERM, then drains the requests in flight within SHUTDOWN_TIMEOUT and shuts the
+// application down. It returns an error when the server cannot listen.
+func (s *Sauri) ListenAndServe() error {
 	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%s", os.Getenv("PORT")),
+		Addr:         fmt.Sprintf(":%s", s.Config.Get("PORT")),
 		ErrorLog:     s.ErrorLog,
 		Handler:      s.Router,
 		IdleTimeout:  30 * time.Second,
@@ -123,21 +126,84 @@ func (s *Sauri) ListenAndServe() {
 		WriteTimeout: 600 * time.Second,
 	}
 
-	// closing the connection pools
-	if s.DBConn.SqlConnPool != nil {
-		defer func(SqlConnPool *sql.DB) {
-			_ = SqlConnPool.Close()
-		}(s.DBConn.SqlConnPool)
+	// serve HTTPS when a certificate or Let's Encrypt hosts are configured
+	var redirectSrv *http.Server
+	if s.config.tls.enabled() {
+		var err error
+		if redirectSrv, err = s.configureTLS(srv); err != nil {
+			s.ErrorLog.Println(err)
+			s.shutdown()
+			return err
+		}
 	}
 
-	if s.DBConn.PgxConnPool != nil {
-		defer s.DBConn.PgxConnPool.Close()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := s.runStartHooks(ctx); err != nil {
+		s.ErrorLog.Println(err)
+		s.shutdown()
+		return err
+	}
+
+	s.InfoLog.Printf("Listening on port %s", s.Config.Get("PORT"))
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if s.config.tls.enabled() {
+			serveErr <- s.serveTLS(srv)
+			return
+		}
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	if redirectSrv != nil {
+		go func() {
+			s.InfoLog.Printf("Redirecting HTTP on %s to HTTPS", redirectSrv.Addr)
+			if err := redirectSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.ErrorLog.Println("HTTP redirect server:", err)
+			}
+		}()
+		defer func() {
+			_ = redirectSrv.Close()
+		}()
+	}
+
+	select {
+	case err := <-serveErr:
+		s.shutdown()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.ErrorLog.Printf("Could not listen on: %s: %v\n", s.Config.Get("PORT"), err)
+			return fmt.Errorf("could not listen on port %s: %w", s.Config.Get("PORT"), err)
+		}
+		return nil
+	case <-ctx.Done():
 	}
 
-	s.InfoLog.Printf("Listening on port %s", os.Getenv("PORT"))
+	s.InfoLog.Println("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout())
+	defer cancel()
 
-	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		s.ErrorLog.Fatalf("Could not listen on: %s: %v\n", os.Getenv("PORT"), err)
+	if redirectSrv != nil {
+		_ = redirectSrv.Shutdown(shutdownCtx)
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		s.ErrorLog.Println("requests still in flight were cut:", err)
+	}
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		s.ErrorLog.Println("shutdown:", err)
+		return err
+	}
+	s.InfoLog.Println("Server stopped")
+	return nil
+}
+
+// shutdown shuts the application down within SHUTDOWN_TIMEOUT, logging what failed
+func (s *Sauri) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout())
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		s.ErrorLog.Println("shutdown:", err)
 	}
 }
 
@@ -147,9 +213,17 @@ func (s *Sauri) CreateRenderer() {
 		RendererEngine:    s.config.rendererEngine,
 		TemplatesRootPath: "resources",
 		Port:              s.config.port,
+		Secure:            s.config.tls.enabled() || s.config.cookie.secure == "true",
 		JetViews:          s.JetViewsSetUp,
-		DevelopmentMode:   s.DebugMode,
+		DevelopmentMode:   s.IsDebugMode(),
 		Session:           s.Session,
+		Logger:            s.moduleLogger("renderer"),
+	}
+
+	// {{route "users.show" "id" .User.ID}} in templates
+	myRenderer.AddCustomFuncs(template.FuncMap{"route": s.routeURL})
+	if s.JetViewsSetUp != nil {
+		s.JetViewsSetUp.AddGlobal("route", s.routeURL)
 	}
 	s.Renderer = myRenderer
 }
@@ -179,7 +253,7 @@ func (s *Sauri) InitializeJetSet(layoutsDir, pagesDir string) (*jet.Set, error)
 
 	// Create a new Jet template set with the custom loader
 	var views *jet.Set
-	if s.DebugMode {
+	if s.IsDebugMode() {
 		views = jet.NewSet(
 			loader,
 			jet.InDevelopmentMode())
@@ -209,46 +283,118 @@ func (s *Sauri) NewValidator(data url.Values, FileData map[string]*multipart.Fil
 	}
 }
 
+// NewJSONValidator creates a new Validator instance for a decoded JSON body, rules may
+// address nested values with dot and wildcard paths such as "items.*.price".
+func (s *Sauri) NewJSONValidator(data map[string]interface{}, rules map[string][]string, dbPool *sql.DB, pgx *pgxpool.Pool) *validator.Validation {
+	v := s.NewValidator(url.Values{}, nil, rules, dbPool, pgx)
+	v.SetJSONData(data)
+	return v
+}
+
+// initValidator sets up the validation of the application: the metadata cache, off in
+// debug mode, and the PASSWORD_BLACKLIST word list, relative to the application root, whose
+// passwords are refused by password:strong along with the embedded ones
+func (s *Sauri) initValidator(rootPath string) {
+	validator.Metadata.SetDevMode(s.DebugMode)
+
+	path := s.Config.Get("PASSWORD_BLACKLIST")
+	if path == "" {
+		return
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(rootPath, path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		s.ErrorLog.Println("cannot load the password blacklist:", err)
+		return
+	}
+	defer func() { _ = file.Close() }()
+	validator.LoadPasswordBlacklist(file)
+}
+
+// OpenCache opens the cache of the CACHE setting, redis or badger, for the tools working on
+// the cache of an application without running it, such as the cli. Close it once done, the
+// badger files can only be opened by one process at a time.
+func (s *Sauri) OpenCache() (cache.Cache, error) {
+	s.config.redis = s.redisSettings()
+	switch s.Config.Get("CACHE") {
+	case "redis":
+		return s.initializeClientRedisCache(), nil
+	case "badger":
+		return s.initializeClientBadgerCache()
+	}
+	return nil, errors.New("CACHE is not set to redis or badger")
+}
+
+// redisSettings reads the REDIS_* settings
+func (s *Sauri) redisSettings() redisConfig {
+	return redisConfig{
+		host:     s.Config.Get("REDIS_HOST"),
+		password: s.Config.Get("REDIS_PASSWORD"),
+		prefix:   s.Config.Get("REDIS_PREFIX"),
+	}
+}
+
 // initializeClientRedisCache create a cache redis client by initializing the
 // redisCache struct type
 func (s *Sauri) initializeClientRedisCache() *cache.RedisCache {
 	return &cache.RedisCache{
-		Conn:   s.NewRedisConnPool(),
-		Prefix: s.config.redis.prefix,
+		Conn: s.NewRedisConnPool(),
+		// the caches open before the settings of the package are populated
+		Prefix: s.Config.Get("REDIS_PREFIX"),
 	}
 }
 
-// initializeClientBadgerCache create a cache redis client by initializing the
-// redisCache struct type
-func (s *Sauri) initializeClientBadgerCache() *cache.BadgerCache {
-	db, err := badger.Open(badger.DefaultOptions(s.RootPath + "storage/badger"))
+// initializeClientBadgerCache create a cache badger client by initializing the
+// badgerCache struct type
+func (s *Sauri) initializeClientBadgerCache() (*cache.BadgerCache, error) {
+	db, err := badger.Open(badger.DefaultOptions(filepath.Join(s.RootPath, "storage", "badger")))
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("cannot open badger database: %w", err)
 	}
 	return &cache.BadgerCache{
 		DBConn: db,
-		Prefix: s.config.redis.prefix,
-	}
+		Prefix: s.Config.Get("REDIS_PREFIX"),
+	}, nil
 }
 
 // popSession initialize and populate the session manager
-func (s *Sauri) popSession() {
+func (s *Sauri) popSession() error {
 	appSession := sessions.Session{
 		CookieName:       s.config.cookie.name,
 		CookieLifeTime:   s.config.cookie.lifetime,
 		CookiePersistent: s.config.cookie.persist,
 		CookieDomain:     s.config.cookie.domain,
 		CookieSecure:     s.config.cookie.secure,
+		SessionStore:     s.config.sessionStoreType,
 	}
 
 	//populate values based on whether db store or redis is being used
-	switch s.config.sessionStoreType {
+	switch strings.ToLower(s.config.sessionStoreType) {
 	case "redis":
-		appSession.RedisConnPool = myRedisCache.Conn
+		if myRedisCache != nil {
+			appSession.RedisConnPool = myRedisCache.Conn
+		}
 	case "mysql", "mariadb", "postgres", "postgresql":
 		appSession.DBConnPool = s.DBConn.SqlConnPool
+	case "cookie":
+		// the sessions are encrypted with KEY
+		if enc := s.Encrypter(); len(enc.Key) > 0 {
+			appSession.Encrypter = enc
+		}
+	case "badger", "file":
+		appSession.BadgerPath = filepath.Join(s.RootPath, "storage", "sessions")
 	}
 
 	// initialized and store the session in Gudu type
-	s.Session = appSession.InitSession()
+	sm, err := appSession.InitSession()
+	if err != nil {
+		return err
+	}
+	s.Session = sm
+	if store, ok := sm.Store.(*sessions.BadgerStore); ok {
+		s.OnShutdown(func(context.Context) error { return store.Close() })
+	}
+	return nil
 }
@@ -0,0 +1,45 @@
+package sauri
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/haskekareem/sauri/cache"
+	"github.com/haskekareem/sauri/scheduler"
+	"net/http"
+)
+
+// Schedule runs fn on a cron schedule, e.g. "0 3 * * *", "*/30 * * * * *" or "@every 5m".
+// A run is skipped while the previous one is going, on any instance sharing the redis or
+// badger cache, so the task runs on one server at a time. Panics and errors are logged.
+//
+//	app.Schedule("@hourly", pruneTokens, scheduler.Name("tokens.prune"), scheduler.Timeout(time.Minute))
+func (s *Sauri) Schedule(spec string, fn func(ctx context.Context) error, options ...scheduler.Option) (*scheduler.Task, error) {
+	return s.Scheduler.Schedule(spec, fn, options...)
+}
+
+// initScheduler creates the task scheduler, it starts with the server and waits for the
+// running tasks on shutdown
+func (s *Sauri) initScheduler() {
+	var locker cache.Locker
+	switch {
+	case myRedisCache != nil:
+		locker = myRedisCache
+	case myBadgerCache != nil:
+		locker = myBadgerCache
+	}
+
+	s.Scheduler = scheduler.New(locker, instanceID(), s.moduleLogger("scheduler"))
+	s.OnStart(func(ctx context.Context) error {
+		s.Scheduler.Start(s.Context())
+		return nil
+	})
+	s.OnShutdown(s.Scheduler.Stop)
+}
+
+// ScheduleHandler serves the scheduled tasks as JSON, used by sauri schedule:list
+func (s *Sauri) ScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(contentType, "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"tasks": s.Scheduler.Tasks()}); err != nil {
+		s.ErrorLog.Println("cannot write the scheduled tasks:", err)
+	}
+}
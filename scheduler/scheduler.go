@@ -0,0 +1,266 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/haskekareem/sauri/cache"
+	"github.com/robfig/cron/v3"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// lockTTL is how long the lock of a running task lives without being extended, a task of a
+// crashed instance blocks the next runs for that long at most
+const lockTTL = time.Minute
+
+// parser accepts the standard five fields, an optional leading seconds field and the
+// descriptors such as @hourly or @every 5m
+var parser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Task is a function run on a schedule
+type Task struct {
+	name         string
+	spec         string
+	fn           func(ctx context.Context) error
+	timeout      time.Duration
+	allowOverlap bool
+	entryID      cron.EntryID
+	running      atomic.Bool
+	lastRun      time.Time
+	lastError    string
+	mu           sync.Mutex
+}
+
+// Option configures a task
+type Option func(t *Task)
+
+// Name names the task in the logs and in sauri schedule:list, the spec when not set
+func Name(name string) Option {
+	return func(t *Task) {
+		t.name = name
+	}
+}
+
+// Timeout cancels the context of a run after d
+func Timeout(d time.Duration) Option {
+	return func(t *Task) {
+		t.timeout = d
+	}
+}
+
+// AllowOverlap lets a run start while the previous one is still going
+func AllowOverlap() Option {
+	return func(t *Task) {
+		t.allowOverlap = true
+	}
+}
+
+// TaskInfo describes a scheduled task
+type TaskInfo struct {
+	Name      string        `json:"name"`
+	Spec      string        `json:"spec"`
+	Timeout   time.Duration `json:"timeout"`
+	Overlap   bool          `json:"overlap"`
+	Running   bool          `json:"running"`
+	Next      time.Time     `json:"next"`
+	LastRun   time.Time     `json:"last_run,omitempty"`
+	LastError string        `json:"last_error,omitempty"`
+}
+
+// Scheduler runs tasks on cron schedules. With a Locker, a task only runs when no run of it
+// is in progress on any instance sharing the cache, which also makes every run happen on a
+// single server.
+type Scheduler struct {
+	cron   *cron.Cron
+	locker cache.Locker
+	owner  string
+	logger *slog.Logger
+
+	mu    sync.RWMutex
+	ctx   context.Context
+	tasks []*Task
+}
+
+// New creates a scheduler, locker may be nil when there is no shared cache and owner
+// identifies this instance in the locks
+func New(locker cache.Locker, owner string, logger *slog.Logger) *Scheduler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Scheduler{
+		cron:   cron.New(cron.WithParser(parser)),
+		locker: locker,
+		owner:  owner,
+		logger: logger,
+		ctx:    context.Background(),
+	}
+}
+
+// Schedule adds a task, the spec is a cron expression such as "*/5 * * * *" or "@daily"
+func (s *Scheduler) Schedule(spec string, fn func(ctx context.Context) error, options ...Option) (*Task, error) {
+	task := &Task{name: spec, spec: spec, fn: fn}
+	for _, option := range options {
+		option(task)
+	}
+
+	id, err := s.cron.AddFunc(spec, func() {
+		s.run(task)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q for task %s: %w", spec, task.name, err)
+	}
+	task.entryID = id
+
+	s.mu.Lock()
+	s.tasks = append(s.tasks, task)
+	s.mu.Unlock()
+	return task, nil
+}
+
+// Start starts running the tasks, their contexts are derived from ctx
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+	s.cron.Start()
+}
+
+// Stop stops scheduling runs and waits for the running ones, up to the end of ctx
+func (s *Scheduler) Stop(ctx context.Context) error {
+	select {
+	case <-s.cron.Stop().Done():
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("scheduled tasks did not stop: %w", ctx.Err())
+	}
+}
+
+// Tasks lists the tasks sorted by name
+func (s *Scheduler) Tasks() []TaskInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	infos := make([]TaskInfo, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		task.mu.Lock()
+		infos = append(infos, TaskInfo{
+			Name:      task.name,
+			Spec:      task.spec,
+			Timeout:   task.timeout,
+			Overlap:   task.allowOverlap,
+			Running:   task.running.Load(),
+			Next:      s.cron.Entry(task.entryID).Next,
+			LastRun:   task.lastRun,
+			LastError: task.lastError,
+		})
+		task.mu.Unlock()
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// run runs a task once, unless a previous run is still going
+func (s *Scheduler) run(task *Task) {
+	if !task.allowOverlap {
+		if !task.running.CompareAndSwap(false, true) {
+			s.logger.Warn("task skipped, the previous run is still going", "task", task.name)
+			return
+		}
+		defer task.running.Store(false)
+	}
+
+	s.mu.RLock()
+	ctx := s.ctx
+	s.mu.RUnlock()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if s.locker != nil && !task.allowOverlap {
+		release, ok := s.lock(ctx, task)
+		if !ok {
+			return
+		}
+		defer release()
+	}
+
+	if task.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, task.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := call(ctx, task.fn)
+
+	task.mu.Lock()
+	task.lastRun = start
+	task.lastError = ""
+	if err != nil {
+		task.lastError = err.Error()
+	}
+	task.mu.Unlock()
+
+	if err != nil {
+		s.logger.Error("task failed", "task", task.name, "duration", time.Since(start), "error", err)
+		return
+	}
+	s.logger.Debug("task done", "task", task.name, "duration", time.Since(start))
+}
+
+// lock takes the lock of a task and keeps extending it until release is called
+func (s *Scheduler) lock(ctx context.Context, task *Task) (release func(), ok bool) {
+	key := "sauri:schedule:" + task.name
+	locked, err := s.locker.Lock(key, s.owner, lockTTL)
+	if err != nil {
+		s.logger.Error("cannot lock the task", "task", task.name, "error", err)
+		return nil, false
+	}
+	if !locked {
+		// running on another instance
+		return nil, false
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(lockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := s.locker.Lock(key, s.owner, lockTTL); err != nil {
+					s.logger.Error("cannot extend the task lock", "task", task.name, "error", err)
+				}
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		if err := s.locker.Unlock(key, s.owner); err != nil {
+			s.logger.Error("cannot unlock the task", "task", task.name, "error", err)
+		}
+	}, true
+}
+
+// call runs fn, turning a panic into an error
+func call(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("task panicked: %v", rec)
+		}
+	}()
+	err = fn(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("task timed out: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryLocker is a cache.Locker shared by the schedulers of a test
+type memoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]string
+}
+
+func (l *memoryLocker) Lock(key, owner string, _ time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if holder, ok := l.locks[key]; ok && holder != owner {
+		return false, nil
+	}
+	l.locks[key] = owner
+	return true, nil
+}
+
+func (l *memoryLocker) Unlock(key, owner string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.locks[key] == owner {
+		delete(l.locks, key)
+	}
+	return nil
+}
+
+func TestScheduler_InvalidSpec(t *testing.T) {
+	s := New(nil, "a", nil)
+	_, err := s.Schedule("every tuesday", func(ctx context.Context) error { return nil })
+	assert.Error(t, err)
+}
+
+func TestScheduler_PreventsOverlap(t *testing.T) {
+	locker := &memoryLocker{locks: map[string]string{}}
+	first, second := New(locker, "a", nil), New(locker, "b", nil)
+
+	var runs atomic.Int32
+	release := make(chan struct{})
+	fn := func(ctx context.Context) error {
+		runs.Add(1)
+		<-release
+		return nil
+	}
+	taskA, err := first.Schedule("@hourly", fn, Name("report"))
+	assert.NoError(t, err)
+	taskB, err := second.Schedule("@hourly", fn, Name("report"))
+	assert.NoError(t, err)
+
+	go first.run(taskA)
+	assert.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, time.Millisecond)
+
+	// the same instance and another one sharing the cache both skip
+	first.run(taskA)
+	second.run(taskB)
+	close(release)
+	assert.Eventually(t, func() bool { return !taskA.running.Load() }, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), runs.Load())
+
+	// once done the next run goes ahead
+	second.run(taskB)
+	assert.Equal(t, int32(2), runs.Load())
+}
+
+func TestScheduler_RecoversPanicsAndRecordsErrors(t *testing.T) {
+	s := New(nil, "a", nil)
+	task, err := s.Schedule("*/5 * * * *", func(ctx context.Context) error { panic("boom") }, Name("prune"), Timeout(time.Second))
+	assert.NoError(t, err)
+
+	s.run(task)
+	infos := s.Tasks()
+	if assert.Len(t, infos, 1) {
+		assert.Equal(t, "prune", infos[0].Name)
+		assert.Equal(t, time.Second, infos[0].Timeout)
+		assert.Contains(t, infos[0].LastError, "boom")
+		assert.False(t, infos[0].LastRun.IsZero())
+	}
+}
+
+func TestScheduler_Timeout(t *testing.T) {
+	s := New(nil, "a", nil)
+	task, err := s.Schedule("@daily", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, Timeout(10*time.Millisecond))
+	assert.NoError(t, err)
+
+	s.run(task)
+	assert.Contains(t, s.Tasks()[0].LastError, "timed out")
+}
@@ -0,0 +1,135 @@
+package sauri
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"github.com/haskekareem/sauri/renderer"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// nonceTag is replaced by the nonce of the request in ContentSecurityPolicy
+const nonceTag = "{nonce}"
+
+// SecureHeadersConfig sets the security headers written by SecureHeaders, empty values are not sent
+type SecureHeadersConfig struct {
+	// ContentSecurityPolicy may contain {nonce}, replaced by a random nonce per request which
+	// templates get as .CSPNonce, e.g. "script-src 'self' 'nonce-{nonce}'"
+	ContentSecurityPolicy string
+	HSTSMaxAge            int // seconds, only sent over HTTPS
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	ReferrerPolicy        string
+	PermissionsPolicy     string
+	FrameOptions          string // X-Frame-Options
+	NoSniff               bool   // X-Content-Type-Options: nosniff
+}
+
+// DefaultSecureHeaders returns a configuration suitable for most server rendered applications
+func DefaultSecureHeaders() SecureHeadersConfig {
+	return SecureHeadersConfig{
+		ContentSecurityPolicy: "default-src 'self'; script-src 'self' 'nonce-{nonce}'; style-src 'self' 'nonce-{nonce}'; " +
+			"img-src 'self' data:; object-src 'none'; base-uri 'self'; frame-ancestors 'none'",
+		HSTSMaxAge:            63072000,
+		HSTSIncludeSubdomains: true,
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+		PermissionsPolicy:     "camera=(), microphone=(), geolocation=()",
+		FrameOptions:          "DENY",
+		NoSniff:               true,
+	}
+}
+
+// SecureHeaders sets Content-Security-Policy, Strict-Transport-Security, Referrer-Policy,
+// Permissions-Policy and the legacy framing and sniffing headers on every response
+func (s *Sauri) SecureHeaders(config SecureHeadersConfig) func(http.Handler) http.Handler {
+	hsts := ""
+	if config.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(config.HSTSMaxAge)
+		if config.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if config.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+
+			if policy := config.ContentSecurityPolicy; policy != "" {
+				if strings.Contains(policy, nonceTag) {
+					nonce, err := newNonce()
+					if err != nil {
+						s.ErrorLog.Println("cannot generate the CSP nonce:", err)
+						http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+						return
+					}
+					policy = strings.ReplaceAll(policy, nonceTag, nonce)
+					r = renderer.WithCSPNonce(r, nonce)
+				}
+				header.Set("Content-Security-Policy", policy)
+			}
+			if hsts != "" && isHTTPS(r) {
+				header.Set("Strict-Transport-Security", hsts)
+			}
+			if config.ReferrerPolicy != "" {
+				header.Set("Referrer-Policy", config.ReferrerPolicy)
+			}
+			if config.PermissionsPolicy != "" {
+				header.Set("Permissions-Policy", config.PermissionsPolicy)
+			}
+			if config.FrameOptions != "" {
+				header.Set("X-Frame-Options", config.FrameOptions)
+			}
+			if config.NoSniff {
+				header.Set("X-Content-Type-Options", "nosniff")
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CSPNonce returns the Content-Security-Policy nonce of the request, for handlers that do not
+// render through TemplateData
+func CSPNonce(r *http.Request) string {
+	return renderer.CSPNonce(r)
+}
+
+// secureHeadersFromEnv starts from DefaultSecureHeaders and applies the CSP, HSTS_MAX_AGE,
+// REFERRER_POLICY and PERMISSIONS_POLICY variables; "off" leaves a header out
+func (s *Sauri) secureHeadersFromEnv() SecureHeadersConfig {
+	config := DefaultSecureHeaders()
+
+	envHeader := func(name string, value *string) {
+		switch v := s.Config.Get(name); v {
+		case "":
+		case "off":
+			*value = ""
+		default:
+			*value = v
+		}
+	}
+	envHeader("CSP", &config.ContentSecurityPolicy)
+	envHeader("REFERRER_POLICY", &config.ReferrerPolicy)
+	envHeader("PERMISSIONS_POLICY", &config.PermissionsPolicy)
+
+	config.HSTSMaxAge = s.Config.GetInt("HSTS_MAX_AGE", config.HSTSMaxAge)
+	return config
+}
+
+// isHTTPS reports whether the request reached the application, or its proxy, over TLS
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// newNonce returns a random base64 nonce
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,86 @@
+package sauri
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// seedCommand is the argument running the seeders instead of the application, see
+// SeedCommand
+const seedCommand = "db:seed"
+
+// Seeder fills the database with the rows an application needs, for development or its
+// first deploy. Seed runs in a transaction of the default connection, rolled back when it
+// returns an error.
+type Seeder interface {
+	Seed(ctx context.Context, tx *Tx) error
+}
+
+// SeederFunc lets a function be a Seeder
+type SeederFunc func(ctx context.Context, tx *Tx) error
+
+// Seed calls f
+func (f SeederFunc) Seed(ctx context.Context, tx *Tx) error {
+	return f(ctx, tx)
+}
+
+// namedSeeder is a seeder registered with AddSeeder
+type namedSeeder struct {
+	name   string
+	seeder Seeder
+}
+
+// AddSeeder registers a seeder under a name, the seeders run in the order they are added
+func (s *Sauri) AddSeeder(name string, seeder Seeder) {
+	s.seeders = append(s.seeders, namedSeeder{name: name, seeder: seeder})
+}
+
+// Seed runs the seeders in order, only the named ones when names are given, each one in its
+// own transaction. It stops at the first failing seeder, the ones before it stay committed.
+func (s *Sauri) Seed(ctx context.Context, names ...string) error {
+	seeders := s.seeders
+	if len(names) > 0 {
+		seeders = nil
+		for _, name := range names {
+			seeder, ok := s.seeder(name)
+			if !ok {
+				return fmt.Errorf("no seeder named %q", name)
+			}
+			seeders = append(seeders, seeder)
+		}
+	}
+
+	for _, seeder := range seeders {
+		start := time.Now()
+		if err := s.DBTransaction(ctx, seeder.seeder.Seed); err != nil {
+			return fmt.Errorf("seeder %s: %w", seeder.name, err)
+		}
+		s.log().Info("seeded", "seeder", seeder.name, "duration", time.Since(start))
+	}
+	return nil
+}
+
+// seeder returns a registered seeder by name
+func (s *Sauri) seeder(name string) (namedSeeder, bool) {
+	for _, seeder := range s.seeders {
+		if seeder.name == name {
+			return seeder, true
+		}
+	}
+	return namedSeeder{}, false
+}
+
+// SeedCommand runs the seeders when args, usually os.Args[1:], start with db:seed and
+// reports whether it did, so that main exits instead of serving. The seeders live in the
+// application code, `sauri db:seed [name...]` runs the application this way:
+//
+//	if seeded, err := app.SeedCommand(os.Args[1:]); seeded {
+//		...
+//	}
+func (s *Sauri) SeedCommand(args []string) (bool, error) {
+	if len(args) == 0 || args[0] != seedCommand {
+		return false, nil
+	}
+	return true, s.Seed(context.Background(), args[1:]...)
+}
@@ -0,0 +1,105 @@
+package sauri
+
+import (
+	"encoding/json"
+	"github.com/haskekareem/sauri/renderer"
+	"net/http"
+)
+
+// SessionPut stores a value in the session of the request. The stores other than memory
+// encode the values with gob, a struct type must be registered with gob.Register, see
+// SessionPutStruct for the types that are not.
+func (s *Sauri) SessionPut(r *http.Request, key string, value any) {
+	s.Session.Put(r.Context(), key, value)
+}
+
+// SessionGet returns the value of key in the session of the request, false when it is not
+// set or has another type:
+//
+//	count, _ := sauri.SessionGet[int](app, r, "count")
+func SessionGet[T any](s *Sauri, r *http.Request, key string) (T, bool) {
+	value, ok := s.Session.Get(r.Context(), key).(T)
+	return value, ok
+}
+
+// SessionPop is SessionGet removing the value from the session
+func SessionPop[T any](s *Sauri, r *http.Request, key string) (T, bool) {
+	value, ok := s.Session.Pop(r.Context(), key).(T)
+	return value, ok
+}
+
+// SessionRemove removes key from the session of the request
+func (s *Sauri) SessionRemove(r *http.Request, key string) {
+	s.Session.Remove(r.Context(), key)
+}
+
+// SessionPutStruct stores v in the session of the request as JSON, so that its type needs no
+// gob registration
+func (s *Sauri) SessionPutStruct(r *http.Request, key string, v any) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.Session.Put(r.Context(), key, string(content))
+	return nil
+}
+
+// SessionGetStruct decodes the value of SessionPutStruct at key into v, it returns false
+// when the session has none
+func (s *Sauri) SessionGetStruct(r *http.Request, key string, v any) (bool, error) {
+	content := s.Session.GetString(r.Context(), key)
+	if content == "" {
+		return false, nil
+	}
+	return true, json.Unmarshal([]byte(content), v)
+}
+
+// SessionIncrement adds n to the integer at key in the session of the request and returns
+// the sum, the integer is 0 when it is not set
+func (s *Sauri) SessionIncrement(r *http.Request, key string, n int) int {
+	sum := s.Session.GetInt(r.Context(), key) + n
+	s.Session.Put(r.Context(), key, sum)
+	return sum
+}
+
+// Flash stores a message for the next page rendered, where it is {{.Flash.<kind>}}:
+//
+//	app.Flash(r, "success", "Your profile was saved")
+//	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+//
+// A message replaces the one of its kind not shown yet.
+func (s *Sauri) Flash(r *http.Request, kind, message string) {
+	flashes := s.flashes(r)
+	flashes[kind] = message
+	s.putFlashes(r, flashes)
+}
+
+// GetFlash returns the flashed message of kind and removes it, empty when there is none
+func (s *Sauri) GetFlash(r *http.Request, kind string) string {
+	flashes := s.flashes(r)
+	message, ok := flashes[kind]
+	if ok {
+		delete(flashes, kind)
+		s.putFlashes(r, flashes)
+	}
+	return message
+}
+
+// flashes returns the flashed messages of the session by kind
+func (s *Sauri) flashes(r *http.Request) map[string]string {
+	flashes := make(map[string]string)
+	if content := s.Session.GetString(r.Context(), renderer.FlashSessionKey); content != "" {
+		_ = json.Unmarshal([]byte(content), &flashes)
+	}
+	return flashes
+}
+
+func (s *Sauri) putFlashes(r *http.Request, flashes map[string]string) {
+	if len(flashes) == 0 {
+		s.Session.Remove(r.Context(), renderer.FlashSessionKey)
+		return
+	}
+	// JSON like the flashed validation errors, so that no gob registration is needed
+	content, _ := json.Marshal(flashes)
+	s.Session.Put(r.Context(), renderer.FlashSessionKey, string(content))
+}
@@ -0,0 +1,122 @@
+package sauri
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/haskekareem/sauri/renderer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sessionClient sends requests through the session middleware, keeping the session cookie
+// between them
+type sessionClient struct {
+	s       *Sauri
+	cookies []*http.Cookie
+}
+
+func newSessionClient() *sessionClient {
+	return &sessionClient{s: &Sauri{Session: scs.New()}}
+}
+
+// do runs handler on a request of the session
+func (c *sessionClient) do(t *testing.T, handler func(r *http.Request)) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range c.cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	c.s.Session.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(r)
+	})).ServeHTTP(rec, req)
+	require.Less(t, rec.Code, http.StatusBadRequest)
+	if cookies := rec.Result().Cookies(); len(cookies) > 0 {
+		c.cookies = cookies
+	}
+}
+
+func TestFlash(t *testing.T) {
+	c := newSessionClient()
+	c.do(t, func(r *http.Request) {
+		c.s.Flash(r, "success", "Your profile was saved")
+		c.s.Flash(r, "error", "The avatar is too large")
+		// a message replaces the one of its kind not shown yet
+		c.s.Flash(r, "success", "Your profile was updated")
+	})
+
+	// the messages are kept for the next request and removed once read
+	c.do(t, func(r *http.Request) {
+		assert.Equal(t, "Your profile was updated", c.s.GetFlash(r, "success"))
+		assert.Equal(t, "", c.s.GetFlash(r, "success"))
+		assert.Equal(t, "", c.s.GetFlash(r, "info"))
+	})
+	c.do(t, func(r *http.Request) {
+		assert.Equal(t, "", c.s.GetFlash(r, "success"))
+		assert.Equal(t, "The avatar is too large", c.s.GetFlash(r, "error"))
+	})
+	c.do(t, func(r *http.Request) {
+		assert.Equal(t, "", c.s.GetFlash(r, "error"))
+		// the session key is removed with the last message
+		assert.False(t, c.s.Session.Exists(r.Context(), renderer.FlashSessionKey))
+	})
+}
+
+func TestSessionGet(t *testing.T) {
+	c := newSessionClient()
+	c.do(t, func(r *http.Request) {
+		c.s.SessionPut(r, "count", 3)
+		c.s.SessionPut(r, "name", "Ada")
+	})
+
+	c.do(t, func(r *http.Request) {
+		count, ok := SessionGet[int](c.s, r, "count")
+		assert.True(t, ok)
+		assert.Equal(t, 3, count)
+
+		// another type gives the zero value
+		name, ok := SessionGet[int](c.s, r, "name")
+		assert.False(t, ok)
+		assert.Equal(t, 0, name)
+
+		missing, ok := SessionGet[string](c.s, r, "missing")
+		assert.False(t, ok)
+		assert.Equal(t, "", missing)
+
+		popped, ok := SessionPop[string](c.s, r, "name")
+		assert.True(t, ok)
+		assert.Equal(t, "Ada", popped)
+	})
+
+	c.do(t, func(r *http.Request) {
+		_, ok := SessionGet[string](c.s, r, "name")
+		assert.False(t, ok)
+		assert.Equal(t, 5, c.s.SessionIncrement(r, "count", 2))
+	})
+}
+
+func TestSessionPutStruct(t *testing.T) {
+	type cart struct {
+		Items []string
+		Total float64
+	}
+	c := newSessionClient()
+	c.do(t, func(r *http.Request) {
+		require.NoError(t, c.s.SessionPutStruct(r, "cart", cart{Items: []string{"pen"}, Total: 1.5}))
+	})
+
+	c.do(t, func(r *http.Request) {
+		var got cart
+		ok, err := c.s.SessionGetStruct(r, "cart", &got)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, cart{Items: []string{"pen"}, Total: 1.5}, got)
+
+		ok, err = c.s.SessionGetStruct(r, "missing", &got)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
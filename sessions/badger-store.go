@@ -0,0 +1,84 @@
+package sessions
+
+import (
+	"errors"
+	"github.com/dgraph-io/badger/v3"
+	"time"
+)
+
+// BadgerStore keeps the sessions in a badger database on disk, so that they survive a restart
+// without a database server. The sessions expire with the TTL of their entries.
+type BadgerStore struct {
+	DB     *badger.DB
+	Prefix string // starts the keys of the sessions
+}
+
+// OpenBadgerStore opens the badger database of the folder dir for the sessions
+func OpenBadgerStore(dir string) (*BadgerStore, error) {
+	db, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	if err != nil {
+		return nil, err
+	}
+	return &BadgerStore{DB: db, Prefix: "session:"}, nil
+}
+
+// Close closes the database
+func (b *BadgerStore) Close() error {
+	return b.DB.Close()
+}
+
+// Find returns the data of the session token
+func (b *BadgerStore) Find(token string) ([]byte, bool, error) {
+	var data []byte
+	err := b.DB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(b.Prefix + token))
+		if err != nil {
+			return err
+		}
+		data, err = item.ValueCopy(nil)
+		return err
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Commit saves the data of the session token until expiry
+func (b *BadgerStore) Commit(token string, data []byte, expiry time.Time) error {
+	ttl := time.Until(expiry)
+	if ttl <= 0 {
+		return b.Delete(token)
+	}
+	return b.DB.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(badger.NewEntry([]byte(b.Prefix+token), data).WithTTL(ttl))
+	})
+}
+
+// Delete removes the session token
+func (b *BadgerStore) Delete(token string) error {
+	return b.DB.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(b.Prefix + token))
+	})
+}
+
+// All returns the data of the sessions not expired by their token
+func (b *BadgerStore) All() (map[string][]byte, error) {
+	sessions := make(map[string][]byte)
+	err := b.DB.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(b.Prefix)})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			data, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			sessions[string(it.Item().Key()[len(b.Prefix):])] = data
+		}
+		return nil
+	})
+	return sessions, err
+}
@@ -0,0 +1,155 @@
+package sessions
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"github.com/alexedwards/scs/v2"
+	"net/http"
+	"time"
+)
+
+// Encrypter encrypts the sessions of the cookie store, sauri.Encryption is one
+type Encrypter interface {
+	Encrypt(text string) (string, error)
+	Decrypt(ciphertext string) (string, error)
+}
+
+// ErrCookieTooLarge is returned when the data of a session does not fit in its cookie
+var ErrCookieTooLarge = errors.New("the session is too large for the cookie store")
+
+// maxCookieSize is the size of the largest cookie the browsers keep
+const maxCookieSize = 4096
+
+// CookieStore keeps the data of the sessions in their cookie, encrypted so that the clients
+// can neither read nor change it, and no storage is needed on the server. A session holds
+// about 3KB of data at most, and it cannot be revoked on the server before it expires.
+//
+// The cookie is written by LoadAndSave, which replaces the one of the session manager.
+type CookieStore struct {
+	Encrypter Encrypter
+}
+
+// cookieKey is the context key of the cookie committed during a request
+type cookieKey struct{}
+
+type committedCookie struct {
+	value string
+}
+
+// Find returns the data of the session whose cookie is token, none when it does not decrypt
+// or expired
+func (c *CookieStore) Find(token string) ([]byte, bool, error) {
+	plain, err := c.Encrypter.Decrypt(token)
+	if err != nil || len(plain) < 8 {
+		return nil, false, nil
+	}
+	expiry := time.Unix(int64(binary.BigEndian.Uint64([]byte(plain[:8]))), 0)
+	if time.Now().After(expiry) {
+		return nil, false, nil
+	}
+	return []byte(plain[8:]), true, nil
+}
+
+// FindCtx is Find
+func (c *CookieStore) FindCtx(_ context.Context, token string) ([]byte, bool, error) {
+	return c.Find(token)
+}
+
+// Commit fails, the sessions are committed by the LoadAndSave of the store
+func (c *CookieStore) Commit(string, []byte, time.Time) error {
+	return errors.New("the sessions of the cookie store are saved by its LoadAndSave")
+}
+
+// CommitCtx encrypts the data of the session for the cookie of the request
+func (c *CookieStore) CommitCtx(ctx context.Context, _ string, data []byte, expiry time.Time) error {
+	committed, ok := ctx.Value(cookieKey{}).(*committedCookie)
+	if !ok {
+		return c.Commit("", nil, expiry)
+	}
+
+	plain := binary.BigEndian.AppendUint64(make([]byte, 0, 8+len(data)), uint64(expiry.Unix()))
+	value, err := c.Encrypter.Encrypt(string(append(plain, data...)))
+	if err != nil {
+		return err
+	}
+	if len(value) > maxCookieSize-256 { // room for the name and the attributes
+		return ErrCookieTooLarge
+	}
+	committed.value = value
+	return nil
+}
+
+// Delete does nothing, the cookie is removed by LoadAndSave
+func (c *CookieStore) Delete(string) error {
+	return nil
+}
+
+// DeleteCtx is Delete
+func (c *CookieStore) DeleteCtx(_ context.Context, token string) error {
+	return c.Delete(token)
+}
+
+// LoadAndSave loads the session of the cookie of each request and writes the cookie of the
+// modified sessions, it is the LoadAndSave of the session manager for the cookie store
+func (c *CookieStore) LoadAndSave(sm *scs.SessionManager, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Cookie")
+
+		var token string
+		if cookie, err := r.Cookie(sm.Cookie.Name); err == nil {
+			token = cookie.Value
+		}
+		committed := &committedCookie{}
+		ctx, err := sm.Load(context.WithValue(r.Context(), cookieKey{}, committed), token)
+		if err != nil {
+			sm.ErrorFunc(w, r, err)
+			return
+		}
+
+		sr := r.WithContext(ctx)
+		sw := &cookieResponseWriter{ResponseWriter: w, commit: func() {
+			switch sm.Status(ctx) {
+			case scs.Modified:
+				_, expiry, err := sm.Commit(ctx)
+				if err != nil {
+					sm.ErrorFunc(w, sr, err)
+					return
+				}
+				sm.WriteSessionCookie(ctx, w, committed.value, expiry)
+			case scs.Destroyed:
+				sm.WriteSessionCookie(ctx, w, "", time.Time{})
+			}
+		}}
+		next.ServeHTTP(sw, sr)
+		sw.writeCookie()
+	})
+}
+
+// cookieResponseWriter writes the cookie of the session before the headers
+type cookieResponseWriter struct {
+	http.ResponseWriter
+	commit  func()
+	written bool
+}
+
+func (w *cookieResponseWriter) writeCookie() {
+	if !w.written {
+		w.written = true
+		w.commit()
+	}
+}
+
+func (w *cookieResponseWriter) Write(b []byte) (int, error) {
+	w.writeCookie()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *cookieResponseWriter) WriteHeader(code int) {
+	w.writeCookie()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cookieResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
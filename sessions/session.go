@@ -2,6 +2,8 @@ package sessions
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"github.com/alexedwards/scs/mysqlstore"
 	"github.com/alexedwards/scs/postgresstore"
 	"github.com/alexedwards/scs/redisstore"
@@ -22,11 +24,14 @@ type Session struct {
 	SessionStore     string
 	DBConnPool       *sql.DB
 	RedisConnPool    *redis.Pool
+	Encrypter        Encrypter // encrypts the sessions of the cookie store
+	BadgerPath       string    // the folder of the database of the badger store
 }
 
 // InitSession initializes and configures a session manager based on the provided
-// Session struct.
-func (s *Session) InitSession() *scs.SessionManager {
+// Session struct. SessionStore is one of memory, the default, cookie, badger, redis, mysql
+// or postgres, an error is returned for the others and for a store missing its connection.
+func (s *Session) InitSession() (*scs.SessionManager, error) {
 	var secure, persist bool
 
 	// how long should the session lasts
@@ -57,19 +62,51 @@ func (s *Session) InitSession() *scs.SessionManager {
 
 	// which session store
 	switch strings.ToLower(s.SessionStore) {
+	case "", "memory":
+		// the default store of scs keeps the sessions in memory
+	case "cookie":
+		// Configure session to keep the data in the encrypted cookie
+		if s.Encrypter == nil {
+			return nil, errors.New("the cookie session store needs an encryption key")
+		}
+		sm.Store = &CookieStore{Encrypter: s.Encrypter}
+	case "badger", "file":
+		// Configure session to use a badger database on disk
+		store, err := OpenBadgerStore(s.BadgerPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open the badger session store: %w", err)
+		}
+		sm.Store = store
 	case "redis":
 		// Configure session to use Redis store
+		if s.RedisConnPool == nil {
+			return nil, errors.New("the redis session store needs a redis connection")
+		}
 		sm.Store = redisstore.New(s.RedisConnPool)
 	case "mysql", "mariadb":
 		// Configure session to use MySQL/MariaDB store
+		if s.DBConnPool == nil {
+			return nil, errors.New("the mysql session store needs a database connection")
+		}
 		sm.Store = mysqlstore.New(s.DBConnPool)
 	case "postgres", "postgresql":
 		// Configure session to use PostgresSQL store
+		if s.DBConnPool == nil {
+			return nil, errors.New("the postgres session store needs a database connection")
+		}
 		sm.Store = postgresstore.New(s.DBConnPool)
 	default:
-		// No external store specified, default to cookie-based session
+		return nil, fmt.Errorf("unknown session store %q, expected memory, cookie, badger, redis, mysql or postgres", s.SessionStore)
 	}
 
-	return sm
+	return sm, nil
+}
 
+// LoadAndSave loads and saves the session of each request with the session manager, or with
+// the cookie store when it is the store of sm
+func LoadAndSave(sm *scs.SessionManager, next http.Handler) http.Handler {
+	if store, ok := sm.Store.(*CookieStore); ok {
+		return store.LoadAndSave(sm, next)
+	}
+	return sm.LoadAndSave(next)
 }
@@ -17,7 +17,7 @@ func TestSession_InitSession(t *testing.T) {
 	t.Setenv("COOKIE_PERSISTENT_TEST", "true")
 	t.Setenv("COOKIE_DOMAIN_TEST", "localhost")
 	t.Setenv("COOKIE_SECURE_TEST", "false")
-	t.Setenv("SESSION_STORE_TEST", "cookie")
+	t.Setenv("SESSION_STORE_TEST", "memory")
 
 	// Initialize the session configuration
 	appSessionConfig := &Session{
@@ -29,7 +29,10 @@ func TestSession_InitSession(t *testing.T) {
 		SessionStore:     os.Getenv("SESSION_STORE_TEST"),
 	}
 
-	sm := appSessionConfig.InitSession()
+	sm, err := appSessionConfig.InitSession()
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// Validate session configuration
 	assert.Equal(t, "test_session", sm.Cookie.Name)
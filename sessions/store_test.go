@@ -0,0 +1,91 @@
+package sessions
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// base64Encrypter is an Encrypter for the tests, it only encodes the text
+type base64Encrypter struct{}
+
+func (base64Encrypter) Encrypt(text string) (string, error) {
+	return "enc." + base64.RawURLEncoding.EncodeToString([]byte(text)), nil
+}
+
+func (base64Encrypter) Decrypt(ciphertext string) (string, error) {
+	text, ok := strings.CutPrefix(ciphertext, "enc.")
+	if !ok {
+		return "", errors.New("tampered")
+	}
+	plain, err := base64.RawURLEncoding.DecodeString(text)
+	return string(plain), err
+}
+
+func TestInitSessionUnknownStore(t *testing.T) {
+	_, err := (&Session{SessionStore: "mongo"}).InitSession()
+	assert.ErrorContains(t, err, `unknown session store "mongo"`)
+
+	_, err = (&Session{SessionStore: "cookie"}).InitSession()
+	assert.Error(t, err, "the cookie store needs an encrypter")
+}
+
+func TestCookieStore(t *testing.T) {
+	sm, err := (&Session{CookieName: "session", SessionStore: "cookie", Encrypter: base64Encrypter{}}).InitSession()
+	require.NoError(t, err)
+
+	handler := LoadAndSave(sm, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/put" {
+			sm.Put(r.Context(), "name", "Ada")
+		}
+		_, _ = w.Write([]byte(sm.GetString(r.Context(), "name")))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/put", nil))
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.True(t, strings.HasPrefix(cookies[0].Value, "enc."))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "Ada", rec.Body.String())
+
+	// a cookie that does not decrypt starts a new session
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "forged"})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestBadgerStore(t *testing.T) {
+	sm, err := (&Session{SessionStore: "badger", BadgerPath: t.TempDir()}).InitSession()
+	require.NoError(t, err)
+	store := sm.Store.(*BadgerStore)
+	defer func() { _ = store.Close() }()
+
+	require.NoError(t, store.Commit("token", []byte("data"), time.Now().Add(time.Minute)))
+	data, found, err := store.Find("token")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("data"), data)
+
+	all, err := store.All()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"token": []byte("data")}, all)
+
+	require.NoError(t, store.Delete("token"))
+	_, found, err = store.Find("token")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
@@ -0,0 +1,94 @@
+package sauri
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StaticOptions sets how Static serves files
+type StaticOptions struct {
+	MaxAge time.Duration // Cache-Control max-age of the files, a day when zero
+	// SPA answers unknown paths without an extension with the index page, for single page
+	// applications routing in the browser. Missing assets still give a 404.
+	SPA   bool
+	Index string // index page of the directories, index.html when empty
+}
+
+// Static serves the files of root, a directory path or an fs.FS such as an embed.FS, under
+// the prefix. Directories are never listed, only their index page is served.
+func (s *Sauri) Static(prefix string, root interface{}, options ...StaticOptions) {
+	var fsys fs.FS
+	switch r := root.(type) {
+	case string:
+		fsys = os.DirFS(r)
+	case fs.FS:
+		fsys = r
+	default:
+		panic(fmt.Sprintf("sauri: Static root must be a directory or an fs.FS, not %T", root))
+	}
+
+	var opts StaticOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.MaxAge == 0 {
+		opts.MaxAge = 24 * time.Hour
+	}
+	if opts.Index == "" {
+		opts.Index = "index.html"
+	}
+
+	prefix = "/" + strings.Trim(prefix, "/")
+	handler := http.StripPrefix(strings.TrimSuffix(prefix, "/"), s.staticHandler(fsys, opts))
+	s.Router.Handle(strings.TrimSuffix(prefix, "/")+"/*", handler)
+	if prefix != "/" {
+		s.Router.Handle(prefix, handler)
+	}
+}
+
+// staticHandler serves the files of fsys
+func (s *Sauri) staticHandler(fsys fs.FS, opts StaticOptions) http.Handler {
+	cacheControl := "public, max-age=" + strconv.Itoa(int(opts.MaxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			s.ErrorStatus(w, http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if name == "" {
+			name = "."
+		}
+
+		info, err := fs.Stat(fsys, name)
+		if err == nil && info.IsDir() {
+			// no listing, the directory needs an index page
+			_, err = fs.Stat(fsys, path.Join(name, opts.Index))
+			name = path.Join(name, opts.Index)
+		}
+		if errors.Is(err, fs.ErrNotExist) && opts.SPA && path.Ext(name) == "" {
+			name, err = opts.Index, nil
+		}
+		if err != nil {
+			s.Error404(w, r)
+			return
+		}
+
+		// the index page changes with every release, the browser must check for a new one
+		if path.Base(name) == opts.Index {
+			w.Header().Set("Cache-Control", "no-cache")
+		} else {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		http.ServeFileFS(w, r, fsys, name)
+	})
+}
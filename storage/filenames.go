@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// ErrExtensionNotAllowed is returned when an upload has an extension that is not allowed
+var ErrExtensionNotAllowed = errors.New("storage: file extension not allowed")
+
+// maxFilenameLength keeps names well under the limits of file systems and object stores
+const maxFilenameLength = 200
+
+// SanitizeFilename turns a client supplied name into a safe base name: directories are
+// dropped, so "../../etc/passwd" gives "passwd", and anything but letters, digits, dots,
+// dashes and underscores becomes a dash.
+func SanitizeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' || r == '-':
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r):
+			if !strings.HasSuffix(b.String(), "-") {
+				b.WriteRune('-')
+			}
+		}
+	}
+
+	// no hidden files and no names made of dots only
+	name = strings.TrimLeft(b.String(), ".-")
+	if len(name) > maxFilenameLength {
+		ext := path.Ext(name)
+		if len(ext) > 20 {
+			ext = ""
+		}
+		name = strings.ToValidUTF8(name[:maxFilenameLength-len(ext)], "") + ext
+	}
+	if name == "" || strings.Trim(name, ".") == "" {
+		return "file"
+	}
+	return name
+}
+
+// AllowedExtension checks the extension of a name against a list such as ".jpg" or "png",
+// an empty list allows every extension
+func AllowedExtension(name string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))
+	for _, a := range allowed {
+		if ext != "" && ext == strings.ToLower(strings.TrimPrefix(a, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// RandomName returns 32 random hex characters with the extension of the name
+func RandomName(name string) string {
+	return hex.EncodeToString(randomBytes(16)) + strings.ToLower(path.Ext(name))
+}
+
+// UUIDName returns a random (version 4) UUID with the extension of the name
+func UUIDName(name string) string {
+	b := randomBytes(16)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]) + strings.ToLower(path.Ext(name))
+}
+
+// randomBytes reads n bytes from the system random source
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return b
+}
+
+// ErrExists is returned when a file is created at a path already taken
+var ErrExists = errors.New("storage: file already exists")
+
+// maxUniqueSuffix is the last -N suffix tried before falling back to a random name
+const maxUniqueSuffix = 999
+
+// creator is a disk that stores a file only when its path is free, in a single step, so that
+// two uploads of the same name cannot replace each other
+type creator interface {
+	// create stores content at the first free path of candidates and returns that path
+	create(ctx context.Context, candidates []string, content io.Reader) (string, error)
+}
+
+// uniqueCandidates returns dir/name, dir/name-1.ext, dir/name-2.ext... then a random name
+func uniqueCandidates(dir, name string) []string {
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+
+	candidates := make([]string, 0, maxUniqueSuffix+2)
+	candidates = append(candidates, path.Join(dir, name))
+	for i := 1; i <= maxUniqueSuffix; i++ {
+		candidates = append(candidates, path.Join(dir, fmt.Sprintf("%s-%d%s", stem, i, ext)))
+	}
+	return append(candidates, path.Join(dir, RandomName(name)))
+}
+
+// UniquePath returns dir/name, or dir/name-1.ext, dir/name-2.ext... when the file exists.
+// The path may be taken before it is written to, PutUnique stores the file safely.
+func UniquePath(ctx context.Context, disk Disk, dir, name string) (string, error) {
+	candidates := uniqueCandidates(dir, name)
+	for _, candidate := range candidates[:len(candidates)-1] {
+		exists, err := disk.Exists(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+	}
+	return candidates[len(candidates)-1], nil
+}
+
+// PutUnique stores content at dir/name, or dir/name-1.ext, dir/name-2.ext... when the file
+// exists, and returns the path. The local and S3 disks create the file only if the path is
+// still free, so concurrent uploads of the same name never replace each other; the other
+// disks check the path first with UniquePath.
+func PutUnique(ctx context.Context, disk Disk, dir, name string, content io.Reader) (string, error) {
+	if c, ok := disk.(creator); ok {
+		return c.create(ctx, uniqueCandidates(dir, name), content)
+	}
+	filePath, err := UniquePath(ctx, disk, dir, name)
+	if err != nil {
+		return "", err
+	}
+	return filePath, disk.Put(ctx, filePath, content)
+}
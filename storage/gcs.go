@@ -0,0 +1,10 @@
+package storage
+
+// gcsEndpoint is the XML API of Google Cloud Storage, which speaks the S3 protocol
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// NewGCS creates a Google Cloud Storage disk. It uses the S3 compatible XML API with an
+// HMAC key of a service account, created under Cloud Storage > Settings > Interoperability.
+func NewGCS(key, secret, bucket string) *S3 {
+	return &S3{Key: key, Secret: secret, Region: "auto", Bucket: bucket, Endpoint: gcsEndpoint, PathStyle: true}
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Local stores files in a directory of the local file system
+type Local struct {
+	Root    string // directory the paths are relative to
+	BaseURL string // URL the Handler is mounted on, e.g. /storage
+	Public  bool   // serve every file, otherwise only temporary URLs are served
+	Key     []byte // signs the temporary URLs
+}
+
+// NewLocal creates a local disk
+func NewLocal(root, baseURL string, public bool, key []byte) *Local {
+	return &Local{Root: root, BaseURL: strings.TrimSuffix(baseURL, "/"), Public: public, Key: key}
+}
+
+// FullPath returns the file system path of a file
+func (l *Local) FullPath(path string) (string, error) {
+	path, err := cleanPath(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.Root, filepath.FromSlash(path)), nil
+}
+
+// Put writes the content to the file, creating the directories it needs
+func (l *Local) Put(ctx context.Context, path string, content io.Reader) error {
+	fullPath, err := l.FullPath(path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("cannot create directory: %w", err)
+	}
+
+	file, err := os.Create(fullPath)
+	if err != nil {
+		return fmt.Errorf("cannot create file: %w", err)
+	}
+	return write(file, content)
+}
+
+// create stores content at the first candidate no file exists at, the file is opened with
+// O_EXCL so that it is never truncated by a concurrent upload
+func (l *Local) create(ctx context.Context, candidates []string, content io.Reader) (string, error) {
+	for _, candidate := range candidates {
+		fullPath, err := l.FullPath(candidate)
+		if err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return "", fmt.Errorf("cannot create directory: %w", err)
+		}
+
+		file, err := os.OpenFile(fullPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("cannot create file: %w", err)
+		}
+		return candidate, write(file, content)
+	}
+	return "", ErrExists
+}
+
+// write copies the content to a created file, the file is removed when it fails
+func write(file *os.File, content io.Reader) error {
+	if _, err := io.Copy(file, content); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return fmt.Errorf("cannot write file: %w", err)
+	}
+	return file.Close()
+}
+
+// Get opens the file, the reader is an *os.File so it can be seeked
+func (l *Local) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	fullPath, err := l.FullPath(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(fullPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Delete removes the file, deleting a missing file is not an error
+func (l *Local) Delete(ctx context.Context, path string) error {
+	fullPath, err := l.FullPath(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Exists reports whether the file exists
+func (l *Local) Exists(ctx context.Context, path string) (bool, error) {
+	fullPath, err := l.FullPath(path)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(fullPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// URL returns the address of the file under BaseURL
+func (l *Local) URL(path string) string {
+	path, _ = cleanPath(path)
+	return l.BaseURL + "/" + (&url.URL{Path: path}).EscapedPath()
+}
+
+// TemporaryURL returns an address of the file that the Handler serves until it expires
+func (l *Local) TemporaryURL(path string, expiry time.Duration) (string, error) {
+	path, err := cleanPath(path)
+	if err != nil {
+		return "", err
+	}
+	if len(l.Key) == 0 {
+		return "", errors.New("storage: local disk has no key to sign temporary URLs")
+	}
+
+	expires := strconv.FormatInt(now().Add(expiry).Unix(), 10)
+	query := url.Values{"expires": {expires}, "signature": {l.sign(path, expires)}}
+	return l.URL(path) + "?" + query.Encode(), nil
+}
+
+// sign returns the signature of a temporary URL
+func (l *Local) sign(path, expires string) string {
+	mac := hmac.New(sha256.New, l.Key)
+	mac.Write([]byte(path + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validSignature checks the signature and the expiry of a temporary URL
+func (l *Local) validSignature(path string, query url.Values) bool {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || len(l.Key) == 0 || now().Unix() > expires {
+		return false
+	}
+	signature, err := hex.DecodeString(query.Get("signature"))
+	if err != nil {
+		return false
+	}
+	expected, _ := hex.DecodeString(l.sign(path, query.Get("expires")))
+	return hmac.Equal(signature, expected)
+}
+
+// Handler serves the files of the disk, the request path is the file path so mount it with
+// http.StripPrefix. Unless the disk is public only valid temporary URLs are served.
+func (l *Local) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, err := cleanPath(r.URL.Path)
+		if err != nil || (!l.Public && !l.validSignature(path, r.URL.Query())) {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+
+		fullPath, _ := l.FullPath(path)
+		file, err := os.Open(fullPath)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		defer func(file *os.File) {
+			_ = file.Close()
+		}(file)
+
+		info, err := file.Stat()
+		if err != nil || info.IsDir() {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+	})
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// s3Object reads an object of an S3 disk, it can be seeked so that http.ServeContent
+// answers range requests without downloading the whole object
+type s3Object struct {
+	disk    *S3
+	ctx     context.Context
+	key     string
+	body    io.ReadCloser
+	offset  int64 // position of the next byte read from body
+	pos     int64 // position asked for by Seek
+	size    int64
+	modTime time.Time
+}
+
+// open requests the object from the current position
+func (o *s3Object) open() error {
+	req, err := o.disk.request(o.ctx, http.MethodGet, o.key, nil, nil)
+	if err != nil {
+		return err
+	}
+	if o.pos > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", o.pos))
+	}
+
+	resp, err := o.disk.do(req)
+	if err != nil {
+		return err
+	}
+	if o.pos > 0 && resp.StatusCode != http.StatusPartialContent {
+		_ = resp.Body.Close()
+		return errors.New("storage: range requests are not supported for " + o.key)
+	}
+
+	if o.pos == 0 {
+		o.size = resp.ContentLength
+		o.modTime, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+	}
+	o.body = resp.Body
+	o.offset = o.pos
+	return nil
+}
+
+// Read reads from the current position, reopening the object after a seek
+func (o *s3Object) Read(p []byte) (int, error) {
+	if o.pos >= o.size && o.size >= 0 {
+		return 0, io.EOF
+	}
+	if o.body == nil || o.offset != o.pos {
+		o.closeBody()
+		if err := o.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := o.body.Read(p)
+	o.offset += int64(n)
+	o.pos = o.offset
+	return n, err
+}
+
+// Seek moves the position without any request, the next Read fetches from there
+func (o *s3Object) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += o.pos
+	case io.SeekEnd:
+		if o.size < 0 {
+			return 0, errors.New("storage: size of " + o.key + " is unknown")
+		}
+		offset += o.size
+	default:
+		return 0, errors.New("storage: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("storage: negative position")
+	}
+	o.pos = offset
+	return offset, nil
+}
+
+// ModTime returns the last modification time sent by the service
+func (o *s3Object) ModTime() time.Time {
+	return o.modTime
+}
+
+// Close releases the body of the current request
+func (o *s3Object) Close() error {
+	o.closeBody()
+	return nil
+}
+
+func (o *s3Object) closeBody() {
+	if o.body != nil {
+		_ = o.body.Close()
+		o.body = nil
+	}
+}
@@ -0,0 +1,396 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unsignedPayload lets the body be streamed without hashing it first
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3 stores files in an Amazon S3 bucket or any S3 compatible service such as MinIO.
+// Requests are signed with AWS Signature Version 4.
+type S3 struct {
+	Key       string
+	Secret    string
+	Region    string
+	Bucket    string
+	Endpoint  string // https://s3.<region>.amazonaws.com when empty
+	PathStyle bool   // address the bucket in the path instead of the host name
+	PublicURL string // base URL returned by URL, the bucket URL when empty
+	Client    *http.Client
+}
+
+// NewS3 creates an S3 disk
+func NewS3(key, secret, region, bucket, endpoint string) *S3 {
+	return &S3{Key: key, Secret: secret, Region: region, Bucket: bucket, Endpoint: endpoint, PathStyle: endpoint != ""}
+}
+
+// Put uploads the content, content of unknown length is spooled to a temporary file first
+// since S3 needs the length before the body
+func (s *S3) Put(ctx context.Context, key string, content io.Reader) error {
+	body, size, cleanup, err := sizedBody(content)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	return s.put(ctx, key, body, size, nil)
+}
+
+// create uploads the content to the first candidate no object exists at, with a conditional
+// request so that an object uploaded meanwhile is never replaced
+func (s *S3) create(ctx context.Context, candidates []string, content io.Reader) (string, error) {
+	body, size, cleanup, err := sizedBody(content)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+	// sizedBody returns a seekable body, read again for every path already taken
+	seeker := body.(io.Seeker)
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+
+	// Google Cloud Storage has its own precondition header
+	condition := http.Header{"If-None-Match": {"*"}}
+	if s.Endpoint == gcsEndpoint {
+		condition = http.Header{"X-Goog-If-Generation-Match": {"0"}}
+	}
+	for _, candidate := range candidates {
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return "", err
+		}
+		// the client closes the bodies it sends, Close is hidden from it
+		err := s.put(ctx, candidate, struct{ io.Reader }{body}, size, condition)
+		if errors.Is(err, ErrExists) {
+			continue
+		}
+		return candidate, err
+	}
+	return "", ErrExists
+}
+
+// put uploads a body of known size, the header holds the preconditions of the request
+func (s *S3) put(ctx context.Context, key string, body io.Reader, size int64, header http.Header) error {
+	req, err := s.request(ctx, http.MethodPut, key, body, header)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType := mime.TypeByExtension(strings.ToLower(path.Ext(key))); contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Get downloads the file, the body is streamed. The reader can be seeked, seeking fetches
+// the rest of the file from the new offset with a range request.
+func (s *S3) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	object := &s3Object{disk: s, ctx: ctx, key: path}
+	if err := object.open(); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// Delete removes the file
+func (s *S3) Delete(ctx context.Context, path string) error {
+	req, err := s.request(ctx, http.MethodDelete, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Exists reports whether the file exists
+func (s *S3) Exists(ctx context.Context, path string) (bool, error) {
+	req, err := s.request(ctx, http.MethodHead, path, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := s.do(req)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, resp.Body.Close()
+}
+
+// URL returns the public address of the file
+func (s *S3) URL(path string) string {
+	key, _ := cleanPath(path)
+	if s.PublicURL != "" {
+		return strings.TrimSuffix(s.PublicURL, "/") + "/" + escapePath(key)
+	}
+	return s.objectURL(key).String()
+}
+
+// TemporaryURL returns a presigned address of the file valid for the expiry, at most 7 days
+func (s *S3) TemporaryURL(path string, expiry time.Duration) (string, error) {
+	key, err := cleanPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	u := s.objectURL(key)
+	t := now().UTC()
+	query := url.Values{
+		"X-Amz-Algorithm":     {"AWS4-HMAC-SHA256"},
+		"X-Amz-Credential":    {s.Key + "/" + s.scope(t)},
+		"X-Amz-Date":          {t.Format("20060102T150405Z")},
+		"X-Amz-Expires":       {strconv.Itoa(int(expiry.Seconds()))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	u.RawQuery = canonicalQuery(query)
+
+	headers := http.Header{}
+	headers.Set("Host", u.Host)
+	signature := s.signature(t, http.MethodGet, u, headers, unsignedPayload)
+	u.RawQuery += "&X-Amz-Signature=" + signature
+	return u.String(), nil
+}
+
+// objectURL returns the address of an object in the bucket
+func (s *S3) objectURL(key string) *url.URL {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + s.Region + ".amazonaws.com"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		u = &url.URL{Scheme: "https", Host: endpoint}
+	}
+
+	rawPath := "/" + escapePath(key)
+	if s.PathStyle {
+		rawPath = "/" + escapePath(s.Bucket) + rawPath
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+	}
+	u.RawPath = rawPath
+	u.Path, _ = url.PathUnescape(rawPath)
+	return u
+}
+
+// request creates a signed request for an object, the header is signed along
+func (s *S3) request(ctx context.Context, method, path string, body io.Reader, header http.Header) (*http.Request, error) {
+	key, err := cleanPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	u := s.objectURL(key)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	t := now().UTC()
+	req.Header.Set("Host", u.Host)
+	req.Header.Set("X-Amz-Date", t.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	signature := s.signature(t, method, u, req.Header, unsignedPayload)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.Key, s.scope(t), signedHeaders(req.Header), signature))
+	req.Header.Del("Host")
+	return req, nil
+}
+
+// do sends the request and turns error responses into errors, 404 gives ErrNotFound and a
+// failed precondition ErrExists
+func (s *S3) do(req *http.Request) (*http.Response, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("storage: %s %s: %w", req.Method, req.URL.Path, err)
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return nil, ErrExists
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("storage: %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(message)))
+}
+
+// scope returns the credential scope of the signature
+func (s *S3) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.Region + "/s3/aws4_request"
+}
+
+// signature computes the AWS Signature Version 4 of a request
+func (s *S3) signature(t time.Time, method string, u *url.URL, headers http.Header, payloadHash string) string {
+	canonicalRequest := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		u.RawQuery,
+		canonicalHeaders(headers),
+		signedHeaders(headers),
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		t.Format("20060102T150405Z"),
+		s.scope(t),
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.Secret), t.Format("20060102"))
+	signingKey = hmacSHA256(signingKey, s.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+}
+
+// canonicalHeaders lists the lowercased headers with their trimmed values, sorted
+func canonicalHeaders(headers http.Header) string {
+	var b strings.Builder
+	for _, name := range sortedHeaderNames(headers) {
+		b.WriteString(name + ":" + strings.TrimSpace(headers.Get(name)) + "\n")
+	}
+	return b.String()
+}
+
+// signedHeaders lists the names of the signed headers
+func signedHeaders(headers http.Header) string {
+	return strings.Join(sortedHeaderNames(headers), ";")
+}
+
+// sortedHeaderNames returns the lowercased header names in order
+func sortedHeaderNames(headers http.Header) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// canonicalQuery encodes a query with sorted keys and %20 for spaces
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			pairs = append(pairs, escape(key, false)+"="+escape(value, false))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// escapePath escapes the segments of a path the way S3 expects
+func escapePath(path string) string {
+	return escape(path, true)
+}
+
+// escape percent-encodes everything but the unreserved characters, and slashes if asked
+func escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (keepSlash && c == '/') {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the data
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// hashHex returns the hex encoded SHA-256 of the data
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// sizedBody returns the content along with its length. Seekable content is measured,
+// anything else is spooled to a temporary file so that it is not held in memory.
+func sizedBody(content io.Reader) (io.Reader, int64, func(), error) {
+	if seeker, ok := content.(io.Seeker); ok {
+		current, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			end, err := seeker.Seek(0, io.SeekEnd)
+			if err == nil {
+				if _, err := seeker.Seek(current, io.SeekStart); err == nil {
+					return content, end - current, func() {}, nil
+				}
+			}
+		}
+	}
+
+	spool, err := os.CreateTemp("", "sauri-upload-*")
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("cannot create temporary file: %w", err)
+	}
+	cleanup := func() {
+		_ = spool.Close()
+		_ = os.Remove(spool.Name())
+	}
+
+	size, err := io.Copy(spool, content)
+	if err == nil {
+		_, err = spool.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		cleanup()
+		return nil, 0, nil, fmt.Errorf("cannot spool upload: %w", err)
+	}
+	return spool, size, cleanup, nil
+}
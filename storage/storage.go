@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a file does not exist on the disk
+var ErrNotFound = errors.New("storage: file not found")
+
+// Disk stores files under slash separated paths, e.g. "uploads/avatar.png"
+type Disk interface {
+	Put(ctx context.Context, path string, content io.Reader) error
+	Get(ctx context.Context, path string) (io.ReadCloser, error)
+	Delete(ctx context.Context, path string) error
+	Exists(ctx context.Context, path string) (bool, error)
+	URL(path string) string
+	TemporaryURL(path string, expiry time.Duration) (string, error)
+}
+
+// now is replaced in tests to sign with a fixed clock
+var now = time.Now
+
+// cleanPath makes a path relative to the disk root and rejects paths leaving it
+func cleanPath(path string) (string, error) {
+	path = strings.TrimPrefix(strings.ReplaceAll(path, "\\", "/"), "/")
+	for _, segment := range strings.Split(path, "/") {
+		if segment == ".." {
+			return "", errors.New("storage: path leaves the disk root: " + path)
+		}
+	}
+	if path == "" || path == "." {
+		return "", errors.New("storage: empty path")
+	}
+	return path, nil
+}
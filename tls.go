@@ -0,0 +1,97 @@
+package sauri
+
+import (
+	"crypto/tls"
+	"errors"
+	"golang.org/x/crypto/acme/autocert"
+	"net"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// tlsConfig holds the TLS settings, either certificate files or Let's Encrypt hosts
+type tlsConfig struct {
+	certFile      string
+	keyFile       string
+	autocertHosts []string
+	autocertEmail string
+	autocertCache string
+	redirectPort  string // plain HTTP port redirecting to HTTPS, none when empty
+}
+
+// tlsFromEnv reads TLS_CERT_FILE, TLS_KEY_FILE, AUTOCERT_HOSTS, AUTOCERT_EMAIL, AUTOCERT_CACHE
+// and HTTP_REDIRECT_PORT
+func (s *Sauri) tlsFromEnv(rootPath string) tlsConfig {
+	return tlsConfig{
+		certFile:      s.Config.Get("TLS_CERT_FILE"),
+		keyFile:       s.Config.Get("TLS_KEY_FILE"),
+		autocertHosts: s.Config.GetStrings("AUTOCERT_HOSTS"),
+		autocertEmail: s.Config.Get("AUTOCERT_EMAIL"),
+		autocertCache: s.Config.Get("AUTOCERT_CACHE", filepath.Join(rootPath, "storage", "certs")),
+		redirectPort:  s.Config.Get("HTTP_REDIRECT_PORT"),
+	}
+}
+
+// enabled reports whether the server is to serve HTTPS
+func (c tlsConfig) enabled() bool {
+	return len(c.autocertHosts) > 0 || (c.certFile != "" && c.keyFile != "")
+}
+
+// configureTLS sets up the TLS configuration of the server and returns the plain HTTP server
+// redirecting to it, nil when HTTP_REDIRECT_PORT is not set. With Let's Encrypt the redirect
+// server also answers the HTTP-01 challenges.
+func (s *Sauri) configureTLS(srv *http.Server) (*http.Server, error) {
+	config := s.config.tls
+	var redirect http.Handler = http.HandlerFunc(redirectToHTTPS(srv.Addr))
+
+	srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(config.autocertHosts) > 0 {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(config.autocertHosts...),
+			Email:      config.autocertEmail,
+			Cache:      autocert.DirCache(config.autocertCache),
+		}
+		srv.TLSConfig = manager.TLSConfig()
+		srv.TLSConfig.MinVersion = tls.VersionTLS12
+		redirect = manager.HTTPHandler(redirect)
+	} else if _, err := tls.LoadX509KeyPair(config.certFile, config.keyFile); err != nil {
+		return nil, errors.New("cannot load the TLS certificate: " + err.Error())
+	}
+
+	if config.redirectPort == "" {
+		return nil, nil
+	}
+	return &http.Server{
+		Addr:              ":" + config.redirectPort,
+		ErrorLog:          s.ErrorLog,
+		Handler:           redirect,
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       30 * time.Second,
+	}, nil
+}
+
+// serveTLS serves HTTPS, HTTP/2 is negotiated by net/http
+func (s *Sauri) serveTLS(srv *http.Server) error {
+	if len(s.config.tls.autocertHosts) > 0 {
+		// the certificates come from the autocert manager
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServeTLS(s.config.tls.certFile, s.config.tls.keyFile)
+}
+
+// redirectToHTTPS sends plain HTTP requests to the same URL over HTTPS on the TLS address
+func redirectToHTTPS(tlsAddr string) http.HandlerFunc {
+	_, port, _ := net.SplitHostPort(tlsAddr)
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	}
+}
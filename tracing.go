@@ -0,0 +1,62 @@
+package sauri
+
+import (
+	"context"
+	"github.com/haskekareem/sauri/cache"
+	"github.com/haskekareem/sauri/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// enableTracing exports traces when the OTEL_* variables ask for it, see tracing.NewProvider.
+// Requests get a span each and the cache and database/sql pool are wrapped to record theirs;
+// the pgx pools are traced by OpenDBConnectionPool.
+func (s *Sauri) enableTracing() {
+	if !tracing.Enabled() {
+		return
+	}
+
+	serviceName := s.AppName
+	if serviceName == "" {
+		serviceName = s.Config.Get("APP_NAME")
+	}
+	if serviceName == "" {
+		serviceName = "sauri"
+	}
+	provider, err := tracing.NewProvider(context.Background(), serviceName, s.Version)
+	if err != nil {
+		s.ErrorLog.Println("cannot enable tracing:", err)
+		return
+	}
+	s.tracer = provider.Tracer(tracing.Name)
+	// registered early, so it runs last and flushes the spans of the other hooks
+	s.OnShutdown(provider.Shutdown)
+	s.InfoLog.Println("OpenTelemetry tracing enabled for", serviceName)
+
+	if s.Cache != nil {
+		s.Cache = &tracing.Cache{Cache: s.Cache, System: s.Config.Get("CACHE"), Tracer: s.tracer}
+	}
+	// the pgx pools and their database/sql pool already trace through pgx
+	if s.DBConn.SqlConnPool != nil && s.DBConn.PgxConnPool == nil {
+		s.DBConn.SqlConnPool = tracing.OpenDB(s.DBConn.SqlConnPool, s.config.dBConfig.dsn, s.DBConn.DatabaseType, s.tracer)
+		s.dbPoolFromConfig().apply(s.DBConn.SqlConnPool)
+	}
+}
+
+// Tracer returns the tracer of the application, a no-op one when tracing is off
+func (s *Sauri) Tracer() trace.Tracer {
+	if s.tracer == nil {
+		return tracing.Tracer()
+	}
+	return s.tracer
+}
+
+// CacheContext returns the cache with its calls traced under the span of ctx, e.g. the
+// request context. It is the cache itself when tracing is off.
+func (s *Sauri) CacheContext(ctx context.Context) cache.Cache {
+	if c, ok := s.Cache.(interface {
+		WithContext(context.Context) cache.Cache
+	}); ok {
+		return c.WithContext(ctx)
+	}
+	return s.Cache
+}
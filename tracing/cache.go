@@ -0,0 +1,116 @@
+package tracing
+
+import (
+	"context"
+	"github.com/haskekareem/sauri/cache"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// Cache wraps a cache.Cache and records a span for the calls made through WithContext.
+// The cache interface takes no context, so calls made on the Cache itself are not traced.
+type Cache struct {
+	Cache  cache.Cache
+	System string // db.system attribute of the spans, e.g. redis
+	Tracer trace.Tracer
+	ctx    context.Context
+}
+
+// WithContext returns the cache with its calls recorded as children of the span in ctx
+func (c *Cache) WithContext(ctx context.Context) cache.Cache {
+	traced := *c
+	traced.ctx = ctx
+	return &traced
+}
+
+// start starts the span of a cache call when there is a span to attach it to
+func (c *Cache) start(operation, key string) trace.Span {
+	if c.ctx == nil || !hasParent(c.ctx) {
+		return trace.SpanFromContext(context.Background())
+	}
+	attributes := []attribute.KeyValue{attribute.String("db.system", c.System)}
+	if key != "" {
+		attributes = append(attributes, attribute.String("cache.key", key))
+	}
+	_, span := c.Tracer.Start(c.ctx, "cache."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+	return span
+}
+
+func (c *Cache) Exists(keyStr string) (bool, error) {
+	span := c.start("exists", keyStr)
+	exists, err := c.Cache.Exists(keyStr)
+	end(span, err)
+	return exists, err
+}
+
+func (c *Cache) Get(keyStr string) (interface{}, error) {
+	span := c.start("get", keyStr)
+	value, err := c.Cache.Get(keyStr)
+	span.SetAttributes(attribute.Bool("cache.hit", err == nil && value != nil))
+	end(span, err)
+	return value, err
+}
+
+func (c *Cache) Set(keyStr string, value interface{}, expires ...time.Duration) error {
+	span := c.start("set", keyStr)
+	err := c.Cache.Set(keyStr, value, expires...)
+	end(span, err)
+	return err
+}
+
+func (c *Cache) Delete(keyStr string) error {
+	span := c.start("delete", keyStr)
+	err := c.Cache.Delete(keyStr)
+	end(span, err)
+	return err
+}
+
+func (c *Cache) EmptyByMatch(keyStr string) error {
+	span := c.start("empty_by_match", keyStr)
+	err := c.Cache.EmptyByMatch(keyStr)
+	end(span, err)
+	return err
+}
+
+func (c *Cache) Empty() error {
+	span := c.start("empty", "")
+	err := c.Cache.Empty()
+	end(span, err)
+	return err
+}
+
+func (c *Cache) Keys(patternOrKey ...string) ([]string, error) {
+	span := c.start("keys", "")
+	keys, err := c.Cache.Keys(patternOrKey...)
+	end(span, err)
+	return keys, err
+}
+
+func (c *Cache) Expire(keyStr string, expiration time.Duration) error {
+	span := c.start("expire", keyStr)
+	err := c.Cache.Expire(keyStr, expiration)
+	end(span, err)
+	return err
+}
+
+func (c *Cache) TTL(keyStr string) (time.Duration, error) {
+	span := c.start("ttl", keyStr)
+	ttl, err := c.Cache.TTL(keyStr)
+	end(span, err)
+	return ttl, err
+}
+
+func (c *Cache) Update(keyStr string, value interface{}, expires ...time.Duration) error {
+	span := c.start("update", keyStr)
+	err := c.Cache.Update(keyStr, value, expires...)
+	end(span, err)
+	return err
+}
+
+func (c *Cache) KeysWithBatchSize(batchSize int, patternOrKey ...string) ([]string, error) {
+	span := c.start("keys", "")
+	keys, err := c.Cache.KeysWithBatchSize(batchSize, patternOrKey...)
+	end(span, err)
+	return keys, err
+}
@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// Middleware starts a server span per request, continuing the trace of the incoming
+// traceparent header. The span is named after the matched route pattern, e.g. "GET /users/{id}".
+func Middleware(tracer trace.Tracer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			scheme := "http"
+			if r.TLS != nil {
+				scheme = "https"
+			}
+			ctx, span := tracer.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.path", r.URL.Path),
+					attribute.String("url.scheme", scheme),
+					attribute.String("server.address", r.Host),
+					attribute.String("client.address", r.RemoteAddr),
+					attribute.String("user_agent.original", r.UserAgent()),
+				),
+			)
+			if id := middleware.GetReqID(ctx); id != "" {
+				span.SetAttributes(attribute.String("request_id", id))
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				// the route is only known once the router matched the request
+				if rctx := chi.RouteContext(r.Context()); rctx != nil {
+					if route := rctx.RoutePattern(); route != "" {
+						span.SetName(r.Method + " " + route)
+						span.SetAttributes(attribute.String("http.route", route))
+					}
+				}
+				span.SetAttributes(attribute.Int("http.response.status_code", status))
+				if status >= http.StatusInternalServerError {
+					span.SetStatus(codes.Error, http.StatusText(status))
+				}
+				span.End()
+			}()
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+		})
+	}
+}
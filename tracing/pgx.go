@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// pgxSpanKey holds the span started by QueryTracer in the query context
+type pgxSpanKey struct{}
+
+// QueryTracer records a span for the queries of a pgx connection or pool, set it as the
+// Tracer of the pgx.ConnConfig
+type QueryTracer struct {
+	Tracer trace.Tracer // the framework tracer when nil
+}
+
+// TraceQueryStart implements pgx.QueryTracer
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if !hasParent(ctx) {
+		return ctx
+	}
+	tracer := t.Tracer
+	if tracer == nil {
+		tracer = Tracer()
+	}
+	ctx, span := tracer.Start(ctx, "db.query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.query.text", data.SQL),
+		),
+	)
+	return context.WithValue(ctx, pgxSpanKey{}, span)
+}
+
+// TraceQueryEnd implements pgx.QueryTracer
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span, ok := ctx.Value(pgxSpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	if data.Err == nil {
+		span.SetAttributes(attribute.String("db.response.status", data.CommandTag.String()))
+	}
+	end(span, data.Err)
+}
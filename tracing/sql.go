@@ -0,0 +1,251 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Connector wraps a database/sql driver so that queries, statements and transactions get
+// a client span each
+type Connector struct {
+	Base   driver.Driver
+	DSN    string
+	System string // db.system attribute of the spans, e.g. postgresql
+	Tracer trace.Tracer
+}
+
+// OpenDB opens a *sql.DB using the same driver and dsn as a regular pool but with spans
+// recorded for the calls made with a context
+func OpenDB(db *sql.DB, dsn, system string, tracer trace.Tracer) *sql.DB {
+	return sql.OpenDB(&Connector{
+		Base:   db.Driver(),
+		DSN:    dsn,
+		System: system,
+		Tracer: tracer,
+	})
+}
+
+// start starts a span for a database call, only under a span of the caller so that pool
+// maintenance does not create traces of its own
+func (c *Connector) start(ctx context.Context, operation, query string) (context.Context, trace.Span) {
+	if !hasParent(ctx) {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	attributes := []attribute.KeyValue{attribute.String("db.system", c.System)}
+	if query != "" {
+		attributes = append(attributes, attribute.String("db.query.text", query))
+	}
+	return c.Tracer.Start(ctx, "db."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+}
+
+// end records the error of a call and ends its span
+func end(span trace.Span, err error) {
+	if err != nil && err != driver.ErrSkip {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Connect implements driver.Connector
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Base.Open(c.DSN)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{conn: conn, connector: c}, nil
+}
+
+// Driver implements driver.Connector. The driver opens traced connections too, so that
+// wrappers built on the pool's driver, such as the chaos one, keep the spans.
+func (c *Connector) Driver() driver.Driver {
+	return tracedDriver{connector: c}
+}
+
+// tracedDriver opens traced connections with the base driver
+type tracedDriver struct {
+	connector *Connector
+}
+
+func (td tracedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := td.connector.Base.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{conn: conn, connector: td.connector}, nil
+}
+
+// tracedConn forwards to the wrapped connection inside a span
+type tracedConn struct {
+	conn      driver.Conn
+	connector *Connector
+}
+
+func (tc *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return tc.PrepareContext(context.Background(), query)
+}
+
+func (tc *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	ctx, span := tc.connector.start(ctx, "prepare", query)
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if p, ok := tc.conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = tc.conn.Prepare(query)
+	}
+	end(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{stmt: stmt, query: query, connector: tc.connector}, nil
+}
+
+func (tc *tracedConn) Close() error {
+	return tc.conn.Close()
+}
+
+func (tc *tracedConn) Begin() (driver.Tx, error) {
+	return tc.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (tc *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	ctx, span := tc.connector.start(ctx, "begin", "")
+	var (
+		tx  driver.Tx
+		err error
+	)
+	if b, ok := tc.conn.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else {
+		// fallback for drivers without BeginTx
+		tx, err = tc.conn.Begin()
+	}
+	end(span, err)
+	return tx, err
+}
+
+func (tc *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := tc.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := tc.connector.start(ctx, "exec", query)
+	result, err := e.ExecContext(ctx, query, args)
+	end(span, err)
+	return result, err
+}
+
+func (tc *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := tc.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := tc.connector.start(ctx, "query", query)
+	rows, err := q.QueryContext(ctx, query, args)
+	end(span, err)
+	return rows, err
+}
+
+func (tc *tracedConn) Ping(ctx context.Context) error {
+	if p, ok := tc.conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (tc *tracedConn) ResetSession(ctx context.Context) error {
+	if r, ok := tc.conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (tc *tracedConn) IsValid() bool {
+	if v, ok := tc.conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (tc *tracedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if c, ok := tc.conn.(driver.NamedValueChecker); ok {
+		return c.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// tracedStmt records a span for every execution of a prepared statement
+type tracedStmt struct {
+	stmt      driver.Stmt
+	query     string
+	connector *Connector
+}
+
+func (ts *tracedStmt) Close() error {
+	return ts.stmt.Close()
+}
+
+func (ts *tracedStmt) NumInput() int {
+	return ts.stmt.NumInput()
+}
+
+func (ts *tracedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return ts.stmt.Exec(args)
+}
+
+func (ts *tracedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return ts.stmt.Query(args)
+}
+
+func (ts *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, span := ts.connector.start(ctx, "exec", ts.query)
+	var (
+		result driver.Result
+		err    error
+	)
+	if e, ok := ts.stmt.(driver.StmtExecContext); ok {
+		result, err = e.ExecContext(ctx, args)
+	} else {
+		result, err = ts.stmt.Exec(values(args))
+	}
+	end(span, err)
+	return result, err
+}
+
+func (ts *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, span := ts.connector.start(ctx, "query", ts.query)
+	var (
+		rows driver.Rows
+		err  error
+	)
+	if q, ok := ts.stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		rows, err = ts.stmt.Query(values(args))
+	}
+	end(span, err)
+	return rows, err
+}
+
+func (ts *tracedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if c, ok := ts.stmt.(driver.NamedValueChecker); ok {
+		return c.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// values converts the arguments for the statements without context support
+func values(args []driver.NamedValue) []driver.Value {
+	converted := make([]driver.Value, len(args))
+	for i, arg := range args {
+		converted[i] = arg.Value
+	}
+	return converted
+}
@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Name is the instrumentation scope of the spans created by the framework
+const Name = "github.com/haskekareem/sauri"
+
+// exporter returns the exporter asked for by OTEL_TRACES_EXPORTER, defaulting to otlp when an
+// OTLP endpoint is set. An empty name means tracing is off.
+func exporter() string {
+	if disabled, _ := strconv.ParseBool(os.Getenv("OTEL_SDK_DISABLED")); disabled {
+		return ""
+	}
+	name := strings.ToLower(strings.TrimSpace(os.Getenv("OTEL_TRACES_EXPORTER")))
+	switch name {
+	case "none":
+		return ""
+	case "":
+		if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "" {
+			return "otlp"
+		}
+		return ""
+	}
+	return name
+}
+
+// Enabled reports whether the OTEL_* variables ask for traces to be exported
+func Enabled() bool {
+	return exporter() != ""
+}
+
+// NewProvider creates a tracer provider exporting spans as the standard OTEL_* variables say:
+// OTEL_TRACES_EXPORTER (otlp or console), OTEL_EXPORTER_OTLP_* for the endpoint and headers,
+// OTEL_TRACES_SAMPLER for sampling and OTEL_RESOURCE_ATTRIBUTES. The service name defaults to
+// the given one when OTEL_SERVICE_NAME is not set. The W3C trace context and baggage
+// propagators are installed globally.
+func NewProvider(ctx context.Context, serviceName, version string) (*sdktrace.TracerProvider, error) {
+	var (
+		spanExporter sdktrace.SpanExporter
+		err          error
+	)
+	switch name := exporter(); name {
+	case "otlp":
+		// only the http/protobuf protocol is supported
+		spanExporter, err = otlptracehttp.New(ctx)
+	case "console":
+		spanExporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "":
+		return nil, fmt.Errorf("tracing is not enabled, set OTEL_TRACES_EXPORTER")
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the trace exporter: %w", err)
+	}
+
+	attributes := []attribute.KeyValue{attribute.String("service.name", serviceName)}
+	if version != "" {
+		attributes = append(attributes, attribute.String("service.version", version))
+	}
+	// the environment comes last so that OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(attributes...),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider, nil
+}
+
+// Tracer returns the tracer of the framework from the global provider, a no-op one until
+// NewProvider is called
+func Tracer() trace.Tracer {
+	return otel.Tracer(Name)
+}
+
+// hasParent reports whether ctx carries a span, used by the instrumentation that would
+// otherwise start a new trace for every call made outside of a request
+func hasParent(ctx context.Context) bool {
+	return trace.SpanContextFromContext(ctx).IsValid()
+}
@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/haskekareem/sauri/cache"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestTracer() (*tracetest.SpanRecorder, *sdktrace.TracerProvider) {
+	recorder := tracetest.NewSpanRecorder()
+	return recorder, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+}
+
+func TestMiddleware_NamesSpansAfterTheRoute(t *testing.T) {
+	recorder, provider := newTestTracer()
+
+	mux := chi.NewRouter()
+	mux.Use(Middleware(provider.Tracer(Name)))
+	mux.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "GET /users/{id}", spans[0].Name())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	}
+}
+
+func TestMiddleware_ContinuesTheIncomingTrace(t *testing.T) {
+	recorder, provider := newTestTracer()
+	handler := Middleware(provider.Tracer(Name))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// the propagator is global, NewProvider installs it
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+	}
+}
+
+// memoryCache is a minimal cache.Cache for the tests
+type memoryCache struct {
+	cache.Cache
+	entries map[string]interface{}
+}
+
+func (m *memoryCache) Get(keyStr string) (interface{}, error) {
+	return m.entries[keyStr], nil
+}
+
+func (m *memoryCache) Set(keyStr string, value interface{}, _ ...time.Duration) error {
+	m.entries[keyStr] = value
+	return nil
+}
+
+func TestCache_TracesOnlyUnderASpan(t *testing.T) {
+	recorder, provider := newTestTracer()
+	tracer := provider.Tracer(Name)
+	c := &Cache{Cache: &memoryCache{entries: map[string]interface{}{}}, System: "memory", Tracer: tracer}
+
+	// no context, no span
+	assert.NoError(t, c.Set("a", 1))
+	assert.Empty(t, recorder.Ended())
+
+	ctx, parent := tracer.Start(context.Background(), "request")
+	value, err := c.WithContext(ctx).Get("a")
+	parent.End()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, value)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, "cache.get", spans[0].Name())
+		assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	}
+}
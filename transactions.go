@@ -0,0 +1,218 @@
+package sauri
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/haskekareem/sauri/db"
+	"github.com/jackc/pgx/v5"
+	"net/http"
+)
+
+// ErrNoDatabase is returned by DBTransaction when the application has no database
+var ErrNoDatabase = errors.New("no database connection, see DATABASE_USE")
+
+// Tx is a transaction of the default connection. It runs on pgx when the application has a
+// pgx pool and on database/sql otherwise, the other field is nil.
+type Tx struct {
+	SQL     *sql.Tx
+	Pgx     pgx.Tx
+	dialect db.Dialect
+}
+
+// Query returns the query builder running in the transaction
+func (tx *Tx) Query() *db.Conn {
+	if tx.Pgx != nil {
+		return db.NewPgx(tx.Pgx)
+	}
+	return db.New(tx.SQL, tx.dialect)
+}
+
+func (tx *Tx) commit(ctx context.Context) error {
+	if tx.Pgx != nil {
+		return tx.Pgx.Commit(ctx)
+	}
+	return tx.SQL.Commit()
+}
+
+func (tx *Tx) rollback(ctx context.Context) error {
+	if tx.Pgx != nil {
+		return tx.Pgx.Rollback(ctx)
+	}
+	return tx.SQL.Rollback()
+}
+
+// txKey is the context key of the transaction of a request
+type txKey struct{}
+
+// TxFromContext returns the transaction started by DBTransaction or the Transaction
+// middleware, nil outside of one
+func TxFromContext(ctx context.Context) *Tx {
+	tx, _ := ctx.Value(txKey{}).(*Tx)
+	return tx
+}
+
+// beginTx starts a transaction on the default connection
+func (s *Sauri) beginTx(ctx context.Context) (*Tx, error) {
+	dialect := db.DialectOf(s.DBConn.DatabaseType)
+	switch {
+	case s.DBConn.PgxConnPool != nil:
+		tx, err := s.DBConn.PgxConnPool.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &Tx{Pgx: tx, dialect: dialect}, nil
+	case s.DBConn.SqlConnPool != nil:
+		tx, err := s.DBConn.SqlConnPool.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &Tx{SQL: tx, dialect: dialect}, nil
+	}
+	return nil, ErrNoDatabase
+}
+
+// DBTransaction runs fn in a transaction of the default connection. The transaction is
+// committed when fn returns nil and rolled back when it returns an error or panics, the
+// panic goes on once it is rolled back. Inside a transaction already, see TxFromContext,
+// fn runs in that one.
+func (s *Sauri) DBTransaction(ctx context.Context, fn func(ctx context.Context, tx *Tx) error) error {
+	if tx := TxFromContext(ctx); tx != nil {
+		return fn(ctx, tx)
+	}
+
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot begin the transaction: %w", err)
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			_ = tx.rollback(context.WithoutCancel(ctx))
+			panic(rec)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx), tx); err != nil {
+		if rollbackErr := tx.rollback(context.WithoutCancel(ctx)); rollbackErr != nil {
+			return errors.Join(err, fmt.Errorf("cannot roll back: %w", rollbackErr))
+		}
+		return err
+	}
+	if err := tx.commit(ctx); err != nil {
+		return fmt.Errorf("cannot commit the transaction: %w", err)
+	}
+	return nil
+}
+
+// Transaction runs the POST, PUT, PATCH and DELETE requests in a transaction of the default
+// connection, the handlers reach it with TxFromContext. It ends right before the response
+// status is written: committed when the status is below 400, rolled back otherwise or on a
+// panic. A failed commit turns the response into a 500, so that the client never sees a
+// success for a write that was not stored.
+func (s *Sauri) Transaction(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if TxFromContext(r.Context()) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// the request context is canceled once the client is gone, the transaction still ends
+		ctx := context.WithoutCancel(r.Context())
+		tx, err := s.beginTx(ctx)
+		if err != nil {
+			s.log().Error("cannot begin the request transaction", "method", r.Method, "path", r.URL.Path, "error", err)
+			s.errorStatus(w, r, http.StatusServiceUnavailable)
+			return
+		}
+
+		tw := &txResponseWriter{ResponseWriter: w}
+		tw.end = func(status int) bool {
+			var err error
+			if status >= http.StatusBadRequest {
+				err = tx.rollback(ctx)
+			} else {
+				err = tx.commit(ctx)
+			}
+			if err == nil {
+				return true
+			}
+			s.log().Error("cannot end the request transaction", "method", r.Method, "path", r.URL.Path, "error", err)
+			if status >= http.StatusBadRequest {
+				return true // the error response goes on, nothing was stored anyway
+			}
+			s.errorStatus(w, r, http.StatusInternalServerError)
+			return false
+		}
+		defer func() {
+			if rec := recover(); rec != nil {
+				if !tw.ended {
+					_ = tx.rollback(ctx)
+				}
+				panic(rec)
+			}
+		}()
+
+		next.ServeHTTP(tw, r.WithContext(context.WithValue(r.Context(), txKey{}, tx)))
+		if !tw.ended {
+			// nothing was written, the response is an empty 200
+			tw.endTx(http.StatusOK)
+		}
+	})
+}
+
+// txResponseWriter ends the transaction of a request before the response status is written,
+// the response of the handler is dropped when the commit fails
+type txResponseWriter struct {
+	http.ResponseWriter
+	end     func(status int) bool // ends the transaction, false when the commit failed
+	ended   bool
+	dropped bool
+}
+
+// endTx ends the transaction once, it reports whether the response of the handler goes on
+func (w *txResponseWriter) endTx(status int) bool {
+	if !w.ended {
+		w.ended = true
+		w.dropped = !w.end(status)
+	}
+	return !w.dropped
+}
+
+func (w *txResponseWriter) WriteHeader(status int) {
+	// the informational statuses come before the final one
+	if status >= http.StatusContinue && status < http.StatusOK && !w.ended {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.endTx(status) {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *txResponseWriter) Write(b []byte) (int, error) {
+	if !w.endTx(http.StatusOK) {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends the buffered response, the transaction ends first
+func (w *txResponseWriter) Flush() {
+	if !w.endTx(http.StatusOK) {
+		return
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *txResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
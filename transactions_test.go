@@ -0,0 +1,178 @@
+package sauri
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// txDriver is a database/sql driver recording how the transactions end
+type txDriver struct {
+	mu         sync.Mutex
+	events     []string
+	failCommit bool
+}
+
+func (d *txDriver) record(event string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.events = append(d.events, event)
+}
+
+func (d *txDriver) Events() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.events...)
+}
+
+func (d *txDriver) Open(string) (driver.Conn, error) { return &txConn{d: d}, nil }
+
+type txConn struct{ d *txDriver }
+
+func (c *txConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *txConn) Close() error                        { return nil }
+func (c *txConn) Begin() (driver.Tx, error) {
+	c.d.record("begin")
+	return &txTx{d: c.d}, nil
+}
+
+type txTx struct{ d *txDriver }
+
+func (t *txTx) Commit() error {
+	if t.d.failCommit {
+		t.d.record("commit failed")
+		return errors.New("commit failed")
+	}
+	t.d.record("commit")
+	return nil
+}
+
+func (t *txTx) Rollback() error {
+	t.d.record("rollback")
+	return nil
+}
+
+var registerTxDriver sync.Once
+
+// newTxApp returns an application whose default connection is a txDriver
+func newTxApp(t *testing.T) (*Sauri, *txDriver) {
+	t.Helper()
+	d := &txDriver{}
+	registerTxDriver.Do(func() {
+		sql.Register("txtest", &txDriverProxy{})
+	})
+	txDrivers.Store(t.Name(), d)
+	pool, err := sql.Open("txtest", t.Name())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = pool.Close()
+		txDrivers.Delete(t.Name())
+	})
+	return &Sauri{DBConn: DatabaseConn{SqlConnPool: pool}}, d
+}
+
+// txDrivers maps the data source names to the driver of their test, sql.Register takes a
+// driver once per name
+var txDrivers sync.Map
+
+type txDriverProxy struct{}
+
+func (txDriverProxy) Open(name string) (driver.Conn, error) {
+	d, ok := txDrivers.Load(name)
+	if !ok {
+		return nil, errors.New("no test driver for " + name)
+	}
+	return d.(*txDriver).Open(name)
+}
+
+func TestTransaction_CommitsBeforeTheResponse(t *testing.T) {
+	s, d := newTxApp(t)
+	handler := s.Transaction(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NotNil(t, TxFromContext(r.Context()))
+		w.WriteHeader(http.StatusCreated)
+		// the transaction is committed once the status is written
+		assert.Equal(t, []string{"begin", "commit"}, d.Events())
+		_, _ = w.Write([]byte("created"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/items", nil))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "created", rr.Body.String())
+	assert.Equal(t, []string{"begin", "commit"}, d.Events())
+}
+
+func TestTransaction_CommitsWithoutResponse(t *testing.T) {
+	s, d := newTxApp(t)
+	handler := s.Transaction(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/items/1", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, []string{"begin", "commit"}, d.Events())
+}
+
+func TestTransaction_RollsBackOnError(t *testing.T) {
+	s, d := newTxApp(t)
+	handler := s.Transaction(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid item", http.StatusUnprocessableEntity)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/items/1", nil))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid item")
+	assert.Equal(t, []string{"begin", "rollback"}, d.Events())
+}
+
+func TestTransaction_RollsBackOnPanic(t *testing.T) {
+	s, d := newTxApp(t)
+	handler := s.Transaction(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("handler failed")
+	}))
+
+	assert.PanicsWithValue(t, "handler failed", func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPatch, "/items/1", nil))
+	})
+	assert.Equal(t, []string{"begin", "rollback"}, d.Events())
+}
+
+func TestTransaction_FailedCommit(t *testing.T) {
+	s, d := newTxApp(t)
+	d.failCommit = true
+	handler := s.Transaction(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/items/1")
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write([]byte("created"))
+		assert.NoError(t, err)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/items", nil))
+
+	// the client never sees the success of a write that was not stored
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "created")
+	assert.Equal(t, []string{"begin", "commit failed"}, d.Events())
+}
+
+func TestTransaction_SkipsReads(t *testing.T) {
+	s, d := newTxApp(t)
+	handler := s.Transaction(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Nil(t, TxFromContext(r.Context()))
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items", nil))
+
+	assert.Empty(t, d.Events())
+}
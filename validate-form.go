@@ -0,0 +1,137 @@
+package sauri
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/haskekareem/sauri/renderer"
+	"github.com/haskekareem/sauri/validator"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// validatedKey is the context key of the validation that passed in ValidateForm
+type validatedKey struct{}
+
+// ValidateForm returns a middleware validating the form or JSON body of the request against
+// the rules. When the input is invalid, requests asking for JSON get a 422 error envelope
+// and the other requests are redirected back with the errors and the old input flashed to
+// the session, where the renderer picks them up as TemplateData.Errors and FormData.
+// The handler runs only for valid input, see Validated.
+func (s *Sauri) ValidateForm(rules map[string][]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			v, err := s.requestValidator(r, rules)
+			if err != nil {
+				s.ErrorLog.Println(err)
+				s.ErrorStatus(w, http.StatusBadRequest)
+				return
+			}
+
+			if v.Validate() {
+				ctx := context.WithValue(r.Context(), validatedKey{}, v)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			if wantsJSON(r) {
+				_ = s.WriteJSON(w, http.StatusUnprocessableEntity, validator.NewValidationError(v.Errors))
+				return
+			}
+
+			s.flashValidation(r, v.Errors, r.Form)
+			http.Redirect(w, r, redirectBack(r), http.StatusSeeOther)
+		})
+	}
+}
+
+// Validated returns the input that passed ValidateForm, cast to Go types
+func Validated(r *http.Request) map[string]interface{} {
+	v, ok := r.Context().Value(validatedKey{}).(*validator.Validation)
+	if !ok {
+		return nil
+	}
+	return v.Validated()
+}
+
+// requestValidator creates a validator for the form or JSON body of the request, the JSON
+// body is put back so that handlers can read it again
+func (s *Sauri) requestValidator(r *http.Request, rules map[string][]string) (*validator.Validation, error) {
+	v := s.NewValidator(url.Values{}, nil, rules, s.DBConn.SqlConnPool, s.DBConn.PgxConnPool)
+	v.SetContext(r.Context(), 0)
+
+	if isJSONRequest(r) {
+		body, data, err := readJSONBody(r)
+		if err != nil {
+			return nil, err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		v.SetJSONData(data)
+		return v, nil
+	}
+
+	if err := r.ParseMultipartForm(maxBindMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return nil, fmt.Errorf("cannot parse form: %w", err)
+	}
+	if r.MultipartForm != nil {
+		v.SetMultipartForm(r.MultipartForm)
+	}
+	v.Data = r.Form
+	return v, nil
+}
+
+// readJSONBody reads a JSON body of up to one megabyte, like ReadJSON, and decodes it with
+// numbers kept as json.Number
+func readJSONBody(r *http.Request) ([]byte, map[string]interface{}, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read request body: %w", err)
+	}
+
+	var data map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+	return body, data, nil
+}
+
+// flashValidation stores the errors and the old input in the session for the next request
+func (s *Sauri) flashValidation(r *http.Request, errs validator.ErrorContainer, input url.Values) {
+	// the session values are JSON so that no gob registration is needed
+	if content, err := json.Marshal(errs); err == nil {
+		s.Session.Put(r.Context(), renderer.ErrorsSessionKey, string(content))
+	}
+	if content, err := json.Marshal(validator.NewOldInput(input)); err == nil {
+		s.Session.Put(r.Context(), renderer.OldInputSessionKey, string(content))
+	}
+}
+
+// redirectBack returns the page the request came from, or the home page
+func redirectBack(r *http.Request) string {
+	referer, err := url.Parse(r.Referer())
+	if err != nil || referer.Path == "" || (referer.Host != "" && referer.Host != r.Host) {
+		return "/"
+	}
+	return referer.RequestURI()
+}
+
+// wantsJSON reports whether the client asks for a JSON response rather than a page
+func wantsJSON(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(accepted))
+		switch {
+		case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+			return true
+		case mediaType == "text/html":
+			return false
+		}
+	}
+	return isJSONRequest(r)
+}
@@ -0,0 +1,138 @@
+package validator
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========================== struct binding ==========================
+
+// ValidationError is returned when the input does not pass validation. Its errors can be
+// written with WriteJSON or assigned to TemplateData.Errors.
+type ValidationError struct {
+	Message string         `json:"message"`
+	Errors  ErrorContainer `json:"errors"`
+}
+
+// NewValidationError wraps the errors into the envelope returned to API clients
+func NewValidationError(errs ErrorContainer) *ValidationError {
+	return &ValidationError{Message: "The given data was invalid", Errors: errs}
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Errors))
+	for field := range e.Errors {
+		fields = append(fields, field)
+	}
+	return fmt.Sprintf("validation failed for %d field(s): %s", len(fields), strings.Join(fields, ", "))
+}
+
+// timeLayouts are the layouts tried when binding a time.Time field
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02"}
+
+// Bind fills the exported fields of the struct dst points to with the form values, matched
+// by their form or json tag. Values that cannot be converted are returned as a
+// *ValidationError, the other fields are still filled.
+func Bind(dst any, data url.Values) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("validator: bind destination must be a pointer to a struct, got %T", dst)
+	}
+	rv = rv.Elem()
+
+	errs := ErrorContainer{}
+	for _, field := range Metadata.Struct(rv.Type()).Fields {
+		values, ok := data[field.Key]
+		if !ok {
+			values, ok = data[field.Key+"[]"]
+		}
+		if !ok || len(values) == 0 {
+			continue
+		}
+
+		if err := setField(rv.FieldByIndex(field.Index), values); err != nil {
+			errs[field.Key] = append(errs[field.Key], fmt.Sprintf("The %s field must be a valid %s", field.Key, field.Type))
+		}
+	}
+
+	if len(errs) > 0 {
+		return NewValidationError(errs)
+	}
+	return nil
+}
+
+// setField converts the form values to the type of the field
+func setField(fv reflect.Value, values []string) error {
+	switch fv.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(fv.Type().Elem())
+		if err := setField(elem.Elem(), values); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	case reflect.Slice:
+		slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setScalar(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+	return setScalar(fv, values[0])
+}
+
+// setScalar converts a single form value to the type of the field
+func setScalar(fv reflect.Value, value string) error {
+	if fv.Type() == reflect.TypeOf(time.Time{}) {
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				fv.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return fmt.Errorf("cannot parse %q as a time", value)
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		switch strings.ToLower(value) {
+		case "true", "1", "on", "yes":
+			fv.SetBool(true)
+		case "false", "0", "off", "no", "":
+			fv.SetBool(false)
+		default:
+			return fmt.Errorf("cannot parse %q as a bool", value)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
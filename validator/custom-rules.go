@@ -0,0 +1,87 @@
+package validator
+
+import (
+	"strconv"
+	"sync"
+)
+
+// ============================ custom rules =============================
+
+// RuleFunc is a custom rule with access to the whole Validation, e.g. its DIContainer,
+// the values of other fields and the database pools. The value is a string for form
+// fields and a *multipart.FileHeader for uploaded files.
+type RuleFunc func(v *Validation, field string, value interface{}, params []string) bool
+
+// Rule is a custom rule along with its default message and the number of parameters it needs
+type Rule struct {
+	Func      RuleFunc
+	Message   string // default message, the catalog placeholders such as :attribute are supported
+	MinParams int    // fewer parameters fail the rule with a configuration error
+	MaxParams int    // 0 means no maximum
+}
+
+// customRules holds the rules registered for every Validation
+var customRules = struct {
+	sync.RWMutex
+	rules map[string]Rule
+}{rules: make(map[string]Rule)}
+
+// RegisterRule registers a custom rule for every Validation of the application.
+// Built-in rules take precedence over custom rules with the same name.
+func RegisterRule(name string, rule Rule) {
+	customRules.Lock()
+	defer customRules.Unlock()
+	customRules.rules[name] = rule
+}
+
+// RegisterRule registers a custom rule for this Validation only, it takes precedence over
+// the rules registered for the whole application.
+func (v *Validation) RegisterRule(name string, rule Rule) {
+	if v.RuleFuncs == nil {
+		v.RuleFuncs = make(map[string]Rule)
+	}
+	v.RuleFuncs[name] = rule
+}
+
+// customRule returns the custom rule registered under the name, if any
+func (v *Validation) customRule(name string) (Rule, bool) {
+	if rule, ok := v.RuleFuncs[name]; ok {
+		return rule, true
+	}
+
+	customRules.RLock()
+	defer customRules.RUnlock()
+	rule, ok := customRules.rules[name]
+	return rule, ok
+}
+
+// applyCustomRule runs a custom rule after checking its number of parameters
+func (v *Validation) applyCustomRule(field string, value interface{}, parsed parsedRule, rule Rule) bool {
+	count := len(parsed.params)
+	if count < rule.MinParams || (rule.MaxParams > 0 && count > rule.MaxParams) {
+		expected := strconv.Itoa(rule.MinParams)
+		if rule.MaxParams != rule.MinParams {
+			expected += " to " + strconv.Itoa(rule.MaxParams)
+		}
+		if rule.MaxParams == 0 {
+			expected = "at least " + strconv.Itoa(rule.MinParams)
+		}
+		v.addError(field, "The %s field uses rule %s with a wrong number of parameters, it expects %s", parsed.variant("params"), parsed.name, expected)
+		return false
+	}
+
+	if rule.Func == nil || rule.Func(v, field, value, parsed.params) {
+		return true
+	}
+
+	message := rule.Message
+	if message == "" {
+		message = "The %s field failed custom validation for rule %s"
+		// custom rules share the "custom" message unless the catalog has one for the rule
+		if _, found := localizedMessage(v.Locale, parsed.name); !found {
+			parsed.message = "custom"
+		}
+	}
+	v.addError(field, message, parsed, parsed.name)
+	return false
+}
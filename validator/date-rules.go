@@ -0,0 +1,118 @@
+package validator
+
+import (
+	"strings"
+	"time"
+)
+
+// ============================ date rules =============================
+
+// dateLayouts are tried in turn when a field has no date_format rule
+var dateLayouts = []string{"2006-01-02", time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04:05"}
+
+// dateComparisons are the rules comparing the date of a field to another date
+var dateComparisons = map[string]func(value, other time.Time) bool{
+	"after":           func(value, other time.Time) bool { return value.After(other) },
+	"after_or_equal":  func(value, other time.Time) bool { return !value.Before(other) },
+	"before":          func(value, other time.Time) bool { return value.Before(other) },
+	"before_or_equal": func(value, other time.Time) bool { return !value.After(other) },
+}
+
+// dateMessages are the default messages of the date comparison rules
+var dateMessages = map[string]string{
+	"after":           "The %s field must be a date after %s",
+	"after_or_equal":  "The %s field must be a date after or equal to %s",
+	"before":          "The %s field must be a date before %s",
+	"before_or_equal": "The %s field must be a date before or equal to %s",
+}
+
+// dateFormat returns the layout of the date_format rule of a field, if it has one
+func (v *Validation) dateFormat(field string) string {
+	return rulesDateFormat(expandRules(v.Rules[field]))
+}
+
+// rulesDateFormat returns the layout of the date_format rule among rules, if there is one
+func rulesDateFormat(rules []string) string {
+	for _, rule := range rules {
+		if parsed := Metadata.rule(rule); parsed.name == "date_format" {
+			return parsed.raw
+		}
+	}
+	return ""
+}
+
+// parseDate parses a date with the layout first and then with the default layouts
+func parseDate(value, layout string) (time.Time, bool) {
+	layouts := dateLayouts
+	if layout != "" {
+		layouts = append([]string{layout}, dateLayouts...)
+	}
+	for _, l := range layouts {
+		if t, err := time.Parse(l, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// isDateFormat checks if a value is a date written exactly in the layout, e.g. 02/01/2006
+func (v *Validation) isDateFormat(value, layout string) bool {
+	t, err := time.Parse(layout, value)
+	return err == nil && t.Format(layout) == value
+}
+
+// isTimezone checks if a value is an IANA time zone name such as Europe/Paris
+func (v *Validation) isTimezone(value string) bool {
+	if value == "" || value == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(value)
+	return err == nil
+}
+
+// dateParam resolves the parameter of a date comparison, which is either another field,
+// one of now, today, tomorrow and yesterday, or a date written in layout. An absent other
+// field is not compared, the rules of that field decide whether it is required.
+func (v *Validation) dateParam(param, layout string) (date time.Time, compare bool, ok bool) {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch strings.ToLower(param) {
+	case "now":
+		return now, true, true
+	case "today":
+		return today, true, true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true, true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true, true
+	}
+
+	if other, exists := v.getFieldValue(param); exists {
+		strValue, isString := other.(string)
+		if !isString || strValue == "" {
+			return time.Time{}, false, true
+		}
+		date, ok = parseDate(strValue, v.dateFormat(param))
+		return date, true, ok
+	}
+	if _, isField := v.Rules[param]; isField {
+		return time.Time{}, false, true
+	}
+
+	date, ok = parseDate(param, layout)
+	return date, true, ok
+}
+
+// compareDate applies one of the date comparison rules to the value of a field, the layout
+// of the dates is the one of the date_format rule among the rules of the field
+func (v *Validation) compareDate(value string, parsed parsedRule, rules []string) bool {
+	layout := rulesDateFormat(rules)
+	other, compare, ok := v.dateParam(parsed.raw, layout)
+	if !compare {
+		return true
+	}
+
+	date, valid := parseDate(value, layout)
+	return ok && valid && dateComparisons[parsed.name](date, other)
+}
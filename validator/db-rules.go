@@ -0,0 +1,156 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ===================== database backed rules ==========================
+
+// defaultDBTimeout is the deadline shared by all the database rules of a validation
+const defaultDBTimeout = 3 * time.Second
+
+// dbRules query the database, Validate runs them concurrently after the other rules
+var dbRules = map[string]bool{
+	"unique": true,
+	"exists": true,
+}
+
+// dbCheck is a database rule waiting to be run
+type dbCheck struct {
+	field string
+	value string
+	rule  parsedRule
+}
+
+// dbContext returns the context database rules run with, bounded by DBTimeout
+func (v *Validation) dbContext() (context.Context, context.CancelFunc) {
+	ctx := v.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timeout := v.DBTimeout
+	if timeout <= 0 {
+		timeout = defaultDBTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// runDBChecks runs the independent database rules concurrently under one shared deadline,
+// then records their errors in the order the rules were declared
+func (v *Validation) runDBChecks(checks []dbCheck, mode BailMode) {
+	if len(checks) == 0 {
+		return
+	}
+
+	ctx, cancel := v.dbContext()
+	defer cancel()
+
+	passed := make([]bool, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check dbCheck) {
+			defer wg.Done()
+			passed[i] = v.checkDBRule(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	failed := make(map[string]bool)
+	for i, check := range checks {
+		if passed[i] || (mode == BailField && failed[check.field]) {
+			continue
+		}
+		v.addDBError(check)
+		failed[check.field] = true
+		if mode == BailAll {
+			return
+		}
+	}
+}
+
+// checkDBRule runs a single database rule
+func (v *Validation) checkDBRule(ctx context.Context, check dbCheck) bool {
+	table, column := v.dbTarget(check.field, check.rule)
+	switch check.rule.name {
+	case "unique":
+		return v.isUnique(ctx, table, column, check.value)
+	case "exists":
+		return v.exists(ctx, table, column, check.value)
+	}
+	return true
+}
+
+// addDBError records the error message of a failed database rule
+func (v *Validation) addDBError(check dbCheck) {
+	switch check.rule.name {
+	case "unique":
+		v.addError(check.field, "The %s field must be unique", check.rule)
+	case "exists":
+		v.addError(check.field, "The %s field does not exist", check.rule)
+	}
+}
+
+// dbTarget returns the table and column of a rule such as "unique:users" or
+// "unique:users,email", the column defaults to the field name
+func (v *Validation) dbTarget(field string, rule parsedRule) (string, string) {
+	table := paramAt(rule.params, 0)
+	column := paramAt(rule.params, 1)
+	if column == "" {
+		column = field
+	}
+	return table, column
+}
+
+// isUnique checks if a value is not yet used in the column of the table.
+func (v *Validation) isUnique(ctx context.Context, table, column, value string) bool {
+	//This line builds an SQL query to check how many rows in the table have
+	//the given column equal to the value.
+	query := fmt.Sprintf("SELECT COUNT(1) FROM %s WHERE %s = %s", table, column, v.placeholder())
+
+	var count int
+	if err := v.queryRow(ctx, query, value, &count); err != nil {
+		return false
+	}
+
+	//If count == 0, it means the value is unique (because no rows were found in the database
+	//with that value), so the function returns true.
+	return count == 0
+}
+
+// exists checks if a value exists in the column of the table.
+func (v *Validation) exists(ctx context.Context, table, column, value string) bool {
+	query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE %s = %s)", table, column, v.placeholder())
+
+	var exist bool
+	if err := v.queryRow(ctx, query, value, &exist); err != nil {
+		return false
+	}
+	return exist
+}
+
+// queryRow runs a single row query on the pgx pool when there is one, otherwise on the
+// database/sql pool
+func (v *Validation) queryRow(ctx context.Context, query, value string, dest interface{}) error {
+	if v.DBPool.PoolPGX != nil {
+		return v.DBPool.PoolPGX.QueryRow(ctx, query, value).Scan(dest)
+	}
+	if v.DBPool.DBPoolSQL != nil {
+		return v.DBPool.DBPoolSQL.QueryRowContext(ctx, query, value).Scan(dest)
+	}
+	return fmt.Errorf("validator: no database connection for %s", query)
+}
+
+// placeholder returns the bind parameter syntax of the database in use
+func (v *Validation) placeholder() string {
+	if v.DBPool.PoolPGX == nil && v.DBPool.DBPoolSQL != nil {
+		if driver := fmt.Sprintf("%T", v.DBPool.DBPoolSQL.Driver()); strings.Contains(strings.ToLower(driver), "mysql") {
+			return "?"
+		}
+	}
+	return "$1"
+}
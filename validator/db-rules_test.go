@@ -0,0 +1,123 @@
+package validator
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowDriver is a database/sql driver answering every query after a delay, the taken
+// values are the ones already stored in the database
+type slowDriver struct {
+	delay time.Duration
+	taken map[string]bool
+}
+
+func (d *slowDriver) Open(string) (driver.Conn, error) { return &slowConn{d}, nil }
+
+type slowConn struct{ d *slowDriver }
+
+func (c *slowConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *slowConn) Close() error                        { return nil }
+func (c *slowConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *slowConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	select {
+	case <-time.After(c.d.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	found := c.d.taken[args[0].Value.(string)]
+	if strings.HasPrefix(query, "SELECT COUNT") {
+		count := int64(0)
+		if found {
+			count = 1
+		}
+		return &slowRows{value: count}, nil
+	}
+	return &slowRows{value: found}, nil
+}
+
+type slowRows struct {
+	value driver.Value
+	done  bool
+}
+
+func (r *slowRows) Columns() []string { return []string{"result"} }
+func (r *slowRows) Close() error      { return nil }
+func (r *slowRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+func newSlowDB(delay time.Duration, taken ...string) *sql.DB {
+	d := &slowDriver{delay: delay, taken: make(map[string]bool)}
+	for _, value := range taken {
+		d.taken[value] = true
+	}
+	return sql.OpenDB(connectorFunc(func() (driver.Conn, error) { return d.Open("") }))
+}
+
+// connectorFunc turns a function into a driver.Connector
+type connectorFunc func() (driver.Conn, error)
+
+func (f connectorFunc) Connect(context.Context) (driver.Conn, error) { return f() }
+func (f connectorFunc) Driver() driver.Driver                        { return &slowDriver{} }
+
+func TestValidation_DBRulesRunConcurrently(t *testing.T) {
+	db := newSlowDB(200*time.Millisecond, "taken@example.com")
+	defer db.Close()
+
+	v := newTestValidationData(
+		url.Values{"email": {"taken@example.com"}, "username": {"ada"}, "team": {"core"}},
+		map[string]string{"email": "email|unique:users", "username": "unique:users", "team": "exists:teams,name"},
+	)
+	v.DBPool.DBPoolSQL = db
+
+	start := time.Now()
+	assert.False(t, v.Validate())
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "database rules should not run one after another")
+
+	assert.Equal(t, []string{"The email field must be unique"}, v.Errors["email"])
+	assert.Empty(t, v.Errors["username"])
+	assert.Equal(t, []string{"The team field does not exist"}, v.Errors["team"])
+}
+
+func TestValidation_DBRulesShareDeadline(t *testing.T) {
+	db := newSlowDB(time.Second)
+	defer db.Close()
+
+	v := newTestValidationData(url.Values{"username": {"ada"}}, map[string]string{"username": "unique:users"})
+	v.DBPool.DBPoolSQL = db
+	v.SetContext(context.Background(), 50*time.Millisecond)
+
+	start := time.Now()
+	assert.False(t, v.Validate())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestValidation_DBRulesSkippedWhenFieldBails(t *testing.T) {
+	db := newSlowDB(0)
+	defer db.Close()
+
+	v := newTestValidationData(url.Values{"email": {"not-an-email"}}, map[string]string{"email": "email|unique:users"})
+	v.DBPool.DBPoolSQL = db
+	v.SetBailMode(BailField)
+
+	assert.False(t, v.Validate())
+	assert.Equal(t, []string{"The email field must be a valid email address"}, v.Errors["email"])
+}
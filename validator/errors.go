@@ -0,0 +1,116 @@
+package validator
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ============================ error bag =============================
+
+// Has reports whether the field has any error
+func (ec ErrorContainer) Has(field string) bool {
+	return len(ec[field]) > 0
+}
+
+// Any reports whether there is any error at all
+func (ec ErrorContainer) Any() bool {
+	for _, messages := range ec {
+		if len(messages) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// First returns the first error of the field, or an empty string
+func (ec ErrorContainer) First(field string) string {
+	if messages := ec[field]; len(messages) > 0 {
+		return messages[0]
+	}
+	return ""
+}
+
+// Get returns every error of the field
+func (ec ErrorContainer) Get(field string) []string {
+	return ec[field]
+}
+
+// All returns every error of every field, ordered by field
+func (ec ErrorContainer) All() []string {
+	var all []string
+	for _, field := range ec.Fields() {
+		all = append(all, ec[field]...)
+	}
+	return all
+}
+
+// Fields returns the fields with errors in alphabetical order
+func (ec ErrorContainer) Fields() []string {
+	fields := make([]string, 0, len(ec))
+	for field, messages := range ec {
+		if len(messages) > 0 {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// MarshalJSON always writes an object of message lists, an empty bag gives {} instead of
+// null and fields without errors are left out
+func (ec ErrorContainer) MarshalJSON() ([]byte, error) {
+	bag := make(map[string][]string, len(ec))
+	for _, field := range ec.Fields() {
+		bag[field] = ec[field]
+	}
+	return json.Marshal(bag)
+}
+
+// ============================ old input =============================
+
+// OldInput holds the input of a failed submission so that forms can be filled again
+type OldInput url.Values
+
+// NewOldInput keeps the input worth giving back, passwords and the CSRF token are dropped
+func NewOldInput(data url.Values) OldInput {
+	old := make(OldInput, len(data))
+	for field, values := range data {
+		if field == "csrf_token" || strings.Contains(strings.ToLower(field), "password") {
+			continue
+		}
+		old[field] = values
+	}
+	return old
+}
+
+// Get returns the first value of the field, or the fallback when there is none
+func (o OldInput) Get(field string, fallback ...string) string {
+	if values := o[field]; len(values) > 0 {
+		return values[0]
+	}
+	if len(fallback) > 0 {
+		return fallback[0]
+	}
+	return ""
+}
+
+// Values returns every value of the field, e.g. the checked boxes of a group
+func (o OldInput) Values(field string) []string {
+	return o[field]
+}
+
+// Has reports whether the field was submitted with the value, or at all without one
+func (o OldInput) Has(field string, value ...string) bool {
+	values, ok := o[field]
+	if len(value) == 0 {
+		return ok
+	}
+	for _, v := range values {
+		if v == value[0] {
+			return true
+		}
+	}
+	return false
+}
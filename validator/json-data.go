@@ -0,0 +1,106 @@
+package validator
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// ======================= decoded JSON bodies support =======================
+
+// SetJSONData sets decoded JSON data to be validated. Nested values are addressed with
+// dot paths ("address.city") and arrays with indexes or wildcards ("items.*.price").
+func (v *Validation) SetJSONData(data map[string]interface{}) {
+	v.JSONData = data
+}
+
+// expandField turns a rule path containing wildcards into the concrete paths that exist
+// in the JSON data, e.g. "items.*.price" into "items.0.price" and "items.1.price"
+func (v *Validation) expandField(field string) []string {
+	if v.JSONData == nil || !strings.Contains(field, "*") {
+		return []string{field}
+	}
+
+	paths := []string{""}
+	for _, segment := range strings.Split(field, ".") {
+		var next []string
+		for _, prefix := range paths {
+			if segment != "*" {
+				next = append(next, joinPath(prefix, segment))
+				continue
+			}
+			// expand the wildcard over the keys or indexes of the current node
+			node, ok := lookupJSON(v.JSONData, prefix)
+			if !ok {
+				continue
+			}
+			switch typed := node.(type) {
+			case []interface{}:
+				for i := range typed {
+					next = append(next, joinPath(prefix, strconv.Itoa(i)))
+				}
+			case map[string]interface{}:
+				for key := range typed {
+					next = append(next, joinPath(prefix, key))
+				}
+			}
+		}
+		paths = next
+	}
+	return paths
+}
+
+// joinPath joins two dot path segments
+func joinPath(prefix, segment string) string {
+	if prefix == "" {
+		return segment
+	}
+	return prefix + "." + segment
+}
+
+// lookupJSON walks the decoded JSON data following a dot path
+func lookupJSON(data map[string]interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return data, true
+	}
+
+	var node interface{} = data
+	for _, segment := range strings.Split(path, ".") {
+		switch typed := node.(type) {
+		case map[string]interface{}:
+			value, ok := typed[segment]
+			if !ok {
+				return nil, false
+			}
+			node = value
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(typed) {
+				return nil, false
+			}
+			node = typed[index]
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// jsonRuleValue converts a decoded JSON scalar to the string form the rules work on,
+// objects and arrays are returned as they are
+func jsonRuleValue(value interface{}) (interface{}, bool) {
+	switch typed := value.(type) {
+	case nil:
+		return nil, false
+	case string:
+		return typed, true
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64), true
+	case json.Number:
+		return typed.String(), true
+	case bool:
+		return strconv.FormatBool(typed), true
+	default:
+		return typed, true
+	}
+}
@@ -0,0 +1,162 @@
+package validator
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ============================ message catalogs ============================
+
+// placeholderNames names the parameters of the rules for the message placeholders,
+// e.g. "between:3:5" gives :min=3 and :max=5
+var placeholderNames = map[string][]string{
+	"min":              {"min"},
+	"max":              {"max"},
+	"between":          {"min", "max"},
+	"digits":           {"digits"},
+	"max_size":         {"max"},
+	"max_total_size":   {"max"},
+	"max_files":        {"max"},
+	"min_items":        {"min"},
+	"max_items":        {"max"},
+	"image-dimensions": {"width", "height"},
+	"required_if":      {"other"},
+	"required_unless":  {"other"},
+}
+
+// catalogs holds the messages of every locale keyed by rule, variants of a rule are
+// keyed "rule.variant" (e.g. "between.string")
+var catalogs = struct {
+	sync.RWMutex
+	locales map[string]map[string]string
+}{
+	locales: map[string]map[string]string{
+		"en": {
+			"required":            "This :attribute is required",
+			"required_if":         "The :attribute field is required when :other is :values",
+			"required_unless":     "The :attribute field is required unless :other is in :values",
+			"required_with":       "The :attribute field is required when :values is present",
+			"min_items":           "The :attribute field must have at least :min items",
+			"max_items":           "The :attribute field must not have more than :max items",
+			"name_format":         "Must start with a letter and contain only letters and numbers",
+			"email":               "The :attribute field must be a valid email address",
+			"min":                 "The :attribute field must be at least :min characters.",
+			"max":                 "The :attribute field must not exceed :max characters.",
+			"regexp":              "The :attribute field format is invalid",
+			"numeric":             "The :attribute field must be a number",
+			"date":                "The :attribute field must be a valid date in YYYY-MM-DD format",
+			"date_format":         "The :attribute field must match the format :param",
+			"after":               "The :attribute field must be a date after :param",
+			"after_or_equal":      "The :attribute field must be a date after or equal to :param",
+			"before":              "The :attribute field must be a date before :param",
+			"before_or_equal":     "The :attribute field must be a date before or equal to :param",
+			"timezone":            "The :attribute field must be a valid time zone",
+			"confirmed":           "The :attribute field confirmation does not match",
+			"unique":              "The :attribute field must be unique",
+			"exists":              "The :attribute field does not exist",
+			"file":                "The :attribute field must be a valid file",
+			"mimes":               "The :attribute field must be a file of type: :values",
+			"max_size":            "The :attribute field must not exceed :max kilobytes",
+			"image-dimensions":    "The :attribute must be at least :width pixels wide and :height pixels tall.",
+			"max_files":           "The :attribute field must not have more than :max files",
+			"max_total_size":      "The :attribute files must not exceed :max kilobytes in total",
+			"password.mixed_case": "The :attribute field must contain both uppercase and lowercase letters",
+			"password.symbol":     "The :attribute field must contain at least one symbol",
+			"password.number":     "The :attribute field must contain at least one number",
+			"password.letter":     "The :attribute field must contain at least one letter",
+			"password.common":     "The :attribute field is a commonly used password",
+			"password.weak":       "The :attribute field is too easy to guess",
+			"url":                 "The :attribute field must be a valid URL",
+			"uuid":                "The :attribute field must be a valid UUID",
+			"ip":                  "The :attribute field must be a valid IP address",
+			"json":                "The :attribute field must be a valid JSON string",
+			"boolean":             "The :attribute field must be true or false",
+			"in":                  "The selected :attribute is invalid, it must be one of: :values",
+			"not_in":              "The selected :attribute is invalid",
+			"between.numeric":     "The :attribute field must be between :min and :max",
+			"between.string":      "The :attribute field must be between :min and :max characters",
+			"digits":              "The :attribute field must be :digits digits",
+			"alpha_dash":          "The :attribute field must only contain letters, numbers, dashes and underscores",
+			"starts_with":         "The :attribute field must start with one of the following: :values",
+			"ends_with":           "The :attribute field must end with one of the following: :values",
+			"custom":              "The :attribute field failed custom validation for rule :rule",
+		},
+	},
+}
+
+// RegisterMessages adds or replaces the messages of a locale, keyed by rule name. Messages
+// can use the :attribute, :param, :values, :rule and named placeholders such as :min.
+func RegisterMessages(locale string, messages map[string]string) {
+	catalogs.Lock()
+	defer catalogs.Unlock()
+
+	locale = strings.ToLower(locale)
+	if catalogs.locales[locale] == nil {
+		catalogs.locales[locale] = make(map[string]string)
+	}
+	for key, message := range messages {
+		catalogs.locales[locale][key] = message
+	}
+}
+
+// localizedMessage looks the message of a rule up in the catalog of the locale, falling back
+// from a regional locale ("pt-br") to its language ("pt"). No locale means no lookup.
+func localizedMessage(locale, key string) (string, bool) {
+	if locale == "" {
+		return "", false
+	}
+
+	catalogs.RLock()
+	defer catalogs.RUnlock()
+
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	for _, candidate := range []string{locale, strings.Split(locale, "-")[0]} {
+		if messages, ok := catalogs.locales[candidate]; ok {
+			if message, ok := messages[key]; ok {
+				return message, true
+			}
+			if base, _, found := strings.Cut(key, "."); found {
+				if message, ok := messages[base]; ok {
+					return message, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// replacePlaceholders fills the :placeholders of a message with the alias and rule parameters
+func replacePlaceholders(message, alias string, rule parsedRule) string {
+	if !strings.Contains(message, ":") {
+		return message
+	}
+
+	values := rule.params
+	if rule.name == "required_if" || rule.name == "required_unless" {
+		values = values[min(1, len(values)):]
+	}
+
+	replacements := map[string]string{
+		":attribute": alias,
+		":param":     rule.raw,
+		":values":    strings.Join(values, ", "),
+		":rule":      rule.name,
+	}
+	for i, name := range placeholderNames[rule.name] {
+		replacements[":"+name] = paramAt(rule.params, i)
+	}
+
+	// replace the longest placeholders first so that :param does not eat into :params
+	keys := make([]string, 0, len(replacements))
+	for key := range replacements {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+
+	pairs := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		pairs = append(pairs, key, replacements[key])
+	}
+	return strings.NewReplacer(pairs...).Replace(message)
+}
@@ -0,0 +1,232 @@
+package validator
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// FieldMeta describes a single exported struct field
+type FieldMeta struct {
+	Name   string       // Go field name
+	Index  []int        // index sequence for reflect.Value.FieldByIndex
+	Key    string       // form/json key the field is bound from
+	Column string       // database column taken from the db tag
+	Rules  []string     // validation rules taken from the validate tag
+	Type   reflect.Type // type of the field
+}
+
+// StructMeta holds the reflection metadata of a struct type
+type StructMeta struct {
+	Type   reflect.Type
+	Fields []FieldMeta
+}
+
+// Field returns the metadata of the field bound to the given key
+func (sm *StructMeta) Field(key string) (FieldMeta, bool) {
+	for _, f := range sm.Fields {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return FieldMeta{}, false
+}
+
+// RuleSet returns the validation rules of every field that has any
+func (sm *StructMeta) RuleSet() map[string][]string {
+	rules := make(map[string][]string)
+	for _, f := range sm.Fields {
+		if len(f.Rules) > 0 {
+			rules[f.Key] = append([]string(nil), f.Rules...)
+		}
+	}
+	return rules
+}
+
+// parsedRule is a rule split into its name and parameters
+type parsedRule struct {
+	name    string
+	raw     string // everything after the first colon
+	params  []string
+	message string // message catalog key when it differs from the name
+}
+
+// variant returns the rule with the message key of one of its messages, e.g. "between.string"
+func (pr parsedRule) variant(key string) parsedRule {
+	pr.message = pr.name + "." + key
+	return pr
+}
+
+// messageKey returns the key of the rule in the message catalogs
+func (pr parsedRule) messageKey() string {
+	if pr.message != "" {
+		return pr.message
+	}
+	return pr.name
+}
+
+// MetadataCache caches parsed rules, compiled regular expressions and struct metadata
+// so that they are computed once per rule or type instead of on every request.
+// In development mode nothing is cached, so changes are picked up immediately.
+type MetadataCache struct {
+	mu      sync.RWMutex
+	devMode bool
+	structs map[reflect.Type]*StructMeta
+	rules   map[string]parsedRule
+	regexps map[string]*regexp.Regexp
+}
+
+// Metadata is the application wide metadata cache used by every Validation
+var Metadata = NewMetadataCache()
+
+// NewMetadataCache creates an empty MetadataCache
+func NewMetadataCache() *MetadataCache {
+	return &MetadataCache{
+		structs: make(map[reflect.Type]*StructMeta),
+		rules:   make(map[string]parsedRule),
+		regexps: make(map[string]*regexp.Regexp),
+	}
+}
+
+// SetDevMode turns caching off in development mode and clears what was cached
+func (mc *MetadataCache) SetDevMode(devMode bool) {
+	mc.mu.Lock()
+	mc.devMode = devMode
+	mc.mu.Unlock()
+	mc.Reset()
+}
+
+// Reset invalidates everything that has been cached
+func (mc *MetadataCache) Reset() {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.structs = make(map[reflect.Type]*StructMeta)
+	mc.rules = make(map[string]parsedRule)
+	mc.regexps = make(map[string]*regexp.Regexp)
+}
+
+// Struct returns the metadata of a struct type (or pointer to struct), computing it lazily
+func (mc *MetadataCache) Struct(t reflect.Type) *StructMeta {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	mc.mu.RLock()
+	meta, ok := mc.structs[t]
+	devMode := mc.devMode
+	mc.mu.RUnlock()
+	if ok {
+		return meta
+	}
+
+	meta = buildStructMeta(t)
+	if !devMode {
+		mc.mu.Lock()
+		mc.structs[t] = meta
+		mc.mu.Unlock()
+	}
+	return meta
+}
+
+// rule returns the parsed version of a rule string
+func (mc *MetadataCache) rule(rule string) parsedRule {
+	mc.mu.RLock()
+	pr, ok := mc.rules[rule]
+	devMode := mc.devMode
+	mc.mu.RUnlock()
+	if ok {
+		return pr
+	}
+
+	pr = parseRule(rule)
+	if !devMode {
+		mc.mu.Lock()
+		mc.rules[rule] = pr
+		mc.mu.Unlock()
+	}
+	return pr
+}
+
+// regexp returns the compiled version of a pattern
+func (mc *MetadataCache) regexp(pattern string) (*regexp.Regexp, error) {
+	mc.mu.RLock()
+	re, ok := mc.regexps[pattern]
+	mc.mu.RUnlock()
+	if ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	// compiled patterns never change so they are cached in development mode too
+	mc.mu.Lock()
+	mc.regexps[pattern] = re
+	mc.mu.Unlock()
+	return re, nil
+}
+
+// buildStructMeta walks the exported fields of a struct type, including embedded structs
+func buildStructMeta(t reflect.Type) *StructMeta {
+	meta := &StructMeta{Type: t}
+	if t.Kind() != reflect.Struct {
+		return meta
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		// flatten embedded structs into the parent
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && sf.Tag.Get("form") == "" && sf.Tag.Get("json") == "" {
+			for _, f := range buildStructMeta(sf.Type).Fields {
+				f.Index = append([]int{i}, f.Index...)
+				meta.Fields = append(meta.Fields, f)
+			}
+			continue
+		}
+
+		key := tagName(sf.Tag.Get("form"))
+		if key == "" {
+			key = tagName(sf.Tag.Get("json"))
+		}
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = sf.Name
+		}
+
+		column := tagName(sf.Tag.Get("db"))
+		if column == "" {
+			column = strings.ToLower(sf.Name)
+		}
+
+		var rules []string
+		if tag := sf.Tag.Get("validate"); tag != "" {
+			rules = ParseRules(tag)
+		}
+
+		meta.Fields = append(meta.Fields, FieldMeta{
+			Name:   sf.Name,
+			Index:  []int{i},
+			Key:    key,
+			Column: column,
+			Rules:  rules,
+			Type:   sf.Type,
+		})
+	}
+	return meta
+}
+
+// tagName returns the name part of a struct tag such as `json:"name,omitempty"`
+func tagName(tag string) string {
+	name, _, _ := strings.Cut(tag, ",")
+	return name
+}
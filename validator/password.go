@@ -0,0 +1,190 @@
+package validator
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ======================= password strength ==========================
+
+//go:embed common-passwords.txt
+var commonPasswordsList string
+
+// commonPasswords is the blacklist of passwords that are too common to be accepted, the
+// embedded list only holds the most common ones, see LoadPasswordBlacklist
+var commonPasswords = struct {
+	sync.RWMutex
+	words map[string]bool
+}{words: parseWordList(strings.NewReader(commonPasswordsList))}
+
+// parseWordList reads one lowercase word per line, blank lines and # comments are skipped
+func parseWordList(r io.Reader) map[string]bool {
+	words := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if word != "" && !strings.HasPrefix(word, "#") {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+// LoadPasswordBlacklist adds the passwords of a word list (one per line) to the embedded
+// list of common passwords, e.g. a bigger top passwords list.
+func LoadPasswordBlacklist(r io.Reader) {
+	words := parseWordList(r)
+
+	commonPasswords.Lock()
+	defer commonPasswords.Unlock()
+	for word := range words {
+		commonPasswords.words[word] = true
+	}
+}
+
+// leetReplacer undoes the usual character substitutions, e.g. p@ssw0rd
+var leetReplacer = strings.NewReplacer("@", "a", "4", "a", "3", "e", "1", "i", "!", "i", "0", "o", "$", "s", "5", "s", "7", "t")
+
+// IsCommonPassword reports whether a password, or the word it is built on with digits and
+// symbols around it or leetspeak substitutions, is in the blacklist. The embedded blacklist
+// has the ~290 most common passwords, a full top 10,000 list is added with
+// LoadPasswordBlacklist.
+func IsCommonPassword(password string) bool {
+	lower := strings.ToLower(password)
+	stripped := strings.TrimFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) })
+
+	commonPasswords.RLock()
+	defer commonPasswords.RUnlock()
+	for _, candidate := range []string{lower, stripped, leetReplacer.Replace(lower), leetReplacer.Replace(stripped)} {
+		if candidate != "" && commonPasswords.words[candidate] {
+			return true
+		}
+	}
+	return false
+}
+
+// PasswordEntropy estimates the entropy of a password in bits from the character classes
+// it uses, discounting repeated characters, sequences and keyboard rows.
+func PasswordEntropy(password string) float64 {
+	if password == "" {
+		return 0
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, char := range password {
+		switch {
+		case unicode.IsLower(char):
+			lower = true
+		case unicode.IsUpper(char):
+			upper = true
+		case unicode.IsDigit(char):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	pool := 0
+	if lower {
+		pool += 26
+	}
+	if upper {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if symbol {
+		pool += 33
+	}
+
+	return float64(effectiveLength(password)) * math.Log2(float64(pool))
+}
+
+// keyboardRows are typed in sequence so often that they count as a single character
+var keyboardRows = []string{"qwertyuiop", "asdfghjkl", "zxcvbnm", "1234567890", "abcdefghijklmnopqrstuvwxyz"}
+
+// effectiveLength counts the characters of a password that add to its entropy: repeats of
+// the previous character and steps of a sequence (abc, 123, qwe, cba) do not count
+func effectiveLength(password string) int {
+	runes := []rune(strings.ToLower(password))
+	length := 1
+	for i := 1; i < len(runes); i++ {
+		prev, curr := runes[i-1], runes[i]
+		if curr == prev || inSequence(prev, curr) {
+			continue
+		}
+		length++
+	}
+	return length
+}
+
+// inSequence reports whether curr follows prev in a keyboard row or the alphabet, either way
+func inSequence(prev, curr rune) bool {
+	if curr-prev == 1 || prev-curr == 1 {
+		return true
+	}
+	for _, row := range keyboardRows {
+		i := strings.IndexRune(row, prev)
+		j := strings.IndexRune(row, curr)
+		if i >= 0 && j >= 0 && (j-i == 1 || i-j == 1) {
+			return true
+		}
+	}
+	return false
+}
+
+// PasswordScore rates a password from 0 (too guessable) to 4 (very unguessable) like
+// zxcvbn does, common passwords always score 0.
+func PasswordScore(password string) int {
+	if IsCommonPassword(password) {
+		return 0
+	}
+
+	entropy := PasswordEntropy(password)
+	switch {
+	case entropy < 28:
+		return 0
+	case entropy < 36:
+		return 1
+	case entropy < 60:
+		return 2
+	case entropy < 80:
+		return 3
+	}
+	return 4
+}
+
+// passwordOptions are the parameters of the password rule, e.g. password:strong or
+// password:score=3,entropy=50
+type passwordOptions struct {
+	strong     bool
+	minScore   int
+	minEntropy float64
+}
+
+// parsePasswordOptions reads the parameters of the password rule
+func parsePasswordOptions(params []string) passwordOptions {
+	var opts passwordOptions
+	for _, param := range params {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		switch key {
+		case "strong":
+			opts.strong = true
+			opts.minScore = max(opts.minScore, 3)
+		case "score":
+			// a score only raises the minimum, strong keeps at least 3 whatever the order
+			score, _ := strconv.Atoi(value)
+			opts.minScore = max(opts.minScore, score)
+		case "entropy":
+			opts.minEntropy, _ = strconv.ParseFloat(value, 64)
+		}
+	}
+	return opts
+}
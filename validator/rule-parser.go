@@ -0,0 +1,121 @@
+package validator
+
+import "strings"
+
+// ============================ rule parsing =============================
+
+// patternRules take their whole parameter verbatim since patterns may contain separators
+var patternRules = map[string]bool{
+	"regexp": true,
+}
+
+// isPatternRule reports whether a rule takes its parameter verbatim, "each" wrapping a
+// pattern rule included
+func isPatternRule(name, rest string) bool {
+	if name == "each" {
+		inner, _, _ := strings.Cut(rest, ":")
+		return patternRules[strings.TrimSpace(inner)]
+	}
+	return patternRules[name]
+}
+
+// ParseRules splits a rule string such as "required|min:3|max:20" into single rules.
+// A pipe can be escaped with a backslash ("\|") to be kept inside a rule.
+func ParseRules(rules string) []string {
+	var result []string
+	for _, rule := range splitUnescaped(rules, '|') {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			result = append(result, rule)
+		}
+	}
+	return result
+}
+
+// expandRules turns the rules of a field into single rules. Elements holding a pattern
+// rule are kept verbatim so that alternations like "regexp:^(a|b)$" are not split.
+func expandRules(fieldRules []string) []string {
+	var result []string
+	for _, element := range fieldRules {
+		name, rest, _ := strings.Cut(element, ":")
+		if isPatternRule(strings.TrimSpace(name), rest) {
+			result = append(result, element)
+			continue
+		}
+		result = append(result, ParseRules(element)...)
+	}
+	return result
+}
+
+// parseRule splits a rule into its name, its raw parameter and the parameters separated
+// by colons or commas, e.g. "between:1:10" gives the params 1 and 10. Separators can be
+// escaped with a backslash. Pattern rules keep their whole parameter as a single one, only
+// their escaped pipes are unescaped, e.g. "regexp:^(a\|b)$" gives the pattern ^(a|b)$.
+func parseRule(rule string) parsedRule {
+	name, rest, found := strings.Cut(rule, ":")
+	pr := parsedRule{name: strings.TrimSpace(name)}
+	if !found {
+		return pr
+	}
+
+	// each wraps another rule which is parsed again when it is applied
+	if pr.name == "each" {
+		pr.raw = rest
+		pr.params = []string{rest}
+		return pr
+	}
+	if patternRules[pr.name] {
+		// only the escaped pipes are rule syntax, the other backslashes belong to the pattern
+		pr.raw = strings.ReplaceAll(rest, `\|`, "|")
+		pr.params = []string{pr.raw}
+		return pr
+	}
+
+	pr.raw = unescape(rest)
+	for _, param := range splitUnescaped(rest, ':', ',') {
+		pr.params = append(pr.params, unescape(param))
+	}
+	return pr
+}
+
+// splitUnescaped splits s on any of the separators that is not preceded by a backslash,
+// the escapes are kept so that the parts can be split again
+func splitUnescaped(s string, separators ...byte) []string {
+	var parts []string
+	var current strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) {
+			current.WriteByte(c)
+			current.WriteByte(s[i+1])
+			i++
+			continue
+		}
+		if isSeparator(c, separators) {
+			parts = append(parts, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteByte(c)
+	}
+	return append(parts, current.String())
+}
+
+// isSeparator reports whether c is one of the separators
+func isSeparator(c byte, separators []byte) bool {
+	for _, sep := range separators {
+		if c == sep {
+			return true
+		}
+	}
+	return false
+}
+
+// unescape removes the backslash in front of escaped separators
+func unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	replacer := strings.NewReplacer(`\|`, "|", `\:`, ":", `\,`, ",", `\\`, `\`)
+	return replacer.Replace(s)
+}
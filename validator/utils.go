@@ -1,23 +1,38 @@
 package validator
 
 import (
-	"context"
-	"fmt"
+	"encoding/json"
+	"errors"
 	"image"
+	"io"
+	"mime"
 	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 	"unicode"
+	"unicode/utf8"
 )
 
 //  ========================== utility functions ===========================
 
+// patterns used by the built-in rules, compiled once
+var (
+	emailRegex      = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+	nameFormatRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9]*$`)
+	letterRegex     = regexp.MustCompile(`[a-zA-Z]`)
+	uuidRegex       = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	alphaDashRegex  = regexp.MustCompile(`^[\p{L}\p{N}_-]+$`)
+)
+
 // isValidEmail checks if a value is a validate email address.
 func (v *Validation) isValidEmail(email string) bool {
-	re := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
-	return re.MatchString(email)
+	return emailRegex.MatchString(email)
 }
 
 // isMin checks if a value's length is at least the specified minimum length.
@@ -40,13 +55,16 @@ func (v *Validation) isMax(value, max string) bool {
 
 // matchesRegex checks if a value matches a regular expression pattern.
 func (v *Validation) matchesRegex(value, pattern string) bool {
-	re := regexp.MustCompile(pattern)
+	// compiled patterns are cached app wide and invalid ones simply fail the rule
+	re, err := Metadata.regexp(pattern)
+	if err != nil {
+		return false
+	}
 	return re.MatchString(value)
 }
 
 func (v *Validation) isValidNameFormat(value string) bool {
-	re := regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9]*$`)
-	return re.MatchString(value)
+	return nameFormatRegex.MatchString(value)
 }
 
 // isNumeric checks if a value is numeric.
@@ -79,58 +97,132 @@ func (v *Validation) isConfirmed(field, value string) bool {
 
 // tip: Use a mock database or data source to check for uniqueness and existence.
 
-// isUnique checks if a field value is unique in the mock database.
-func (v *Validation) isUnique(field, value, tableName string) bool {
-	//This line builds an SQL query to check how many rows in the table tableName have
-	//the given field equal to the value.
-	query := fmt.Sprintf("SELECT COUNT(1) FROM %s WHERE %s = $1", tableName, field)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+// return at most one row.
 
-	var count int
-	err := v.DBPool.DBPoolSQL.QueryRowContext(ctx, query, value).Scan(&count)
+// isValidMimeType checks if a file's MIME type is validate.
+// The client supplied Content-Type is never trusted, the type is sniffed from the content
+// of the file and cross-checked with its extension. Options are MIME types (image/png) or
+// extensions (png).
+func (v *Validation) isValidMimeType(file *multipart.FileHeader, mimes string) bool {
+	detected, err := sniffMimeType(file)
 	if err != nil {
-		//v.addError(field, "Database error during uniqueness check")
 		return false
 	}
+	ext := strings.ToLower(filepath.Ext(file.Filename))
 
-	//If count == 0, it means the value is unique (because no rows were found in the database
-	//with that value), so the function returns true.
-	//Otherwise, if the value already exists, it returns false
-	return count == 0
-}
+	for _, option := range strings.Split(mimes, ",") {
+		option = strings.ToLower(strings.TrimSpace(option))
+		if option == "" {
+			continue
+		}
+
+		expected := option
+		if !strings.Contains(option, "/") {
+			// an extension option only accepts files with that extension
+			if ext != "."+strings.TrimPrefix(option, ".") {
+				continue
+			}
+			if expected = extensionMimeType(ext); expected == "" {
+				// unknown extension, nothing more to check the content against
+				return !isSniffable(detected)
+			}
+		}
 
-// exists checks if a field value exists in the mock database.
-func (v *Validation) exists(field, value, tableName string) bool {
-	query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE %s = $1)", tableName, field)
+		if mimeMatches(detected, ext, expected) {
+			return true
+		}
+	}
+	return false
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+// genericMimeTypes are returned by content sniffing when it cannot tell the exact type
+var genericMimeTypes = map[string]bool{
+	"application/octet-stream": true,
+	"text/plain":               true,
+	"text/xml":                 true,
+	"application/zip":          true,
+}
 
-	var exist bool
-	err := v.DBPool.DBPoolSQL.QueryRowContext(ctx, query, value).Scan(&exist)
-	if err != nil {
-		//v.addError(field, "Database error during existence check")
+// isSniffable reports whether content sniffing recognises the type for sure, so that a
+// file of this type is never accepted on its extension alone
+func isSniffable(mimeType string) bool {
+	if genericMimeTypes[mimeType] {
 		return false
 	}
+	return strings.HasPrefix(mimeType, "image/") || strings.HasPrefix(mimeType, "audio/") ||
+		strings.HasPrefix(mimeType, "video/") || strings.HasPrefix(mimeType, "font/") ||
+		mimeType == "application/pdf" || mimeType == "application/x-gzip" ||
+		mimeType == "application/x-rar-compressed" || mimeType == "application/wasm" ||
+		mimeType == "application/ogg" || mimeType == "text/html"
+}
 
-	//if count > 0, it means the value exists in the database, so the function returns true.
-	//Otherwise, it returns false (i.e., the value does not exist).
-	return exist
+// extensionMimeTypes completes the types known by the mime package, whose table depends
+// on the system, with common upload extensions
+var extensionMimeTypes = map[string]string{
+	".csv":  "text/csv",
+	".txt":  "text/plain",
+	".md":   "text/markdown",
+	".zip":  "application/zip",
+	".gz":   "application/x-gzip",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".mp3":  "audio/mpeg",
+	".mp4":  "video/mp4",
+	".webm": "video/webm",
+	".ico":  "image/x-icon",
+	".bmp":  "image/bmp",
+	".exe":  "application/x-msdownload",
+	".dll":  "application/x-msdownload",
+	".sh":   "application/x-sh",
 }
 
-// return at most one row.
+// extensionMimeType returns the type registered for an extension, empty when unknown
+func extensionMimeType(ext string) string {
+	if mimeType, ok := extensionMimeTypes[ext]; ok {
+		return mimeType
+	}
+	return baseMimeType(mime.TypeByExtension(ext))
+}
 
-// isValidMimeType checks if a file's MIME type is validate.
-func (v *Validation) isValidMimeType(file *multipart.FileHeader, mimes string) bool {
-	options := strings.Split(mimes, ",")
-	for _, option := range options {
-		if file.Header.Get("Content-Type") == option {
-			return true
-		}
+// mimeMatches checks the sniffed type and the extension of a file against the expected type
+func mimeMatches(detected, ext, expected string) bool {
+	// the extension must be of the expected type, files without extension are judged on
+	// their content alone
+	if extType := extensionMimeType(ext); (extType == "" && ext != "") || (extType != "" && extType != expected) {
+		return false
 	}
-	return false
+	if detected == expected {
+		return true
+	}
+	// sniffing only tells a generic type for many documents (csv, json, docx, svg...)
+	// so those are accepted on their extension, unless the expected type is sniffable
+	return genericMimeTypes[detected] && !isSniffable(expected) && ext != ""
+}
+
+// sniffMimeType detects the type of a file from its first 512 bytes
+func sniffMimeType(file *multipart.FileHeader) (string, error) {
+	f, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer func(f multipart.File) {
+		_ = f.Close()
+	}(f)
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return baseMimeType(http.DetectContentType(buf[:n])), nil
+}
+
+// baseMimeType strips the parameters of a MIME type, e.g. "text/plain; charset=utf-8"
+func baseMimeType(mimeType string) string {
+	base, _, _ := strings.Cut(mimeType, ";")
+	return strings.ToLower(strings.TrimSpace(base))
 }
 
 // isValidFileSize checks if a file's size is within the maximum allowed size.
@@ -143,6 +235,27 @@ func (v *Validation) isValidFileSize(file *multipart.FileHeader, maxSizeStr stri
 	return file.Size <= int64(maxSize*1024)
 }
 
+// perFileRules are checked against every file of a multi-file upload
+var perFileRules = map[string]bool{
+	"file":             true,
+	"mimes":            true,
+	"max_size":         true,
+	"image-dimensions": true,
+}
+
+// isValidTotalFileSize checks if the files together are within the maximum allowed size.
+func (v *Validation) isValidTotalFileSize(files []*multipart.FileHeader, maxSizeStr string) bool {
+	maxSize, err := strconv.Atoi(maxSizeStr)
+	if err != nil {
+		return false
+	}
+	var total int64
+	for _, file := range files {
+		total += file.Size
+	}
+	return total <= int64(maxSize*1024)
+}
+
 // isValidImageDimensions checks if a file's image dimensions are within the allowed size.
 func (v *Validation) isValidImageDimensions(file *multipart.FileHeader, minWidth, minHeight int) bool {
 	f, err := file.Open()
@@ -162,27 +275,6 @@ func (v *Validation) isValidImageDimensions(file *multipart.FileHeader, minWidth
 	return width >= minWidth && height >= minHeight
 }
 
-// addErrorForCrossFieldValidation validation where the value of one field depends on another.
-func (v *Validation) addErrorForCrossFieldValidation(field1, field2, rule, defaultMsg string) {
-	alias1 := field1
-	if customAlias, exists := v.AttributeAliases[field1]; exists {
-		alias1 = customAlias
-	}
-	alias2 := field2
-	if customAlias, exists := v.AttributeAliases[field2]; exists {
-		alias2 = customAlias
-	}
-	message, ok := v.CustomMessages[field1+"."+field2+"."+rule]
-	if !ok {
-		message = defaultMsg
-	}
-
-	formattedMessage := strings.Replace(message, "%s", alias1, 1)
-	formattedMessage = strings.Replace(formattedMessage, "%s", alias2, 1)
-
-	v.Errors[field1] = append(v.Errors[field1], formattedMessage)
-}
-
 // password checking methods
 
 // isMixedCase checks if a password contains both uppercase and lowercase letters.
@@ -226,6 +318,228 @@ func (v *Validation) hasNumber(value string) bool {
 }
 
 func (v *Validation) hasLetter(s string) bool {
-	re := regexp.MustCompile(`[a-zA-Z]`)
-	return re.MatchString(s)
+	return letterRegex.MatchString(s)
+}
+
+// isURL checks if a value is an absolute URL with a scheme and a host.
+func (v *Validation) isURL(value string) bool {
+	u, err := url.ParseRequestURI(value)
+	if err != nil {
+		return false
+	}
+	return u.Scheme != "" && u.Host != ""
+}
+
+// isUUID checks if a value is a UUID in its canonical form.
+func (v *Validation) isUUID(value string) bool {
+	return uuidRegex.MatchString(value)
+}
+
+// isIP checks if a value is a valid IPv4 or IPv6 address.
+func (v *Validation) isIP(value string) bool {
+	return net.ParseIP(value) != nil
+}
+
+// isJSON checks if a value is a valid JSON document.
+func (v *Validation) isJSON(value string) bool {
+	return json.Valid([]byte(value))
+}
+
+// isBoolean checks if a value can be read as a boolean (true, false, 1, 0, on, off, yes, no).
+func (v *Validation) isBoolean(value string) bool {
+	switch strings.ToLower(value) {
+	case "true", "false", "1", "0", "on", "off", "yes", "no":
+		return true
+	}
+	return false
+}
+
+// isIn checks if a value is one of the allowed options.
+func (v *Validation) isIn(value string, options []string) bool {
+	for _, option := range options {
+		if value == option {
+			return true
+		}
+	}
+	return false
+}
+
+// isBetween checks if a number (when the field is numeric) or the length of a string is
+// between the min and max parameters, both inclusive.
+func (v *Validation) isBetween(value string, params []string, numeric bool) bool {
+	if len(params) != 2 {
+		return false
+	}
+	minValue, err1 := strconv.ParseFloat(params[0], 64)
+	maxValue, err2 := strconv.ParseFloat(params[1], 64)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+
+	size := float64(utf8.RuneCountInString(value))
+	if numeric {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false
+		}
+		size = number
+	}
+	return size >= minValue && size <= maxValue
+}
+
+// isDigits checks if a value only contains digits and has exactly the given length.
+func (v *Validation) isDigits(value, length string) bool {
+	n, err := strconv.Atoi(length)
+	if err != nil || len(value) != n {
+		return false
+	}
+	for _, char := range value {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isAlphaDash checks if a value only contains letters, numbers, dashes and underscores.
+func (v *Validation) isAlphaDash(value string) bool {
+	return alphaDashRegex.MatchString(value)
+}
+
+// startsWith checks if a value starts with one of the prefixes.
+func (v *Validation) startsWith(value string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// endsWith checks if a value ends with one of the suffixes.
+func (v *Validation) endsWith(value string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(value, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// paramAt returns the parameter at index i or an empty string.
+func paramAt(params []string, i int) string {
+	if i < len(params) {
+		return params[i]
+	}
+	return ""
+}
+
+// isEmpty checks if a value is an empty string, a missing file or an empty list.
+func (v *Validation) isEmpty(value interface{}) bool {
+	switch val := value.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case *multipart.FileHeader:
+		return val == nil
+	case []interface{}:
+		return len(val) == 0
+	}
+	return false
+}
+
+// fieldIn checks if the value of another field is one of the given values.
+func (v *Validation) fieldIn(other string, values []string) bool {
+	otherValue, exists := v.getFieldValue(other)
+	if !exists {
+		return false
+	}
+	strValue, ok := otherValue.(string)
+	return ok && v.isIn(strValue, values)
+}
+
+// anyPresent checks if any of the fields is present and not empty.
+func (v *Validation) anyPresent(fields []string) bool {
+	for _, field := range fields {
+		if value, exists := v.getFieldValue(field); exists && !v.isEmpty(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsRule checks if one of the rules has the given name.
+func containsRule(rules []string, ruleName string) bool {
+	for _, rule := range rules {
+		if Metadata.rule(rule).name == ruleName {
+			return true
+		}
+	}
+	return false
+}
+
+// files returns the uploaded files of a field, SetMultipartForm keeps a single file apart
+// from the multi-file uploads
+func (v *Validation) files(field string) []*multipart.FileHeader {
+	if files := v.MultiFileData[field]; len(files) > 0 {
+		return files
+	}
+	if file := v.FileData[field]; file != nil {
+		return []*multipart.FileHeader{file}
+	}
+	return nil
+}
+
+// fieldValues returns every value of a field: all values of a repeated form input
+// ("field" or "field[]"), all files of a multi-file upload, the elements of a JSON array
+// or the single value of the field.
+func (v *Validation) fieldValues(field string) []interface{} {
+	var values []interface{}
+	for _, key := range []string{field, field + "[]"} {
+		for _, value := range v.Data[key] {
+			values = append(values, value)
+		}
+	}
+	for _, file := range v.files(field) {
+		values = append(values, file)
+	}
+	if len(values) > 0 {
+		return values
+	}
+
+	if v.JSONData != nil {
+		if node, exists := lookupJSON(v.JSONData, field); exists {
+			if items, ok := node.([]interface{}); ok {
+				for _, item := range items {
+					value, _ := jsonRuleValue(item)
+					values = append(values, value)
+				}
+				return values
+			}
+		}
+	}
+
+	if value, exists := v.getFieldValue(field); exists {
+		values = append(values, value)
+	}
+	return values
+}
+
+// isMinItems checks if a field has at least the given number of values.
+func (v *Validation) isMinItems(count int, param string) bool {
+	minItems, err := strconv.Atoi(param)
+	if err != nil {
+		return false
+	}
+	return count >= minItems
+}
+
+// isMaxItems checks if a field has at most the given number of values.
+func (v *Validation) isMaxItems(count int, param string) bool {
+	maxItems, err := strconv.Atoi(param)
+	if err != nil {
+		return false
+	}
+	return count <= maxItems
 }
@@ -0,0 +1,90 @@
+package validator
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ======================= validated data extraction ========================
+
+// Validated returns the fields that have rules and passed them, call it after Validate. The
+// fields not validated because BailAll stopped on a failure before them are left out.
+// Values are cast according to their rules: numeric gives an int64 or a float64, boolean
+// a bool and date a time.Time. Repeated inputs give a []interface{} of cast values and
+// uploaded files their *multipart.FileHeader.
+func (v *Validation) Validated() map[string]interface{} {
+	validated := make(map[string]interface{})
+
+	for pattern, fieldRules := range v.Rules {
+		rules := expandRules(fieldRules)
+		for _, field := range v.expandField(pattern) {
+			if _, failed := v.Errors[field]; failed || !v.checked[field] {
+				continue
+			}
+			value, exists := v.getFieldValue(field)
+			if !exists {
+				continue
+			}
+
+			if containsRule(rules, "each") || len(v.fieldValues(field)) > 1 {
+				var items []interface{}
+				for _, item := range v.fieldValues(field) {
+					items = append(items, castValue(item, eachRules(rules)))
+				}
+				validated[field] = items
+				continue
+			}
+			validated[field] = castValue(value, rules)
+		}
+	}
+	return validated
+}
+
+// eachRules returns the rules applied to every item of a repeated input
+func eachRules(rules []string) []string {
+	var result []string
+	for _, rule := range rules {
+		if parsed := Metadata.rule(rule); parsed.name == "each" {
+			result = append(result, parsed.raw)
+		}
+	}
+	return append(result, rules...)
+}
+
+// castValue converts a string value to the Go type its rules describe, values that do not
+// parse are returned unchanged
+func castValue(value interface{}, rules []string) interface{} {
+	strValue, ok := value.(string)
+	if !ok {
+		return value
+	}
+
+	for _, rule := range rules {
+		switch Metadata.rule(rule).name {
+		case "numeric", "digits":
+			if i, err := strconv.ParseInt(strValue, 10, 64); err == nil {
+				return i
+			}
+			if f, err := strconv.ParseFloat(strValue, 64); err == nil {
+				return f
+			}
+		case "boolean":
+			switch strings.ToLower(strValue) {
+			case "true", "1", "on", "yes":
+				return true
+			case "false", "0", "off", "no":
+				return false
+			}
+		case "date":
+			if t, err := time.Parse("2006-01-02", strValue); err == nil {
+				return t
+			}
+		case "date_format":
+			if t, err := time.Parse(Metadata.rule(rule).raw, strValue); err == nil {
+				return t
+			}
+		}
+	}
+	return strValue
+}
@@ -1,6 +1,11 @@
 package validator
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"mime/multipart"
+	"time"
+)
 
 // ============================== User Methods ===========================
 
@@ -45,6 +50,39 @@ func (v *Validation) AddRule(field, rule string) {
 	v.Rules[field] = append(v.Rules[field], rule)
 }
 
+// AddRules adds rules written as a pipe separated string, e.g. "required|min:3|max:20".
+func (v *Validation) AddRules(field, rules string) {
+	v.Rules[field] = append(v.Rules[field], ParseRules(rules)...)
+}
+
+// SetRules replaces all rules with rules written as pipe separated strings.
+func (v *Validation) SetRules(rules map[string]string) {
+	v.Rules = make(map[string][]string, len(rules))
+	for field, fieldRules := range rules {
+		v.AddRules(field, fieldRules)
+	}
+}
+
+// SetMultipartForm sets the values and the uploaded files of a parsed multipart form.
+// Fields with several files are validated file by file.
+func (v *Validation) SetMultipartForm(form *multipart.Form) {
+	v.Data = form.Value
+	if v.FileData == nil {
+		v.FileData = make(map[string]*multipart.FileHeader)
+	}
+	if v.MultiFileData == nil {
+		v.MultiFileData = make(map[string][]*multipart.FileHeader)
+	}
+
+	for field, files := range form.File {
+		if len(files) == 1 {
+			v.FileData[field] = files[0]
+			continue
+		}
+		v.MultiFileData[field] = files
+	}
+}
+
 // SetDependency sets a dependency in the DI container.
 func (v *Validation) SetDependency(key string, value interface{}) {
 	v.DIContainer[key] = value
@@ -55,3 +93,22 @@ func (v *Validation) GetDependency(key string) (interface{}, bool) {
 	value, exists := v.DIContainer[key]
 	return value, exists
 }
+
+// SetLocale sets the locale used to look up the error messages, e.g. "fr" or "pt-BR".
+// Rules without a message in that locale keep their default message.
+func (v *Validation) SetLocale(lang string) {
+	v.Locale = lang
+}
+
+// SetBailMode sets whether validation collects every error (CollectAll), stops the rules of a
+// field on its first failure (BailField) or stops altogether on the first failure (BailAll).
+func (v *Validation) SetBailMode(mode BailMode) {
+	v.Bail = mode
+}
+
+// SetContext sets the parent context of the database rules, usually the request context,
+// and the deadline they share.
+func (v *Validation) SetContext(ctx context.Context, dbTimeout time.Duration) {
+	v.Context = ctx
+	v.DBTimeout = dbTimeout
+}
@@ -1,81 +1,182 @@
 package validator
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"maps"
 	"mime/multipart"
 	"net/url"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// CustomValidationFunc defines a function for custom validation.
+// CustomValidationFunc defines a function for custom validation, see RuleFunc for rules
+// that need more than the value.
 type CustomValidationFunc func(value string, params ...string) bool
 
 // ErrorContainer ValidatorErrors holds the validation errors.
 type ErrorContainer map[string][]string
 
+// BailMode sets how much validation goes on after a rule fails.
+type BailMode int
+
+const (
+	// CollectAll runs every rule of every field and collects all the errors (default)
+	CollectAll BailMode = iota
+	// BailField stops the remaining rules of a field on its first failure
+	BailField
+	// BailAll stops the whole validation on the first failure
+	BailAll
+)
+
 // Validation struct holds the data to be validated and the validation rules.
 type Validation struct {
 	Data             url.Values
 	Errors           ErrorContainer
 	Rules            map[string][]string
 	CustomValidation map[string]CustomValidationFunc
+	RuleFuncs        map[string]Rule // custom rules of this validation, see RegisterRule
 	CustomMessages   map[string]string
 	AttributeAliases map[string]string
 	FileData         map[string]*multipart.FileHeader
+	MultiFileData    map[string][]*multipart.FileHeader
+	JSONData         map[string]interface{}
+	Locale           string // locale of the error messages, see RegisterMessages
 	DIContainer      map[string]interface{}
-	StopOnFirstFail  bool
+	StopOnFirstFail  bool            // deprecated: same as Bail set to BailField
+	Bail             BailMode        // see SetBailMode
+	Context          context.Context // parent context of the database rules
+	DBTimeout        time.Duration   // deadline shared by all database rules, 3s by default
 	DBPool           struct {
 		DBPoolSQL *sql.DB
 		PoolPGX   *pgxpool.Pool
 	}
+
+	checked map[string]bool // the fields whose rules ran, see Validated
 }
 
 // ============ main functionalities and features definitions ========
 
-// Validate runs the validation rules on the data.
+// Validate runs the validation rules on the data. Fields are validated in alphabetical
+// order so that the outcome of BailAll does not depend on map ordering.
 func (v *Validation) Validate() bool {
+	mode := v.bailMode()
+
+	patterns := make([]string, 0, len(v.Rules))
+	for pattern := range v.Rules {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	// database rules are collected and run concurrently once the other rules have passed
+	var checks []dbCheck
+	v.checked = make(map[string]bool)
 
 	// Iterate over each field and its associated rules
-	for field, fieldRules := range v.Rules {
-		// Get the value of the field
-		value, exists := v.getFieldValue(field)
-		if !exists {
-			value = ""
-		}
-		// Apply each rule to the field's value
-		for _, rule := range fieldRules {
-			// Directly apply each rule — no conditional logic
-			if !v.applyRule(field, value, rule) && v.StopOnFirstFail {
-				break
+	for _, pattern := range patterns {
+		// wildcard paths of JSON data expand to one field per matching element
+		for _, field := range v.expandField(pattern) {
+			v.checked[field] = true
+			if !v.validateField(field, expandRules(v.Rules[pattern]), mode, &checks) && mode == BailAll {
+				return false
 			}
 		}
 	}
+
+	v.runDBChecks(checks, mode)
 	return len(v.Errors) == 0
 }
 
+// validateField applies the rules of a field and reports whether they all passed. A "bail"
+// rule stops the remaining rules of the field on the first failure whatever the mode.
+func (v *Validation) validateField(field string, rules []string, mode BailMode, checks *[]dbCheck) bool {
+	// Get the value of the field
+	value, exists := v.getFieldValue(field)
+	// "sometimes" fields are only validated when they are present in the input
+	if !exists && containsRule(rules, "sometimes") {
+		return true
+	}
+	if !exists {
+		value = ""
+	}
+
+	bail := mode != CollectAll || containsRule(rules, "bail")
+	passed := true
+	var fieldChecks []dbCheck
+	// Apply each rule to the field's value
+	for _, rule := range rules {
+		if parsed := Metadata.rule(rule); dbRules[parsed.name] {
+			if strValue, ok := value.(string); ok {
+				fieldChecks = append(fieldChecks, dbCheck{field: field, value: strValue, rule: parsed})
+			}
+			continue
+		}
+		if !v.applyFieldRule(field, value, rule, rules) {
+			passed = false
+			if bail {
+				break
+			}
+		}
+	}
+
+	// with bailing, a field that already failed does not hit the database
+	if passed || !bail {
+		*checks = append(*checks, fieldChecks...)
+	}
+	return passed
+}
+
+// bailMode returns the bail mode, honouring the older StopOnFirstFail flag
+func (v *Validation) bailMode() BailMode {
+	if v.Bail == CollectAll && v.StopOnFirstFail {
+		return BailField
+	}
+	return v.Bail
+}
+
 // getFieldValue retrieves the value of a field from the data.
 func (v *Validation) getFieldValue(field string) (interface{}, bool) {
 	// Check if the field is in the file data
 	if fileValue, exists := v.FileData[field]; exists {
 		return fileValue, true
 	}
-	// Check if the field is in the URL values
+	if files, exists := v.MultiFileData[field]; exists && len(files) > 0 {
+		return files[0], true
+	}
+	// Check if the field is in the URL values, repeated inputs may be named "field[]"
 	if value, exists := v.Data[field]; exists && len(value) > 0 {
 		return value[0], true
 	}
+	if value, exists := v.Data[field+"[]"]; exists && len(value) > 0 {
+		return value[0], true
+	}
+	// Check if the field is in the decoded JSON data
+	if v.JSONData != nil {
+		if value, exists := lookupJSON(v.JSONData, field); exists {
+			return jsonRuleValue(value)
+		}
+	}
 	return nil, false
 }
 
-// addError adds an error message for a field.
-func (v *Validation) addError(field, defaultMsg, rule string, params ...string) {
-	key := fmt.Sprintf("%s.%s", field, rule)
-
-	// Retrieve the custom message if it exists, otherwise use the default message
-	message, ok := v.CustomMessages[key]
+// addError adds an error message for a field. The message is the custom message of the
+// field and rule, the message of the rule in the locale catalog or the default message.
+// Messages can use the :attribute, :param, :values and named (:min, :max...) placeholders,
+// default messages use %s for the alias followed by the params.
+func (v *Validation) addError(field, defaultMsg string, rule parsedRule, params ...string) {
+	// Retrieve the custom message if it exists, otherwise use the localized or default message
+	message, ok := v.CustomMessages[fmt.Sprintf("%s.%s", field, rule.messageKey())]
+	if !ok {
+		message, ok = v.CustomMessages[fmt.Sprintf("%s.%s", field, rule.name)]
+	}
+	if !ok {
+		message, ok = localizedMessage(v.Locale, rule.messageKey())
+	}
 	if !ok {
 		message = defaultMsg
 	}
@@ -85,8 +186,10 @@ func (v *Validation) addError(field, defaultMsg, rule string, params ...string)
 		alias = customAlias
 	}
 
+	formattedMessage := replacePlaceholders(message, alias, rule)
+
 	// Replace the first %s with the alias
-	formattedMessage := strings.Replace(message, "%s", alias, 1)
+	formattedMessage = strings.Replace(formattedMessage, "%s", alias, 1)
 	// Replace subsequent %s with params
 	for _, param := range params {
 		formattedMessage = strings.Replace(formattedMessage, "%s", param, 1)
@@ -96,111 +199,189 @@ func (v *Validation) addError(field, defaultMsg, rule string, params ...string)
 	v.Errors[field] = append(v.Errors[field], formattedMessage)
 }
 
-// applyRule applies a single validation rule to a field value.
-func (v *Validation) applyRule(field string, value interface{}, rule string) bool {
-	// Split the rule into its name and parameter
-	parts := strings.Split(rule, ":")
+// applyFieldRule applies a rule to the value of a field, the per-file rules are applied to
+// every file of a multi-file upload. rules are all the rules of the field, those of its
+// wildcard pattern for the fields of JSON data.
+func (v *Validation) applyFieldRule(field string, value interface{}, rule string, rules []string) bool {
+	files := v.MultiFileData[field]
+	if len(files) == 0 || !perFileRules[Metadata.rule(rule).name] {
+		return v.applyRule(field, value, rule, rules)
+	}
+
+	for _, file := range files {
+		if !v.applyRule(field, file, rule, rules) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyRule applies a single validation rule to a field value, the other rules of the field
+// change how some rules compare, e.g. between with numeric.
+func (v *Validation) applyRule(field string, value interface{}, rule string, rules []string) bool {
+	// Split the rule into its name and parameter, parsed rules are cached app wide
+	parsed := Metadata.rule(rule)
 	//The first part of the split rule, which represents the name of the validation rule (e.g., "min").
-	ruleName := parts[0]
+	ruleName := parsed.name
 
 	//The second part of the split rule, if it exists, which represents the parameter for the rule
 	// (e.g., "3" for "min:3").
-	var ruleParams string
-	if len(parts) > 1 {
-		ruleParams = parts[1]
-	}
+	ruleParams := parsed.raw
 
 	// Apply the appropriate validation logic based on the rule name
 	//The switch statement checks the ruleName and applies the corresponding validation logic.
 	switch ruleName {
 	case "required":
-		if strValue, ok := value.(string); ok && strValue == "" {
-			v.addError(field, "This %s is required", ruleName)
+		if v.isEmpty(value) {
+			v.addError(field, "This %s is required", parsed)
+			return false
+		}
+
+	case "required_if":
+		// required_if:other,value1,value2 requires the field when other has one of the values
+		if len(parsed.params) > 1 && v.fieldIn(parsed.params[0], parsed.params[1:]) && v.isEmpty(value) {
+			v.addError(field, "The %s field is required when %s is %s", parsed, parsed.params[0], strings.Join(parsed.params[1:], ", "))
 			return false
-		} else if fileValue, ok := value.(*multipart.FileHeader); ok && fileValue == nil {
-			v.addError(field, "This %s is required", ruleName)
+		}
+
+	case "required_unless":
+		// required_unless:other,value1,value2 requires the field unless other has one of the values
+		if len(parsed.params) > 1 && !v.fieldIn(parsed.params[0], parsed.params[1:]) && v.isEmpty(value) {
+			v.addError(field, "The %s field is required unless %s is in %s", parsed, parsed.params[0], strings.Join(parsed.params[1:], ", "))
 			return false
 		}
 
+	case "required_with":
+		// required_with:foo,bar requires the field when any of the other fields is present
+		if v.anyPresent(parsed.params) && v.isEmpty(value) {
+			v.addError(field, "The %s field is required when %s is present", parsed, strings.Join(parsed.params, " / "))
+			return false
+		}
+
+	case "each":
+		// each:numeric applies the rule to every value of a repeated input, e.g. tags[]
+		for _, item := range v.fieldValues(field) {
+			if !v.applyRule(field, item, ruleParams, rules) {
+				return false
+			}
+		}
+
+	case "min_items":
+		if count := len(v.fieldValues(field)); !v.isMinItems(count, ruleParams) {
+			v.addError(field, "The %s field must have at least %s items", parsed, ruleParams)
+			return false
+		}
+
+	case "max_items":
+		if count := len(v.fieldValues(field)); !v.isMaxItems(count, ruleParams) {
+			v.addError(field, "The %s field must not have more than %s items", parsed, ruleParams)
+			return false
+		}
+
+	case "bail":
+		// handled in validateField, the remaining rules stop on the first failure
+
+	case "sometimes":
+		// handled in Validate, the remaining rules only run when the field is present
+
 	case "name_format":
 		if strValue, ok := value.(string); ok {
 			if !v.isValidNameFormat(strValue) {
-				v.addError(field, "Must start with a letter and contain only letters and numbers", ruleName)
+				v.addError(field, "Must start with a letter and contain only letters and numbers", parsed)
 				return false
 			}
 		}
 
 	case "email":
 		if strValue, ok := value.(string); ok && !v.isValidEmail(strValue) {
-			v.addError(field, "The %s field must be a valid email address", ruleName)
+			v.addError(field, "The %s field must be a valid email address", parsed)
 			return false
 		}
 
 	case "min":
 		if strValue, ok := value.(string); ok && !v.isMin(strValue, ruleParams) {
-			v.addError(field, "The %s field must be at least %s characters.", ruleName, ruleParams)
+			v.addError(field, "The %s field must be at least %s characters.", parsed, ruleParams)
 			return false
 		}
 
 	case "max":
 		if strValue, ok := value.(string); ok && !v.isMax(strValue, ruleParams) {
-			v.addError(field, "The %s field must not exceed %s characters.", ruleName, ruleParams)
+			v.addError(field, "The %s field must not exceed %s characters.", parsed, ruleParams)
 			return false
 		}
 
 	case "regexp":
 		if strValue, ok := value.(string); ok && !v.matchesRegex(strValue, ruleParams) {
-			v.addError(field, "The %s field format is invalid", ruleName)
+			v.addError(field, "The %s field format is invalid", parsed)
 			return false
 		}
 
 	case "numeric":
 		if strValue, ok := value.(string); ok && !v.isNumeric(strValue) {
-			v.addError(field, "The %s field must be a number", ruleName)
+			v.addError(field, "The %s field must be a number", parsed)
 			return false
 		}
 
 	case "date":
 		if strValue, ok := value.(string); ok {
 			if !v.isValidDateFormat(strValue) {
-				v.addError(field, "The %s field must be a valid date in YYYY-MM-DD format", ruleName)
+				v.addError(field, "The %s field must be a valid date in YYYY-MM-DD format", parsed)
 				return false
 			}
 		}
 
-	case "confirmed":
-		if strValue, ok := value.(string); ok && !v.isConfirmed(field, strValue) {
-			v.addError(field, "The %s field confirmation does not match", ruleName)
+	case "date_format":
+		if strValue, ok := value.(string); ok && !v.isDateFormat(strValue, ruleParams) {
+			v.addError(field, "The %s field must match the format %s", parsed, ruleParams)
 			return false
 		}
 
-	case "unique":
-		if strValue, ok := value.(string); ok && !v.isUnique(field, strValue, ruleParams) {
-			v.addError(field, "The %s field must be unique", ruleName)
+	case "after", "after_or_equal", "before", "before_or_equal":
+		// after:start_date, before:today or after_or_equal:2024-01-01
+		if strValue, ok := value.(string); ok && !v.compareDate(strValue, parsed, rules) {
+			v.addError(field, dateMessages[ruleName], parsed, ruleParams)
 			return false
 		}
 
-	case "exists":
-		if strValue, ok := value.(string); ok && !v.exists(field, strValue, ruleParams) {
-			v.addError(field, "The %s field does not exist", ruleName)
+	case "timezone":
+		if strValue, ok := value.(string); ok && !v.isTimezone(strValue) {
+			v.addError(field, "The %s field must be a valid time zone", parsed)
 			return false
 		}
 
+	case "confirmed":
+		if strValue, ok := value.(string); ok && !v.isConfirmed(field, strValue) {
+			v.addError(field, "The %s field confirmation does not match", parsed)
+			return false
+		}
+
+	case "unique", "exists":
+		// Validate runs these concurrently, they are only checked here when applied directly
+		if strValue, ok := value.(string); ok {
+			ctx, cancel := v.dbContext()
+			defer cancel()
+			check := dbCheck{field: field, value: strValue, rule: parsed}
+			if !v.checkDBRule(ctx, check) {
+				v.addDBError(check)
+				return false
+			}
+		}
+
 	case "file":
 		if fileValue, ok := value.(*multipart.FileHeader); !ok && fileValue == nil {
-			v.addError(field, "The %s field must be a valid file", ruleName)
+			v.addError(field, "The %s field must be a valid file", parsed)
 			return false
 		}
 
 	case "mimes":
 		if fileValue, ok := value.(*multipart.FileHeader); ok && !v.isValidMimeType(fileValue, ruleParams) {
-			v.addError(field, "The %s field must be a file of type: %s", ruleName, ruleParams)
+			v.addError(field, "The %s field must be a file of type: %s", parsed, ruleParams)
 			return false
 		}
 
 	case "max_size":
 		if fileValue, ok := value.(*multipart.FileHeader); ok && !v.isValidFileSize(fileValue, ruleParams) {
-			v.addError(field, "The %s field must not exceed %s kilobytes", ruleName, ruleParams)
+			v.addError(field, "The %s field must not exceed %s kilobytes", parsed, ruleParams)
 			return false
 		}
 
@@ -209,34 +390,145 @@ func (v *Validation) applyRule(field string, value interface{}, rule string) boo
 		minWidth, _ := strconv.Atoi(dims[0])
 		minHeight, _ := strconv.Atoi(dims[0])
 		if fileValue, ok := value.(*multipart.FileHeader); ok && !v.isValidImageDimensions(fileValue, minWidth, minHeight) {
-			v.addError(field, "The %s must be at least %s pixels wide and %s pixels tall.", ruleName, strconv.Itoa(minWidth), strconv.Itoa(minHeight))
+			v.addError(field, "The %s must be at least %s pixels wide and %s pixels tall.", parsed, strconv.Itoa(minWidth), strconv.Itoa(minHeight))
+			return false
+		}
+
+	case "max_files":
+		if files := v.files(field); !v.isMaxItems(len(files), ruleParams) {
+			v.addError(field, "The %s field must not have more than %s files", parsed, ruleParams)
+			return false
+		}
+
+	case "max_total_size":
+		if files := v.files(field); !v.isValidTotalFileSize(files, ruleParams) {
+			v.addError(field, "The %s files must not exceed %s kilobytes in total", parsed, ruleParams)
 			return false
 		}
 
 	case "password":
 		if strValue, ok := value.(string); ok {
 			if !v.isMixedCase(strValue) {
-				v.addError(field, "The %s field must contain both uppercase and lowercase letters", ruleName)
+				v.addError(field, "The %s field must contain both uppercase and lowercase letters", parsed.variant("mixed_case"))
 				return false
 			}
 			if !v.hasSymbol(strValue) {
-				v.addError(field, "The %s field must contain at least one symbol", ruleName)
+				v.addError(field, "The %s field must contain at least one symbol", parsed.variant("symbol"))
 				return false
 			}
 			if !v.hasNumber(strValue) {
-				v.addError(field, "The %s field must contain at least one number", ruleName)
+				v.addError(field, "The %s field must contain at least one number", parsed.variant("number"))
 				return false
 			}
 			if !v.hasLetter(strValue) {
-				v.addError(field, "The %s field must contain at least one letter", ruleName)
+				v.addError(field, "The %s field must contain at least one letter", parsed.variant("letter"))
+				return false
+			}
+
+			// password:strong or password:score=3,entropy=50 also rate how guessable it is
+			opts := parsePasswordOptions(parsed.params)
+			if opts.strong && IsCommonPassword(strValue) {
+				v.addError(field, "The %s field is a commonly used password", parsed.variant("common"))
+				return false
+			}
+			if opts.minScore > 0 && PasswordScore(strValue) < opts.minScore {
+				v.addError(field, "The %s field is too easy to guess", parsed.variant("weak"))
+				return false
+			}
+			if opts.minEntropy > 0 && PasswordEntropy(strValue) < opts.minEntropy {
+				v.addError(field, "The %s field is too easy to guess", parsed.variant("weak"))
 				return false
 			}
 		}
 
+	case "url":
+		if strValue, ok := value.(string); ok && !v.isURL(strValue) {
+			v.addError(field, "The %s field must be a valid URL", parsed)
+			return false
+		}
+
+	case "uuid":
+		if strValue, ok := value.(string); ok && !v.isUUID(strValue) {
+			v.addError(field, "The %s field must be a valid UUID", parsed)
+			return false
+		}
+
+	case "ip":
+		if strValue, ok := value.(string); ok && !v.isIP(strValue) {
+			v.addError(field, "The %s field must be a valid IP address", parsed)
+			return false
+		}
+
+	case "json":
+		if strValue, ok := value.(string); ok && !v.isJSON(strValue) {
+			v.addError(field, "The %s field must be a valid JSON string", parsed)
+			return false
+		}
+
+	case "boolean":
+		if strValue, ok := value.(string); ok && !v.isBoolean(strValue) {
+			v.addError(field, "The %s field must be true or false", parsed)
+			return false
+		}
+
+	case "in":
+		if strValue, ok := value.(string); ok && !v.isIn(strValue, parsed.params) {
+			v.addError(field, "The selected %s is invalid, it must be one of: %s", parsed, strings.Join(parsed.params, ", "))
+			return false
+		}
+
+	case "not_in":
+		if strValue, ok := value.(string); ok && v.isIn(strValue, parsed.params) {
+			v.addError(field, "The selected %s is invalid", parsed)
+			return false
+		}
+
+	case "between":
+		if strValue, ok := value.(string); ok && !v.isBetween(strValue, parsed.params, containsRule(rules, "numeric")) {
+			if containsRule(rules, "numeric") {
+				v.addError(field, "The %s field must be between %s and %s", parsed.variant("numeric"), paramAt(parsed.params, 0), paramAt(parsed.params, 1))
+			} else {
+				v.addError(field, "The %s field must be between %s and %s characters", parsed.variant("string"), paramAt(parsed.params, 0), paramAt(parsed.params, 1))
+			}
+			return false
+		}
+
+	case "digits":
+		if strValue, ok := value.(string); ok && !v.isDigits(strValue, ruleParams) {
+			v.addError(field, "The %s field must be %s digits", parsed, ruleParams)
+			return false
+		}
+
+	case "alpha_dash":
+		if strValue, ok := value.(string); ok && !v.isAlphaDash(strValue) {
+			v.addError(field, "The %s field must only contain letters, numbers, dashes and underscores", parsed)
+			return false
+		}
+
+	case "starts_with":
+		if strValue, ok := value.(string); ok && !v.startsWith(strValue, parsed.params) {
+			v.addError(field, "The %s field must start with one of the following: %s", parsed, strings.Join(parsed.params, ", "))
+			return false
+		}
+
+	case "ends_with":
+		if strValue, ok := value.(string); ok && !v.endsWith(strValue, parsed.params) {
+			v.addError(field, "The %s field must end with one of the following: %s", parsed, strings.Join(parsed.params, ", "))
+			return false
+		}
+
 	default:
+		if rule, ok := v.customRule(ruleName); ok {
+			return v.applyCustomRule(field, value, parsed, rule)
+		}
 		if customFunc, ok := v.CustomValidation[ruleName]; ok {
-			if strValue, ok := value.(string); ok && !customFunc(strValue, ruleParams) {
-				v.addError(field, "The %s field failed custom validation for rule %s", ruleName, ruleName, ruleParams)
+			if strValue, ok := value.(string); ok && !customFunc(strValue, parsed.params...) {
+				// custom rules share the "custom" message unless the catalog has one for the rule
+				custom := parsed
+				if _, found := localizedMessage(v.Locale, ruleName); !found {
+					custom.message = "custom"
+				}
+				v.addError(field, "The %s field failed custom validation for rule %s", custom, ruleName, ruleParams)
 				return false
 			}
 		}
@@ -245,22 +537,25 @@ func (v *Validation) applyRule(field string, value interface{}, rule string) boo
 	return true
 }
 
-// ValidateDateOrder  checks if the end date is after the start date.
+// ValidateDateOrder checks that the end date is not before the start date. Before Validate
+// it adds the after_or_equal rule to the end field, on a copy of the rules so that a rule map
+// shared between requests is left as it is. After Validate the dates are checked right away.
+//
+// Deprecated: add "after_or_equal:<start field>" to the rules of the end field instead.
 func (v *Validation) ValidateDateOrder(startField, endField string) {
-	startDate, startExist := v.getFieldValue(startField)
-	endDate, endExist := v.getFieldValue(endField)
-	if !startExist || !endExist {
-		return
-	}
+	rule := "after_or_equal:" + startField
+	fieldRules := append(slices.Clip(v.Rules[endField]), rule)
 
-	start, err1 := time.Parse("2006-01-02", startDate.(string))
-	end, err2 := time.Parse("2006-01-02", endDate.(string))
-	if err1 != nil || err2 != nil {
+	if v.checked != nil {
+		// Validate already ran, a missing date is left to the rules of the field
+		if value, exists := v.getFieldValue(endField); exists {
+			v.applyFieldRule(endField, value, rule, fieldRules)
+		}
 		return
 	}
 
-	if end.Before(start) {
-		v.addErrorForCrossFieldValidation(startField, endField, "date_order", "The %s must be before %s.")
-	}
-
+	rules := make(map[string][]string, len(v.Rules)+1)
+	maps.Copy(rules, v.Rules)
+	rules[endField] = fieldRules
+	v.Rules = rules
 }
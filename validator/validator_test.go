@@ -0,0 +1,783 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestValidation builds a Validation for a single field and value
+func newTestValidation(field, value, rules string) *Validation {
+	v := &Validation{
+		Data:             url.Values{field: {value}},
+		Errors:           make(ErrorContainer),
+		Rules:            make(map[string][]string),
+		CustomValidation: make(map[string]CustomValidationFunc),
+		CustomMessages:   make(map[string]string),
+		AttributeAliases: make(map[string]string),
+		DIContainer:      make(map[string]interface{}),
+	}
+	v.AddRules(field, rules)
+	return v
+}
+
+func TestValidation_StandardRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		value string
+		valid bool
+	}{
+		{"url valid", "url", "https://example.com/path?q=1", true},
+		{"url without scheme", "url", "example.com", false},
+		{"uuid valid", "uuid", "123e4567-e89b-12d3-a456-426614174000", true},
+		{"uuid invalid", "uuid", "123e4567-e89b-12d3-a456", false},
+		{"ipv4", "ip", "192.168.1.1", true},
+		{"ipv6", "ip", "::1", true},
+		{"ip invalid", "ip", "300.1.1.1", false},
+		{"json valid", "json", `{"a":[1,2]}`, true},
+		{"json invalid", "json", `{"a":`, false},
+		{"boolean valid", "boolean", "yes", true},
+		{"boolean invalid", "boolean", "maybe", false},
+		{"in valid", "in:red,green,blue", "green", true},
+		{"in invalid", "in:red,green,blue", "pink", false},
+		{"not_in valid", "not_in:admin,root", "guest", true},
+		{"not_in invalid", "not_in:admin,root", "root", false},
+		{"between length", "between:3:5", "abcd", true},
+		{"between length too long", "between:3:5", "abcdef", false},
+		{"between numeric", "numeric|between:1:10", "7", true},
+		{"between numeric out of range", "numeric|between:1:10", "11", false},
+		{"digits valid", "digits:4", "1234", true},
+		{"digits wrong length", "digits:4", "123", false},
+		{"digits not numeric", "digits:4", "12a4", false},
+		{"alpha_dash valid", "alpha_dash", "my-slug_01", true},
+		{"alpha_dash invalid", "alpha_dash", "my slug!", false},
+		{"starts_with valid", "starts_with:foo,bar", "barbaz", true},
+		{"starts_with invalid", "starts_with:foo,bar", "bazbar", false},
+		{"ends_with valid", "ends_with:.jpg,.png", "photo.png", true},
+		{"ends_with invalid", "ends_with:.jpg,.png", "photo.gif", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestValidation("field", tt.value, tt.rules)
+			assert.Equal(t, tt.valid, v.Validate(), v.Errors)
+		})
+	}
+}
+
+func TestValidation_StandardRuleMessages(t *testing.T) {
+	v := newTestValidation("color", "pink", "in:red,green")
+	v.Validate()
+	assert.Equal(t, []string{"The selected color is invalid, it must be one of: red, green"}, v.Errors["color"])
+
+	v = newTestValidation("name", "ab", "between:3:5")
+	v.Validate()
+	assert.Equal(t, []string{"The name field must be between 3 and 5 characters"}, v.Errors["name"])
+}
+
+// newTestValidationData builds a Validation for several fields
+func newTestValidationData(data url.Values, rules map[string]string) *Validation {
+	v := &Validation{
+		Data:             data,
+		Errors:           make(ErrorContainer),
+		CustomValidation: make(map[string]CustomValidationFunc),
+		CustomMessages:   make(map[string]string),
+		AttributeAliases: make(map[string]string),
+		DIContainer:      make(map[string]interface{}),
+	}
+	v.SetRules(rules)
+	return v
+}
+
+func TestValidation_ConditionalRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  url.Values
+		rules map[string]string
+		valid bool
+	}{
+		{"required_if matched and missing", url.Values{"payment": {"card"}}, map[string]string{"card_number": "required_if:payment,card,debit"}, false},
+		{"required_if matched and present", url.Values{"payment": {"card"}, "card_number": {"4242"}}, map[string]string{"card_number": "required_if:payment,card"}, true},
+		{"required_if not matched", url.Values{"payment": {"cash"}}, map[string]string{"card_number": "required_if:payment,card"}, true},
+		{"required_unless matched", url.Values{"role": {"guest"}}, map[string]string{"email": "required_unless:role,guest"}, true},
+		{"required_unless not matched", url.Values{"role": {"admin"}}, map[string]string{"email": "required_unless:role,guest"}, false},
+		{"required_with present", url.Values{"first_name": {"Ada"}}, map[string]string{"last_name": "required_with:first_name,middle_name"}, false},
+		{"required_with absent", url.Values{}, map[string]string{"last_name": "required_with:first_name,middle_name"}, true},
+		{"sometimes absent", url.Values{}, map[string]string{"nickname": "sometimes|required|min:3"}, true},
+		{"sometimes present", url.Values{"nickname": {"ab"}}, map[string]string{"nickname": "sometimes|required|min:3"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestValidationData(tt.data, tt.rules)
+			assert.Equal(t, tt.valid, v.Validate(), v.Errors)
+		})
+	}
+}
+
+func TestValidation_RepeatedInputs(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  url.Values
+		rules string
+		valid bool
+	}{
+		{"each valid", url.Values{"tags[]": {"1", "2", "3"}}, "each:numeric", true},
+		{"each invalid", url.Values{"tags[]": {"1", "two", "3"}}, "each:numeric", false},
+		{"each with parameter", url.Values{"tags": {"go", "rust"}}, "each:min:3", false},
+		{"each pattern", url.Values{"tags": {"a", "b"}}, "each:regexp:^[ab]$", true},
+		{"min_items valid", url.Values{"tags[]": {"a"}}, "min_items:1", true},
+		{"min_items invalid", url.Values{}, "min_items:1", false},
+		{"max_items valid", url.Values{"tags[]": {"a", "b"}}, "max_items:2", true},
+		{"max_items invalid", url.Values{"tags[]": {"a", "b", "c"}}, "max_items:2", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestValidationData(tt.data, map[string]string{"tags": tt.rules})
+			assert.Equal(t, tt.valid, v.Validate(), v.Errors)
+		})
+	}
+}
+
+func TestValidation_MultiFileUploads(t *testing.T) {
+	files := []*multipart.FileHeader{
+		{Filename: "a.png", Size: 600 * 1024, Header: textproto.MIMEHeader{"Content-Type": {"image/png"}}},
+		{Filename: "b.pdf", Size: 600 * 1024, Header: textproto.MIMEHeader{"Content-Type": {"application/pdf"}}},
+	}
+
+	tests := []struct {
+		name  string
+		rules string
+		valid bool
+	}{
+		{"per file mimes", "mimes:image/png", false},
+		{"per file max_size", "max_size:700", true},
+		{"max_files valid", "max_files:2", true},
+		{"max_files invalid", "max_files:1", false},
+		{"max_total_size valid", "max_total_size:1200", true},
+		{"max_total_size invalid", "max_total_size:1000", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestValidationData(url.Values{}, map[string]string{"attachments": tt.rules})
+			v.SetMultipartForm(&multipart.Form{Value: url.Values{}, File: map[string][]*multipart.FileHeader{"attachments": files}})
+			assert.Equal(t, tt.valid, v.Validate(), v.Errors)
+		})
+	}
+}
+
+func TestValidation_SingleFileUploadLimits(t *testing.T) {
+	file := &multipart.FileHeader{Filename: "a.png", Size: 600 * 1024, Header: textproto.MIMEHeader{"Content-Type": {"image/png"}}}
+
+	tests := []struct {
+		rules string
+		valid bool
+	}{
+		{"max_total_size:700", true},
+		{"max_total_size:500", false},
+		{"max_files:1", true},
+		{"max_files:0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rules, func(t *testing.T) {
+			v := newTestValidationData(url.Values{}, map[string]string{"attachments": tt.rules})
+			// a single file goes to FileData
+			v.SetMultipartForm(&multipart.Form{Value: url.Values{}, File: map[string][]*multipart.FileHeader{"attachments": {file}}})
+			assert.Equal(t, tt.valid, v.Validate(), v.Errors)
+		})
+	}
+}
+
+// newTestFileHeader builds a real uploaded file so that its content can be read
+func newTestFileHeader(t *testing.T, filename, contentType string, content []byte) *multipart.FileHeader {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="upload"; filename="`+filename+`"`)
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	require.NoError(t, err)
+	_, err = part.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	require.NoError(t, err)
+	return form.File["upload"][0]
+}
+
+func TestValidation_MimesSniffsContent(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+	executable := []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff\x00\x00")
+
+	tests := []struct {
+		name     string
+		filename string
+		header   string
+		content  []byte
+		rules    string
+		valid    bool
+	}{
+		{"real png", "logo.png", "image/png", png, "mimes:image/png", true},
+		{"real png by extension", "logo.png", "image/png", png, "mimes:png,jpg", true},
+		{"executable labeled png", "logo.png", "image/png", executable, "mimes:image/png", false},
+		{"executable with png extension option", "logo.png", "image/png", executable, "mimes:png", false},
+		{"png renamed to exe", "setup.exe", "image/png", png, "mimes:image/png", false},
+		{"csv on its extension", "users.csv", "text/csv", []byte("id,name\n1,ada\n"), "mimes:text/csv", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestValidationData(url.Values{}, map[string]string{"upload": tt.rules})
+			v.FileData = map[string]*multipart.FileHeader{"upload": newTestFileHeader(t, tt.filename, tt.header, tt.content)}
+			assert.Equal(t, tt.valid, v.Validate(), v.Errors)
+		})
+	}
+}
+
+func TestValidation_LocalizedMessages(t *testing.T) {
+	RegisterMessages("fr", map[string]string{
+		"required":       "Le champ :attribute est obligatoire",
+		"between.string": "Le champ :attribute doit contenir entre :min et :max caractères",
+	})
+
+	v := newTestValidationData(url.Values{"name": {"ab"}}, map[string]string{"email": "required", "name": "between:3:5"})
+	v.SetLocale("fr-FR")
+	v.SetAttributeAlias("email", "e-mail")
+	v.Validate()
+	assert.Equal(t, []string{"Le champ e-mail est obligatoire"}, v.Errors["email"])
+	assert.Equal(t, []string{"Le champ name doit contenir entre 3 et 5 caractères"}, v.Errors["name"])
+
+	// rules missing from the locale keep their default message
+	v = newTestValidationData(url.Values{"age": {"x"}}, map[string]string{"age": "numeric"})
+	v.SetLocale("fr")
+	v.Validate()
+	assert.Equal(t, []string{"The age field must be a number"}, v.Errors["age"])
+
+	// custom messages may use the placeholders too
+	v = newTestValidationData(url.Values{"code": {"12"}}, map[string]string{"code": "digits:4"})
+	v.SetCustomMessageForRule("code", "digits", ":attribute needs :digits digits")
+	v.Validate()
+	assert.Equal(t, []string{"code needs 4 digits"}, v.Errors["code"])
+}
+
+func TestValidation_BailModes(t *testing.T) {
+	data := url.Values{"age": {"abc"}, "name": {"a"}}
+	rules := map[string]string{"age": "numeric|min:5", "name": "min:3|email"}
+
+	v := newTestValidationData(data, rules)
+	v.Validate()
+	assert.Len(t, v.Errors["age"], 2)
+	assert.Len(t, v.Errors["name"], 2)
+
+	v = newTestValidationData(data, rules)
+	v.SetBailMode(BailField)
+	v.Validate()
+	assert.Len(t, v.Errors["age"], 1)
+	assert.Len(t, v.Errors["name"], 1)
+
+	v = newTestValidationData(data, rules)
+	v.SetBailMode(BailAll)
+	assert.False(t, v.Validate())
+	assert.Len(t, v.Errors, 1)
+	assert.Len(t, v.Errors["age"], 1)
+
+	// the bail rule only stops its own field
+	v = newTestValidationData(data, map[string]string{"age": "bail|numeric|min:5", "name": "min:3|email"})
+	v.Validate()
+	assert.Len(t, v.Errors["age"], 1)
+	assert.Len(t, v.Errors["name"], 2)
+
+	// the older flag keeps stopping per field
+	v = newTestValidationData(data, rules)
+	v.StopOnFirstFail = true
+	v.Validate()
+	assert.Len(t, v.Errors["age"], 1)
+	assert.Len(t, v.Errors["name"], 1)
+}
+
+func TestValidation_Validated(t *testing.T) {
+	v := newTestValidationData(
+		url.Values{"age": {"42"}, "price": {"9.99"}, "active": {"on"}, "born": {"1990-05-17"}, "name": {"Ada"}, "email": {"bad"}, "tags[]": {"1", "2"}},
+		map[string]string{"age": "numeric", "price": "numeric", "active": "boolean", "born": "date", "name": "min:2", "email": "email", "tags": "each:numeric"},
+	)
+	assert.False(t, v.Validate())
+
+	validated := v.Validated()
+	assert.Equal(t, int64(42), validated["age"])
+	assert.Equal(t, 9.99, validated["price"])
+	assert.Equal(t, true, validated["active"])
+	assert.Equal(t, time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC), validated["born"])
+	assert.Equal(t, "Ada", validated["name"])
+	assert.Equal(t, []interface{}{int64(1), int64(2)}, validated["tags"])
+	assert.NotContains(t, validated, "email", "failed fields are left out")
+}
+
+func TestBind(t *testing.T) {
+	type signup struct {
+		Name     string    `form:"name"`
+		Age      int       `form:"age"`
+		Score    *float64  `json:"score"`
+		Tags     []string  `form:"tags"`
+		Active   bool      `form:"active"`
+		Birthday time.Time `form:"birthday"`
+		Ignored  string    `form:"-"`
+	}
+
+	var dst signup
+	err := Bind(&dst, url.Values{
+		"name": {"Ada"}, "age": {"36"}, "score": {"9.5"}, "tags[]": {"go", "sql"},
+		"active": {"on"}, "birthday": {"1815-12-10"}, "Ignored": {"x"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", dst.Name)
+	assert.Equal(t, 36, dst.Age)
+	require.NotNil(t, dst.Score)
+	assert.Equal(t, 9.5, *dst.Score)
+	assert.Equal(t, []string{"go", "sql"}, dst.Tags)
+	assert.True(t, dst.Active)
+	assert.Equal(t, 1815, dst.Birthday.Year())
+	assert.Empty(t, dst.Ignored)
+
+	err = Bind(&dst, url.Values{"age": {"old"}})
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Contains(t, validationErr.Errors, "age")
+
+	assert.Error(t, Bind(dst, url.Values{}), "a non pointer destination is rejected")
+}
+
+func TestValidation_PasswordStrength(t *testing.T) {
+	assert.True(t, IsCommonPassword("P@ssw0rd!"), "leetspeak and trailing symbols are normalized")
+	assert.False(t, IsCommonPassword("Tr0ub4dor&3"))
+	assert.Equal(t, 0, PasswordScore("Aa1!aaaa"))
+	assert.GreaterOrEqual(t, PasswordScore("Tr0ub4dor&3"), 3)
+	assert.Less(t, PasswordEntropy("Abc123!!"), PasswordEntropy("Kx9#mQ2v"), "sequences and repeats lower the entropy")
+
+	cases := []struct {
+		value string
+		rules string
+		valid bool
+	}{
+		{"P@ssw0rd!", "password", true},
+		{"P@ssw0rd!", "password:strong", false},
+		{"Aa1!aaaa", "password:score=2", false},
+		{"Tr0ub4dor&3", "password:strong", true},
+		{"Tr0ub4dor&3", "password:entropy=100", false},
+		{"Mango!42", "password:score=1", true},
+		{"Mango!42", "password:strong,score=1", false},
+		{"Mango!42", "password:score=1,strong", false},
+	}
+	for _, c := range cases {
+		v := newTestValidation("password", c.value, c.rules)
+		assert.Equal(t, c.valid, v.Validate(), "%s with %s", c.value, c.rules)
+	}
+
+	LoadPasswordBlacklist(strings.NewReader("# site specific\nTr0ub4dor&3\n"))
+	assert.True(t, IsCommonPassword("tr0ub4dor&3"))
+}
+
+func TestValidation_RuleFunc(t *testing.T) {
+	RegisterRule("different_from", Rule{
+		Func: func(v *Validation, field string, value interface{}, params []string) bool {
+			return value != v.Data.Get(params[0])
+		},
+		Message:   "The :attribute field must differ from :param",
+		MinParams: 1,
+		MaxParams: 1,
+	})
+
+	v := newTestValidationData(url.Values{"username": {"ada"}, "nickname": {"ada"}}, map[string]string{"nickname": "different_from:username"})
+	assert.False(t, v.Validate())
+	assert.Equal(t, []string{"The nickname field must differ from username"}, v.Errors["nickname"])
+
+	v = newTestValidationData(url.Values{"nickname": {"ada"}}, map[string]string{"nickname": "different_from"})
+	assert.False(t, v.Validate())
+	assert.Contains(t, v.Errors["nickname"][0], "expects 1")
+
+	v = newTestValidationData(url.Values{"code": {"abc"}}, map[string]string{"code": "reserved"})
+	v.SetDependency("reserved", []string{"abc", "admin"})
+	v.RegisterRule("reserved", Rule{Func: func(v *Validation, field string, value interface{}, params []string) bool {
+		reserved, _ := v.GetDependency("reserved")
+		return !v.isIn(value.(string), reserved.([]string))
+	}})
+	assert.False(t, v.Validate())
+	assert.Equal(t, []string{"The code field failed custom validation for rule reserved"}, v.Errors["code"])
+}
+
+func TestErrorContainer(t *testing.T) {
+	errs := ErrorContainer{"name": {"too short", "not unique"}, "age": {"required"}, "email": nil}
+	assert.True(t, errs.Any())
+	assert.True(t, errs.Has("name"))
+	assert.False(t, errs.Has("email"))
+	assert.Equal(t, "too short", errs.First("name"))
+	assert.Empty(t, errs.First("email"))
+	assert.Equal(t, []string{"required", "too short", "not unique"}, errs.All())
+
+	content, err := json.Marshal(errs)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"age":["required"],"name":["too short","not unique"]}`, string(content))
+
+	content, err = json.Marshal(NewValidationError(nil))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"message":"The given data was invalid","errors":{}}`, string(content))
+	assert.False(t, ErrorContainer{}.Any())
+}
+
+func TestOldInput(t *testing.T) {
+	old := NewOldInput(url.Values{"email": {"ada@example.com"}, "password": {"secret"}, "csrf_token": {"x"}, "tags": {"go", "sql"}})
+	assert.Equal(t, "ada@example.com", old.Get("email"))
+	assert.Equal(t, "", old.Get("password"))
+	assert.Equal(t, "none", old.Get("name", "none"))
+	assert.False(t, old.Has("csrf_token"))
+	assert.True(t, old.Has("tags", "sql"))
+	assert.Equal(t, []string{"go", "sql"}, old.Values("tags"))
+}
+
+func TestValidation_DateRules(t *testing.T) {
+	cases := []struct {
+		data  url.Values
+		rules map[string]string
+		valid bool
+	}{
+		{url.Values{"d": {"31/12/2024"}}, map[string]string{"d": "date_format:02/01/2006"}, true},
+		{url.Values{"d": {"2024-12-31"}}, map[string]string{"d": "date_format:02/01/2006"}, false},
+		{url.Values{"t": {"10:30"}}, map[string]string{"t": "date_format:15:04"}, true},
+		{url.Values{"start": {"2024-01-10"}, "end": {"2024-01-12"}}, map[string]string{"end": "after:start"}, true},
+		{url.Values{"start": {"2024-01-10"}, "end": {"2024-01-10"}}, map[string]string{"end": "after:start"}, false},
+		{url.Values{"start": {"2024-01-10"}, "end": {"2024-01-10"}}, map[string]string{"end": "after_or_equal:start"}, true},
+		{url.Values{"start": {"10/01/2024"}, "end": {"2024-01-09"}}, map[string]string{"start": "date_format:02/01/2006", "end": "after:start"}, false},
+		{url.Values{"end": {"2024-01-12"}}, map[string]string{"start": "sometimes|date", "end": "after:start"}, true},
+		{url.Values{"d": {"2000-01-01"}}, map[string]string{"d": "before:today"}, true},
+		{url.Values{"d": {"2000-01-01"}}, map[string]string{"d": "after:tomorrow"}, false},
+		{url.Values{"d": {"2024-06-01"}}, map[string]string{"d": "before_or_equal:2024-06-01"}, true},
+		{url.Values{"tz": {"Europe/Paris"}}, map[string]string{"tz": "timezone"}, true},
+		{url.Values{"tz": {"Mars/Olympus"}}, map[string]string{"tz": "timezone"}, false},
+	}
+	for _, c := range cases {
+		v := newTestValidationData(c.data, c.rules)
+		assert.Equal(t, c.valid, v.Validate(), "%v with %v: %v", c.data, c.rules, v.Errors)
+	}
+
+	v := newTestValidationData(url.Values{"start": {"2024-01-10"}, "end": {"2024-01-09"}}, map[string]string{"start": "date"})
+	v.ValidateDateOrder("start", "end")
+	assert.False(t, v.Validate())
+	assert.Equal(t, "The end field must be a date after or equal to start", v.Errors.First("end"))
+}
+
+func TestValidation_ValidateDateOrder(t *testing.T) {
+	dates := url.Values{"start": {"2024-01-10"}, "end": {"2024-01-09"}}
+
+	t.Run("nil rules", func(t *testing.T) {
+		v := newTestValidationData(dates, nil)
+		v.Rules = nil
+		v.ValidateDateOrder("start", "end")
+		assert.False(t, v.Validate())
+		assert.Contains(t, v.Errors, "end")
+	})
+
+	t.Run("shared rules", func(t *testing.T) {
+		shared := map[string][]string{"end": make([]string, 1, 4)}
+		shared["end"][0] = "date"
+		for i := 0; i < 2; i++ {
+			v := newTestValidationData(dates, nil)
+			v.Rules = shared
+			v.ValidateDateOrder("start", "end")
+			assert.False(t, v.Validate())
+			assert.Equal(t, []string{"date", "after_or_equal:start"}, v.Rules["end"])
+		}
+		assert.Equal(t, map[string][]string{"end": {"date"}}, shared)
+		assert.Equal(t, []string{"date", ""}, shared["end"][:2], "the spare capacity is not written")
+	})
+
+	t.Run("after Validate", func(t *testing.T) {
+		v := newTestValidationData(dates, map[string]string{"start": "date"})
+		assert.True(t, v.Validate())
+		v.ValidateDateOrder("start", "end")
+		assert.Equal(t, "The end field must be a date after or equal to start", v.Errors.First("end"))
+
+		v = newTestValidationData(url.Values{"start": {"2024-01-10"}}, map[string]string{"start": "date"})
+		assert.True(t, v.Validate())
+		v.ValidateDateOrder("start", "end")
+		assert.Empty(t, v.Errors)
+	})
+}
+
+// newTestJSONValidation builds a Validation of a JSON body
+func newTestJSONValidation(t *testing.T, body string, rules map[string]string) *Validation {
+	t.Helper()
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(body), &data))
+	v := newTestValidationData(url.Values{}, rules)
+	v.SetJSONData(data)
+	return v
+}
+
+func TestValidation_WildcardBetween(t *testing.T) {
+	rules := map[string]string{"items.*.qty": "numeric|between:1,100"}
+
+	v := newTestJSONValidation(t, `{"items":[{"qty":5},{"qty":500}]}`, rules)
+	assert.False(t, v.Validate())
+	assert.NotContains(t, v.Errors, "items.0.qty")
+	assert.Equal(t, "The items.1.qty field must be between 1 and 100", v.Errors.First("items.1.qty"))
+
+	v = newTestJSONValidation(t, `{"items":[{"qty":"100"},{"qty":1}]}`, rules)
+	assert.True(t, v.Validate(), v.Errors)
+}
+
+func TestValidation_WildcardDates(t *testing.T) {
+	rules := map[string]string{"events.*.on": "date_format:02/01/2006|after:01/01/2024"}
+
+	v := newTestJSONValidation(t, `{"events":[{"on":"31/12/2024"},{"on":"15/06/2023"}]}`, rules)
+	assert.False(t, v.Validate())
+	assert.NotContains(t, v.Errors, "events.0.on")
+	assert.Contains(t, v.Errors, "events.1.on")
+
+	v = newTestJSONValidation(t, `{"events":[{"on":"02/01/2024"}]}`, rules)
+	assert.True(t, v.Validate(), v.Errors)
+}
+
+func TestValidation_ValidatedWithBailAll(t *testing.T) {
+	rules := map[string]string{"city": "min:2", "age": "numeric", "name": "min:2", "email": "email"}
+	v := newTestValidationData(url.Values{"age": {"abc"}, "city": {"Paris"}, "name": {"Ada"}, "email": {"ada@example.com"}}, rules)
+	v.SetBailMode(BailAll)
+	assert.False(t, v.Validate())
+
+	// the fields are validated in order, city, email and name were never checked
+	assert.Equal(t, map[string]interface{}{}, v.Validated())
+
+	v = newTestValidationData(url.Values{"age": {"42"}, "city": {"Paris"}, "name": {"Ada"}, "email": {"ada@example.com"}}, rules)
+	v.SetBailMode(BailAll)
+	assert.True(t, v.Validate())
+	assert.Len(t, v.Validated(), 4)
+}
+
+func TestValidation_ExpandField(t *testing.T) {
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"items": [{"price": 1, "tags": ["a", "b"]}, {"price": 2}],
+		"address": {"home": {"city": "Paris"}, "work": {"city": "Lyon"}},
+		"name": "Ada"
+	}`), &data))
+	v := newTestValidationData(url.Values{}, nil)
+	v.SetJSONData(data)
+
+	tests := []struct {
+		field string
+		want  []string
+	}{
+		{"name", []string{"name"}},
+		{"items.0.price", []string{"items.0.price"}},
+		{"items.*.price", []string{"items.0.price", "items.1.price"}},
+		{"items.*.tags.*", []string{"items.0.tags.0", "items.0.tags.1"}},
+		{"address.*.city", []string{"address.home.city", "address.work.city"}},
+		{"missing.*.price", nil},
+		{"name.*", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got := v.expandField(tt.field)
+			// the keys of an object come in map order
+			sort.Strings(got)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// without JSON data the field is kept as it is
+	assert.Equal(t, []string{"items.*.price"}, newTestValidationData(url.Values{}, nil).expandField("items.*.price"))
+}
+
+func TestLookupJSON(t *testing.T) {
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"items": [{"price": 1.5}], "address": {"city": "Paris"}, "empty": null}`), &data))
+
+	tests := []struct {
+		path   string
+		want   interface{}
+		wantOK bool
+	}{
+		{"address.city", "Paris", true},
+		{"items.0.price", 1.5, true},
+		{"empty", nil, true},
+		{"items.1.price", nil, false},
+		{"items.-1.price", nil, false},
+		{"items.first", nil, false},
+		{"address.city.name", nil, false},
+		{"address.zip", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := lookupJSON(data, tt.path)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	root, ok := lookupJSON(data, "")
+	assert.True(t, ok)
+	assert.Equal(t, data, root)
+}
+
+func TestJSONRuleValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  interface{}
+		want   interface{}
+		wantOK bool
+	}{
+		{"null", nil, nil, false},
+		{"string", "Ada", "Ada", true},
+		{"integer", float64(42), "42", true},
+		{"float", 1.5, "1.5", true},
+		{"large number", float64(1e21), "1000000000000000000000", true},
+		{"json number", json.Number("12.50"), "12.50", true},
+		{"bool", true, "true", true},
+		{"array", []interface{}{"a"}, []interface{}{"a"}, true},
+		{"object", map[string]interface{}{"a": "b"}, map[string]interface{}{"a": "b"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := jsonRuleValue(tt.value)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		rules string
+		want  []string
+	}{
+		{"required|min:3|max:20", []string{"required", "min:3", "max:20"}},
+		{" required | email ||", []string{"required", "email"}},
+		{`in:a\|b,c|required`, []string{`in:a\|b,c`, "required"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rules, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseRules(tt.rules))
+		})
+	}
+
+	// a pattern rule given as its own element keeps its pipes
+	assert.Equal(t, []string{"required", "regexp:^(a|b)$", "each:regexp:^(c|d)$"},
+		expandRules([]string{"required", "regexp:^(a|b)$", "each:regexp:^(c|d)$"}))
+}
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		rule   string
+		name   string
+		raw    string
+		params []string
+	}{
+		{"required", "required", "", nil},
+		{"between:1:10", "between", "1:10", []string{"1", "10"}},
+		{"in:a,b,c", "in", "a,b,c", []string{"a", "b", "c"}},
+		{`in:a\,b,c\:d`, "in", `a,b,c:d`, []string{"a,b", "c:d"}},
+		{`in:a\|b,c\\d`, "in", `a|b,c\d`, []string{"a|b", `c\d`}},
+		{"regexp:^[a-z]{2,3}:\\d+$", "regexp", "^[a-z]{2,3}:\\d+$", []string{"^[a-z]{2,3}:\\d+$"}},
+		{`regexp:^(a\|b)$`, "regexp", "^(a|b)$", []string{"^(a|b)$"}},
+		{`regexp:^a\\|b$`, "regexp", `^a\|b$`, []string{`^a\|b$`}},
+		{"each:between:1,5", "each", "between:1,5", []string{"between:1,5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			pr := parseRule(tt.rule)
+			assert.Equal(t, tt.name, pr.name)
+			assert.Equal(t, tt.raw, pr.raw)
+			assert.Equal(t, tt.params, pr.params)
+		})
+	}
+}
+
+func TestValidation_RegexpAlternation(t *testing.T) {
+	for _, tt := range []struct {
+		value string
+		valid bool
+	}{
+		{"a", true},
+		{"b", true},
+		{"c", false},
+		{"a|b", false},
+	} {
+		v := newTestValidation("code", tt.value, `required|regexp:^(a\|b)$`)
+		assert.Equal(t, tt.valid, v.Validate(), "%q: %v", tt.value, v.Errors)
+	}
+}
+
+func TestMetadataCache(t *testing.T) {
+	type Address struct {
+		City string `json:"city" validate:"required"`
+	}
+	type User struct {
+		Address
+		Name   string `form:"name" db:"full_name" validate:"required|min:2"`
+		Email  string `json:"email,omitempty" validate:"email"`
+		Secret string `json:"-"`
+		Age    int
+		note   string
+	}
+
+	mc := NewMetadataCache()
+	meta := mc.Struct(reflect.TypeOf(&User{}))
+	assert.Same(t, meta, mc.Struct(reflect.TypeOf(User{})), "the metadata is cached")
+
+	var keys []string
+	for _, f := range meta.Fields {
+		keys = append(keys, f.Key)
+	}
+	assert.Equal(t, []string{"city", "name", "email", "Age"}, keys)
+
+	name, ok := meta.Field("name")
+	require.True(t, ok)
+	assert.Equal(t, "full_name", name.Column)
+	assert.Equal(t, []int{1}, name.Index)
+	city, _ := meta.Field("city")
+	assert.Equal(t, []int{0, 0}, city.Index)
+	age, _ := meta.Field("Age")
+	assert.Equal(t, "age", age.Column)
+	assert.Equal(t, map[string][]string{
+		"city":  {"required"},
+		"name":  {"required", "min:2"},
+		"email": {"email"},
+	}, meta.RuleSet())
+
+	assert.Equal(t, parseRule("between:1,5"), mc.rule("between:1,5"))
+	assert.Contains(t, mc.rules, "between:1,5")
+	re, err := mc.regexp("^a+$")
+	require.NoError(t, err)
+	again, _ := mc.regexp("^a+$")
+	assert.Same(t, re, again)
+	_, err = mc.regexp("(")
+	assert.Error(t, err)
+
+	// development mode clears the cache and caches no struct or rule
+	mc.SetDevMode(true)
+	assert.Empty(t, mc.structs)
+	assert.NotSame(t, meta, mc.Struct(reflect.TypeOf(User{})))
+	assert.Empty(t, mc.structs)
+	mc.rule("required")
+	assert.Empty(t, mc.rules)
+	_, _ = mc.regexp("^a+$")
+	assert.Contains(t, mc.regexps, "^a+$")
+
+	mc.SetDevMode(false)
+	mc.Reset()
+	assert.Empty(t, mc.regexps)
+}
@@ -0,0 +1,131 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	xws "golang.org/x/net/websocket"
+)
+
+// ErrClosed is returned when sending to a closed connection
+var ErrClosed = errors.New("websocket: connection closed")
+
+// ErrSlowConsumer is returned when the send buffer of a connection is full
+var ErrSlowConsumer = errors.New("websocket: send buffer full")
+
+// Conn is a client connected to a Hub
+type Conn struct {
+	ID     string
+	UserID string // set by Config.Identify, empty for guests
+
+	hub    *Hub
+	ws     *xws.Conn
+	send   chan []byte
+	mu     sync.RWMutex
+	values map[string]interface{}
+	rooms  map[string]bool
+	closed chan struct{}
+	once   sync.Once
+}
+
+// Send queues a text message, it never blocks: a full buffer gives ErrSlowConsumer
+func (c *Conn) Send(message []byte) error {
+	select {
+	case <-c.closed:
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case c.send <- message:
+		return nil
+	case <-c.closed:
+		return ErrClosed
+	default:
+		return ErrSlowConsumer
+	}
+}
+
+// SendJSON queues the JSON encoding of v
+func (c *Conn) SendJSON(v interface{}) error {
+	message, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Send(message)
+}
+
+// Set stores a value for the lifetime of the connection
+func (c *Conn) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+}
+
+// Get returns a value stored with Set
+func (c *Conn) Get(key string) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	value, ok := c.values[key]
+	return value, ok
+}
+
+// Join adds the connection to a room
+func (c *Conn) Join(room string) {
+	c.hub.join(c, room)
+}
+
+// Leave removes the connection from a room
+func (c *Conn) Leave(room string) {
+	c.hub.leave(c, room)
+}
+
+// Rooms returns the rooms the connection is in
+func (c *Conn) Rooms() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	rooms := make([]string, 0, len(c.rooms))
+	for room := range c.rooms {
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+// Close closes the connection, the hub forgets it and OnClose is called
+func (c *Conn) Close() error {
+	var err error
+	c.once.Do(func() {
+		close(c.closed)
+		err = c.ws.Close()
+	})
+	return err
+}
+
+// writeLoop sends the queued messages until the connection closes
+func (c *Conn) writeLoop() {
+	for {
+		select {
+		case message := <-c.send:
+			if err := xws.Message.Send(c.ws, string(message)); err != nil {
+				_ = c.Close()
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// readLoop hands the received messages to OnMessage until the connection closes
+func (c *Conn) readLoop() {
+	for {
+		var message []byte
+		if err := xws.Message.Receive(c.ws, &message); err != nil {
+			return
+		}
+		if c.hub.config.OnMessage != nil {
+			c.hub.config.OnMessage(c, message)
+		}
+	}
+}
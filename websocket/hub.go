@@ -0,0 +1,269 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	xws "golang.org/x/net/websocket"
+)
+
+// Config sets up a Hub
+type Config struct {
+	// AllowedOrigins lists the origins allowed to connect, e.g. https://example.com.
+	// Empty allows the origin of the host the request was sent to only.
+	AllowedOrigins []string
+	// Identify associates the connection with a user, e.g. from the session
+	Identify func(r *http.Request) string
+	// SendBuffer is the number of messages queued per connection, 64 when 0
+	SendBuffer int
+
+	OnConnect func(c *Conn)
+	OnMessage func(c *Conn, message []byte)
+	OnClose   func(c *Conn)
+}
+
+// Hub keeps track of the connected clients and the rooms they joined
+type Hub struct {
+	config Config
+	mu     sync.RWMutex
+	conns  map[*Conn]bool
+	rooms  map[string]map[*Conn]bool
+	done   bool
+}
+
+// NewHub creates a Hub
+func NewHub(config Config) *Hub {
+	if config.SendBuffer <= 0 {
+		config.SendBuffer = 64
+	}
+	return &Hub{
+		config: config,
+		conns:  make(map[*Conn]bool),
+		rooms:  make(map[string]map[*Conn]bool),
+	}
+}
+
+// Handler upgrades requests to WebSocket connections of the hub, mount it on a route
+func (h *Hub) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := ""
+		if h.config.Identify != nil {
+			userID = h.config.Identify(r)
+		}
+
+		server := xws.Server{
+			Handshake: func(config *xws.Config, req *http.Request) error {
+				return h.checkOrigin(config, req)
+			},
+			Handler: func(ws *xws.Conn) {
+				h.serve(ws, userID)
+			},
+		}
+		// middlewares wrap the writer, the hijacker is reached through Unwrap
+		server.ServeHTTP(hijackWriter{w}, r)
+	})
+}
+
+// checkOrigin accepts the allowed origins, or the origin of the host when none are set
+func (h *Hub) checkOrigin(config *xws.Config, req *http.Request) error {
+	origin, err := xws.Origin(config, req)
+	if err != nil || origin == nil {
+		return errors.New("websocket: missing origin")
+	}
+	config.Origin = origin
+
+	if len(h.config.AllowedOrigins) == 0 {
+		if origin.Host == req.Host {
+			return nil
+		}
+		return errors.New("websocket: cross origin request")
+	}
+	for _, allowed := range h.config.AllowedOrigins {
+		if u, err := url.Parse(allowed); err == nil && u.Scheme == origin.Scheme && u.Host == origin.Host {
+			return nil
+		}
+	}
+	return errors.New("websocket: origin not allowed")
+}
+
+// serve runs a connection until it closes
+func (h *Hub) serve(ws *xws.Conn, userID string) {
+	c := &Conn{
+		ID:     newID(),
+		UserID: userID,
+		hub:    h,
+		ws:     ws,
+		send:   make(chan []byte, h.config.SendBuffer),
+		values: make(map[string]interface{}),
+		rooms:  make(map[string]bool),
+		closed: make(chan struct{}),
+	}
+	if !h.add(c) {
+		_ = c.Close()
+		return
+	}
+	defer h.remove(c)
+
+	go c.writeLoop()
+	if h.config.OnConnect != nil {
+		h.config.OnConnect(c)
+	}
+	c.readLoop()
+	_ = c.Close()
+}
+
+// add registers a connection, unless the hub is shutting down
+func (h *Hub) add(c *Conn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.done {
+		return false
+	}
+	h.conns[c] = true
+	return true
+}
+
+// remove forgets a connection and the rooms it was in
+func (h *Hub) remove(c *Conn) {
+	h.mu.Lock()
+	delete(h.conns, c)
+	for room := range c.rooms {
+		delete(h.rooms[room], c)
+		if len(h.rooms[room]) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+	h.mu.Unlock()
+
+	if h.config.OnClose != nil {
+		h.config.OnClose(c)
+	}
+}
+
+func (h *Hub) join(c *Conn, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.conns[c] {
+		return
+	}
+	if h.rooms[room] == nil {
+		h.rooms[room] = make(map[*Conn]bool)
+	}
+	h.rooms[room][c] = true
+
+	c.mu.Lock()
+	c.rooms[room] = true
+	c.mu.Unlock()
+}
+
+func (h *Hub) leave(c *Conn, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.rooms[room], c)
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+
+	c.mu.Lock()
+	delete(c.rooms, room)
+	c.mu.Unlock()
+}
+
+// Broadcast sends the message to every connection
+func (h *Hub) Broadcast(message []byte) {
+	h.sendAll(h.Conns(), message)
+}
+
+// BroadcastRoom sends the message to the connections in the room
+func (h *Hub) BroadcastRoom(room string, message []byte) {
+	h.mu.RLock()
+	conns := make([]*Conn, 0, len(h.rooms[room]))
+	for c := range h.rooms[room] {
+		conns = append(conns, c)
+	}
+	h.mu.RUnlock()
+	h.sendAll(conns, message)
+}
+
+// SendToUser sends the message to every connection of the user
+func (h *Hub) SendToUser(userID string, message []byte) {
+	var conns []*Conn
+	for _, c := range h.Conns() {
+		if c.UserID == userID {
+			conns = append(conns, c)
+		}
+	}
+	h.sendAll(conns, message)
+}
+
+// sendAll queues the message, slow consumers miss it rather than hold everyone up
+func (h *Hub) sendAll(conns []*Conn, message []byte) {
+	for _, c := range conns {
+		_ = c.Send(message)
+	}
+}
+
+// Conns returns the open connections
+func (h *Hub) Conns() []*Conn {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	conns := make([]*Conn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// Count returns the number of open connections
+func (h *Hub) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+// Shutdown refuses new connections, closes the open ones and waits for them to be gone
+// or for the context to end
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.done = true
+	h.mu.Unlock()
+
+	for _, c := range h.Conns() {
+		_ = c.Close()
+	}
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for h.Count() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// newID returns a random connection id
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// hijackWriter reaches the http.Hijacker of writers wrapped by middlewares
+type hijackWriter struct {
+	http.ResponseWriter
+}
+
+func (w hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
@@ -0,0 +1,161 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	xws "golang.org/x/net/websocket"
+)
+
+// wrappedWriter hides the http.Hijacker like most middlewares do
+type wrappedWriter struct {
+	http.ResponseWriter
+}
+
+func (w wrappedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func newTestServer(t *testing.T, hub *Hub) *httptest.Server {
+	handler := hub.Handler()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(wrappedWriter{w}, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func dial(t *testing.T, srv *httptest.Server, origin string) *xws.Conn {
+	ws, err := xws.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), "", origin)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ws.Close() })
+	return ws
+}
+
+func receive(t *testing.T, ws *xws.Conn) string {
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(2*time.Second)))
+	var message string
+	require.NoError(t, xws.Message.Receive(ws, &message))
+	return message
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHub_EchoAndUser(t *testing.T) {
+	hub := NewHub(Config{
+		Identify: func(r *http.Request) string { return r.URL.Query().Get("user") },
+		OnMessage: func(c *Conn, message []byte) {
+			_ = c.Send(append([]byte(c.UserID+":"), message...))
+		},
+	})
+	srv := newTestServer(t, hub)
+
+	ws, err := xws.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/?user=42", "", srv.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	require.NoError(t, xws.Message.Send(ws, "hello"))
+	assert.Equal(t, "42:hello", receive(t, ws))
+	assert.Equal(t, 1, hub.Count())
+}
+
+func TestHub_RejectsCrossOrigin(t *testing.T) {
+	srv := newTestServer(t, NewHub(Config{}))
+
+	_, err := xws.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), "", "http://evil.example.com")
+	assert.Error(t, err)
+
+	hub := NewHub(Config{AllowedOrigins: []string{"http://app.example.com"}})
+	srv = newTestServer(t, hub)
+	dial(t, srv, "http://app.example.com")
+	waitFor(t, func() bool { return hub.Count() == 1 })
+}
+
+func TestHub_Rooms(t *testing.T) {
+	hub := NewHub(Config{
+		OnConnect: func(c *Conn) {
+			c.Join("lobby")
+			c.Set("name", "guest")
+		},
+		OnMessage: func(c *Conn, message []byte) {
+			c.Leave(string(message))
+			_ = c.Send([]byte("left"))
+		},
+	})
+	srv := newTestServer(t, hub)
+
+	a := dial(t, srv, srv.URL)
+	b := dial(t, srv, srv.URL)
+	waitFor(t, func() bool { return hub.Count() == 2 })
+
+	require.NoError(t, xws.Message.Send(b, "lobby"))
+	assert.Equal(t, "left", receive(t, b))
+
+	hub.BroadcastRoom("lobby", []byte("room"))
+	hub.Broadcast([]byte("all"))
+	assert.Equal(t, "room", receive(t, a))
+	assert.Equal(t, "all", receive(t, a))
+	assert.Equal(t, "all", receive(t, b))
+
+	for _, c := range hub.Conns() {
+		name, ok := c.Get("name")
+		assert.True(t, ok)
+		assert.Equal(t, "guest", name)
+	}
+}
+
+func TestHub_SendToUser(t *testing.T) {
+	hub := NewHub(Config{Identify: func(r *http.Request) string { return r.URL.Query().Get("user") }})
+	srv := newTestServer(t, hub)
+	base := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	alice, err := xws.Dial(base+"/?user=alice", "", srv.URL)
+	require.NoError(t, err)
+	defer alice.Close()
+	bob, err := xws.Dial(base+"/?user=bob", "", srv.URL)
+	require.NoError(t, err)
+	defer bob.Close()
+	waitFor(t, func() bool { return hub.Count() == 2 })
+
+	hub.SendToUser("bob", []byte("for bob"))
+	hub.Broadcast([]byte("for all"))
+	assert.Equal(t, "for bob", receive(t, bob))
+	assert.Equal(t, "for all", receive(t, alice))
+}
+
+func TestHub_Shutdown(t *testing.T) {
+	closed := make(chan string, 1)
+	hub := NewHub(Config{OnClose: func(c *Conn) { closed <- c.ID }})
+	srv := newTestServer(t, hub)
+
+	ws := dial(t, srv, srv.URL)
+	waitFor(t, func() bool { return hub.Count() == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, hub.Shutdown(ctx))
+	assert.Equal(t, 0, hub.Count())
+	assert.NotEmpty(t, <-closed)
+
+	var message string
+	assert.Error(t, xws.Message.Receive(ws, &message))
+
+	// no new connections once shut down
+	late := dial(t, srv, srv.URL)
+	assert.Error(t, xws.Message.Receive(late, &message))
+	assert.Equal(t, 0, hub.Count())
+}
@@ -0,0 +1,42 @@
+package sauri
+
+import (
+	"context"
+	"fmt"
+	"github.com/haskekareem/sauri/websocket"
+	"net/http"
+)
+
+// NewWebSocketHub creates a websocket hub whose connections are associated with the
+// logged-in user of the session, if any. The hub is closed when the application shuts down.
+// Mount hub.Handler() on a route behind the session middleware.
+func (s *Sauri) NewWebSocketHub(config websocket.Config) *websocket.Hub {
+	if config.Identify == nil && s.Session != nil {
+		config.Identify = func(r *http.Request) string {
+			if !s.Session.Exists(r.Context(), "userID") {
+				return ""
+			}
+			return fmt.Sprint(s.Session.Get(r.Context(), "userID"))
+		}
+	}
+
+	hub := websocket.NewHub(config)
+	s.hubsMu.Lock()
+	s.hubs = append(s.hubs, hub)
+	s.hubsMu.Unlock()
+	return hub
+}
+
+// ShutdownWebSockets closes the connections of every hub created with NewWebSocketHub
+func (s *Sauri) ShutdownWebSockets(ctx context.Context) error {
+	s.hubsMu.Lock()
+	hubs := append([]*websocket.Hub(nil), s.hubs...)
+	s.hubsMu.Unlock()
+
+	for _, hub := range hubs {
+		if err := hub.Shutdown(ctx); err != nil {
+			return fmt.Errorf("cannot close websocket connections: %w", err)
+		}
+	}
+	return nil
+}